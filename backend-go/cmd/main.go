@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
@@ -21,6 +22,7 @@ import (
 	"ecommerce-backend/internal/database"
 	"ecommerce-backend/internal/handlers"
 	"ecommerce-backend/internal/middleware"
+	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/repositories"
 	"ecommerce-backend/internal/seeds"
 	"ecommerce-backend/internal/services"
@@ -39,10 +41,19 @@ func main() {
 	godotenv.Load()
 
 	var (
-		mode      = flag.String("mode", "server", "Mode: server, init, seed, admin, generate-images, auto-init")
+		mode      = flag.String("mode", "server", "Mode: server, init, migrate, seed, admin, create-admin, generate-images, auto-init")
 		waitForDB = flag.Bool("wait", false, "Wait for database to be available")
 		timeout   = flag.Duration("timeout", 30*time.Second, "Timeout for database connection")
 		seedType  = flag.String("type", "all", "Seed type: all, categories, products, users, orders, reviews")
+		dryRun    = flag.Bool("dry-run", false, "With -mode=migrate, print pending migrations without applying them")
+		count     = flag.Int("count", 0, "With -mode=seed, generate this many products, users and orders")
+		products  = flag.Int("count-products", 0, "With -mode=seed, number of products to generate (overrides -count)")
+		users     = flag.Int("count-users", 0, "With -mode=seed, number of users to generate (overrides -count)")
+		orders    = flag.Int("count-orders", 0, "With -mode=seed, number of orders to generate (overrides -count)")
+		randSeed  = flag.Int64("seed", 0, "With -mode=seed, random seed for reproducible generated data (0 picks one)")
+		batchSize = flag.Int("batch-size", repositories.DefaultBatchSize, "With -mode=seed, rows per insert statement for generated data")
+		email     = flag.String("email", "", "With -mode=create-admin, email of the admin to create")
+		name      = flag.String("name", "", "With -mode=create-admin, display name of the admin to create")
 		help      = flag.Bool("help", false, "Show help message")
 	)
 	flag.Parse()
@@ -60,10 +71,14 @@ func main() {
 	switch *mode {
 	case "init":
 		runInit(cfg, *waitForDB, *timeout)
+	case "migrate":
+		runMigrate(cfg, *dryRun)
 	case "seed":
-		runSeed(cfg, *seedType)
+		runSeed(cfg, *seedType, seedOptions(*count, *products, *users, *orders, *randSeed, *batchSize))
 	case "admin":
 		runAdmin(cfg)
+	case "create-admin":
+		runCreateAdmin(cfg, *email, *name)
 	case "generate-images":
 		runGenerateImages()
 	case "auto-init":
@@ -71,7 +86,7 @@ func main() {
 	case "server":
 		runServer(cfg)
 	default:
-		log.Fatal("Invalid mode. Use: server, init, seed, admin, generate-images, auto-init")
+		log.Fatal("Invalid mode. Use: server, init, migrate, seed, admin, create-admin, generate-images, auto-init")
 	}
 }
 
@@ -105,7 +120,84 @@ func runInit(cfg *config.AppConfig, waitForDB bool, timeout time.Duration) {
 	fmt.Println("🎉 Database setup completed!")
 }
 
-func runSeed(cfg *config.AppConfig, seedType string) {
+// runMigrate applies pending migrations. With dryRun it only prints them and
+// their SQL, exiting with status 1 when any are pending so CI can gate on it.
+func runMigrate(cfg *config.AppConfig, dryRun bool) {
+	if err := database.InitDatabase(); err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+	defer database.CloseDatabase()
+
+	if !dryRun {
+		fmt.Println("🔄 Running migrations...")
+		if err := database.RunMigrations(database.GetDB()); err != nil {
+			log.Fatal("Failed to run migrations:", err)
+		}
+		fmt.Println("✅ Migrations completed successfully!")
+		return
+	}
+
+	pending, err := database.NewMigrationManager(database.GetDB()).Pending()
+	if err != nil {
+		log.Fatal("Failed to check migrations:", err)
+	}
+	if len(pending) == 0 {
+		fmt.Println("✅ No pending migrations")
+		return
+	}
+
+	fmt.Printf("⚠️  %d pending migration(s) (dry run, nothing applied):\n", len(pending))
+	for _, migration := range pending {
+		fmt.Printf("\n-- %03d_%s\n%s\n", migration.Version, migration.Name, strings.TrimSpace(dedent(migration.UpSQL)))
+	}
+
+	database.CloseDatabase()
+	os.Exit(1)
+}
+
+// dedent strips the indentation shared by every non-blank line of sql, which
+// builtin migrations inherit from their Go source.
+func dedent(sql string) string {
+	lines := strings.Split(sql, "\n")
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent <= 0 {
+		return sql
+	}
+	for i, line := range lines {
+		if len(line) >= indent {
+			lines[i] = line[indent:]
+		} else {
+			lines[i] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// seedOptions turns the -count flags into per-seeder counts; a per-type flag
+// wins over -count.
+func seedOptions(count, products, users, orders int, randSeed int64, batchSize int) seeds.Options {
+	counts := map[string]int{}
+	for name, n := range map[string]int{"products": products, "users": users, "orders": orders} {
+		if n == 0 {
+			n = count
+		}
+		if n > 0 {
+			counts[name] = n
+		}
+	}
+	return seeds.Options{Counts: counts, RandSeed: randSeed, BatchSize: batchSize}
+}
+
+func runSeed(cfg *config.AppConfig, seedType string, options seeds.Options) {
 	fmt.Println("🌱 Seeding database...")
 
 	if err := database.InitDatabase(); err != nil {
@@ -118,6 +210,7 @@ func runSeed(cfg *config.AppConfig, seedType string) {
 		log.Fatal("Failed to create seed manager:", err)
 	}
 	defer seedManager.Close()
+	seedManager.SetOptions(options)
 
 	if seedType == "all" {
 		err = seedManager.Run()
@@ -132,6 +225,41 @@ func runSeed(cfg *config.AppConfig, seedType string) {
 	fmt.Printf("✅ Database seeded with %s data successfully!\n", seedType)
 }
 
+// runCreateAdmin creates the first admin account. The password comes from
+// ADMIN_PASSWORD, or is prompted for, so it never appears in the process
+// list or shell history. Nothing changes when an admin already exists.
+func runCreateAdmin(cfg *config.AppConfig, email, name string) {
+	if err := database.InitDatabase(); err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+	defer database.CloseDatabase()
+
+	stdin := bufio.NewReader(os.Stdin)
+	prompt := func(label string) string {
+		fmt.Print(label)
+		value, _ := stdin.ReadString('\n')
+		return strings.TrimSpace(value)
+	}
+	if email == "" {
+		email = prompt("Admin email: ")
+	}
+	password := os.Getenv("ADMIN_PASSWORD")
+	if password == "" {
+		password = prompt("Admin password: ")
+	}
+
+	userService := services.NewUserService(repositories.NewUserRepository(database.GetDB()), newPasswordHasher(cfg.Password))
+	admin, created, err := userService.CreateFirstAdmin(strings.TrimSpace(email), password, name)
+	if err != nil {
+		log.Fatal("Failed to create admin: ", err)
+	}
+	if !created {
+		fmt.Println("ℹ️  An admin account already exists; nothing to do")
+		return
+	}
+	fmt.Printf("✅ Admin %s created\n", admin.Email)
+}
+
 func runAdmin(cfg *config.AppConfig) {
 	fmt.Println("🔧 Starting admin panel...")
 
@@ -140,12 +268,18 @@ func runAdmin(cfg *config.AppConfig) {
 	}
 	defer database.CloseDatabase()
 
+	jobQueue := services.NewJobQueue(cfg.Jobs.Workers, cfg.Jobs.MaxAttempts)
+	jobHandler := handlers.NewJobHandler(services.NewSeedService(database.GetDB(), jobQueue))
+
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
+	if err := middleware.ConfigureClientIP(r, clientIPConfig(cfg.Server)); err != nil {
+		log.Fatal("Invalid trusted proxies config:", err)
+	}
 
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
-	r.Use(corsMiddleware())
+	r.Use(corsMiddleware(cfg.Security.CORSMaxAge))
 	r.Use(authMiddleware())
 
 	r.Static("/static", "./static")
@@ -162,9 +296,12 @@ func runAdmin(cfg *config.AppConfig) {
 	r.GET("/api/orders", ordersHandler)
 	r.GET("/api/health", healthHandler)
 
-	r.POST("/api/seed", seedHandler)
+	r.GET("/api/jobs/:id", jobHandler.GetJob)
+
+	r.POST("/api/seed", jobHandler.StartSeed)
 	r.POST("/api/migrate", migrateHandler)
 	r.POST("/api/cache/clear", clearCacheHandler)
+	r.POST("/api/cache/stats/reset", resetCacheStatsHandler)
 	r.POST("/api/logs/clear", clearLogsHandler)
 
 	r.GET("/ws", websocketHandler)
@@ -186,6 +323,7 @@ func runServer(cfg *config.AppConfig) {
 	fmt.Println("🚀 Starting Eshop server...")
 
 	utils.InitJWT(cfg.JWT.Secret, cfg.JWT.ExpiresIn, cfg.JWT.RefreshIn, cfg.JWT.Issuer, cfg.JWT.Audience)
+	utils.GetLogger().SetLevel(utils.ParseLogLevel(cfg.Logging.Level))
 	if err := database.InitDatabase(); err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
@@ -197,43 +335,196 @@ func runServer(cfg *config.AppConfig) {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	r := gin.New()
-	r.Use(gin.Recovery())
-	r.Use(middleware.CORSMiddleware())
-	r.Use(middleware.SecurityHeadersMiddleware())
+	if err := middleware.ConfigureClientIP(r, clientIPConfig(cfg.Server)); err != nil {
+		log.Fatal("Invalid trusted proxies config:", err)
+	}
+	r.Use(middleware.RecoveryMiddleware())
+	r.Use(middleware.CORSMiddlewareWithMaxAge(cfg.Security.CORSMaxAge))
+	securityHeaders, err := buildSecurityHeaders(cfg.Security)
+	if err != nil {
+		log.Fatal("Invalid security headers config:", err)
+	}
+	r.Use(middleware.SecurityHeadersWithConfig(securityHeaders))
 	r.Use(middleware.LoggingMiddleware())
-	r.Use(middleware.RequestIDMiddleware())
+	r.Use(middleware.RequestIDMiddlewareWithHeader(cfg.Server.RequestIDHeader))
 	r.Use(middleware.MetricsMiddleware())
-	r.Use(middleware.RateLimitMiddleware(100, time.Minute))
+	r.Use(middleware.MaxBodySize(cfg.Server.MaxBodyBytes, map[string]int64{
+		"/api/uploads/": cfg.Server.MaxUploadBytes,
+	}))
+	if cfg.Logging.DebugBodies && len(cfg.Logging.DebugBodyRoutes) > 0 {
+		utils.Warn("Debug body logging is enabled", "routes", cfg.Logging.DebugBodyRoutes)
+		r.Use(middleware.DebugBodyLogging(cfg.Logging.DebugBodyRoutes, cfg.Logging.DebugBodyMaxBytes))
+	}
+	r.Use(middleware.RateLimitMiddleware(100, time.Minute, cfg.Server.RateLimitBypassPaths...))
+	maintenance := middleware.NewMaintenanceMode(cfg.Maintenance.Enabled, cfg.Maintenance.Message, cfg.Maintenance.RetryAfter, cfg.Maintenance.AllowedPaths)
+	r.Use(middleware.MaintenanceMiddleware(maintenance))
+	database.ConfigureResilience(cfg.Database.ReadRetries, cfg.Database.RetryBackoff, cfg.Database.BreakerThreshold, cfg.Database.BreakerCooldown)
+	dbBreaker := database.Resilience.Breaker
+	r.Use(middleware.DatabaseCircuitBreaker(dbBreaker, []string{"/api/health", "/api/version"}))
 
 	r.LoadHTMLGlob("templates/*")
+	for name, cacheCfg := range cfg.Cache.Caches {
+		utils.ConfigureCache(name, cacheCfg.TTL, cacheCfg.MaxSize)
+	}
 	db := database.GetDB()
 	userRepo := repositories.NewUserRepository(db)
 	productRepo := repositories.NewProductRepository(db)
 	categoryRepo := repositories.NewCategoryRepository(db)
 	reviewRepo := repositories.NewReviewRepository(db)
+	questionRepo := repositories.NewQuestionRepository(db)
 	cartRepo := repositories.NewCartRepository(db)
 	orderRepo := repositories.NewOrderRepository(db)
+	shipmentRepo := repositories.NewShipmentRepository(db)
+	returnRepo := repositories.NewReturnRepository(db)
 	paymentRepo := repositories.NewPaymentRepository(db)
+	downloadRepo := repositories.NewDownloadRepository(db)
+	notificationPreferenceRepo := repositories.NewNotificationPreferenceRepository(db)
+	notificationRepo := repositories.NewNotificationRepository(db)
 	wishlistRepo := repositories.NewWishlistRepository(db)
-	userService := services.NewUserService(userRepo)
-	productService := services.NewProductService(productRepo, categoryRepo, reviewRepo)
-	reviewService := services.NewReviewService(reviewRepo)
-	cartService := services.NewCartService(cartRepo, productRepo)
-	orderService := services.NewOrderService(orderRepo, cartRepo, productRepo)
-	paymentService := services.NewPaymentService(paymentRepo, orderRepo)
-	wishlistService := services.NewWishlistService(wishlistRepo)
-	categoryService := services.NewCategoryService(categoryRepo, productRepo)
-	authHandler := handlers.NewAuthHandler(userService, cfg)
-	productHandler := handlers.NewProductHandler(productService)
+	uploadRepo := repositories.NewUploadRepository(db)
+	priceHistoryRepo := repositories.NewPriceHistoryRepository(db)
+	productImageRepo := repositories.NewProductImageRepository(db)
+	translationRepo := repositories.NewTranslationRepository(db)
+	dataExportRepo := repositories.NewDataExportRepository(db)
+	auditRepo := repositories.NewAuditRepository(db)
+	recommendationRepo := repositories.NewRecommendationRepository(db)
+	storeRepo := repositories.NewStoreRepository(db)
+	reportRepo := repositories.NewReportRepository(db)
+	maintenanceRepo := repositories.NewMaintenanceRepository(db)
+	inventoryRepo := repositories.NewInventoryRepository(db)
+	bundleRepo := repositories.NewBundleRepository(db)
+	wsHub := websocket.NewHub(cfg.WebSocket.BroadcastWorkers, cfg.WebSocket.BroadcastBuffer)
+	wsHub.SetKeepalive(websocket.KeepaliveConfig{
+		PingInterval: cfg.WebSocket.PingInterval,
+		PongWait:     cfg.WebSocket.PongWait,
+		WriteWait:    cfg.WebSocket.WriteWait,
+	})
+	wsHub.SetConnectionLimits(websocket.ConnectionLimits{
+		MaxConnections:        cfg.WebSocket.MaxConnections,
+		MaxConnectionsPerUser: cfg.WebSocket.MaxConnectionsPerUser,
+	})
+	go wsHub.Run()
+	maintenance.OnToggle(func(enabled bool, message string) {
+		if !enabled {
+			message = "Maintenance finished, the service is available again"
+		}
+		wsHub.SendMaintenanceAlert(message, time.Now())
+	})
+	maintenanceService := services.NewMaintenanceService(maintenanceRepo, maintenance, wsHub, cfg.Maintenance.AlertLeadTimes)
+	uploadPath := "./uploads"
+	uploadService := services.NewUploadService(uploadRepo, uploadPath, int64(cfg.Uploads.UserQuotaMB)*1024*1024, int64(cfg.Uploads.AdminQuotaMB)*1024*1024, cfg.Uploads.SigningSecret, cfg.Uploads.SignedURLTTL, models.ImageConstraints{
+		MinWidth:        cfg.Uploads.ProductMinWidth,
+		MinHeight:       cfg.Uploads.ProductMinHeight,
+		AspectRatio:     cfg.Uploads.ProductAspect(),
+		AspectTolerance: cfg.Uploads.ProductAspectTolerance,
+	})
+	passwordHasher := newPasswordHasher(cfg.Password)
+	utils.SetPasswordHasher(passwordHasher)
+	userService := services.NewUserService(userRepo, passwordHasher)
+	jobQueue := services.NewJobQueue(cfg.Jobs.Workers, cfg.Jobs.MaxAttempts)
+	notificationPreferenceService := services.NewNotificationPreferenceService(notificationPreferenceRepo)
+	wsHub.SetOptOutFilter(notificationPreferenceService.WebSocketOptOuts)
+	notificationService := services.NewNotificationService(notificationRepo)
+	wsHub.SetNotificationStore(notificationService.Record)
+	emailService := services.NewEmailService(cfg.Email)
+	auditService := services.NewAuditService(auditRepo, jobQueue)
+	recommendationService := services.NewRecommendationService(recommendationRepo, productRepo)
+	jobQueue.Schedule("refresh_product_associations", cfg.Jobs.RecommendationsInterval, recommendationService.RefreshAssociations)
+	captchaService := services.NewCaptchaService(cfg.Register.CaptchaEnabled, cfg.Register.CaptchaProvider, cfg.Register.CaptchaSecret)
+	emailDomainPolicy := services.NewEmailDomainPolicy(cfg.Register)
+	if cfg.Register.BlockedEmailDomainsFile != "" && !cfg.Register.EmailDomainCheckDisabled {
+		jobQueue.Schedule("reload_email_domains", cfg.Register.BlockedEmailDomainsReload, emailDomainPolicy.Reload)
+	}
+	seedService := services.NewSeedService(db, jobQueue)
+	accountService := services.NewAccountService(userRepo, orderRepo, paymentRepo, reviewRepo, wishlistRepo, dataExportRepo, uploadService, passwordHasher, jobQueue, emailService, "./exports", cfg.Server.PublicURL)
+	utils.SetRevocationCheck(accountService.TokenRevoked)
+	jobQueue.Schedule("sweep_expired_data_exports", services.DataExportSweepInterval, accountService.SweepExpiredExports)
+	translationService := services.NewTranslationService(translationRepo, productRepo, categoryRepo, cfg.Catalog.DefaultLocale, cfg.Catalog.Locales)
+	productService := services.NewProductService(productRepo, categoryRepo, reviewRepo, priceHistoryRepo, productImageRepo, inventoryRepo, uploadRepo, auditService, translationService, cfg.Catalog)
+	reviewService := services.NewReviewService(reviewRepo, uploadService, wsHub, models.ReviewLimits{
+		CommentMinLength: cfg.Reviews.CommentMinLength,
+		CommentMaxLength: cfg.Reviews.CommentMaxLength,
+	}, cfg.Reviews.UpsertDuplicates)
+	questionService := services.NewQuestionService(questionRepo, productRepo, auditService, wsHub)
+	shippingEstimator := services.NewShippingEstimator(cfg.Orders)
+	orderPricing := services.NewOrderPricing(cfg.Orders, shippingEstimator)
+	cartService := services.NewCartService(cartRepo, productRepo, orderPricing, shippingEstimator, cfg.Cart.ItemMaxAge)
+	jobQueue.Schedule("sweep_stale_cart_items", cfg.Cart.SweepInterval, cartService.SweepStaleItems)
+	jobQueue.Schedule("maintenance_windows", cfg.Maintenance.CheckInterval, maintenanceService.Tick)
+	orderService := services.NewOrderService(orderRepo, cartRepo, productRepo, userRepo, shipmentRepo, inventoryRepo, bundleRepo, auditService, orderPricing, emailService, jobQueue, wsHub,
+		cfg.Orders.ConfirmationResendLimit, cfg.Orders.ConfirmationResendWindow)
+	if cfg.Orders.UnpaidTimeout > 0 {
+		jobQueue.Schedule("cancel_unpaid_orders", cfg.Orders.UnpaidCheckInterval, func() error {
+			return orderService.CancelUnpaidOrders(cfg.Orders.UnpaidTimeout)
+		})
+	}
+	shipmentService := services.NewShipmentService(shipmentRepo, orderRepo, wsHub)
+	downloadService := services.NewDownloadService(downloadRepo, orderRepo, cfg.Orders.DownloadLimit, cfg.Orders.DownloadTTL)
+	paymentService := services.NewPaymentService(paymentRepo, orderRepo, paymentProviders(cfg), downloadService, wsHub, cfg.Payments.TestMode)
+	if cfg.Payments.TestMode {
+		utils.Warn("Payments are in test mode; no payment gateway is charged")
+	}
+	returnService := services.NewReturnService(returnRepo, orderRepo, shipmentRepo, inventoryRepo, paymentService, wsHub, cfg.Orders.ReturnWindowDays)
+	wishlistService := services.NewWishlistService(wishlistRepo, productRepo, priceHistoryRepo)
+	categoryService := services.NewCategoryService(categoryRepo, productRepo, translationService)
+	searchService := services.NewSearchService(productService, productRepo, categoryRepo)
+	storeService := services.NewStoreService(storeRepo, cfg.Stores.BaseDomain)
+	reportService := services.NewReportService(reportRepo, jobQueue, "./exports/reports")
+	inventoryService := services.NewInventoryService(inventoryRepo, productRepo)
+	bundleService := services.NewBundleService(bundleRepo, productRepo)
+	feedService := services.NewFeedService(productRepo, categoryRepo, cfg.Server.SiteURL, cfg.Server.PublicURL, cfg.Catalog.Currency, cfg.Catalog.StoreName)
+	cacheWarmer := services.NewCacheWarmer(productService, categoryService, storeService, cfg.Catalog.DefaultLocale)
+	if cfg.Cache.Warmup {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Cache.WarmupTimeout)
+			defer cancel()
+			if err := cacheWarmer.Warm(ctx); err != nil {
+				log.Printf("⚠️  Cache warmup incomplete: %v", err)
+			}
+		}()
+	}
+	authHandler := handlers.NewAuthHandler(userService, accountService, auditService, captchaService, emailDomainPolicy, cfg)
+	productHandler := handlers.NewProductHandler(productService, recommendationService)
 	reviewHandler := handlers.NewReviewHandler(reviewService)
+	questionHandler := handlers.NewQuestionHandler(questionService)
 	cartHandler := handlers.NewCartHandler(cartService)
-	orderHandler := handlers.NewOrderHandler(orderService)
+	orderHandler := handlers.NewOrderHandler(orderService, shipmentService, returnService, auditService)
 	paymentHandler := handlers.NewPaymentHandler(paymentService)
+	auditHandler := handlers.NewAuditHandler(auditService)
 	wishlistHandler := handlers.NewWishlistHandler(wishlistService)
 	categoryHandler := handlers.NewCategoryHandler(categoryService)
-	uploadHandler := handlers.NewUploadHandler("./uploads")
-	wsHub := websocket.NewHub()
-	go wsHub.Run()
+	searchHandler := handlers.NewSearchHandler(searchService)
+	feedHandler := handlers.NewFeedHandler(feedService)
+	translationHandler := handlers.NewTranslationHandler(translationService)
+	jobHandler := handlers.NewJobHandler(seedService)
+	uploadHandler := handlers.NewUploadHandler(uploadPath, uploadService)
+	downloadHandler := handlers.NewDownloadHandler(uploadPath, downloadService)
+	notificationPreferenceHandler := handlers.NewNotificationPreferenceHandler(notificationPreferenceService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	storeHandler := handlers.NewStoreHandler(storeService)
+	reportHandler := handlers.NewReportHandler(reportService)
+	maintenanceHandler := handlers.NewMaintenanceHandler(maintenanceService)
+	inventoryHandler := handlers.NewInventoryHandler(inventoryService)
+	bundleHandler := handlers.NewBundleHandler(bundleService)
+	r.Use(middleware.StoreResolver(middleware.StoreResolverConfig{
+		Header:         cfg.Stores.Header,
+		JWTClaim:       cfg.Stores.JWTClaim,
+		DefaultStoreID: models.DefaultStoreID,
+		ByIdentifier: func(value string) (string, error) {
+			store, err := storeService.Resolve(value)
+			if err != nil {
+				return "", err
+			}
+			return store.ID, nil
+		},
+		ByHost: func(host string) (string, error) {
+			store, err := storeService.ResolveHost(host)
+			if err != nil {
+				return "", err
+			}
+			return store.ID, nil
+		},
+	}))
 	r.GET("/api/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"status":    "healthy",
@@ -247,6 +538,75 @@ func runServer(cfg *config.AppConfig) {
 		c.Status(200)
 	})
 
+	// Build metadata and which optional features are switched on; no hosts,
+	// keys or other configuration values are exposed.
+	r.GET("/api/version", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"build": utils.GetBuildInfo(),
+			"features": gin.H{
+				"maintenance":   maintenance.Status().Enabled,
+				"captcha":       cfg.Register.CaptchaEnabled,
+				"metrics":       cfg.Metrics.Enabled,
+				"review_upsert": cfg.Reviews.UpsertDuplicates,
+				"stripe":        cfg.Stripe.SecretKey != "",
+				"hsts":          !cfg.Security.DisableHSTS,
+			},
+		})
+	})
+
+	r.GET("/sitemap.xml", feedHandler.GetSitemap)
+	r.GET("/sitemaps/:page", feedHandler.GetSitemapPage)
+	r.GET("/api/health/ready", func(c *gin.Context) {
+		ready := true
+		databaseStatus := "healthy"
+		// Readiness probes double as breaker probes, so the circuit closes as
+		// soon as the database answers again.
+		if err := db.Ping(); err != nil {
+			dbBreaker.Failure()
+			ready = false
+			databaseStatus = "unhealthy"
+		} else {
+			dbBreaker.Success()
+		}
+		circuitState := dbBreaker.State()
+		if circuitState == database.BreakerOpen {
+			ready = false
+		}
+		hubStatus := "healthy"
+		if !wsHub.Healthy() {
+			ready = false
+			hubStatus = "unhealthy"
+		}
+		// A cache backend outage degrades performance, not availability:
+		// reads fall through to the database, so readiness still passes.
+		cacheStatus, cacheCircuit := utils.CacheBackendStatus()
+		warmupStatus := "disabled"
+		if cfg.Cache.Warmup {
+			warmupStatus = "complete"
+			if !cacheWarmer.Done() {
+				ready = false
+				warmupStatus = "running"
+			}
+		}
+		status := http.StatusOK
+		if !ready {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{
+			"ready":     ready,
+			"timestamp": time.Now().Format(time.RFC3339),
+			"checks": gin.H{
+				"database": gin.H{"status": databaseStatus, "circuit": circuitState},
+				"websocket": gin.H{
+					"status":  hubStatus,
+					"clients": wsHub.GetClientCount(),
+				},
+				"cache":        gin.H{"status": cacheStatus, "circuit": cacheCircuit},
+				"cache_warmup": gin.H{"status": warmupStatus},
+			},
+		})
+	})
+
 	r.GET("/api/metrics", func(c *gin.Context) {
 		stats := middleware.GlobalMetrics.GetStats()
 		c.Header("Content-Type", "text/plain")
@@ -274,7 +634,7 @@ http_request_duration_seconds %s
 	r.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "Eshop API Server",
-			"version": "2.0.0",
+			"version": utils.Version,
 			"status":  "running",
 			"endpoints": gin.H{
 				"products":   "/api/products",
@@ -283,22 +643,25 @@ http_request_duration_seconds %s
 				"cart":       "/api/cart",
 				"orders":     "/api/orders",
 				"reviews":    "/api/reviews",
+				"questions":  "/api/questions",
 				"payments":   "/api/payments",
 				"wishlist":   "/api/wishlist",
 				"health":     "/api/health",
+				"version":    "/api/version",
 				"docs":       "/docs",
 				"admin":      "/admin",
 			},
 		})
 	})
 
-	r.GET("/docs", func(c *gin.Context) {
+	pageHeaders := middleware.PageSecurityHeaders(securityHeaders)
+	r.GET("/docs", pageHeaders, func(c *gin.Context) {
 		c.HTML(200, "docs.html", gin.H{
 			"title": "Eshop API Documentation",
 		})
 	})
 
-	r.GET("/admin", func(c *gin.Context) {
+	r.GET("/admin", pageHeaders, func(c *gin.Context) {
 		c.HTML(200, "dashboard.html", gin.H{
 			"title": "Admin Dashboard",
 		})
@@ -308,29 +671,58 @@ http_request_duration_seconds %s
 		auth.POST("/register", authHandler.Register)
 		auth.POST("/login", authHandler.Login)
 		auth.GET("/profile", middleware.AuthMiddleware(), authHandler.Profile)
-		auth.PUT("/profile", middleware.AuthMiddleware(), authHandler.UpdateProfile)
+		auth.PUT("/profile", middleware.AuthMiddleware(), middleware.NoImpersonationMiddleware(), authHandler.UpdateProfile)
+		auth.DELETE("/account", middleware.AuthMiddleware(), middleware.NoImpersonationMiddleware(), authHandler.DeleteAccount)
+		auth.GET("/account/export", middleware.AuthMiddleware(), middleware.NoImpersonationMiddleware(), authHandler.RequestDataExport)
+		auth.GET("/account/export/:token", authHandler.DownloadDataExport)
+		auth.GET("/notification-preferences", middleware.AuthMiddleware(), notificationPreferenceHandler.GetPreferences)
+		auth.PUT("/notification-preferences", middleware.AuthMiddleware(), notificationPreferenceHandler.UpdatePreferences)
+		auth.POST("/impersonation/end", middleware.AuthMiddleware(), authHandler.EndImpersonation)
 	}
 	products := r.Group("/api/products")
 	{
 		products.GET("/", productHandler.GetProducts)
 		products.GET("/featured", productHandler.GetFeaturedProducts)
+		products.GET("/new", productHandler.GetNewArrivals)
+		products.GET("/feed.xml", feedHandler.GetProductFeed)
 		products.GET("/search", productHandler.SearchProducts)
+		products.POST("/batch", productHandler.GetProductsBatch)
+		products.POST("/stock-check", productHandler.CheckStock)
+		products.GET("/by-sku/:sku", productHandler.GetProductBySKU)
+		products.GET("/by-barcode/:barcode", productHandler.GetProductByBarcode)
 		products.GET("/:id", productHandler.GetProduct)
+		products.GET("/:id/frequently-bought-together", productHandler.GetFrequentlyBoughtTogether)
+		products.GET("/:id/bundle", bundleHandler.GetBundle)
+		products.GET("/:id/inventory-history", middleware.AuthMiddleware(), middleware.AdminMiddleware(), inventoryHandler.GetInventoryHistory)
+	}
+	search := r.Group("/api/search")
+	{
+		search.GET("", searchHandler.Search)
+		search.GET("/suggest", searchHandler.Suggest)
 	}
 	categories := r.Group("/api/categories")
 	{
 		categories.GET("/", categoryHandler.GetCategories)
 		categories.GET("/:slug", categoryHandler.GetCategory)
+		categories.GET("/:slug/stats", categoryHandler.GetCategoryStats)
 		categories.POST("/", middleware.AuthMiddleware(), categoryHandler.CreateCategory)
 		categories.PUT("/:slug", middleware.AuthMiddleware(), categoryHandler.UpdateCategory)
 		categories.DELETE("/:slug", middleware.AuthMiddleware(), categoryHandler.DeleteCategory)
 	}
+	notifications := r.Group("/api/notifications")
+	notifications.Use(middleware.AuthMiddleware())
+	{
+		notifications.GET("", notificationHandler.GetNotifications)
+		notifications.POST("/:id/read", notificationHandler.MarkRead)
+	}
 	cart := r.Group("/api/cart")
 	cart.Use(middleware.AuthMiddleware())
 	{
 		cart.GET("/", cartHandler.GetCart)
 		cart.POST("/", cartHandler.AddToCart)
+		cart.POST("/shipping-estimate", cartHandler.EstimateShipping)
 		cart.PUT("/:id", cartHandler.UpdateCartItem)
+		cart.DELETE("/notices", cartHandler.AcknowledgeNotices)
 		cart.DELETE("/:id", cartHandler.RemoveFromCart)
 		cart.DELETE("/", cartHandler.ClearCart)
 	}
@@ -341,17 +733,54 @@ http_request_duration_seconds %s
 		orders.GET("/:id", orderHandler.GetOrder)
 		orders.POST("/", orderHandler.CreateOrder)
 		orders.PUT("/:id/status", orderHandler.UpdateOrderStatus)
+		orders.POST("/:id/resend-confirmation", orderHandler.ResendConfirmation)
 		orders.DELETE("/:id", orderHandler.CancelOrder)
+		orders.GET("/:id/notes", middleware.AdminMiddleware(), orderHandler.GetInternalNotes)
+		orders.POST("/:id/notes", middleware.AdminMiddleware(), orderHandler.AddInternalNote)
+		orders.POST("/:id/shipments", middleware.AdminMiddleware(), orderHandler.CreateShipment)
+		orders.PUT("/:id/shipments/:shipmentId", middleware.AdminMiddleware(), orderHandler.UpdateShipment)
+		orders.GET("/:id/downloads", downloadHandler.GetOrderDownloads)
+		orders.GET("/:id/returns", orderHandler.GetReturns)
+		orders.POST("/:id/returns", orderHandler.RequestReturn)
+		orders.PUT("/:id/returns/:returnId/approve", middleware.AdminMiddleware(), orderHandler.ApproveReturn)
+		orders.PUT("/:id/returns/:returnId/reject", middleware.AdminMiddleware(), orderHandler.RejectReturn)
+		orders.PUT("/:id/returns/:returnId/receive", middleware.AdminMiddleware(), orderHandler.ReceiveReturn)
 	}
 	reviews := r.Group("/api/reviews")
 	{
 		reviews.GET("/product/:productId", reviewHandler.GetProductReviews)
+		reviews.POST("/summary-batch", reviewHandler.GetReviewSummaryBatch)
+		reviews.GET("/product/:productId/summary", reviewHandler.GetProductReviewSummary)
 		reviews.GET("/user", middleware.AuthMiddleware(), reviewHandler.GetUserReviews)
 		reviews.GET("/user/:productId", middleware.AuthMiddleware(), reviewHandler.GetUserReviewForProduct)
 		reviews.POST("/", middleware.AuthMiddleware(), reviewHandler.CreateReview)
 		reviews.PUT("/:id", middleware.AuthMiddleware(), reviewHandler.UpdateReview)
 		reviews.DELETE("/:id", middleware.AuthMiddleware(), reviewHandler.DeleteReview)
+		reviews.POST("/:id/reply", middleware.AuthMiddleware(), middleware.RoleMiddleware("admin", "seller"), reviewHandler.CreateReply)
+		reviews.PUT("/:id/reply", middleware.AuthMiddleware(), middleware.RoleMiddleware("admin", "seller"), reviewHandler.UpdateReply)
+		reviews.DELETE("/:id/reply", middleware.AuthMiddleware(), middleware.RoleMiddleware("admin", "seller"), reviewHandler.DeleteReply)
+	}
+
+	questions := r.Group("/api/questions")
+	{
+		questions.GET("/product/:productId", questionHandler.GetProductQuestions)
+		questions.POST("/", middleware.AuthMiddleware(), questionHandler.CreateQuestion)
+		questions.DELETE("/:id", middleware.AuthMiddleware(), questionHandler.DeleteQuestion)
+		questions.POST("/:id/answers", middleware.AuthMiddleware(), questionHandler.CreateAnswer)
+		questions.POST("/:id/upvote", middleware.AuthMiddleware(), questionHandler.UpvoteQuestion)
+		questions.DELETE("/:id/upvote", middleware.AuthMiddleware(), questionHandler.RemoveQuestionUpvote)
+		questions.POST("/:id/report", middleware.AuthMiddleware(), questionHandler.ReportQuestion)
 	}
+
+	answers := r.Group("/api/answers")
+	{
+		answers.DELETE("/:id", middleware.AuthMiddleware(), questionHandler.DeleteAnswer)
+		answers.POST("/:id/upvote", middleware.AuthMiddleware(), questionHandler.UpvoteAnswer)
+		answers.DELETE("/:id/upvote", middleware.AuthMiddleware(), questionHandler.RemoveAnswerUpvote)
+		answers.POST("/:id/report", middleware.AuthMiddleware(), questionHandler.ReportAnswer)
+	}
+	// Webhooks are authenticated by the provider's signature, not a user token.
+	r.POST("/api/payments/webhook/:provider", paymentHandler.HandleWebhook)
 	payments := r.Group("/api/payments")
 	payments.Use(middleware.AuthMiddleware())
 	{
@@ -367,14 +796,19 @@ http_request_duration_seconds %s
 		wishlist.POST("/", wishlistHandler.AddToWishlist)
 		wishlist.DELETE("/:productId", wishlistHandler.RemoveFromWishlist)
 		wishlist.GET("/:productId/check", wishlistHandler.IsInWishlist)
+		wishlist.GET("/:productId/price-history", wishlistHandler.GetPriceHistory)
 		wishlist.DELETE("/", wishlistHandler.ClearWishlist)
 	}
 	uploads := r.Group("/api/uploads")
 	{
 		uploads.POST("/", middleware.AuthMiddleware(), uploadHandler.UploadImage)
+		uploads.GET("/", middleware.AuthMiddleware(), uploadHandler.ListUploads)
+		uploads.GET("/usage", middleware.AuthMiddleware(), uploadHandler.GetUsage)
 		uploads.DELETE("/:filename", middleware.AuthMiddleware(), uploadHandler.DeleteImage)
 		uploads.GET("/:filename", uploadHandler.ServeImage)
+		uploads.GET("/:filename/url", middleware.AuthMiddleware(), uploadHandler.GetSignedURL)
 	}
+	r.GET("/api/downloads/:token", downloadHandler.Download)
 	wsHandler := websocket.NewHandler(wsHub)
 	ws := r.Group("/ws")
 	{
@@ -429,18 +863,99 @@ http_request_duration_seconds %s
 				},
 			})
 		})
-		admin.POST("/seed", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "Database seeded successfully"})
-		})
+		admin.POST("/seed", middleware.AuthMiddleware(), middleware.AdminMiddleware(), jobHandler.StartSeed)
+		admin.GET("/jobs/:id", middleware.AuthMiddleware(), middleware.AdminMiddleware(), jobHandler.GetJob)
 		admin.POST("/migrate", func(c *gin.Context) {
 			c.JSON(200, gin.H{"message": "Database migrated successfully"})
 		})
-		admin.POST("/cache/clear", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "Cache cleared successfully"})
+		// Clearing every cache is only audited behind admin auth, so each
+		// entry names the admin who did it.
+		clearAllCaches := func(c *gin.Context) {
+			utils.ClearAllCaches()
+			auditService.Record(handlers.AuditEntry(c, models.AuditActionCacheClear, "cache", "*"), nil, nil)
+			c.JSON(http.StatusOK, gin.H{"message": "Cache cleared successfully"})
+		}
+		admin.POST("/cache/clear", middleware.AuthMiddleware(), middleware.AdminMiddleware(), clearAllCaches)
+		admin.GET("/cache", middleware.AuthMiddleware(), middleware.AdminMiddleware(), func(c *gin.Context) {
+			c.JSON(http.StatusOK, getCacheStats())
+		})
+		admin.POST("/cache/stats/reset", middleware.AuthMiddleware(), middleware.AdminMiddleware(), func(c *gin.Context) {
+			name := c.Query("name")
+			if !utils.ResetCacheStats(name) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Cache not found"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Cache stats reset successfully"})
+		})
+		admin.DELETE("/cache", middleware.AuthMiddleware(), middleware.AdminMiddleware(), func(c *gin.Context) {
+			prefix := c.Query("prefix")
+			if prefix == "" {
+				clearAllCaches(c)
+				return
+			}
+			removed := utils.CacheInvalidatePrefix(prefix)
+			auditService.Record(handlers.AuditEntry(c, models.AuditActionCacheClear, "cache", prefix), nil, gin.H{"removed": removed})
+			c.JSON(http.StatusOK, gin.H{
+				"message": "Cache entries invalidated successfully",
+				"prefix":  prefix,
+				"removed": removed,
+			})
 		})
 		admin.POST("/logs/clear", func(c *gin.Context) {
 			c.JSON(200, gin.H{"message": "Logs cleared successfully"})
 		})
+		admin.PUT("/products/:id", middleware.AuthMiddleware(), middleware.AdminMiddleware(), productHandler.UpdateProduct)
+		admin.POST("/products/merge", middleware.AuthMiddleware(), middleware.AdminMiddleware(), productHandler.MergeProducts)
+		admin.PUT("/products/:id/bundle", middleware.AuthMiddleware(), middleware.AdminMiddleware(), bundleHandler.SetBundle)
+		admin.DELETE("/products/:id/bundle", middleware.AuthMiddleware(), middleware.AdminMiddleware(), bundleHandler.RemoveBundle)
+		admin.POST("/products/:id/images", middleware.AuthMiddleware(), middleware.AdminMiddleware(), productHandler.AddProductImage)
+		admin.POST("/orders/bulk-status", middleware.AuthMiddleware(), middleware.AdminMiddleware(), orderHandler.BulkUpdateOrderStatus)
+		admin.PUT("/products/:id/images/order", middleware.AuthMiddleware(), middleware.AdminMiddleware(), productHandler.ReorderProductImages)
+		admin.PUT("/products/:id/images/:imageId/primary", middleware.AuthMiddleware(), middleware.AdminMiddleware(), productHandler.SetPrimaryProductImage)
+		admin.DELETE("/products/:id/images/:imageId", middleware.AuthMiddleware(), middleware.AdminMiddleware(), productHandler.DeleteProductImage)
+		admin.GET("/products/:id/translations", middleware.AuthMiddleware(), middleware.AdminMiddleware(), translationHandler.ListProductTranslations)
+		admin.PUT("/products/:id/translations/:locale", middleware.AuthMiddleware(), middleware.AdminMiddleware(), translationHandler.SetProductTranslation)
+		admin.DELETE("/products/:id/translations/:locale", middleware.AuthMiddleware(), middleware.AdminMiddleware(), translationHandler.DeleteProductTranslation)
+		admin.GET("/categories/:slug/translations", middleware.AuthMiddleware(), middleware.AdminMiddleware(), translationHandler.ListCategoryTranslations)
+		admin.PUT("/categories/:slug/translations/:locale", middleware.AuthMiddleware(), middleware.AdminMiddleware(), translationHandler.SetCategoryTranslation)
+		admin.DELETE("/categories/:slug/translations/:locale", middleware.AuthMiddleware(), middleware.AdminMiddleware(), translationHandler.DeleteCategoryTranslation)
+		admin.GET("/audit", middleware.AuthMiddleware(), middleware.AdminMiddleware(), auditHandler.GetAuditLog)
+		admin.GET("/questions", middleware.AuthMiddleware(), middleware.AdminMiddleware(), questionHandler.GetQuestionQueue)
+		admin.PUT("/questions/:id/status", middleware.AuthMiddleware(), middleware.AdminMiddleware(), questionHandler.ModerateQuestion)
+		admin.GET("/answers", middleware.AuthMiddleware(), middleware.AdminMiddleware(), questionHandler.GetAnswerQueue)
+		admin.PUT("/answers/:id/status", middleware.AuthMiddleware(), middleware.AdminMiddleware(), questionHandler.ModerateAnswer)
+		admin.PUT("/users/:id/role", middleware.AuthMiddleware(), middleware.AdminMiddleware(), authHandler.UpdateUserRole)
+		admin.PUT("/users/:id/store", middleware.AuthMiddleware(), middleware.AdminMiddleware(), authHandler.UpdateUserStore)
+		admin.POST("/users/:id/impersonate", middleware.AuthMiddleware(), middleware.AdminMiddleware(), authHandler.ImpersonateUser)
+		admin.GET("/stores", middleware.AuthMiddleware(), middleware.AdminMiddleware(), storeHandler.ListStores)
+		admin.POST("/stores", middleware.AuthMiddleware(), middleware.AdminMiddleware(), storeHandler.CreateStore)
+		admin.GET("/stores/:id", middleware.AuthMiddleware(), middleware.AdminMiddleware(), storeHandler.GetStore)
+		admin.PUT("/stores/:id", middleware.AuthMiddleware(), middleware.AdminMiddleware(), storeHandler.UpdateStore)
+		admin.GET("/reports/orders", middleware.AuthMiddleware(), middleware.RoleMiddleware("admin", "finance"), reportHandler.GetOrderReport)
+		admin.GET("/reports/jobs/:id", middleware.AuthMiddleware(), middleware.RoleMiddleware("admin", "finance"), reportHandler.GetReportJob)
+		admin.GET("/reports/jobs/:id/download", middleware.AuthMiddleware(), middleware.RoleMiddleware("admin", "finance"), reportHandler.DownloadReport)
+		admin.GET("/reports/inventory", middleware.AuthMiddleware(), middleware.AdminMiddleware(), inventoryHandler.GetInventoryReport)
+		admin.GET("/maintenance", middleware.AuthMiddleware(), middleware.AdminMiddleware(), func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"maintenance": maintenance.Status()})
+		})
+		admin.PUT("/maintenance", middleware.AuthMiddleware(), middleware.AdminMiddleware(), func(c *gin.Context) {
+			var req struct {
+				Enabled *bool  `json:"enabled" binding:"required"`
+				Message string `json:"message"`
+			}
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			maintenance.Set(*req.Enabled, req.Message)
+			c.JSON(http.StatusOK, gin.H{
+				"message":     "Maintenance mode updated successfully",
+				"maintenance": maintenance.Status(),
+			})
+		})
+		admin.GET("/maintenance/windows", middleware.AuthMiddleware(), middleware.AdminMiddleware(), maintenanceHandler.ListWindows)
+		admin.POST("/maintenance/windows", middleware.AuthMiddleware(), middleware.AdminMiddleware(), maintenanceHandler.ScheduleWindow)
+		admin.DELETE("/maintenance/windows/:id", middleware.AuthMiddleware(), middleware.AdminMiddleware(), maintenanceHandler.CancelWindow)
 	}
 
 	r.NoRoute(func(c *gin.Context) {
@@ -450,11 +965,13 @@ http_request_duration_seconds %s
 		})
 	})
 	server := &http.Server{
-		Addr:         ":" + fmt.Sprintf("%d", cfg.Server.Port),
-		Handler:      r,
-		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout) * time.Second,
-		WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
-		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout) * time.Second,
+		Addr:              ":" + fmt.Sprintf("%d", cfg.Server.Port),
+		Handler:           r,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
 	}
 	go func() {
 		log.Printf("🚀 Server starting on port %d", cfg.Server.Port)
@@ -484,13 +1001,78 @@ http_request_duration_seconds %s
 	log.Println("Server exited")
 }
 
-func corsMiddleware() gin.HandlerFunc {
+func clientIPConfig(cfg config.ServerConfig) middleware.ClientIPConfig {
+	return middleware.ClientIPConfig{
+		TrustedProxies:  cfg.TrustedProxies,
+		RemoteIPHeaders: cfg.RemoteIPHeaders,
+		TrustedPlatform: cfg.TrustedPlatform,
+	}
+}
+
+func newPasswordHasher(cfg config.PasswordConfig) *utils.PasswordHasher {
+	return utils.NewPasswordHasher(utils.PasswordHashOptions{
+		Algorithm:     cfg.Algorithm,
+		BcryptCost:    cfg.BcryptCost,
+		Argon2Time:    uint32(cfg.Argon2Time),
+		Argon2Memory:  uint32(cfg.Argon2Memory),
+		Argon2Threads: uint8(cfg.Argon2Threads),
+	})
+}
+
+// paymentProviders builds the enabled payment providers; the config has
+// already checked that every name is known.
+func paymentProviders(cfg *config.AppConfig) *services.PaymentProviders {
+	var providers []services.PaymentProvider
+	for _, name := range cfg.Payments.Providers {
+		switch name {
+		case models.PaymentProviderStripe:
+			providers = append(providers, services.NewStripeProvider(cfg.Stripe.SecretKey, cfg.Stripe.WebhookSecret))
+		case models.PaymentProviderMock:
+			providers = append(providers, services.NewMockPaymentProvider(cfg.Payments.MockWebhookSecret))
+		}
+	}
+	return services.NewPaymentProviders(cfg.Payments.Provider, providers...)
+}
+
+// buildSecurityHeaders starts from the configured preset and applies any
+// per-header overrides on top of it.
+func buildSecurityHeaders(cfg config.SecurityConfig) (middleware.SecurityHeadersConfig, error) {
+	headers, err := middleware.SecurityHeadersPreset(cfg.HeadersPreset)
+	if err != nil {
+		return headers, err
+	}
+	if cfg.ContentSecurityPolicy != "" {
+		headers.ContentSecurityPolicy = cfg.ContentSecurityPolicy
+	}
+	if cfg.HSTSMaxAge > 0 {
+		headers.HSTSMaxAge = cfg.HSTSMaxAge
+	}
+	if cfg.DisableHSTS {
+		headers.HSTSMaxAge = 0
+	}
+	if cfg.FrameOptions != "" {
+		headers.FrameOptions = strings.ToUpper(cfg.FrameOptions)
+	}
+	if cfg.ReferrerPolicy != "" {
+		headers.ReferrerPolicy = cfg.ReferrerPolicy
+	}
+	if cfg.PermissionsPolicy != "" {
+		headers.PermissionsPolicy = cfg.PermissionsPolicy
+	}
+	return headers, nil
+}
+
+func corsMiddleware(maxAge time.Duration) gin.HandlerFunc {
+	maxAgeSeconds := strconv.FormatInt(int64(maxAge/time.Second), 10)
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization")
 
 		if c.Request.Method == "OPTIONS" {
+			if maxAge > 0 {
+				c.Header("Access-Control-Max-Age", maxAgeSeconds)
+			}
 			c.AbortWithStatus(204)
 			return
 		}
@@ -554,59 +1136,68 @@ func cacheHandler(c *gin.Context) {
 }
 
 func usersHandler(c *gin.Context) {
-	users := getUsers()
-	c.JSON(http.StatusOK, gin.H{"users": users})
+	page, pageSize, search := adminListParams(c)
+	users, total, err := getUsers(page, pageSize, search)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get users")
+		return
+	}
+	utils.PaginatedResponse(c, users, total, page, pageSize)
 }
 
 func productsHandler(c *gin.Context) {
-	products := getProducts()
-	c.JSON(http.StatusOK, gin.H{"products": products})
+	page, pageSize, search := adminListParams(c)
+	products, total, err := getProducts(page, pageSize, search)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get products")
+		return
+	}
+	utils.PaginatedResponse(c, products, total, page, pageSize)
 }
 
 func ordersHandler(c *gin.Context) {
-	orders := getOrders()
-	c.JSON(http.StatusOK, gin.H{"orders": orders})
-}
-
-func healthHandler(c *gin.Context) {
-	health := getHealthStatus()
-	c.JSON(http.StatusOK, health)
-}
-
-func seedHandler(c *gin.Context) {
-	seedType := c.Query("type")
-	if seedType == "" {
-		seedType = "all"
-	}
-
-	seedManager, err := seeds.NewSeedManager()
+	page, pageSize, search := adminListParams(c)
+	orders, total, err := getOrders(page, pageSize, search)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to create seed manager",
-			"details": err.Error(),
-		})
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get orders")
 		return
 	}
-	defer seedManager.Close()
+	utils.PaginatedResponse(c, orders, total, page, pageSize)
+}
 
-	if seedType == "all" {
-		err = seedManager.Run()
-	} else {
-		err = seedManager.RunSpecific([]string{seedType})
+// adminListParams reads the page, page_size and search query parameters
+// shared by the admin tables. page_size defaults to 20 and is capped at 100.
+func adminListParams(c *gin.Context) (int, int, string) {
+	page := utils.GetIntQuery(c, "page", 1)
+	if page < 1 {
+		page = 1
+	}
+	pageSize := utils.GetIntQuery(c, "page_size", 20)
+	if pageSize < 1 {
+		pageSize = 20
 	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+	return page, pageSize, strings.TrimSpace(c.Query("search"))
+}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to seed database",
-			"details": err.Error(),
-		})
-		return
+// adminSearchClause builds a WHERE clause matching search against columns
+// with ILIKE. It returns an empty clause and no args when search is empty.
+func adminSearchClause(search string, columns ...string) (string, []interface{}) {
+	if search == "" {
+		return "", nil
+	}
+	conditions := make([]string, len(columns))
+	for i, column := range columns {
+		conditions[i] = column + " ILIKE $1"
 	}
+	return "WHERE " + strings.Join(conditions, " OR "), []interface{}{"%" + search + "%"}
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Database seeded successfully",
-		"type":    seedType,
-	})
+func healthHandler(c *gin.Context) {
+	health := getHealthStatus()
+	c.JSON(http.StatusOK, health)
 }
 
 func migrateHandler(c *gin.Context) {
@@ -631,9 +1222,7 @@ func migrateHandler(c *gin.Context) {
 }
 
 func clearCacheHandler(c *gin.Context) {
-	utils.GetCacheStats()
-	globalCacheManager := utils.NewCacheManager()
-	globalCacheManager.ClearAll()
+	utils.ClearAllCaches()
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":   "Cache cleared successfully",
@@ -641,6 +1230,19 @@ func clearCacheHandler(c *gin.Context) {
 	})
 }
 
+func resetCacheStatsHandler(c *gin.Context) {
+	name := c.Query("name")
+	if !utils.ResetCacheStats(name) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cache not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Cache stats reset successfully",
+		"timestamp": time.Now().Unix(),
+	})
+}
+
 func clearLogsHandler(c *gin.Context) {
 	logFiles := []string{
 		"logs/backend/access.log",
@@ -769,26 +1371,38 @@ func getCacheStats() map[string]interface{} {
 	}
 
 	var totalSize, totalHits, totalMisses int
-	var totalHitRate, totalMissRate float64
+	perCache := make(map[string]interface{}, len(cacheStats))
 
-	for _, stats := range cacheStats {
+	for name, stats := range cacheStats {
 		totalSize += stats.Size
 		totalHits += int(stats.TotalHits)
 		totalMisses += int(stats.TotalMisses)
-		totalHitRate += stats.HitRate
-		totalMissRate += stats.MissRate
+		perCache[name] = map[string]interface{}{
+			"size":         stats.Size,
+			"max_size":     stats.MaxSize,
+			"ttl":          stats.TTL.String(),
+			"hit_rate":     fmt.Sprintf("%.1f%%", stats.HitRate),
+			"miss_rate":    fmt.Sprintf("%.1f%%", stats.MissRate),
+			"total_hits":   stats.TotalHits,
+			"total_misses": stats.TotalMisses,
+			"evictions":    stats.Evictions,
+		}
 	}
 
-	avgHitRate := totalHitRate / float64(len(cacheStats))
-	avgMissRate := totalMissRate / float64(len(cacheStats))
+	var hitRate, missRate float64
+	if total := totalHits + totalMisses; total > 0 {
+		hitRate = float64(totalHits) / float64(total) * 100
+		missRate = float64(totalMisses) / float64(total) * 100
+	}
 
 	return map[string]interface{}{
 		"size":         totalSize,
-		"hit_rate":     fmt.Sprintf("%.1f%%", avgHitRate),
-		"miss_rate":    fmt.Sprintf("%.1f%%", avgMissRate),
+		"hit_rate":     fmt.Sprintf("%.1f%%", hitRate),
+		"miss_rate":    fmt.Sprintf("%.1f%%", missRate),
 		"total_hits":   totalHits,
 		"total_misses": totalMisses,
 		"caches":       len(cacheStats),
+		"per_cache":    perCache,
 	}
 }
 
@@ -861,24 +1475,33 @@ func getMetrics() map[string]interface{} {
 	}
 }
 
-func getUsers() []map[string]interface{} {
+func getUsers(page, pageSize int, search string) ([]map[string]interface{}, int64, error) {
 	db := database.GetDB()
 	if db == nil {
-		return []map[string]interface{}{}
+		return []map[string]interface{}{}, 0, nil
 	}
 
-	rows, err := db.Query(`
+	where, args := adminSearchClause(search, "email", "name")
+
+	var total int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM users "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
 		SELECT id, email, name, role, created_at, updated_at 
 		FROM users 
+		%s
 		ORDER BY created_at DESC 
-		LIMIT 10
-	`)
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	rows, err := db.Query(query, append(args, pageSize, (page-1)*pageSize)...)
 	if err != nil {
-		return []map[string]interface{}{}
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var users []map[string]interface{}
+	users := []map[string]interface{}{}
 	for rows.Next() {
 		var user struct {
 			ID        string
@@ -890,7 +1513,7 @@ func getUsers() []map[string]interface{} {
 		}
 
 		if err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.CreatedAt, &user.UpdatedAt); err != nil {
-			continue
+			return nil, 0, err
 		}
 
 		users = append(users, map[string]interface{}{
@@ -903,33 +1526,42 @@ func getUsers() []map[string]interface{} {
 		})
 	}
 
-	return users
+	return users, total, rows.Err()
 }
 
-func getProducts() []map[string]interface{} {
+func getProducts(page, pageSize int, search string) ([]map[string]interface{}, int64, error) {
 	db := database.GetDB()
 	if db == nil {
-		return []map[string]interface{}{}
+		return []map[string]interface{}{}, 0, nil
+	}
+
+	where, args := adminSearchClause(search, "p.name", "p.slug")
+
+	var total int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM products p "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
 	}
 
-	rows, err := db.Query(`
+	query := fmt.Sprintf(`
 		SELECT p.id, p.name, p.price, p.stock, c.name as category, p.created_at, p.updated_at
 		FROM products p
 		LEFT JOIN categories c ON p.category_id = c.id
+		%s
 		ORDER BY p.created_at DESC 
-		LIMIT 10
-	`)
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	rows, err := db.Query(query, append(args, pageSize, (page-1)*pageSize)...)
 	if err != nil {
-		return []map[string]interface{}{}
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var products []map[string]interface{}
+	products := []map[string]interface{}{}
 	for rows.Next() {
 		var product struct {
 			ID        string
 			Name      string
-			Price     float64
+			Price     models.Money
 			Stock     int
 			Category  *string
 			CreatedAt time.Time
@@ -937,7 +1569,7 @@ func getProducts() []map[string]interface{} {
 		}
 
 		if err := rows.Scan(&product.ID, &product.Name, &product.Price, &product.Stock, &product.Category, &product.CreatedAt, &product.UpdatedAt); err != nil {
-			continue
+			return nil, 0, err
 		}
 
 		category := "Uncategorized"
@@ -956,40 +1588,49 @@ func getProducts() []map[string]interface{} {
 		})
 	}
 
-	return products
+	return products, total, rows.Err()
 }
 
-func getOrders() []map[string]interface{} {
+func getOrders(page, pageSize int, search string) ([]map[string]interface{}, int64, error) {
 	db := database.GetDB()
 	if db == nil {
-		return []map[string]interface{}{}
+		return []map[string]interface{}{}, 0, nil
+	}
+
+	where, args := adminSearchClause(search, "o.id::text", "o.status", "u.name", "u.email")
+
+	var total int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM orders o LEFT JOIN users u ON o.user_id = u.id "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
 	}
 
-	rows, err := db.Query(`
+	query := fmt.Sprintf(`
 		SELECT o.id, o.user_id, o.total, o.status, o.created_at, u.name as user_name
 		FROM orders o
 		LEFT JOIN users u ON o.user_id = u.id
+		%s
 		ORDER BY o.created_at DESC 
-		LIMIT 10
-	`)
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	rows, err := db.Query(query, append(args, pageSize, (page-1)*pageSize)...)
 	if err != nil {
-		return []map[string]interface{}{}
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var orders []map[string]interface{}
+	orders := []map[string]interface{}{}
 	for rows.Next() {
 		var order struct {
 			ID        string
 			UserID    string
-			Total     float64
+			Total     models.Money
 			Status    string
 			CreatedAt time.Time
 			UserName  *string
 		}
 
 		if err := rows.Scan(&order.ID, &order.UserID, &order.Total, &order.Status, &order.CreatedAt, &order.UserName); err != nil {
-			continue
+			return nil, 0, err
 		}
 
 		userName := "Unknown User"
@@ -1007,7 +1648,7 @@ func getOrders() []map[string]interface{} {
 		})
 	}
 
-	return orders
+	return orders, total, rows.Err()
 }
 
 func getHealthStatus() map[string]interface{} {
@@ -1046,7 +1687,7 @@ func runAutoInit(cfg *config.AppConfig, waitForDB bool, timeout time.Duration) {
 	runInit(cfg, waitForDB, timeout)
 
 	fmt.Println("\n🌱 Step 2: Seeding database with sample data...")
-	runSeed(cfg, "all")
+	runSeed(cfg, "all", seeds.Options{})
 
 	fmt.Println("\n🎨 Step 3: Generating placeholder images...")
 	runGenerateImages()
@@ -1220,8 +1861,10 @@ func showHelp() {
 	fmt.Println("Modes:")
 	fmt.Println("  -mode=server    Start the main API server (default)")
 	fmt.Println("  -mode=init      Initialize database and run migrations")
+	fmt.Println("  -mode=migrate   Apply pending migrations")
 	fmt.Println("  -mode=seed      Seed database with sample data")
 	fmt.Println("  -mode=admin     Start admin panel")
+	fmt.Println("  -mode=create-admin  Create the first admin account if none exists")
 	fmt.Println("  -mode=generate-images  Generate placeholder images")
 	fmt.Println("  -mode=auto-init Full project initialization (init + seed + images)")
 	fmt.Println()
@@ -1232,6 +1875,21 @@ func showHelp() {
 	fmt.Println("        Timeout for database connection (default: 30s)")
 	fmt.Println("  -type string")
 	fmt.Println("        Seed type: all, categories, products, users, orders, reviews (default: all)")
+	fmt.Println("  -count int")
+	fmt.Println("        With -mode=seed, generate this many products, users and orders instead of the")
+	fmt.Println("        sample data; -count-products, -count-users and -count-orders set them per type")
+	fmt.Println("  -seed int")
+	fmt.Println("        With -mode=seed, random seed for generated data; the same seed reproduces a run")
+	fmt.Println("  -batch-size int")
+	fmt.Println("        With -mode=seed, rows per insert statement for generated data (default: 500)")
+	fmt.Println("  -dry-run")
+	fmt.Println("        With -mode=migrate, print pending migrations and their SQL without applying them;")
+	fmt.Println("        exits with status 1 if any are pending")
+	fmt.Println("  -email string")
+	fmt.Println("        With -mode=create-admin, the admin's email (prompted for when omitted); the")
+	fmt.Println("        password is read from ADMIN_PASSWORD or prompted for")
+	fmt.Println("  -name string")
+	fmt.Println("        With -mode=create-admin, the admin's display name")
 	fmt.Println("  -help")
 	fmt.Println("        Show this help message")
 }
@@ -3,30 +3,103 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+
+	"ecommerce-backend/internal/utils"
 )
 
 type AppConfig struct {
-	Server   ServerConfig   `json:"server"`
-	Database DatabaseConfig `json:"database"`
-	Redis    RedisConfig    `json:"redis"`
-	JWT      JWTConfig      `json:"jwt"`
-	Stripe   StripeConfig   `json:"stripe"`
-	Logging  LoggingConfig  `json:"logging"`
-	Cache    CacheConfig    `json:"cache"`
-	Metrics  MetricsConfig  `json:"metrics"`
+	Server      ServerConfig      `json:"server"`
+	Database    DatabaseConfig    `json:"database"`
+	Redis       RedisConfig       `json:"redis"`
+	JWT         JWTConfig         `json:"jwt"`
+	Password    PasswordConfig    `json:"password"`
+	Register    RegisterConfig    `json:"register"`
+	Stripe      StripeConfig      `json:"stripe"`
+	Payments    PaymentsConfig    `json:"payments"`
+	Logging     LoggingConfig     `json:"logging"`
+	Cache       CacheConfig       `json:"cache"`
+	Metrics     MetricsConfig     `json:"metrics"`
+	Maintenance MaintenanceConfig `json:"maintenance"`
+	Security    SecurityConfig    `json:"security"`
+	Catalog     CatalogConfig     `json:"catalog"`
+	Orders      OrdersConfig      `json:"orders"`
+	Cart        CartConfig        `json:"cart"`
+	Reviews     ReviewsConfig     `json:"reviews"`
+	Uploads     UploadsConfig     `json:"uploads"`
+	Email       EmailConfig       `json:"email"`
+	Jobs        JobsConfig        `json:"jobs"`
+	WebSocket   WebSocketConfig   `json:"websocket"`
+	Stores      StoresConfig      `json:"stores"`
 }
 
 type ServerConfig struct {
-	Host         string        `json:"host"`
-	Port         int           `json:"port"`
-	ReadTimeout  time.Duration `json:"read_timeout"`
-	WriteTimeout time.Duration `json:"write_timeout"`
-	IdleTimeout  time.Duration `json:"idle_timeout"`
-	Environment  string        `json:"environment"`
+	Host              string        `json:"host"`
+	Port              int           `json:"port"`
+	ReadTimeout       time.Duration `json:"read_timeout"`
+	ReadHeaderTimeout time.Duration `json:"read_header_timeout"`
+	WriteTimeout      time.Duration `json:"write_timeout"`
+	IdleTimeout       time.Duration `json:"idle_timeout"`
+	MaxHeaderBytes    int           `json:"max_header_bytes"`
+	MaxBodyBytes      int64         `json:"max_body_bytes"`
+	MaxUploadBytes    int64         `json:"max_upload_bytes"`
+	Environment       string        `json:"environment"`
+	PublicURL         string        `json:"public_url"`
+	SiteURL           string        `json:"site_url"`
+	// TrustedProxies are the IPs or CIDRs whose X-Forwarded-For and
+	// X-Real-IP headers are believed. Empty trusts none.
+	TrustedProxies  []string `json:"trusted_proxies"`
+	RemoteIPHeaders []string `json:"remote_ip_headers"`
+	TrustedPlatform string   `json:"trusted_platform"`
+	// RateLimitBypassPaths are exempt from the global rate limit, along with
+	// everything below them.
+	RateLimitBypassPaths []string `json:"rate_limit_bypass_paths"`
+	// RequestIDHeader carries the request ID; a valid incoming value is
+	// kept so a trace can start upstream.
+	RequestIDHeader string `json:"request_id_header"`
+}
+
+// UnmarshalJSON accepts timeouts either as integer seconds (15) or as
+// duration strings ("15s").
+func (s *ServerConfig) UnmarshalJSON(data []byte) error {
+	type plain ServerConfig
+	aux := struct {
+		*plain
+		ReadTimeout       json.RawMessage `json:"read_timeout"`
+		ReadHeaderTimeout json.RawMessage `json:"read_header_timeout"`
+		WriteTimeout      json.RawMessage `json:"write_timeout"`
+		IdleTimeout       json.RawMessage `json:"idle_timeout"`
+	}{plain: (*plain)(s)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	fields := []struct {
+		name  string
+		raw   json.RawMessage
+		value *time.Duration
+	}{
+		{"read_timeout", aux.ReadTimeout, &s.ReadTimeout},
+		{"read_header_timeout", aux.ReadHeaderTimeout, &s.ReadHeaderTimeout},
+		{"write_timeout", aux.WriteTimeout, &s.WriteTimeout},
+		{"idle_timeout", aux.IdleTimeout, &s.IdleTimeout},
+	}
+	for _, field := range fields {
+		if len(field.raw) == 0 {
+			continue
+		}
+		value := strings.Trim(string(field.raw), `"`)
+		duration, err := parseTimeout(value)
+		if err != nil {
+			return fmt.Errorf("invalid server.%s %s: %w", field.name, field.raw, err)
+		}
+		*field.value = duration
+	}
+	return nil
 }
 
 type DatabaseConfig struct {
@@ -41,6 +114,13 @@ type DatabaseConfig struct {
 	MaxIdleConns    int           `json:"max_idle_conns"`
 	ConnMaxLifetime time.Duration `json:"conn_max_lifetime"`
 	ConnMaxIdleTime time.Duration `json:"conn_max_idle_time"`
+	// Reads that fail to reach the database are retried ReadRetries times,
+	// starting at RetryBackoff. BreakerThreshold consecutive failures open
+	// the circuit breaker for BreakerCooldown.
+	ReadRetries      int           `json:"read_retries"`
+	RetryBackoff     time.Duration `json:"retry_backoff"`
+	BreakerThreshold int           `json:"breaker_threshold"`
+	BreakerCooldown  time.Duration `json:"breaker_cooldown"`
 }
 
 type RedisConfig struct {
@@ -56,6 +136,33 @@ type JWTConfig struct {
 	RefreshIn time.Duration `json:"refresh_in"`
 	Issuer    string        `json:"issuer"`
 	Audience  string        `json:"audience"`
+	// ImpersonationTTL is how long a support impersonation token lasts.
+	ImpersonationTTL time.Duration `json:"impersonation_ttl"`
+}
+
+type PasswordConfig struct {
+	Algorithm     string `json:"algorithm"`
+	BcryptCost    int    `json:"bcrypt_cost"`
+	Argon2Time    int    `json:"argon2_time"`
+	Argon2Memory  int    `json:"argon2_memory"`
+	Argon2Threads int    `json:"argon2_threads"`
+}
+
+type RegisterConfig struct {
+	CaptchaEnabled  bool          `json:"captcha_enabled"`
+	CaptchaProvider string        `json:"captcha_provider"`
+	CaptchaSecret   string        `json:"captcha_secret"`
+	RateLimit       int           `json:"rate_limit"`
+	RateWindow      time.Duration `json:"rate_window"`
+	// EmailDomainCheckDisabled turns the blocked-domain check off, e.g. for
+	// internal environments that register throwaway test accounts.
+	EmailDomainCheckDisabled bool     `json:"email_domain_check_disabled"`
+	BlockedEmailDomains      []string `json:"blocked_email_domains"`
+	// BlockedEmailDomainsFile lists one domain per line and is re-read every
+	// BlockedEmailDomainsReload when it changes.
+	BlockedEmailDomainsFile   string        `json:"blocked_email_domains_file"`
+	BlockedEmailDomainsReload time.Duration `json:"blocked_email_domains_reload"`
+	BlockDisposableEmails     bool          `json:"block_disposable_emails"`
 }
 
 type StripeConfig struct {
@@ -64,6 +171,19 @@ type StripeConfig struct {
 	PublishableKey string `json:"publishable_key"`
 }
 
+// PaymentsConfig selects the payment providers. Provider is used when a
+// request does not name one; Providers lists every provider clients may
+// pick. The mock provider is an in-memory gateway for development and
+// tests and is refused in production. TestMode routes every payment
+// through a sandbox that never reaches a real gateway; it is also refused in
+// production.
+type PaymentsConfig struct {
+	Provider          string   `json:"provider"`
+	Providers         []string `json:"providers"`
+	MockWebhookSecret string   `json:"mock_webhook_secret"`
+	TestMode          bool     `json:"test_mode"`
+}
+
 type LoggingConfig struct {
 	Level      string `json:"level"`
 	Format     string `json:"format"`
@@ -73,12 +193,26 @@ type LoggingConfig struct {
 	MaxBackups int    `json:"max_backups"`
 	MaxAge     int    `json:"max_age"`
 	Compress   bool   `json:"compress"`
+
+	DebugBodies       bool     `json:"debug_bodies"`
+	DebugBodyRoutes   []string `json:"debug_body_routes"`
+	DebugBodyMaxBytes int      `json:"debug_body_max_bytes"`
 }
 
 type CacheConfig struct {
-	DefaultTTL      time.Duration `json:"default_ttl"`
-	MaxSize         int           `json:"max_size"`
-	CleanupInterval time.Duration `json:"cleanup_interval"`
+	DefaultTTL      time.Duration               `json:"default_ttl"`
+	MaxSize         int                         `json:"max_size"`
+	CleanupInterval time.Duration               `json:"cleanup_interval"`
+	Caches          map[string]NamedCacheConfig `json:"caches"`
+	// Warmup fills the hot read caches on start; readiness fails until it
+	// finishes or WarmupTimeout passes.
+	Warmup        bool          `json:"warmup"`
+	WarmupTimeout time.Duration `json:"warmup_timeout"`
+}
+
+type NamedCacheConfig struct {
+	TTL     time.Duration `json:"ttl"`
+	MaxSize int           `json:"max_size"`
 }
 
 type MetricsConfig struct {
@@ -88,6 +222,175 @@ type MetricsConfig struct {
 	Namespace string `json:"namespace"`
 }
 
+type MaintenanceConfig struct {
+	Enabled      bool          `json:"enabled"`
+	Message      string        `json:"message"`
+	RetryAfter   time.Duration `json:"retry_after"`
+	AllowedPaths []string      `json:"allowed_paths"`
+
+	// CheckInterval is how often scheduled maintenance windows are checked;
+	// AlertLeadTimes are how long before a window's start countdown alerts
+	// are broadcast.
+	CheckInterval  time.Duration   `json:"check_interval"`
+	AlertLeadTimes []time.Duration `json:"alert_lead_times"`
+}
+
+// SecurityConfig picks a security headers preset ("default" or "strict")
+// and optionally overrides individual headers from it.
+type SecurityConfig struct {
+	HeadersPreset         string        `json:"headers_preset"`
+	ContentSecurityPolicy string        `json:"content_security_policy"`
+	HSTSMaxAge            time.Duration `json:"hsts_max_age"`
+	DisableHSTS           bool          `json:"disable_hsts"`
+	FrameOptions          string        `json:"frame_options"`
+	ReferrerPolicy        string        `json:"referrer_policy"`
+	PermissionsPolicy     string        `json:"permissions_policy"`
+	CORSMaxAge            time.Duration `json:"cors_max_age"`
+}
+
+type CatalogConfig struct {
+	DefaultSort      string   `json:"default_sort"`
+	DefaultSortOrder string   `json:"default_sort_order"`
+	DefaultPageSize  int      `json:"default_page_size"`
+	MaxPageSize      int      `json:"max_page_size"`
+	Currency         string   `json:"currency"`
+	StoreName        string   `json:"store_name"`
+	DefaultLocale    string   `json:"default_locale"`
+	Locales          []string `json:"locales"`
+	// NewArrivalsDays is how far back GET /api/products/new looks by default.
+	NewArrivalsDays int `json:"new_arrivals_days"`
+	// PriceFacetBounds are the ascending boundaries of the price facet
+	// buckets on the product listing.
+	PriceFacetBounds []float64 `json:"price_facet_bounds"`
+}
+
+type OrdersConfig struct {
+	ReturnWindowDays      int           `json:"return_window_days"`
+	MinOrderAmount        float64       `json:"min_order_amount"`
+	FreeShippingThreshold float64       `json:"free_shipping_threshold"`
+	ShippingFlatRate      float64       `json:"shipping_flat_rate"`
+	GiftWrapFee           float64       `json:"gift_wrap_fee"`
+	OriginCountry         string        `json:"origin_country"`
+	ExpressSurcharge      float64       `json:"express_surcharge"`
+	InternationalRate     float64       `json:"international_rate"`
+	RemoteAreaSurcharge   float64       `json:"remote_area_surcharge"`
+	DownloadLimit         int           `json:"download_limit"`
+	DownloadTTL           time.Duration `json:"download_ttl"`
+	// ConfirmationResendLimit caps how often the confirmation email of one
+	// order can be re-sent within ConfirmationResendWindow.
+	ConfirmationResendLimit  int           `json:"confirmation_resend_limit"`
+	ConfirmationResendWindow time.Duration `json:"confirmation_resend_window"`
+	// TaxRate applies to the subtotal. When PricesIncludeTax is set, prices
+	// are gross and the tax is extracted from them rather than added on top.
+	// TaxRounding is half_up or half_even, for tax that falls between cents.
+	TaxRate          float64 `json:"tax_rate"`
+	PricesIncludeTax bool    `json:"prices_include_tax"`
+	TaxRounding      string  `json:"tax_rounding"`
+	// UnpaidTimeout cancels orders still pending without a payment this long
+	// after they were placed, checked every UnpaidCheckInterval. Zero keeps
+	// them pending.
+	UnpaidTimeout       time.Duration `json:"unpaid_timeout"`
+	UnpaidCheckInterval time.Duration `json:"unpaid_check_interval"`
+}
+
+// CartConfig controls the background sweep that drops cart lines for
+// discontinued products and, when ItemMaxAge is set, lines left untouched
+// for longer than that.
+type CartConfig struct {
+	SweepInterval time.Duration `json:"sweep_interval"`
+	ItemMaxAge    time.Duration `json:"item_max_age"`
+}
+
+// ReviewsConfig bounds the length of review comments, counted in characters
+// after surrounding whitespace is trimmed. With UpsertDuplicates a second
+// review of the same product updates the first instead of failing with 409.
+type ReviewsConfig struct {
+	CommentMinLength int  `json:"comment_min_length"`
+	CommentMaxLength int  `json:"comment_max_length"`
+	UpsertDuplicates bool `json:"upsert_duplicates"`
+}
+
+// UploadsConfig holds per-user storage quotas and the settings for signed
+// URLs to private uploads. SigningSecret defaults to the JWT secret.
+type UploadsConfig struct {
+	UserQuotaMB   int           `json:"user_quota_mb"`
+	AdminQuotaMB  int           `json:"admin_quota_mb"`
+	SigningSecret string        `json:"signing_secret"`
+	SignedURLTTL  time.Duration `json:"signed_url_ttl"`
+
+	// Product image uploads must be at least ProductMinWidth x
+	// ProductMinHeight pixels and, when ProductAspectRatio is set (e.g.
+	// "1:1" or "4:3"), within ProductAspectTolerance of that ratio.
+	ProductMinWidth        int     `json:"product_min_width"`
+	ProductMinHeight       int     `json:"product_min_height"`
+	ProductAspectRatio     string  `json:"product_aspect_ratio"`
+	ProductAspectTolerance float64 `json:"product_aspect_tolerance"`
+}
+
+// ProductAspect returns ProductAspectRatio as width divided by height, or 0
+// when any ratio is allowed.
+func (u UploadsConfig) ProductAspect() float64 {
+	ratio, _ := parseAspectRatio(u.ProductAspectRatio)
+	return ratio
+}
+
+func parseAspectRatio(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid aspect ratio %q, expected width:height", value)
+	}
+	width, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || width <= 0 {
+		return 0, fmt.Errorf("invalid aspect ratio %q, expected width:height", value)
+	}
+	height, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || height <= 0 {
+		return 0, fmt.Errorf("invalid aspect ratio %q, expected width:height", value)
+	}
+	return width / height, nil
+}
+
+type EmailConfig struct {
+	SMTPHost string `json:"smtp_host"`
+	SMTPPort int    `json:"smtp_port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	From     string `json:"from"`
+}
+
+type JobsConfig struct {
+	Workers                 int           `json:"workers"`
+	MaxAttempts             int           `json:"max_attempts"`
+	RecommendationsInterval time.Duration `json:"recommendations_interval"`
+}
+
+// WebSocketConfig tunes the hub. PongWait must exceed PingInterval so a
+// healthy client always answers a ping before its read deadline; keep
+// PingInterval below any load balancer idle timeout.
+type WebSocketConfig struct {
+	BroadcastWorkers int           `json:"broadcast_workers"`
+	BroadcastBuffer  int           `json:"broadcast_buffer"`
+	PingInterval     time.Duration `json:"ping_interval"`
+	PongWait         time.Duration `json:"pong_wait"`
+	WriteWait        time.Duration `json:"write_wait"`
+	// MaxConnections caps the sockets held by the hub; MaxConnectionsPerUser
+	// caps those of one signed-in user. Connections over either are closed.
+	MaxConnections        int `json:"max_connections"`
+	MaxConnectionsPerUser int `json:"max_connections_per_user"`
+}
+
+// StoresConfig controls how a request is mapped to a store. A JWT claim
+// wins over the header, which wins over the host; requests matching none
+// use the default store. An empty BaseDomain disables subdomain lookup.
+type StoresConfig struct {
+	Header     string `json:"header"`
+	JWTClaim   string `json:"jwt_claim"`
+	BaseDomain string `json:"base_domain"`
+}
+
 var globalConfig *AppConfig
 
 func LoadConfig(configPath string) (*AppConfig, error) {
@@ -101,6 +404,9 @@ func LoadConfig(configPath string) (*AppConfig, error) {
 
 	loadFromEnv(config)
 	setDefaults(config)
+	if err := validate(config); err != nil {
+		return nil, err
+	}
 
 	globalConfig = config
 	return config, nil
@@ -127,6 +433,20 @@ func loadFromEnv(config *AppConfig) {
 	config.Server.Host = getEnv("SERVER_HOST", config.Server.Host)
 	config.Server.Port = getEnvAsInt("SERVER_PORT", config.Server.Port)
 	config.Server.Environment = getEnv("ENVIRONMENT", config.Server.Environment)
+	config.Server.PublicURL = getEnv("PUBLIC_URL", config.Server.PublicURL)
+	config.Server.SiteURL = getEnv("SITE_URL", getEnv("FRONTEND_URL", config.Server.SiteURL))
+	config.Server.ReadTimeout = getEnvAsTimeout("SERVER_READ_TIMEOUT", config.Server.ReadTimeout)
+	config.Server.ReadHeaderTimeout = getEnvAsTimeout("SERVER_READ_HEADER_TIMEOUT", config.Server.ReadHeaderTimeout)
+	config.Server.WriteTimeout = getEnvAsTimeout("SERVER_WRITE_TIMEOUT", config.Server.WriteTimeout)
+	config.Server.IdleTimeout = getEnvAsTimeout("SERVER_IDLE_TIMEOUT", config.Server.IdleTimeout)
+	config.Server.MaxHeaderBytes = getEnvAsInt("SERVER_MAX_HEADER_BYTES", config.Server.MaxHeaderBytes)
+	config.Server.MaxBodyBytes = int64(getEnvAsInt("MAX_BODY_BYTES", int(config.Server.MaxBodyBytes)))
+	config.Server.MaxUploadBytes = int64(getEnvAsInt("MAX_UPLOAD_BYTES", int(config.Server.MaxUploadBytes)))
+	config.Server.TrustedProxies = getEnvAsSlice("TRUSTED_PROXIES", config.Server.TrustedProxies)
+	config.Server.RemoteIPHeaders = getEnvAsSlice("REMOTE_IP_HEADERS", config.Server.RemoteIPHeaders)
+	config.Server.TrustedPlatform = getEnv("TRUSTED_PLATFORM", config.Server.TrustedPlatform)
+	config.Server.RateLimitBypassPaths = getEnvAsSlice("RATE_LIMIT_BYPASS_PATHS", config.Server.RateLimitBypassPaths)
+	config.Server.RequestIDHeader = getEnv("REQUEST_ID_HEADER", config.Server.RequestIDHeader)
 
 	config.Database.Driver = getEnv("DB_DRIVER", config.Database.Driver)
 	config.Database.Host = getEnv("DB_HOST", config.Database.Host)
@@ -137,6 +457,10 @@ func loadFromEnv(config *AppConfig) {
 	config.Database.SSLMode = getEnv("DB_SSLMODE", config.Database.SSLMode)
 	config.Database.MaxOpenConns = getEnvAsInt("DB_MAX_OPEN_CONNS", config.Database.MaxOpenConns)
 	config.Database.MaxIdleConns = getEnvAsInt("DB_MAX_IDLE_CONNS", config.Database.MaxIdleConns)
+	config.Database.ReadRetries = getEnvAsInt("DB_READ_RETRIES", config.Database.ReadRetries)
+	config.Database.RetryBackoff = getEnvAsDuration("DB_RETRY_BACKOFF", config.Database.RetryBackoff)
+	config.Database.BreakerThreshold = getEnvAsInt("DB_BREAKER_THRESHOLD", config.Database.BreakerThreshold)
+	config.Database.BreakerCooldown = getEnvAsDuration("DB_BREAKER_COOLDOWN", config.Database.BreakerCooldown)
 
 	config.Redis.Host = getEnv("REDIS_HOST", config.Redis.Host)
 	config.Redis.Port = getEnvAsInt("REDIS_PORT", config.Redis.Port)
@@ -148,10 +472,32 @@ func loadFromEnv(config *AppConfig) {
 	config.JWT.RefreshIn = getEnvAsDuration("JWT_REFRESH_IN", config.JWT.RefreshIn)
 	config.JWT.Issuer = getEnv("JWT_ISSUER", config.JWT.Issuer)
 	config.JWT.Audience = getEnv("JWT_AUDIENCE", config.JWT.Audience)
+	config.JWT.ImpersonationTTL = getEnvAsDuration("JWT_IMPERSONATION_TTL", config.JWT.ImpersonationTTL)
+
+	config.Password.Algorithm = getEnv("PASSWORD_HASH_ALGORITHM", config.Password.Algorithm)
+	config.Password.BcryptCost = getEnvAsInt("BCRYPT_COST", config.Password.BcryptCost)
+	config.Password.Argon2Time = getEnvAsInt("ARGON2_TIME", config.Password.Argon2Time)
+	config.Password.Argon2Memory = getEnvAsInt("ARGON2_MEMORY_KB", config.Password.Argon2Memory)
+	config.Password.Argon2Threads = getEnvAsInt("ARGON2_THREADS", config.Password.Argon2Threads)
+
+	config.Register.CaptchaEnabled = getEnvAsBool("REGISTER_CAPTCHA_ENABLED", config.Register.CaptchaEnabled)
+	config.Register.CaptchaProvider = getEnv("CAPTCHA_PROVIDER", config.Register.CaptchaProvider)
+	config.Register.CaptchaSecret = getEnv("CAPTCHA_SECRET", config.Register.CaptchaSecret)
+	config.Register.RateLimit = getEnvAsInt("REGISTER_RATE_LIMIT", config.Register.RateLimit)
+	config.Register.RateWindow = getEnvAsDuration("REGISTER_RATE_WINDOW", config.Register.RateWindow)
+	config.Register.EmailDomainCheckDisabled = getEnvAsBool("REGISTER_EMAIL_DOMAIN_CHECK_DISABLED", config.Register.EmailDomainCheckDisabled)
+	config.Register.BlockedEmailDomains = getEnvAsSlice("REGISTER_BLOCKED_EMAIL_DOMAINS", config.Register.BlockedEmailDomains)
+	config.Register.BlockedEmailDomainsFile = getEnv("REGISTER_BLOCKED_EMAIL_DOMAINS_FILE", config.Register.BlockedEmailDomainsFile)
+	config.Register.BlockedEmailDomainsReload = getEnvAsDuration("REGISTER_BLOCKED_EMAIL_DOMAINS_RELOAD", config.Register.BlockedEmailDomainsReload)
+	config.Register.BlockDisposableEmails = getEnvAsBool("REGISTER_BLOCK_DISPOSABLE_EMAILS", config.Register.BlockDisposableEmails)
 
 	config.Stripe.SecretKey = getEnv("STRIPE_SECRET_KEY", config.Stripe.SecretKey)
 	config.Stripe.WebhookSecret = getEnv("STRIPE_WEBHOOK_SECRET", config.Stripe.WebhookSecret)
 	config.Stripe.PublishableKey = getEnv("STRIPE_PUBLISHABLE_KEY", config.Stripe.PublishableKey)
+	config.Payments.Provider = getEnv("PAYMENT_PROVIDER", config.Payments.Provider)
+	config.Payments.Providers = getEnvAsSlice("PAYMENT_PROVIDERS", config.Payments.Providers)
+	config.Payments.MockWebhookSecret = getEnv("PAYMENT_MOCK_WEBHOOK_SECRET", config.Payments.MockWebhookSecret)
+	config.Payments.TestMode = getEnvAsBool("PAYMENT_TEST_MODE", config.Payments.TestMode)
 
 	config.Logging.Level = getEnv("LOG_LEVEL", config.Logging.Level)
 	config.Logging.Format = getEnv("LOG_FORMAT", config.Logging.Format)
@@ -161,15 +507,116 @@ func loadFromEnv(config *AppConfig) {
 	config.Logging.MaxBackups = getEnvAsInt("LOG_MAX_BACKUPS", config.Logging.MaxBackups)
 	config.Logging.MaxAge = getEnvAsInt("LOG_MAX_AGE", config.Logging.MaxAge)
 	config.Logging.Compress = getEnvAsBool("LOG_COMPRESS", config.Logging.Compress)
+	config.Logging.DebugBodies = getEnvAsBool("LOG_DEBUG_BODIES", config.Logging.DebugBodies)
+	config.Logging.DebugBodyRoutes = getEnvAsSlice("LOG_DEBUG_BODY_ROUTES", config.Logging.DebugBodyRoutes)
+	config.Logging.DebugBodyMaxBytes = getEnvAsInt("LOG_DEBUG_BODY_MAX_BYTES", config.Logging.DebugBodyMaxBytes)
 
 	config.Cache.DefaultTTL = getEnvAsDuration("CACHE_DEFAULT_TTL", config.Cache.DefaultTTL)
 	config.Cache.MaxSize = getEnvAsInt("CACHE_MAX_SIZE", config.Cache.MaxSize)
 	config.Cache.CleanupInterval = getEnvAsDuration("CACHE_CLEANUP_INTERVAL", config.Cache.CleanupInterval)
+	config.Cache.Warmup = getEnvAsBool("CACHE_WARMUP", config.Cache.Warmup)
+	config.Cache.WarmupTimeout = getEnvAsDuration("CACHE_WARMUP_TIMEOUT", config.Cache.WarmupTimeout)
+	for _, name := range []string{"products", "search", "shipping", "http"} {
+		named := config.Cache.Caches[name]
+		prefix := "CACHE_" + strings.ToUpper(name)
+		named.TTL = getEnvAsDuration(prefix+"_TTL", named.TTL)
+		named.MaxSize = getEnvAsInt(prefix+"_MAX_SIZE", named.MaxSize)
+		if named.TTL != 0 || named.MaxSize != 0 {
+			if config.Cache.Caches == nil {
+				config.Cache.Caches = make(map[string]NamedCacheConfig)
+			}
+			config.Cache.Caches[name] = named
+		}
+	}
 
 	config.Metrics.Enabled = getEnvAsBool("METRICS_ENABLED", config.Metrics.Enabled)
 	config.Metrics.Port = getEnvAsInt("METRICS_PORT", config.Metrics.Port)
 	config.Metrics.Path = getEnv("METRICS_PATH", config.Metrics.Path)
 	config.Metrics.Namespace = getEnv("METRICS_NAMESPACE", config.Metrics.Namespace)
+
+	config.Maintenance.Enabled = getEnvAsBool("MAINTENANCE_MODE", config.Maintenance.Enabled)
+	config.Maintenance.Message = getEnv("MAINTENANCE_MESSAGE", config.Maintenance.Message)
+	config.Maintenance.RetryAfter = getEnvAsDuration("MAINTENANCE_RETRY_AFTER", config.Maintenance.RetryAfter)
+	config.Maintenance.AllowedPaths = getEnvAsSlice("MAINTENANCE_ALLOWED_PATHS", config.Maintenance.AllowedPaths)
+	config.Maintenance.CheckInterval = getEnvAsDuration("MAINTENANCE_CHECK_INTERVAL", config.Maintenance.CheckInterval)
+	config.Maintenance.AlertLeadTimes = getEnvAsDurationSlice("MAINTENANCE_ALERT_LEAD_TIMES", config.Maintenance.AlertLeadTimes)
+
+	config.Security.HeadersPreset = getEnv("SECURITY_HEADERS_PRESET", config.Security.HeadersPreset)
+	config.Security.ContentSecurityPolicy = getEnv("SECURITY_CSP", config.Security.ContentSecurityPolicy)
+	config.Security.HSTSMaxAge = getEnvAsDuration("SECURITY_HSTS_MAX_AGE", config.Security.HSTSMaxAge)
+	config.Security.DisableHSTS = getEnvAsBool("SECURITY_HSTS_DISABLED", config.Security.DisableHSTS)
+	config.Security.FrameOptions = getEnv("SECURITY_FRAME_OPTIONS", config.Security.FrameOptions)
+	config.Security.ReferrerPolicy = getEnv("SECURITY_REFERRER_POLICY", config.Security.ReferrerPolicy)
+	config.Security.PermissionsPolicy = getEnv("SECURITY_PERMISSIONS_POLICY", config.Security.PermissionsPolicy)
+	config.Security.CORSMaxAge = getEnvAsDuration("CORS_MAX_AGE", config.Security.CORSMaxAge)
+
+	config.Catalog.DefaultSort = getEnv("CATALOG_DEFAULT_SORT", config.Catalog.DefaultSort)
+	config.Catalog.DefaultSortOrder = getEnv("CATALOG_DEFAULT_SORT_ORDER", config.Catalog.DefaultSortOrder)
+	config.Catalog.DefaultPageSize = getEnvAsInt("CATALOG_DEFAULT_PAGE_SIZE", config.Catalog.DefaultPageSize)
+	config.Catalog.MaxPageSize = getEnvAsInt("CATALOG_MAX_PAGE_SIZE", config.Catalog.MaxPageSize)
+	config.Catalog.Currency = getEnv("CATALOG_CURRENCY", config.Catalog.Currency)
+	config.Catalog.StoreName = getEnv("STORE_NAME", config.Catalog.StoreName)
+	config.Catalog.DefaultLocale = getEnv("CATALOG_DEFAULT_LOCALE", config.Catalog.DefaultLocale)
+	config.Catalog.Locales = getEnvAsSlice("CATALOG_LOCALES", config.Catalog.Locales)
+	config.Catalog.NewArrivalsDays = getEnvAsInt("CATALOG_NEW_ARRIVALS_DAYS", config.Catalog.NewArrivalsDays)
+	config.Catalog.PriceFacetBounds = getEnvAsFloatSlice("CATALOG_PRICE_FACET_BOUNDS", config.Catalog.PriceFacetBounds)
+
+	config.Orders.ReturnWindowDays = getEnvAsInt("RETURN_WINDOW_DAYS", config.Orders.ReturnWindowDays)
+	config.Orders.MinOrderAmount = getEnvAsFloat("ORDER_MIN_AMOUNT", config.Orders.MinOrderAmount)
+	config.Orders.FreeShippingThreshold = getEnvAsFloat("ORDER_FREE_SHIPPING_THRESHOLD", config.Orders.FreeShippingThreshold)
+	config.Orders.ShippingFlatRate = getEnvAsFloat("ORDER_SHIPPING_FLAT_RATE", config.Orders.ShippingFlatRate)
+	config.Orders.GiftWrapFee = getEnvAsFloat("ORDER_GIFT_WRAP_FEE", config.Orders.GiftWrapFee)
+	config.Orders.TaxRate = getEnvAsFloat("ORDER_TAX_RATE", config.Orders.TaxRate)
+	config.Orders.PricesIncludeTax = getEnvAsBool("PRICES_INCLUDE_TAX", config.Orders.PricesIncludeTax)
+	config.Orders.TaxRounding = getEnv("ORDER_TAX_ROUNDING", config.Orders.TaxRounding)
+	config.Orders.OriginCountry = getEnv("SHIPPING_ORIGIN_COUNTRY", config.Orders.OriginCountry)
+	config.Orders.ExpressSurcharge = getEnvAsFloat("SHIPPING_EXPRESS_SURCHARGE", config.Orders.ExpressSurcharge)
+	config.Orders.InternationalRate = getEnvAsFloat("SHIPPING_INTERNATIONAL_RATE", config.Orders.InternationalRate)
+	config.Orders.RemoteAreaSurcharge = getEnvAsFloat("SHIPPING_REMOTE_AREA_SURCHARGE", config.Orders.RemoteAreaSurcharge)
+	config.Orders.DownloadLimit = getEnvAsInt("ORDER_DOWNLOAD_LIMIT", config.Orders.DownloadLimit)
+	config.Orders.DownloadTTL = getEnvAsDuration("ORDER_DOWNLOAD_TTL", config.Orders.DownloadTTL)
+	config.Orders.ConfirmationResendLimit = getEnvAsInt("ORDER_CONFIRMATION_RESEND_LIMIT", config.Orders.ConfirmationResendLimit)
+	config.Orders.ConfirmationResendWindow = getEnvAsDuration("ORDER_CONFIRMATION_RESEND_WINDOW", config.Orders.ConfirmationResendWindow)
+	config.Orders.UnpaidTimeout = getEnvAsDuration("ORDER_UNPAID_TIMEOUT", config.Orders.UnpaidTimeout)
+	config.Orders.UnpaidCheckInterval = getEnvAsDuration("ORDER_UNPAID_CHECK_INTERVAL", config.Orders.UnpaidCheckInterval)
+
+	config.Uploads.UserQuotaMB = getEnvAsInt("UPLOAD_QUOTA_MB", config.Uploads.UserQuotaMB)
+	config.Uploads.AdminQuotaMB = getEnvAsInt("UPLOAD_ADMIN_QUOTA_MB", config.Uploads.AdminQuotaMB)
+	config.Uploads.SigningSecret = getEnv("UPLOAD_SIGNING_SECRET", config.Uploads.SigningSecret)
+	config.Uploads.SignedURLTTL = getEnvAsDuration("UPLOAD_SIGNED_URL_TTL", config.Uploads.SignedURLTTL)
+	config.Uploads.ProductMinWidth = getEnvAsInt("UPLOAD_PRODUCT_MIN_WIDTH", config.Uploads.ProductMinWidth)
+	config.Uploads.ProductMinHeight = getEnvAsInt("UPLOAD_PRODUCT_MIN_HEIGHT", config.Uploads.ProductMinHeight)
+	config.Uploads.ProductAspectRatio = getEnv("UPLOAD_PRODUCT_ASPECT_RATIO", config.Uploads.ProductAspectRatio)
+	config.Uploads.ProductAspectTolerance = getEnvAsFloat("UPLOAD_PRODUCT_ASPECT_TOLERANCE", config.Uploads.ProductAspectTolerance)
+
+	config.Email.SMTPHost = getEnv("SMTP_HOST", config.Email.SMTPHost)
+	config.Email.SMTPPort = getEnvAsInt("SMTP_PORT", config.Email.SMTPPort)
+	config.Email.Username = getEnv("SMTP_USERNAME", config.Email.Username)
+	config.Email.Password = getEnv("SMTP_PASSWORD", config.Email.Password)
+	config.Email.From = getEnv("EMAIL_FROM", config.Email.From)
+
+	config.Cart.SweepInterval = getEnvAsDuration("CART_SWEEP_INTERVAL", config.Cart.SweepInterval)
+	config.Cart.ItemMaxAge = getEnvAsDuration("CART_ITEM_MAX_AGE", config.Cart.ItemMaxAge)
+
+	config.Reviews.CommentMinLength = getEnvAsInt("REVIEW_COMMENT_MIN_LENGTH", config.Reviews.CommentMinLength)
+	config.Reviews.CommentMaxLength = getEnvAsInt("REVIEW_COMMENT_MAX_LENGTH", config.Reviews.CommentMaxLength)
+	config.Reviews.UpsertDuplicates = getEnvAsBool("REVIEW_UPSERT_DUPLICATES", config.Reviews.UpsertDuplicates)
+
+	config.Jobs.Workers = getEnvAsInt("JOB_WORKERS", config.Jobs.Workers)
+	config.Jobs.MaxAttempts = getEnvAsInt("JOB_MAX_ATTEMPTS", config.Jobs.MaxAttempts)
+	config.Jobs.RecommendationsInterval = getEnvAsDuration("RECOMMENDATIONS_REFRESH_INTERVAL", config.Jobs.RecommendationsInterval)
+
+	config.WebSocket.BroadcastWorkers = getEnvAsInt("WS_BROADCAST_WORKERS", config.WebSocket.BroadcastWorkers)
+	config.WebSocket.BroadcastBuffer = getEnvAsInt("WS_BROADCAST_BUFFER", config.WebSocket.BroadcastBuffer)
+	config.WebSocket.PingInterval = getEnvAsDuration("WS_PING_INTERVAL", config.WebSocket.PingInterval)
+	config.WebSocket.PongWait = getEnvAsDuration("WS_PONG_WAIT", config.WebSocket.PongWait)
+	config.WebSocket.WriteWait = getEnvAsDuration("WS_WRITE_WAIT", config.WebSocket.WriteWait)
+	config.WebSocket.MaxConnections = getEnvAsInt("WS_MAX_CONNECTIONS", config.WebSocket.MaxConnections)
+	config.WebSocket.MaxConnectionsPerUser = getEnvAsInt("WS_MAX_CONNECTIONS_PER_USER", config.WebSocket.MaxConnectionsPerUser)
+
+	config.Stores.Header = getEnv("STORE_HEADER", config.Stores.Header)
+	config.Stores.JWTClaim = getEnv("STORE_JWT_CLAIM", config.Stores.JWTClaim)
+	config.Stores.BaseDomain = getEnv("STORE_BASE_DOMAIN", config.Stores.BaseDomain)
 }
 
 func setDefaults(config *AppConfig) {
@@ -188,9 +635,42 @@ func setDefaults(config *AppConfig) {
 	if config.Server.IdleTimeout == 0 {
 		config.Server.IdleTimeout = 120 * time.Second
 	}
+	if config.Server.ReadHeaderTimeout == 0 {
+		config.Server.ReadHeaderTimeout = 10 * time.Second
+	}
+	if config.Server.MaxHeaderBytes == 0 {
+		config.Server.MaxHeaderBytes = 1 << 20
+	}
+	if config.Server.MaxBodyBytes == 0 {
+		config.Server.MaxBodyBytes = 1 << 20
+	}
+	if config.Server.MaxUploadBytes == 0 {
+		config.Server.MaxUploadBytes = 11 << 20
+	}
 	if config.Server.Environment == "" {
 		config.Server.Environment = "development"
 	}
+	if len(config.Server.RateLimitBypassPaths) == 0 {
+		config.Server.RateLimitBypassPaths = []string{"/api/health", "/api/metrics", "/api/version"}
+	}
+	if config.Server.RequestIDHeader == "" {
+		config.Server.RequestIDHeader = "X-Request-ID"
+	}
+	if config.Security.HeadersPreset == "" {
+		config.Security.HeadersPreset = "default"
+		if config.IsProduction() {
+			config.Security.HeadersPreset = "strict"
+		}
+	}
+	if config.Security.CORSMaxAge == 0 {
+		config.Security.CORSMaxAge = 10 * time.Minute
+	}
+	if config.Server.PublicURL == "" {
+		config.Server.PublicURL = fmt.Sprintf("http://localhost:%d", config.Server.Port)
+	}
+	if config.Server.SiteURL == "" {
+		config.Server.SiteURL = "http://localhost:3000"
+	}
 
 	if config.Database.Driver == "" {
 		config.Database.Driver = "postgres"
@@ -225,6 +705,18 @@ func setDefaults(config *AppConfig) {
 	if config.Database.ConnMaxIdleTime == 0 {
 		config.Database.ConnMaxIdleTime = 1 * time.Minute
 	}
+	if config.Database.ReadRetries == 0 {
+		config.Database.ReadRetries = 2
+	}
+	if config.Database.RetryBackoff == 0 {
+		config.Database.RetryBackoff = 100 * time.Millisecond
+	}
+	if config.Database.BreakerThreshold == 0 {
+		config.Database.BreakerThreshold = 5
+	}
+	if config.Database.BreakerCooldown == 0 {
+		config.Database.BreakerCooldown = 30 * time.Second
+	}
 
 	if config.Redis.Host == "" {
 		config.Redis.Host = "localhost"
@@ -248,6 +740,45 @@ func setDefaults(config *AppConfig) {
 	if config.JWT.Audience == "" {
 		config.JWT.Audience = "ecommerce-client"
 	}
+	if config.JWT.ImpersonationTTL == 0 {
+		config.JWT.ImpersonationTTL = 15 * time.Minute
+	}
+
+	if config.Password.Algorithm == "" {
+		config.Password.Algorithm = "bcrypt"
+	}
+	if config.Password.BcryptCost == 0 {
+		config.Password.BcryptCost = 10
+	}
+	if config.Password.Argon2Time == 0 {
+		config.Password.Argon2Time = 1
+	}
+	if config.Password.Argon2Memory == 0 {
+		config.Password.Argon2Memory = 64 * 1024
+	}
+	if config.Password.Argon2Threads == 0 {
+		config.Password.Argon2Threads = 4
+	}
+
+	if config.Register.CaptchaProvider == "" {
+		config.Register.CaptchaProvider = "turnstile"
+	}
+	if config.Register.RateLimit == 0 {
+		config.Register.RateLimit = 10
+	}
+	if config.Register.RateWindow == 0 {
+		config.Register.RateWindow = time.Hour
+	}
+	if config.Register.BlockedEmailDomainsReload == 0 {
+		config.Register.BlockedEmailDomainsReload = time.Minute
+	}
+
+	if config.Payments.Provider == "" {
+		config.Payments.Provider = "stripe"
+	}
+	if len(config.Payments.Providers) == 0 {
+		config.Payments.Providers = []string{config.Payments.Provider}
+	}
 
 	if config.Logging.Level == "" {
 		config.Logging.Level = "info"
@@ -258,6 +789,9 @@ func setDefaults(config *AppConfig) {
 	if config.Logging.Output == "" {
 		config.Logging.Output = "stdout"
 	}
+	if config.Logging.DebugBodyMaxBytes == 0 {
+		config.Logging.DebugBodyMaxBytes = 4096
+	}
 
 	if config.Cache.DefaultTTL == 0 {
 		config.Cache.DefaultTTL = 1 * time.Hour
@@ -268,6 +802,35 @@ func setDefaults(config *AppConfig) {
 	if config.Cache.CleanupInterval == 0 {
 		config.Cache.CleanupInterval = 10 * time.Minute
 	}
+	if config.Cache.WarmupTimeout == 0 {
+		config.Cache.WarmupTimeout = 30 * time.Second
+	}
+	if config.Cache.Caches == nil {
+		config.Cache.Caches = make(map[string]NamedCacheConfig)
+	}
+	for name, defaults := range map[string]NamedCacheConfig{
+		"products": {TTL: 5 * time.Minute, MaxSize: 500},
+		"search":   {TTL: 5 * time.Minute, MaxSize: 1000},
+		"shipping": {TTL: time.Hour, MaxSize: 1000},
+	} {
+		named := config.Cache.Caches[name]
+		if named.TTL == 0 {
+			named.TTL = defaults.TTL
+		}
+		if named.MaxSize == 0 {
+			named.MaxSize = defaults.MaxSize
+		}
+		config.Cache.Caches[name] = named
+	}
+	for name, named := range config.Cache.Caches {
+		if named.TTL == 0 {
+			named.TTL = config.Cache.DefaultTTL
+		}
+		if named.MaxSize == 0 {
+			named.MaxSize = config.Cache.MaxSize
+		}
+		config.Cache.Caches[name] = named
+	}
 
 	if config.Metrics.Port == 0 {
 		config.Metrics.Port = 9090
@@ -278,6 +841,335 @@ func setDefaults(config *AppConfig) {
 	if config.Metrics.Namespace == "" {
 		config.Metrics.Namespace = "ecommerce"
 	}
+
+	if config.Maintenance.Message == "" {
+		config.Maintenance.Message = "The service is undergoing scheduled maintenance"
+	}
+	if config.Maintenance.RetryAfter == 0 {
+		config.Maintenance.RetryAfter = 5 * time.Minute
+	}
+	if len(config.Maintenance.AllowedPaths) == 0 {
+		config.Maintenance.AllowedPaths = []string{"/api/health", "/api/version", "/admin"}
+	}
+	if config.Maintenance.CheckInterval == 0 {
+		config.Maintenance.CheckInterval = 30 * time.Second
+	}
+	if len(config.Maintenance.AlertLeadTimes) == 0 {
+		config.Maintenance.AlertLeadTimes = []time.Duration{24 * time.Hour, time.Hour, 15 * time.Minute, 5 * time.Minute, time.Minute}
+	}
+
+	if config.Catalog.DefaultSort == "" {
+		config.Catalog.DefaultSort = "created_at"
+	}
+	if config.Catalog.DefaultSortOrder == "" {
+		config.Catalog.DefaultSortOrder = "desc"
+	}
+	if config.Catalog.MaxPageSize == 0 {
+		config.Catalog.MaxPageSize = 100
+	}
+	if config.Catalog.DefaultPageSize == 0 {
+		config.Catalog.DefaultPageSize = 20
+	}
+	if config.Catalog.DefaultPageSize > config.Catalog.MaxPageSize {
+		config.Catalog.DefaultPageSize = config.Catalog.MaxPageSize
+	}
+	if config.Catalog.Currency == "" {
+		config.Catalog.Currency = "USD"
+	}
+	config.Catalog.Currency = strings.ToUpper(config.Catalog.Currency)
+	if config.Catalog.StoreName == "" {
+		config.Catalog.StoreName = "Eshop"
+	}
+	if config.Catalog.DefaultLocale == "" {
+		config.Catalog.DefaultLocale = "en"
+	}
+	config.Catalog.DefaultLocale = normalizeLocale(config.Catalog.DefaultLocale)
+	locales := []string{config.Catalog.DefaultLocale}
+	seen := map[string]bool{config.Catalog.DefaultLocale: true}
+	for _, locale := range config.Catalog.Locales {
+		if locale = normalizeLocale(locale); locale != "" && !seen[locale] {
+			seen[locale] = true
+			locales = append(locales, locale)
+		}
+	}
+	config.Catalog.Locales = locales
+	if config.Catalog.NewArrivalsDays == 0 {
+		config.Catalog.NewArrivalsDays = 30
+	}
+	if len(config.Catalog.PriceFacetBounds) == 0 {
+		config.Catalog.PriceFacetBounds = []float64{25, 50, 100, 250, 500}
+	}
+
+	if config.Orders.ReturnWindowDays == 0 {
+		config.Orders.ReturnWindowDays = 30
+	}
+	if config.Orders.ShippingFlatRate == 0 {
+		config.Orders.ShippingFlatRate = 10
+	}
+	if config.Orders.GiftWrapFee == 0 {
+		config.Orders.GiftWrapFee = 5
+	}
+	if config.Orders.TaxRate == 0 {
+		config.Orders.TaxRate = 0.1
+	}
+	if config.Orders.TaxRounding == "" {
+		config.Orders.TaxRounding = "half_up"
+	}
+	if config.Orders.OriginCountry == "" {
+		config.Orders.OriginCountry = "US"
+	}
+	config.Orders.OriginCountry = strings.ToUpper(config.Orders.OriginCountry)
+	if config.Orders.ExpressSurcharge == 0 {
+		config.Orders.ExpressSurcharge = 15
+	}
+	if config.Orders.InternationalRate == 0 {
+		config.Orders.InternationalRate = 25
+	}
+	if config.Orders.RemoteAreaSurcharge == 0 {
+		config.Orders.RemoteAreaSurcharge = 10
+	}
+	if config.Orders.DownloadLimit == 0 {
+		config.Orders.DownloadLimit = 5
+	}
+	if config.Orders.DownloadTTL == 0 {
+		config.Orders.DownloadTTL = 30 * 24 * time.Hour
+	}
+	if config.Orders.ConfirmationResendLimit == 0 {
+		config.Orders.ConfirmationResendLimit = 3
+	}
+	if config.Orders.ConfirmationResendWindow == 0 {
+		config.Orders.ConfirmationResendWindow = time.Hour
+	}
+	if config.Orders.UnpaidCheckInterval == 0 {
+		config.Orders.UnpaidCheckInterval = 5 * time.Minute
+	}
+
+	if config.Uploads.UserQuotaMB == 0 {
+		config.Uploads.UserQuotaMB = 100
+	}
+	if config.Uploads.SigningSecret == "" {
+		config.Uploads.SigningSecret = config.JWT.Secret
+	}
+	if config.Uploads.SignedURLTTL == 0 {
+		config.Uploads.SignedURLTTL = time.Hour
+	}
+	if config.Uploads.ProductMinWidth == 0 {
+		config.Uploads.ProductMinWidth = 600
+	}
+	if config.Uploads.ProductMinHeight == 0 {
+		config.Uploads.ProductMinHeight = 600
+	}
+	if config.Uploads.ProductAspectTolerance == 0 {
+		config.Uploads.ProductAspectTolerance = 0.02
+	}
+
+	if config.Email.SMTPPort == 0 {
+		config.Email.SMTPPort = 587
+	}
+	if config.Email.From == "" {
+		config.Email.From = "no-reply@ecommerce.local"
+	}
+
+	if config.Cart.SweepInterval == 0 {
+		config.Cart.SweepInterval = time.Hour
+	}
+
+	if config.Reviews.CommentMinLength == 0 {
+		config.Reviews.CommentMinLength = 1
+	}
+	if config.Reviews.CommentMaxLength == 0 {
+		config.Reviews.CommentMaxLength = 5000
+	}
+
+	if config.Jobs.Workers == 0 {
+		config.Jobs.Workers = 4
+	}
+	if config.Jobs.MaxAttempts == 0 {
+		config.Jobs.MaxAttempts = 3
+	}
+	if config.Jobs.RecommendationsInterval == 0 {
+		config.Jobs.RecommendationsInterval = 6 * time.Hour
+	}
+
+	if config.WebSocket.BroadcastBuffer == 0 {
+		config.WebSocket.BroadcastBuffer = 256
+	}
+	if config.WebSocket.PingInterval == 0 {
+		config.WebSocket.PingInterval = 30 * time.Second
+	}
+	if config.WebSocket.PongWait == 0 {
+		config.WebSocket.PongWait = 60 * time.Second
+	}
+	if config.WebSocket.WriteWait == 0 {
+		config.WebSocket.WriteWait = 10 * time.Second
+	}
+	if config.WebSocket.MaxConnections == 0 {
+		config.WebSocket.MaxConnections = 10000
+	}
+	if config.WebSocket.MaxConnectionsPerUser == 0 {
+		config.WebSocket.MaxConnectionsPerUser = 10
+	}
+
+	if config.Stores.Header == "" {
+		config.Stores.Header = "X-Store"
+	}
+	if config.Stores.JWTClaim == "" {
+		config.Stores.JWTClaim = "store_id"
+	}
+}
+
+// validate rejects negative server limits, which net/http would treat as
+// "no limit" and leave the server open to slow-client attacks.
+func validate(config *AppConfig) error {
+	timeouts := map[string]time.Duration{
+		"read_timeout":        config.Server.ReadTimeout,
+		"read_header_timeout": config.Server.ReadHeaderTimeout,
+		"write_timeout":       config.Server.WriteTimeout,
+		"idle_timeout":        config.Server.IdleTimeout,
+	}
+	for name, timeout := range timeouts {
+		if timeout < 0 {
+			return fmt.Errorf("server.%s must be positive, got %s", name, timeout)
+		}
+	}
+	if config.Server.MaxHeaderBytes < 0 {
+		return fmt.Errorf("server.max_header_bytes must be positive, got %d", config.Server.MaxHeaderBytes)
+	}
+	if config.Server.MaxBodyBytes < 0 || config.Server.MaxUploadBytes < 0 {
+		return fmt.Errorf("server body size limits must be positive")
+	}
+	for _, proxy := range config.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("server.trusted_proxies: %q is not an IP or CIDR", proxy)
+		}
+	}
+	for _, path := range config.Server.RateLimitBypassPaths {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("server.rate_limit_bypass_paths: %q must start with /", path)
+		}
+	}
+	if strings.Trim(config.Server.RequestIDHeader, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-") != "" {
+		return fmt.Errorf("server.request_id_header %q is not a valid header name", config.Server.RequestIDHeader)
+	}
+	defaultEnabled := false
+	for _, provider := range config.Payments.Providers {
+		switch provider {
+		case "stripe":
+		case "mock":
+			if config.IsProduction() {
+				return fmt.Errorf("payments: the mock provider cannot be enabled in production")
+			}
+		default:
+			return fmt.Errorf("payments.providers: unknown provider %q", provider)
+		}
+		defaultEnabled = defaultEnabled || provider == config.Payments.Provider
+	}
+	if !defaultEnabled {
+		return fmt.Errorf("payments.provider %q must be one of payments.providers", config.Payments.Provider)
+	}
+	if config.Payments.TestMode && config.IsProduction() {
+		return fmt.Errorf("payments: test mode cannot be enabled in production")
+	}
+	switch config.Security.HeadersPreset {
+	case "default", "strict":
+	default:
+		return fmt.Errorf("security.headers_preset must be \"default\" or \"strict\", got %q", config.Security.HeadersPreset)
+	}
+	switch strings.ToUpper(config.Security.FrameOptions) {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		return fmt.Errorf("security.frame_options must be DENY or SAMEORIGIN, got %q", config.Security.FrameOptions)
+	}
+	if config.Security.HSTSMaxAge < 0 {
+		return fmt.Errorf("security.hsts_max_age must be positive, got %s", config.Security.HSTSMaxAge)
+	}
+	if config.JWT.ImpersonationTTL < 0 || config.JWT.ImpersonationTTL > time.Hour {
+		return fmt.Errorf("jwt.impersonation_ttl must be between 0 and 1h, got %s", config.JWT.ImpersonationTTL)
+	}
+	if config.Security.CORSMaxAge < 0 {
+		return fmt.Errorf("security.cors_max_age must be positive, got %s", config.Security.CORSMaxAge)
+	}
+	if config.Register.BlockedEmailDomainsReload < 0 {
+		return fmt.Errorf("register.blocked_email_domains_reload must be positive, got %s", config.Register.BlockedEmailDomainsReload)
+	}
+	if config.Orders.MinOrderAmount < 0 || config.Orders.FreeShippingThreshold < 0 || config.Orders.ShippingFlatRate < 0 || config.Orders.GiftWrapFee < 0 ||
+		config.Orders.ExpressSurcharge < 0 || config.Orders.InternationalRate < 0 || config.Orders.RemoteAreaSurcharge < 0 {
+		return fmt.Errorf("order amounts must be positive")
+	}
+	// Amounts are stored in hundredths, which only fits two-decimal currencies.
+	if !utils.IsSupportedCurrency(config.Catalog.Currency) {
+		return fmt.Errorf("catalog.currency %q is not supported", config.Catalog.Currency)
+	}
+	if config.Cache.WarmupTimeout < 0 {
+		return fmt.Errorf("cache warmup timeout must be positive")
+	}
+	if config.Catalog.NewArrivalsDays < 0 {
+		return fmt.Errorf("catalog.new_arrivals_days must be positive")
+	}
+	for i, bound := range config.Catalog.PriceFacetBounds {
+		if bound <= 0 || (i > 0 && bound <= config.Catalog.PriceFacetBounds[i-1]) {
+			return fmt.Errorf("catalog.price_facet_bounds must be positive and ascending")
+		}
+	}
+	if config.Orders.TaxRate < 0 || config.Orders.TaxRate >= 1 {
+		return fmt.Errorf("order tax rate must be between 0 and 1")
+	}
+	if config.Orders.TaxRounding != "half_up" && config.Orders.TaxRounding != "half_even" {
+		return fmt.Errorf("order tax rounding must be half_up or half_even")
+	}
+	if config.Orders.DownloadLimit < 0 || config.Orders.DownloadTTL < 0 {
+		return fmt.Errorf("order download limit and ttl must be positive")
+	}
+	if config.Orders.ConfirmationResendLimit < 0 || config.Orders.ConfirmationResendWindow < 0 {
+		return fmt.Errorf("order confirmation resend limit and window must be positive")
+	}
+	if config.Orders.UnpaidTimeout < 0 || config.Orders.UnpaidCheckInterval < 0 {
+		return fmt.Errorf("order unpaid timeout and check interval must be positive")
+	}
+	if config.Uploads.SignedURLTTL < 0 {
+		return fmt.Errorf("uploads.signed_url_ttl must be positive, got %s", config.Uploads.SignedURLTTL)
+	}
+	if config.Uploads.ProductMinWidth < 0 || config.Uploads.ProductMinHeight < 0 || config.Uploads.ProductAspectTolerance < 0 {
+		return fmt.Errorf("uploads product image dimensions and aspect tolerance must be positive")
+	}
+	if _, err := parseAspectRatio(config.Uploads.ProductAspectRatio); err != nil {
+		return fmt.Errorf("uploads.product_aspect_ratio: %w", err)
+	}
+	if config.Maintenance.CheckInterval < 0 {
+		return fmt.Errorf("maintenance.check_interval must be positive, got %s", config.Maintenance.CheckInterval)
+	}
+	for _, lead := range config.Maintenance.AlertLeadTimes {
+		if lead <= 0 {
+			return fmt.Errorf("maintenance.alert_lead_times must be positive, got %s", lead)
+		}
+	}
+	if config.Cart.SweepInterval < 0 || config.Cart.ItemMaxAge < 0 {
+		return fmt.Errorf("cart sweep interval and item max age must be positive")
+	}
+	if config.Reviews.CommentMinLength < 0 || config.Reviews.CommentMaxLength < config.Reviews.CommentMinLength {
+		return fmt.Errorf("reviews.comment_min_length (%d) must be positive and not above reviews.comment_max_length (%d)",
+			config.Reviews.CommentMinLength, config.Reviews.CommentMaxLength)
+	}
+	if config.Database.ReadRetries < 0 || config.Database.RetryBackoff < 0 || config.Database.BreakerThreshold < 0 || config.Database.BreakerCooldown < 0 {
+		return fmt.Errorf("database retry and circuit breaker settings must be positive")
+	}
+	if config.WebSocket.PingInterval < 0 || config.WebSocket.PongWait < 0 || config.WebSocket.WriteWait < 0 {
+		return fmt.Errorf("websocket ping interval, pong wait and write wait must be positive")
+	}
+	if config.WebSocket.PongWait <= config.WebSocket.PingInterval {
+		return fmt.Errorf("websocket.pong_wait (%s) must be greater than websocket.ping_interval (%s)",
+			config.WebSocket.PongWait, config.WebSocket.PingInterval)
+	}
+	if config.WebSocket.MaxConnections < 0 || config.WebSocket.MaxConnectionsPerUser < 0 {
+		return fmt.Errorf("websocket connection limits must be positive")
+	}
+	// A minimum above the threshold would make every order ship free and
+	// hide the "add more for free shipping" hint entirely.
+	if config.Orders.FreeShippingThreshold > 0 && config.Orders.MinOrderAmount > config.Orders.FreeShippingThreshold {
+		return fmt.Errorf("orders.min_order_amount (%.2f) must not exceed orders.free_shipping_threshold (%.2f)",
+			config.Orders.MinOrderAmount, config.Orders.FreeShippingThreshold)
+	}
+	return nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -296,6 +1188,15 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -314,6 +1215,75 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	return defaultValue
 }
 
+// parseTimeout accepts integer seconds ("15") or a duration string ("15s").
+func parseTimeout(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func getEnvAsTimeout(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := parseTimeout(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// getEnvAsDurationSlice parses a comma-separated list such as "1h,15m",
+// keeping the default if any entry is invalid.
+func getEnvAsDurationSlice(key string, defaultValue []time.Duration) []time.Duration {
+	values := getEnvAsSlice(key, nil)
+	if len(values) == 0 {
+		return defaultValue
+	}
+	result := make([]time.Duration, 0, len(values))
+	for _, value := range values {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return defaultValue
+		}
+		result = append(result, duration)
+	}
+	return result
+}
+
+func getEnvAsFloatSlice(key string, defaultValue []float64) []float64 {
+	values := getEnvAsSlice(key, nil)
+	if len(values) == 0 {
+		return defaultValue
+	}
+	result := make([]float64, 0, len(values))
+	for _, value := range values {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return defaultValue
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		parts := strings.Split(value, ",")
+		result := make([]string, 0, len(parts))
+		for _, part := range parts {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+		return result
+	}
+	return defaultValue
+}
+
 func (c *AppConfig) IsDevelopment() bool {
 	return strings.ToLower(c.Server.Environment) == "development"
 }
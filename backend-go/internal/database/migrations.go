@@ -294,6 +294,894 @@ func (mm *MigrationManager) LoadBuiltinMigrations() []Migration {
 				ALTER TABLE orders DROP COLUMN IF EXISTS deleted_at;
 			`,
 		},
+		{
+			Version: 4,
+			Name:    "add_review_replies",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS review_replies (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					review_id UUID UNIQUE NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+					user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+					body TEXT NOT NULL,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS review_replies;
+			`,
+		},
+		{
+			Version: 5,
+			Name:    "add_uploads_and_review_images",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS uploads (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+					filename VARCHAR(255) UNIQUE NOT NULL,
+					content_type VARCHAR(100) NOT NULL,
+					size BIGINT NOT NULL,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+
+				CREATE TABLE IF NOT EXISTS review_images (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					review_id UUID NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+					upload_id UUID NOT NULL REFERENCES uploads(id) ON DELETE CASCADE,
+					position INTEGER NOT NULL DEFAULT 0,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					UNIQUE(review_id, upload_id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_uploads_user_id ON uploads(user_id);
+				CREATE INDEX IF NOT EXISTS idx_review_images_review_id ON review_images(review_id);
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS review_images;
+				DROP TABLE IF EXISTS uploads;
+			`,
+		},
+		{
+			Version: 6,
+			Name:    "add_search_prefix_indexes",
+			UpSQL: `
+				CREATE INDEX IF NOT EXISTS idx_products_name_prefix ON products (lower(name) text_pattern_ops);
+				CREATE INDEX IF NOT EXISTS idx_categories_name_prefix ON categories (lower(name) text_pattern_ops);
+				CREATE INDEX IF NOT EXISTS idx_order_items_product_id ON order_items(product_id);
+			`,
+			DownSQL: `
+				DROP INDEX IF EXISTS idx_products_name_prefix;
+				DROP INDEX IF EXISTS idx_categories_name_prefix;
+				DROP INDEX IF EXISTS idx_order_items_product_id;
+			`,
+		},
+		{
+			Version: 7,
+			Name:    "add_price_history",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS price_history (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+					price DECIMAL(10,2) NOT NULL,
+					recorded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_price_history_product_recorded ON price_history(product_id, recorded_at);
+
+				-- Seed each existing product's current price so its series starts now.
+				INSERT INTO price_history (product_id, price, recorded_at)
+				SELECT p.id, p.price, COALESCE(p.updated_at, p.created_at, CURRENT_TIMESTAMP)
+				FROM products p
+				WHERE NOT EXISTS (SELECT 1 FROM price_history ph WHERE ph.product_id = p.id);
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS price_history;
+			`,
+		},
+		{
+			Version: 8,
+			Name:    "add_order_notes",
+			UpSQL: `
+				ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_note TEXT;
+
+				CREATE TABLE IF NOT EXISTS order_internal_notes (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+					author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+					note TEXT NOT NULL,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_order_internal_notes_order_id ON order_internal_notes(order_id);
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS order_internal_notes;
+				ALTER TABLE orders DROP COLUMN IF EXISTS customer_note;
+			`,
+		},
+		{
+			Version: 9,
+			Name:    "create_shipments",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS shipments (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+					carrier VARCHAR(100),
+					tracking_number VARCHAR(255),
+					status VARCHAR(50) NOT NULL DEFAULT 'shipped',
+					shipped_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					delivered_at TIMESTAMP,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+
+				CREATE TABLE IF NOT EXISTS shipment_items (
+					shipment_id UUID NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+					order_item_id UUID NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+					quantity INTEGER NOT NULL CHECK (quantity > 0),
+					PRIMARY KEY (shipment_id, order_item_id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_shipments_order_id ON shipments(order_id);
+				CREATE INDEX IF NOT EXISTS idx_shipment_items_order_item_id ON shipment_items(order_item_id);
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS shipment_items;
+				DROP TABLE IF EXISTS shipments;
+			`,
+		},
+		{
+			Version: 10,
+			Name:    "create_returns",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS returns (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+					user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+					status VARCHAR(50) NOT NULL DEFAULT 'requested',
+					reason TEXT NOT NULL,
+					admin_note TEXT,
+					refund_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+					refund_id VARCHAR(255),
+					received_at TIMESTAMP,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+
+				CREATE TABLE IF NOT EXISTS return_items (
+					return_id UUID NOT NULL REFERENCES returns(id) ON DELETE CASCADE,
+					order_item_id UUID NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+					quantity INTEGER NOT NULL CHECK (quantity > 0),
+					PRIMARY KEY (return_id, order_item_id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_returns_order_id ON returns(order_id);
+				CREATE INDEX IF NOT EXISTS idx_returns_status ON returns(status);
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS return_items;
+				DROP TABLE IF EXISTS returns;
+			`,
+		},
+		{
+			Version: 11,
+			Name:    "create_data_exports",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS data_exports (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					token VARCHAR(64) UNIQUE NOT NULL,
+					status VARCHAR(20) NOT NULL DEFAULT 'pending',
+					file_path VARCHAR(500),
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					completed_at TIMESTAMP,
+					expires_at TIMESTAMP
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_data_exports_user_created ON data_exports(user_id, created_at DESC);
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS data_exports;
+			`,
+		},
+		{
+			Version: 12,
+			Name:    "create_audit_log",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS audit_log (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+					actor_ip VARCHAR(64),
+					action VARCHAR(50) NOT NULL,
+					target_type VARCHAR(50),
+					target_id VARCHAR(255),
+					before JSONB,
+					after JSONB,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at DESC);
+				CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action);
+				CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log(actor_id);
+				CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_type, target_id);
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS audit_log;
+			`,
+		},
+		{
+			Version: 13,
+			Name:    "create_product_associations",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS product_associations (
+					product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+					associated_product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+					score INTEGER NOT NULL,
+					updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY (product_id, associated_product_id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_product_associations_score ON product_associations(product_id, score DESC);
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS product_associations;
+			`,
+		},
+		{
+			Version: 14,
+			Name:    "create_product_images",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS product_images (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+					url TEXT NOT NULL,
+					position INTEGER NOT NULL DEFAULT 0,
+					is_primary BOOLEAN NOT NULL DEFAULT false,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_product_images_product_id ON product_images(product_id, position);
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_product_images_primary ON product_images(product_id) WHERE is_primary;
+
+				INSERT INTO product_images (product_id, url, position, is_primary)
+				SELECT p.id, img.url, img.ord - 1, img.ord = 1
+				FROM products p, unnest(p.images) WITH ORDINALITY AS img(url, ord)
+				WHERE NOT EXISTS (SELECT 1 FROM product_images pi WHERE pi.product_id = p.id);
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS product_images;
+			`,
+		},
+		{
+			Version: 15,
+			Name:    "create_translations",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS product_translations (
+					product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+					locale VARCHAR(16) NOT NULL,
+					name VARCHAR(255) NOT NULL,
+					description TEXT,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY (product_id, locale)
+				);
+
+				CREATE TABLE IF NOT EXISTS category_translations (
+					category_id UUID NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+					locale VARCHAR(16) NOT NULL,
+					name VARCHAR(255) NOT NULL,
+					description TEXT,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY (category_id, locale)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_product_translations_locale ON product_translations(locale);
+				CREATE INDEX IF NOT EXISTS idx_category_translations_locale ON category_translations(locale);
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS category_translations;
+				DROP TABLE IF EXISTS product_translations;
+			`,
+		},
+		{
+			Version: 16,
+			Name:    "create_carts",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS carts (
+					user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+					version BIGINT NOT NULL DEFAULT 0,
+					updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+
+				INSERT INTO carts (user_id, version, updated_at)
+				SELECT user_id, 1, MAX(updated_at) FROM cart_items
+				WHERE user_id IS NOT NULL
+				GROUP BY user_id
+				ON CONFLICT (user_id) DO NOTHING;
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS carts;
+			`,
+		},
+		{
+			Version: 17,
+			Name:    "add_order_gift_options",
+			UpSQL: `
+				ALTER TABLE orders ADD COLUMN IF NOT EXISTS is_gift BOOLEAN NOT NULL DEFAULT false;
+				ALTER TABLE orders ADD COLUMN IF NOT EXISTS gift_message TEXT;
+				ALTER TABLE orders ADD COLUMN IF NOT EXISTS gift_wrap BOOLEAN NOT NULL DEFAULT false;
+				ALTER TABLE orders ADD COLUMN IF NOT EXISTS gift_wrap_fee DECIMAL(10,2) NOT NULL DEFAULT 0;
+			`,
+			DownSQL: `
+				ALTER TABLE orders DROP COLUMN IF EXISTS gift_wrap_fee;
+				ALTER TABLE orders DROP COLUMN IF EXISTS gift_wrap;
+				ALTER TABLE orders DROP COLUMN IF EXISTS gift_message;
+				ALTER TABLE orders DROP COLUMN IF EXISTS is_gift;
+			`,
+		},
+		{
+			Version: 18,
+			Name:    "create_product_qa",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS product_questions (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+					user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+					body TEXT NOT NULL,
+					upvotes INTEGER NOT NULL DEFAULT 0,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+
+				CREATE TABLE IF NOT EXISTS product_answers (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					question_id UUID NOT NULL REFERENCES product_questions(id) ON DELETE CASCADE,
+					user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+					body TEXT NOT NULL,
+					is_official BOOLEAN NOT NULL DEFAULT false,
+					upvotes INTEGER NOT NULL DEFAULT 0,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+
+				CREATE TABLE IF NOT EXISTS product_qa_votes (
+					user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					target_type VARCHAR(16) NOT NULL CHECK (target_type IN ('question', 'answer')),
+					target_id UUID NOT NULL,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY (user_id, target_type, target_id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_product_questions_product ON product_questions(product_id, created_at DESC);
+				CREATE INDEX IF NOT EXISTS idx_product_answers_question ON product_answers(question_id);
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS product_qa_votes;
+				DROP TABLE IF EXISTS product_answers;
+				DROP TABLE IF EXISTS product_questions;
+			`,
+		},
+		{
+			Version: 19,
+			Name:    "add_cart_item_price_and_notices",
+			UpSQL: `
+				ALTER TABLE cart_items ADD COLUMN IF NOT EXISTS added_price DECIMAL(10,2);
+
+				UPDATE cart_items ci SET added_price = p.price
+				FROM products p
+				WHERE ci.product_id = p.id AND ci.added_price IS NULL;
+
+				CREATE TABLE IF NOT EXISTS cart_notices (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					product_id UUID,
+					product_name VARCHAR(255),
+					type VARCHAR(32) NOT NULL,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_cart_notices_user_id ON cart_notices(user_id);
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS cart_notices;
+				ALTER TABLE cart_items DROP COLUMN IF EXISTS added_price;
+			`,
+		},
+		{
+			Version: 20,
+			Name:    "add_upload_private_flag",
+			UpSQL: `
+				ALTER TABLE uploads ADD COLUMN IF NOT EXISTS private BOOLEAN NOT NULL DEFAULT FALSE;
+			`,
+			DownSQL: `
+				ALTER TABLE uploads DROP COLUMN IF EXISTS private;
+			`,
+		},
+		{
+			Version: 21,
+			Name:    "add_digital_products_and_downloads",
+			UpSQL: `
+				ALTER TABLE products ADD COLUMN IF NOT EXISTS product_type VARCHAR(16) NOT NULL DEFAULT 'physical'
+					CHECK (product_type IN ('physical', 'digital'));
+				ALTER TABLE products ADD COLUMN IF NOT EXISTS digital_file VARCHAR(255);
+
+				CREATE TABLE IF NOT EXISTS order_downloads (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+					product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+					user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					token VARCHAR(64) NOT NULL UNIQUE,
+					download_count INTEGER NOT NULL DEFAULT 0,
+					max_downloads INTEGER NOT NULL,
+					expires_at TIMESTAMP NOT NULL,
+					last_downloaded_at TIMESTAMP,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					UNIQUE (order_id, product_id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_order_downloads_user_id ON order_downloads(user_id);
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS order_downloads;
+				ALTER TABLE products DROP COLUMN IF EXISTS digital_file;
+				ALTER TABLE products DROP COLUMN IF EXISTS product_type;
+			`,
+		},
+		{
+			Version: 22,
+			Name:    "store_money_as_minor_units",
+			UpSQL: `
+				DO $$
+				DECLARE col RECORD;
+				BEGIN
+					FOR col IN
+						SELECT table_name, column_name FROM information_schema.columns
+						WHERE table_schema = current_schema() AND data_type = 'numeric'
+						  AND (table_name, column_name) IN (
+							('products', 'price'), ('products', 'compare_price'),
+							('orders', 'total'), ('orders', 'subtotal'), ('orders', 'tax'), ('orders', 'shipping'),
+							('orders', 'gift_wrap_fee'), ('order_items', 'price'), ('cart_items', 'added_price'),
+							('payments', 'amount'), ('price_history', 'price'), ('returns', 'refund_amount'))
+					LOOP
+						EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE BIGINT USING ROUND(%I * 100)::BIGINT',
+							col.table_name, col.column_name, col.column_name);
+					END LOOP;
+				END $$;
+			`,
+			DownSQL: `
+				DO $$
+				DECLARE col RECORD;
+				BEGIN
+					FOR col IN
+						SELECT table_name, column_name FROM information_schema.columns
+						WHERE table_schema = current_schema() AND data_type = 'bigint'
+						  AND (table_name, column_name) IN (
+							('products', 'price'), ('products', 'compare_price'),
+							('orders', 'total'), ('orders', 'subtotal'), ('orders', 'tax'), ('orders', 'shipping'),
+							('orders', 'gift_wrap_fee'), ('order_items', 'price'), ('cart_items', 'added_price'),
+							('payments', 'amount'), ('price_history', 'price'), ('returns', 'refund_amount'))
+					LOOP
+						EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE DECIMAL(12,2) USING %I / 100.0',
+							col.table_name, col.column_name, col.column_name);
+					END LOOP;
+				END $$;
+			`,
+		},
+		{
+			Version: 23,
+			Name:    "create_notification_preferences",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS notification_preferences (
+					user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					channel VARCHAR(16) NOT NULL CHECK (channel IN ('email', 'websocket')),
+					event_type VARCHAR(16) NOT NULL CHECK (event_type IN ('order', 'promotion', 'price', 'stock')),
+					enabled BOOLEAN NOT NULL,
+					updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY (user_id, channel, event_type)
+				);
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS notification_preferences;
+			`,
+		},
+		{
+			Version: 24,
+			Name:    "create_notifications",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS notifications (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					type VARCHAR(32) NOT NULL,
+					message TEXT NOT NULL,
+					data JSONB,
+					read_at TIMESTAMP,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_notifications_user_created ON notifications(user_id, created_at DESC);
+				CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS notifications;
+			`,
+		},
+		{
+			Version: 25,
+			Name:    "snapshot_order_item_products",
+			UpSQL: `
+				ALTER TABLE order_items ADD COLUMN IF NOT EXISTS product_name VARCHAR(255) NOT NULL DEFAULT '';
+				ALTER TABLE order_items ADD COLUMN IF NOT EXISTS product_image TEXT;
+
+				UPDATE order_items oi
+				SET product_name = p.name, product_image = p.images[1]
+				FROM products p
+				WHERE oi.product_id = p.id AND oi.product_name = '';
+
+				-- Order history must survive the product being deleted.
+				ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_product_id_fkey;
+				ALTER TABLE order_items ADD CONSTRAINT order_items_product_id_fkey
+					FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE SET NULL;
+			`,
+			DownSQL: `
+				ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_product_id_fkey;
+				ALTER TABLE order_items ADD CONSTRAINT order_items_product_id_fkey
+					FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE;
+				ALTER TABLE order_items DROP COLUMN IF EXISTS product_image;
+				ALTER TABLE order_items DROP COLUMN IF EXISTS product_name;
+			`,
+		},
+		{
+			Version: 26,
+			Name:    "add_stores",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS stores (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					slug VARCHAR(64) UNIQUE NOT NULL,
+					name VARCHAR(255) NOT NULL,
+					domain VARCHAR(255) UNIQUE,
+					active BOOLEAN NOT NULL DEFAULT TRUE,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+
+				INSERT INTO stores (id, slug, name)
+				VALUES ('00000000-0000-0000-0000-000000000001', 'default', 'Default Store')
+				ON CONFLICT (id) DO NOTHING;
+
+				ALTER TABLE products ADD COLUMN IF NOT EXISTS store_id UUID NOT NULL
+					DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES stores(id);
+				ALTER TABLE categories ADD COLUMN IF NOT EXISTS store_id UUID NOT NULL
+					DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES stores(id);
+				ALTER TABLE orders ADD COLUMN IF NOT EXISTS store_id UUID NOT NULL
+					DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES stores(id);
+
+				-- Slugs only need to be unique within a store.
+				ALTER TABLE products DROP CONSTRAINT IF EXISTS products_slug_key;
+				ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_slug_key;
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_products_store_slug ON products(store_id, slug);
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_store_slug ON categories(store_id, slug);
+				CREATE INDEX IF NOT EXISTS idx_orders_store_user ON orders(store_id, user_id);
+			`,
+			DownSQL: `
+				DROP INDEX IF EXISTS idx_orders_store_user;
+				DROP INDEX IF EXISTS idx_categories_store_slug;
+				DROP INDEX IF EXISTS idx_products_store_slug;
+				ALTER TABLE orders DROP COLUMN IF EXISTS store_id;
+				ALTER TABLE categories DROP COLUMN IF EXISTS store_id;
+				ALTER TABLE products DROP COLUMN IF EXISTS store_id;
+				ALTER TABLE categories ADD CONSTRAINT categories_slug_key UNIQUE (slug);
+				ALTER TABLE products ADD CONSTRAINT products_slug_key UNIQUE (slug);
+				DROP TABLE IF EXISTS stores;
+			`,
+		},
+		{
+			Version: 27,
+			Name:    "unique_review_per_user_product",
+			UpSQL: `
+				-- Keep each user's most recent review of a product; images and
+				-- replies of the removed duplicates cascade.
+				DELETE FROM reviews r
+				USING (
+					SELECT id, ROW_NUMBER() OVER (
+						PARTITION BY user_id, product_id
+						ORDER BY updated_at DESC NULLS LAST, created_at DESC NULLS LAST, id DESC
+					) AS rank
+					FROM reviews
+				) ranked
+				WHERE r.id = ranked.id AND ranked.rank > 1;
+
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_reviews_user_product ON reviews(user_id, product_id);
+			`,
+			DownSQL: `
+				DROP INDEX IF EXISTS idx_reviews_user_product;
+			`,
+		},
+		{
+			Version: 28,
+			Name:    "add_product_sku_barcode",
+			UpSQL: `
+				ALTER TABLE products ADD COLUMN IF NOT EXISTS sku VARCHAR(64);
+				ALTER TABLE products ADD COLUMN IF NOT EXISTS barcode VARCHAR(64);
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_products_store_sku ON products(store_id, sku);
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_products_store_barcode ON products(store_id, barcode);
+			`,
+			DownSQL: `
+				DROP INDEX IF EXISTS idx_products_store_barcode;
+				DROP INDEX IF EXISTS idx_products_store_sku;
+				ALTER TABLE products DROP COLUMN IF EXISTS barcode;
+				ALTER TABLE products DROP COLUMN IF EXISTS sku;
+			`,
+		},
+		{
+			Version: 29,
+			Name:    "add_product_category_versions",
+			UpSQL: `
+				ALTER TABLE products ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+				ALTER TABLE categories ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+			`,
+			DownSQL: `
+				ALTER TABLE categories DROP COLUMN IF EXISTS version;
+				ALTER TABLE products DROP COLUMN IF EXISTS version;
+			`,
+		},
+		{
+			Version: 30,
+			Name:    "add_product_units_and_decimal_quantities",
+			UpSQL: `
+				ALTER TABLE products ADD COLUMN IF NOT EXISTS unit VARCHAR(16) NOT NULL DEFAULT 'each';
+				ALTER TABLE order_items ADD COLUMN IF NOT EXISTS unit VARCHAR(16) NOT NULL DEFAULT 'each';
+				ALTER TABLE cart_items ALTER COLUMN quantity TYPE NUMERIC(12,3);
+				ALTER TABLE order_items ALTER COLUMN quantity TYPE NUMERIC(12,3);
+				ALTER TABLE shipment_items ALTER COLUMN quantity TYPE NUMERIC(12,3);
+				ALTER TABLE return_items ALTER COLUMN quantity TYPE NUMERIC(12,3);
+			`,
+			DownSQL: `
+				ALTER TABLE return_items ALTER COLUMN quantity TYPE INTEGER USING CEIL(quantity);
+				ALTER TABLE shipment_items ALTER COLUMN quantity TYPE INTEGER USING CEIL(quantity);
+				ALTER TABLE order_items ALTER COLUMN quantity TYPE INTEGER USING CEIL(quantity);
+				ALTER TABLE cart_items ALTER COLUMN quantity TYPE INTEGER USING CEIL(quantity);
+				ALTER TABLE order_items DROP COLUMN IF EXISTS unit;
+				ALTER TABLE products DROP COLUMN IF EXISTS unit;
+			`,
+		},
+		{
+			Version: 31,
+			Name:    "create_maintenance_windows",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS maintenance_windows (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					starts_at TIMESTAMP NOT NULL,
+					ends_at TIMESTAMP NOT NULL,
+					message TEXT NOT NULL,
+					status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+					last_alert_lead BIGINT,
+					created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					CHECK (ends_at > starts_at)
+				);
+				CREATE INDEX IF NOT EXISTS idx_maintenance_windows_status ON maintenance_windows(status, starts_at);
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS maintenance_windows;
+			`,
+		},
+		{
+			Version: 32,
+			Name:    "create_inventory_movements",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS inventory_movements (
+					id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+					product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+					delta INTEGER NOT NULL,
+					stock_after INTEGER NOT NULL,
+					reason VARCHAR(16) NOT NULL CHECK (reason IN ('initial', 'sale', 'cancellation', 'return', 'restock', 'adjustment')),
+					reference VARCHAR(100),
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+				);
+				CREATE INDEX IF NOT EXISTS idx_inventory_movements_product ON inventory_movements(product_id, created_at DESC);
+				CREATE INDEX IF NOT EXISTS idx_inventory_movements_reference ON inventory_movements(reference) WHERE reference IS NOT NULL;
+				-- Open the ledger with the stock each product already has.
+				INSERT INTO inventory_movements (product_id, delta, stock_after, reason, reference)
+				SELECT id, stock, stock, 'initial', 'opening balance' FROM products WHERE stock <> 0;
+			`,
+			DownSQL: `
+				DROP TABLE IF EXISTS inventory_movements;
+			`,
+		},
+		{
+			Version: 33,
+			Name:    "add_payment_provider",
+			UpSQL: `
+				ALTER TABLE payments ADD COLUMN IF NOT EXISTS provider VARCHAR(20) NOT NULL DEFAULT 'stripe';
+			`,
+			DownSQL: `
+				ALTER TABLE payments DROP COLUMN IF EXISTS provider;
+			`,
+		},
+		{
+			Version: 34,
+			Name:    "add_payment_test_mode",
+			UpSQL: `
+				ALTER TABLE payments ADD COLUMN IF NOT EXISTS test_mode BOOLEAN NOT NULL DEFAULT false;
+			`,
+			DownSQL: `
+				ALTER TABLE payments DROP COLUMN IF EXISTS test_mode;
+			`,
+		},
+		{
+			Version: 35,
+			Name:    "add_order_tax_included",
+			UpSQL: `
+				ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_included BOOLEAN NOT NULL DEFAULT false;
+			`,
+			DownSQL: `
+				ALTER TABLE orders DROP COLUMN IF EXISTS tax_included;
+			`,
+		},
+		{
+			Version: 36,
+			Name:    "add_payments_open_order_index",
+			UpSQL: `
+				-- An order keeps at most one open payment; older duplicates are
+				-- retired before the index is built.
+				UPDATE payments p SET status = 'cancelled', updated_at = NOW()
+				WHERE p.status = 'pending' AND p.order_id IS NOT NULL AND EXISTS (
+					SELECT 1 FROM payments newer
+					WHERE newer.order_id = p.order_id AND newer.status = 'pending'
+					  AND (newer.created_at, newer.id) > (p.created_at, p.id)
+				);
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_open_order ON payments(order_id) WHERE status = 'pending';
+			`,
+			DownSQL: `
+				DROP INDEX IF EXISTS idx_payments_open_order;
+			`,
+		},
+		{
+			Version: 37,
+			Name:    "normalize_user_emails",
+			UpSQL: `
+				-- Emails are stored trimmed and lowercased. Accounts that only
+				-- differ by case are left as they are for support to resolve,
+				-- with a warning, and the case-insensitive index waits until
+				-- they are gone.
+				UPDATE users u SET email = LOWER(TRIM(u.email))
+				WHERE u.email <> LOWER(TRIM(u.email)) AND NOT EXISTS (
+					SELECT 1 FROM users other
+					WHERE other.id <> u.id AND LOWER(TRIM(other.email)) = LOWER(TRIM(u.email))
+				);
+				DO $$
+				DECLARE
+					collisions INTEGER;
+				BEGIN
+					SELECT COUNT(*) INTO collisions FROM (
+						SELECT 1 FROM users GROUP BY LOWER(email) HAVING COUNT(*) > 1
+					) duplicated;
+					IF collisions = 0 THEN
+						CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users(LOWER(email));
+					ELSE
+						RAISE WARNING '% emails are shared by accounts that differ only by case; idx_users_email_lower was not created. Merge or rename them, then run: CREATE UNIQUE INDEX idx_users_email_lower ON users(LOWER(email))', collisions;
+					END IF;
+				END $$;
+			`,
+			DownSQL: `
+				DROP INDEX IF EXISTS idx_users_email_lower;
+			`,
+		},
+		{
+			Version: 38,
+			Name:    "create_product_bundles",
+			UpSQL: `
+				CREATE TABLE IF NOT EXISTS product_bundle_items (
+					bundle_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+					component_id UUID NOT NULL REFERENCES products(id) ON DELETE RESTRICT,
+					quantity INTEGER NOT NULL CHECK (quantity > 0),
+					PRIMARY KEY (bundle_id, component_id),
+					CHECK (bundle_id <> component_id)
+				);
+				CREATE INDEX IF NOT EXISTS idx_product_bundle_items_component ON product_bundle_items(component_id);
+				-- Component lines of a bundle sold in an order point at the
+				-- bundle's line.
+				ALTER TABLE order_items ADD COLUMN IF NOT EXISTS bundle_item_id UUID REFERENCES order_items(id) ON DELETE CASCADE;
+			`,
+			DownSQL: `
+				ALTER TABLE order_items DROP COLUMN IF EXISTS bundle_item_id;
+				DROP TABLE IF EXISTS product_bundle_items;
+			`,
+		},
+		{
+			Version: 39,
+			Name:    "add_users_tokens_revoked_at",
+			UpSQL: `
+				-- Tokens issued to the user at or before this time are rejected.
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_revoked_at TIMESTAMP;
+			`,
+			DownSQL: `
+				ALTER TABLE users DROP COLUMN IF EXISTS tokens_revoked_at;
+			`,
+		},
+		{
+			Version: 40,
+			Name:    "add_orders_shipping_method",
+			UpSQL: `
+				-- The shipping method an order's shipping was priced for.
+				ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_method VARCHAR(20);
+			`,
+			DownSQL: `
+				ALTER TABLE orders DROP COLUMN IF EXISTS shipping_method;
+			`,
+		},
+		{
+			Version: 41,
+			Name:    "add_product_qa_moderation",
+			UpSQL: `
+				-- Existing Q&A stays visible; new posts wait for a moderator.
+				ALTER TABLE product_questions ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'approved'
+					CHECK (status IN ('pending', 'approved', 'hidden'));
+				ALTER TABLE product_questions ALTER COLUMN status SET DEFAULT 'pending';
+				ALTER TABLE product_questions ADD COLUMN IF NOT EXISTS reports INTEGER NOT NULL DEFAULT 0;
+				ALTER TABLE product_answers ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'approved'
+					CHECK (status IN ('pending', 'approved', 'hidden'));
+				ALTER TABLE product_answers ALTER COLUMN status SET DEFAULT 'pending';
+				ALTER TABLE product_answers ADD COLUMN IF NOT EXISTS reports INTEGER NOT NULL DEFAULT 0;
+
+				CREATE TABLE IF NOT EXISTS product_qa_reports (
+					user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					target_type VARCHAR(16) NOT NULL CHECK (target_type IN ('question', 'answer')),
+					target_id UUID NOT NULL,
+					reason TEXT NOT NULL,
+					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+					PRIMARY KEY (user_id, target_type, target_id)
+				);
+
+				CREATE INDEX IF NOT EXISTS idx_product_questions_status ON product_questions(status, created_at);
+				CREATE INDEX IF NOT EXISTS idx_product_answers_status ON product_answers(status, created_at);
+			`,
+			DownSQL: `
+				DROP INDEX IF EXISTS idx_product_answers_status;
+				DROP INDEX IF EXISTS idx_product_questions_status;
+				DROP TABLE IF EXISTS product_qa_reports;
+				ALTER TABLE product_answers DROP COLUMN IF EXISTS reports;
+				ALTER TABLE product_answers DROP COLUMN IF EXISTS status;
+				ALTER TABLE product_questions DROP COLUMN IF EXISTS reports;
+				ALTER TABLE product_questions DROP COLUMN IF EXISTS status;
+			`,
+		},
+		{
+			Version: 42,
+			Name:    "make_digital_files_private",
+			UpSQL: `
+				-- Digital product files are served only through download links.
+				UPDATE uploads SET private = true
+				WHERE private = false AND filename IN (SELECT digital_file FROM products WHERE digital_file IS NOT NULL);
+			`,
+			DownSQL: `
+				-- The files stay private: there is no record of which were public.
+			`,
+		},
+		{
+			Version: 43,
+			Name:    "add_user_store",
+			UpSQL: `
+				-- Staff pinned to a store; NULL means the user is not tied to one.
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS store_id UUID REFERENCES stores(id) ON DELETE SET NULL;
+			`,
+			DownSQL: `
+				ALTER TABLE users DROP COLUMN IF EXISTS store_id;
+			`,
+		},
+		{
+			Version: 44,
+			Name:    "add_review_images_upload_index",
+			UpSQL: `
+				-- Looked up before an upload is deleted with a review.
+				CREATE INDEX IF NOT EXISTS idx_review_images_upload_id ON review_images(upload_id);
+			`,
+			DownSQL: `
+				DROP INDEX IF EXISTS idx_review_images_upload_id;
+			`,
+		},
 	}
 }
 
@@ -423,6 +1311,32 @@ func (mm *MigrationManager) Status() ([]Migration, error) {
 	return migrations, nil
 }
 
+// Pending returns the builtin migrations that have not been applied, without
+// creating the migrations table or changing anything else in the database.
+func (mm *MigrationManager) Pending() ([]Migration, error) {
+	var exists bool
+	if err := mm.db.QueryRow("SELECT to_regclass('migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check migrations table: %w", err)
+	}
+
+	applied := make(map[int]bool)
+	if exists {
+		var err error
+		if applied, err = mm.GetAppliedMigrations(); err != nil {
+			return nil, err
+		}
+	}
+
+	var pending []Migration
+	for _, migration := range mm.LoadBuiltinMigrations() {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
 func (mm *MigrationManager) CreateMigration(name string) error {
 	migrations := mm.LoadBuiltinMigrations()
 	nextVersion := 1
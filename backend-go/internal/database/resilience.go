@@ -0,0 +1,182 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrUnavailable is returned instead of querying while the circuit breaker
+// is open.
+var ErrUnavailable = errors.New("database unavailable")
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// CircuitBreaker opens after threshold consecutive connection failures and
+// rejects work for cooldown. After that it lets requests through again and
+// the next success closes it, while the next failure reopens it.
+type CircuitBreaker struct {
+	mutex     sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	open      bool
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.state()
+}
+
+func (b *CircuitBreaker) state() BreakerState {
+	switch {
+	case !b.open:
+		return BreakerClosed
+	case time.Since(b.openedAt) >= b.cooldown:
+		return BreakerHalfOpen
+	default:
+		return BreakerOpen
+	}
+}
+
+// Allow returns ErrUnavailable while the breaker is open.
+func (b *CircuitBreaker) Allow() error {
+	if b.State() == BreakerOpen {
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// RetryAfter is the time left until the breaker lets requests through again,
+// rounded up to a whole second.
+func (b *CircuitBreaker) RetryAfter() time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.state() != BreakerOpen {
+		return 0
+	}
+	remaining := b.cooldown - time.Since(b.openedAt)
+	return (remaining + time.Second - 1).Truncate(time.Second)
+}
+
+func (b *CircuitBreaker) Success() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.failures = 0
+	b.open = false
+}
+
+func (b *CircuitBreaker) Failure() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.failures++
+	if b.state() == BreakerHalfOpen || b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = time.Now()
+	}
+}
+
+// Record feeds the outcome of a database call to the breaker. Only
+// connection-level errors count as failures; a query error or sql.ErrNoRows
+// proves the database is reachable.
+func (b *CircuitBreaker) Record(err error) {
+	if IsTransientError(err) {
+		b.Failure()
+	} else {
+		b.Success()
+	}
+}
+
+// IsTransientError reports whether err means the connection to PostgreSQL
+// failed, as opposed to the query itself being rejected.
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// Class 08 is connection exception; 57P01-57P03 are shutdowns and
+		// "cannot connect now" during startup or recovery.
+		return pqErr.Code.Class() == "08" || pqErr.Code == "57P01" || pqErr.Code == "57P02" || pqErr.Code == "57P03"
+	}
+	return false
+}
+
+// ReadPolicy retries idempotent reads that fail with a transient error,
+// doubling the backoff after each attempt.
+type ReadPolicy struct {
+	Retries int
+	Backoff time.Duration
+	Breaker *CircuitBreaker
+}
+
+// Resilience is used by repositories for reads; ConfigureResilience replaces
+// it at startup.
+var Resilience = &ReadPolicy{Retries: 2, Backoff: 100 * time.Millisecond, Breaker: NewCircuitBreaker(5, 30*time.Second)}
+
+func ConfigureResilience(retries int, backoff time.Duration, breakerThreshold int, breakerCooldown time.Duration) {
+	Resilience = &ReadPolicy{
+		Retries: retries,
+		Backoff: backoff,
+		Breaker: NewCircuitBreaker(breakerThreshold, breakerCooldown),
+	}
+}
+
+// Read runs fn, which must only read, and retries it on transient errors.
+// It fails fast with ErrUnavailable while the breaker is open.
+func (p *ReadPolicy) Read(fn func() error) error {
+	if err := p.Breaker.Allow(); err != nil {
+		return err
+	}
+
+	backoff := p.Backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		p.Breaker.Record(err)
+		if !IsTransientError(err) || attempt >= p.Retries || p.Breaker.Allow() != nil {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// RetryRead runs fn with the current Resilience policy.
+func RetryRead(fn func() error) error {
+	return Resilience.Read(fn)
+}
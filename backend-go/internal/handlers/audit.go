@@ -0,0 +1,44 @@
+﻿package handlers
+import (
+	"net/http"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/services"
+	"ecommerce-backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+func NewAuditHandler(auditService *services.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+func (h *AuditHandler) GetAuditLog(c *gin.Context) {
+	var query models.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entries, total, err := h.auditService.List(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get audit log"})
+		return
+	}
+	utils.PaginatedResponse(c, entries, int64(total), query.Page, query.Limit)
+}
+// AuditEntry builds an audit entry attributed to the authenticated caller.
+// Under impersonation the admin behind the token is the actor.
+func AuditEntry(c *gin.Context, action, targetType, targetID string) models.AuditEntry {
+	entry := models.AuditEntry{
+		ActorIP:    c.ClientIP(),
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+	}
+	if userID := c.GetString("user_id"); userID != "" {
+		entry.ActorID = &userID
+	}
+	if adminID := c.GetString("impersonator_id"); adminID != "" {
+		entry.ActorID = &adminID
+	}
+	return entry
+}
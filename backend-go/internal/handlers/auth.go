@@ -6,34 +6,68 @@ import (
 	"ecommerce-backend/internal/services"
 	"ecommerce-backend/internal/utils"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthHandler struct {
-	userService *services.UserService
-	config      *config.AppConfig
+	userService    *services.UserService
+	accountService *services.AccountService
+	auditService   *services.AuditService
+	captchaService *services.CaptchaService
+	emailDomains   *services.EmailDomainPolicy
+	config         *config.AppConfig
 }
 
-func NewAuthHandler(userService *services.UserService, cfg *config.AppConfig) *AuthHandler {
+func NewAuthHandler(userService *services.UserService, accountService *services.AccountService, auditService *services.AuditService, captchaService *services.CaptchaService, emailDomains *services.EmailDomainPolicy, cfg *config.AppConfig) *AuthHandler {
 	return &AuthHandler{
-		userService: userService,
-		config:      cfg,
+		userService:    userService,
+		accountService: accountService,
+		auditService:   auditService,
+		captchaService: captchaService,
+		emailDomains:   emailDomains,
+		config:         cfg,
 	}
 }
 
 func (h *AuthHandler) Register(c *gin.Context) {
+	if limit := h.config.Register.RateLimit; limit > 0 {
+		count, resetAt := utils.GetCache("registration").Increment("ip:"+c.ClientIP(), h.config.Register.RateWindow)
+		if count > limit {
+			retryAfter := int(time.Until(resetAt).Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Too many registration attempts, please try again later",
+				"retry_after": retryAfter,
+			})
+			return
+		}
+	}
 	var req models.UserCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := h.captchaService.Verify(req.CaptchaToken, c.ClientIP()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "captcha_failed"})
+		return
+	}
+	if err := h.emailDomains.Check(req.Email); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": "email_domain_blocked"})
+		return
+	}
 	user, err := h.userService.CreateUser(req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if err.Error() == "email already registered" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
-	token, err := utils.GenerateJWT(user.ID, user.Email, user.Role)
+	token, err := utils.GenerateJWT(user.ID, user.Email, user.Role, "")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
@@ -50,16 +84,16 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	user, err := h.userService.GetUserByEmail(req.Email)
+	user, err := h.userService.Authenticate(req.Email, req.Password)
 	if err != nil {
+		h.auditService.Record(AuditEntry(c, models.AuditActionLoginFailed, "user", req.Email), nil, nil)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
-	if err := h.userService.VerifyPassword(user.Password, req.Password); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
-		return
-	}
-	token, err := utils.GenerateJWT(user.ID, user.Email, user.Role)
+	entry := AuditEntry(c, models.AuditActionLogin, "user", user.ID)
+	entry.ActorID = &user.ID
+	h.auditService.Record(entry, nil, nil)
+	token, err := utils.GenerateJWT(user.ID, user.Email, user.Role, tokenStore(user))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
@@ -105,6 +139,10 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 	}
 	user, err := h.userService.UpdateUser(userID, updates)
 	if err != nil {
+		if err.Error() == "email already registered" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -113,3 +151,183 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 		"user":    user,
 	})
 }
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	var req models.AccountDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.accountService.RequestErasure(c.GetString("user_id"), req.Password, utils.GetRequestID(c))
+	if err != nil {
+		switch err.Error() {
+		case "invalid credentials":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, resp)
+}
+func (h *AuthHandler) RequestDataExport(c *gin.Context) {
+	export, err := h.accountService.RequestExport(c.GetString("user_id"), utils.GetRequestID(c))
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		case "export already requested in the last 24 hours":
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Data export can only be requested once per day"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request data export"})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Data export requested successfully; a download link will be sent by email",
+		"export":  export,
+	})
+}
+
+func (h *AuthHandler) DownloadDataExport(c *gin.Context) {
+	path, err := h.accountService.GetExportFile(c.Param("token"))
+	if err != nil {
+		if err.Error() == "export expired" {
+			c.JSON(http.StatusGone, gin.H{"error": "Export link has expired"})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+	c.FileAttachment(path, "account-export.json")
+}
+func (h *AuthHandler) UpdateUserRole(c *gin.Context) {
+	var req models.UserRoleUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	targetID := c.Param("id")
+	user, previousRole, err := h.userService.UpdateRole(targetID, req.Role)
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user role"})
+		return
+	}
+	h.auditService.Record(AuditEntry(c, models.AuditActionRoleChange, "user", targetID),
+		gin.H{"role": previousRole}, gin.H{"role": user.Role})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User role updated successfully",
+		"user":    user,
+	})
+}
+// UpdateUserStore pins a user to a store. Tokens issued to them afterwards
+// carry the store claim, so they can only act on that store. Admins who are
+// themselves pinned cannot move users between stores.
+func (h *AuthHandler) UpdateUserStore(c *gin.Context) {
+	var req models.UserStoreUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	actor, err := h.userService.GetUserByID(c.GetString("user_id"))
+	if err != nil || actor.StoreID != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Store admins cannot assign stores"})
+		return
+	}
+	targetID := c.Param("id")
+	user, previousStore, err := h.userService.UpdateStore(targetID, req.StoreID)
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		case "store not found":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Store not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user store"})
+		}
+		return
+	}
+	h.auditService.Record(AuditEntry(c, models.AuditActionStoreAssign, "user", targetID),
+		gin.H{"store_id": previousStore}, gin.H{"store_id": user.StoreID})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User store updated successfully",
+		"user":    user,
+	})
+}
+// tokenStore is the store claim issued to user: their pinned store, or ""
+// when they are not tied to one.
+func tokenStore(user *models.User) string {
+	if user.StoreID == nil {
+		return ""
+	}
+	return *user.StoreID
+}
+// ImpersonateUser issues a short-lived token that lets a support admin see
+// the shop as the given user.
+func (h *AuthHandler) ImpersonateUser(c *gin.Context) {
+	adminID := c.GetString("user_id")
+	targetID := c.Param("id")
+	if targetID == adminID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot impersonate yourself"})
+		return
+	}
+	user, err := h.userService.GetUserByID(targetID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.Role == "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot impersonate an admin"})
+		return
+	}
+	admin, err := h.userService.GetUserByID(adminID)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+	// The session stays in the admin's store.
+	token, expiresAt, err := utils.GenerateImpersonationJWT(user.ID, user.Email, user.Role, adminID, tokenStore(admin), h.config.JWT.ImpersonationTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	h.auditService.Record(AuditEntry(c, models.AuditActionImpersonateStart, "user", user.ID),
+		nil, gin.H{"expires_at": expiresAt})
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Impersonation started successfully",
+		"token":         token,
+		"expires_at":    expiresAt,
+		"impersonating": true,
+		"user":          user.ToResponse(),
+	})
+}
+// EndImpersonation closes an impersonation session and hands the admin a
+// fresh token for their own account.
+func (h *AuthHandler) EndImpersonation(c *gin.Context) {
+	adminID := c.GetString("impersonator_id")
+	if adminID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Not impersonating a user"})
+		return
+	}
+	admin, err := h.userService.GetUserByID(adminID)
+	if err != nil || admin.Role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+	h.auditService.Record(AuditEntry(c, models.AuditActionImpersonateEnd, "user", c.GetString("user_id")), nil, nil)
+	token, err := utils.GenerateJWT(admin.ID, admin.Email, admin.Role, tokenStore(admin))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, models.AuthResponse{
+		Message: "Impersonation ended successfully",
+		User:    admin.ToResponse(),
+		Token:   token,
+	})
+}
@@ -0,0 +1,72 @@
+﻿package handlers
+import (
+	"net/http"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+type BundleHandler struct {
+	bundleService *services.BundleService
+}
+func NewBundleHandler(bundleService *services.BundleService) *BundleHandler {
+	return &BundleHandler{bundleService: bundleService}
+}
+func (h *BundleHandler) GetBundle(c *gin.Context) {
+	bundle, err := h.bundleService.GetBundle(c.Param("id"), c.GetString("store_id"))
+	if err != nil {
+		switch err.Error() {
+		case "product not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		case "bundle not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product is not a bundle"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get bundle"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bundle retrieved successfully",
+		"bundle":  bundle,
+	})
+}
+// SetBundle replaces the components of a bundle; a plain product becomes a
+// bundle the first time it is given components.
+func (h *BundleHandler) SetBundle(c *gin.Context) {
+	var req models.BundleSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	bundle, err := h.bundleService.SetBundle(c.Param("id"), c.GetString("store_id"), req)
+	if err != nil {
+		switch err.Error() {
+		case "product not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		case "bundle component not found":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Every component must be a product of the same store"})
+		case "a bundle must be sold per item", "a bundle cannot contain itself", "bundle component listed more than once", "bundles cannot contain bundles":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save bundle"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bundle saved successfully",
+		"bundle":  bundle,
+	})
+}
+func (h *BundleHandler) RemoveBundle(c *gin.Context) {
+	if err := h.bundleService.RemoveBundle(c.Param("id"), c.GetString("store_id")); err != nil {
+		switch err.Error() {
+		case "product not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		case "bundle not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product is not a bundle"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove bundle"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Bundle removed successfully"})
+}
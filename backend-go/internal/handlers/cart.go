@@ -1,6 +1,7 @@
 ﻿package handlers
 import (
 	"net/http"
+	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/services"
 	"github.com/gin-gonic/gin"
 )
@@ -12,27 +13,16 @@ func NewCartHandler(cartService *services.CartService) *CartHandler {
 }
 func (h *CartHandler) GetCart(c *gin.Context) {
 	userID := c.GetString("user_id")
-	items, err := h.cartService.GetCartItems(userID)
+	cart, err := h.cartService.GetCart(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cart items"})
 		return
 	}
-	total, err := h.cartService.GetCartTotal(userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate total"})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{
-		"items": items,
-		"total": total,
-	})
+	c.JSON(http.StatusOK, cart)
 }
 func (h *CartHandler) AddToCart(c *gin.Context) {
 	userID := c.GetString("user_id")
-	var req struct {
-		ProductID string `json:"product_id" binding:"required"`
-		Quantity  int    `json:"quantity" binding:"required,min=1"`
-	}
+	var req models.CartItemRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -50,21 +40,37 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 func (h *CartHandler) UpdateCartItem(c *gin.Context) {
 	userID := c.GetString("user_id")
 	itemID := c.Param("id")
-	var req struct {
-		Quantity int `json:"quantity" binding:"required,min=0"`
-	}
+	var req models.CartItemUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	item, err := h.cartService.UpdateCartItem(userID, itemID, req.Quantity)
+	item, err := h.cartService.UpdateCartItem(userID, itemID, req.Quantity, req.Version)
 	if err != nil {
+		if err.Error() == "cart version conflict" {
+			cart, cartErr := h.cartService.GetCart(userID)
+			if cartErr != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cart items"})
+				return
+			}
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Cart was changed on another device",
+				"cart":  cart,
+			})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	cart, err := h.cartService.GetCart(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cart items"})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Cart item updated",
 		"item":    item,
+		"cart":    cart,
 	})
 }
 func (h *CartHandler) RemoveFromCart(c *gin.Context) {
@@ -80,6 +86,35 @@ func (h *CartHandler) RemoveFromCart(c *gin.Context) {
 		"item":    item,
 	})
 }
+func (h *CartHandler) EstimateShipping(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req models.ShippingEstimateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	estimate, err := h.cartService.EstimateShipping(userID, req)
+	if err != nil {
+		if err.Error() == "cart is empty" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cart is empty"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to estimate shipping"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Shipping estimated successfully",
+		"estimate": estimate,
+	})
+}
+func (h *CartHandler) AcknowledgeNotices(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if err := h.cartService.AcknowledgeNotices(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge cart notices"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cart notices acknowledged successfully"})
+}
 func (h *CartHandler) ClearCart(c *gin.Context) {
 	userID := c.GetString("user_id")
 	if err := h.cartService.ClearCart(userID); err != nil {
@@ -2,6 +2,7 @@
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/services"
 	"github.com/gin-gonic/gin"
@@ -25,13 +26,21 @@ func (h *CategoryHandler) GetCategories(c *gin.Context) {
 		limit = 20
 	}
 	includeProductsBool := includeProducts == "true"
-	categories, total, err := h.categoryService.GetCategories(page, limit, includeProductsBool)
+	locale := h.resolveLocale(c)
+	var categories []models.CategoryWithProducts
+	var total int
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		categories, total, err = h.categoryService.SearchCategories(c.GetString("store_id"), q, page, limit, locale)
+	} else {
+		categories, total, err = h.categoryService.GetCategories(c.GetString("store_id"), page, limit, includeProductsBool, locale)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get categories"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Categories retrieved successfully",
+		"locale":     locale,
 		"categories": categories,
 		"pagination": gin.H{
 			"page":        page,
@@ -49,23 +58,46 @@ func (h *CategoryHandler) GetCategory(c *gin.Context) {
 	}
 	includeProducts := c.DefaultQuery("include_products", "true")
 	includeProductsBool := includeProducts == "true"
-	category, err := h.categoryService.GetCategoryBySlug(slug, includeProductsBool)
+	locale := h.resolveLocale(c)
+	category, err := h.categoryService.GetCategoryBySlug(c.GetString("store_id"), slug, includeProductsBool, locale)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"message":  "Category retrieved successfully",
+		"locale":   locale,
 		"category": category,
 	})
 }
+func (h *CategoryHandler) GetCategoryStats(c *gin.Context) {
+	stats, err := h.categoryService.GetCategoryStats(c.GetString("store_id"), c.Param("slug"))
+	if err != nil {
+		if err.Error() == "category not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get category stats"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Category stats retrieved successfully",
+		"stats":   stats,
+	})
+}
+func (h *CategoryHandler) resolveLocale(c *gin.Context) string {
+	locale := h.categoryService.ResolveLocale(c.Query("lang"), c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", locale)
+	c.Writer.Header().Add("Vary", "Accept-Language")
+	return locale
+}
 func (h *CategoryHandler) CreateCategory(c *gin.Context) {
 	var req models.CategoryCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	category, err := h.categoryService.CreateCategory(req)
+	category, err := h.categoryService.CreateCategory(c.GetString("store_id"), req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
 		return
@@ -86,9 +118,19 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	category, err := h.categoryService.UpdateCategory(slug, req)
+	category, err := h.categoryService.UpdateCategory(c.GetString("store_id"), slug, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+		switch err.Error() {
+		case "category version conflict":
+			c.JSON(http.StatusConflict, gin.H{
+				"error":    "Category was changed by someone else",
+				"category": category,
+			})
+		case "category not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+		}
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -102,7 +144,7 @@ func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Category slug is required"})
 		return
 	}
-	err := h.categoryService.DeleteCategory(slug)
+	err := h.categoryService.DeleteCategory(c.GetString("store_id"), slug)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
 		return
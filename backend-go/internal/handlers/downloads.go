@@ -0,0 +1,56 @@
+﻿package handlers
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"ecommerce-backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+type DownloadHandler struct {
+	uploadPath      string
+	downloadService *services.DownloadService
+}
+func NewDownloadHandler(uploadPath string, downloadService *services.DownloadService) *DownloadHandler {
+	return &DownloadHandler{uploadPath: uploadPath, downloadService: downloadService}
+}
+func (h *DownloadHandler) GetOrderDownloads(c *gin.Context) {
+	downloads, err := h.downloadService.GetOrderDownloads(c.Param("id"), c.GetString("user_id"), c.GetString("user_role"))
+	if err != nil {
+		switch err.Error() {
+		case "order not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		case "order is not paid":
+			c.JSON(http.StatusConflict, gin.H{"error": "Downloads are available once the order is paid"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get downloads"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Downloads retrieved successfully",
+		"downloads": downloads,
+	})
+}
+func (h *DownloadHandler) Download(c *gin.Context) {
+	download, err := h.downloadService.Consume(c.Param("token"))
+	if err != nil {
+		switch err.Error() {
+		case "download not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Download not found"})
+		case "download expired":
+			c.JSON(http.StatusGone, gin.H{"error": "Download link has expired"})
+		case "download limit reached":
+			c.JSON(http.StatusGone, gin.H{"error": "Download limit reached"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to download file"})
+		}
+		return
+	}
+	path := filepath.Join(h.uploadPath, filepath.Base(download.File))
+	if _, err := os.Stat(path); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	c.Header("Cache-Control", "private, no-store")
+	c.FileAttachment(path, download.ProductName+filepath.Ext(download.File))
+}
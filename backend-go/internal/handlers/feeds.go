@@ -0,0 +1,50 @@
+﻿package handlers
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"ecommerce-backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+type FeedHandler struct {
+	feedService *services.FeedService
+}
+func NewFeedHandler(feedService *services.FeedService) *FeedHandler {
+	return &FeedHandler{feedService: feedService}
+}
+func (h *FeedHandler) GetSitemap(c *gin.Context) {
+	sitemap, err := h.feedService.Sitemap(c.GetString("store_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate sitemap"})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.XML(http.StatusOK, sitemap)
+}
+func (h *FeedHandler) GetSitemapPage(c *gin.Context) {
+	page, err := strconv.Atoi(strings.TrimSuffix(c.Param("page"), ".xml"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sitemap page not found"})
+		return
+	}
+	sitemap, err := h.feedService.SitemapPage(c.GetString("store_id"), page)
+	if err != nil {
+		if err.Error() == "sitemap page not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Sitemap page not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate sitemap"})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.XML(http.StatusOK, sitemap)
+}
+func (h *FeedHandler) GetProductFeed(c *gin.Context) {
+	feed, err := h.feedService.ProductFeed(c.GetString("store_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate product feed"})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.XML(http.StatusOK, feed)
+}
@@ -0,0 +1,44 @@
+﻿package handlers
+import (
+	"net/http"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/services"
+	"ecommerce-backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+type InventoryHandler struct {
+	inventoryService *services.InventoryService
+}
+func NewInventoryHandler(inventoryService *services.InventoryService) *InventoryHandler {
+	return &InventoryHandler{inventoryService: inventoryService}
+}
+func (h *InventoryHandler) GetInventoryHistory(c *gin.Context) {
+	var query models.InventoryHistoryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	movements, total, err := h.inventoryService.GetHistory(c.GetString("store_id"), c.Param("id"), query)
+	if err != nil {
+		if err.Error() == "product not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get inventory history"})
+		return
+	}
+	utils.PaginatedResponse(c, movements, int64(total), query.Page, query.Limit)
+}
+// GetInventoryReport reconciles stock against the ledger; ?mismatched=true
+// lists only the products that disagree.
+func (h *InventoryHandler) GetInventoryReport(c *gin.Context) {
+	report, err := h.inventoryService.Reconcile(c.GetString("store_id"), c.Query("mismatched") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile inventory"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Inventory report generated successfully",
+		"report":  report,
+	})
+}
@@ -0,0 +1,43 @@
+﻿package handlers
+import (
+	"net/http"
+	"ecommerce-backend/internal/services"
+	"ecommerce-backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+type JobHandler struct {
+	seedService *services.SeedService
+}
+func NewJobHandler(seedService *services.SeedService) *JobHandler {
+	return &JobHandler{seedService: seedService}
+}
+// StartSeed queues a seed run and returns 202 with the job to poll.
+func (h *JobHandler) StartSeed(c *gin.Context) {
+	seedType := c.DefaultQuery("type", services.SeedAll)
+	job, err := h.seedService.Start(seedType, utils.GetRequestID(c))
+	if err != nil {
+		switch err.Error() {
+		case "unknown seed type":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown seed type", "type": seedType})
+		case "seed already running":
+			c.JSON(http.StatusConflict, gin.H{"error": "A seed of this type is already running", "type": seedType})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start seed"})
+		}
+		return
+	}
+	c.Header("Location", "jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Seed queued successfully",
+		"type":    seedType,
+		"job":     job,
+	})
+}
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, ok := h.seedService.GetJob(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
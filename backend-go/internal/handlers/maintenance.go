@@ -0,0 +1,68 @@
+﻿package handlers
+import (
+	"net/http"
+	"strings"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+type MaintenanceHandler struct {
+	maintenanceService *services.MaintenanceService
+}
+func NewMaintenanceHandler(maintenanceService *services.MaintenanceService) *MaintenanceHandler {
+	return &MaintenanceHandler{maintenanceService: maintenanceService}
+}
+// ListWindows returns scheduled and active windows; ?all=true includes
+// completed and cancelled ones.
+func (h *MaintenanceHandler) ListWindows(c *gin.Context) {
+	windows, err := h.maintenanceService.List(c.Query("all") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get maintenance windows"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Maintenance windows retrieved successfully",
+		"windows": windows,
+	})
+}
+func (h *MaintenanceHandler) ScheduleWindow(c *gin.Context) {
+	var req models.MaintenanceWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	window, err := h.maintenanceService.Schedule(c.GetString("user_id"), req)
+	if err != nil {
+		switch err.Error() {
+		case "message is required", "maintenance window must end after it starts", "maintenance window is already over":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "maintenance window overlaps another window":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule maintenance window"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Maintenance window scheduled successfully",
+		"window":  window,
+	})
+}
+func (h *MaintenanceHandler) CancelWindow(c *gin.Context) {
+	window, err := h.maintenanceService.Cancel(c.Param("id"))
+	if err != nil {
+		switch {
+		case err.Error() == "maintenance window not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Maintenance window not found"})
+		case strings.HasPrefix(err.Error(), "maintenance window is already"), err.Error() == "maintenance window changed, try again":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel maintenance window"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Maintenance window cancelled successfully",
+		"window":  window,
+	})
+}
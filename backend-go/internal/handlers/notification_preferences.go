@@ -0,0 +1,45 @@
+﻿package handlers
+import (
+	"net/http"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+type NotificationPreferenceHandler struct {
+	preferenceService *services.NotificationPreferenceService
+}
+func NewNotificationPreferenceHandler(preferenceService *services.NotificationPreferenceService) *NotificationPreferenceHandler {
+	return &NotificationPreferenceHandler{preferenceService: preferenceService}
+}
+func (h *NotificationPreferenceHandler) GetPreferences(c *gin.Context) {
+	prefs, err := h.preferenceService.Get(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notification preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Notification preferences retrieved successfully",
+		"preferences": prefs,
+	})
+}
+func (h *NotificationPreferenceHandler) UpdatePreferences(c *gin.Context) {
+	var req models.NotificationPreferences
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	prefs, err := h.preferenceService.Update(c.GetString("user_id"), req)
+	if err != nil {
+		switch err.Error() {
+		case "unknown notification channel", "unknown notification event", "order notifications cannot be disabled":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification preferences"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Notification preferences updated successfully",
+		"preferences": prefs,
+	})
+}
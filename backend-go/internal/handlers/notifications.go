@@ -0,0 +1,55 @@
+﻿package handlers
+import (
+	"net/http"
+	"strconv"
+	"ecommerce-backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+}
+func NewNotificationHandler(notificationService *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+	unreadOnly := c.Query("unread") == "true"
+	notifications, total, unread, err := h.notificationService.GetUserNotifications(c.GetString("user_id"), unreadOnly, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Notifications retrieved successfully",
+		"notifications": notifications,
+		"unread_count":  unread,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + limit - 1) / limit,
+		},
+	})
+}
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	notification, err := h.notificationService.MarkRead(c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		if err.Error() == "notification not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notification as read"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Notification marked as read",
+		"notification": notification,
+	})
+}
@@ -2,15 +2,21 @@
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/services"
+	"ecommerce-backend/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 type OrderHandler struct {
-	orderService *services.OrderService
+	orderService    *services.OrderService
+	shipmentService *services.ShipmentService
+	returnService   *services.ReturnService
+	auditService    *services.AuditService
 }
-func NewOrderHandler(orderService *services.OrderService) *OrderHandler {
-	return &OrderHandler{orderService: orderService}
+func NewOrderHandler(orderService *services.OrderService, shipmentService *services.ShipmentService, returnService *services.ReturnService, auditService *services.AuditService) *OrderHandler {
+	return &OrderHandler{orderService: orderService, shipmentService: shipmentService, returnService: returnService, auditService: auditService}
 }
 func (h *OrderHandler) GetOrders(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -28,7 +34,7 @@ func (h *OrderHandler) GetOrders(c *gin.Context) {
 	if err != nil || limit < 1 || limit > 100 {
 		limit = 10
 	}
-	orders, total, err := h.orderService.GetUserOrders(userID, page, limit)
+	orders, total, err := h.orderService.GetUserOrders(userID, c.GetString("store_id"), page, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get orders"})
 		return
@@ -55,7 +61,13 @@ func (h *OrderHandler) GetOrder(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
-	order, err := h.orderService.GetOrderByID(orderID, userID)
+	var order *models.OrderWithItems
+	var err error
+	if c.GetString("user_role") == "admin" {
+		order, err = h.orderService.GetOrderForAdmin(orderID, c.GetString("store_id"))
+	} else {
+		order, err = h.orderService.GetOrderByID(orderID, userID, c.GetString("store_id"))
+	}
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
 		return
@@ -76,8 +88,36 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	order, err := h.orderService.CreateOrder(userID, req)
+	req.RequestID = utils.GetRequestID(c)
+	order, err := h.orderService.CreateOrder(userID, c.GetString("store_id"), req)
 	if err != nil {
+		if err.Error() == "order below minimum amount" {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":            "Order subtotal is below the minimum order amount",
+				"min_order_amount": h.orderService.MinOrderAmount(),
+			})
+			return
+		}
+		if err.Error() == "shipping address is required" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Shipping address is required for physical products"})
+			return
+		}
+		if err.Error() == "quantity must be a whole number" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cart contains a fractional quantity of a product sold by the piece"})
+			return
+		}
+		if err.Error() == "unsupported shipping method" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Shipping method is not available for this destination"})
+			return
+		}
+		if err.Error() == "cart changed during checkout" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Your cart changed while the order was being placed; please review it and try again"})
+			return
+		}
+		if err.Error() == "insufficient stock" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Some items in your cart are no longer in stock; please review it and try again"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
 		return
 	}
@@ -97,16 +137,72 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	order, err := h.orderService.UpdateOrderStatus(orderID, *req.Status)
+	if req.Status == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status is required"})
+		return
+	}
+	order, previousStatus, err := h.orderService.UpdateOrderStatus(orderID, *req.Status)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+		switch {
+		case err.Error() == "order not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		case err.Error() == "invalid order status":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case strings.HasPrefix(err.Error(), "cannot change status"):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+		}
 		return
 	}
+	h.auditService.Record(AuditEntry(c, models.AuditActionOrderStatusChange, "order", orderID),
+		gin.H{"status": previousStatus}, gin.H{"status": order.Status})
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Order status updated successfully",
 		"order":   order,
 	})
 }
+func (h *OrderHandler) BulkUpdateOrderStatus(c *gin.Context) {
+	var req models.BulkOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	results, err := h.orderService.BulkUpdateStatus(req)
+	if err != nil {
+		if err.Error() == "invalid order status" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order statuses"})
+		return
+	}
+	applied, failed := 0, 0
+	for _, result := range results {
+		if !result.Applied {
+			failed++
+			continue
+		}
+		applied++
+		h.auditService.Record(AuditEntry(c, models.AuditActionOrderStatusChange, "order", result.OrderID),
+			gin.H{"status": result.PreviousStatus}, gin.H{"status": result.Status, "bulk": true})
+	}
+	if req.Atomic && failed > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Bulk status update rejected; no orders were changed",
+			"applied": 0,
+			"failed":  failed,
+			"results": results,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order statuses updated successfully",
+		"applied": applied,
+		"failed":  failed,
+		"results": results,
+	})
+}
 func (h *OrderHandler) CancelOrder(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
@@ -126,4 +222,196 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Order cancelled successfully",
 	})
-}
\ No newline at end of file
+}
+// ResendConfirmation emails the order confirmation to the customer again.
+func (h *OrderHandler) ResendConfirmation(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	resetAt, err := h.orderService.ResendConfirmation(c.Param("id"), userID, c.GetString("store_id"), c.GetString("user_role") == "admin")
+	if err != nil {
+		switch err.Error() {
+		case "order not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		case "order has no confirmation to send":
+			c.JSON(http.StatusConflict, gin.H{"error": "Cancelled orders have no confirmation to send"})
+		case "confirmation resend limit reached":
+			retryAfter := int(time.Until(resetAt).Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "The confirmation for this order was re-sent too often, please try again later",
+				"retry_after": retryAfter,
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send order confirmation"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order confirmation sent successfully",
+	})
+}
+func (h *OrderHandler) GetInternalNotes(c *gin.Context) {
+	orderID := c.Param("id")
+	notes, err := h.orderService.GetInternalNotes(orderID)
+	if err != nil {
+		if err.Error() == "order not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get internal notes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Internal notes retrieved successfully",
+		"notes":   notes,
+	})
+}
+func (h *OrderHandler) AddInternalNote(c *gin.Context) {
+	orderID := c.Param("id")
+	var req models.OrderNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	note, err := h.orderService.AddInternalNote(orderID, c.GetString("user_id"), req)
+	if err != nil {
+		if err.Error() == "order not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add internal note"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Internal note added successfully",
+		"note":    note,
+	})
+}
+func (h *OrderHandler) CreateShipment(c *gin.Context) {
+	orderID := c.Param("id")
+	var req models.ShipmentCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	shipment, err := h.shipmentService.CreateShipment(orderID, req)
+	if err != nil {
+		if err.Error() == "order not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Shipment created successfully",
+		"shipment": shipment,
+	})
+}
+func (h *OrderHandler) UpdateShipment(c *gin.Context) {
+	orderID := c.Param("id")
+	shipmentID := c.Param("shipmentId")
+	var req models.ShipmentUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	shipment, err := h.shipmentService.UpdateShipment(orderID, shipmentID, req)
+	if err != nil {
+		switch err.Error() {
+		case "order not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		case "shipment not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update shipment"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Shipment updated successfully",
+		"shipment": shipment,
+	})
+}
+func (h *OrderHandler) GetReturns(c *gin.Context) {
+	returns, err := h.returnService.GetReturns(c.Param("id"), c.GetString("user_id"), c.GetString("user_role"))
+	if err != nil {
+		if err.Error() == "order not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get returns"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Returns retrieved successfully",
+		"returns": returns,
+	})
+}
+func (h *OrderHandler) RequestReturn(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	var req models.ReturnCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ret, err := h.returnService.RequestReturn(userID, c.Param("id"), req)
+	if err != nil {
+		if err.Error() == "order not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Return requested successfully",
+		"return":  ret,
+	})
+}
+func (h *OrderHandler) ApproveReturn(c *gin.Context) {
+	h.transitionReturn(c, h.returnService.ApproveReturn, "Return approved successfully")
+}
+func (h *OrderHandler) RejectReturn(c *gin.Context) {
+	h.transitionReturn(c, h.returnService.RejectReturn, "Return rejected successfully")
+}
+func (h *OrderHandler) ReceiveReturn(c *gin.Context) {
+	ret := h.transitionReturn(c, h.returnService.ReceiveReturn, "Return received successfully")
+	if ret != nil && ret.RefundID != nil {
+		h.auditService.Record(AuditEntry(c, models.AuditActionRefund, "order", ret.OrderID), nil,
+			gin.H{"return_id": ret.ID, "refund_id": *ret.RefundID, "amount": ret.RefundAmount})
+	}
+}
+func (h *OrderHandler) transitionReturn(c *gin.Context, fn func(orderID, returnID string, req models.ReturnDecisionRequest) (*models.OrderReturn, error), message string) *models.OrderReturn {
+	var req models.ReturnDecisionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil
+		}
+	}
+	ret, err := fn(c.Param("id"), c.Param("returnId"), req)
+	if err != nil {
+		switch err.Error() {
+		case "order not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		case "return not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return nil
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"return":  ret,
+	})
+	return ret
+}
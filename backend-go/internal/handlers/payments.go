@@ -1,8 +1,10 @@
 ﻿package handlers
 import (
 	"net/http"
+	"time"
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/services"
+	"ecommerce-backend/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 type PaymentHandler struct {
@@ -22,8 +24,20 @@ func (h *PaymentHandler) CreatePaymentIntent(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	req.RequestID = utils.GetRequestID(c)
 	paymentIntent, err := h.paymentService.CreatePaymentIntent(userID, req)
 	if err != nil {
+		switch err.Error() {
+		case "unknown payment provider":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown payment provider"})
+			return
+		case "order not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		case "order is not awaiting payment":
+			c.JSON(http.StatusConflict, gin.H{"error": "Order is not awaiting payment"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payment intent"})
 		return
 	}
@@ -59,24 +73,51 @@ func (h *PaymentHandler) GetPaymentHistory(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
-	payments, err := h.paymentService.GetUserPayments(userID)
+	var query models.PaymentHistoryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for name, target := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if value := c.Query(name); value != "" {
+			parsed, err := parseReportDate(value, name == "to")
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + " date"})
+				return
+			}
+			*target = parsed
+		}
+	}
+	payments, total, err := h.paymentService.GetPaymentHistory(userID, query)
 	if err != nil {
+		if err.Error() == "from must be before to" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get payment history"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"message":  "Payment history retrieved successfully",
-		"payments": payments,
-	})
+	utils.PaginatedResponse(c, payments, int64(total), query.Page, query.Limit)
 }
 func (h *PaymentHandler) HandleWebhook(c *gin.Context) {
-	var payload models.StripeWebhookPayload
-	if err := c.ShouldBindJSON(&payload); err != nil {
+	payload, err := c.GetRawData()
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook payload"})
 		return
 	}
-	err := h.paymentService.HandleWebhook(payload)
+	err = h.paymentService.HandleWebhook(c.Param("provider"), payload, c.Request.Header)
 	if err != nil {
+		switch err.Error() {
+		case "unknown payment provider":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown payment provider"})
+			return
+		case "invalid webhook signature":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook signature"})
+			return
+		case "invalid webhook payload":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook payload"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook"})
 		return
 	}
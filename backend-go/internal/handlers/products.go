@@ -1,16 +1,20 @@
 ﻿package handlers
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/services"
+	"ecommerce-backend/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 type ProductHandler struct {
-	productService *services.ProductService
+	productService        *services.ProductService
+	recommendationService *services.RecommendationService
 }
-func NewProductHandler(productService *services.ProductService) *ProductHandler {
-	return &ProductHandler{productService: productService}
+func NewProductHandler(productService *services.ProductService, recommendationService *services.RecommendationService) *ProductHandler {
+	return &ProductHandler{productService: productService, recommendationService: recommendationService}
 }
 func (h *ProductHandler) GetProducts(c *gin.Context) {
 	var query models.ProductQuery
@@ -18,12 +22,46 @@ func (h *ProductHandler) GetProducts(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	query.Locale = h.resolveLocale(c)
+	query.StoreID = c.GetString("store_id")
+	requestedLimit := query.Limit
+	if h.productService.ApplyQueryDefaults(&query) {
+		c.Header("X-Limit-Clamped", fmt.Sprintf("requested=%d, applied=%d", requestedLimit, query.Limit))
+	}
+	fields, err := utils.ParseFieldSelection(c.Query("fields"), models.ProductListFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	products, err := h.productService.GetProducts(query)
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "unknown facet") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get products"})
 		return
 	}
-	c.JSON(http.StatusOK, products)
+	if fields == nil {
+		c.JSON(http.StatusOK, products)
+		return
+	}
+	data := make([]interface{}, len(products.Data))
+	for i := range products.Data {
+		if data[i], err = utils.ProjectFields(products.Data[i], fields); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get products"})
+			return
+		}
+	}
+	response := gin.H{
+		"locale":     products.Locale,
+		"data":       data,
+		"pagination": products.Pagination,
+	}
+	if products.Facets != nil {
+		response["facets"] = products.Facets
+	}
+	c.JSON(http.StatusOK, response)
 }
 func (h *ProductHandler) GetProduct(c *gin.Context) {
 	id := c.Param("id")
@@ -31,14 +69,105 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Product ID is required"})
 		return
 	}
-	product, err := h.productService.GetProduct(id)
+	h.respondProduct(c, func(storeID, locale string) (*models.ProductWithCategory, error) {
+		return h.productService.GetProduct(storeID, id, locale)
+	})
+}
+func (h *ProductHandler) GetProductBySKU(c *gin.Context) {
+	sku := c.Param("sku")
+	h.respondProduct(c, func(storeID, locale string) (*models.ProductWithCategory, error) {
+		return h.productService.GetProductBySKU(storeID, sku, locale)
+	})
+}
+func (h *ProductHandler) GetProductByBarcode(c *gin.Context) {
+	barcode := c.Param("barcode")
+	h.respondProduct(c, func(storeID, locale string) (*models.ProductWithCategory, error) {
+		return h.productService.GetProductByBarcode(storeID, barcode, locale)
+	})
+}
+// respondProduct writes a single product in the store and locale of the
+// request, honouring fields=.
+func (h *ProductHandler) respondProduct(c *gin.Context, load func(storeID, locale string) (*models.ProductWithCategory, error)) {
+	fields, err := utils.ParseFieldSelection(c.Query("fields"), models.ProductDetailFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	locale := h.resolveLocale(c)
+	product, err := load(c.GetString("store_id"), locale)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
 	}
+	projected, err := utils.ProjectFields(product, fields)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get product"})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Product retrieved successfully",
-		"product": product,
+		"locale":  locale,
+		"product": projected,
+	})
+}
+// GetProductsBatch returns several products by id in request order, for
+// hydrating carts and orders without a request per product.
+func (h *ProductHandler) GetProductsBatch(c *gin.Context) {
+	var req models.ProductBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fields, err := utils.ParseFieldSelection(c.Query("fields"), models.ProductBatchFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	locale := h.resolveLocale(c)
+	batch, err := h.productService.GetProductsByIDs(c.GetString("store_id"), req.IDs, locale)
+	if err != nil {
+		if err.Error() == "too many product ids" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "max_ids": models.MaxProductBatchSize})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get products"})
+		return
+	}
+	products := make([]interface{}, len(batch.Products))
+	for i := range batch.Products {
+		if products[i], err = utils.ProjectFields(batch.Products[i], fields); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get products"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Products retrieved successfully",
+		"locale":   locale,
+		"products": products,
+		"missing":  batch.Missing,
+	})
+}
+// CheckStock returns the availability of several products at once so the
+// storefront can render add-to-cart buttons without a request per product.
+func (h *ProductHandler) CheckStock(c *gin.Context) {
+	var req models.StockCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := h.productService.CheckStock(c.GetString("store_id"), req.IDs)
+	if err != nil {
+		if err.Error() == "too many product ids" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "max_ids": models.MaxProductBatchSize})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check stock"})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Stock checked successfully",
+		"items":   items,
 	})
 }
 func (h *ProductHandler) GetFeaturedProducts(c *gin.Context) {
@@ -47,16 +176,49 @@ func (h *ProductHandler) GetFeaturedProducts(c *gin.Context) {
 	if err != nil {
 		limit = 10
 	}
-	products, err := h.productService.GetFeaturedProducts(limit)
+	locale := h.resolveLocale(c)
+	products, err := h.productService.GetFeaturedProducts(c.GetString("store_id"), limit, locale)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get featured products"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"message":  "Featured products retrieved successfully",
+		"locale":   locale,
 		"products": products,
 	})
 }
+// GetNewArrivals lists recently added, in-stock products, newest first.
+func (h *ProductHandler) GetNewArrivals(c *gin.Context) {
+	var query models.NewArrivalsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query.StoreID = c.GetString("store_id")
+	query.Locale = h.resolveLocale(c)
+	result, err := h.productService.GetNewArrivals(query)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "days must be") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get new arrivals"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "New arrivals retrieved successfully",
+		"locale":     result.Locale,
+		"products":   result.Data,
+		"pagination": result.Pagination,
+	})
+}
+func (h *ProductHandler) resolveLocale(c *gin.Context) string {
+	locale := h.productService.ResolveLocale(c.Query("lang"), c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", locale)
+	c.Writer.Header().Add("Vary", "Accept-Language")
+	return locale
+}
 func (h *ProductHandler) SearchProducts(c *gin.Context) {
 	query := c.Query("q")
 	if query == "" {
@@ -68,7 +230,7 @@ func (h *ProductHandler) SearchProducts(c *gin.Context) {
 	if err != nil {
 		limit = 20
 	}
-	products, err := h.productService.SearchProducts(query, limit)
+	products, err := h.productService.SearchProducts(c.GetString("store_id"), query, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search products"})
 		return
@@ -78,4 +240,168 @@ func (h *ProductHandler) SearchProducts(c *gin.Context) {
 		"query":    query,
 		"products": products,
 	})
-}
\ No newline at end of file
+}
+func (h *ProductHandler) GetFrequentlyBoughtTogether(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "6"))
+	if err != nil {
+		limit = 6
+	}
+	products, source, err := h.recommendationService.FrequentlyBoughtTogether(c.Param("id"), limit)
+	if err != nil {
+		if err.Error() == "product not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get recommendations"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Recommendations retrieved successfully",
+		"products": products,
+		"source":   source,
+	})
+}
+// UpdateProduct requires the version the editor loaded and answers 409 with
+// the current product when someone else saved in the meantime.
+func (h *ProductHandler) UpdateProduct(c *gin.Context) {
+	var req models.ProductUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	product, err := h.productService.UpdateProduct(c.GetString("store_id"), c.Param("id"), req, AuditEntry(c, models.AuditActionPriceChange, "product", c.Param("id")))
+	if err != nil {
+		switch err.Error() {
+		case "product version conflict":
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Product was changed by someone else",
+				"product": product,
+			})
+		case "product not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		case "sku already exists":
+			c.JSON(http.StatusConflict, gin.H{"error": "Another product already has this SKU"})
+		case "barcode already exists":
+			c.JSON(http.StatusConflict, gin.H{"error": "Another product already has this barcode"})
+		case "bundle stock is derived from its components":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bundle stock is derived from its components"})
+		case "digital file must be a private upload":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Digital file must be a private upload"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product updated successfully",
+		"product": product,
+	})
+}
+// MergeProducts folds duplicate products created by imports into one.
+func (h *ProductHandler) MergeProducts(c *gin.Context) {
+	var req models.ProductMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	result, err := h.productService.MergeProducts(c.GetString("store_id"), req, AuditEntry(c, models.AuditActionProductMerge, "product", req.PrimaryID))
+	if err != nil {
+		switch err.Error() {
+		case "cannot merge a product into itself", "products belong to different stores":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "product not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		case "duplicate product not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Duplicate product not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge products"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Products merged successfully",
+		"merge":   result,
+	})
+}
+func (h *ProductHandler) AddProductImage(c *gin.Context) {
+	var req models.ProductImageCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	product, err := h.productService.AddProductImage(c.GetString("store_id"), c.Param("id"), req)
+	if err != nil {
+		if err.Error() == "product not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add product image"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Product image added successfully",
+		"product": product,
+	})
+}
+func (h *ProductHandler) ReorderProductImages(c *gin.Context) {
+	var req models.ProductImageReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	product, err := h.productService.ReorderProductImages(c.GetString("store_id"), c.Param("id"), req.ImageIDs)
+	if err != nil {
+		switch err.Error() {
+		case "product not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		case "image list does not match product images":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Image ids must list every image of the product exactly once"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder product images"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product images reordered successfully",
+		"product": product,
+	})
+}
+func (h *ProductHandler) SetPrimaryProductImage(c *gin.Context) {
+	product, err := h.productService.SetPrimaryProductImage(c.GetString("store_id"), c.Param("id"), c.Param("imageId"))
+	if err != nil {
+		switch err.Error() {
+		case "product not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		case "image not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set primary image"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Primary image updated successfully",
+		"product": product,
+	})
+}
+func (h *ProductHandler) DeleteProductImage(c *gin.Context) {
+	product, err := h.productService.DeleteProductImage(c.GetString("store_id"), c.Param("id"), c.Param("imageId"))
+	if err != nil {
+		switch err.Error() {
+		case "product not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		case "image not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete product image"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product image deleted successfully",
+		"product": product,
+	})
+}
@@ -0,0 +1,178 @@
+﻿package handlers
+import (
+	"net/http"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/services"
+	"ecommerce-backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+type QuestionHandler struct {
+	questionService *services.QuestionService
+}
+func NewQuestionHandler(questionService *services.QuestionService) *QuestionHandler {
+	return &QuestionHandler{questionService: questionService}
+}
+func (h *QuestionHandler) GetProductQuestions(c *gin.Context) {
+	var query models.QuestionQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	questions, total, err := h.questionService.GetProductQuestions(c.Param("productId"), &query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get questions"})
+		return
+	}
+	utils.PaginatedResponse(c, questions, int64(total), query.Page, query.Limit)
+}
+func (h *QuestionHandler) CreateQuestion(c *gin.Context) {
+	var req models.QuestionCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	question, err := h.questionService.CreateQuestion(c.GetString("user_id"), c.GetString("user_role"), req)
+	if err != nil {
+		respondQAError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Question created successfully",
+		"question": question,
+	})
+}
+func (h *QuestionHandler) DeleteQuestion(c *gin.Context) {
+	if err := h.questionService.DeleteQuestion(c.GetString("user_id"), c.GetString("user_role"), c.Param("id")); err != nil {
+		respondQAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Question deleted successfully"})
+}
+func (h *QuestionHandler) CreateAnswer(c *gin.Context) {
+	var req models.AnswerCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	answer, err := h.questionService.CreateAnswer(c.GetString("user_id"), c.GetString("user_role"), c.Param("id"), req)
+	if err != nil {
+		respondQAError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Answer created successfully",
+		"answer":  answer,
+	})
+}
+func (h *QuestionHandler) DeleteAnswer(c *gin.Context) {
+	if err := h.questionService.DeleteAnswer(c.GetString("user_id"), c.GetString("user_role"), c.Param("id")); err != nil {
+		respondQAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Answer deleted successfully"})
+}
+func (h *QuestionHandler) UpvoteQuestion(c *gin.Context) {
+	h.vote(c, models.QAVoteQuestion, true)
+}
+func (h *QuestionHandler) RemoveQuestionUpvote(c *gin.Context) {
+	h.vote(c, models.QAVoteQuestion, false)
+}
+func (h *QuestionHandler) UpvoteAnswer(c *gin.Context) {
+	h.vote(c, models.QAVoteAnswer, true)
+}
+func (h *QuestionHandler) RemoveAnswerUpvote(c *gin.Context) {
+	h.vote(c, models.QAVoteAnswer, false)
+}
+func (h *QuestionHandler) vote(c *gin.Context, targetType string, up bool) {
+	upvotes, err := h.questionService.Vote(c.GetString("user_id"), targetType, c.Param("id"), up)
+	if err != nil {
+		respondQAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Vote recorded successfully",
+		"upvotes": upvotes,
+	})
+}
+func (h *QuestionHandler) ReportQuestion(c *gin.Context) {
+	h.report(c, models.QAVoteQuestion)
+}
+func (h *QuestionHandler) ReportAnswer(c *gin.Context) {
+	h.report(c, models.QAVoteAnswer)
+}
+func (h *QuestionHandler) report(c *gin.Context, targetType string) {
+	var req models.QAReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	status, err := h.questionService.Report(c.GetString("user_id"), targetType, c.Param("id"), req)
+	if err != nil {
+		respondQAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Report received",
+		"status":  status,
+	})
+}
+func (h *QuestionHandler) GetQuestionQueue(c *gin.Context) {
+	var query models.QAModerationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	questions, total, err := h.questionService.QuestionQueue(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get questions"})
+		return
+	}
+	utils.PaginatedResponse(c, questions, int64(total), query.Page, query.Limit)
+}
+func (h *QuestionHandler) GetAnswerQueue(c *gin.Context) {
+	var query models.QAModerationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	answers, total, err := h.questionService.AnswerQueue(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get answers"})
+		return
+	}
+	utils.PaginatedResponse(c, answers, int64(total), query.Page, query.Limit)
+}
+func (h *QuestionHandler) ModerateQuestion(c *gin.Context) {
+	h.moderate(c, models.QAVoteQuestion)
+}
+func (h *QuestionHandler) ModerateAnswer(c *gin.Context) {
+	h.moderate(c, models.QAVoteAnswer)
+}
+func (h *QuestionHandler) moderate(c *gin.Context, targetType string) {
+	var req models.QAStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	audit := AuditEntry(c, models.AuditActionQAModerate, targetType, c.Param("id"))
+	if err := h.questionService.Moderate(targetType, c.Param("id"), req, audit); err != nil {
+		respondQAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Status updated successfully",
+		"status":  req.Status,
+	})
+}
+func respondQAError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "product not found", "question not found", "answer not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "unauthorized", "only staff can post official answers":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "question body is required", "answer body is required", "report reason is required":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+	}
+}
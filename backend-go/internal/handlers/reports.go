@@ -0,0 +1,126 @@
+﻿package handlers
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/services"
+	"ecommerce-backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+// defaultReportRange is used when from is omitted.
+const defaultReportRange = 30 * 24 * time.Hour
+type ReportHandler struct {
+	reportService *services.ReportService
+}
+func NewReportHandler(reportService *services.ReportService) *ReportHandler {
+	return &ReportHandler{reportService: reportService}
+}
+// parseReportDate accepts RFC 3339 timestamps or plain dates. A plain "to"
+// date includes the whole day.
+func parseReportDate(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24 * time.Hour)
+	}
+	return t, nil
+}
+// GetOrderReport streams the report, or queues it and returns 202 when it is
+// larger than services.OrderReportSyncLimit or async=true is passed.
+func (h *ReportHandler) GetOrderReport(c *gin.Context) {
+	q := models.OrderReportQuery{
+		To:           time.Now(),
+		Status:       models.OrderStatus(c.Query("status")),
+		StoreID:      c.GetString("store_id"),
+		IncludeItems: c.Query("items") == "true",
+	}
+	if to := c.Query("to"); to != "" {
+		parsed, err := parseReportDate(to, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date"})
+			return
+		}
+		q.To = parsed
+	}
+	q.From = q.To.Add(-defaultReportRange)
+	if from := c.Query("from"); from != "" {
+		parsed, err := parseReportDate(from, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+			return
+		}
+		q.From = parsed
+	}
+	format := c.DefaultQuery("format", models.ReportFormatCSV)
+	if err := h.reportService.ValidateOrderReport(q, format); err != nil {
+		switch err.Error() {
+		case "invalid report format":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be csv or json"})
+		case "invalid order status":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order status"})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		}
+		return
+	}
+	count, err := h.reportService.CountOrders(q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate report"})
+		return
+	}
+	if count > services.OrderReportSyncLimit || c.Query("async") == "true" {
+		job := h.reportService.StartOrderReport(q, format, utils.GetRequestID(c))
+		c.Header("Location", "jobs/"+job.ID)
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":      "Report queued successfully",
+			"orders":       count,
+			"job":          job,
+			"download_url": "/admin/api/reports/jobs/" + job.ID + "/download",
+		})
+		return
+	}
+	filename := fmt.Sprintf("orders-%s-%s.%s", q.From.Format("20060102"), q.To.Format("20060102"), format)
+	if format == models.ReportFormatJSON {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+	if err := h.reportService.WriteOrderReport(c.Writer, q, format); err != nil {
+		// Headers are already sent; abort so the client sees a truncated body.
+		c.Error(err)
+		c.Abort()
+	}
+}
+func (h *ReportHandler) GetReportJob(c *gin.Context) {
+	job, ok := h.reportService.GetJob(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	}
+	response := gin.H{"job": job}
+	if job.Status == models.JobStatusCompleted {
+		response["download_url"] = "/admin/api/reports/jobs/" + job.ID + "/download"
+	}
+	c.JSON(http.StatusOK, response)
+}
+func (h *ReportHandler) DownloadReport(c *gin.Context) {
+	path, err := h.reportService.OrderReportFile(c.Param("id"))
+	if err != nil {
+		if err.Error() == "report not ready" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Report is not ready yet"})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	}
+	c.FileAttachment(path, "orders-report"+filepath.Ext(path))
+}
@@ -1,9 +1,12 @@
 ﻿package handlers
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/services"
+	"ecommerce-backend/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 type ReviewHandler struct {
@@ -19,18 +22,75 @@ func (h *ReviewHandler) CreateReview(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	review, err := h.reviewService.CreateReview(userID, req)
+	review, created, err := h.reviewService.CreateReview(userID, req)
 	if err != nil {
+		if respondReviewValidation(c, err) {
+			return
+		}
+		if err.Error() == "review already exists for this product" {
+			c.JSON(http.StatusConflict, gin.H{"error": "You have already reviewed this product"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !created {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Review updated successfully",
+			"review":  review,
+		})
+		return
+	}
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Review created successfully",
 		"review":  review,
 	})
 }
+// respondReviewValidation writes a 422 listing each invalid field when err
+// holds validation errors.
+func respondReviewValidation(c *gin.Context, err error) bool {
+	var errs utils.ValidationErrors
+	if !errors.As(err, &errs) {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid review", "details": errs})
+	return true
+}
+// uuidParam returns the named path parameter, answering 400 when it is not
+// a UUID so malformed ids never reach the database.
+func uuidParam(c *gin.Context, name string) (string, bool) {
+	value := c.Param(name)
+	if !utils.IsValidUUID(value) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return "", false
+	}
+	return value, true
+}
+// respondReviewError maps a review or reply service error to its status
+// code. Errors it does not recognise are internal and answer 500 without
+// their details.
+func respondReviewError(c *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case msg == "review not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+	case msg == "reply not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reply not found"})
+	case msg == "unauthorized":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to change this review"})
+	case msg == "reply already exists for this review",
+		strings.HasPrefix(msg, "too many images"), strings.HasPrefix(msg, "images too large"),
+		strings.HasPrefix(msg, "upload ") && strings.HasSuffix(msg, " not found"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process review"})
+	}
+}
 func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
-	productID := c.Param("productId")
+	productID, ok := uuidParam(c, "productId")
+	if !ok {
+		return
+	}
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	reviews, err := h.reviewService.GetProductReviews(productID, page, limit)
@@ -40,20 +100,59 @@ func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, reviews)
 }
+func (h *ReviewHandler) GetProductReviewSummary(c *gin.Context) {
+	productID, ok := uuidParam(c, "productId")
+	if !ok {
+		return
+	}
+	summary, err := h.reviewService.GetProductReviewSummary(productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get review summary"})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+func (h *ReviewHandler) GetReviewSummaryBatch(c *gin.Context) {
+	var req models.ReviewSummaryBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	summaries, err := h.reviewService.GetRatingSummaries(req.ProductIDs)
+	if err != nil {
+		if err.Error() == "too many product ids" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Too many product ids"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get review summaries"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"summaries": summaries})
+}
 func (h *ReviewHandler) GetUserReviews(c *gin.Context) {
 	userID := c.GetString("user_id")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	reviews, err := h.reviewService.GetUserReviews(userID, page, limit)
+	var query models.ReviewQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.Sort != "" && query.Sort != "newest" && query.Sort != "highest" && query.Sort != "lowest" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort. Use newest, highest or lowest"})
+		return
+	}
+	reviews, total, err := h.reviewService.GetUserReviews(userID, &query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get reviews"})
 		return
 	}
-	c.JSON(http.StatusOK, reviews)
+	utils.PaginatedResponse(c, reviews, int64(total), query.Page, query.Limit)
 }
 func (h *ReviewHandler) UpdateReview(c *gin.Context) {
 	userID := c.GetString("user_id")
-	reviewID := c.Param("id")
+	reviewID, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
 	var req models.ReviewUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -61,7 +160,10 @@ func (h *ReviewHandler) UpdateReview(c *gin.Context) {
 	}
 	review, err := h.reviewService.UpdateReview(userID, reviewID, req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if respondReviewValidation(c, err) {
+			return
+		}
+		respondReviewError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -71,20 +173,82 @@ func (h *ReviewHandler) UpdateReview(c *gin.Context) {
 }
 func (h *ReviewHandler) DeleteReview(c *gin.Context) {
 	userID := c.GetString("user_id")
-	reviewID := c.Param("id")
+	reviewID, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
 	if err := h.reviewService.DeleteReview(userID, reviewID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondReviewError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Review deleted successfully"})
 }
 func (h *ReviewHandler) GetUserReviewForProduct(c *gin.Context) {
 	userID := c.GetString("user_id")
-	productID := c.Param("productId")
+	productID, ok := uuidParam(c, "productId")
+	if !ok {
+		return
+	}
 	review, err := h.reviewService.GetUserReviewForProduct(userID, productID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get review"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"review": review})
+}
+func (h *ReviewHandler) CreateReply(c *gin.Context) {
+	userID := c.GetString("user_id")
+	reviewID, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+	var req models.ReviewReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reply, err := h.reviewService.CreateReply(userID, reviewID, req)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Reply created successfully",
+		"reply":   reply,
+	})
+}
+func (h *ReviewHandler) UpdateReply(c *gin.Context) {
+	userID := c.GetString("user_id")
+	userRole := c.GetString("user_role")
+	reviewID, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+	var req models.ReviewReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reply, err := h.reviewService.UpdateReply(userID, userRole, reviewID, req)
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reply updated successfully",
+		"reply":   reply,
+	})
+}
+func (h *ReviewHandler) DeleteReply(c *gin.Context) {
+	userID := c.GetString("user_id")
+	userRole := c.GetString("user_role")
+	reviewID, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.reviewService.DeleteReply(userID, userRole, reviewID); err != nil {
+		respondReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Reply deleted successfully"})
 }
\ No newline at end of file
@@ -0,0 +1,52 @@
+﻿package handlers
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+type SearchHandler struct {
+	searchService *services.SearchService
+}
+func NewSearchHandler(searchService *services.SearchService) *SearchHandler {
+	return &SearchHandler{searchService: searchService}
+}
+func (h *SearchHandler) Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+	var types []string
+	if typesParam := c.Query("types"); typesParam != "" {
+		for _, t := range strings.Split(typesParam, ",") {
+			t = strings.TrimSpace(t)
+			if t != models.SearchTypeProducts && t != models.SearchTypeCategories {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search type: " + t})
+				return
+			}
+			types = append(types, t)
+		}
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if err != nil {
+		limit = 5
+	}
+	results, err := h.searchService.Search(c.GetString("store_id"), query, types, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+func (h *SearchHandler) Suggest(c *gin.Context) {
+	suggestions, err := h.searchService.Suggest(c.GetString("store_id"), c.Query("q"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get suggestions"})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=60")
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
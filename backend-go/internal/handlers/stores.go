@@ -0,0 +1,87 @@
+﻿package handlers
+import (
+	"net/http"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+type StoreHandler struct {
+	storeService *services.StoreService
+}
+func NewStoreHandler(storeService *services.StoreService) *StoreHandler {
+	return &StoreHandler{storeService: storeService}
+}
+func (h *StoreHandler) ListStores(c *gin.Context) {
+	stores, err := h.storeService.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get stores"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Stores retrieved successfully",
+		"stores":  stores,
+	})
+}
+func (h *StoreHandler) GetStore(c *gin.Context) {
+	store, err := h.storeService.Get(c.Param("id"))
+	if err != nil {
+		if err.Error() == "store not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Store not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get store"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Store retrieved successfully",
+		"store":   store,
+	})
+}
+func (h *StoreHandler) CreateStore(c *gin.Context) {
+	var req models.StoreCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	store, err := h.storeService.Create(req)
+	if err != nil {
+		switch err.Error() {
+		case "invalid store slug":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "store already exists", "store domain already in use":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create store"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Store created successfully",
+		"store":   store,
+	})
+}
+func (h *StoreHandler) UpdateStore(c *gin.Context) {
+	var req models.StoreUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	store, err := h.storeService.Update(c.Param("id"), req)
+	if err != nil {
+		switch err.Error() {
+		case "store not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Store not found"})
+		case "default store cannot be deactivated":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "store domain already in use":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update store"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Store updated successfully",
+		"store":   store,
+	})
+}
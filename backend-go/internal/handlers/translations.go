@@ -0,0 +1,97 @@
+﻿package handlers
+import (
+	"net/http"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+type TranslationHandler struct {
+	translationService *services.TranslationService
+}
+func NewTranslationHandler(translationService *services.TranslationService) *TranslationHandler {
+	return &TranslationHandler{translationService: translationService}
+}
+func (h *TranslationHandler) ListProductTranslations(c *gin.Context) {
+	translations, err := h.translationService.ListProductTranslations(c.GetString("store_id"), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err, "Failed to get product translations")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Product translations retrieved successfully",
+		"translations": translations,
+	})
+}
+func (h *TranslationHandler) SetProductTranslation(c *gin.Context) {
+	var req models.TranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	translation, err := h.translationService.SetProductTranslation(c.GetString("store_id"), c.Param("id"), c.Param("locale"), req)
+	if err != nil {
+		h.handleError(c, err, "Failed to save product translation")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Product translation saved successfully",
+		"translation": translation,
+	})
+}
+func (h *TranslationHandler) DeleteProductTranslation(c *gin.Context) {
+	if err := h.translationService.DeleteProductTranslation(c.GetString("store_id"), c.Param("id"), c.Param("locale")); err != nil {
+		h.handleError(c, err, "Failed to delete product translation")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Product translation deleted successfully"})
+}
+func (h *TranslationHandler) ListCategoryTranslations(c *gin.Context) {
+	translations, err := h.translationService.ListCategoryTranslations(c.GetString("store_id"), c.Param("slug"))
+	if err != nil {
+		h.handleError(c, err, "Failed to get category translations")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Category translations retrieved successfully",
+		"translations": translations,
+	})
+}
+func (h *TranslationHandler) SetCategoryTranslation(c *gin.Context) {
+	var req models.TranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	translation, err := h.translationService.SetCategoryTranslation(c.GetString("store_id"), c.Param("slug"), c.Param("locale"), req)
+	if err != nil {
+		h.handleError(c, err, "Failed to save category translation")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Category translation saved successfully",
+		"translation": translation,
+	})
+}
+func (h *TranslationHandler) DeleteCategoryTranslation(c *gin.Context) {
+	if err := h.translationService.DeleteCategoryTranslation(c.GetString("store_id"), c.Param("slug"), c.Param("locale")); err != nil {
+		h.handleError(c, err, "Failed to delete category translation")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Category translation deleted successfully"})
+}
+func (h *TranslationHandler) handleError(c *gin.Context, err error, fallback string) {
+	switch err.Error() {
+	case "product not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	case "category not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+	case "translation not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Translation not found"})
+	case "unsupported locale":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported locale"})
+	case "default locale is edited on the entity itself":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Default locale content is edited on the product or category itself"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -1,74 +1,157 @@
 ﻿package handlers
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
+	"strconv"
 	"time"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/services"
+	"ecommerce-backend/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 type UploadHandler struct {
-	uploadPath string
+	uploadPath    string
+	uploadService *services.UploadService
 }
-func NewUploadHandler(uploadPath string) *UploadHandler {
+func NewUploadHandler(uploadPath string, uploadService *services.UploadService) *UploadHandler {
 	if uploadPath == "" {
 		uploadPath = "./uploads"
 	}
 	if err := os.MkdirAll(uploadPath, 0755); err != nil {
 		panic(fmt.Sprintf("Failed to create upload directory: %v", err))
 	}
-	return &UploadHandler{uploadPath: uploadPath}
+	return &UploadHandler{uploadPath: uploadPath, uploadService: uploadService}
 }
 func (h *UploadHandler) UploadImage(c *gin.Context) {
 	file, header, err := c.Request.FormFile("image")
 	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large", "max_bytes": tooLarge.Limit})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No image file provided"})
 		return
 	}
 	defer file.Close()
-	if !isValidImageType(header.Header.Get("Content-Type")) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image type. Only JPEG, PNG, and GIF are allowed"})
+	contentType, err := sniffContentType(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image type. Only JPEG, PNG, GIF, and WebP are allowed"})
 		return
 	}
 	if header.Size > 10*1024*1024 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "File size too large. Maximum 10MB allowed"})
 		return
 	}
-	ext := filepath.Ext(header.Filename)
-	if ext == "" {
-		ext = ".jpg"
+	// Product images are validated from the original, before anything is
+	// written or counted against the quota.
+	if c.PostForm("purpose") == models.UploadPurposeProduct {
+		width, height, err := h.uploadService.CheckProductImage(file)
+		if err != nil {
+			switch err.Error() {
+			case "image is too small", "image aspect ratio is not allowed":
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"error":    "Image does not meet the product image requirements: " + err.Error(),
+					"width":    width,
+					"height":   height,
+					"required": h.uploadService.ProductImageConstraints(),
+				})
+			case "invalid image":
+				c.JSON(http.StatusBadRequest, gin.H{"error": "File is not a readable image"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read image"})
+			}
+			return
+		}
+	}
+	userID := c.GetString("user_id")
+	if usage, err := h.uploadService.CheckQuota(userID, c.GetString("user_role"), header.Size); err != nil {
+		if err.Error() == "upload quota exceeded" {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Upload quota exceeded", "usage": usage})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check upload quota"})
+		return
 	}
-	filename := fmt.Sprintf("%d_%s%s", time.Now().Unix(), generateRandomString(8), ext)
+	filename := fmt.Sprintf("%d_%s%s", time.Now().Unix(), utils.GenerateUUID(), ext)
 	filepath := filepath.Join(h.uploadPath, filename)
-	dst, err := os.Create(filepath)
+	dst, err := os.OpenFile(filepath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create file"})
 		return
 	}
 	defer dst.Close()
-	if _, err := io.Copy(dst, file); err != nil {
+	size, err := io.Copy(dst, file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		return
+	}
+	private, _ := strconv.ParseBool(c.DefaultPostForm("private", "false"))
+	upload, err := h.uploadService.RecordUpload(userID, c.GetString("user_role"), filename, contentType, size, private)
+	if err != nil {
+		os.Remove(filepath)
+		if err.Error() == "upload quota exceeded" {
+			usage, _ := h.uploadService.GetUsage(userID, c.GetString("user_role"))
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Upload quota exceeded", "usage": usage})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
-		"message":  "Image uploaded successfully",
-		"filename": filename,
-		"url":      fmt.Sprintf("/uploads/%s", filename),
+		"message":        "Image uploaded successfully",
+		"filename":       filename,
+		"private":        upload.Private,
+		"url":            upload.URL,
+		"url_expires_at": upload.URLExpires,
 	})
 }
+func (h *UploadHandler) ListUploads(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	uploads, total, err := h.uploadService.ListUserUploads(c.GetString("user_id"), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list uploads"})
+		return
+	}
+	utils.PaginatedResponse(c, uploads, int64(total), page, limit)
+}
+func (h *UploadHandler) GetUsage(c *gin.Context) {
+	usage, err := h.uploadService.GetUsage(c.GetString("user_id"), c.GetString("user_role"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get upload usage"})
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
 func (h *UploadHandler) DeleteImage(c *gin.Context) {
 	filename := c.Param("filename")
 	if filename == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Filename is required"})
 		return
 	}
-	filepath := filepath.Join(h.uploadPath, filename)
-	if err := os.Remove(filepath); err != nil {
-		if os.IsNotExist(err) {
+	if err := h.uploadService.DeleteByFilename(c.GetString("user_id"), c.GetString("user_role"), filename); err != nil {
+		switch err.Error() {
+		case "upload not found":
 			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
-		} else {
+		case "unauthorized":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to delete this file"})
+		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete file"})
 		}
 		return
@@ -81,33 +164,64 @@ func (h *UploadHandler) ServeImage(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Filename is required"})
 		return
 	}
+	upload, err := h.uploadService.AuthorizeServe(filename, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		switch err.Error() {
+		case "signature expired":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Link has expired"})
+		case "invalid signature":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid link signature"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serve file"})
+		}
+		return
+	}
 	filepath := filepath.Join(h.uploadPath, filename)
 	if _, err := os.Stat(filepath); os.IsNotExist(err) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
 		return
 	}
+	if upload != nil && upload.Private {
+		c.Header("Cache-Control", "private, no-store")
+	}
 	c.File(filepath)
 }
-func isValidImageType(contentType string) bool {
-	validTypes := []string{
-		"image/jpeg",
-		"image/jpg", 
-		"image/png",
-		"image/gif",
-		"image/webp",
-	}
-	for _, validType := range validTypes {
-		if strings.Contains(contentType, validType) {
-			return true
+func (h *UploadHandler) GetSignedURL(c *gin.Context) {
+	upload, err := h.uploadService.SignedURL(c.GetString("user_id"), c.GetString("user_role"), c.Param("filename"))
+	if err != nil {
+		switch err.Error() {
+		case "upload not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		case "unauthorized":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to access this file"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign URL"})
 		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"url":            upload.URL,
+		"url_expires_at": upload.URLExpires,
+	})
+}
+// imageExtensions maps the accepted sniffed image types to the extension
+// the file is stored under.
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+// sniffContentType detects the type of file from its first bytes rather
+// than trusting the client's Content-Type, then rewinds it.
+func sniffContentType(file io.ReadSeeker) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
 	}
-	return false
+	return http.DetectContentType(head[:n]), nil
 }
-func generateRandomString(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, length)
-	for i := range b {
-		b[i] = charset[time.Now().UnixNano()%int64(len(charset))]
-	}
-	return string(b)
-}
\ No newline at end of file
@@ -55,13 +55,25 @@ func (h *WishlistHandler) AddToWishlist(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	err := h.wishlistService.AddToWishlist(userID, req.ProductID)
+	added, err := h.wishlistService.AddToWishlist(userID, req.ProductID)
 	if err != nil {
+		if err.Error() == "product not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to wishlist"})
 		return
 	}
+	if !added {
+		c.JSON(http.StatusOK, gin.H{
+			"message":         "Product already in wishlist",
+			"already_present": true,
+		})
+		return
+	}
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Product added to wishlist successfully",
+		"message":         "Product added to wishlist successfully",
+		"already_present": false,
 	})
 }
 func (h *WishlistHandler) RemoveFromWishlist(c *gin.Context) {
@@ -75,13 +87,14 @@ func (h *WishlistHandler) RemoveFromWishlist(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Product ID is required"})
 		return
 	}
-	err := h.wishlistService.RemoveFromWishlist(userID, productID)
+	removed, err := h.wishlistService.RemoveFromWishlist(userID, productID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove from wishlist"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Product removed from wishlist successfully",
+		"removed": removed,
 	})
 }
 func (h *WishlistHandler) IsInWishlist(c *gin.Context) {
@@ -119,4 +132,29 @@ func (h *WishlistHandler) ClearWishlist(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Wishlist cleared successfully",
 	})
-}
\ No newline at end of file
+}
+func (h *WishlistHandler) GetPriceHistory(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	productID := c.Param("productId")
+	if productID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Product ID is required"})
+		return
+	}
+	history, err := h.wishlistService.GetPriceHistory(userID, productID)
+	if err != nil {
+		if err.Error() == "product not in wishlist" || err.Error() == "product not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get price history"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Price history retrieved successfully",
+		"price_history": history,
+	})
+}
@@ -108,10 +108,12 @@ func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
 
 func RecoveryMiddleware() gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
-		utils.Error("Panic recovered", "error", recovered, "path", c.Request.URL.Path)
+		requestID := utils.GetRequestID(c)
+		utils.Error("Panic recovered", "error", recovered, "path", c.Request.URL.Path, "request_id", requestID)
 
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Internal server error",
+			"error":      "Internal server error",
+			"request_id": requestID,
 		})
 	})
 }
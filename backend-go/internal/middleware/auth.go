@@ -11,9 +11,10 @@ import (
 )
 
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID         string `json:"user_id"`
+	Email          string `json:"email"`
+	Role           string `json:"role"`
+	ImpersonatorID string `json:"impersonator_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -40,6 +41,22 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
 		c.Set("user_role", claims.Role)
+		if claims.IsImpersonation() {
+			c.Set("impersonator_id", claims.ImpersonatorID)
+			c.Header("X-Impersonated-By", claims.ImpersonatorID)
+		}
+		c.Next()
+	}
+}
+// NoImpersonationMiddleware refuses the request when the caller is an admin
+// impersonating a user, for actions only the account owner may take.
+func NoImpersonationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("impersonator_id") != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed while impersonating a user"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -59,6 +76,24 @@ func AdminMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+func RoleMiddleware(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, exists := c.Get("user_role")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found"})
+			c.Abort()
+			return
+		}
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.Abort()
+	}
+}
 func OptionalAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -85,6 +120,9 @@ func OptionalAuthMiddleware() gin.HandlerFunc {
 			c.Set("user_id", claims.UserID)
 			c.Set("user_email", claims.Email)
 			c.Set("user_role", claims.Role)
+			if claims.ImpersonatorID != "" {
+				c.Set("impersonator_id", claims.ImpersonatorID)
+			}
 		}
 		c.Next()
 	}
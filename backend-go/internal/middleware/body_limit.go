@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxBodySize caps request bodies at limit bytes, or at the limit registered
+// in routeLimits for the matched route pattern (e.g. a larger one for
+// uploads). Requests declaring a larger Content-Length are rejected with 413;
+// other bodies are wrapped in http.MaxBytesReader so reads past the limit
+// fail with *http.MaxBytesError.
+func MaxBodySize(limit int64, routeLimits map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := limit
+		if routeLimit, ok := routeLimits[c.FullPath()]; ok {
+			limit = routeLimit
+		}
+		if limit <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":     "Request body too large",
+				"max_bytes": limit,
+			})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+
+		c.Next()
+	}
+}
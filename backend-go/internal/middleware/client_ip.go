@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientIPConfig controls where c.ClientIP(), and so the rate limiter, the
+// audit log and the request log, takes the client address from.
+//
+// Forwarded headers such as X-Forwarded-For are set by whoever sends the
+// request, so they are only believed when the connection comes from one of
+// TrustedProxies. Trusting a range that clients can connect from lets them
+// pick any address they like: they can dodge per-IP rate limits and put
+// someone else's address in the audit log. With no trusted proxies the
+// headers are ignored and the peer address is used.
+type ClientIPConfig struct {
+	// TrustedProxies lists the IPs or CIDRs of load balancers and reverse
+	// proxies in front of the server.
+	TrustedProxies []string
+	// RemoteIPHeaders are checked in order on requests from a trusted
+	// proxy. Defaults to X-Forwarded-For, then X-Real-IP.
+	RemoteIPHeaders []string
+	// TrustedPlatform names a CDN or platform ("cloudflare",
+	// "google-app-engine", "fly") or a header whose value is the client
+	// address. It is believed from any peer, so only set it when the
+	// server cannot be reached except through that platform.
+	TrustedPlatform string
+}
+
+var trustedPlatforms = map[string]string{
+	"cloudflare":        gin.PlatformCloudflare,
+	"google-app-engine": gin.PlatformGoogleAppEngine,
+	"fly":               gin.PlatformFlyIO,
+}
+
+// ConfigureClientIP applies cfg to the engine. It fails on a proxy that is
+// neither an IP nor a CIDR.
+func ConfigureClientIP(engine *gin.Engine, cfg ClientIPConfig) error {
+	if err := engine.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted proxy: %w", err)
+	}
+	engine.ForwardedByClientIP = true
+	engine.RemoteIPHeaders = []string{"X-Forwarded-For", "X-Real-IP"}
+	if len(cfg.RemoteIPHeaders) > 0 {
+		engine.RemoteIPHeaders = cfg.RemoteIPHeaders
+	}
+	engine.TrustedPlatform = ""
+	if platform := strings.TrimSpace(cfg.TrustedPlatform); platform != "" {
+		if header, ok := trustedPlatforms[strings.ToLower(platform)]; ok {
+			platform = header
+		}
+		engine.TrustedPlatform = platform
+	}
+	return nil
+}
@@ -1,11 +1,19 @@
 ﻿package middleware
 import (
 	"os"
+	"time"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
+// DefaultCORSMaxAge is how long browsers may cache a preflight response.
+const DefaultCORSMaxAge = 10 * time.Minute
 func CORSMiddleware() gin.HandlerFunc {
+	return CORSMiddlewareWithMaxAge(DefaultCORSMaxAge)
+}
+// CORSMiddlewareWithMaxAge is CORSMiddleware with a custom
+// Access-Control-Max-Age; a non-positive maxAge omits the header.
+func CORSMiddlewareWithMaxAge(maxAge time.Duration) gin.HandlerFunc {
 	godotenv.Load()
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{
@@ -26,15 +34,10 @@ func CORSMiddleware() gin.HandlerFunc {
 		"X-CSRF-Token",
 	}
 	config.AllowCredentials = true
-	config.ExposeHeaders = []string{"Content-Length"}
+	config.MaxAge = maxAge
+	config.ExposeHeaders = []string{"Content-Length", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After", "Warning", "X-Limit-Clamped", DefaultRequestIDHeader}
 	return cors.New(config)
 }
 func SecurityHeadersMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Header("X-Content-Type-Options", "nosniff")
-		c.Header("X-Frame-Options", "DENY")
-		c.Header("X-XSS-Protection", "1; mode=block")
-		c.Header("Referrer-Policy", "strict-origin-when-cross-origin")
-		c.Next()
-	}
+	return SecurityHeadersWithConfig(DefaultSecurityHeaders())
 }
\ No newline at end of file
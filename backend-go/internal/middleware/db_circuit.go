@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ecommerce-backend/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DatabaseCircuitBreaker answers API requests with 503 while the breaker is
+// open instead of letting them queue up on a database that is down. Paths
+// under skipPrefixes, such as health checks, are always served.
+func DatabaseCircuitBreaker(breaker *database.CircuitBreaker, skipPrefixes []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if !strings.HasPrefix(path, "/api/") || breaker.State() != database.BreakerOpen {
+			c.Next()
+			return
+		}
+		for _, prefix := range skipPrefixes {
+			if strings.HasPrefix(path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		retryAfter := int(breaker.RetryAfter().Seconds())
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":       "Service temporarily unavailable",
+			"retry_after": retryAfter,
+		})
+		c.Abort()
+	}
+}
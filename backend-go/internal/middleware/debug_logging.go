@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"ecommerce-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const redactedValue = "[REDACTED]"
+
+// sensitiveFields are matched case-insensitively as substrings of JSON keys.
+var sensitiveFields = []string{"password", "token", "secret", "card", "cvc", "cvv", "authorization", "captcha"}
+
+type bodyCaptureWriter struct {
+	gin.ResponseWriter
+	body  bytes.Buffer
+	limit int
+}
+
+func (w *bodyCaptureWriter) Write(data []byte) (int, error) {
+	if remaining := w.limit - w.body.Len(); remaining > 0 {
+		if len(data) > remaining {
+			w.body.Write(data[:remaining])
+		} else {
+			w.body.Write(data)
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// DebugBodyLogging logs JSON request and response bodies at debug level for
+// requests whose path starts with one of routes. Bodies are captured up to
+// maxBytes and sensitive fields are redacted; headers are never logged, and
+// bodies that are not JSON or exceed the cap are reported by size only.
+func DebugBodyLogging(routes []string, maxBytes int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !matchesRoute(c.Request.URL.Path, routes) {
+			c.Next()
+			return
+		}
+
+		var requestBody interface{}
+		if c.Request.Body != nil && strings.Contains(c.GetHeader("Content-Type"), "application/json") {
+			switch {
+			case c.Request.ContentLength > int64(maxBytes):
+				requestBody = map[string]interface{}{"omitted": "body exceeds capture limit", "size": c.Request.ContentLength}
+			case c.Request.ContentLength > 0:
+				data, err := io.ReadAll(c.Request.Body)
+				c.Request.Body = io.NopCloser(bytes.NewReader(data))
+				if err == nil {
+					requestBody = redactBody(data, maxBytes)
+				}
+			}
+		}
+
+		writer := &bodyCaptureWriter{ResponseWriter: c.Writer, limit: maxBytes + 1}
+		c.Writer = writer
+
+		c.Next()
+
+		var responseBody interface{}
+		if strings.Contains(writer.Header().Get("Content-Type"), "application/json") {
+			responseBody = redactBody(writer.body.Bytes(), maxBytes)
+		}
+
+		utils.Debug("HTTP request body capture",
+			"request_id", c.GetString("request_id"),
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", writer.Status(),
+			"request_body", requestBody,
+			"response_body", responseBody,
+		)
+	}
+}
+
+func matchesRoute(path string, routes []string) bool {
+	for _, route := range routes {
+		if strings.HasPrefix(path, route) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactBody(data []byte, maxBytes int) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	if len(data) > maxBytes {
+		return map[string]interface{}{"omitted": "body exceeds capture limit", "size": len(data)}
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return map[string]interface{}{"omitted": "body is not valid JSON", "size": len(data)}
+	}
+	return redactValue(body)
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if isSensitiveField(key) {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactValue(field)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func isSensitiveField(key string) bool {
+	key = strings.ToLower(key)
+	for _, field := range sensitiveFields {
+		if strings.Contains(key, field) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,12 +1,15 @@
 ﻿package middleware
 import (
 	"fmt"
+	"regexp"
 	"time"
+	"ecommerce-backend/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 func LoggingMiddleware() gin.HandlerFunc {
 	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		return fmt.Sprintf("%s - [%s] \"%s %s %s %d %s \"%s\" %s\"\n",
+		requestID, _ := param.Keys["request_id"].(string)
+		return fmt.Sprintf("%s - [%s] \"%s %s %s %d %s \"%s\" %s\" request_id=%s\n",
 			param.ClientIP,
 			param.TimeStamp.Format(time.RFC1123),
 			param.Method,
@@ -16,28 +19,34 @@ func LoggingMiddleware() gin.HandlerFunc {
 			param.Latency,
 			param.Request.UserAgent(),
 			param.ErrorMessage,
+			requestID,
 		)
 	})
 }
+// DefaultRequestIDHeader carries the request ID in and out of the API.
+const DefaultRequestIDHeader = "X-Request-ID"
+// requestIDPattern is what an incoming request ID must look like to be kept:
+// a UUID, a trace ID or similar token, never free text that could forge log
+// lines or response headers.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]{0,127}$`)
 func RequestIDMiddleware() gin.HandlerFunc {
+	return RequestIDMiddlewareWithHeader(DefaultRequestIDHeader)
+}
+// RequestIDMiddlewareWithHeader reuses a valid request ID from header or
+// generates one, echoes it in the response and stores it as "request_id"
+// for logs, error responses and queued jobs.
+func RequestIDMiddlewareWithHeader(header string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		requestID := c.GetHeader("X-Request-ID")
-		if requestID == "" {
-			requestID = generateRequestID()
+		requestID := c.GetHeader(header)
+		if !ValidRequestID(requestID) {
+			requestID = utils.GenerateUUID()
 		}
-		c.Header("X-Request-ID", requestID)
+		c.Header(header, requestID)
 		c.Set("request_id", requestID)
 		c.Next()
 	}
 }
-func generateRequestID() string {
-	return time.Now().Format("20060102150405") + "-" + randomString(8)
+// ValidRequestID reports whether an incoming request ID may be propagated.
+func ValidRequestID(id string) bool {
+	return requestIDPattern.MatchString(id)
 }
-func randomString(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, length)
-	for i := range b {
-		b[i] = charset[time.Now().UnixNano()%int64(len(charset))]
-	}
-	return string(b)
-}
\ No newline at end of file
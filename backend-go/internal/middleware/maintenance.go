@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ecommerce-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MaintenanceMode struct {
+	mutex        sync.RWMutex
+	enabled      bool
+	message      string
+	retryAfter   time.Duration
+	allowedPaths []string
+	since        time.Time
+	onToggle     func(enabled bool, message string)
+}
+
+type MaintenanceStatus struct {
+	Enabled    bool      `json:"enabled"`
+	Message    string    `json:"message"`
+	RetryAfter int       `json:"retry_after_seconds"`
+	Since      time.Time `json:"since,omitempty"`
+}
+
+func NewMaintenanceMode(enabled bool, message string, retryAfter time.Duration, allowedPaths []string) *MaintenanceMode {
+	m := &MaintenanceMode{
+		enabled:      enabled,
+		message:      message,
+		retryAfter:   retryAfter,
+		allowedPaths: allowedPaths,
+	}
+	if enabled {
+		m.since = time.Now()
+	}
+	return m
+}
+
+func (m *MaintenanceMode) OnToggle(fn func(enabled bool, message string)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.onToggle = fn
+}
+
+func (m *MaintenanceMode) Set(enabled bool, message string) {
+	m.mutex.Lock()
+	changed := m.enabled != enabled
+	m.enabled = enabled
+	if message != "" {
+		m.message = message
+	}
+	if changed && enabled {
+		m.since = time.Now()
+	}
+	current := m.message
+	onToggle := m.onToggle
+	m.mutex.Unlock()
+
+	if changed && onToggle != nil {
+		onToggle(enabled, current)
+	}
+}
+
+func (m *MaintenanceMode) Status() MaintenanceStatus {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	status := MaintenanceStatus{
+		Enabled:    m.enabled,
+		Message:    m.message,
+		RetryAfter: int(m.retryAfter.Seconds()),
+	}
+	if m.enabled {
+		status.Since = m.since
+	}
+	return status
+}
+
+func (m *MaintenanceMode) isAllowed(path string) bool {
+	return pathListed(path, m.allowedPaths)
+}
+
+func MaintenanceMiddleware(m *MaintenanceMode) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := m.Status()
+		if !status.Enabled || m.isAllowed(c.Request.URL.Path) || isAdminRequest(c) {
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", strconv.Itoa(status.RetryAfter))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":       "Service under maintenance",
+			"message":     status.Message,
+			"retry_after": status.RetryAfter,
+		})
+		c.Abort()
+	}
+}
+
+func isAdminRequest(c *gin.Context) bool {
+	authHeader := c.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return false
+	}
+	claims, err := utils.ValidateJWT(strings.TrimPrefix(authHeader, "Bearer "))
+	if err != nil {
+		return false
+	}
+	return claims.Role == "admin"
+}
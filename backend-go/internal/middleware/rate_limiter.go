@@ -1,10 +1,15 @@
 ﻿package middleware
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+	"ecommerce-backend/internal/utils"
 	"github.com/gin-gonic/gin"
 )
+const rateLimitWarningThreshold = 0.8
 type RateLimiter struct {
 	requests map[string][]time.Time
 	mutex    sync.RWMutex
@@ -43,10 +48,14 @@ func (rl *RateLimiter) cleanup() {
 	}
 }
 func (rl *RateLimiter) IsAllowed(ip string) bool {
+	allowed, _, _ := rl.Allow(ip)
+	return allowed
+}
+func (rl *RateLimiter) Allow(key string) (bool, int, time.Time) {
 	rl.mutex.Lock()
 	defer rl.mutex.Unlock()
 	now := time.Now()
-	requests := rl.requests[ip]
+	requests := rl.requests[key]
 	var validRequests []time.Time
 	for _, reqTime := range requests {
 		if now.Sub(reqTime) < rl.window {
@@ -54,17 +63,29 @@ func (rl *RateLimiter) IsAllowed(ip string) bool {
 		}
 	}
 	if len(validRequests) >= rl.limit {
-		return false
+		rl.requests[key] = validRequests
+		return false, 0, validRequests[0].Add(rl.window)
 	}
 	validRequests = append(validRequests, now)
-	rl.requests[ip] = validRequests
-	return true
+	rl.requests[key] = validRequests
+	return true, rl.limit - len(validRequests), validRequests[0].Add(rl.window)
 }
-func RateLimitMiddleware(limit int, window time.Duration) gin.HandlerFunc {
+// RateLimitMiddleware limits each client to limit requests per window.
+// Requests under bypassPaths, such as health probes and metrics scrapes, are
+// never limited so monitoring keeps working under load.
+func RateLimitMiddleware(limit int, window time.Duration, bypassPaths ...string) gin.HandlerFunc {
 	limiter := NewRateLimiter(limit, window)
 	return func(c *gin.Context) {
-		ip := c.ClientIP()
-		if !limiter.IsAllowed(ip) {
+		if pathListed(c.Request.URL.Path, bypassPaths) {
+			c.Next()
+			return
+		}
+		allowed, remaining, resetAt := limiter.Allow(rateLimitIdentity(c))
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(time.Until(resetAt).Seconds())+1))
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error":   "Rate limit exceeded",
 				"message": "Too many requests, please try again later",
@@ -72,6 +93,27 @@ func RateLimitMiddleware(limit int, window time.Duration) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if float64(limit-remaining) >= float64(limit)*rateLimitWarningThreshold {
+			c.Header("Warning", fmt.Sprintf(`199 - "Rate limit nearly exhausted: %d of %d requests remaining"`, remaining, limit))
+		}
 		c.Next()
 	}
+}
+// pathListed reports whether path is one of paths or below one of them.
+func pathListed(path string, paths []string) bool {
+	for _, listed := range paths {
+		if path == listed || strings.HasPrefix(path, strings.TrimSuffix(listed, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+func rateLimitIdentity(c *gin.Context) string {
+	authHeader := c.GetHeader("Authorization")
+	if strings.HasPrefix(authHeader, "Bearer ") {
+		if claims, err := utils.ValidateJWT(strings.TrimPrefix(authHeader, "Bearer ")); err == nil {
+			return "user:" + claims.UserID
+		}
+	}
+	return "ip:" + c.ClientIP()
 }
\ No newline at end of file
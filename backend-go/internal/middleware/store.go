@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"ecommerce-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StoreLookup maps a store ID or slug, or a request host, to the ID of an
+// active store. It returns an error when nothing matches.
+type StoreLookup func(value string) (string, error)
+
+// StoreResolverConfig names where the store of a request can come from.
+type StoreResolverConfig struct {
+	Header         string
+	JWTClaim       string
+	DefaultStoreID string
+	ByIdentifier   StoreLookup
+	ByHost         StoreLookup
+}
+
+// StoreResolver sets "store_id" on the context. A store claim in a valid
+// bearer token takes precedence and pins the request to that store: a header
+// or host naming another store is rejected. Without a claim the header, then
+// the host, then the default store are used. An unknown store in the header
+// is a 404 rather than a silent fallback to the default store.
+func StoreResolver(cfg StoreResolverConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimed := ""
+		if authHeader := c.GetHeader("Authorization"); cfg.JWTClaim != "" && strings.HasPrefix(authHeader, "Bearer ") {
+			value, err := utils.StringClaim(strings.TrimPrefix(authHeader, "Bearer "), cfg.JWTClaim)
+			if err == nil && value != "" {
+				storeID, err := cfg.ByIdentifier(value)
+				if err != nil {
+					c.JSON(http.StatusForbidden, gin.H{"error": "Token is not valid for any active store"})
+					c.Abort()
+					return
+				}
+				claimed = storeID
+			}
+		}
+
+		requested := ""
+		if value := c.GetHeader(cfg.Header); cfg.Header != "" && value != "" {
+			storeID, err := cfg.ByIdentifier(value)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Store not found"})
+				c.Abort()
+				return
+			}
+			requested = storeID
+		} else if cfg.ByHost != nil {
+			if storeID, err := cfg.ByHost(c.Request.Host); err == nil {
+				requested = storeID
+			}
+		}
+
+		storeID := cfg.DefaultStoreID
+		switch {
+		case claimed != "" && requested != "" && claimed != requested:
+			c.JSON(http.StatusForbidden, gin.H{"error": "Token is not valid for this store"})
+			c.Abort()
+			return
+		case claimed != "":
+			storeID = claimed
+		case requested != "":
+			storeID = requested
+		}
+		c.Set("store_id", storeID)
+		c.Next()
+	}
+}
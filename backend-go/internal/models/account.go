@@ -0,0 +1,39 @@
+﻿package models
+import (
+	"time"
+)
+type AccountDeleteRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+type AccountDeleteResponse struct {
+	Message  string   `json:"message"`
+	Erased   []string `json:"erased"`
+	Retained []string `json:"retained"`
+}
+type DataExportStatus string
+const (
+	DataExportStatusPending DataExportStatus = "pending"
+	DataExportStatusReady   DataExportStatus = "ready"
+	DataExportStatusFailed  DataExportStatus = "failed"
+	// DataExportStatusExpired marks an export whose file has been deleted.
+	DataExportStatusExpired DataExportStatus = "expired"
+)
+type DataExport struct {
+	ID          string           `json:"id" db:"id"`
+	UserID      string           `json:"user_id" db:"user_id"`
+	Token       string           `json:"-" db:"token"`
+	Status      DataExportStatus `json:"status" db:"status"`
+	FilePath    *string          `json:"-" db:"file_path"`
+	CreatedAt   time.Time        `json:"created_at" db:"created_at"`
+	CompletedAt *time.Time       `json:"completed_at" db:"completed_at"`
+	ExpiresAt   *time.Time       `json:"expires_at" db:"expires_at"`
+}
+type AccountExport struct {
+	GeneratedAt time.Time                 `json:"generated_at"`
+	Profile     UserResponse              `json:"profile"`
+	Addresses   []string                  `json:"addresses"`
+	Orders      []OrderWithItems          `json:"orders"`
+	Payments    []Payment                 `json:"payments"`
+	Reviews     []*Review                 `json:"reviews"`
+	Wishlist    []WishlistItemWithProduct `json:"wishlist"`
+}
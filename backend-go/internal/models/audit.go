@@ -0,0 +1,41 @@
+﻿package models
+import (
+	"encoding/json"
+	"time"
+)
+const (
+	AuditActionLogin             = "login"
+	AuditActionLoginFailed       = "login_failed"
+	AuditActionRoleChange        = "role_change"
+	AuditActionOrderStatusChange = "order_status_change"
+	AuditActionRefund            = "refund"
+	AuditActionPriceChange       = "price_change"
+	AuditActionCacheClear        = "cache_clear"
+	AuditActionImpersonateStart  = "impersonate_start"
+	AuditActionImpersonateEnd    = "impersonate_end"
+	AuditActionProductMerge      = "product_merge"
+	AuditActionOrderAutoCancel   = "order_auto_cancel"
+	AuditActionQAModerate        = "qa_moderate"
+	AuditActionStoreAssign       = "store_assign"
+)
+type AuditEntry struct {
+	ID         string          `json:"id" db:"id"`
+	ActorID    *string         `json:"actor_id" db:"actor_id"`
+	ActorIP    string          `json:"actor_ip" db:"actor_ip"`
+	Action     string          `json:"action" db:"action"`
+	TargetType string          `json:"target_type" db:"target_type"`
+	TargetID   string          `json:"target_id" db:"target_id"`
+	Before     json.RawMessage `json:"before,omitempty" db:"before"`
+	After      json.RawMessage `json:"after,omitempty" db:"after"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+type AuditQuery struct {
+	Page       int       `form:"page,default=1" binding:"min=1"`
+	Limit      int       `form:"limit,default=50" binding:"min=1,max=200"`
+	Action     string    `form:"action"`
+	ActorID    string    `form:"actor_id"`
+	TargetType string    `form:"target_type"`
+	TargetID   string    `form:"target_id"`
+	From       time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
@@ -0,0 +1,45 @@
+﻿package models
+// BundleComponent is a product sold as part of a bundle, Quantity units per
+// bundle. Its stock is what the bundle's availability is worked out from.
+type BundleComponent struct {
+	ProductID   string      `json:"product_id" db:"component_id"`
+	Name        string      `json:"name" db:"name"`
+	Quantity    int         `json:"quantity" db:"quantity"`
+	Unit        string      `json:"unit" db:"unit"`
+	ProductType ProductType `json:"product_type" db:"product_type"`
+	Image       *string     `json:"image,omitempty" db:"image"`
+	Stock       int         `json:"stock" db:"stock"`
+	InStock     bool        `json:"in_stock" db:"in_stock"`
+}
+// ProductBundle lists a bundle's components. Available is how many bundles
+// the components' stock can make up.
+type ProductBundle struct {
+	BundleID   string            `json:"bundle_id"`
+	Components []BundleComponent `json:"components"`
+	Available  int               `json:"available"`
+}
+type BundleItemRequest struct {
+	ProductID string `json:"product_id" binding:"required,uuid"`
+	Quantity  int    `json:"quantity" binding:"required,min=1,max=1000"`
+}
+type BundleSetRequest struct {
+	Items []BundleItemRequest `json:"items" binding:"required,min=1,max=50,dive"`
+}
+// BundleAvailability is the number of whole bundles the components' stock
+// covers: the smallest stock/quantity over the components.
+func BundleAvailability(components []BundleComponent) int {
+	if len(components) == 0 {
+		return 0
+	}
+	available := -1
+	for _, component := range components {
+		count := 0
+		if component.InStock && component.Stock > 0 {
+			count = component.Stock / component.Quantity
+		}
+		if available < 0 || count < available {
+			available = count
+		}
+	}
+	return available
+}
@@ -2,23 +2,65 @@
 import (
 	"time"
 )
+// CartItem.AddedPrice is the unit price when the item was added or when a
+// price change was last acknowledged.
 type CartItem struct {
-	ID        string    `json:"id" db:"id"`
-	UserID    string    `json:"user_id" db:"user_id"`
-	ProductID string    `json:"product_id" db:"product_id"`
-	Quantity  int       `json:"quantity" db:"quantity"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID         string    `json:"id" db:"id"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	ProductID  string    `json:"product_id" db:"product_id"`
+	Quantity   Quantity  `json:"quantity" db:"quantity"`
+	AddedPrice *Money    `json:"added_price" db:"added_price"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
 }
 type CartItemWithProduct struct {
 	CartItem
-	Product Product `json:"product"`
+	Product      Product `json:"product"`
+	PriceChanged bool    `json:"price_changed"`
+}
+const (
+	CartNoticePriceChanged = "price_changed"
+	CartNoticeDiscontinued = "discontinued"
+	CartNoticeExpired      = "expired"
+)
+// CartNotice tells the shopper why their cart changed. Removal notices are
+// stored until acknowledged; price changes are derived from AddedPrice.
+type CartNotice struct {
+	Type        string    `json:"type"`
+	ProductID   string    `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	OldPrice    *Money    `json:"old_price,omitempty"`
+	NewPrice    *Money    `json:"new_price,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 type CartResponse struct {
-	Items []CartItemWithProduct `json:"items"`
-	Total float64               `json:"total"`
+	Items     []CartItemWithProduct `json:"items"`
+	Total     Money                 `json:"total"`
+	Totals    CartTotals            `json:"totals"`
+	Version   int64                 `json:"version"`
+	UpdatedAt *time.Time            `json:"updated_at,omitempty"`
+	Notices   []CartNotice          `json:"notices"`
+}
+type CartTotals struct {
+	Subtotal                 Money `json:"subtotal"`
+	EstimatedShipping        Money `json:"estimated_shipping"`
+	MinOrderAmount           Money `json:"min_order_amount"`
+	MeetsMinimum             bool  `json:"meets_minimum"`
+	AmountToMinimum          Money `json:"amount_to_minimum"`
+	FreeShippingThreshold    Money `json:"free_shipping_threshold"`
+	QualifiesForFreeShipping bool  `json:"qualifies_for_free_shipping"`
+	AmountToFreeShipping     Money `json:"amount_to_free_shipping"`
+	// Tax is part of Subtotal when TaxIncluded, and added to it otherwise.
+	Tax            Money   `json:"tax"`
+	TaxRate        float64 `json:"tax_rate"`
+	TaxIncluded    bool    `json:"tax_included"`
+	EstimatedTotal Money   `json:"estimated_total"`
 }
 type CartItemRequest struct {
-	ProductID string `json:"product_id" binding:"required"`
-	Quantity  int    `json:"quantity" binding:"required,min=1"`
+	ProductID string   `json:"product_id" binding:"required"`
+	Quantity  Quantity `json:"quantity" binding:"required,gt=0"`
+}
+type CartItemUpdateRequest struct {
+	Quantity Quantity `json:"quantity" binding:"gte=0"`
+	Version  *int64   `json:"version"`
 }
\ No newline at end of file
@@ -8,6 +8,8 @@ type Category struct {
 	Slug        string    `json:"slug" db:"slug"`
 	Description *string   `json:"description" db:"description"`
 	Image       *string   `json:"image" db:"image"`
+	StoreID     string    `json:"store_id" db:"store_id"`
+	Version     int64     `json:"version" db:"version"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -25,4 +27,17 @@ type CategoryUpdateRequest struct {
 	Name        *string `json:"name"`
 	Description *string `json:"description"`
 	Image       *string `json:"image"`
+	Version     *int64  `json:"version" binding:"required"`
+}
+// CategoryStats summarises the live products of a category. The price range
+// and average rating are nil when the category has no products or reviews.
+type CategoryStats struct {
+	CategoryID    string   `json:"category_id"`
+	Slug          string   `json:"slug"`
+	ProductCount  int      `json:"product_count"`
+	InStockCount  int      `json:"in_stock_count"`
+	MinPrice      *Money   `json:"min_price"`
+	MaxPrice      *Money   `json:"max_price"`
+	AverageRating *float64 `json:"average_rating"`
+	ReviewCount   int      `json:"review_count"`
 }
\ No newline at end of file
@@ -0,0 +1,21 @@
+﻿package models
+import (
+	"time"
+)
+// OrderDownload is a tokenized link to a digital product bought in an order.
+// The link stops working after MaxDownloads uses or at ExpiresAt.
+type OrderDownload struct {
+	ID               string     `json:"id" db:"id"`
+	OrderID          string     `json:"order_id" db:"order_id"`
+	ProductID        string     `json:"product_id" db:"product_id"`
+	ProductName      string     `json:"product_name"`
+	UserID           string     `json:"-" db:"user_id"`
+	Token            string     `json:"-" db:"token"`
+	URL              string     `json:"url"`
+	DownloadCount    int        `json:"download_count" db:"download_count"`
+	MaxDownloads     int        `json:"max_downloads" db:"max_downloads"`
+	ExpiresAt        time.Time  `json:"expires_at" db:"expires_at"`
+	LastDownloadedAt *time.Time `json:"last_downloaded_at,omitempty" db:"last_downloaded_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	File             string     `json:"-"`
+}
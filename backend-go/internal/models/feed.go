@@ -0,0 +1,48 @@
+﻿package models
+import (
+	"encoding/xml"
+)
+type SitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []SitemapURL `xml:"url"`
+}
+type SitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+type SitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []SitemapRef `xml:"sitemap"`
+}
+type SitemapRef struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+type ProductFeed struct {
+	XMLName xml.Name           `xml:"rss"`
+	Version string             `xml:"version,attr"`
+	XmlnsG  string             `xml:"xmlns:g,attr"`
+	Channel ProductFeedChannel `xml:"channel"`
+}
+type ProductFeedChannel struct {
+	Title       string            `xml:"title"`
+	Link        string            `xml:"link"`
+	Description string            `xml:"description"`
+	Items       []ProductFeedItem `xml:"item"`
+}
+type ProductFeedItem struct {
+	ID                   string   `xml:"g:id"`
+	Title                string   `xml:"g:title"`
+	Description          string   `xml:"g:description"`
+	Link                 string   `xml:"g:link"`
+	ImageLink            string   `xml:"g:image_link,omitempty"`
+	AdditionalImageLinks []string `xml:"g:additional_image_link,omitempty"`
+	Availability         string   `xml:"g:availability"`
+	Price                string   `xml:"g:price"`
+	SalePrice            string   `xml:"g:sale_price,omitempty"`
+	ProductType          string   `xml:"g:product_type,omitempty"`
+	GTIN                 string   `xml:"g:gtin,omitempty"`
+	Condition            string   `xml:"g:condition"`
+}
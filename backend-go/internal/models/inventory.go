@@ -0,0 +1,46 @@
+﻿package models
+import (
+	"time"
+)
+type InventoryReason string
+const (
+	InventoryReasonInitial      InventoryReason = "initial"
+	InventoryReasonSale         InventoryReason = "sale"
+	InventoryReasonCancellation InventoryReason = "cancellation"
+	InventoryReasonReturn       InventoryReason = "return"
+	InventoryReasonRestock      InventoryReason = "restock"
+	InventoryReasonAdjustment   InventoryReason = "adjustment"
+)
+// InventoryMovement is one entry in the stock ledger. Reference ties the
+// movement to what caused it, such as an order id for sales.
+type InventoryMovement struct {
+	ID         string          `json:"id" db:"id"`
+	ProductID  string          `json:"product_id" db:"product_id"`
+	Delta      int             `json:"delta" db:"delta"`
+	StockAfter int             `json:"stock_after" db:"stock_after"`
+	Reason     InventoryReason `json:"reason" db:"reason"`
+	Reference  *string         `json:"reference,omitempty" db:"reference"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+type InventoryHistoryQuery struct {
+	Page   int    `form:"page,default=1" binding:"min=1"`
+	Limit  int    `form:"limit,default=50" binding:"min=1,max=200"`
+	Reason string `form:"reason" binding:"omitempty,oneof=initial sale cancellation return restock adjustment"`
+}
+// InventoryReconciliation compares a product's stock with the sum of its
+// ledger. A non-zero Difference means stock changed without a movement.
+type InventoryReconciliation struct {
+	ProductID      string     `json:"product_id"`
+	Name           string     `json:"name"`
+	SKU            *string    `json:"sku,omitempty"`
+	Stock          int        `json:"stock"`
+	LedgerStock    int        `json:"ledger_stock"`
+	Difference     int        `json:"difference"`
+	Movements      int        `json:"movements"`
+	LastMovementAt *time.Time `json:"last_movement_at,omitempty"`
+}
+type InventoryReport struct {
+	Products   []InventoryReconciliation `json:"products"`
+	Checked    int                       `json:"checked"`
+	Mismatched int                       `json:"mismatched"`
+}
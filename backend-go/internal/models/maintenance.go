@@ -0,0 +1,29 @@
+﻿package models
+import (
+	"time"
+)
+type MaintenanceWindowStatus string
+const (
+	MaintenanceWindowScheduled MaintenanceWindowStatus = "scheduled"
+	MaintenanceWindowActive    MaintenanceWindowStatus = "active"
+	MaintenanceWindowCompleted MaintenanceWindowStatus = "completed"
+	MaintenanceWindowCancelled MaintenanceWindowStatus = "cancelled"
+)
+// MaintenanceWindow is a planned period of maintenance mode. LastAlertLead
+// is the lead time, in seconds, of the last countdown alert broadcast for it.
+type MaintenanceWindow struct {
+	ID            string                  `json:"id" db:"id"`
+	StartsAt      time.Time               `json:"starts_at" db:"starts_at"`
+	EndsAt        time.Time               `json:"ends_at" db:"ends_at"`
+	Message       string                  `json:"message" db:"message"`
+	Status        MaintenanceWindowStatus `json:"status" db:"status"`
+	LastAlertLead *int64                  `json:"-" db:"last_alert_lead"`
+	CreatedBy     *string                 `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt     time.Time               `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time               `json:"updated_at" db:"updated_at"`
+}
+type MaintenanceWindowRequest struct {
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required"`
+	Message  string    `json:"message" binding:"required,max=500"`
+}
@@ -0,0 +1,137 @@
+﻿package models
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"ecommerce-backend/internal/utils"
+)
+// Money is an amount in minor units (cents) of the store currency. It is
+// stored as BIGINT and encoded in JSON as a decimal number with two places,
+// so 1999 is sent as 19.99 and arithmetic never goes through floats.
+type Money int64
+// MoneyFromFloat converts a major-unit amount such as a config value,
+// rounding half away from zero to the nearest cent.
+func MoneyFromFloat(amount float64) Money {
+	return Money(math.Round(amount * 100))
+}
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+func (m Money) Mul(quantity int) Money {
+	return m * Money(quantity)
+}
+// MulQuantity prices a possibly fractional quantity, rounding the line total
+// half away from zero to the nearest cent.
+func (m Money) MulQuantity(quantity Quantity) Money {
+	total := int64(m) * int64(quantity)
+	half := int64(quantityScale / 2)
+	if total < 0 {
+		half = -half
+	}
+	return Money((total + half) / quantityScale)
+}
+// MulRate applies a fractional rate (e.g. 0.1 for 10% tax) and rounds the
+// result to the nearest cent.
+func (m Money) MulRate(rate float64) Money {
+	return Money(math.Round(float64(m) * rate))
+}
+// Rounding modes for an amount that falls between two cents.
+const (
+	RoundHalfUp   = "half_up"
+	RoundHalfEven = "half_even"
+)
+// TaxRate is a tax rate in millionths, so 20% is 200000. Keeping rates as
+// integers makes tax exact to the cent under either rounding mode.
+type TaxRate int64
+const taxRateScale = 1000000
+func TaxRateFromFloat(rate float64) TaxRate {
+	return TaxRate(math.Round(rate * taxRateScale))
+}
+func (r TaxRate) Float64() float64 {
+	return float64(r) / taxRateScale
+}
+// TaxOn returns the tax charged on top of the net amount m.
+func (m Money) TaxOn(rate TaxRate, rounding string) Money {
+	return divRound(int64(m)*int64(rate), taxRateScale, rounding)
+}
+// TaxIn returns the tax contained in the gross amount m. It is m less its
+// rounded net amount, so net and tax always add back up to m.
+func (m Money) TaxIn(rate TaxRate, rounding string) Money {
+	return m - divRound(int64(m)*taxRateScale, taxRateScale+int64(rate), rounding)
+}
+// divRound divides num by the positive den, rounding the quotient to the
+// nearest cent with ties broken by the rounding mode.
+func divRound(num, den int64, rounding string) Money {
+	quotient, remainder := num/den, num%den
+	if remainder < 0 {
+		remainder = -remainder
+	}
+	step := int64(1)
+	if num < 0 {
+		step = -1
+	}
+	switch {
+	case remainder*2 > den:
+		quotient += step
+	case remainder*2 == den && (rounding != RoundHalfEven || quotient%2 != 0):
+		quotient += step
+	}
+	return Money(quotient)
+}
+func (m Money) String() string {
+	sign := ""
+	minor := int64(m)
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, minor/100, minor%100)
+}
+// Format renders the amount for display, e.g. "$1,234.50" for en or
+// "1.234,50 €" for de.
+func (m Money) Format(currency, locale string) string {
+	return utils.FormatMoney(int64(m), currency, locale)
+}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+func (m *Money) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "null" {
+		return nil
+	}
+	amount, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("invalid money amount %q", text)
+	}
+	*m = MoneyFromFloat(amount)
+	return nil
+}
+// Scan reads minor units. Aggregates such as SUM over BIGINT columns arrive
+// as numeric text and are rounded to whole cents.
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case int64:
+		*m = Money(v)
+	case float64:
+		*m = Money(math.Round(v))
+	case []byte:
+		return m.Scan(string(v))
+	case string:
+		amount, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid money value %q", v)
+		}
+		*m = Money(math.Round(amount))
+	case nil:
+		*m = 0
+	default:
+		return fmt.Errorf("cannot scan %T into Money", value)
+	}
+	return nil
+}
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
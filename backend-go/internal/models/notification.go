@@ -0,0 +1,16 @@
+﻿package models
+import (
+	"encoding/json"
+	"time"
+)
+// Notification is a user-targeted message kept so it can be read after the
+// real-time delivery was missed.
+type Notification struct {
+	ID        string          `json:"id" db:"id"`
+	UserID    string          `json:"-" db:"user_id"`
+	Type      string          `json:"type" db:"type"`
+	Message   string          `json:"message" db:"message"`
+	Data      json.RawMessage `json:"data,omitempty" db:"data"`
+	ReadAt    *time.Time      `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
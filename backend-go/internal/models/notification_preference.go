@@ -0,0 +1,60 @@
+﻿package models
+type NotificationChannel string
+type NotificationEvent string
+const (
+	NotificationChannelEmail     NotificationChannel = "email"
+	NotificationChannelWebSocket NotificationChannel = "websocket"
+)
+const (
+	NotificationEventOrder     NotificationEvent = "order"
+	NotificationEventPromotion NotificationEvent = "promotion"
+	NotificationEventPrice     NotificationEvent = "price"
+	NotificationEventStock     NotificationEvent = "stock"
+)
+var NotificationChannels = []NotificationChannel{NotificationChannelEmail, NotificationChannelWebSocket}
+var NotificationEvents = []NotificationEvent{NotificationEventOrder, NotificationEventPromotion, NotificationEventPrice, NotificationEventStock}
+// NotificationPreferences maps each channel to the events a user wants to
+// receive on it. Order messages are transactional and always delivered. No
+// promotion, price or stock mail exists yet, so only the WebSocket settings
+// take effect.
+type NotificationPreferences map[NotificationChannel]map[NotificationEvent]bool
+// DefaultNotificationPreferences applies to any channel/event a user has not
+// set: everything in the app, and only order and price-drop mail.
+func DefaultNotificationPreferences() NotificationPreferences {
+	return NotificationPreferences{
+		NotificationChannelEmail: {
+			NotificationEventOrder:     true,
+			NotificationEventPromotion: false,
+			NotificationEventPrice:     true,
+			NotificationEventStock:     false,
+		},
+		NotificationChannelWebSocket: {
+			NotificationEventOrder:     true,
+			NotificationEventPromotion: true,
+			NotificationEventPrice:     true,
+			NotificationEventStock:     true,
+		},
+	}
+}
+func (p NotificationPreferences) Allows(channel NotificationChannel, event NotificationEvent) bool {
+	if event == NotificationEventOrder {
+		return true
+	}
+	return p[channel][event]
+}
+func IsNotificationChannel(channel NotificationChannel) bool {
+	for _, c := range NotificationChannels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+func IsNotificationEvent(event NotificationEvent) bool {
+	for _, e := range NotificationEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -4,46 +4,143 @@ import (
 )
 type OrderStatus string
 const (
-	OrderStatusPending    OrderStatus = "pending"
-	OrderStatusProcessing OrderStatus = "processing"
-	OrderStatusShipped    OrderStatus = "shipped"
-	OrderStatusDelivered  OrderStatus = "delivered"
-	OrderStatusCancelled  OrderStatus = "cancelled"
+	OrderStatusPending          OrderStatus = "pending"
+	OrderStatusProcessing       OrderStatus = "processing"
+	OrderStatusPartiallyShipped OrderStatus = "partially_shipped"
+	OrderStatusShipped          OrderStatus = "shipped"
+	OrderStatusDelivered        OrderStatus = "delivered"
+	OrderStatusCancelled        OrderStatus = "cancelled"
 )
+// orderStatusTransitions lists the statuses an order may move to from each
+// status. Delivered and cancelled orders are final.
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:          {OrderStatusProcessing, OrderStatusCancelled},
+	OrderStatusProcessing:       {OrderStatusPartiallyShipped, OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusPartiallyShipped: {OrderStatusShipped, OrderStatusDelivered},
+	OrderStatusShipped:          {OrderStatusDelivered},
+}
+func (s OrderStatus) Valid() bool {
+	switch s {
+	case OrderStatusPending, OrderStatusProcessing, OrderStatusPartiallyShipped, OrderStatusShipped, OrderStatusDelivered, OrderStatusCancelled:
+		return true
+	}
+	return false
+}
+// Shippable reports whether shipments may be created for an order in this
+// status: it must be paid and not yet fully shipped.
+func (s OrderStatus) Shippable() bool {
+	return s == OrderStatusProcessing || s == OrderStatusPartiallyShipped
+}
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
 type Order struct {
 	ID              string      `json:"id" db:"id"`
 	UserID          string      `json:"user_id" db:"user_id"`
+	StoreID         string      `json:"store_id" db:"store_id"`
 	Status          OrderStatus `json:"status" db:"status"`
-	Total           float64     `json:"total" db:"total"`
-	Subtotal        float64     `json:"subtotal" db:"subtotal"`
-	Tax             float64     `json:"tax" db:"tax"`
-	Shipping        float64     `json:"shipping" db:"shipping"`
+	Total           Money       `json:"total" db:"total"`
+	Subtotal        Money       `json:"subtotal" db:"subtotal"`
+	Tax             Money       `json:"tax" db:"tax"`
+	Shipping        Money       `json:"shipping" db:"shipping"`
+	ShippingMethod  *string     `json:"shipping_method,omitempty" db:"shipping_method"`
 	ShippingAddress string      `json:"shipping_address" db:"shipping_address"`
 	BillingAddress  string      `json:"billing_address" db:"billing_address"`
 	PaymentIntent   *string     `json:"payment_intent" db:"payment_intent"`
+	CustomerNote    *string     `json:"customer_note,omitempty" db:"customer_note"`
+	IsGift          bool        `json:"is_gift" db:"is_gift"`
+	GiftMessage     *string     `json:"gift_message,omitempty" db:"gift_message"`
+	GiftWrap        bool        `json:"gift_wrap" db:"gift_wrap"`
+	GiftWrapFee     Money       `json:"gift_wrap_fee" db:"gift_wrap_fee"`
+	TaxIncluded     bool        `json:"tax_included" db:"tax_included"`
 	CreatedAt       time.Time   `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time   `json:"updated_at" db:"updated_at"`
 }
+// OrderItem keeps the product's name, image, unit and price as they were when
+// the order was placed. ProductID is empty once the product has been deleted.
+// A bundle is one priced line whose Components are the zero-priced lines it
+// is fulfilled with; they are stored with BundleItemID set to the bundle's
+// line.
 type OrderItem struct {
-	ID        string  `json:"id" db:"id"`
-	OrderID   string  `json:"order_id" db:"order_id"`
-	ProductID string  `json:"product_id" db:"product_id"`
-	Quantity  int     `json:"quantity" db:"quantity"`
-	Price     float64 `json:"price" db:"price"`
+	ID           string      `json:"id" db:"id"`
+	OrderID      string      `json:"order_id" db:"order_id"`
+	ProductID    string      `json:"product_id" db:"product_id"`
+	ProductName  string      `json:"product_name" db:"product_name"`
+	ProductImage *string     `json:"product_image,omitempty" db:"product_image"`
+	Quantity     Quantity    `json:"quantity" db:"quantity"`
+	Unit         string      `json:"unit" db:"unit"`
+	Price        Money       `json:"price" db:"price"`
+	BundleItemID *string     `json:"-" db:"bundle_item_id"`
+	Components   []OrderItem `json:"components,omitempty" db:"-"`
+}
+// FulfillmentItems lists the lines that are picked and shipped: each bundle
+// line is replaced by its components.
+func FulfillmentItems(items []OrderItemWithProduct) []OrderItem {
+	var lines []OrderItem
+	for _, item := range items {
+		if len(item.Components) > 0 {
+			lines = append(lines, item.Components...)
+			continue
+		}
+		lines = append(lines, item.OrderItem)
+	}
+	return lines
 }
 type OrderWithItems struct {
 	Order
 	User      *UserResponse      `json:"user,omitempty"`
 	OrderItems []OrderItemWithProduct `json:"order_items,omitempty"`
+	Shipments []Shipment `json:"shipments,omitempty"`
+	InternalNotes []OrderInternalNote `json:"internal_notes,omitempty"`
+}
+type OrderInternalNote struct {
+	ID        string    `json:"id" db:"id"`
+	OrderID   string    `json:"order_id" db:"order_id"`
+	AuthorID  string    `json:"author_id" db:"author_id"`
+	Note      string    `json:"note" db:"note"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+type OrderNoteRequest struct {
+	Note string `json:"note" binding:"required,max=2000"`
 }
 type OrderItemWithProduct struct {
 	OrderItem
 	Product *ProductWithRating `json:"product,omitempty"`
 }
 type OrderCreateRequest struct {
-	ShippingAddress string `json:"shipping_address" binding:"required"`
+	ShippingAddress string `json:"shipping_address"`
 	BillingAddress  string `json:"billing_address" binding:"required"`
+	CustomerNote    string `json:"customer_note" binding:"max=1000"`
+	IsGift          bool   `json:"is_gift"`
+	GiftMessage     string `json:"gift_message" binding:"max=500"`
+	GiftWrap        bool   `json:"gift_wrap"`
+	// The destination and method shipping is priced for, as in a shipping
+	// estimate. Without a country the origin country is assumed.
+	ShippingCountry    string `json:"shipping_country" binding:"omitempty,len=2"`
+	ShippingPostalCode string `json:"shipping_postal_code" binding:"max=12"`
+	ShippingMethod     string `json:"shipping_method" binding:"omitempty,oneof=standard express"`
+	RequestID          string `json:"-"`
 }
 type OrderUpdateRequest struct {
 	Status *OrderStatus `json:"status"`
+}
+// BulkOrderStatusRequest moves many orders to one status. With Atomic set the
+// whole batch is rejected if any order cannot transition.
+type BulkOrderStatusRequest struct {
+	OrderIDs []string    `json:"order_ids" binding:"required,min=1,max=500,dive,required,uuid"`
+	Status   OrderStatus `json:"status" binding:"required"`
+	Atomic   bool        `json:"atomic"`
+}
+type BulkOrderStatusResult struct {
+	OrderID        string      `json:"order_id"`
+	UserID         string      `json:"-"`
+	PreviousStatus OrderStatus `json:"previous_status,omitempty"`
+	Status         OrderStatus `json:"status,omitempty"`
+	Applied        bool        `json:"applied"`
+	Error          string      `json:"error,omitempty"`
 }
\ No newline at end of file
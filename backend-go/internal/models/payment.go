@@ -9,22 +9,57 @@ const (
 	PaymentStatusFailed    PaymentStatus = "failed"
 	PaymentStatusCancelled PaymentStatus = "cancelled"
 )
+const (
+	PaymentProviderStripe = "stripe"
+	PaymentProviderMock   = "mock"
+)
 type Payment struct {
 	ID              string        `json:"id" db:"id"`
 	UserID          string        `json:"user_id" db:"user_id"`
 	OrderID         *string       `json:"order_id" db:"order_id"`
-	Amount          float64       `json:"amount" db:"amount"`
+	Amount          Money         `json:"amount" db:"amount"`
 	Currency        string        `json:"currency" db:"currency"`
 	Status          PaymentStatus `json:"status" db:"status"`
+	Provider        string        `json:"provider" db:"provider"`
+	TestMode        bool          `json:"test_mode" db:"test_mode"`
 	PaymentIntentID string        `json:"payment_intent_id" db:"payment_intent_id"`
 	ClientSecret    string        `json:"client_secret" db:"client_secret"`
 	CreatedAt       time.Time     `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time     `json:"updated_at" db:"updated_at"`
 }
+// PaymentHistoryQuery filters a user's payments. Status "refunded" matches
+// payments with at least one refund; From and To bound created_at.
+type PaymentHistoryQuery struct {
+	Page   int       `form:"page,default=1" binding:"min=1"`
+	Limit  int       `form:"limit,default=20" binding:"min=1,max=100"`
+	Status string    `form:"status" binding:"omitempty,oneof=pending succeeded failed cancelled refunded"`
+	From   time.Time `form:"-"`
+	To     time.Time `form:"-"`
+}
+// PaymentRefund is a refund issued against a payment for an approved return.
+type PaymentRefund struct {
+	ReturnID   string    `json:"return_id"`
+	RefundID   string    `json:"refund_id"`
+	Amount     Money     `json:"amount"`
+	RefundedAt time.Time `json:"refunded_at"`
+}
+type PaymentHistoryEntry struct {
+	Payment
+	Refunded Money           `json:"refunded"`
+	Refunds  []PaymentRefund `json:"refunds"`
+}
 type PaymentIntentRequest struct {
-	Amount   float64 `json:"amount" binding:"required,min=0.01"`
+	// Amount is ignored for an order, which is charged its total.
+	Amount   Money   `json:"amount" binding:"required_without=OrderID,omitempty,min=1"`
 	Currency string  `json:"currency" binding:"required"`
 	OrderID  *string `json:"order_id"`
+	// Provider picks one of the enabled payment providers; empty uses the
+	// configured default.
+	Provider string `json:"provider"`
+	// TestOutcome picks the result of a sandbox payment; it is ignored
+	// unless payments run in test mode.
+	TestOutcome string `json:"test_outcome" binding:"omitempty,oneof=succeeded failed"`
+	RequestID   string `json:"-"`
 }
 type PaymentConfirmRequest struct {
 	PaymentIntentID string `json:"payment_intent_id" binding:"required"`
@@ -35,6 +70,43 @@ type PaymentIntentResponse struct {
 	Amount       int64  `json:"amount"`
 	Currency     string `json:"currency"`
 	Status       string `json:"status"`
+	Provider     string `json:"provider"`
+	TestMode     bool   `json:"test_mode"`
+	// Reused is set when the order's open intent was returned instead of a
+	// new one.
+	Reused bool `json:"reused,omitempty"`
+}
+// TestDeclineCents marks a sandbox payment to be declined: any amount whose
+// cents are 02, e.g. 10.02, fails unless test_outcome says otherwise.
+const TestDeclineCents = 2
+// ProviderIntentRequest is what a payment provider needs to open an intent.
+// Requests with the same IdempotencyKey open a single intent.
+type ProviderIntentRequest struct {
+	Amount         Money
+	Currency       string
+	Metadata       map[string]string
+	IdempotencyKey string
+}
+// ProviderIntent is a payment intent opened with a provider. ClientSecret is
+// handed to the client to complete the payment.
+type ProviderIntent struct {
+	ID           string
+	ClientSecret string
+	Status       PaymentStatus
+	// ProviderStatus is the provider's own status, e.g. Stripe's
+	// "requires_payment_method".
+	ProviderStatus string
+}
+type PaymentEventType string
+const (
+	PaymentEventSucceeded PaymentEventType = "payment_succeeded"
+	PaymentEventFailed    PaymentEventType = "payment_failed"
+)
+// PaymentEvent is a verified webhook notification. Type is empty for events
+// that need no action.
+type PaymentEvent struct {
+	Type     PaymentEventType
+	IntentID string
 }
 type StripeWebhookPayload struct {
 	ID      string                 `json:"id"`
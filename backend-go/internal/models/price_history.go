@@ -0,0 +1,14 @@
+﻿package models
+import (
+	"time"
+)
+type PricePoint struct {
+	Price      Money     `json:"price" db:"price"`
+	RecordedAt time.Time `json:"recorded_at" db:"recorded_at"`
+}
+type PriceHistory struct {
+	ProductID    string       `json:"product_id"`
+	CurrentPrice Money        `json:"current_price"`
+	Lowest30Days *Money       `json:"lowest_30_days"`
+	Points       []PricePoint `json:"points"`
+}
@@ -2,24 +2,37 @@
 import (
 	"time"
 )
+type ProductType string
+const (
+	ProductTypePhysical ProductType = "physical"
+	ProductTypeDigital  ProductType = "digital"
+)
 type Product struct {
-	ID           string    `json:"id" db:"id"`
-	Name         string    `json:"name" db:"name"`
-	Slug         string    `json:"slug" db:"slug"`
-	Description  *string   `json:"description" db:"description"`
-	Price        float64   `json:"price" db:"price"`
-	ComparePrice *float64  `json:"compare_price" db:"compare_price"`
-	Images       []string  `json:"images" db:"images"`
-	InStock      bool      `json:"in_stock" db:"in_stock"`
-	Stock        int       `json:"stock" db:"stock"`
-	Featured     bool      `json:"featured" db:"featured"`
-	CategoryID   string    `json:"category_id" db:"category_id"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID           string      `json:"id" db:"id"`
+	Name         string      `json:"name" db:"name"`
+	Slug         string      `json:"slug" db:"slug"`
+	Description  *string     `json:"description" db:"description"`
+	Price        Money       `json:"price" db:"price"`
+	ComparePrice *Money      `json:"compare_price" db:"compare_price"`
+	Images       []string    `json:"images" db:"images"`
+	InStock      bool        `json:"in_stock" db:"in_stock"`
+	Stock        int         `json:"stock" db:"stock"`
+	Featured     bool        `json:"featured" db:"featured"`
+	ProductType  ProductType `json:"product_type" db:"product_type"`
+	Unit         string      `json:"unit" db:"unit"`
+	SKU          *string     `json:"sku" db:"sku"`
+	Barcode      *string     `json:"barcode" db:"barcode"`
+	DigitalFile  *string     `json:"-" db:"digital_file"`
+	CategoryID   string      `json:"category_id" db:"category_id"`
+	StoreID      string      `json:"store_id" db:"store_id"`
+	Version      int64       `json:"version" db:"version"`
+	CreatedAt    time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at" db:"updated_at"`
 }
 type ProductWithCategory struct {
 	Product
-	Category *Category `json:"category,omitempty"`
+	Category *Category      `json:"category,omitempty"`
+	Gallery  []ProductImage `json:"gallery,omitempty"`
 }
 type ProductWithRating struct {
 	Product
@@ -27,25 +40,108 @@ type ProductWithRating struct {
 	AverageRating float64   `json:"average_rating"`
 	ReviewCount   int       `json:"review_count"`
 }
+// ProductListFields, ProductDetailFields and ProductBatchFields are the
+// names accepted by the fields= query parameter on the product list, detail
+// and batch endpoints.
+var ProductListFields = []string{
+	"id", "name", "slug", "description", "price", "compare_price", "images", "in_stock", "stock",
+	"featured", "product_type", "unit", "sku", "barcode", "category_id", "version", "created_at", "updated_at", "category", "average_rating", "review_count",
+}
+var ProductDetailFields = []string{
+	"id", "name", "slug", "description", "price", "compare_price", "images", "in_stock", "stock",
+	"featured", "product_type", "unit", "sku", "barcode", "category_id", "version", "created_at", "updated_at", "category", "gallery",
+}
+var ProductBatchFields = []string{
+	"id", "name", "slug", "description", "price", "compare_price", "images", "in_stock", "stock",
+	"featured", "product_type", "unit", "sku", "barcode", "category_id", "version", "created_at", "updated_at", "category",
+}
+// MaxProductBatchSize caps the ids accepted by the batch endpoint.
+const MaxProductBatchSize = 100
+const MaxNewArrivalsDays = 365
+// ProductMergeRequest folds duplicate products into a primary one.
+type ProductMergeRequest struct {
+	PrimaryID    string   `json:"primary_id" binding:"required,uuid"`
+	DuplicateIDs []string `json:"duplicate_ids" binding:"required,min=1,max=50,dive,uuid"`
+}
+// ProductMergeResult counts what a merge moved to the primary product.
+// Reviews and wishlist entries of users who already had one for the primary
+// stay behind; the duplicates' reviews remain hidden with them and their
+// wishlist entries are removed. Cart lines for a duplicate are folded into
+// the user's line for the primary.
+type ProductMergeResult struct {
+	PrimaryID            string   `json:"primary_id"`
+	MergedIDs            []string `json:"merged_ids"`
+	ReviewsReassigned    int      `json:"reviews_reassigned"`
+	ReviewsLeft          int      `json:"reviews_left"`
+	OrderItemsReassigned int      `json:"order_items_reassigned"`
+	WishlistReassigned   int      `json:"wishlist_items_reassigned"`
+	WishlistRemoved      int      `json:"wishlist_items_removed"`
+	CartItemsReassigned  int      `json:"cart_items_reassigned"`
+	ImagesReassigned     int      `json:"images_reassigned"`
+}
+type ProductBatchRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1,max=100"`
+}
+// ProductBatchResponse lists the found products in request order; Missing
+// holds the requested ids with no product in the store.
+type ProductBatchResponse struct {
+	Products []ProductWithCategory `json:"products"`
+	Missing  []string              `json:"missing"`
+}
+// StockAvailability is the purchasable state of a product reported by the
+// stock check endpoint.
+type StockAvailability string
+const (
+	StockInStock     StockAvailability = "in_stock"
+	StockOutOfStock  StockAvailability = "out_of_stock"
+	StockUnavailable StockAvailability = "unavailable"
+)
+type StockCheckRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1,max=100"`
+}
+// StockCheckItem is the availability of one requested product. Ids with no
+// product in the store are reported as unavailable.
+type StockCheckItem struct {
+	ProductID    string            `json:"product_id"`
+	Available    int               `json:"available"`
+	Availability StockAvailability `json:"availability"`
+}
 type ProductCreateRequest struct {
 	Name         string   `json:"name" binding:"required"`
 	Description  string   `json:"description"`
-	Price        float64  `json:"price" binding:"required,min=0"`
-	ComparePrice *float64 `json:"compare_price"`
+	Price        Money    `json:"price" binding:"required,min=0"`
+	ComparePrice *Money   `json:"compare_price"`
 	Images       []string `json:"images"`
 	Stock        int      `json:"stock" binding:"required,min=0"`
 	Featured     bool     `json:"featured"`
+	ProductType  string   `json:"product_type" binding:"omitempty,oneof=physical digital"`
+	Unit         string   `json:"unit" binding:"omitempty,oneof=each kg g lb m cm l ml"`
+	SKU          string   `json:"sku" binding:"max=64"`
+	Barcode      string   `json:"barcode" binding:"max=64"`
+	DigitalFile  string   `json:"digital_file"`
 	CategoryID   string   `json:"category_id" binding:"required"`
 }
 type ProductUpdateRequest struct {
 	Name         *string  `json:"name"`
 	Description  *string  `json:"description"`
-	Price        *float64 `json:"price"`
-	ComparePrice *float64 `json:"compare_price"`
+	Price        *Money   `json:"price"`
+	ComparePrice *Money   `json:"compare_price"`
 	Images       []string `json:"images"`
 	Stock        *int     `json:"stock"`
 	Featured     *bool    `json:"featured"`
+	ProductType  *string  `json:"product_type" binding:"omitempty,oneof=physical digital"`
+	Unit         *string  `json:"unit" binding:"omitempty,oneof=each kg g lb m cm l ml"`
+	SKU          *string  `json:"sku" binding:"omitempty,max=64"`
+	Barcode      *string  `json:"barcode" binding:"omitempty,max=64"`
+	DigitalFile  *string  `json:"digital_file"`
 	CategoryID   *string  `json:"category_id"`
+	// StockReason and StockReference describe a stock change in the
+	// inventory ledger; the reason defaults to adjustment.
+	StockReason    string  `json:"stock_reason" binding:"omitempty,oneof=adjustment restock"`
+	StockReference *string `json:"stock_reference" binding:"omitempty,max=100"`
+	// Version is the version the editor loaded; the update is rejected if
+	// the product has changed since.
+	Version *int64 `json:"version" binding:"required"`
 }
 type ProductQuery struct {
 	Page      int    `form:"page"`
@@ -55,10 +151,53 @@ type ProductQuery struct {
 	Featured  bool   `form:"featured"`
 	SortBy    string `form:"sort_by"`
 	SortOrder string `form:"sort_order"`
+	// Facets is a comma-separated list of ProductFacetNames to count for
+	// the current filters.
+	Facets  string `form:"facets"`
+	Locale  string `form:"-"`
+	StoreID string `form:"-"`
+}
+// NewArrivalsQuery pages through recently added products. Days defaults to
+// the catalog's new arrivals window.
+type NewArrivalsQuery struct {
+	Page    int    `form:"page"`
+	Limit   int    `form:"limit"`
+	Days    int    `form:"days"`
+	Locale  string `form:"-"`
+	StoreID string `form:"-"`
 }
 type PaginatedProducts struct {
+	Locale     string              `json:"locale,omitempty"`
 	Data       []ProductWithRating `json:"data"`
 	Pagination Pagination          `json:"pagination"`
+	Facets     *ProductFacets      `json:"facets,omitempty"`
+}
+const (
+	FacetCategory     = "category"
+	FacetPrice        = "price"
+	FacetAvailability = "availability"
+)
+// ProductFacetNames are the facets accepted by the facets= query parameter.
+var ProductFacetNames = []string{FacetCategory, FacetPrice, FacetAvailability}
+// ProductFacets holds product counts for the requested facets. The category
+// facet ignores the category filter so the sidebar can offer the other
+// categories; the others count the filtered listing.
+type ProductFacets struct {
+	Category     []FacetCount       `json:"category,omitempty"`
+	Price        []PriceFacetBucket `json:"price,omitempty"`
+	Availability []FacetCount       `json:"availability,omitempty"`
+}
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+// PriceFacetBucket counts products priced from Min (inclusive) up to Max
+// (exclusive); the open-ended first and last buckets omit one bound.
+type PriceFacetBucket struct {
+	Min   *Money `json:"min,omitempty"`
+	Max   *Money `json:"max,omitempty"`
+	Count int    `json:"count"`
 }
 type Pagination struct {
 	Page  int `json:"page"`
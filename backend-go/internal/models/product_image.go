@@ -0,0 +1,19 @@
+﻿package models
+import (
+	"time"
+)
+type ProductImage struct {
+	ID        string    `json:"id" db:"id"`
+	ProductID string    `json:"product_id" db:"product_id"`
+	URL       string    `json:"url" db:"url"`
+	Position  int       `json:"position" db:"position"`
+	IsPrimary bool      `json:"is_primary" db:"is_primary"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+type ProductImageCreateRequest struct {
+	URL       string `json:"url" binding:"required"`
+	IsPrimary bool   `json:"is_primary"`
+}
+type ProductImageReorderRequest struct {
+	ImageIDs []string `json:"image_ids" binding:"required,min=1"`
+}
@@ -0,0 +1,122 @@
+﻿package models
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+// Quantity is an amount of a product in thousandths of its unit, so 1.5 kg
+// is 1500 and three T-shirts are 3000. It is stored as NUMERIC(12,3) and
+// encoded in JSON as a plain decimal number such as 1.5 or 3.
+type Quantity int64
+const quantityScale = 1000
+// Product units. Count products are sold per piece and take whole
+// quantities; measured units accept up to three decimal places.
+const (
+	UnitEach       = "each"
+	UnitKilogram   = "kg"
+	UnitGram       = "g"
+	UnitPound      = "lb"
+	UnitMeter      = "m"
+	UnitCentimeter = "cm"
+	UnitLiter      = "l"
+	UnitMilliliter = "ml"
+)
+func IsMeasuredUnit(unit string) bool {
+	return unit != "" && unit != UnitEach
+}
+// CheckQuantity rejects non-positive amounts and fractional amounts of a
+// product sold in unit.
+func CheckQuantity(unit string, quantity Quantity) error {
+	if quantity <= 0 {
+		return fmt.Errorf("quantity must be positive")
+	}
+	if !IsMeasuredUnit(unit) && !quantity.IsWhole() {
+		return fmt.Errorf("quantity must be a whole number")
+	}
+	return nil
+}
+func QuantityFromInt(n int) Quantity {
+	return Quantity(n) * quantityScale
+}
+// ParseQuantity reads a decimal amount with at most three decimal places.
+func ParseQuantity(text string) (Quantity, error) {
+	amount, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || math.IsInf(amount, 0) || math.IsNaN(amount) {
+		return 0, fmt.Errorf("invalid quantity %q", text)
+	}
+	scaled := math.Round(amount * quantityScale)
+	if math.Abs(scaled-amount*quantityScale) > 1e-6 {
+		return 0, fmt.Errorf("quantity %q has more than three decimal places", text)
+	}
+	return Quantity(scaled), nil
+}
+func (q Quantity) IsWhole() bool {
+	return q%quantityScale == 0
+}
+// Int returns the whole units in q, dropping any fraction.
+func (q Quantity) Int() int {
+	return int(q / quantityScale)
+}
+// Ceil returns the whole units needed to cover q.
+func (q Quantity) Ceil() int {
+	if q > 0 && !q.IsWhole() {
+		return q.Int() + 1
+	}
+	return q.Int()
+}
+func (q Quantity) String() string {
+	sign := ""
+	thousandths := int64(q)
+	if thousandths < 0 {
+		sign = "-"
+		thousandths = -thousandths
+	}
+	whole, fraction := thousandths/quantityScale, thousandths%quantityScale
+	if fraction == 0 {
+		return fmt.Sprintf("%s%d", sign, whole)
+	}
+	return strings.TrimRight(fmt.Sprintf("%s%d.%03d", sign, whole, fraction), "0")
+}
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "null" {
+		return nil
+	}
+	quantity, err := ParseQuantity(text)
+	if err != nil {
+		return err
+	}
+	*q = quantity
+	return nil
+}
+// Scan reads NUMERIC quantities, which arrive as text, as well as plain
+// integers from aggregates over older INTEGER columns.
+func (q *Quantity) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case int64:
+		*q = Quantity(v) * quantityScale
+	case float64:
+		*q = Quantity(math.Round(v * quantityScale))
+	case []byte:
+		return q.Scan(string(v))
+	case string:
+		quantity, err := ParseQuantity(v)
+		if err != nil {
+			return err
+		}
+		*q = quantity
+	case nil:
+		*q = 0
+	default:
+		return fmt.Errorf("cannot scan %T into Quantity", value)
+	}
+	return nil
+}
+func (q Quantity) Value() (driver.Value, error) {
+	return q.String(), nil
+}
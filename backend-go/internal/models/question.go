@@ -0,0 +1,69 @@
+﻿package models
+import "time"
+const (
+	QAVoteQuestion = "question"
+	QAVoteAnswer   = "answer"
+)
+// Questions and answers from shoppers start pending and are listed once a
+// moderator approves them; staff posts are approved at once. Content
+// reported QAReportThreshold times goes back to pending for review.
+const (
+	QAStatusPending  = "pending"
+	QAStatusApproved = "approved"
+	QAStatusHidden   = "hidden"
+)
+const QAReportThreshold = 3
+type ProductQuestion struct {
+	ID        string          `json:"id" db:"id"`
+	ProductID string          `json:"product_id" db:"product_id"`
+	UserID    *string         `json:"user_id" db:"user_id"`
+	UserName  string          `json:"user_name"`
+	Body      string          `json:"body" db:"body"`
+	Status    string          `json:"status" db:"status"`
+	Reports   int             `json:"reports,omitempty" db:"reports"`
+	Upvotes   int             `json:"upvotes" db:"upvotes"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at" db:"updated_at"`
+	Answers   []ProductAnswer `json:"answers"`
+}
+type ProductAnswer struct {
+	ID         string    `json:"id" db:"id"`
+	QuestionID string    `json:"question_id" db:"question_id"`
+	UserID     *string   `json:"user_id" db:"user_id"`
+	UserName   string    `json:"user_name"`
+	Body       string    `json:"body" db:"body"`
+	IsOfficial bool      `json:"is_official" db:"is_official"`
+	Status     string    `json:"status" db:"status"`
+	Reports    int       `json:"reports,omitempty" db:"reports"`
+	Upvotes    int       `json:"upvotes" db:"upvotes"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+type QuestionCreateRequest struct {
+	ProductID string `json:"product_id" binding:"required"`
+	Body      string `json:"body" binding:"required,max=1000"`
+}
+// AnswerCreateRequest marks the answer official when Official is set; only
+// admin and seller accounts may do so.
+type AnswerCreateRequest struct {
+	Body     string `json:"body" binding:"required,max=2000"`
+	Official bool   `json:"official"`
+}
+type QAReportRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+type QAStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=pending approved hidden"`
+}
+// QAModerationQuery lists questions or answers in one status for moderators,
+// oldest first.
+type QAModerationQuery struct {
+	Status string `form:"status,default=pending" binding:"oneof=pending approved hidden"`
+	Page   int    `form:"page,default=1" binding:"min=1"`
+	Limit  int    `form:"limit,default=50" binding:"min=1,max=200"`
+}
+type QuestionQuery struct {
+	Page  int    `form:"page"`
+	Limit int    `form:"limit"`
+	Sort  string `form:"sort" binding:"omitempty,oneof=newest top"`
+}
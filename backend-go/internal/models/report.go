@@ -0,0 +1,55 @@
+﻿package models
+import "time"
+const (
+	ReportFormatCSV  = "csv"
+	ReportFormatJSON = "json"
+)
+// OrderReportQuery selects orders created in [From, To). Empty Status and
+// StoreID match every order.
+type OrderReportQuery struct {
+	From         time.Time
+	To           time.Time
+	Status       OrderStatus
+	StoreID      string
+	IncludeItems bool
+}
+// OrderReportRow is one order with its money columns. Paid sums succeeded
+// payments and Refunded sums issued return refunds, so Net = Paid - Refunded
+// reconciles with the payment provider even for partial refunds.
+type OrderReportRow struct {
+	OrderID     string      `json:"order_id"`
+	StoreID     string      `json:"store_id"`
+	UserID      string      `json:"user_id"`
+	Status      OrderStatus `json:"status"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Subtotal    Money       `json:"subtotal"`
+	Tax         Money       `json:"tax"`
+	Shipping    Money       `json:"shipping"`
+	GiftWrapFee Money       `json:"gift_wrap_fee"`
+	Total       Money       `json:"total"`
+	Paid        Money       `json:"paid"`
+	Refunded    Money       `json:"refunded"`
+	Net         Money       `json:"net"`
+	Items       []OrderItem `json:"items,omitempty"`
+}
+// OrderReportTotals sums the money columns of every row in a report.
+type OrderReportTotals struct {
+	Orders   int   `json:"orders"`
+	Subtotal Money `json:"subtotal"`
+	Tax      Money `json:"tax"`
+	Shipping Money `json:"shipping"`
+	Total    Money `json:"total"`
+	Paid     Money `json:"paid"`
+	Refunded Money `json:"refunded"`
+	Net      Money `json:"net"`
+}
+func (t *OrderReportTotals) Add(row *OrderReportRow) {
+	t.Orders++
+	t.Subtotal += row.Subtotal
+	t.Tax += row.Tax
+	t.Shipping += row.Shipping
+	t.Total += row.Total
+	t.Paid += row.Paid
+	t.Refunded += row.Refunded
+	t.Net += row.Net
+}
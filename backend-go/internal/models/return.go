@@ -0,0 +1,36 @@
+﻿package models
+import (
+	"time"
+)
+type ReturnStatus string
+const (
+	ReturnStatusRequested ReturnStatus = "requested"
+	ReturnStatusApproved  ReturnStatus = "approved"
+	ReturnStatusRejected  ReturnStatus = "rejected"
+	ReturnStatusReceived  ReturnStatus = "received"
+)
+type OrderReturn struct {
+	ID           string       `json:"id" db:"id"`
+	OrderID      string       `json:"order_id" db:"order_id"`
+	UserID       string       `json:"user_id" db:"user_id"`
+	Status       ReturnStatus `json:"status" db:"status"`
+	Reason       string       `json:"reason" db:"reason"`
+	AdminNote    *string      `json:"admin_note" db:"admin_note"`
+	RefundAmount Money        `json:"refund_amount" db:"refund_amount"`
+	RefundID     *string      `json:"refund_id" db:"refund_id"`
+	Items        []ReturnItem `json:"items"`
+	ReceivedAt   *time.Time   `json:"received_at" db:"received_at"`
+	CreatedAt    time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at" db:"updated_at"`
+}
+type ReturnItem struct {
+	OrderItemID string   `json:"order_item_id" db:"order_item_id" binding:"required"`
+	Quantity    Quantity `json:"quantity" db:"quantity" binding:"required,gt=0"`
+}
+type ReturnCreateRequest struct {
+	Reason string       `json:"reason" binding:"required,max=2000"`
+	Items  []ReturnItem `json:"items" binding:"required,min=1,dive"`
+}
+type ReturnDecisionRequest struct {
+	Note *string `json:"note"`
+}
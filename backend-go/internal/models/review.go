@@ -1,30 +1,118 @@
 ﻿package models
 import (
+	"fmt"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
+	"ecommerce-backend/internal/utils"
 )
 type Review struct {
+	ID        string        `json:"id" db:"id"`
+	UserID    string        `json:"user_id" db:"user_id"`
+	ProductID string        `json:"product_id" db:"product_id"`
+	Rating    int           `json:"rating" db:"rating"`
+	Comment   *string       `json:"comment" db:"comment"`
+	Helpful   *bool         `json:"helpful" db:"helpful"`
+	CreatedAt time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at" db:"updated_at"`
+	Images    []ReviewImage `json:"images,omitempty"`
+}
+type ReviewImage struct {
+	ID       string `json:"id" db:"id"`
+	ReviewID string `json:"review_id" db:"review_id"`
+	UploadID string `json:"upload_id" db:"upload_id"`
+	Filename string `json:"filename" db:"filename"`
+	URL      string `json:"url"`
+	Size     int64  `json:"size" db:"size"`
+	Position int    `json:"position" db:"position"`
+}
+type ReviewWithUser struct {
+	Review
+	UserName  string       `json:"user_name"`
+	UserImage *string      `json:"user_image"`
+	Reply     *ReviewReply `json:"reply,omitempty"`
+}
+type ReviewReply struct {
 	ID        string    `json:"id" db:"id"`
+	ReviewID  string    `json:"review_id" db:"review_id"`
 	UserID    string    `json:"user_id" db:"user_id"`
-	ProductID string    `json:"product_id" db:"product_id"`
-	Rating    int       `json:"rating" db:"rating"`
-	Comment   *string   `json:"comment" db:"comment"`
-	Helpful   *bool     `json:"helpful" db:"helpful"`
+	Body      string    `json:"body" db:"body"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
-type ReviewWithUser struct {
-	Review
-	UserName  string  `json:"user_name"`
-	UserImage *string `json:"user_image"`
+type ReviewReplyRequest struct {
+	Body string `json:"body" binding:"required"`
 }
 type ReviewCreateRequest struct {
-	ProductID string `json:"product_id" binding:"required"`
-	Rating    int    `json:"rating" binding:"required,min=1,max=5"`
-	Comment   string `json:"comment" binding:"required"`
-	Helpful   *bool  `json:"helpful"`
+	ProductID string   `json:"product_id" binding:"required,uuid"`
+	Rating    int      `json:"rating"`
+	Comment   string   `json:"comment"`
+	Helpful   *bool    `json:"helpful"`
+	Images    []string `json:"images"`
 }
 type ReviewUpdateRequest struct {
-	Rating  *int    `json:"rating"`
-	Comment *string `json:"comment"`
-	Helpful *bool   `json:"helpful"`
+	Rating  *int     `json:"rating"`
+	Comment *string  `json:"comment"`
+	Helpful *bool    `json:"helpful"`
+	Images  []string `json:"images"`
+}
+const (
+	MinReviewRating = 1
+	MaxReviewRating = 5
+)
+// ReviewLimits bounds review comments by character count.
+type ReviewLimits struct {
+	CommentMinLength int
+	CommentMaxLength int
+}
+// Check validates a rating and comment, either of which may be nil when not
+// being changed. The comment is trimmed in place; control characters other
+// than newlines and tabs are rejected.
+func (l ReviewLimits) Check(rating *int, comment *string) utils.ValidationErrors {
+	v := utils.NewValidator()
+	if rating != nil && (*rating < MinReviewRating || *rating > MaxReviewRating) {
+		v.AddError("rating", fmt.Sprintf("must be between %d and %d", MinReviewRating, MaxReviewRating))
+	}
+	if comment != nil {
+		*comment = strings.TrimSpace(*comment)
+		length := utf8.RuneCountInString(*comment)
+		switch {
+		case length == 0 && l.CommentMinLength > 0:
+			v.AddError("comment", "is required")
+		case length < l.CommentMinLength:
+			v.AddError("comment", fmt.Sprintf("must be at least %d characters long", l.CommentMinLength))
+		case l.CommentMaxLength > 0 && length > l.CommentMaxLength:
+			v.AddError("comment", fmt.Sprintf("must be at most %d characters long", l.CommentMaxLength))
+		}
+		if strings.IndexFunc(*comment, isDisallowedControl) >= 0 {
+			v.AddError("comment", "must not contain control characters")
+		}
+	}
+	return v.GetErrors()
+}
+func isDisallowedControl(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t'
+}
+type ReviewQuery struct {
+	Page     int    `form:"page"`
+	Limit    int    `form:"limit"`
+	Sort     string `form:"sort"`
+	Rating   int    `form:"rating" binding:"omitempty,min=1,max=5"`
+	HasReply *bool  `form:"has_reply"`
+}
+type ReviewSummary struct {
+	ProductID     string      `json:"product_id"`
+	TotalCount    int         `json:"total_count"`
+	AverageRating float64     `json:"average_rating"`
+	Distribution  map[int]int `json:"distribution"`
+	VerifiedCount int         `json:"verified_count"`
+}
+type ReviewSummaryBatchRequest struct {
+	ProductIDs []string `json:"product_ids" binding:"required,min=1,max=100,dive,uuid"`
+}
+type RatingSummary struct {
+	ProductID     string  `json:"product_id"`
+	AverageRating float64 `json:"average_rating"`
+	ReviewCount   int     `json:"review_count"`
 }
@@ -0,0 +1,18 @@
+﻿package models
+const (
+	SearchTypeProducts     = "products"
+	SearchTypeCategories   = "categories"
+	SuggestionTypeProduct  = "product"
+	SuggestionTypeCategory = "category"
+)
+type SearchResults struct {
+	Query      string              `json:"query"`
+	Products   []ProductWithRating `json:"products,omitempty"`
+	Categories []*Category         `json:"categories,omitempty"`
+}
+type SearchSuggestion struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+	Score int    `json:"-"`
+}
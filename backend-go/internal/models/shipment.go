@@ -0,0 +1,36 @@
+﻿package models
+import (
+	"time"
+)
+type ShipmentStatus string
+const (
+	ShipmentStatusShipped   ShipmentStatus = "shipped"
+	ShipmentStatusInTransit ShipmentStatus = "in_transit"
+	ShipmentStatusDelivered ShipmentStatus = "delivered"
+)
+type Shipment struct {
+	ID             string         `json:"id" db:"id"`
+	OrderID        string         `json:"order_id" db:"order_id"`
+	Carrier        *string        `json:"carrier" db:"carrier"`
+	TrackingNumber *string        `json:"tracking_number" db:"tracking_number"`
+	Status         ShipmentStatus `json:"status" db:"status"`
+	Items          []ShipmentItem `json:"items"`
+	ShippedAt      time.Time      `json:"shipped_at" db:"shipped_at"`
+	DeliveredAt    *time.Time     `json:"delivered_at" db:"delivered_at"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+}
+type ShipmentItem struct {
+	OrderItemID string   `json:"order_item_id" db:"order_item_id"`
+	Quantity    Quantity `json:"quantity" db:"quantity"`
+}
+type ShipmentCreateRequest struct {
+	Carrier        *string        `json:"carrier"`
+	TrackingNumber *string        `json:"tracking_number"`
+	Items          []ShipmentItem `json:"items"`
+}
+type ShipmentUpdateRequest struct {
+	Status         *ShipmentStatus `json:"status" binding:"omitempty,oneof=shipped in_transit delivered"`
+	Carrier        *string         `json:"carrier"`
+	TrackingNumber *string         `json:"tracking_number"`
+}
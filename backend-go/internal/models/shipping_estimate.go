@@ -0,0 +1,28 @@
+﻿package models
+import (
+	"time"
+)
+const (
+	ShippingMethodStandard = "standard"
+	ShippingMethodExpress  = "express"
+)
+type ShippingEstimateRequest struct {
+	Country    string `json:"country" binding:"required,len=2"`
+	PostalCode string `json:"postal_code" binding:"max=12"`
+}
+type ShippingMethod struct {
+	Code         string     `json:"code"`
+	Name         string     `json:"name"`
+	Cost         Money      `json:"cost"`
+	Free         bool       `json:"free"`
+	MinDays      int        `json:"min_days"`
+	MaxDays      int        `json:"max_days"`
+	EarliestDate *time.Time `json:"earliest_delivery,omitempty"`
+	LatestDate   *time.Time `json:"latest_delivery,omitempty"`
+}
+type ShippingEstimate struct {
+	Country    string           `json:"country"`
+	PostalCode string           `json:"postal_code,omitempty"`
+	Subtotal   Money            `json:"subtotal"`
+	Methods    []ShippingMethod `json:"methods"`
+}
@@ -0,0 +1,26 @@
+﻿package models
+import (
+	"time"
+)
+// DefaultStoreID is the store created by the migration that introduced
+// stores; all pre-existing products, categories and orders belong to it.
+const DefaultStoreID = "00000000-0000-0000-0000-000000000001"
+type Store struct {
+	ID        string    `json:"id" db:"id"`
+	Slug      string    `json:"slug" db:"slug"`
+	Name      string    `json:"name" db:"name"`
+	Domain    *string   `json:"domain,omitempty" db:"domain"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+type StoreCreateRequest struct {
+	Slug   string `json:"slug" binding:"required,max=64"`
+	Name   string `json:"name" binding:"required,max=255"`
+	Domain string `json:"domain" binding:"max=255"`
+}
+type StoreUpdateRequest struct {
+	Name   *string `json:"name" binding:"omitempty,max=255"`
+	Domain *string `json:"domain" binding:"omitempty,max=255"`
+	Active *bool   `json:"active"`
+}
@@ -0,0 +1,16 @@
+﻿package models
+import (
+	"time"
+)
+type Translation struct {
+	EntityID    string    `json:"entity_id"`
+	Locale      string    `json:"locale"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+type TranslationRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Description *string `json:"description"`
+}
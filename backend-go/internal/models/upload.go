@@ -0,0 +1,47 @@
+﻿package models
+import (
+	"fmt"
+	"math"
+	"time"
+)
+type Upload struct {
+	ID          string     `json:"id" db:"id"`
+	UserID      string     `json:"user_id" db:"user_id"`
+	Filename    string     `json:"filename" db:"filename"`
+	ContentType string     `json:"content_type" db:"content_type"`
+	Size        int64      `json:"size" db:"size"`
+	Private     bool       `json:"private" db:"private"`
+	URL         string     `json:"url"`
+	URLExpires  *time.Time `json:"url_expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+// UploadPurposeProduct marks an upload meant for a product gallery, which
+// must meet the configured ImageConstraints.
+const UploadPurposeProduct = "product"
+// ImageConstraints are the smallest dimensions an image may have and, when
+// AspectRatio (width / height) is set, the ratio it must be within
+// AspectTolerance of.
+type ImageConstraints struct {
+	MinWidth        int     `json:"min_width"`
+	MinHeight       int     `json:"min_height"`
+	AspectRatio     float64 `json:"aspect_ratio,omitempty"`
+	AspectTolerance float64 `json:"-"`
+}
+func (c ImageConstraints) Check(width, height int) error {
+	if width < c.MinWidth || height < c.MinHeight {
+		return fmt.Errorf("image is too small")
+	}
+	if c.AspectRatio > 0 && height > 0 {
+		ratio := float64(width) / float64(height)
+		if math.Abs(ratio-c.AspectRatio) > c.AspectRatio*c.AspectTolerance {
+			return fmt.Errorf("image aspect ratio is not allowed")
+		}
+	}
+	return nil
+}
+type UploadUsage struct {
+	UsedBytes      int64 `json:"used_bytes"`
+	QuotaBytes     int64 `json:"quota_bytes"`
+	RemainingBytes int64 `json:"remaining_bytes"`
+	Unlimited      bool  `json:"unlimited"`
+}
@@ -9,13 +9,22 @@ type User struct {
 	Password  string    `json:"-" db:"password"`
 	Role      string    `json:"role" db:"role"`
 	Image     *string   `json:"image" db:"image"`
+	StoreID   *string   `json:"store_id" db:"store_id"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 type UserCreateRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
-	Name     string `json:"name"`
+	Email        string `json:"email" binding:"required,email"`
+	Password     string `json:"password" binding:"required,min=6"`
+	Name         string `json:"name"`
+	CaptchaToken string `json:"captcha_token"`
+}
+type UserRoleUpdateRequest struct {
+	Role string `json:"role" binding:"required,oneof=user seller admin finance"`
+}
+// UserStoreUpdateRequest pins a user to a store; a null store_id unpins them.
+type UserStoreUpdateRequest struct {
+	StoreID *string `json:"store_id" binding:"omitempty,uuid"`
 }
 type UserLoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
@@ -27,6 +36,7 @@ type UserResponse struct {
 	Name      *string   `json:"name"`
 	Role      string    `json:"role"`
 	Image     *string   `json:"image"`
+	StoreID   *string   `json:"store_id"`
 	CreatedAt time.Time `json:"created_at"`
 }
 type AuthResponse struct {
@@ -41,6 +51,7 @@ func (u *User) ToResponse() UserResponse {
 		Name:      u.Name,
 		Role:      u.Role,
 		Image:     u.Image,
+		StoreID:   u.StoreID,
 		CreatedAt: u.CreatedAt,
 	}
 }
\ No newline at end of file
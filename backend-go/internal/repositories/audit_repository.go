@@ -0,0 +1,87 @@
+﻿package repositories
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"ecommerce-backend/internal/models"
+)
+type AuditRepository struct {
+	db *sql.DB
+}
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+func (r *AuditRepository) Create(entry *models.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (id, actor_id, actor_ip, action, target_type, target_id, before, after, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.db.Exec(query, entry.ID, entry.ActorID, entry.ActorIP, entry.Action,
+		entry.TargetType, entry.TargetID, nullJSON(entry.Before), nullJSON(entry.After), entry.CreatedAt)
+	return err
+}
+func (r *AuditRepository) List(query models.AuditQuery, limit, offset int) ([]models.AuditEntry, int, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	add := func(clause string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(clause, len(args)))
+	}
+	if query.Action != "" {
+		add("action = $%d", query.Action)
+	}
+	if query.ActorID != "" {
+		add("actor_id = $%d", query.ActorID)
+	}
+	if query.TargetType != "" {
+		add("target_type = $%d", query.TargetType)
+	}
+	if query.TargetID != "" {
+		add("target_id = $%d", query.TargetID)
+	}
+	if !query.From.IsZero() {
+		add("created_at >= $%d", query.From)
+	}
+	if !query.To.IsZero() {
+		add("created_at <= $%d", query.To)
+	}
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM audit_log "+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	selectQuery := fmt.Sprintf(`
+		SELECT id, actor_id, COALESCE(actor_ip, ''), action, COALESCE(target_type, ''), COALESCE(target_id, ''), before, after, created_at
+		FROM audit_log %s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+	rows, err := r.db.Query(selectQuery, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var before, after []byte
+		if err := rows.Scan(&entry.ID, &entry.ActorID, &entry.ActorIP, &entry.Action, &entry.TargetType,
+			&entry.TargetID, &before, &after, &entry.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		entry.Before = before
+		entry.After = after
+		entries = append(entries, entry)
+	}
+	return entries, total, nil
+}
+func nullJSON(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
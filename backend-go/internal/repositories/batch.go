@@ -0,0 +1,71 @@
+﻿package repositories
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+// DefaultBatchSize is the number of rows CreateBatch methods write per
+// statement when no batch size is given. With about a dozen columns it stays
+// well below PostgreSQL's limit of 65535 bind parameters.
+const DefaultBatchSize = 500
+// insertBatches writes rows with one multi-row INSERT and one transaction per
+// batch, so a failure only loses the batch it happened in. It returns the
+// number of rows written, which is less than len(rows) when suffix skips
+// conflicts.
+func insertBatches(db *sql.DB, insert, suffix string, rows [][]interface{}, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	inserted := 0
+	for start := 0; start < len(rows); start += batchSize {
+		end := start + batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return inserted, err
+		}
+		affected, err := insertRows(tx, insert, suffix, rows[start:end])
+		if err != nil {
+			tx.Rollback()
+			return inserted, fmt.Errorf("failed to insert rows %d-%d: %w", start+1, end, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return inserted, err
+		}
+		inserted += affected
+	}
+	return inserted, nil
+}
+// insertRows writes rows with a single multi-row INSERT. insert is the
+// statement up to and including VALUES and suffix follows the value lists.
+func insertRows(tx *sql.Tx, insert, suffix string, rows [][]interface{}) (int, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	var query strings.Builder
+	query.WriteString(insert)
+	args := make([]interface{}, 0, len(rows)*len(rows[0]))
+	for i, row := range rows {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(")
+		for j, value := range row {
+			if j > 0 {
+				query.WriteString(", ")
+			}
+			args = append(args, value)
+			fmt.Fprintf(&query, "$%d", len(args))
+		}
+		query.WriteString(")")
+	}
+	query.WriteString(" " + suffix)
+	result, err := tx.Exec(query.String(), args...)
+	if err != nil {
+		return 0, err
+	}
+	affected, _ := result.RowsAffected()
+	return int(affected), nil
+}
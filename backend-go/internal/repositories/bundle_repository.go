@@ -0,0 +1,139 @@
+﻿package repositories
+import (
+	"database/sql"
+	"fmt"
+	"ecommerce-backend/internal/models"
+	"github.com/lib/pq"
+)
+type BundleRepository struct {
+	db *sql.DB
+}
+func NewBundleRepository(db *sql.DB) *BundleRepository {
+	return &BundleRepository{db: db}
+}
+// GetComponents returns a bundle's components, or none when the product is
+// not a bundle. Deleted components are reported out of stock.
+func (r *BundleRepository) GetComponents(bundleID string) ([]models.BundleComponent, error) {
+	query := `
+		SELECT bi.component_id, c.name, bi.quantity, c.unit, c.product_type, c.images[1], c.stock, c.in_stock AND c.deleted_at IS NULL
+		FROM product_bundle_items bi
+		JOIN products c ON c.id = bi.component_id
+		WHERE bi.bundle_id = $1
+		ORDER BY c.name, c.id
+	`
+	rows, err := r.db.Query(query, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	components := []models.BundleComponent{}
+	for rows.Next() {
+		var component models.BundleComponent
+		if err := rows.Scan(&component.ProductID, &component.Name, &component.Quantity, &component.Unit, &component.ProductType,
+			&component.Image, &component.Stock, &component.InStock); err != nil {
+			return nil, err
+		}
+		components = append(components, component)
+	}
+	return components, rows.Err()
+}
+// Set replaces a bundle's components and derives its stock from theirs.
+// Components must be live products of the bundle's store, and bundles
+// cannot be nested in either direction.
+func (r *BundleRepository) Set(bundleID string, items []models.BundleItemRequest) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var storeID string
+	err = tx.QueryRow("SELECT store_id FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", bundleID).Scan(&storeID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("product not found")
+	}
+	if err != nil {
+		return err
+	}
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ProductID
+	}
+	var found int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM products WHERE id::text = ANY($1) AND deleted_at IS NULL AND store_id = $2",
+		pq.Array(ids), storeID).Scan(&found); err != nil {
+		return err
+	}
+	if found != len(ids) {
+		return fmt.Errorf("bundle component not found")
+	}
+	var nested bool
+	if err := tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM product_bundle_items WHERE component_id = $1 OR bundle_id::text = ANY($2))
+	`, bundleID, pq.Array(ids)).Scan(&nested); err != nil {
+		return err
+	}
+	if nested {
+		return fmt.Errorf("bundles cannot contain bundles")
+	}
+	if _, err := tx.Exec("DELETE FROM product_bundle_items WHERE bundle_id = $1", bundleID); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if _, err := tx.Exec("INSERT INTO product_bundle_items (bundle_id, component_id, quantity) VALUES ($1, $2, $3)",
+			bundleID, item.ProductID, item.Quantity); err != nil {
+			return err
+		}
+	}
+	if err := syncBundleStock(tx, bundleID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+// Delete turns a bundle back into a plain product. It keeps the stock last
+// derived from its components, recorded in the ledger as an adjustment.
+func (r *BundleRepository) Delete(bundleID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	result, err := tx.Exec("DELETE FROM product_bundle_items WHERE bundle_id = $1", bundleID)
+	if err != nil {
+		return err
+	}
+	if removed, _ := result.RowsAffected(); removed == 0 {
+		return fmt.Errorf("bundle not found")
+	}
+	_, err = tx.Exec(`
+		INSERT INTO inventory_movements (product_id, delta, stock_after, reason, reference)
+		SELECT p.id, p.stock - COALESCE(SUM(m.delta), 0), p.stock, $2, 'bundle'
+		FROM products p
+		LEFT JOIN inventory_movements m ON m.product_id = p.id
+		WHERE p.id = $1
+		GROUP BY p.id, p.stock
+		HAVING p.stock <> COALESCE(SUM(m.delta), 0)
+	`, bundleID, models.InventoryReasonAdjustment)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+// syncBundleStock sets the stock of the bundles productID belongs to, or of
+// productID itself when it is a bundle, to the number of whole bundles their
+// components can make up. Bundle stock is derived, so it is not recorded in
+// the ledger.
+func syncBundleStock(tx *sql.Tx, productID string) error {
+	_, err := tx.Exec(`
+		UPDATE products b
+		SET stock = s.available, in_stock = s.available > 0, updated_at = NOW(), version = b.version + 1
+		FROM (
+			SELECT bi.bundle_id, MIN(CASE WHEN c.in_stock AND c.deleted_at IS NULL THEN GREATEST(c.stock, 0) / bi.quantity ELSE 0 END) AS available
+			FROM product_bundle_items bi
+			JOIN products c ON c.id = bi.component_id
+			WHERE bi.bundle_id IN (SELECT bundle_id FROM product_bundle_items WHERE component_id = $1 OR bundle_id = $1)
+			GROUP BY bi.bundle_id
+		) s
+		WHERE b.id = s.bundle_id AND (b.stock <> s.available OR b.in_stock <> (s.available > 0))
+	`, productID)
+	return err
+}
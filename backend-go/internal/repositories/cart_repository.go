@@ -3,6 +3,8 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
+	"ecommerce-backend/internal/database"
 	"ecommerce-backend/internal/models"
 	"github.com/lib/pq"
 )
@@ -14,20 +16,30 @@ func NewCartRepository(db *sql.DB) *CartRepository {
 }
 func (r *CartRepository) Create(item *models.CartItem) error {
 	query := `
-		INSERT INTO cart_items (id, user_id, product_id, quantity, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO cart_items (id, user_id, product_id, quantity, added_price, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
-	_, err := r.db.Exec(query, item.ID, item.UserID, item.ProductID, item.Quantity, item.CreatedAt, item.UpdatedAt)
-	return err
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(query, item.ID, item.UserID, item.ProductID, item.Quantity, item.AddedPrice, item.CreatedAt, item.UpdatedAt); err != nil {
+		return err
+	}
+	if _, err := bumpCartVersion(tx, item.UserID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 func (r *CartRepository) GetByID(id string) (*models.CartItem, error) {
 	query := `
-		SELECT id, user_id, product_id, quantity, created_at, updated_at
+		SELECT id, user_id, product_id, quantity, added_price, created_at, updated_at
 		FROM cart_items WHERE id = $1
 	`
 	item := &models.CartItem{}
 	err := r.db.QueryRow(query, id).Scan(
-		&item.ID, &item.UserID, &item.ProductID, &item.Quantity, &item.CreatedAt, &item.UpdatedAt,
+		&item.ID, &item.UserID, &item.ProductID, &item.Quantity, &item.AddedPrice, &item.CreatedAt, &item.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("cart item not found")
@@ -36,12 +48,12 @@ func (r *CartRepository) GetByID(id string) (*models.CartItem, error) {
 }
 func (r *CartRepository) GetByUserAndProduct(userID, productID string) (*models.CartItem, error) {
 	query := `
-		SELECT id, user_id, product_id, quantity, created_at, updated_at
+		SELECT id, user_id, product_id, quantity, added_price, created_at, updated_at
 		FROM cart_items WHERE user_id = $1 AND product_id = $2
 	`
 	item := &models.CartItem{}
 	err := r.db.QueryRow(query, userID, productID).Scan(
-		&item.ID, &item.UserID, &item.ProductID, &item.Quantity, &item.CreatedAt, &item.UpdatedAt,
+		&item.ID, &item.UserID, &item.ProductID, &item.Quantity, &item.AddedPrice, &item.CreatedAt, &item.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("cart item not found")
@@ -49,12 +61,21 @@ func (r *CartRepository) GetByUserAndProduct(userID, productID string) (*models.
 	return item, err
 }
 func (r *CartRepository) GetByUserID(userID string) ([]models.CartItemWithProduct, error) {
+	var items []models.CartItemWithProduct
+	err := database.RetryRead(func() error {
+		var err error
+		items, err = r.getByUserID(userID)
+		return err
+	})
+	return items, err
+}
+func (r *CartRepository) getByUserID(userID string) ([]models.CartItemWithProduct, error) {
 	query := `
-		SELECT ci.id, ci.user_id, ci.product_id, ci.quantity, ci.created_at, ci.updated_at,
-		       p.id, p.name, p.slug, p.description, p.price, p.compare_price, p.images, p.in_stock, p.stock, p.featured, p.category_id, p.created_at, p.updated_at
+		SELECT ci.id, ci.user_id, ci.product_id, ci.quantity, ci.added_price, ci.created_at, ci.updated_at,
+		       p.id, p.name, p.slug, p.description, p.price, p.compare_price, p.images, p.in_stock, p.stock, p.featured, p.product_type, p.unit, p.category_id, p.created_at, p.updated_at
 		FROM cart_items ci
 		JOIN products p ON ci.product_id = p.id
-		WHERE ci.user_id = $1
+		WHERE ci.user_id = $1 AND p.deleted_at IS NULL
 		ORDER BY ci.created_at DESC
 	`
 	rows, err := r.db.Query(query, userID)
@@ -68,9 +89,9 @@ func (r *CartRepository) GetByUserID(userID string) ([]models.CartItemWithProduc
 		product := models.Product{}
 		var images pq.StringArray
 		err := rows.Scan(
-			&item.ID, &item.UserID, &item.ProductID, &item.Quantity, &item.CreatedAt, &item.UpdatedAt,
+			&item.ID, &item.UserID, &item.ProductID, &item.Quantity, &item.AddedPrice, &item.CreatedAt, &item.UpdatedAt,
 			&product.ID, &product.Name, &product.Slug, &product.Description, &product.Price, &product.ComparePrice,
-			&images, &product.InStock, &product.Stock, &product.Featured, &product.CategoryID, &product.CreatedAt, &product.UpdatedAt,
+			&images, &product.InStock, &product.Stock, &product.Featured, &product.ProductType, &product.Unit, &product.CategoryID, &product.CreatedAt, &product.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -95,25 +116,117 @@ func (r *CartRepository) Update(id string, updates map[string]interface{}) error
 		args = append(args, value)
 		argIndex++
 	}
-	query := fmt.Sprintf("UPDATE cart_items SET %s WHERE id = $%d", strings.Join(setParts, ", "), argIndex)
+	query := fmt.Sprintf("UPDATE cart_items SET %s WHERE id = $%d RETURNING user_id", strings.Join(setParts, ", "), argIndex)
 	args = append(args, id)
-	_, err := r.db.Exec(query, args...)
-	return err
+	return r.mutateItem(query, args...)
 }
 func (r *CartRepository) Delete(id string) error {
-	query := "DELETE FROM cart_items WHERE id = $1"
-	_, err := r.db.Exec(query, id)
-	return err
+	return r.mutateItem("DELETE FROM cart_items WHERE id = $1 RETURNING user_id", id)
 }
 func (r *CartRepository) DeleteByUserID(userID string) error {
-	query := "DELETE FROM cart_items WHERE user_id = $1"
-	_, err := r.db.Exec(query, userID)
-	return err
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM cart_items WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	if _, err := bumpCartVersion(tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+func (r *CartRepository) GetVersion(userID string) (int64, *time.Time, error) {
+	var version int64
+	var updatedAt time.Time
+	err := r.db.QueryRow("SELECT version, updated_at FROM carts WHERE user_id = $1", userID).Scan(&version, &updatedAt)
+	if err == sql.ErrNoRows {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	return version, &updatedAt, nil
+}
+// UpdateQuantityVersioned sets an item's quantity (removing it at zero) only
+// if the cart is still at expectedVersion, and returns the new version. The
+// conditional UPDATE on carts locks the row, so of several writers holding
+// the same version exactly one succeeds.
+func (r *CartRepository) UpdateQuantityVersioned(userID, itemID string, quantity models.Quantity, expectedVersion int64) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("INSERT INTO carts (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING", userID); err != nil {
+		return 0, err
+	}
+	var version int64
+	err = tx.QueryRow(`
+		UPDATE carts SET version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND version = $2
+		RETURNING version
+	`, userID, expectedVersion).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("cart version conflict")
+	}
+	if err != nil {
+		return 0, err
+	}
+	var result sql.Result
+	if quantity <= 0 {
+		result, err = tx.Exec("DELETE FROM cart_items WHERE id = $1 AND user_id = $2", itemID, userID)
+	} else {
+		result, err = tx.Exec("UPDATE cart_items SET quantity = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND user_id = $3", quantity, itemID, userID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if rows == 0 {
+		return 0, fmt.Errorf("cart item not found")
+	}
+	return version, tx.Commit()
+}
+func (r *CartRepository) mutateItem(query string, args ...interface{}) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var userID string
+	err = tx.QueryRow(query, args...).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := bumpCartVersion(tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+// bumpCartVersion gives a user's cart a new version inside tx, creating the
+// carts row if needed.
+func bumpCartVersion(tx *sql.Tx, userID string) (int64, error) {
+	var version int64
+	err := tx.QueryRow(`
+		INSERT INTO carts (user_id, version) VALUES ($1, 1)
+		ON CONFLICT (user_id) DO UPDATE SET version = carts.version + 1, updated_at = CURRENT_TIMESTAMP
+		RETURNING version
+	`, userID).Scan(&version)
+	return version, err
 }
 func (r *CartRepository) GetUserCartItems(userID string) ([]*models.CartItem, error) {
 	query := `
-		SELECT id, user_id, product_id, quantity, created_at, updated_at
-		FROM cart_items WHERE user_id = $1 ORDER BY created_at DESC
+		SELECT ci.id, ci.user_id, ci.product_id, ci.quantity, ci.added_price, ci.created_at, ci.updated_at
+		FROM cart_items ci
+		JOIN products p ON ci.product_id = p.id
+		WHERE ci.user_id = $1 AND p.deleted_at IS NULL
+		ORDER BY ci.created_at DESC
 	`
 	rows, err := r.db.Query(query, userID)
 	if err != nil {
@@ -124,7 +237,7 @@ func (r *CartRepository) GetUserCartItems(userID string) ([]*models.CartItem, er
 	for rows.Next() {
 		item := &models.CartItem{}
 		err := rows.Scan(
-			&item.ID, &item.UserID, &item.ProductID, &item.Quantity, &item.CreatedAt, &item.UpdatedAt,
+			&item.ID, &item.UserID, &item.ProductID, &item.Quantity, &item.AddedPrice, &item.CreatedAt, &item.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -135,4 +248,99 @@ func (r *CartRepository) GetUserCartItems(userID string) ([]*models.CartItem, er
 }
 func (r *CartRepository) ClearUserCart(userID string) error {
 	return r.DeleteByUserID(userID)
+}
+// RemoveStaleItems deletes cart lines whose product is soft-deleted and, when
+// expiredBefore is set, lines not updated since then. A notice is stored for
+// each removed line and affected carts get a new version. An empty userID
+// sweeps every cart.
+func (r *CartRepository) RemoveStaleItems(userID string, expiredBefore *time.Time) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.Query(`
+		WITH removed AS (
+			DELETE FROM cart_items ci
+			USING products p
+			WHERE ci.product_id = p.id
+			  AND ($1 = '' OR ci.user_id::text = $1)
+			  AND (p.deleted_at IS NOT NULL OR ($2::timestamp IS NOT NULL AND ci.updated_at < $2::timestamp))
+			RETURNING ci.user_id, ci.product_id, p.name, p.deleted_at IS NOT NULL AS discontinued
+		)
+		INSERT INTO cart_notices (user_id, product_id, product_name, type)
+		SELECT user_id, product_id, name, CASE WHEN discontinued THEN $3 ELSE $4 END FROM removed
+		RETURNING user_id
+	`, userID, expiredBefore, models.CartNoticeDiscontinued, models.CartNoticeExpired)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	users := make(map[string]bool)
+	for rows.Next() {
+		var affected string
+		if err := rows.Scan(&affected); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		users[affected] = true
+		removed++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for affected := range users {
+		if _, err := bumpCartVersion(tx, affected); err != nil {
+			return 0, err
+		}
+	}
+	return removed, tx.Commit()
+}
+func (r *CartRepository) GetNotices(userID string) ([]models.CartNotice, error) {
+	rows, err := r.db.Query(`
+		SELECT type, COALESCE(product_id::text, ''), COALESCE(product_name, ''), created_at
+		FROM cart_notices WHERE user_id = $1 ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	notices := []models.CartNotice{}
+	for rows.Next() {
+		var notice models.CartNotice
+		if err := rows.Scan(&notice.Type, &notice.ProductID, &notice.ProductName, &notice.CreatedAt); err != nil {
+			return nil, err
+		}
+		notices = append(notices, notice)
+	}
+	return notices, rows.Err()
+}
+// AcknowledgeNotices clears stored removal notices and accepts the current
+// price of every line in the cart.
+func (r *CartRepository) AcknowledgeNotices(userID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM cart_notices WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	result, err := tx.Exec(`
+		UPDATE cart_items ci SET added_price = p.price
+		FROM products p
+		WHERE ci.product_id = p.id AND ci.user_id = $1 AND ci.added_price IS DISTINCT FROM p.price
+	`, userID)
+	if err != nil {
+		return err
+	}
+	if changed, err := result.RowsAffected(); err != nil {
+		return err
+	} else if changed > 0 {
+		if _, err := bumpCartVersion(tx, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
\ No newline at end of file
@@ -3,6 +3,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"ecommerce-backend/internal/database"
 	"ecommerce-backend/internal/models"
 )
 type CategoryRepository struct {
@@ -13,46 +14,64 @@ func NewCategoryRepository(db *sql.DB) *CategoryRepository {
 }
 func (r *CategoryRepository) Create(category *models.Category) error {
 	query := `
-		INSERT INTO categories (id, name, slug, description, image, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO categories (id, name, slug, description, image, store_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
-	_, err := r.db.Exec(query, category.ID, category.Name, category.Slug, category.Description, category.Image, category.CreatedAt, category.UpdatedAt)
+	category.StoreID = storeOrDefault(category.StoreID)
+	_, err := r.db.Exec(query, category.ID, category.Name, category.Slug, category.Description, category.Image, category.StoreID, category.CreatedAt, category.UpdatedAt)
 	return err
 }
 func (r *CategoryRepository) GetByID(id string) (*models.Category, error) {
 	query := `
-		SELECT id, name, slug, description, image, created_at, updated_at
+		SELECT id, name, slug, description, image, store_id, version, created_at, updated_at
 		FROM categories WHERE id = $1
 	`
 	category := &models.Category{}
-	err := r.db.QueryRow(query, id).Scan(
-		&category.ID, &category.Name, &category.Slug, &category.Description, &category.Image, &category.CreatedAt, &category.UpdatedAt,
-	)
+	err := database.RetryRead(func() error {
+		return r.db.QueryRow(query, id).Scan(
+			&category.ID, &category.Name, &category.Slug, &category.Description, &category.Image, &category.StoreID, &category.Version, &category.CreatedAt, &category.UpdatedAt,
+		)
+	})
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("category not found")
 	}
 	return category, err
 }
-func (r *CategoryRepository) GetBySlug(slug string) (*models.Category, error) {
+// GetBySlug looks a slug up within one store, since slugs are only unique
+// per store. An empty storeID means the default store.
+func (r *CategoryRepository) GetBySlug(storeID, slug string) (*models.Category, error) {
 	query := `
-		SELECT id, name, slug, description, image, created_at, updated_at
-		FROM categories WHERE slug = $1
+		SELECT id, name, slug, description, image, store_id, version, created_at, updated_at
+		FROM categories WHERE store_id = $1 AND slug = $2
 	`
 	category := &models.Category{}
-	err := r.db.QueryRow(query, slug).Scan(
-		&category.ID, &category.Name, &category.Slug, &category.Description, &category.Image, &category.CreatedAt, &category.UpdatedAt,
-	)
+	err := database.RetryRead(func() error {
+		return r.db.QueryRow(query, storeOrDefault(storeID), slug).Scan(
+			&category.ID, &category.Name, &category.Slug, &category.Description, &category.Image, &category.StoreID, &category.Version, &category.CreatedAt, &category.UpdatedAt,
+		)
+	})
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("category not found")
 	}
 	return category, err
 }
-func (r *CategoryRepository) List(limit, offset int) ([]*models.Category, error) {
+// List pages through the categories of a store, or of every store when
+// storeID is empty.
+func (r *CategoryRepository) List(storeID string, limit, offset int) ([]*models.Category, error) {
+	var categories []*models.Category
+	err := database.RetryRead(func() error {
+		var err error
+		categories, err = r.list(storeID, limit, offset)
+		return err
+	})
+	return categories, err
+}
+func (r *CategoryRepository) list(storeID string, limit, offset int) ([]*models.Category, error) {
 	query := `
-		SELECT id, name, slug, description, image, created_at, updated_at
-		FROM categories ORDER BY name LIMIT $1 OFFSET $2
+		SELECT id, name, slug, description, image, store_id, version, created_at, updated_at
+		FROM categories WHERE ($3::uuid IS NULL OR store_id = $3) ORDER BY name LIMIT $1 OFFSET $2
 	`
-	rows, err := r.db.Query(query, limit, offset)
+	rows, err := r.db.Query(query, limit, offset, storeParam(storeID))
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +80,7 @@ func (r *CategoryRepository) List(limit, offset int) ([]*models.Category, error)
 	for rows.Next() {
 		category := &models.Category{}
 		err := rows.Scan(
-			&category.ID, &category.Name, &category.Slug, &category.Description, &category.Image, &category.CreatedAt, &category.UpdatedAt,
+			&category.ID, &category.Name, &category.Slug, &category.Description, &category.Image, &category.StoreID, &category.Version, &category.CreatedAt, &category.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -74,19 +93,28 @@ func (r *CategoryRepository) Update(id string, updates map[string]interface{}) e
 	if len(updates) == 0 {
 		return nil
 	}
-	setParts := make([]string, 0, len(updates))
-	args := make([]interface{}, 0, len(updates)+1)
-	argIndex := 1
-	for key, value := range updates {
-		setParts = append(setParts, fmt.Sprintf("%s = $%d", key, argIndex))
-		args = append(args, value)
-		argIndex++
-	}
-	query := fmt.Sprintf("UPDATE categories SET %s WHERE id = $%d", strings.Join(setParts, ", "), argIndex)
-	args = append(args, id)
-	_, err := r.db.Exec(query, args...)
+	_, err := updateVersioned(r.db, "categories", "", id, nil, updates, nil)
+	if err != nil && err.Error() == "not found" {
+		return nil
+	}
 	return err
 }
+// UpdateVersioned applies updates only if the category is still at
+// expectedVersion and returns the new version. It fails with "category
+// version conflict" when someone else saved first. Callers find the category
+// by slug within its store first, so the id is not scoped again.
+func (r *CategoryRepository) UpdateVersioned(id string, expectedVersion int64, updates map[string]interface{}) (int64, error) {
+	version, err := updateVersioned(r.db, "categories", "", id, &expectedVersion, updates, nil)
+	if err != nil {
+		switch err.Error() {
+		case "not found":
+			return 0, fmt.Errorf("category not found")
+		case "version conflict":
+			return 0, fmt.Errorf("category version conflict")
+		}
+	}
+	return version, err
+}
 func (r *CategoryRepository) Delete(id string) error {
 	query := "DELETE FROM categories WHERE id = $1"
 	_, err := r.db.Exec(query, id)
@@ -95,8 +123,8 @@ func (r *CategoryRepository) Delete(id string) error {
 func (r *CategoryRepository) CreateCategory(category *models.Category) error {
 	return r.Create(category)
 }
-func (r *CategoryRepository) GetCategoryBySlug(slug string) (*models.Category, error) {
-	return r.GetBySlug(slug)
+func (r *CategoryRepository) GetCategoryBySlug(storeID, slug string) (*models.Category, error) {
+	return r.GetBySlug(storeID, slug)
 }
 func (r *CategoryRepository) UpdateCategory(id string, updates map[string]interface{}) error {
 	return r.Update(id, updates)
@@ -104,12 +132,132 @@ func (r *CategoryRepository) UpdateCategory(id string, updates map[string]interf
 func (r *CategoryRepository) DeleteCategory(id string) error {
 	return r.Delete(id)
 }
-func (r *CategoryRepository) GetCategories(limit, offset int) ([]*models.Category, error) {
-	return r.List(limit, offset)
+func (r *CategoryRepository) GetCategories(storeID string, limit, offset int) ([]*models.Category, error) {
+	return r.List(storeID, limit, offset)
 }
-func (r *CategoryRepository) CountCategories() (int, error) {
-	query := "SELECT COUNT(*) FROM categories"
+func (r *CategoryRepository) CountCategories(storeID string) (int, error) {
+	query := "SELECT COUNT(*) FROM categories WHERE ($1::uuid IS NULL OR store_id = $1)"
 	var count int
-	err := r.db.QueryRow(query).Scan(&count)
+	err := r.db.QueryRow(query, storeParam(storeID)).Scan(&count)
 	return count, err
-}
\ No newline at end of file
+}
+func (r *CategoryRepository) Search(storeID, query string, limit int) ([]*models.Category, error) {
+	searchQuery := `
+		SELECT id, name, slug, description, image, store_id, version, created_at, updated_at
+		FROM categories
+		WHERE (name ILIKE $1 ESCAPE '\' OR description ILIKE $1 ESCAPE '\') AND ($3::uuid IS NULL OR store_id = $3)
+		ORDER BY name
+		LIMIT $2
+	`
+	rows, err := r.db.Query(searchQuery, "%"+escapeLike(query)+"%", limit, storeParam(storeID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var categories []*models.Category
+	for rows.Next() {
+		category := &models.Category{}
+		err := rows.Scan(
+			&category.ID, &category.Name, &category.Slug, &category.Description, &category.Image, &category.StoreID, &category.Version, &category.CreatedAt, &category.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+// SearchByText pages through the categories whose name or slug contains
+// text, case-insensitively, each with its count of live products. It also
+// returns the total number of matches.
+func (r *CategoryRepository) SearchByText(storeID, text string, limit, offset int) ([]models.CategoryWithProducts, int, error) {
+	pattern := "%" + escapeLike(text) + "%"
+	var total int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM categories
+		WHERE (name ILIKE $1 ESCAPE '\' OR slug ILIKE $1 ESCAPE '\') AND ($2::uuid IS NULL OR store_id = $2)
+	`, pattern, storeParam(storeID)).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := r.db.Query(`
+		SELECT c.id, c.name, c.slug, c.description, c.image, c.store_id, c.version, c.created_at, c.updated_at,
+		       (SELECT COUNT(*) FROM products p WHERE p.category_id = c.id AND p.deleted_at IS NULL)
+		FROM categories c
+		WHERE (c.name ILIKE $1 ESCAPE '\' OR c.slug ILIKE $1 ESCAPE '\') AND ($4::uuid IS NULL OR c.store_id = $4)
+		ORDER BY c.name, c.id
+		LIMIT $2 OFFSET $3
+	`, pattern, limit, offset, storeParam(storeID))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var categories []models.CategoryWithProducts
+	for rows.Next() {
+		var category models.CategoryWithProducts
+		err := rows.Scan(
+			&category.ID, &category.Name, &category.Slug, &category.Description, &category.Image, &category.StoreID, &category.Version, &category.CreatedAt, &category.UpdatedAt,
+			&category.Count,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, total, rows.Err()
+}
+// GetStats computes the stats of a category by slug in one grouped query.
+// Categories are not nested, so only the category's own products count.
+func (r *CategoryRepository) GetStats(storeID, slug string) (*models.CategoryStats, error) {
+	query := `
+		SELECT c.id, c.slug, COUNT(p.id), COUNT(p.id) FILTER (WHERE p.in_stock), MIN(p.price), MAX(p.price),
+		       COALESCE(SUM(rs.reviews), 0), SUM(rs.rating_sum) / NULLIF(SUM(rs.reviews), 0)
+		FROM categories c
+		LEFT JOIN products p ON p.category_id = c.id AND p.deleted_at IS NULL
+		LEFT JOIN (
+			SELECT product_id, COUNT(*) AS reviews, SUM(rating)::float8 AS rating_sum
+			FROM reviews GROUP BY product_id
+		) rs ON rs.product_id = p.id
+		WHERE c.store_id = $1 AND c.slug = $2
+		GROUP BY c.id, c.slug
+	`
+	stats := &models.CategoryStats{}
+	err := database.RetryRead(func() error {
+		return r.db.QueryRow(query, storeOrDefault(storeID), slug).Scan(
+			&stats.CategoryID, &stats.Slug, &stats.ProductCount, &stats.InStockCount, &stats.MinPrice, &stats.MaxPrice,
+			&stats.ReviewCount, &stats.AverageRating,
+		)
+	})
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("category not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+func (r *CategoryRepository) SuggestByPrefix(storeID, prefix string, limit int) ([]models.SearchSuggestion, error) {
+	query := `
+		SELECT c.id, c.name, COUNT(p.id) AS popularity
+		FROM categories c
+		LEFT JOIN products p ON p.category_id = c.id
+		WHERE lower(c.name) LIKE $1 ESCAPE '\' AND ($3::uuid IS NULL OR c.store_id = $3)
+		GROUP BY c.id, c.name
+		ORDER BY popularity DESC, c.name
+		LIMIT $2
+	`
+	rows, err := r.db.Query(query, escapeLike(strings.ToLower(prefix))+"%", limit, storeParam(storeID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var suggestions []models.SearchSuggestion
+	for rows.Next() {
+		suggestion := models.SearchSuggestion{Type: models.SuggestionTypeCategory}
+		if err := rows.Scan(&suggestion.ID, &suggestion.Label, &suggestion.Score); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,98 @@
+﻿package repositories
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"ecommerce-backend/internal/models"
+)
+type DataExportRepository struct {
+	db *sql.DB
+}
+func NewDataExportRepository(db *sql.DB) *DataExportRepository {
+	return &DataExportRepository{db: db}
+}
+// CreateUnlessRecent creates export unless the user has another export that
+// has not failed created after since. The user's row is locked so concurrent
+// requests cannot both pass the check. It reports whether export was created.
+func (r *DataExportRepository) CreateUnlessRecent(export *models.DataExport, since time.Time) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("SELECT 1 FROM users WHERE id = $1 FOR UPDATE", export.UserID); err != nil {
+		return false, err
+	}
+	var recent bool
+	err = tx.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM data_exports WHERE user_id = $1 AND status <> 'failed' AND created_at > $2)",
+		export.UserID, since,
+	).Scan(&recent)
+	if err != nil || recent {
+		return false, err
+	}
+	query := `
+		INSERT INTO data_exports (id, user_id, token, status, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	if _, err := tx.Exec(query, export.ID, export.UserID, export.Token, export.Status, export.CreatedAt); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+func (r *DataExportRepository) GetByToken(token string) (*models.DataExport, error) {
+	return r.getOne("WHERE token = $1", token)
+}
+// ListExpired returns up to limit ready exports whose download link expired
+// before now.
+func (r *DataExportRepository) ListExpired(now time.Time, limit int) ([]*models.DataExport, error) {
+	return r.list("WHERE status = 'ready' AND expires_at < $1 ORDER BY expires_at LIMIT $2", now, limit)
+}
+// ListByUser returns every export of the user.
+func (r *DataExportRepository) ListByUser(userID string) ([]*models.DataExport, error) {
+	return r.list("WHERE user_id = $1", userID)
+}
+func (r *DataExportRepository) list(where string, args ...interface{}) ([]*models.DataExport, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, token, status, file_path, created_at, completed_at, expires_at
+		FROM data_exports `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var exports []*models.DataExport
+	for rows.Next() {
+		export := &models.DataExport{}
+		if err := rows.Scan(
+			&export.ID, &export.UserID, &export.Token, &export.Status, &export.FilePath,
+			&export.CreatedAt, &export.CompletedAt, &export.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		exports = append(exports, export)
+	}
+	return exports, rows.Err()
+}
+func (r *DataExportRepository) getOne(where string, arg interface{}) (*models.DataExport, error) {
+	query := `
+		SELECT id, user_id, token, status, file_path, created_at, completed_at, expires_at
+		FROM data_exports ` + where
+	export := &models.DataExport{}
+	err := r.db.QueryRow(query, arg).Scan(
+		&export.ID, &export.UserID, &export.Token, &export.Status, &export.FilePath,
+		&export.CreatedAt, &export.CompletedAt, &export.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("export not found")
+	}
+	return export, err
+}
+func (r *DataExportRepository) Update(export *models.DataExport) error {
+	query := `
+		UPDATE data_exports
+		SET status = $2, file_path = $3, completed_at = $4, expires_at = $5
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(query, export.ID, export.Status, export.FilePath, export.CompletedAt, export.ExpiresAt)
+	return err
+}
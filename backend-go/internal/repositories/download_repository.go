@@ -0,0 +1,122 @@
+﻿package repositories
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"ecommerce-backend/internal/models"
+)
+type DownloadRepository struct {
+	db *sql.DB
+}
+func NewDownloadRepository(db *sql.DB) *DownloadRepository {
+	return &DownloadRepository{db: db}
+}
+// GetOrderProductTypes returns the digital product IDs in an order and
+// whether the order also holds physical items.
+func (r *DownloadRepository) GetOrderProductTypes(orderID string) ([]string, bool, error) {
+	query := `
+		SELECT DISTINCT p.id, p.product_type
+		FROM order_items oi
+		JOIN products p ON oi.product_id = p.id
+		WHERE oi.order_id = $1
+	`
+	rows, err := r.db.Query(query, orderID)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+	var digital []string
+	hasPhysical := false
+	for rows.Next() {
+		var productID string
+		var productType models.ProductType
+		if err := rows.Scan(&productID, &productType); err != nil {
+			return nil, false, err
+		}
+		if productType == models.ProductTypeDigital {
+			digital = append(digital, productID)
+		} else {
+			hasPhysical = true
+		}
+	}
+	return digital, hasPhysical, rows.Err()
+}
+// Create inserts a download link unless the order already has one for the
+// product, so issuing links on a repeated payment event is a no-op.
+func (r *DownloadRepository) Create(download *models.OrderDownload) (bool, error) {
+	query := `
+		INSERT INTO order_downloads (order_id, product_id, user_id, token, max_downloads, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (order_id, product_id) DO NOTHING
+	`
+	result, err := r.db.Exec(query, download.OrderID, download.ProductID, download.UserID, download.Token,
+		download.MaxDownloads, download.ExpiresAt, download.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+func (r *DownloadRepository) GetByOrderID(orderID string) ([]models.OrderDownload, error) {
+	query := `
+		SELECT d.id, d.order_id, d.product_id, p.name, d.user_id, d.token, d.download_count, d.max_downloads,
+		       d.expires_at, d.last_downloaded_at, d.created_at
+		FROM order_downloads d
+		JOIN products p ON d.product_id = p.id
+		WHERE d.order_id = $1
+		ORDER BY p.name
+	`
+	rows, err := r.db.Query(query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	downloads := []models.OrderDownload{}
+	for rows.Next() {
+		var d models.OrderDownload
+		err := rows.Scan(&d.ID, &d.OrderID, &d.ProductID, &d.ProductName, &d.UserID, &d.Token, &d.DownloadCount,
+			&d.MaxDownloads, &d.ExpiresAt, &d.LastDownloadedAt, &d.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		downloads = append(downloads, d)
+	}
+	return downloads, rows.Err()
+}
+// Consume counts one use of the link identified by token and returns it with
+// the product's file. The count only moves while the link is unexpired and
+// under its limit, so concurrent requests cannot exceed MaxDownloads.
+func (r *DownloadRepository) Consume(token string, now time.Time) (*models.OrderDownload, error) {
+	query := `
+		UPDATE order_downloads d
+		SET download_count = d.download_count + 1, last_downloaded_at = $2
+		FROM products p
+		WHERE d.token = $1 AND p.id = d.product_id
+		  AND d.download_count < d.max_downloads AND d.expires_at > $2
+		RETURNING d.id, d.order_id, d.product_id, p.name, d.user_id, d.download_count, d.max_downloads,
+		          d.expires_at, d.last_downloaded_at, d.created_at, COALESCE(p.digital_file, '')
+	`
+	d := &models.OrderDownload{Token: token}
+	err := r.db.QueryRow(query, token, now).Scan(&d.ID, &d.OrderID, &d.ProductID, &d.ProductName, &d.UserID,
+		&d.DownloadCount, &d.MaxDownloads, &d.ExpiresAt, &d.LastDownloadedAt, &d.CreatedAt, &d.File)
+	if err == nil {
+		return d, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+	var count, max int
+	var expiresAt time.Time
+	err = r.db.QueryRow("SELECT download_count, max_downloads, expires_at FROM order_downloads WHERE token = $1", token).
+		Scan(&count, &max, &expiresAt)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, fmt.Errorf("download not found")
+	case err != nil:
+		return nil, err
+	case !expiresAt.After(now):
+		return nil, fmt.Errorf("download expired")
+	default:
+		return nil, fmt.Errorf("download limit reached")
+	}
+}
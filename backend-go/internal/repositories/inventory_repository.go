@@ -0,0 +1,249 @@
+﻿package repositories
+import (
+	"database/sql"
+	"fmt"
+	"ecommerce-backend/internal/models"
+)
+type InventoryRepository struct {
+	db *sql.DB
+}
+func NewInventoryRepository(db *sql.DB) *InventoryRepository {
+	return &InventoryRepository{db: db}
+}
+// Apply changes a product's stock by delta and records the movement in the
+// same transaction.
+func (r *InventoryRepository) Apply(productID string, delta int, reason models.InventoryReason, reference *string) (*models.InventoryMovement, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	movement, err := applyMovement(tx, productID, delta, reason, reference)
+	if err != nil {
+		return nil, err
+	}
+	return movement, tx.Commit()
+}
+// SetStock sets a product's stock to an absolute level and records the
+// difference. It returns nil when the stock is already at that level. A
+// bundle's stock follows its components and cannot be set.
+func (r *InventoryRepository) SetStock(productID string, stock int, reason models.InventoryReason, reference *string) (*models.InventoryMovement, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var current int
+	err = tx.QueryRow("SELECT stock FROM products WHERE id = $1 FOR UPDATE", productID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("product not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	var bundle bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM product_bundle_items WHERE bundle_id = $1)", productID).Scan(&bundle); err != nil {
+		return nil, err
+	}
+	if bundle {
+		return nil, fmt.Errorf("bundle stock is derived from its components")
+	}
+	if current == stock {
+		return nil, nil
+	}
+	movement, err := applyMovement(tx, productID, stock-current, reason, reference)
+	if err != nil {
+		return nil, err
+	}
+	return movement, tx.Commit()
+}
+func applyMovement(tx *sql.Tx, productID string, delta int, reason models.InventoryReason, reference *string) (*models.InventoryMovement, error) {
+	movement := &models.InventoryMovement{ProductID: productID, Delta: delta, Reason: reason, Reference: reference}
+	err := tx.QueryRow(`
+		UPDATE products
+		SET stock = stock + $2, in_stock = (stock + $2) > 0, updated_at = NOW(), version = version + 1
+		WHERE id = $1
+		RETURNING stock
+	`, productID, delta).Scan(&movement.StockAfter)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("product not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return movement, insertMovement(tx, movement)
+}
+// takeMovement takes units from a product's stock within tx, provided that
+// many are left, and records the movement. It fails with "insufficient
+// stock" rather than letting the stock go negative.
+func takeMovement(tx *sql.Tx, productID string, units int, reason models.InventoryReason, reference *string) (*models.InventoryMovement, error) {
+	movement := &models.InventoryMovement{ProductID: productID, Delta: -units, Reason: reason, Reference: reference}
+	err := tx.QueryRow(`
+		UPDATE products
+		SET stock = stock - $2, in_stock = (stock - $2) > 0, updated_at = NOW(), version = version + 1
+		WHERE id = $1 AND stock >= $2
+		RETURNING stock
+	`, productID, units).Scan(&movement.StockAfter)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("insufficient stock")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return movement, insertMovement(tx, movement)
+}
+// insertMovement records a stock change already applied within tx and
+// brings the stock of bundles containing the product in line.
+func insertMovement(tx *sql.Tx, movement *models.InventoryMovement) error {
+	err := tx.QueryRow(`
+		INSERT INTO inventory_movements (product_id, delta, stock_after, reason, reference)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, movement.ProductID, movement.Delta, movement.StockAfter, movement.Reason, movement.Reference).Scan(&movement.ID, &movement.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return syncBundleStock(tx, movement.ProductID)
+}
+// Record writes a movement for a stock change that has already been made,
+// such as the opening stock of a new product.
+func (r *InventoryRepository) Record(movement *models.InventoryMovement) error {
+	query := `
+		INSERT INTO inventory_movements (product_id, delta, stock_after, reason, reference)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(query, movement.ProductID, movement.Delta, movement.StockAfter, movement.Reason, movement.Reference).
+		Scan(&movement.ID, &movement.CreatedAt)
+}
+// Revert undoes the net effect of the from movements recorded against
+// reference, recording the reversal under reason. Reverting twice is a
+// no-op, and references without from movements are left alone.
+func (r *InventoryRepository) Revert(reference string, from, reason models.InventoryReason) ([]models.InventoryMovement, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.Query(`
+		SELECT product_id, SUM(delta)
+		FROM inventory_movements
+		WHERE reference = $1 AND reason IN ($2, $3)
+		GROUP BY product_id
+		HAVING SUM(delta) <> 0
+		ORDER BY product_id
+	`, reference, from, reason)
+	if err != nil {
+		return nil, err
+	}
+	nets := map[string]int{}
+	var productIDs []string
+	for rows.Next() {
+		var productID string
+		var net int
+		if err := rows.Scan(&productID, &net); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		nets[productID] = net
+		productIDs = append(productIDs, productID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	movements := []models.InventoryMovement{}
+	for _, productID := range productIDs {
+		movement, err := applyMovement(tx, productID, -nets[productID], reason, &reference)
+		if err != nil {
+			return nil, err
+		}
+		movements = append(movements, *movement)
+	}
+	return movements, tx.Commit()
+}
+// RecordOpeningBalances adds an initial movement for every product with
+// stock but no ledger yet, for rows inserted without going through Apply.
+func (r *InventoryRepository) RecordOpeningBalances(reference string) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO inventory_movements (product_id, delta, stock_after, reason, reference)
+		SELECT p.id, p.stock, p.stock, 'initial', $1
+		FROM products p
+		WHERE p.stock <> 0 AND NOT EXISTS (SELECT 1 FROM inventory_movements m WHERE m.product_id = p.id)
+	`, reference)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+// ListByProduct returns a product's movements, newest first, optionally
+// limited to one reason.
+func (r *InventoryRepository) ListByProduct(productID string, reason string, limit, offset int) ([]models.InventoryMovement, int, error) {
+	var total int
+	err := r.db.QueryRow(
+		"SELECT COUNT(*) FROM inventory_movements WHERE product_id = $1 AND ($2 = '' OR reason = $2)",
+		productID, reason,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := `
+		SELECT id, product_id, delta, stock_after, reason, reference, created_at
+		FROM inventory_movements
+		WHERE product_id = $1 AND ($2 = '' OR reason = $2)
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(query, productID, reason, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	movements := []models.InventoryMovement{}
+	for rows.Next() {
+		var movement models.InventoryMovement
+		if err := rows.Scan(&movement.ID, &movement.ProductID, &movement.Delta, &movement.StockAfter,
+			&movement.Reason, &movement.Reference, &movement.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		movements = append(movements, movement)
+	}
+	return movements, total, rows.Err()
+}
+// Reconcile compares each product's stock with the sum of its movements.
+// Bundles are left out since their stock follows their components.
+func (r *InventoryRepository) Reconcile(storeID string, mismatchedOnly bool) ([]models.InventoryReconciliation, error) {
+	query := `
+		SELECT p.id, p.name, p.sku, p.stock, COALESCE(m.ledger, 0), COALESCE(m.movements, 0), m.last_movement_at
+		FROM products p
+		LEFT JOIN (
+			SELECT product_id, SUM(delta) AS ledger, COUNT(*) AS movements, MAX(created_at) AS last_movement_at
+			FROM inventory_movements
+			GROUP BY product_id
+		) m ON m.product_id = p.id
+		WHERE p.deleted_at IS NULL AND ($1::uuid IS NULL OR p.store_id = $1)
+		  AND NOT EXISTS (SELECT 1 FROM product_bundle_items bi WHERE bi.bundle_id = p.id)
+		  AND (NOT $2 OR p.stock <> COALESCE(m.ledger, 0))
+		ORDER BY p.name, p.id
+	`
+	rows, err := r.db.Query(query, storeParam(storeID), mismatchedOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	products := []models.InventoryReconciliation{}
+	for rows.Next() {
+		var product models.InventoryReconciliation
+		var lastMovementAt sql.NullTime
+		if err := rows.Scan(&product.ProductID, &product.Name, &product.SKU, &product.Stock,
+			&product.LedgerStock, &product.Movements, &lastMovementAt); err != nil {
+			return nil, err
+		}
+		product.Difference = product.Stock - product.LedgerStock
+		if lastMovementAt.Valid {
+			product.LastMovementAt = &lastMovementAt.Time
+		}
+		products = append(products, product)
+	}
+	return products, rows.Err()
+}
@@ -0,0 +1,87 @@
+﻿package repositories
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"ecommerce-backend/internal/models"
+)
+type MaintenanceRepository struct {
+	db *sql.DB
+}
+func NewMaintenanceRepository(db *sql.DB) *MaintenanceRepository {
+	return &MaintenanceRepository{db: db}
+}
+const maintenanceWindowColumns = "id, starts_at, ends_at, message, status, last_alert_lead, created_by, created_at, updated_at"
+func scanMaintenanceWindow(row interface{ Scan(...interface{}) error }) (*models.MaintenanceWindow, error) {
+	window := &models.MaintenanceWindow{}
+	err := row.Scan(&window.ID, &window.StartsAt, &window.EndsAt, &window.Message, &window.Status,
+		&window.LastAlertLead, &window.CreatedBy, &window.CreatedAt, &window.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("maintenance window not found")
+	}
+	return window, err
+}
+// Times are stored as UTC in TIMESTAMP columns, like the rest of the schema.
+func (r *MaintenanceRepository) Create(window *models.MaintenanceWindow) error {
+	query := `
+		INSERT INTO maintenance_windows (starts_at, ends_at, message, status, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+	return r.db.QueryRow(query, window.StartsAt.UTC(), window.EndsAt.UTC(), window.Message, window.Status,
+		window.CreatedBy, window.CreatedAt, window.UpdatedAt).Scan(&window.ID)
+}
+func (r *MaintenanceRepository) GetByID(id string) (*models.MaintenanceWindow, error) {
+	return scanMaintenanceWindow(r.db.QueryRow("SELECT "+maintenanceWindowColumns+" FROM maintenance_windows WHERE id = $1", id))
+}
+// List returns open (scheduled or active) windows, or every window when
+// includeClosed is set, soonest first.
+func (r *MaintenanceRepository) List(includeClosed bool) ([]models.MaintenanceWindow, error) {
+	query := "SELECT " + maintenanceWindowColumns + " FROM maintenance_windows"
+	if !includeClosed {
+		query += " WHERE status IN ('scheduled', 'active')"
+	}
+	rows, err := r.db.Query(query + " ORDER BY starts_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	windows := []models.MaintenanceWindow{}
+	for rows.Next() {
+		window, err := scanMaintenanceWindow(rows)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, *window)
+	}
+	return windows, rows.Err()
+}
+// Overlaps reports whether an open window intersects [start, end).
+func (r *MaintenanceRepository) Overlaps(start, end time.Time) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM maintenance_windows
+			WHERE status IN ('scheduled', 'active') AND starts_at < $2 AND ends_at > $1
+		)
+	`, start.UTC(), end.UTC()).Scan(&exists)
+	return exists, err
+}
+// Transition moves a window from one status to another and reports whether
+// it was still in the expected status, so the scheduler and a concurrent
+// cancel never both act on the same window.
+func (r *MaintenanceRepository) Transition(id string, from, to models.MaintenanceWindowStatus) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE maintenance_windows SET status = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND status = $2",
+		id, from, to,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+func (r *MaintenanceRepository) SetLastAlertLead(id string, lead time.Duration) error {
+	_, err := r.db.Exec("UPDATE maintenance_windows SET last_alert_lead = $2 WHERE id = $1", id, int64(lead.Seconds()))
+	return err
+}
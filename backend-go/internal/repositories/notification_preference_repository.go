@@ -0,0 +1,79 @@
+﻿package repositories
+import (
+	"database/sql"
+	"ecommerce-backend/internal/models"
+	"github.com/lib/pq"
+)
+type NotificationPreferenceRepository struct {
+	db *sql.DB
+}
+func NewNotificationPreferenceRepository(db *sql.DB) *NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{db: db}
+}
+// GetByUserID returns only the settings the user has stored; callers fill the
+// rest from the defaults.
+func (r *NotificationPreferenceRepository) GetByUserID(userID string) (models.NotificationPreferences, error) {
+	rows, err := r.db.Query("SELECT channel, event_type, enabled FROM notification_preferences WHERE user_id = $1", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	prefs := models.NotificationPreferences{}
+	for rows.Next() {
+		var channel models.NotificationChannel
+		var event models.NotificationEvent
+		var enabled bool
+		if err := rows.Scan(&channel, &event, &enabled); err != nil {
+			return nil, err
+		}
+		if prefs[channel] == nil {
+			prefs[channel] = map[models.NotificationEvent]bool{}
+		}
+		prefs[channel][event] = enabled
+	}
+	return prefs, rows.Err()
+}
+// GetSettings returns the stored setting for one channel and event for each
+// of userIDs that has one.
+func (r *NotificationPreferenceRepository) GetSettings(channel models.NotificationChannel, event models.NotificationEvent, userIDs []string) (map[string]bool, error) {
+	query := `
+		SELECT user_id, enabled FROM notification_preferences
+		WHERE channel = $1 AND event_type = $2 AND user_id = ANY($3::uuid[])
+	`
+	rows, err := r.db.Query(query, channel, event, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	settings := make(map[string]bool)
+	for rows.Next() {
+		var userID string
+		var enabled bool
+		if err := rows.Scan(&userID, &enabled); err != nil {
+			return nil, err
+		}
+		settings[userID] = enabled
+	}
+	return settings, rows.Err()
+}
+func (r *NotificationPreferenceRepository) Upsert(userID string, prefs models.NotificationPreferences) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	query := `
+		INSERT INTO notification_preferences (user_id, channel, event_type, enabled, updated_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id, channel, event_type)
+		DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = CURRENT_TIMESTAMP
+	`
+	for channel, events := range prefs {
+		for event, enabled := range events {
+			if _, err := tx.Exec(query, userID, channel, event, enabled); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,74 @@
+﻿package repositories
+import (
+	"database/sql"
+	"fmt"
+	"ecommerce-backend/internal/models"
+)
+type NotificationRepository struct {
+	db *sql.DB
+}
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+func (r *NotificationRepository) Create(notification *models.Notification) error {
+	query := `
+		INSERT INTO notifications (user_id, type, message, data, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+	var data interface{}
+	if len(notification.Data) > 0 {
+		data = []byte(notification.Data)
+	}
+	return r.db.QueryRow(query, notification.UserID, notification.Type, notification.Message, data, notification.CreatedAt).Scan(&notification.ID)
+}
+func (r *NotificationRepository) GetByUserID(userID string, unreadOnly bool, limit, offset int) ([]models.Notification, error) {
+	query := `
+		SELECT id, user_id, type, message, data, read_at, created_at
+		FROM notifications
+		WHERE user_id = $1 AND ($2 = FALSE OR read_at IS NULL)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(query, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var notification models.Notification
+		var data []byte
+		if err := rows.Scan(&notification.ID, &notification.UserID, &notification.Type, &notification.Message, &data, &notification.ReadAt, &notification.CreatedAt); err != nil {
+			return nil, err
+		}
+		notification.Data = data
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
+func (r *NotificationRepository) CountByUserID(userID string, unreadOnly bool) (int, error) {
+	var total int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND ($2 = FALSE OR read_at IS NULL)", userID, unreadOnly).Scan(&total)
+	return total, err
+}
+// MarkRead sets read_at on the user's notification. Marking an already read
+// notification again keeps its original read time.
+func (r *NotificationRepository) MarkRead(id, userID string) (*models.Notification, error) {
+	query := `
+		UPDATE notifications SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP)
+		WHERE id = $1 AND user_id = $2
+		RETURNING id, user_id, type, message, data, read_at, created_at
+	`
+	var notification models.Notification
+	var data []byte
+	err := r.db.QueryRow(query, id, userID).Scan(&notification.ID, &notification.UserID, &notification.Type, &notification.Message, &data, &notification.ReadAt, &notification.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("notification not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	notification.Data = data
+	return &notification, nil
+}
@@ -1,7 +1,12 @@
 ﻿package repositories
 import (
 	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+	"ecommerce-backend/internal/database"
 	"ecommerce-backend/internal/models"
+	"github.com/google/uuid"
 )
 type OrderRepository struct {
 	db *sql.DB
@@ -9,79 +14,249 @@ type OrderRepository struct {
 func NewOrderRepository(db *sql.DB) *OrderRepository {
 	return &OrderRepository{db: db}
 }
-func (r *OrderRepository) CreateOrder(order *models.Order) error {
-	query := `
-		INSERT INTO orders (id, user_id, status, total, subtotal, tax, shipping, 
-		                   shipping_address, billing_address, payment_intent, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
-	_, err := r.db.Exec(query, order.ID, order.UserID, order.Status, order.Total,
+const insertOrderQuery = `
+		INSERT INTO orders (id, user_id, store_id, status, total, subtotal, tax, shipping, 
+		                   shipping_address, billing_address, payment_intent, customer_note,
+		                   is_gift, gift_message, gift_wrap, gift_wrap_fee, tax_included, shipping_method, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`
+const insertOrderItemQuery = `
+		INSERT INTO order_items (id, order_id, product_id, product_name, product_image, quantity, unit, price, bundle_item_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+func orderInsertArgs(order *models.Order) []interface{} {
+	order.StoreID = storeOrDefault(order.StoreID)
+	return []interface{}{order.ID, order.UserID, order.StoreID, order.Status, order.Total,
 		order.Subtotal, order.Tax, order.Shipping, order.ShippingAddress,
-		order.BillingAddress, order.PaymentIntent, order.CreatedAt, order.UpdatedAt)
+		order.BillingAddress, order.PaymentIntent, order.CustomerNote,
+		order.IsGift, order.GiftMessage, order.GiftWrap, order.GiftWrapFee, order.TaxIncluded, order.ShippingMethod, order.CreatedAt, order.UpdatedAt}
+}
+func orderItemInsertArgs(item *models.OrderItem) []interface{} {
+	return []interface{}{item.ID, item.OrderID, item.ProductID, item.ProductName, item.ProductImage, item.Quantity, productUnit(item.Unit), item.Price, item.BundleItemID}
+}
+func (r *OrderRepository) CreateOrder(order *models.Order) error {
+	_, err := r.db.Exec(insertOrderQuery, orderInsertArgs(order)...)
 	return err
 }
+// CreateFromCart stores an order with its items and empties the customer's
+// cart in one transaction, so a failure leaves neither an order without a
+// cleared cart nor a cleared cart without an order. The cart row is locked
+// and must still be at cartVersion, the version the order was priced from;
+// otherwise it fails with "cart changed during checkout".
+func (r *OrderRepository) CreateFromCart(order *models.Order, items []models.OrderItem, cartVersion int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("INSERT INTO carts (user_id, version) VALUES ($1, 0) ON CONFLICT (user_id) DO NOTHING", order.UserID); err != nil {
+		return err
+	}
+	var version int64
+	if err := tx.QueryRow("SELECT version FROM carts WHERE user_id = $1 FOR UPDATE", order.UserID).Scan(&version); err != nil {
+		return err
+	}
+	if version != cartVersion {
+		return fmt.Errorf("cart changed during checkout")
+	}
+	if _, err := tx.Exec(insertOrderQuery, orderInsertArgs(order)...); err != nil {
+		return err
+	}
+	for i := range items {
+		items[i].OrderID = order.ID
+		if _, err := tx.Exec(insertOrderItemQuery, orderItemInsertArgs(&items[i])...); err != nil {
+			return err
+		}
+		for j := range items[i].Components {
+			component := &items[i].Components[j]
+			component.OrderID = order.ID
+			component.BundleItemID = &items[i].ID
+			if _, err := tx.Exec(insertOrderItemQuery, orderItemInsertArgs(component)...); err != nil {
+				return err
+			}
+		}
+	}
+	if err := takeOrderStock(tx, order.ID, items); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM cart_items WHERE user_id = $1", order.UserID); err != nil {
+		return err
+	}
+	if _, err := bumpCartVersion(tx, order.UserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+// takeOrderStock records the sale of an order's items in the inventory
+// ledger within the order's transaction, so the order fails with
+// "insufficient stock" instead of overselling. A measured line takes every
+// unit it opens, so 1.5 kg takes 2 from stock. A bundle takes its
+// components' stock; its own follows from theirs. Products are taken in id
+// order so concurrent checkouts lock them in the same order.
+func takeOrderStock(tx *sql.Tx, orderID string, items []models.OrderItem) error {
+	units := make(map[string]int)
+	for _, item := range items {
+		lines := []models.OrderItem{item}
+		if len(item.Components) > 0 {
+			lines = item.Components
+		}
+		for _, line := range lines {
+			units[line.ProductID] += line.Quantity.Ceil()
+		}
+	}
+	productIDs := make([]string, 0, len(units))
+	for productID, n := range units {
+		if n > 0 {
+			productIDs = append(productIDs, productID)
+		}
+	}
+	sort.Strings(productIDs)
+	for _, productID := range productIDs {
+		if _, err := takeMovement(tx, productID, units[productID], models.InventoryReasonSale, &orderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+// CreateBatch inserts orders with their items, batchSize orders at a time
+// and each batch in its own transaction, for seeding and imports. Empty order
+// and item IDs are generated.
+func (r *OrderRepository) CreateBatch(orders []*models.OrderWithItems, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	inserted := 0
+	for start := 0; start < len(orders); start += batchSize {
+		end := start + batchSize
+		if end > len(orders) {
+			end = len(orders)
+		}
+		var orderRows, itemRows [][]interface{}
+		for _, order := range orders[start:end] {
+			if order.ID == "" {
+				order.ID = uuid.New().String()
+			}
+			order.StoreID = storeOrDefault(order.StoreID)
+			orderRows = append(orderRows, []interface{}{
+				order.ID, order.UserID, order.StoreID, order.Status, order.Total, order.Subtotal, order.Tax, order.Shipping,
+				order.ShippingAddress, order.BillingAddress, order.CreatedAt, order.UpdatedAt,
+			})
+			for i := range order.OrderItems {
+				item := &order.OrderItems[i]
+				if item.ID == "" {
+					item.ID = uuid.New().String()
+				}
+				item.OrderID = order.ID
+				itemRows = append(itemRows, []interface{}{item.ID, item.OrderID, item.ProductID, item.ProductName, item.ProductImage, item.Quantity, productUnit(item.Unit), item.Price, order.CreatedAt})
+			}
+		}
+		tx, err := r.db.Begin()
+		if err != nil {
+			return inserted, err
+		}
+		_, err = insertRows(tx, `
+			INSERT INTO orders (id, user_id, store_id, status, total, subtotal, tax, shipping, shipping_address, billing_address, created_at, updated_at)
+			VALUES `, "", orderRows)
+		if err == nil {
+			_, err = insertRows(tx, `
+				INSERT INTO order_items (id, order_id, product_id, product_name, product_image, quantity, unit, price, created_at)
+				VALUES `, "", itemRows)
+		}
+		if err != nil {
+			tx.Rollback()
+			return inserted, fmt.Errorf("failed to insert orders %d-%d: %w", start+1, end, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return inserted, err
+		}
+		inserted += len(orderRows)
+	}
+	return inserted, nil
+}
 func (r *OrderRepository) CreateOrderItem(item *models.OrderItem) error {
-	query := `
-		INSERT INTO order_items (id, order_id, product_id, quantity, price)
-		VALUES ($1, $2, $3, $4, $5)`
-	_, err := r.db.Exec(query, item.ID, item.OrderID, item.ProductID, item.Quantity, item.Price)
+	_, err := r.db.Exec(insertOrderItemQuery, orderItemInsertArgs(item)...)
 	return err
 }
 func (r *OrderRepository) GetOrderByID(orderID string) (*models.Order, error) {
 	query := `
-		SELECT id, user_id, status, total, subtotal, tax, shipping, 
-		       shipping_address, billing_address, payment_intent, created_at, updated_at
+		SELECT id, user_id, store_id, status, total, subtotal, tax, shipping, 
+		       shipping_address, billing_address, payment_intent, customer_note,
+		       is_gift, gift_message, gift_wrap, gift_wrap_fee, tax_included, shipping_method, created_at, updated_at
 		FROM orders WHERE id = $1`
 	order := &models.Order{}
-	err := r.db.QueryRow(query, orderID).Scan(
-		&order.ID, &order.UserID, &order.Status, &order.Total,
-		&order.Subtotal, &order.Tax, &order.Shipping, &order.ShippingAddress,
-		&order.BillingAddress, &order.PaymentIntent, &order.CreatedAt, &order.UpdatedAt)
+	err := database.RetryRead(func() error {
+		return r.db.QueryRow(query, orderID).Scan(
+			&order.ID, &order.UserID, &order.StoreID, &order.Status, &order.Total,
+			&order.Subtotal, &order.Tax, &order.Shipping, &order.ShippingAddress,
+			&order.BillingAddress, &order.PaymentIntent, &order.CustomerNote,
+			&order.IsGift, &order.GiftMessage, &order.GiftWrap, &order.GiftWrapFee, &order.TaxIncluded, &order.ShippingMethod, &order.CreatedAt, &order.UpdatedAt)
+	})
 	if err != nil {
 		return nil, err
 	}
 	return order, nil
 }
+// GetOrderItems returns the line items with their order-time snapshots
+// rather than live product data, so edits to a product never rewrite order
+// history. Bundle component lines are nested under their bundle's line.
 func (r *OrderRepository) GetOrderItems(orderID string) ([]models.OrderItemWithProduct, error) {
 	query := `
-		SELECT oi.id, oi.order_id, oi.product_id, oi.quantity, oi.price,
-		       p.id, p.name, p.description, p.price, p.image, p.category_id,
-		       p.stock_quantity, p.is_featured, p.created_at, p.updated_at
-		FROM order_items oi
-		JOIN products p ON oi.product_id = p.id
-		WHERE oi.order_id = $1`
+		SELECT id, order_id, COALESCE(product_id::text, ''), product_name, product_image, quantity, unit, price, bundle_item_id
+		FROM order_items
+		WHERE order_id = $1
+		ORDER BY created_at, id`
 	rows, err := r.db.Query(query, orderID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var items []models.OrderItemWithProduct
+	var components []models.OrderItem
 	for rows.Next() {
 		var item models.OrderItemWithProduct
-		var product models.Product
 		err := rows.Scan(
-			&item.ID, &item.OrderID, &item.ProductID, &item.Quantity, &item.Price,
-			&product.ID, &product.Name, &product.Description, &product.Price,
-			&product.Images, &product.CategoryID, &product.Stock,
-			&product.Featured, &product.CreatedAt, &product.UpdatedAt)
+			&item.ID, &item.OrderID, &item.ProductID, &item.ProductName, &item.ProductImage, &item.Quantity, &item.Unit, &item.Price, &item.BundleItemID)
 		if err != nil {
 			return nil, err
 		}
-		item.Product = &models.ProductWithRating{
-			Product: product,
+		if item.BundleItemID != nil {
+			components = append(components, item.OrderItem)
+			continue
 		}
 		items = append(items, item)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, component := range components {
+		for i := range items {
+			if items[i].ID == *component.BundleItemID {
+				items[i].Components = append(items[i].Components, component)
+				break
+			}
+		}
+	}
 	return items, nil
 }
-func (r *OrderRepository) GetUserOrders(userID string, limit, offset int) ([]models.OrderWithItems, error) {
+// GetUserOrders lists a user's orders placed in one store, or in every store
+// when storeID is empty.
+func (r *OrderRepository) GetUserOrders(userID, storeID string, limit, offset int) ([]models.OrderWithItems, error) {
+	var orders []models.OrderWithItems
+	err := database.RetryRead(func() error {
+		var err error
+		orders, err = r.getUserOrders(userID, storeID, limit, offset)
+		return err
+	})
+	return orders, err
+}
+func (r *OrderRepository) getUserOrders(userID, storeID string, limit, offset int) ([]models.OrderWithItems, error) {
 	query := `
-		SELECT id, user_id, status, total, subtotal, tax, shipping, 
-		       shipping_address, billing_address, payment_intent, created_at, updated_at
+		SELECT id, user_id, store_id, status, total, subtotal, tax, shipping, 
+		       shipping_address, billing_address, payment_intent, customer_note,
+		       is_gift, gift_message, gift_wrap, gift_wrap_fee, tax_included, shipping_method, created_at, updated_at
 		FROM orders 
-		WHERE user_id = $1 
+		WHERE user_id = $1 AND ($4::uuid IS NULL OR store_id = $4)
 		ORDER BY created_at DESC 
 		LIMIT $2 OFFSET $3`
-	rows, err := r.db.Query(query, userID, limit, offset)
+	rows, err := r.db.Query(query, userID, limit, offset, storeParam(storeID))
 	if err != nil {
 		return nil, err
 	}
@@ -90,9 +265,10 @@ func (r *OrderRepository) GetUserOrders(userID string, limit, offset int) ([]mod
 	for rows.Next() {
 		var order models.OrderWithItems
 		err := rows.Scan(
-			&order.ID, &order.UserID, &order.Status, &order.Total,
+			&order.ID, &order.UserID, &order.StoreID, &order.Status, &order.Total,
 			&order.Subtotal, &order.Tax, &order.Shipping, &order.ShippingAddress,
-			&order.BillingAddress, &order.PaymentIntent, &order.CreatedAt, &order.UpdatedAt)
+			&order.BillingAddress, &order.PaymentIntent, &order.CustomerNote,
+			&order.IsGift, &order.GiftMessage, &order.GiftWrap, &order.GiftWrapFee, &order.TaxIncluded, &order.ShippingMethod, &order.CreatedAt, &order.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -104,10 +280,10 @@ func (r *OrderRepository) GetUserOrders(userID string, limit, offset int) ([]mod
 	}
 	return orders, nil
 }
-func (r *OrderRepository) CountUserOrders(userID string) (int, error) {
-	query := `SELECT COUNT(*) FROM orders WHERE user_id = $1`
+func (r *OrderRepository) CountUserOrders(userID, storeID string) (int, error) {
+	query := `SELECT COUNT(*) FROM orders WHERE user_id = $1 AND ($2::uuid IS NULL OR store_id = $2)`
 	var count int
-	err := r.db.QueryRow(query, userID).Scan(&count)
+	err := r.db.QueryRow(query, userID, storeParam(storeID)).Scan(&count)
 	return count, err
 }
 func (r *OrderRepository) UpdateOrder(order *models.Order) error {
@@ -121,3 +297,104 @@ func (r *OrderRepository) UpdateOrder(order *models.Order) error {
 		order.BillingAddress, order.PaymentIntent, order.UpdatedAt)
 	return err
 }
+// BulkUpdateStatus moves each order to status inside one transaction, locking
+// the rows and skipping those for which check fails. When atomic is set and
+// any order is skipped, nothing is written and every result is unapplied.
+func (r *OrderRepository) BulkUpdateStatus(orderIDs []string, status models.OrderStatus, atomic bool, check func(current models.OrderStatus) error) ([]models.BulkOrderStatusResult, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	results := make([]models.BulkOrderStatusResult, 0, len(orderIDs))
+	failed := false
+	for _, orderID := range orderIDs {
+		result := models.BulkOrderStatusResult{OrderID: orderID}
+		err := tx.QueryRow("SELECT user_id, status FROM orders WHERE id = $1 FOR UPDATE", orderID).Scan(&result.UserID, &result.PreviousStatus)
+		if err == sql.ErrNoRows {
+			result.Error = "order not found"
+		} else if err != nil {
+			return nil, err
+		} else if err := check(result.PreviousStatus); err != nil {
+			result.Error = err.Error()
+		} else {
+			if _, err := tx.Exec("UPDATE orders SET status = $2, updated_at = NOW() WHERE id = $1", orderID, status); err != nil {
+				return nil, fmt.Errorf("failed to update order %s: %w", orderID, err)
+			}
+			result.Status = status
+			result.Applied = true
+		}
+		failed = failed || !result.Applied
+		results = append(results, result)
+	}
+	if atomic && failed {
+		for i := range results {
+			if results[i].Applied {
+				results[i].Applied = false
+				results[i].Status = ""
+			}
+		}
+		return results, nil
+	}
+	return results, tx.Commit()
+}
+// CancelUnpaid cancels up to limit pending orders placed before cutoff that
+// have no pending or succeeded payment, oldest first, and returns them.
+// Orders locked by another transaction are left for the next run.
+func (r *OrderRepository) CancelUnpaid(cutoff time.Time, limit int) ([]models.Order, error) {
+	query := `
+		UPDATE orders o SET status = 'cancelled', updated_at = NOW()
+		WHERE o.id IN (
+			SELECT c.id FROM orders c
+			WHERE c.status = 'pending' AND c.created_at < $1
+			  AND NOT EXISTS (SELECT 1 FROM payments p WHERE p.order_id = c.id AND p.status IN ('pending', 'succeeded'))
+			ORDER BY c.created_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		) AND o.status = 'pending'
+		RETURNING o.id, o.user_id, o.store_id, o.status, o.total, o.created_at, o.updated_at`
+	rows, err := r.db.Query(query, cutoff, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var orders []models.Order
+	for rows.Next() {
+		var order models.Order
+		if err := rows.Scan(&order.ID, &order.UserID, &order.StoreID, &order.Status, &order.Total, &order.CreatedAt, &order.UpdatedAt); err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+func (r *OrderRepository) AddInternalNote(note *models.OrderInternalNote) error {
+	query := `
+		INSERT INTO order_internal_notes (id, order_id, author_id, note, created_at)
+		VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.db.Exec(query, note.ID, note.OrderID, note.AuthorID, note.Note, note.CreatedAt)
+	return err
+}
+func (r *OrderRepository) GetInternalNotes(orderID string) ([]models.OrderInternalNote, error) {
+	query := `
+		SELECT id, order_id, author_id, note, created_at
+		FROM order_internal_notes
+		WHERE order_id = $1
+		ORDER BY created_at`
+	rows, err := r.db.Query(query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	notes := []models.OrderInternalNote{}
+	for rows.Next() {
+		var note models.OrderInternalNote
+		var authorID sql.NullString
+		if err := rows.Scan(&note.ID, &note.OrderID, &authorID, &note.Note, &note.CreatedAt); err != nil {
+			return nil, err
+		}
+		note.AuthorID = authorID.String
+		notes = append(notes, note)
+	}
+	return notes, nil
+}
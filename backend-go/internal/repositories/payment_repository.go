@@ -1,7 +1,10 @@
 ﻿package repositories
 import (
 	"database/sql"
+	"fmt"
+	"strings"
 	"ecommerce-backend/internal/models"
+	"github.com/lib/pq"
 )
 type PaymentRepository struct {
 	db *sql.DB
@@ -9,25 +12,79 @@ type PaymentRepository struct {
 func NewPaymentRepository(db *sql.DB) *PaymentRepository {
 	return &PaymentRepository{db: db}
 }
+// CreatePayment fails with "order has an open payment" when the order
+// already has a pending payment. An order payment is inserted while the
+// order row is locked and still pending, so the unpaid-order sweep cannot
+// cancel the order between the status check and the insert.
 func (r *PaymentRepository) CreatePayment(payment *models.Payment) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if payment.OrderID != nil {
+		var status models.OrderStatus
+		err := tx.QueryRow("SELECT status FROM orders WHERE id = $1 FOR UPDATE", *payment.OrderID).Scan(&status)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("order not found")
+		}
+		if err != nil {
+			return err
+		}
+		if status != models.OrderStatusPending {
+			return fmt.Errorf("order is not awaiting payment")
+		}
+	}
 	query := `
-		INSERT INTO payments (id, user_id, order_id, amount, currency, status, 
+		INSERT INTO payments (id, user_id, order_id, amount, currency, status, provider, test_mode,
 		                     payment_intent_id, client_secret, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
-	_, err := r.db.Exec(query, payment.ID, payment.UserID, payment.OrderID,
-		payment.Amount, payment.Currency, payment.Status, payment.PaymentIntentID,
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	_, err = tx.Exec(query, payment.ID, payment.UserID, payment.OrderID,
+		payment.Amount, payment.Currency, payment.Status, payment.Provider, payment.TestMode, payment.PaymentIntentID,
 		payment.ClientSecret, payment.CreatedAt, payment.UpdatedAt)
-	return err
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" && pqErr.Constraint == "idx_payments_open_order" {
+		return fmt.Errorf("order has an open payment")
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+// GetOpenOrderPayment returns the pending payment of an order, or nil when
+// it has none.
+func (r *PaymentRepository) GetOpenOrderPayment(orderID string) (*models.Payment, error) {
+	query := `
+		SELECT id, user_id, order_id, amount, currency, status, provider, test_mode,
+		       payment_intent_id, client_secret, created_at, updated_at
+		FROM payments WHERE order_id = $1 AND status = 'pending'`
+	payment := &models.Payment{}
+	err := r.db.QueryRow(query, orderID).Scan(
+		&payment.ID, &payment.UserID, &payment.OrderID, &payment.Amount,
+		&payment.Currency, &payment.Status, &payment.Provider, &payment.TestMode, &payment.PaymentIntentID,
+		&payment.ClientSecret, &payment.CreatedAt, &payment.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+// CountOrderPayments counts every payment ever opened for an order.
+func (r *PaymentRepository) CountOrderPayments(orderID string) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM payments WHERE order_id = $1", orderID).Scan(&count)
+	return count, err
 }
 func (r *PaymentRepository) GetPaymentByIntentID(paymentIntentID string) (*models.Payment, error) {
 	query := `
-		SELECT id, user_id, order_id, amount, currency, status, 
+		SELECT id, user_id, order_id, amount, currency, status, provider, test_mode,
 		       payment_intent_id, client_secret, created_at, updated_at
 		FROM payments WHERE payment_intent_id = $1`
 	payment := &models.Payment{}
 	err := r.db.QueryRow(query, paymentIntentID).Scan(
 		&payment.ID, &payment.UserID, &payment.OrderID, &payment.Amount,
-		&payment.Currency, &payment.Status, &payment.PaymentIntentID,
+		&payment.Currency, &payment.Status, &payment.Provider, &payment.TestMode, &payment.PaymentIntentID,
 		&payment.ClientSecret, &payment.CreatedAt, &payment.UpdatedAt)
 	if err != nil {
 		return nil, err
@@ -36,7 +93,7 @@ func (r *PaymentRepository) GetPaymentByIntentID(paymentIntentID string) (*model
 }
 func (r *PaymentRepository) GetUserPayments(userID string) ([]models.Payment, error) {
 	query := `
-		SELECT id, user_id, order_id, amount, currency, status, 
+		SELECT id, user_id, order_id, amount, currency, status, provider, test_mode,
 		       payment_intent_id, client_secret, created_at, updated_at
 		FROM payments 
 		WHERE user_id = $1 
@@ -51,7 +108,7 @@ func (r *PaymentRepository) GetUserPayments(userID string) ([]models.Payment, er
 		var payment models.Payment
 		err := rows.Scan(
 			&payment.ID, &payment.UserID, &payment.OrderID, &payment.Amount,
-			&payment.Currency, &payment.Status, &payment.PaymentIntentID,
+			&payment.Currency, &payment.Status, &payment.Provider, &payment.TestMode, &payment.PaymentIntentID,
 			&payment.ClientSecret, &payment.CreatedAt, &payment.UpdatedAt)
 		if err != nil {
 			return nil, err
@@ -60,6 +117,103 @@ func (r *PaymentRepository) GetUserPayments(userID string) ([]models.Payment, er
 	}
 	return payments, nil
 }
+// paymentRefundCondition matches refunds issued against payment p's intent.
+const paymentRefundCondition = `
+	SELECT 1 FROM returns rt JOIN orders o ON o.id = rt.order_id
+	WHERE o.payment_intent = p.payment_intent_id AND rt.refund_id IS NOT NULL`
+// ListUserPayments returns a page of a user's payments, newest first, with
+// the refunds issued against each.
+func (r *PaymentRepository) ListUserPayments(userID string, query models.PaymentHistoryQuery, limit, offset int) ([]models.PaymentHistoryEntry, int, error) {
+	conditions := []string{"p.user_id = $1"}
+	args := []interface{}{userID}
+	add := func(clause string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(clause, len(args)))
+	}
+	switch query.Status {
+	case "":
+	case "refunded":
+		conditions = append(conditions, "EXISTS ("+paymentRefundCondition+")")
+	default:
+		add("p.status = $%d", query.Status)
+	}
+	if !query.From.IsZero() {
+		add("p.created_at >= $%d", query.From)
+	}
+	if !query.To.IsZero() {
+		add("p.created_at < $%d", query.To)
+	}
+	where := strings.Join(conditions, " AND ")
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM payments p WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	args = append(args, limit, offset)
+	rows, err := r.db.Query(fmt.Sprintf(`
+		SELECT p.id, p.user_id, p.order_id, p.amount, p.currency, p.status, p.provider, p.test_mode,
+		       p.payment_intent_id, p.client_secret, p.created_at, p.updated_at
+		FROM payments p
+		WHERE %s
+		ORDER BY p.created_at DESC, p.id
+		LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	entries := []models.PaymentHistoryEntry{}
+	intents := []string{}
+	for rows.Next() {
+		entry := models.PaymentHistoryEntry{Refunds: []models.PaymentRefund{}}
+		payment := &entry.Payment
+		if err := rows.Scan(
+			&payment.ID, &payment.UserID, &payment.OrderID, &payment.Amount,
+			&payment.Currency, &payment.Status, &payment.Provider, &payment.TestMode, &payment.PaymentIntentID,
+			&payment.ClientSecret, &payment.CreatedAt, &payment.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+		intents = append(intents, payment.PaymentIntentID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(entries) == 0 {
+		return entries, total, nil
+	}
+	refunds, err := r.refundsByIntent(intents)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range entries {
+		for _, refund := range refunds[entries[i].PaymentIntentID] {
+			entries[i].Refunds = append(entries[i].Refunds, refund)
+			entries[i].Refunded += refund.Amount
+		}
+	}
+	return entries, total, nil
+}
+func (r *PaymentRepository) refundsByIntent(intents []string) (map[string][]models.PaymentRefund, error) {
+	rows, err := r.db.Query(`
+		SELECT o.payment_intent, rt.id, rt.refund_id, rt.refund_amount, rt.updated_at
+		FROM returns rt
+		JOIN orders o ON o.id = rt.order_id
+		WHERE o.payment_intent = ANY($1) AND rt.refund_id IS NOT NULL
+		ORDER BY rt.updated_at`, pq.Array(intents))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	refunds := map[string][]models.PaymentRefund{}
+	for rows.Next() {
+		var intent string
+		var refund models.PaymentRefund
+		if err := rows.Scan(&intent, &refund.ReturnID, &refund.RefundID, &refund.Amount, &refund.RefundedAt); err != nil {
+			return nil, err
+		}
+		refunds[intent] = append(refunds[intent], refund)
+	}
+	return refunds, rows.Err()
+}
 func (r *PaymentRepository) UpdatePayment(payment *models.Payment) error {
 	query := `
 		UPDATE payments 
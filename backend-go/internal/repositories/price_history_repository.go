@@ -0,0 +1,61 @@
+﻿package repositories
+import (
+	"database/sql"
+	"time"
+	"ecommerce-backend/internal/models"
+)
+const (
+	priceHistoryRetention = 365 * 24 * time.Hour
+	priceHistoryRawWindow = 30 * 24 * time.Hour
+)
+type PriceHistoryRepository struct {
+	db *sql.DB
+}
+func NewPriceHistoryRepository(db *sql.DB) *PriceHistoryRepository {
+	return &PriceHistoryRepository{db: db}
+}
+func (r *PriceHistoryRepository) Record(productID string, price models.Money) error {
+	now := time.Now()
+	_, err := r.db.Exec(
+		"INSERT INTO price_history (product_id, price, recorded_at) VALUES ($1, $2, $3)",
+		productID, price, now,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(
+		"DELETE FROM price_history WHERE product_id = $1 AND recorded_at < $2",
+		productID, now.Add(-priceHistoryRetention),
+	)
+	return err
+}
+func (r *PriceHistoryRepository) GetByProductID(productID string) ([]models.PricePoint, error) {
+	now := time.Now()
+	query := `
+		SELECT price, recorded_at FROM (
+			SELECT price, recorded_at
+			FROM price_history
+			WHERE product_id = $1 AND recorded_at >= $2
+			UNION ALL
+			SELECT MIN(price), date_trunc('day', recorded_at)
+			FROM price_history
+			WHERE product_id = $1 AND recorded_at < $2 AND recorded_at >= $3
+			GROUP BY date_trunc('day', recorded_at)
+		) points
+		ORDER BY recorded_at
+	`
+	rows, err := r.db.Query(query, productID, now.Add(-priceHistoryRawWindow), now.Add(-priceHistoryRetention))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	points := []models.PricePoint{}
+	for rows.Next() {
+		var point models.PricePoint
+		if err := rows.Scan(&point.Price, &point.RecordedAt); err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
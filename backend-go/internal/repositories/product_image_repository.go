@@ -0,0 +1,187 @@
+﻿package repositories
+import (
+	"database/sql"
+	"fmt"
+	"ecommerce-backend/internal/models"
+)
+type ProductImageRepository struct {
+	db *sql.DB
+}
+func NewProductImageRepository(db *sql.DB) *ProductImageRepository {
+	return &ProductImageRepository{db: db}
+}
+func (r *ProductImageRepository) GetByProductID(productID string) ([]models.ProductImage, error) {
+	query := `
+		SELECT id, product_id, url, position, is_primary, created_at
+		FROM product_images WHERE product_id = $1
+		ORDER BY position, created_at
+	`
+	rows, err := r.db.Query(query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	images := []models.ProductImage{}
+	for rows.Next() {
+		var image models.ProductImage
+		if err := rows.Scan(&image.ID, &image.ProductID, &image.URL, &image.Position, &image.IsPrimary, &image.CreatedAt); err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+	return images, rows.Err()
+}
+func (r *ProductImageRepository) Create(image *models.ProductImage) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var count int
+	err = tx.QueryRow(
+		"SELECT COUNT(*), COALESCE(MAX(position) + 1, 0) FROM product_images WHERE product_id = $1",
+		image.ProductID,
+	).Scan(&count, &image.Position)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		image.IsPrimary = true
+	}
+	if image.IsPrimary {
+		if _, err := tx.Exec("UPDATE product_images SET is_primary = false WHERE product_id = $1", image.ProductID); err != nil {
+			return err
+		}
+	}
+	query := `
+		INSERT INTO product_images (id, product_id, url, position, is_primary, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	if _, err := tx.Exec(query, image.ID, image.ProductID, image.URL, image.Position, image.IsPrimary, image.CreatedAt); err != nil {
+		return err
+	}
+	if err := syncProductImages(tx, image.ProductID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+func (r *ProductImageRepository) Replace(productID string, images []models.ProductImage) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM product_images WHERE product_id = $1", productID); err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO product_images (id, product_id, url, position, is_primary, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	for _, image := range images {
+		if _, err := tx.Exec(query, image.ID, productID, image.URL, image.Position, image.IsPrimary, image.CreatedAt); err != nil {
+			return err
+		}
+	}
+	if err := syncProductImages(tx, productID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+func (r *ProductImageRepository) Reorder(productID string, imageIDs []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM product_images WHERE product_id = $1", productID).Scan(&count); err != nil {
+		return err
+	}
+	if count != len(imageIDs) {
+		return fmt.Errorf("image list does not match product images")
+	}
+	for position, imageID := range imageIDs {
+		result, err := tx.Exec(
+			"UPDATE product_images SET position = $1 WHERE id = $2 AND product_id = $3",
+			position, imageID, productID,
+		)
+		if err != nil {
+			return err
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return fmt.Errorf("image list does not match product images")
+		}
+	}
+	if err := syncProductImages(tx, productID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+func (r *ProductImageRepository) SetPrimary(productID, imageID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var exists bool
+	err = tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM product_images WHERE id = $1 AND product_id = $2)", imageID, productID,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("image not found")
+	}
+	if _, err := tx.Exec("UPDATE product_images SET is_primary = false WHERE product_id = $1", productID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE product_images SET is_primary = true WHERE id = $1", imageID); err != nil {
+		return err
+	}
+	if err := syncProductImages(tx, productID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+func (r *ProductImageRepository) Delete(productID, imageID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var wasPrimary bool
+	err = tx.QueryRow(
+		"DELETE FROM product_images WHERE id = $1 AND product_id = $2 RETURNING is_primary", imageID, productID,
+	).Scan(&wasPrimary)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("image not found")
+	}
+	if err != nil {
+		return err
+	}
+	if wasPrimary {
+		query := `
+			UPDATE product_images SET is_primary = true
+			WHERE id = (SELECT id FROM product_images WHERE product_id = $1 ORDER BY position, created_at LIMIT 1)
+		`
+		if _, err := tx.Exec(query, productID); err != nil {
+			return err
+		}
+	}
+	if err := syncProductImages(tx, productID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+func syncProductImages(tx *sql.Tx, productID string) error {
+	query := `
+		UPDATE products SET images = ARRAY(
+			SELECT url FROM product_images WHERE product_id = $1 ORDER BY is_primary DESC, position, created_at
+		), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	_, err := tx.Exec(query, productID)
+	return err
+}
@@ -3,7 +3,10 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
+	"ecommerce-backend/internal/database"
 	"ecommerce-backend/internal/models"
+	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
 type ProductRepository struct {
@@ -14,52 +17,222 @@ func NewProductRepository(db *sql.DB) *ProductRepository {
 }
 func (r *ProductRepository) Create(product *models.Product) error {
 	query := `
-		INSERT INTO products (id, name, slug, description, price, compare_price, images, in_stock, stock, featured, category_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		INSERT INTO products (id, name, slug, description, price, compare_price, images, in_stock, stock, featured, product_type, unit, sku, barcode, digital_file, category_id, store_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 	`
+	product.StoreID = storeOrDefault(product.StoreID)
 	_, err := r.db.Exec(query, 
 		product.ID, product.Name, product.Slug, product.Description, product.Price, product.ComparePrice, 
-		pq.Array(product.Images), product.InStock, product.Stock, product.Featured, product.CategoryID, 
-		product.CreatedAt, product.UpdatedAt,
+		pq.Array(product.Images), product.InStock, product.Stock, product.Featured, product.ProductType, productUnit(product.Unit), product.SKU, product.Barcode, product.DigitalFile, product.CategoryID, 
+		product.StoreID, product.CreatedAt, product.UpdatedAt,
 	)
+	return productUniqueError(err)
+}
+func productUnit(unit string) string {
+	if unit == "" {
+		return models.UnitEach
+	}
+	return unit
+}
+// productUniqueError maps violations of the per-store SKU and barcode
+// indexes to errors the handlers can report as conflicts.
+func productUniqueError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		switch pqErr.Constraint {
+		case "idx_products_store_sku":
+			return fmt.Errorf("sku already exists")
+		case "idx_products_store_barcode":
+			return fmt.Errorf("barcode already exists")
+		}
+	}
 	return err
 }
-func (r *ProductRepository) GetByID(id string) (*models.Product, error) {
+// CreateBatch inserts products batchSize rows at a time, which is far faster
+// than calling Create per product for seeding and imports. Products whose
+// slug already exists in their store are skipped; the number actually
+// inserted is returned. Empty IDs are generated.
+func (r *ProductRepository) CreateBatch(products []*models.Product, batchSize int) (int, error) {
+	rows := make([][]interface{}, len(products))
+	for i, product := range products {
+		if product.ID == "" {
+			product.ID = uuid.New().String()
+		}
+		product.StoreID = storeOrDefault(product.StoreID)
+		var categoryID interface{}
+		if product.CategoryID != "" {
+			categoryID = product.CategoryID
+		}
+		productType := product.ProductType
+		if productType == "" {
+			productType = models.ProductTypePhysical
+		}
+		rows[i] = []interface{}{
+			product.ID, product.Name, product.Slug, product.Description, product.Price, product.ComparePrice,
+			pq.Array(product.Images), product.InStock, product.Stock, product.Featured, productType, productUnit(product.Unit), product.SKU, product.Barcode, product.DigitalFile, categoryID,
+			product.StoreID, product.CreatedAt, product.UpdatedAt,
+		}
+	}
+	return insertBatches(r.db, `
+		INSERT INTO products (id, name, slug, description, price, compare_price, images, in_stock, stock, featured, product_type, unit, sku, barcode, digital_file, category_id, store_id, created_at, updated_at)
+		VALUES `, "ON CONFLICT (store_id, slug) DO NOTHING", rows, batchSize)
+}
+// GetByID reports a product of a store other than storeID as not found. An
+// empty storeID matches every store.
+func (r *ProductRepository) GetByID(storeID, id string) (*models.Product, error) {
+	return r.getOne("id = $1 AND ($2::uuid IS NULL OR store_id = $2)", id, storeParam(storeID))
+}
+// GetBySKU and GetByBarcode look up a product by its external identifiers,
+// which are unique per store. An empty storeID matches every store.
+func (r *ProductRepository) GetBySKU(storeID, sku string) (*models.Product, error) {
+	return r.getOne("sku = $1 AND ($2::uuid IS NULL OR store_id = $2)", sku, storeParam(storeID))
+}
+func (r *ProductRepository) GetByBarcode(storeID, barcode string) (*models.Product, error) {
+	return r.getOne("barcode = $1 AND ($2::uuid IS NULL OR store_id = $2)", barcode, storeParam(storeID))
+}
+// getOne never returns a soft-deleted product, such as a merged duplicate.
+func (r *ProductRepository) getOne(where string, args ...interface{}) (*models.Product, error) {
 	query := `
-		SELECT id, name, slug, description, price, compare_price, images, in_stock, stock, featured, category_id, created_at, updated_at
-		FROM products WHERE id = $1
-	`
+		SELECT id, name, slug, description, price, compare_price, images, in_stock, stock, featured, product_type, unit, sku, barcode, category_id, store_id, version, created_at, updated_at
+		FROM products WHERE deleted_at IS NULL AND ` + where + `
+		LIMIT 1`
 	product := &models.Product{}
 	var images pq.StringArray
-	err := r.db.QueryRow(query, id).Scan(
-		&product.ID, &product.Name, &product.Slug, &product.Description, &product.Price, &product.ComparePrice,
-		&images, &product.InStock, &product.Stock, &product.Featured, &product.CategoryID, &product.CreatedAt, &product.UpdatedAt,
-	)
+	err := database.RetryRead(func() error {
+		return r.db.QueryRow(query, args...).Scan(
+			&product.ID, &product.Name, &product.Slug, &product.Description, &product.Price, &product.ComparePrice,
+			&images, &product.InStock, &product.Stock, &product.Featured, &product.ProductType, &product.Unit, &product.SKU, &product.Barcode, &product.CategoryID, &product.StoreID, &product.Version, &product.CreatedAt, &product.UpdatedAt,
+		)
+	})
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("product not found")
 	}
 	product.Images = []string(images)
 	return product, err
 }
+// GetAvailableStock returns the quantity available to buy for each of the
+// given products of a store, in one query. Unknown and deleted ids are
+// missing from the map and products flagged out of stock have none.
+func (r *ProductRepository) GetAvailableStock(storeID string, ids []string) (map[string]int, error) {
+	query := `
+		SELECT id, CASE WHEN in_stock THEN GREATEST(stock, 0) ELSE 0 END
+		FROM products
+		WHERE id::text = ANY($1) AND deleted_at IS NULL AND ($2::uuid IS NULL OR store_id = $2)
+	`
+	available := make(map[string]int, len(ids))
+	err := database.RetryRead(func() error {
+		rows, err := r.db.Query(query, pq.Array(ids), storeParam(storeID))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id string
+			var stock int
+			if err := rows.Scan(&id, &stock); err != nil {
+				return err
+			}
+			available[id] = stock
+		}
+		return rows.Err()
+	})
+	return available, err
+}
+// GetByIDs loads products of a store (every store when storeID is empty)
+// with their categories in one query. Unknown and deleted ids are skipped
+// and the result is in no particular order.
+func (r *ProductRepository) GetByIDs(storeID string, ids []string) ([]models.ProductWithCategory, error) {
+	query := `
+		SELECT p.id, p.name, p.slug, p.description, p.price, p.compare_price, p.images, p.in_stock, p.stock, p.featured, p.product_type, p.unit, p.sku, p.barcode, p.category_id, p.store_id, p.version, p.created_at, p.updated_at,
+		       c.id, c.name, c.slug, c.description, c.image, c.created_at, c.updated_at
+		FROM products p
+		LEFT JOIN categories c ON p.category_id = c.id
+		WHERE p.id::text = ANY($1) AND p.deleted_at IS NULL AND ($2::uuid IS NULL OR p.store_id = $2)
+	`
+	var products []models.ProductWithCategory
+	err := database.RetryRead(func() error {
+		rows, err := r.db.Query(query, pq.Array(ids), storeParam(storeID))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		products = products[:0]
+		for rows.Next() {
+			product := models.Product{}
+			var images pq.StringArray
+			var categoryID, categoryName, categorySlug sql.NullString
+			var categoryDescription, categoryImage sql.NullString
+			var categoryCreatedAt, categoryUpdatedAt sql.NullTime
+			var joinedID sql.NullString
+			err := rows.Scan(
+				&product.ID, &product.Name, &product.Slug, &product.Description, &product.Price, &product.ComparePrice,
+				&images, &product.InStock, &product.Stock, &product.Featured, &product.ProductType, &product.Unit, &product.SKU, &product.Barcode, &categoryID, &product.StoreID, &product.Version, &product.CreatedAt, &product.UpdatedAt,
+				&joinedID, &categoryName, &categorySlug, &categoryDescription, &categoryImage, &categoryCreatedAt, &categoryUpdatedAt,
+			)
+			if err != nil {
+				return err
+			}
+			product.Images = []string(images)
+			product.CategoryID = categoryID.String
+			item := models.ProductWithCategory{Product: product}
+			if joinedID.Valid {
+				item.Category = &models.Category{
+					ID:          joinedID.String,
+					Name:        categoryName.String,
+					Slug:        categorySlug.String,
+					Description: &categoryDescription.String,
+					Image:       &categoryImage.String,
+					CreatedAt:   categoryCreatedAt.Time,
+					UpdatedAt:   categoryUpdatedAt.Time,
+				}
+			}
+			products = append(products, item)
+		}
+		return rows.Err()
+	})
+	return products, err
+}
+// ListWithFilters retries on transient connection errors, as do the other
+// catalog, cart and order reads that back the busiest pages.
 func (r *ProductRepository) ListWithFilters(query models.ProductQuery, offset int) ([]models.ProductWithCategory, int, error) {
-	whereClause := "WHERE 1=1"
+	var products []models.ProductWithCategory
+	var total int
+	err := database.RetryRead(func() error {
+		var err error
+		products, total, err = r.listWithFilters(query, offset)
+		return err
+	})
+	return products, total, err
+}
+// productListFilter builds the WHERE clause shared by the product listing
+// and its facet counts. withCategory false leaves out the category filter.
+func productListFilter(query models.ProductQuery, withCategory bool) (string, []interface{}) {
+	whereClause := "WHERE p.deleted_at IS NULL"
 	args := []interface{}{}
 	argIndex := 1
-	if query.Category != "" {
+	if query.StoreID != "" {
+		whereClause += fmt.Sprintf(" AND p.store_id = $%d", argIndex)
+		args = append(args, query.StoreID)
+		argIndex++
+	}
+	if withCategory && query.Category != "" {
 		whereClause += fmt.Sprintf(" AND p.category_id = $%d", argIndex)
 		args = append(args, query.Category)
 		argIndex++
 	}
 	if query.Search != "" {
-		whereClause += fmt.Sprintf(" AND (p.name ILIKE $%d OR p.description ILIKE $%d)", argIndex, argIndex)
-		args = append(args, "%"+query.Search+"%")
+		whereClause += fmt.Sprintf(" AND (p.name ILIKE $%d ESCAPE '\\' OR p.description ILIKE $%d ESCAPE '\\')", argIndex, argIndex)
+		args = append(args, "%"+escapeLike(query.Search)+"%")
 		argIndex++
 	}
 	if query.Featured {
 		whereClause += fmt.Sprintf(" AND p.featured = $%d", argIndex)
 		args = append(args, true)
-		argIndex++
 	}
+	return whereClause, args
+}
+func (r *ProductRepository) listWithFilters(query models.ProductQuery, offset int) ([]models.ProductWithCategory, int, error) {
+	whereClause, args := productListFilter(query, true)
+	argIndex := len(args) + 1
 	orderClause := "ORDER BY p.created_at DESC"
 	if query.SortBy != "" {
 		switch query.SortBy {
@@ -67,7 +240,7 @@ func (r *ProductRepository) ListWithFilters(query models.ProductQuery, offset in
 			orderClause = "ORDER BY p.name"
 		case "price":
 			orderClause = "ORDER BY p.price"
-		case "created_at":
+		default:
 			orderClause = "ORDER BY p.created_at"
 		}
 		if query.SortOrder == "asc" {
@@ -85,7 +258,7 @@ func (r *ProductRepository) ListWithFilters(query models.ProductQuery, offset in
 		return nil, 0, err
 	}
 	querySQL := fmt.Sprintf(`
-		SELECT p.id, p.name, p.slug, p.description, p.price, p.compare_price, p.images, p.in_stock, p.stock, p.featured, p.category_id, p.created_at, p.updated_at,
+		SELECT p.id, p.name, p.slug, p.description, p.price, p.compare_price, p.images, p.in_stock, p.stock, p.featured, p.product_type, p.unit, p.sku, p.barcode, p.category_id, p.store_id, p.version, p.created_at, p.updated_at,
 		       c.id, c.name, c.slug, c.description, c.image, c.created_at, c.updated_at
 		FROM products p
 		LEFT JOIN categories c ON p.category_id = c.id
@@ -113,7 +286,7 @@ func (r *ProductRepository) ListWithFilters(query models.ProductQuery, offset in
 		var categoryUpdatedAt sql.NullTime
 		err := rows.Scan(
 			&product.ID, &product.Name, &product.Slug, &product.Description, &product.Price, &product.ComparePrice,
-			&images, &product.InStock, &product.Stock, &product.Featured, &categoryID, &product.CreatedAt, &product.UpdatedAt,
+			&images, &product.InStock, &product.Stock, &product.Featured, &product.ProductType, &product.Unit, &product.SKU, &product.Barcode, &categoryID, &product.StoreID, &product.Version, &product.CreatedAt, &product.UpdatedAt,
 			&category.ID, &categoryName, &categorySlug, &categoryDescription, &categoryImage, &categoryCreatedAt, &categoryUpdatedAt,
 		)
 		if err != nil {
@@ -122,7 +295,7 @@ func (r *ProductRepository) ListWithFilters(query models.ProductQuery, offset in
 		product.Images = []string(images)
 		product.CategoryID = categoryID.String
 		if categoryID.Valid {
-			category.ID = category.ID
+			category.ID = categoryID.String
 			category.Name = categoryName.String
 			category.Slug = categorySlug.String
 			category.Description = &categoryDescription.String
@@ -142,17 +315,185 @@ func (r *ProductRepository) ListWithFilters(query models.ProductQuery, offset in
 	}
 	return products, total, nil
 }
-func (r *ProductRepository) GetFeatured(limit int) ([]models.ProductWithCategory, error) {
+// CountByCategory counts the products matching query in each category,
+// ignoring query.Category, largest first.
+func (r *ProductRepository) CountByCategory(query models.ProductQuery) ([]models.FacetCount, error) {
+	whereClause, args := productListFilter(query, false)
+	var counts []models.FacetCount
+	err := database.RetryRead(func() error {
+		rows, err := r.db.Query(fmt.Sprintf(`
+			SELECT c.id, c.name, COUNT(*)
+			FROM products p
+			JOIN categories c ON p.category_id = c.id
+			%s
+			GROUP BY c.id, c.name
+			ORDER BY COUNT(*) DESC, c.name
+		`, whereClause), args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		counts = nil
+		for rows.Next() {
+			var count models.FacetCount
+			if err := rows.Scan(&count.Value, &count.Label, &count.Count); err != nil {
+				return err
+			}
+			counts = append(counts, count)
+		}
+		return rows.Err()
+	})
+	return counts, err
+}
+// CountByPrice counts the products matching query in the price buckets
+// split at bounds. The result has len(bounds)+1 entries; entry i counts
+// prices in [bounds[i-1], bounds[i]).
+func (r *ProductRepository) CountByPrice(query models.ProductQuery, bounds []models.Money) ([]int, error) {
+	whereClause, args := productListFilter(query, true)
+	// Prices are stored in cents, so the bounds are compared as cents too.
+	thresholds := make([]int64, len(bounds))
+	for i, bound := range bounds {
+		thresholds[i] = int64(bound)
+	}
+	args = append(args, pq.Array(thresholds))
+	counts := make([]int, len(bounds)+1)
+	err := database.RetryRead(func() error {
+		rows, err := r.db.Query(fmt.Sprintf(`
+			SELECT width_bucket(p.price, $%d::bigint[]) AS bucket, COUNT(*)
+			FROM products p
+			%s
+			GROUP BY bucket
+		`, len(args), whereClause), args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for i := range counts {
+			counts[i] = 0
+		}
+		for rows.Next() {
+			var bucket, count int
+			if err := rows.Scan(&bucket, &count); err != nil {
+				return err
+			}
+			if bucket >= 0 && bucket < len(counts) {
+				counts[bucket] = count
+			}
+		}
+		return rows.Err()
+	})
+	return counts, err
+}
+// CountByAvailability counts the products matching query that are in and
+// out of stock.
+func (r *ProductRepository) CountByAvailability(query models.ProductQuery) (inStock, outOfStock int, err error) {
+	whereClause, args := productListFilter(query, true)
+	err = database.RetryRead(func() error {
+		return r.db.QueryRow(fmt.Sprintf(`
+			SELECT COUNT(*) FILTER (WHERE p.in_stock), COUNT(*) FILTER (WHERE NOT p.in_stock)
+			FROM products p
+			%s
+		`, whereClause), args...).Scan(&inStock, &outOfStock)
+	})
+	return inStock, outOfStock, err
+}
+// ListNewArrivals lists in-stock, non-deleted products created since the
+// given time, newest first.
+func (r *ProductRepository) ListNewArrivals(storeID string, since time.Time, limit, offset int) ([]models.ProductWithCategory, int, error) {
+	var products []models.ProductWithCategory
+	var total int
+	err := database.RetryRead(func() error {
+		var err error
+		products, total, err = r.listNewArrivals(storeID, since, limit, offset)
+		return err
+	})
+	return products, total, err
+}
+func (r *ProductRepository) listNewArrivals(storeID string, since time.Time, limit, offset int) ([]models.ProductWithCategory, int, error) {
+	whereClause := "WHERE p.created_at >= $1 AND p.in_stock = true AND p.deleted_at IS NULL AND ($2::uuid IS NULL OR p.store_id = $2)"
+	var total int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM products p "+whereClause, since, storeParam(storeID)).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := fmt.Sprintf(`
+		SELECT p.id, p.name, p.slug, p.description, p.price, p.compare_price, p.images, p.in_stock, p.stock, p.featured, p.product_type, p.unit, p.sku, p.barcode, p.category_id, p.store_id, p.version, p.created_at, p.updated_at,
+		       c.id, c.name, c.slug, c.description, c.image, c.created_at, c.updated_at
+		FROM products p
+		LEFT JOIN categories c ON p.category_id = c.id
+		%s
+		ORDER BY p.created_at DESC, p.id
+		LIMIT $3 OFFSET $4
+	`, whereClause)
+	rows, err := r.db.Query(query, since, storeParam(storeID), limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var products []models.ProductWithCategory
+	for rows.Next() {
+		product := models.Product{}
+		var category models.Category
+		var images pq.StringArray
+		var categoryID sql.NullString
+		var categoryName sql.NullString
+		var categorySlug sql.NullString
+		var categoryDescription sql.NullString
+		var categoryImage sql.NullString
+		var categoryCreatedAt sql.NullTime
+		var categoryUpdatedAt sql.NullTime
+		err := rows.Scan(
+			&product.ID, &product.Name, &product.Slug, &product.Description, &product.Price, &product.ComparePrice,
+			&images, &product.InStock, &product.Stock, &product.Featured, &product.ProductType, &product.Unit, &product.SKU, &product.Barcode, &categoryID, &product.StoreID, &product.Version, &product.CreatedAt, &product.UpdatedAt,
+			&category.ID, &categoryName, &categorySlug, &categoryDescription, &categoryImage, &categoryCreatedAt, &categoryUpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		product.Images = []string(images)
+		product.CategoryID = categoryID.String
+		if categoryID.Valid {
+			category.Name = categoryName.String
+			category.Slug = categorySlug.String
+			category.Description = &categoryDescription.String
+			category.Image = &categoryImage.String
+			category.CreatedAt = categoryCreatedAt.Time
+			category.UpdatedAt = categoryUpdatedAt.Time
+			products = append(products, models.ProductWithCategory{
+				Product:  product,
+				Category: &category,
+			})
+		} else {
+			products = append(products, models.ProductWithCategory{
+				Product:  product,
+				Category: nil,
+			})
+		}
+	}
+	return products, total, rows.Err()
+}
+// GetFeatured lists featured products of a store, or of every store when
+// storeID is empty.
+func (r *ProductRepository) GetFeatured(storeID string, limit int) ([]models.ProductWithCategory, error) {
+	var products []models.ProductWithCategory
+	err := database.RetryRead(func() error {
+		var err error
+		products, err = r.getFeatured(storeID, limit)
+		return err
+	})
+	return products, err
+}
+func (r *ProductRepository) getFeatured(storeID string, limit int) ([]models.ProductWithCategory, error) {
 	query := `
-		SELECT p.id, p.name, p.slug, p.description, p.price, p.compare_price, p.images, p.in_stock, p.stock, p.featured, p.category_id, p.created_at, p.updated_at,
+		SELECT p.id, p.name, p.slug, p.description, p.price, p.compare_price, p.images, p.in_stock, p.stock, p.featured, p.product_type, p.unit, p.sku, p.barcode, p.category_id, p.store_id, p.version, p.created_at, p.updated_at,
 		       c.id, c.name, c.slug, c.description, c.image, c.created_at, c.updated_at
 		FROM products p
 		LEFT JOIN categories c ON p.category_id = c.id
-		WHERE p.featured = true AND p.in_stock = true
+		WHERE p.featured = true AND p.in_stock = true AND p.deleted_at IS NULL AND ($2::uuid IS NULL OR p.store_id = $2)
 		ORDER BY p.created_at DESC
 		LIMIT $1
 	`
-	rows, err := r.db.Query(query, limit)
+	rows, err := r.db.Query(query, limit, storeParam(storeID))
 	if err != nil {
 		return nil, err
 	}
@@ -171,7 +512,7 @@ func (r *ProductRepository) GetFeatured(limit int) ([]models.ProductWithCategory
 		var categoryUpdatedAt sql.NullTime
 		err := rows.Scan(
 			&product.ID, &product.Name, &product.Slug, &product.Description, &product.Price, &product.ComparePrice,
-			&images, &product.InStock, &product.Stock, &product.Featured, &categoryID, &product.CreatedAt, &product.UpdatedAt,
+			&images, &product.InStock, &product.Stock, &product.Featured, &product.ProductType, &product.Unit, &product.SKU, &product.Barcode, &categoryID, &product.StoreID, &product.Version, &product.CreatedAt, &product.UpdatedAt,
 			&category.ID, &categoryName, &categorySlug, &categoryDescription, &categoryImage, &categoryCreatedAt, &categoryUpdatedAt,
 		)
 		if err != nil {
@@ -180,7 +521,7 @@ func (r *ProductRepository) GetFeatured(limit int) ([]models.ProductWithCategory
 		product.Images = []string(images)
 		product.CategoryID = categoryID.String
 		if categoryID.Valid {
-			category.ID = category.ID
+			category.ID = categoryID.String
 			category.Name = categoryName.String
 			category.Slug = categorySlug.String
 			category.Description = &categoryDescription.String
@@ -200,17 +541,17 @@ func (r *ProductRepository) GetFeatured(limit int) ([]models.ProductWithCategory
 	}
 	return products, nil
 }
-func (r *ProductRepository) Search(query string, limit int) ([]models.ProductWithCategory, error) {
+func (r *ProductRepository) Search(storeID, query string, limit int) ([]models.ProductWithCategory, error) {
 	searchQuery := `
-		SELECT p.id, p.name, p.slug, p.description, p.price, p.compare_price, p.images, p.in_stock, p.stock, p.featured, p.category_id, p.created_at, p.updated_at,
+		SELECT p.id, p.name, p.slug, p.description, p.price, p.compare_price, p.images, p.in_stock, p.stock, p.featured, p.product_type, p.unit, p.sku, p.barcode, p.category_id, p.store_id, p.version, p.created_at, p.updated_at,
 		       c.id, c.name, c.slug, c.description, c.image, c.created_at, c.updated_at
 		FROM products p
 		LEFT JOIN categories c ON p.category_id = c.id
-		WHERE p.name ILIKE $1 OR p.description ILIKE $1
+		WHERE (p.name ILIKE $1 ESCAPE '\' OR p.description ILIKE $1 ESCAPE '\') AND p.deleted_at IS NULL AND ($3::uuid IS NULL OR p.store_id = $3)
 		ORDER BY p.name
 		LIMIT $2
 	`
-	rows, err := r.db.Query(searchQuery, "%"+query+"%", limit)
+	rows, err := r.db.Query(searchQuery, "%"+escapeLike(query)+"%", limit, storeParam(storeID))
 	if err != nil {
 		return nil, err
 	}
@@ -229,7 +570,7 @@ func (r *ProductRepository) Search(query string, limit int) ([]models.ProductWit
 		var categoryUpdatedAt sql.NullTime
 		err := rows.Scan(
 			&product.ID, &product.Name, &product.Slug, &product.Description, &product.Price, &product.ComparePrice,
-			&images, &product.InStock, &product.Stock, &product.Featured, &categoryID, &product.CreatedAt, &product.UpdatedAt,
+			&images, &product.InStock, &product.Stock, &product.Featured, &product.ProductType, &product.Unit, &product.SKU, &product.Barcode, &categoryID, &product.StoreID, &product.Version, &product.CreatedAt, &product.UpdatedAt,
 			&category.ID, &categoryName, &categorySlug, &categoryDescription, &categoryImage, &categoryCreatedAt, &categoryUpdatedAt,
 		)
 		if err != nil {
@@ -238,7 +579,7 @@ func (r *ProductRepository) Search(query string, limit int) ([]models.ProductWit
 		product.Images = []string(images)
 		product.CategoryID = categoryID.String
 		if categoryID.Valid {
-			category.ID = category.ID
+			category.ID = categoryID.String
 			category.Name = categoryName.String
 			category.Slug = categorySlug.String
 			category.Description = &categoryDescription.String
@@ -258,39 +599,198 @@ func (r *ProductRepository) Search(query string, limit int) ([]models.ProductWit
 	}
 	return products, nil
 }
-func (r *ProductRepository) Update(id string, updates map[string]interface{}) error {
+// Update writes without a version check but still bumps the version, so an
+// editor holding an older copy cannot overwrite the change. A product of
+// another store is not found.
+func (r *ProductRepository) Update(storeID, id string, updates map[string]interface{}) error {
 	if len(updates) == 0 {
 		return nil
 	}
-	setParts := make([]string, 0, len(updates))
-	args := make([]interface{}, 0, len(updates)+1)
-	argIndex := 1
-	for key, value := range updates {
-		if key == "images" {
-			setParts = append(setParts, fmt.Sprintf("%s = $%d", key, argIndex))
-			args = append(args, pq.Array(value))
-		} else {
-			setParts = append(setParts, fmt.Sprintf("%s = $%d", key, argIndex))
-			args = append(args, value)
+	_, err := updateVersioned(r.db, "products", storeID, id, nil, updates, productColumnArg)
+	if err != nil && err.Error() == "not found" {
+		return fmt.Errorf("product not found")
+	}
+	return productUniqueError(err)
+}
+// UpdateVersioned applies updates only if the product is still at
+// expectedVersion and returns the new version. It fails with "product
+// version conflict" when someone else saved first.
+func (r *ProductRepository) UpdateVersioned(storeID, id string, expectedVersion int64, updates map[string]interface{}) (int64, error) {
+	version, err := updateVersioned(r.db, "products", storeID, id, &expectedVersion, updates, productColumnArg)
+	if err != nil {
+		switch err.Error() {
+		case "not found":
+			return 0, fmt.Errorf("product not found")
+		case "version conflict":
+			return 0, fmt.Errorf("product version conflict")
 		}
-		argIndex++
 	}
-	query := fmt.Sprintf("UPDATE products SET %s WHERE id = $%d", strings.Join(setParts, ", "), argIndex)
-	args = append(args, id)
-	_, err := r.db.Exec(query, args...)
-	return err
+	return version, productUniqueError(err)
 }
-func (r *ProductRepository) Delete(id string) error {
-	query := "DELETE FROM products WHERE id = $1"
-	_, err := r.db.Exec(query, id)
-	return err
+func productColumnArg(column string, value interface{}) interface{} {
+	if column == "images" {
+		return pq.Array(value)
+	}
+	return value
+}
+// Delete removes a product of storeID. A product that is a component of a
+// bundle must be taken out of the bundle first.
+func (r *ProductRepository) Delete(storeID, id string) error {
+	query := "DELETE FROM products WHERE id = $1 AND ($2::uuid IS NULL OR store_id = $2)"
+	res, err := r.db.Exec(query, id, storeParam(storeID))
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" && pqErr.Table == "product_bundle_items" {
+		return fmt.Errorf("product is part of a bundle")
+	}
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return fmt.Errorf("product not found")
+	}
+	return nil
+}
+// Merge moves the reviews, order lines, wishlist entries and images of the
+// duplicates to primaryID and soft-deletes the duplicates, in one
+// transaction. All products must be live and belong to the same store;
+// products of a store other than storeID are not found.
+func (r *ProductRepository) Merge(storeID, primaryID string, duplicateIDs []string) (*models.ProductMergeResult, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.Query(`
+		SELECT id, store_id FROM products
+		WHERE id::text = ANY($1) AND deleted_at IS NULL AND ($2::uuid IS NULL OR store_id = $2)
+		ORDER BY id
+		FOR UPDATE
+	`, pq.Array(append([]string{primaryID}, duplicateIDs...)), storeParam(storeID))
+	if err != nil {
+		return nil, err
+	}
+	stores := make(map[string]sql.NullString)
+	for rows.Next() {
+		var id string
+		var storeID sql.NullString
+		if err := rows.Scan(&id, &storeID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stores[id] = storeID
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	primaryStore, ok := stores[primaryID]
+	if !ok {
+		return nil, fmt.Errorf("product not found")
+	}
+	for _, id := range duplicateIDs {
+		storeID, ok := stores[id]
+		if !ok {
+			return nil, fmt.Errorf("duplicate product not found")
+		}
+		if storeID != primaryStore {
+			return nil, fmt.Errorf("products belong to different stores")
+		}
+	}
+	result := &models.ProductMergeResult{PrimaryID: primaryID, MergedIDs: duplicateIDs}
+	duplicates := pq.Array(duplicateIDs)
+	// A user keeps one review per product: their newest review among the
+	// duplicates moves unless they already reviewed the primary.
+	if result.ReviewsReassigned, err = execCount(tx, `
+		UPDATE reviews r SET product_id = $1
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (
+				PARTITION BY user_id
+				ORDER BY updated_at DESC NULLS LAST, created_at DESC NULLS LAST, id DESC
+			) AS rank
+			FROM reviews d
+			WHERE d.product_id::text = ANY($2)
+			  AND NOT EXISTS (SELECT 1 FROM reviews p WHERE p.product_id = $1 AND p.user_id = d.user_id)
+		) ranked
+		WHERE r.id = ranked.id AND (ranked.rank = 1 OR r.user_id IS NULL)
+	`, primaryID, duplicates); err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRow("SELECT COUNT(*) FROM reviews WHERE product_id::text = ANY($1)", duplicates).Scan(&result.ReviewsLeft); err != nil {
+		return nil, err
+	}
+	if result.OrderItemsReassigned, err = execCount(tx,
+		"UPDATE order_items SET product_id = $1 WHERE product_id::text = ANY($2)", primaryID, duplicates); err != nil {
+		return nil, err
+	}
+	if result.WishlistReassigned, err = execCount(tx, `
+		UPDATE wishlist_items w SET product_id = $1
+		FROM (
+			SELECT DISTINCT ON (user_id) id
+			FROM wishlist_items d
+			WHERE d.product_id::text = ANY($2)
+			  AND NOT EXISTS (SELECT 1 FROM wishlist_items p WHERE p.product_id = $1 AND p.user_id = d.user_id)
+			ORDER BY user_id, created_at
+		) moved
+		WHERE w.id = moved.id
+	`, primaryID, duplicates); err != nil {
+		return nil, err
+	}
+	if result.WishlistRemoved, err = execCount(tx,
+		"DELETE FROM wishlist_items WHERE product_id::text = ANY($1)", duplicates); err != nil {
+		return nil, err
+	}
+	// Images go after the primary's own gallery and never replace its
+	// primary image.
+	if result.ImagesReassigned, err = execCount(tx, `
+		UPDATE product_images SET product_id = $1, is_primary = false,
+			position = position + (SELECT COALESCE(MAX(position) + 1, 0) FROM product_images WHERE product_id = $1)
+		WHERE product_id::text = ANY($2)
+	`, primaryID, duplicates); err != nil {
+		return nil, err
+	}
+	// Cart lines fold into the user's line for the primary at its current
+	// price, and carts get a new version so checkouts in flight start over.
+	if _, err := tx.Exec(`
+		INSERT INTO carts (user_id, version)
+		SELECT DISTINCT user_id, 1 FROM cart_items WHERE product_id::text = ANY($1)
+		ON CONFLICT (user_id) DO UPDATE SET version = carts.version + 1, updated_at = CURRENT_TIMESTAMP
+	`, duplicates); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO cart_items (user_id, product_id, quantity, added_price)
+		SELECT d.user_id, $1, SUM(d.quantity), (SELECT price FROM products WHERE id = $1)
+		FROM cart_items d
+		WHERE d.product_id::text = ANY($2)
+		GROUP BY d.user_id
+		ON CONFLICT (user_id, product_id) DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity, updated_at = CURRENT_TIMESTAMP
+	`, primaryID, duplicates); err != nil {
+		return nil, err
+	}
+	if result.CartItemsReassigned, err = execCount(tx,
+		"DELETE FROM cart_items WHERE product_id::text = ANY($1)", duplicates); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("UPDATE products SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id::text = ANY($1)", duplicates); err != nil {
+		return nil, err
+	}
+	return result, tx.Commit()
+}
+func execCount(tx *sql.Tx, query string, args ...interface{}) (int, error) {
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := res.RowsAffected()
+	return int(affected), err
 }
-func (r *ProductRepository) GetProductByID(id string) (*models.Product, error) {
-	return r.GetByID(id)
+func (r *ProductRepository) GetProductByID(storeID, id string) (*models.Product, error) {
+	return r.GetByID(storeID, id)
 }
 func (r *ProductRepository) GetProductsByCategory(categoryID string, limit, offset int) ([]*models.Product, error) {
 	query := `
-		SELECT id, name, slug, description, price, compare_price, images, in_stock, stock, featured, category_id, created_at, updated_at
+		SELECT id, name, slug, description, price, compare_price, images, in_stock, stock, featured, product_type, category_id, created_at, updated_at
 		FROM products WHERE category_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3
 	`
 	rows, err := r.db.Query(query, categoryID, limit, offset)
@@ -304,7 +804,7 @@ func (r *ProductRepository) GetProductsByCategory(categoryID string, limit, offs
 		var images pq.StringArray
 		err := rows.Scan(
 			&product.ID, &product.Name, &product.Slug, &product.Description, &product.Price, &product.ComparePrice,
-			&images, &product.InStock, &product.Stock, &product.Featured, &product.CategoryID, &product.CreatedAt, &product.UpdatedAt,
+			&images, &product.InStock, &product.Stock, &product.Featured, &product.ProductType, &product.CategoryID, &product.CreatedAt, &product.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -313,4 +813,75 @@ func (r *ProductRepository) GetProductsByCategory(categoryID string, limit, offs
 		products = append(products, product)
 	}
 	return products, nil
-}
\ No newline at end of file
+}
+func (r *ProductRepository) CountProducts(storeID string) (int, error) {
+	var total int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM products WHERE deleted_at IS NULL AND ($1::uuid IS NULL OR store_id = $1)", storeParam(storeID)).Scan(&total)
+	return total, err
+}
+func (r *ProductRepository) ListForFeed(storeID string, limit, offset int) ([]models.ProductWithCategory, error) {
+	query := `
+		SELECT p.id, p.name, p.slug, p.description, p.price, p.compare_price, p.images, p.in_stock, p.stock, p.featured, p.product_type,
+		       p.sku, p.barcode, COALESCE(p.category_id::text, ''), p.created_at, p.updated_at, c.name
+		FROM products p
+		LEFT JOIN categories c ON p.category_id = c.id
+		WHERE p.deleted_at IS NULL AND ($3::uuid IS NULL OR p.store_id = $3)
+		ORDER BY p.created_at, p.id
+		LIMIT $1 OFFSET $2
+	`
+	rows, err := r.db.Query(query, limit, offset, storeParam(storeID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var products []models.ProductWithCategory
+	for rows.Next() {
+		var product models.ProductWithCategory
+		var images pq.StringArray
+		var categoryName sql.NullString
+		err := rows.Scan(
+			&product.ID, &product.Name, &product.Slug, &product.Description, &product.Price, &product.ComparePrice,
+			&images, &product.InStock, &product.Stock, &product.Featured, &product.ProductType, &product.SKU, &product.Barcode, &product.CategoryID, &product.CreatedAt, &product.UpdatedAt,
+			&categoryName,
+		)
+		if err != nil {
+			return nil, err
+		}
+		product.Images = []string(images)
+		if categoryName.Valid {
+			product.Category = &models.Category{ID: product.CategoryID, Name: categoryName.String}
+		}
+		products = append(products, product)
+	}
+	return products, rows.Err()
+}
+func (r *ProductRepository) SuggestByPrefix(storeID, prefix string, limit int) ([]models.SearchSuggestion, error) {
+	query := `
+		SELECT p.id, p.name, COALESCE(SUM(oi.quantity), 0) AS popularity
+		FROM products p
+		LEFT JOIN order_items oi ON oi.product_id = p.id
+		WHERE lower(p.name) LIKE $1 ESCAPE '\' AND ($3::uuid IS NULL OR p.store_id = $3)
+		GROUP BY p.id, p.name
+		ORDER BY popularity DESC, p.name
+		LIMIT $2
+	`
+	rows, err := r.db.Query(query, escapeLike(strings.ToLower(prefix))+"%", limit, storeParam(storeID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var suggestions []models.SearchSuggestion
+	for rows.Next() {
+		suggestion := models.SearchSuggestion{Type: models.SuggestionTypeProduct}
+		if err := rows.Scan(&suggestion.ID, &suggestion.Label, &suggestion.Score); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, nil
+}
+// escapeLike escapes the LIKE wildcards in value; queries using it must
+// declare ESCAPE '\'.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,341 @@
+﻿package repositories
+import (
+	"database/sql"
+	"fmt"
+	"ecommerce-backend/internal/models"
+	"github.com/lib/pq"
+)
+type QuestionRepository struct {
+	db *sql.DB
+}
+func NewQuestionRepository(db *sql.DB) *QuestionRepository {
+	return &QuestionRepository{db: db}
+}
+func (r *QuestionRepository) CreateQuestion(question *models.ProductQuestion) error {
+	query := `
+		INSERT INTO product_questions (id, product_id, user_id, body, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.Exec(query, question.ID, question.ProductID, question.UserID, question.Body, question.Status, question.CreatedAt, question.UpdatedAt)
+	return err
+}
+func (r *QuestionRepository) GetQuestionByID(id string) (*models.ProductQuestion, error) {
+	query := `
+		SELECT q.id, q.product_id, q.user_id, COALESCE(u.name, ''), q.body, q.status, q.reports, q.upvotes, q.created_at, q.updated_at
+		FROM product_questions q
+		LEFT JOIN users u ON q.user_id = u.id
+		WHERE q.id = $1
+	`
+	question := &models.ProductQuestion{}
+	err := r.db.QueryRow(query, id).Scan(
+		&question.ID, &question.ProductID, &question.UserID, &question.UserName, &question.Body, &question.Status, &question.Reports, &question.Upvotes, &question.CreatedAt, &question.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("question not found")
+	}
+	return question, err
+}
+// GetByProductID returns a page of approved questions for a product, newest
+// first or, with sort "top", by upvotes. Answers are not loaded.
+func (r *QuestionRepository) GetByProductID(productID, sort string, limit, offset int) ([]*models.ProductQuestion, int, error) {
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM product_questions WHERE product_id = $1 AND status = 'approved'", productID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	orderBy := "q.created_at DESC"
+	if sort == "top" {
+		orderBy = "q.upvotes DESC, q.created_at DESC"
+	}
+	query := `
+		SELECT q.id, q.product_id, q.user_id, COALESCE(u.name, ''), q.body, q.status, q.reports, q.upvotes, q.created_at, q.updated_at
+		FROM product_questions q
+		LEFT JOIN users u ON q.user_id = u.id
+		WHERE q.product_id = $1 AND q.status = 'approved'
+		ORDER BY ` + orderBy + `
+		LIMIT $2 OFFSET $3
+	`
+	questions, err := r.queryQuestions(query, productID, limit, offset)
+	return questions, total, err
+}
+// GetByStatus returns a page of questions in one status, oldest first, for
+// the moderation queue.
+func (r *QuestionRepository) GetByStatus(status string, limit, offset int) ([]*models.ProductQuestion, int, error) {
+	query := `
+		SELECT q.id, q.product_id, q.user_id, COALESCE(u.name, ''), q.body, q.status, q.reports, q.upvotes, q.created_at, q.updated_at
+		FROM product_questions q
+		LEFT JOIN users u ON q.user_id = u.id
+		WHERE q.status = $1
+		ORDER BY q.created_at, q.id
+		LIMIT $2 OFFSET $3
+	`
+	questions, err := r.queryQuestions(query, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM product_questions WHERE status = $1", status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	return questions, total, nil
+}
+func (r *QuestionRepository) queryQuestions(query string, args ...interface{}) ([]*models.ProductQuestion, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	questions := []*models.ProductQuestion{}
+	for rows.Next() {
+		question := &models.ProductQuestion{}
+		if err := rows.Scan(
+			&question.ID, &question.ProductID, &question.UserID, &question.UserName, &question.Body, &question.Status, &question.Reports, &question.Upvotes, &question.CreatedAt, &question.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		questions = append(questions, question)
+	}
+	return questions, rows.Err()
+}
+func (r *QuestionRepository) DeleteQuestion(id string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	statements := []string{
+		"DELETE FROM product_qa_votes WHERE target_type = 'answer' AND target_id IN (SELECT id FROM product_answers WHERE question_id = $1)",
+		"DELETE FROM product_qa_votes WHERE target_type = 'question' AND target_id = $1",
+		"DELETE FROM product_qa_reports WHERE target_type = 'answer' AND target_id IN (SELECT id FROM product_answers WHERE question_id = $1)",
+		"DELETE FROM product_qa_reports WHERE target_type = 'question' AND target_id = $1",
+		"DELETE FROM product_questions WHERE id = $1",
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+func (r *QuestionRepository) CreateAnswer(answer *models.ProductAnswer) error {
+	query := `
+		INSERT INTO product_answers (id, question_id, user_id, body, is_official, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.db.Exec(query, answer.ID, answer.QuestionID, answer.UserID, answer.Body, answer.IsOfficial, answer.Status, answer.CreatedAt, answer.UpdatedAt)
+	return err
+}
+func (r *QuestionRepository) GetAnswerByID(id string) (*models.ProductAnswer, error) {
+	query := `
+		SELECT a.id, a.question_id, a.user_id, COALESCE(u.name, ''), a.body, a.is_official, a.status, a.reports, a.upvotes, a.created_at, a.updated_at
+		FROM product_answers a
+		LEFT JOIN users u ON a.user_id = u.id
+		WHERE a.id = $1
+	`
+	answer := &models.ProductAnswer{}
+	err := r.db.QueryRow(query, id).Scan(
+		&answer.ID, &answer.QuestionID, &answer.UserID, &answer.UserName, &answer.Body, &answer.IsOfficial, &answer.Status, &answer.Reports, &answer.Upvotes, &answer.CreatedAt, &answer.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("answer not found")
+	}
+	return answer, err
+}
+// GetAnswersByQuestionIDs groups the approved answers by question, official
+// answers first and then by upvotes.
+func (r *QuestionRepository) GetAnswersByQuestionIDs(questionIDs []string) (map[string][]models.ProductAnswer, error) {
+	answers := make(map[string][]models.ProductAnswer)
+	if len(questionIDs) == 0 {
+		return answers, nil
+	}
+	query := `
+		SELECT a.id, a.question_id, a.user_id, COALESCE(u.name, ''), a.body, a.is_official, a.status, a.reports, a.upvotes, a.created_at, a.updated_at
+		FROM product_answers a
+		LEFT JOIN users u ON a.user_id = u.id
+		WHERE a.question_id = ANY($1) AND a.status = 'approved'
+		ORDER BY a.is_official DESC, a.upvotes DESC, a.created_at
+	`
+	list, err := r.queryAnswers(query, pq.Array(questionIDs))
+	if err != nil {
+		return nil, err
+	}
+	for _, answer := range list {
+		answers[answer.QuestionID] = append(answers[answer.QuestionID], answer)
+	}
+	return answers, nil
+}
+// GetAnswersByStatus returns a page of answers in one status, oldest first,
+// for the moderation queue.
+func (r *QuestionRepository) GetAnswersByStatus(status string, limit, offset int) ([]models.ProductAnswer, int, error) {
+	query := `
+		SELECT a.id, a.question_id, a.user_id, COALESCE(u.name, ''), a.body, a.is_official, a.status, a.reports, a.upvotes, a.created_at, a.updated_at
+		FROM product_answers a
+		LEFT JOIN users u ON a.user_id = u.id
+		WHERE a.status = $1
+		ORDER BY a.created_at, a.id
+		LIMIT $2 OFFSET $3
+	`
+	answers, err := r.queryAnswers(query, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM product_answers WHERE status = $1", status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	return answers, total, nil
+}
+func (r *QuestionRepository) queryAnswers(query string, args ...interface{}) ([]models.ProductAnswer, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	answers := []models.ProductAnswer{}
+	for rows.Next() {
+		var answer models.ProductAnswer
+		if err := rows.Scan(
+			&answer.ID, &answer.QuestionID, &answer.UserID, &answer.UserName, &answer.Body, &answer.IsOfficial, &answer.Status, &answer.Reports, &answer.Upvotes, &answer.CreatedAt, &answer.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		answers = append(answers, answer)
+	}
+	return answers, rows.Err()
+}
+func (r *QuestionRepository) DeleteAnswer(id string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM product_qa_votes WHERE target_type = 'answer' AND target_id = $1", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM product_qa_reports WHERE target_type = 'answer' AND target_id = $1", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM product_answers WHERE id = $1", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+func qaTable(targetType string) (string, error) {
+	switch targetType {
+	case models.QAVoteQuestion:
+		return "product_questions", nil
+	case models.QAVoteAnswer:
+		return "product_answers", nil
+	default:
+		return "", fmt.Errorf("invalid vote target")
+	}
+}
+// SetVote records or withdraws a user's upvote on approved content and keeps
+// the denormalized upvotes count in step. Repeating the same vote is a no-op.
+// It returns the resulting count.
+func (r *QuestionRepository) SetVote(userID, targetType, targetID string, up bool) (int, error) {
+	table, err := qaTable(targetType)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	var result sql.Result
+	delta := 1
+	if up {
+		result, err = tx.Exec("INSERT INTO product_qa_votes (user_id, target_type, target_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", userID, targetType, targetID)
+	} else {
+		delta = -1
+		result, err = tx.Exec("DELETE FROM product_qa_votes WHERE user_id = $1 AND target_type = $2 AND target_id = $3", userID, targetType, targetID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if changed == 0 {
+		delta = 0
+	}
+	var upvotes int
+	err = tx.QueryRow("UPDATE "+table+" SET upvotes = upvotes + $2 WHERE id = $1 AND status = 'approved' RETURNING upvotes", targetID, delta).Scan(&upvotes)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%s not found", targetType)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return upvotes, tx.Commit()
+}
+// Report records a user's report of approved content; repeating it is a
+// no-op. Content reaching threshold reports goes back to pending, and the
+// resulting status is returned.
+func (r *QuestionRepository) Report(userID, targetType, targetID, reason string, threshold int) (string, error) {
+	table, err := qaTable(targetType)
+	if err != nil {
+		return "", err
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	result, err := tx.Exec("INSERT INTO product_qa_reports (user_id, target_type, target_id, reason) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING", userID, targetType, targetID, reason)
+	if err != nil {
+		return "", err
+	}
+	added, err := result.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+	var status string
+	err = tx.QueryRow(`
+		UPDATE `+table+` SET reports = reports + $2,
+			status = CASE WHEN reports + $2 >= $3 THEN 'pending' ELSE status END
+		WHERE id = $1 AND status = 'approved'
+		RETURNING status
+	`, targetID, added, threshold).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("%s not found", targetType)
+	}
+	if err != nil {
+		return "", err
+	}
+	return status, tx.Commit()
+}
+// SetStatus moves a question or answer to status and returns its previous
+// status. Approving clears its reports so it can be reported again.
+func (r *QuestionRepository) SetStatus(targetType, targetID, status string) (string, error) {
+	table, err := qaTable(targetType)
+	if err != nil {
+		return "", err
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	var previous string
+	err = tx.QueryRow("SELECT status FROM "+table+" WHERE id = $1 FOR UPDATE", targetID).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("%s not found", targetType)
+	}
+	if err != nil {
+		return "", err
+	}
+	if status == models.QAStatusApproved {
+		if _, err := tx.Exec("DELETE FROM product_qa_reports WHERE target_type = $1 AND target_id = $2", targetType, targetID); err != nil {
+			return "", err
+		}
+		if _, err := tx.Exec("UPDATE "+table+" SET reports = 0 WHERE id = $1", targetID); err != nil {
+			return "", err
+		}
+	}
+	if _, err := tx.Exec("UPDATE "+table+" SET status = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1", targetID, status); err != nil {
+		return "", err
+	}
+	return previous, tx.Commit()
+}
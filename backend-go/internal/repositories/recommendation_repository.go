@@ -0,0 +1,62 @@
+﻿package repositories
+import (
+	"database/sql"
+)
+type RecommendationRepository struct {
+	db *sql.DB
+}
+func NewRecommendationRepository(db *sql.DB) *RecommendationRepository {
+	return &RecommendationRepository{db: db}
+}
+func (r *RecommendationRepository) RebuildAssociations(minSupport int) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM product_associations"); err != nil {
+		return 0, err
+	}
+	query := `
+		INSERT INTO product_associations (product_id, associated_product_id, score, updated_at)
+		SELECT a.product_id, b.product_id, COUNT(DISTINCT a.order_id), NOW()
+		FROM order_items a
+		JOIN order_items b ON a.order_id = b.order_id AND a.product_id <> b.product_id
+		JOIN orders o ON o.id = a.order_id
+		WHERE o.status <> 'cancelled'
+		GROUP BY a.product_id, b.product_id
+		HAVING COUNT(DISTINCT a.order_id) >= $1
+	`
+	result, err := tx.Exec(query, minSupport)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+func (r *RecommendationRepository) GetAssociatedProductIDs(productID string, limit int) ([]string, error) {
+	query := `
+		SELECT pa.associated_product_id
+		FROM product_associations pa
+		JOIN products p ON p.id = pa.associated_product_id
+		WHERE pa.product_id = $1 AND p.in_stock = true
+		ORDER BY pa.score DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Query(query, productID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
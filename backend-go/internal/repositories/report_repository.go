@@ -0,0 +1,91 @@
+﻿package repositories
+import (
+	"database/sql"
+	"ecommerce-backend/internal/models"
+)
+type ReportRepository struct {
+	db *sql.DB
+}
+func NewReportRepository(db *sql.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+const orderReportFilter = `
+		WHERE o.created_at >= $1 AND o.created_at < $2
+		  AND ($3 = '' OR o.status = $3)
+		  AND ($4::uuid IS NULL OR o.store_id = $4)
+		  AND NOT EXISTS (SELECT 1 FROM payments tp WHERE tp.order_id = o.id AND tp.test_mode)`
+func orderReportArgs(q models.OrderReportQuery) []interface{} {
+	return []interface{}{q.From, q.To, string(q.Status), storeParam(q.StoreID)}
+}
+func (r *ReportRepository) CountOrders(q models.OrderReportQuery) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM orders o`+orderReportFilter, orderReportArgs(q)...).Scan(&count)
+	return count, err
+}
+// StreamOrders calls fn for each matching order, oldest first, without
+// holding the whole report in memory. Line items, as sold rather than bundle
+// components, are joined in when q.IncludeItems is set.
+func (r *ReportRepository) StreamOrders(q models.OrderReportQuery, fn func(*models.OrderReportRow) error) error {
+	columns := `
+		SELECT o.id, o.store_id, o.user_id, o.status, o.created_at, o.subtotal, o.tax, o.shipping, o.gift_wrap_fee, o.total,
+		       COALESCE((SELECT SUM(p.amount) FROM payments p WHERE p.order_id = o.id AND p.status = 'succeeded'), 0),
+		       COALESCE((SELECT SUM(rt.refund_amount) FROM returns rt WHERE rt.order_id = o.id AND rt.refund_id IS NOT NULL), 0)`
+	from := ` FROM orders o`
+	if q.IncludeItems {
+		columns += `, oi.id, COALESCE(oi.product_id::text, ''), oi.product_name, oi.quantity, oi.unit, oi.price`
+		from += ` LEFT JOIN order_items oi ON oi.order_id = o.id AND oi.bundle_item_id IS NULL`
+	}
+	query := columns + from + orderReportFilter + ` ORDER BY o.created_at, o.id`
+	if q.IncludeItems {
+		query += `, oi.created_at, oi.id`
+	}
+	rows, err := r.db.Query(query, orderReportArgs(q)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var current *models.OrderReportRow
+	for rows.Next() {
+		var row models.OrderReportRow
+		dest := []interface{}{
+			&row.OrderID, &row.StoreID, &row.UserID, &row.Status, &row.CreatedAt, &row.Subtotal, &row.Tax,
+			&row.Shipping, &row.GiftWrapFee, &row.Total, &row.Paid, &row.Refunded,
+		}
+		var itemID, productID, productName, unit sql.NullString
+		var quantity models.Quantity
+		var price models.Money
+		if q.IncludeItems {
+			dest = append(dest, &itemID, &productID, &productName, &quantity, &unit, &price)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		if current == nil || current.OrderID != row.OrderID {
+			if current != nil {
+				if err := fn(current); err != nil {
+					return err
+				}
+			}
+			row.Net = row.Paid - row.Refunded
+			current = &row
+		}
+		if itemID.Valid {
+			current.Items = append(current.Items, models.OrderItem{
+				ID:          itemID.String,
+				OrderID:     current.OrderID,
+				ProductID:   productID.String,
+				ProductName: productName.String,
+				Quantity:    quantity,
+				Unit:        unit.String,
+				Price:       price,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if current != nil {
+		return fn(current)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+﻿package repositories
+import (
+	"database/sql"
+	"fmt"
+	"ecommerce-backend/internal/models"
+	"github.com/lib/pq"
+)
+type ReturnRepository struct {
+	db *sql.DB
+}
+func NewReturnRepository(db *sql.DB) *ReturnRepository {
+	return &ReturnRepository{db: db}
+}
+func (r *ReturnRepository) Create(ret *models.OrderReturn) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	query := `
+		INSERT INTO returns (id, order_id, user_id, status, reason, refund_amount, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err = tx.Exec(query, ret.ID, ret.OrderID, ret.UserID, ret.Status, ret.Reason, ret.RefundAmount, ret.CreatedAt, ret.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	for _, item := range ret.Items {
+		_, err := tx.Exec(
+			"INSERT INTO return_items (return_id, order_item_id, quantity) VALUES ($1, $2, $3)",
+			ret.ID, item.OrderItemID, item.Quantity,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+func (r *ReturnRepository) GetByID(returnID string) (*models.OrderReturn, error) {
+	query := `
+		SELECT id, order_id, COALESCE(user_id::text, ''), status, reason, admin_note, refund_amount, refund_id, received_at, created_at, updated_at
+		FROM returns WHERE id = $1
+	`
+	ret := &models.OrderReturn{}
+	err := r.db.QueryRow(query, returnID).Scan(
+		&ret.ID, &ret.OrderID, &ret.UserID, &ret.Status, &ret.Reason, &ret.AdminNote,
+		&ret.RefundAmount, &ret.RefundID, &ret.ReceivedAt, &ret.CreatedAt, &ret.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("return not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	items, err := r.getItems([]string{ret.ID})
+	if err != nil {
+		return nil, err
+	}
+	ret.Items = items[ret.ID]
+	return ret, nil
+}
+func (r *ReturnRepository) GetByOrderID(orderID string) ([]models.OrderReturn, error) {
+	query := `
+		SELECT id, order_id, COALESCE(user_id::text, ''), status, reason, admin_note, refund_amount, refund_id, received_at, created_at, updated_at
+		FROM returns WHERE order_id = $1
+		ORDER BY created_at
+	`
+	rows, err := r.db.Query(query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	returns := []models.OrderReturn{}
+	var ids []string
+	for rows.Next() {
+		var ret models.OrderReturn
+		err := rows.Scan(
+			&ret.ID, &ret.OrderID, &ret.UserID, &ret.Status, &ret.Reason, &ret.AdminNote,
+			&ret.RefundAmount, &ret.RefundID, &ret.ReceivedAt, &ret.CreatedAt, &ret.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		returns = append(returns, ret)
+		ids = append(ids, ret.ID)
+	}
+	if len(ids) == 0 {
+		return returns, nil
+	}
+	items, err := r.getItems(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range returns {
+		returns[i].Items = items[returns[i].ID]
+	}
+	return returns, nil
+}
+func (r *ReturnRepository) getItems(returnIDs []string) (map[string][]models.ReturnItem, error) {
+	rows, err := r.db.Query(
+		"SELECT return_id, order_item_id, quantity FROM return_items WHERE return_id = ANY($1)",
+		pq.Array(returnIDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make(map[string][]models.ReturnItem)
+	for rows.Next() {
+		var returnID string
+		var item models.ReturnItem
+		if err := rows.Scan(&returnID, &item.OrderItemID, &item.Quantity); err != nil {
+			return nil, err
+		}
+		items[returnID] = append(items[returnID], item)
+	}
+	return items, nil
+}
+func (r *ReturnRepository) GetReturnedQuantities(orderID string) (map[string]models.Quantity, error) {
+	query := `
+		SELECT ri.order_item_id, SUM(ri.quantity)
+		FROM return_items ri
+		JOIN returns rt ON ri.return_id = rt.id
+		WHERE rt.order_id = $1 AND rt.status <> 'rejected'
+		GROUP BY ri.order_item_id
+	`
+	rows, err := r.db.Query(query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	returned := make(map[string]models.Quantity)
+	for rows.Next() {
+		var orderItemID string
+		var quantity models.Quantity
+		if err := rows.Scan(&orderItemID, &quantity); err != nil {
+			return nil, err
+		}
+		returned[orderItemID] = quantity
+	}
+	return returned, nil
+}
+func (r *ReturnRepository) Update(ret *models.OrderReturn) error {
+	query := `
+		UPDATE returns
+		SET status = $2, admin_note = $3, refund_id = $4, received_at = $5, updated_at = $6
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(query, ret.ID, ret.Status, ret.AdminNote, ret.RefundID, ret.ReceivedAt, ret.UpdatedAt)
+	return err
+}
+// UpdateFrom saves ret only while the stored return is still in status from.
+// It reports whether the row was updated, so of two concurrent changes to the
+// same return only one succeeds.
+func (r *ReturnRepository) UpdateFrom(ret *models.OrderReturn, from models.ReturnStatus) (bool, error) {
+	query := `
+		UPDATE returns
+		SET status = $2, admin_note = $3, refund_id = $4, received_at = $5, updated_at = $6
+		WHERE id = $1 AND status = $7
+	`
+	result, err := r.db.Exec(query, ret.ID, ret.Status, ret.AdminNote, ret.RefundID, ret.ReceivedAt, ret.UpdatedAt, from)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"ecommerce-backend/internal/models"
+	"github.com/lib/pq"
 )
 type ReviewRepository struct {
 	db *sql.DB
@@ -17,6 +18,9 @@ func (r *ReviewRepository) Create(review *models.Review) error {
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 	_, err := r.db.Exec(query, review.ID, review.UserID, review.ProductID, review.Rating, review.Comment, review.Helpful, review.CreatedAt, review.UpdatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return fmt.Errorf("review already exists for this product")
+	}
 	return err
 }
 func (r *ReviewRepository) GetByID(id string) (*models.Review, error) {
@@ -36,9 +40,11 @@ func (r *ReviewRepository) GetByID(id string) (*models.Review, error) {
 func (r *ReviewRepository) GetByProductID(productID string, limit, offset int) ([]models.ReviewWithUser, error) {
 	query := `
 		SELECT r.id, r.user_id, r.product_id, r.rating, r.comment, r.helpful, r.created_at, r.updated_at,
-		       u.name, u.image
+		       u.name, u.image,
+		       rr.id, rr.user_id, rr.body, rr.created_at, rr.updated_at
 		FROM reviews r
 		JOIN users u ON r.user_id = u.id
+		LEFT JOIN review_replies rr ON rr.review_id = r.id
 		WHERE r.product_id = $1
 		ORDER BY r.created_at DESC
 		LIMIT $2 OFFSET $3
@@ -53,31 +59,73 @@ func (r *ReviewRepository) GetByProductID(productID string, limit, offset int) (
 		review := models.Review{}
 		var userName sql.NullString
 		var userImage sql.NullString
+		var replyID, replyUserID, replyBody sql.NullString
+		var replyCreatedAt, replyUpdatedAt sql.NullTime
 		err := rows.Scan(
 			&review.ID, &review.UserID, &review.ProductID, &review.Rating, &review.Comment, &review.Helpful, &review.CreatedAt, &review.UpdatedAt,
 			&userName, &userImage,
+			&replyID, &replyUserID, &replyBody, &replyCreatedAt, &replyUpdatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
-		reviews = append(reviews, models.ReviewWithUser{
+		item := models.ReviewWithUser{
 			Review: review,
 			UserName: userName.String,
 			UserImage: &userImage.String,
-		})
+		}
+		if replyID.Valid {
+			item.Reply = &models.ReviewReply{
+				ID:        replyID.String,
+				ReviewID:  review.ID,
+				UserID:    replyUserID.String,
+				Body:      replyBody.String,
+				CreatedAt: replyCreatedAt.Time,
+				UpdatedAt: replyUpdatedAt.Time,
+			}
+		}
+		reviews = append(reviews, item)
 	}
 	return reviews, nil
 }
-func (r *ReviewRepository) GetByUserID(userID string, limit, offset int) ([]*models.Review, error) {
-	query := `
-		SELECT id, user_id, product_id, rating, comment, helpful, created_at, updated_at
-		FROM reviews WHERE user_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
-	`
-	rows, err := r.db.Query(query, userID, limit, offset)
+func (r *ReviewRepository) GetByUserID(userID string, query models.ReviewQuery, limit, offset int) ([]*models.Review, int, error) {
+	whereClause := "WHERE r.user_id = $1"
+	args := []interface{}{userID}
+	argIndex := 2
+	if query.Rating > 0 {
+		whereClause += fmt.Sprintf(" AND r.rating = $%d", argIndex)
+		args = append(args, query.Rating)
+		argIndex++
+	}
+	if query.HasReply != nil {
+		if *query.HasReply {
+			whereClause += " AND EXISTS (SELECT 1 FROM review_replies rr WHERE rr.review_id = r.id)"
+		} else {
+			whereClause += " AND NOT EXISTS (SELECT 1 FROM review_replies rr WHERE rr.review_id = r.id)"
+		}
+	}
+	var total int
+	countQuery := "SELECT COUNT(*) FROM reviews r " + whereClause
+	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	orderBy := "r.created_at DESC"
+	switch query.Sort {
+	case "highest":
+		orderBy = "r.rating DESC, r.created_at DESC"
+	case "lowest":
+		orderBy = "r.rating ASC, r.created_at DESC"
+	}
+	selectQuery := fmt.Sprintf(`
+		SELECT r.id, r.user_id, r.product_id, r.rating, r.comment, r.helpful, r.created_at, r.updated_at
+		FROM reviews r %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, whereClause, orderBy, argIndex, argIndex+1)
+	args = append(args, limit, offset)
+	rows, err := r.db.Query(selectQuery, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 	var reviews []*models.Review
@@ -87,11 +135,11 @@ func (r *ReviewRepository) GetByUserID(userID string, limit, offset int) ([]*mod
 			&review.ID, &review.UserID, &review.ProductID, &review.Rating, &review.Comment, &review.Helpful, &review.CreatedAt, &review.UpdatedAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		reviews = append(reviews, review)
 	}
-	return reviews, nil
+	return reviews, total, nil
 }
 func (r *ReviewRepository) GetUserReviewForProduct(userID, productID string) (*models.Review, error) {
 	query := `
@@ -123,6 +171,63 @@ func (r *ReviewRepository) GetProductRating(productID string) (float64, int, err
 	}
 	return 0, 0, nil
 }
+func (r *ReviewRepository) GetRatingSummaries(productIDs []string) (map[string]*models.RatingSummary, error) {
+	query := `
+		SELECT product_id, COUNT(*), AVG(rating)
+		FROM reviews WHERE product_id = ANY($1)
+		GROUP BY product_id
+	`
+	rows, err := r.db.Query(query, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	summaries := make(map[string]*models.RatingSummary, len(productIDs))
+	for rows.Next() {
+		summary := &models.RatingSummary{}
+		var avgRating sql.NullFloat64
+		if err := rows.Scan(&summary.ProductID, &summary.ReviewCount, &avgRating); err != nil {
+			return nil, err
+		}
+		if avgRating.Valid {
+			summary.AverageRating = avgRating.Float64
+		}
+		summaries[summary.ProductID] = summary
+	}
+	return summaries, rows.Err()
+}
+func (r *ReviewRepository) GetProductSummary(productID string) (*models.ReviewSummary, error) {
+	query := `
+		SELECT COUNT(*), AVG(r.rating),
+		       COUNT(*) FILTER (WHERE r.rating = 1),
+		       COUNT(*) FILTER (WHERE r.rating = 2),
+		       COUNT(*) FILTER (WHERE r.rating = 3),
+		       COUNT(*) FILTER (WHERE r.rating = 4),
+		       COUNT(*) FILTER (WHERE r.rating = 5),
+		       COUNT(*) FILTER (WHERE EXISTS (
+		           SELECT 1 FROM order_items oi
+		           JOIN orders o ON oi.order_id = o.id
+		           WHERE o.user_id = r.user_id AND oi.product_id = r.product_id AND o.status <> 'cancelled'
+		       ))
+		FROM reviews r WHERE r.product_id = $1
+	`
+	summary := &models.ReviewSummary{ProductID: productID, Distribution: make(map[int]int, 5)}
+	var avgRating sql.NullFloat64
+	var stars [5]int
+	err := r.db.QueryRow(query, productID).Scan(
+		&summary.TotalCount, &avgRating, &stars[0], &stars[1], &stars[2], &stars[3], &stars[4], &summary.VerifiedCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if avgRating.Valid {
+		summary.AverageRating = avgRating.Float64
+	}
+	for i, count := range stars {
+		summary.Distribution[i+1] = count
+	}
+	return summary, nil
+}
 func (r *ReviewRepository) Update(id string, updates map[string]interface{}) error {
 	if len(updates) == 0 {
 		return nil
@@ -144,4 +249,89 @@ func (r *ReviewRepository) Delete(id string) error {
 	query := "DELETE FROM reviews WHERE id = $1"
 	_, err := r.db.Exec(query, id)
 	return err
+}
+func (r *ReviewRepository) CreateReply(reply *models.ReviewReply) error {
+	query := `
+		INSERT INTO review_replies (id, review_id, user_id, body, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.Exec(query, reply.ID, reply.ReviewID, reply.UserID, reply.Body, reply.CreatedAt, reply.UpdatedAt)
+	return err
+}
+func (r *ReviewRepository) GetReplyByReviewID(reviewID string) (*models.ReviewReply, error) {
+	query := `
+		SELECT id, review_id, user_id, body, created_at, updated_at
+		FROM review_replies WHERE review_id = $1
+	`
+	reply := &models.ReviewReply{}
+	var userID sql.NullString
+	err := r.db.QueryRow(query, reviewID).Scan(
+		&reply.ID, &reply.ReviewID, &userID, &reply.Body, &reply.CreatedAt, &reply.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	reply.UserID = userID.String
+	return reply, err
+}
+func (r *ReviewRepository) UpdateReply(id, body string) error {
+	query := "UPDATE review_replies SET body = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2"
+	_, err := r.db.Exec(query, body, id)
+	return err
+}
+func (r *ReviewRepository) DeleteReply(id string) error {
+	query := "DELETE FROM review_replies WHERE id = $1"
+	_, err := r.db.Exec(query, id)
+	return err
+}
+func (r *ReviewRepository) ReplaceImages(reviewID string, uploads []*models.Upload) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM review_images WHERE review_id = $1", reviewID); err != nil {
+		return err
+	}
+	for i, upload := range uploads {
+		_, err := tx.Exec(
+			"INSERT INTO review_images (review_id, upload_id, position) VALUES ($1, $2, $3)",
+			reviewID, upload.ID, i,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+// IsUploadReferenced reports whether any review still shows the upload.
+func (r *ReviewRepository) IsUploadReferenced(uploadID string) (bool, error) {
+	var referenced bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM review_images WHERE upload_id = $1)", uploadID).Scan(&referenced)
+	return referenced, err
+}
+func (r *ReviewRepository) GetImagesByReviewIDs(reviewIDs []string) (map[string][]models.ReviewImage, error) {
+	query := `
+		SELECT ri.id, ri.review_id, ri.upload_id, u.filename, u.size, ri.position
+		FROM review_images ri
+		JOIN uploads u ON ri.upload_id = u.id
+		WHERE ri.review_id = ANY($1)
+		ORDER BY ri.review_id, ri.position
+	`
+	rows, err := r.db.Query(query, pq.Array(reviewIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	images := make(map[string][]models.ReviewImage)
+	for rows.Next() {
+		var image models.ReviewImage
+		err := rows.Scan(&image.ID, &image.ReviewID, &image.UploadID, &image.Filename, &image.Size, &image.Position)
+		if err != nil {
+			return nil, err
+		}
+		image.URL = fmt.Sprintf("/uploads/%s", image.Filename)
+		images[image.ReviewID] = append(images[image.ReviewID], image)
+	}
+	return images, nil
 }
\ No newline at end of file
@@ -0,0 +1,183 @@
+﻿package repositories
+import (
+	"database/sql"
+	"fmt"
+	"ecommerce-backend/internal/models"
+	"github.com/lib/pq"
+)
+type ShipmentRepository struct {
+	db *sql.DB
+}
+func NewShipmentRepository(db *sql.DB) *ShipmentRepository {
+	return &ShipmentRepository{db: db}
+}
+// Create records shipment. The order row is locked while its status and the
+// quantities already shipped are re-read, so concurrent shipments cannot ship
+// more of an item than ordered holds.
+func (r *ShipmentRepository) Create(shipment *models.Shipment, ordered map[string]models.Quantity) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var status models.OrderStatus
+	err = tx.QueryRow("SELECT status FROM orders WHERE id = $1 FOR UPDATE", shipment.OrderID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("order not found")
+	}
+	if err != nil {
+		return err
+	}
+	if !status.Shippable() {
+		return fmt.Errorf("order cannot be shipped")
+	}
+	rows, err := tx.Query(shippedQuantitiesQuery, shipment.OrderID)
+	if err != nil {
+		return err
+	}
+	shipped, err := scanShippedQuantities(rows)
+	if err != nil {
+		return err
+	}
+	for _, item := range shipment.Items {
+		if left := ordered[item.OrderItemID] - shipped[item.OrderItemID]; item.Quantity > left {
+			return fmt.Errorf("invalid quantity for order item %s: %s remaining", item.OrderItemID, left)
+		}
+	}
+	query := `
+		INSERT INTO shipments (id, order_id, carrier, tracking_number, status, shipped_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err = tx.Exec(query, shipment.ID, shipment.OrderID, shipment.Carrier, shipment.TrackingNumber,
+		shipment.Status, shipment.ShippedAt, shipment.CreatedAt, shipment.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	for _, item := range shipment.Items {
+		_, err := tx.Exec(
+			"INSERT INTO shipment_items (shipment_id, order_item_id, quantity) VALUES ($1, $2, $3)",
+			shipment.ID, item.OrderItemID, item.Quantity,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+func (r *ShipmentRepository) GetByID(shipmentID string) (*models.Shipment, error) {
+	query := `
+		SELECT id, order_id, carrier, tracking_number, status, shipped_at, delivered_at, created_at, updated_at
+		FROM shipments WHERE id = $1
+	`
+	shipment := &models.Shipment{}
+	err := r.db.QueryRow(query, shipmentID).Scan(
+		&shipment.ID, &shipment.OrderID, &shipment.Carrier, &shipment.TrackingNumber, &shipment.Status,
+		&shipment.ShippedAt, &shipment.DeliveredAt, &shipment.CreatedAt, &shipment.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("shipment not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	items, err := r.getItems([]string{shipment.ID})
+	if err != nil {
+		return nil, err
+	}
+	shipment.Items = items[shipment.ID]
+	return shipment, nil
+}
+func (r *ShipmentRepository) GetByOrderID(orderID string) ([]models.Shipment, error) {
+	query := `
+		SELECT id, order_id, carrier, tracking_number, status, shipped_at, delivered_at, created_at, updated_at
+		FROM shipments WHERE order_id = $1
+		ORDER BY created_at
+	`
+	rows, err := r.db.Query(query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	shipments := []models.Shipment{}
+	var ids []string
+	for rows.Next() {
+		var shipment models.Shipment
+		err := rows.Scan(
+			&shipment.ID, &shipment.OrderID, &shipment.Carrier, &shipment.TrackingNumber, &shipment.Status,
+			&shipment.ShippedAt, &shipment.DeliveredAt, &shipment.CreatedAt, &shipment.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		shipments = append(shipments, shipment)
+		ids = append(ids, shipment.ID)
+	}
+	if len(ids) == 0 {
+		return shipments, nil
+	}
+	items, err := r.getItems(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range shipments {
+		shipments[i].Items = items[shipments[i].ID]
+	}
+	return shipments, nil
+}
+func (r *ShipmentRepository) getItems(shipmentIDs []string) (map[string][]models.ShipmentItem, error) {
+	rows, err := r.db.Query(
+		"SELECT shipment_id, order_item_id, quantity FROM shipment_items WHERE shipment_id = ANY($1)",
+		pq.Array(shipmentIDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make(map[string][]models.ShipmentItem)
+	for rows.Next() {
+		var shipmentID string
+		var item models.ShipmentItem
+		if err := rows.Scan(&shipmentID, &item.OrderItemID, &item.Quantity); err != nil {
+			return nil, err
+		}
+		items[shipmentID] = append(items[shipmentID], item)
+	}
+	return items, nil
+}
+const shippedQuantitiesQuery = `
+	SELECT si.order_item_id, SUM(si.quantity)
+	FROM shipment_items si
+	JOIN shipments s ON si.shipment_id = s.id
+	WHERE s.order_id = $1
+	GROUP BY si.order_item_id
+`
+func (r *ShipmentRepository) GetShippedQuantities(orderID string) (map[string]models.Quantity, error) {
+	rows, err := r.db.Query(shippedQuantitiesQuery, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return scanShippedQuantities(rows)
+}
+func scanShippedQuantities(rows *sql.Rows) (map[string]models.Quantity, error) {
+	defer rows.Close()
+	shipped := make(map[string]models.Quantity)
+	for rows.Next() {
+		var orderItemID string
+		var quantity models.Quantity
+		if err := rows.Scan(&orderItemID, &quantity); err != nil {
+			return nil, err
+		}
+		shipped[orderItemID] = quantity
+	}
+	return shipped, nil
+}
+func (r *ShipmentRepository) Update(shipment *models.Shipment) error {
+	query := `
+		UPDATE shipments
+		SET carrier = $2, tracking_number = $3, status = $4, delivered_at = $5, updated_at = $6
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(query, shipment.ID, shipment.Carrier, shipment.TrackingNumber,
+		shipment.Status, shipment.DeliveredAt, shipment.UpdatedAt)
+	return err
+}
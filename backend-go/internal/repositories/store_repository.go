@@ -0,0 +1,83 @@
+﻿package repositories
+import (
+	"database/sql"
+	"fmt"
+	"ecommerce-backend/internal/models"
+)
+type StoreRepository struct {
+	db *sql.DB
+}
+func NewStoreRepository(db *sql.DB) *StoreRepository {
+	return &StoreRepository{db: db}
+}
+const storeColumns = "id, slug, name, domain, active, created_at, updated_at"
+func scanStore(row interface{ Scan(...interface{}) error }) (*models.Store, error) {
+	store := &models.Store{}
+	err := row.Scan(&store.ID, &store.Slug, &store.Name, &store.Domain, &store.Active, &store.CreatedAt, &store.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("store not found")
+	}
+	return store, err
+}
+func (r *StoreRepository) Create(store *models.Store) error {
+	query := `
+		INSERT INTO stores (slug, name, domain, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+	return r.db.QueryRow(query, store.Slug, store.Name, store.Domain, store.Active, store.CreatedAt, store.UpdatedAt).Scan(&store.ID)
+}
+func (r *StoreRepository) GetByID(id string) (*models.Store, error) {
+	return scanStore(r.db.QueryRow("SELECT "+storeColumns+" FROM stores WHERE id = $1", id))
+}
+func (r *StoreRepository) GetBySlug(slug string) (*models.Store, error) {
+	return scanStore(r.db.QueryRow("SELECT "+storeColumns+" FROM stores WHERE slug = $1", slug))
+}
+func (r *StoreRepository) GetByDomain(domain string) (*models.Store, error) {
+	return scanStore(r.db.QueryRow("SELECT "+storeColumns+" FROM stores WHERE domain = $1", domain))
+}
+func (r *StoreRepository) List() ([]models.Store, error) {
+	rows, err := r.db.Query("SELECT " + storeColumns + " FROM stores ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stores := []models.Store{}
+	for rows.Next() {
+		store, err := scanStore(rows)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, *store)
+	}
+	return stores, rows.Err()
+}
+func (r *StoreRepository) Update(store *models.Store) error {
+	query := `
+		UPDATE stores SET name = $2, domain = $3, active = $4, updated_at = $5
+		WHERE id = $1
+	`
+	result, err := r.db.Exec(query, store.ID, store.Name, store.Domain, store.Active, store.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("store not found")
+	}
+	return nil
+}
+// storeParam turns a store filter into a query argument. An empty filter
+// becomes NULL so "($n::uuid IS NULL OR store_id = $n)" matches every store.
+func storeParam(storeID string) interface{} {
+	if storeID == "" {
+		return nil
+	}
+	return storeID
+}
+// storeOrDefault is the store new rows are written to when none is given.
+func storeOrDefault(storeID string) string {
+	if storeID == "" {
+		return models.DefaultStoreID
+	}
+	return storeID
+}
@@ -0,0 +1,106 @@
+﻿package repositories
+import (
+	"database/sql"
+	"fmt"
+	"ecommerce-backend/internal/models"
+	"github.com/lib/pq"
+)
+type TranslationRepository struct {
+	db *sql.DB
+}
+func NewTranslationRepository(db *sql.DB) *TranslationRepository {
+	return &TranslationRepository{db: db}
+}
+func (r *TranslationRepository) GetProductTranslations(productIDs []string, locale string) (map[string]models.Translation, error) {
+	return r.getTranslations("product_translations", "product_id", productIDs, locale)
+}
+func (r *TranslationRepository) GetCategoryTranslations(categoryIDs []string, locale string) (map[string]models.Translation, error) {
+	return r.getTranslations("category_translations", "category_id", categoryIDs, locale)
+}
+func (r *TranslationRepository) ListProductTranslations(productID string) ([]models.Translation, error) {
+	return r.listTranslations("product_translations", "product_id", productID)
+}
+func (r *TranslationRepository) ListCategoryTranslations(categoryID string) ([]models.Translation, error) {
+	return r.listTranslations("category_translations", "category_id", categoryID)
+}
+func (r *TranslationRepository) UpsertProductTranslation(translation *models.Translation) error {
+	return r.upsertTranslation("product_translations", "product_id", translation)
+}
+func (r *TranslationRepository) UpsertCategoryTranslation(translation *models.Translation) error {
+	return r.upsertTranslation("category_translations", "category_id", translation)
+}
+func (r *TranslationRepository) DeleteProductTranslation(productID, locale string) error {
+	return r.deleteTranslation("product_translations", "product_id", productID, locale)
+}
+func (r *TranslationRepository) DeleteCategoryTranslation(categoryID, locale string) error {
+	return r.deleteTranslation("category_translations", "category_id", categoryID, locale)
+}
+func (r *TranslationRepository) getTranslations(table, idColumn string, ids []string, locale string) (map[string]models.Translation, error) {
+	translations := make(map[string]models.Translation)
+	if len(ids) == 0 {
+		return translations, nil
+	}
+	query := fmt.Sprintf(`
+		SELECT %[2]s, locale, name, description, created_at, updated_at
+		FROM %[1]s WHERE %[2]s = ANY($1) AND locale = $2
+	`, table, idColumn)
+	rows, err := r.db.Query(query, pq.Array(ids), locale)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t models.Translation
+		if err := rows.Scan(&t.EntityID, &t.Locale, &t.Name, &t.Description, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		translations[t.EntityID] = t
+	}
+	return translations, rows.Err()
+}
+func (r *TranslationRepository) listTranslations(table, idColumn, id string) ([]models.Translation, error) {
+	query := fmt.Sprintf(`
+		SELECT %[2]s, locale, name, description, created_at, updated_at
+		FROM %[1]s WHERE %[2]s = $1
+		ORDER BY locale
+	`, table, idColumn)
+	rows, err := r.db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	translations := []models.Translation{}
+	for rows.Next() {
+		var t models.Translation
+		if err := rows.Scan(&t.EntityID, &t.Locale, &t.Name, &t.Description, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		translations = append(translations, t)
+	}
+	return translations, rows.Err()
+}
+func (r *TranslationRepository) upsertTranslation(table, idColumn string, t *models.Translation) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s (%[2]s, locale, name, description)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (%[2]s, locale) DO UPDATE
+		SET name = EXCLUDED.name, description = EXCLUDED.description, updated_at = CURRENT_TIMESTAMP
+		RETURNING created_at, updated_at
+	`, table, idColumn)
+	return r.db.QueryRow(query, t.EntityID, t.Locale, t.Name, t.Description).Scan(&t.CreatedAt, &t.UpdatedAt)
+}
+func (r *TranslationRepository) deleteTranslation(table, idColumn, id, locale string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = $1 AND locale = $2", table, idColumn)
+	result, err := r.db.Exec(query, id, locale)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("translation not found")
+	}
+	return nil
+}
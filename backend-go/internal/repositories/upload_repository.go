@@ -0,0 +1,150 @@
+﻿package repositories
+import (
+	"database/sql"
+	"fmt"
+	"ecommerce-backend/internal/models"
+	"github.com/lib/pq"
+)
+type UploadRepository struct {
+	db *sql.DB
+}
+func NewUploadRepository(db *sql.DB) *UploadRepository {
+	return &UploadRepository{db: db}
+}
+// CreateWithinQuota records upload unless it would take the user's total
+// past quota bytes; a quota of zero or less is unlimited. The user's row is
+// locked while the total is summed, so concurrent uploads cannot both fit
+// into the same remaining space.
+func (r *UploadRepository) CreateWithinQuota(upload *models.Upload, quota int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if quota > 0 {
+		if _, err := tx.Exec("SELECT 1 FROM users WHERE id = $1 FOR UPDATE", upload.UserID); err != nil {
+			return err
+		}
+		var used int64
+		if err := tx.QueryRow("SELECT COALESCE(SUM(size), 0) FROM uploads WHERE user_id = $1", upload.UserID).Scan(&used); err != nil {
+			return err
+		}
+		if used+upload.Size > quota {
+			return fmt.Errorf("upload quota exceeded")
+		}
+	}
+	query := `
+		INSERT INTO uploads (id, user_id, filename, content_type, size, private, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err = tx.Exec(query, upload.ID, upload.UserID, upload.Filename, upload.ContentType, upload.Size, upload.Private, upload.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+func (r *UploadRepository) GetByFilename(filename string) (*models.Upload, error) {
+	query := `
+		SELECT id, user_id, filename, content_type, size, private, created_at
+		FROM uploads WHERE filename = $1
+	`
+	upload := &models.Upload{}
+	err := r.db.QueryRow(query, filename).Scan(
+		&upload.ID, &upload.UserID, &upload.Filename, &upload.ContentType, &upload.Size, &upload.Private, &upload.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("upload not found")
+	}
+	return upload, err
+}
+func (r *UploadRepository) GetByFilenames(filenames []string) ([]*models.Upload, error) {
+	query := `
+		SELECT id, user_id, filename, content_type, size, private, created_at
+		FROM uploads WHERE filename = ANY($1)
+	`
+	rows, err := r.db.Query(query, pq.Array(filenames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var uploads []*models.Upload
+	for rows.Next() {
+		upload := &models.Upload{}
+		err := rows.Scan(
+			&upload.ID, &upload.UserID, &upload.Filename, &upload.ContentType, &upload.Size, &upload.Private, &upload.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, nil
+}
+func (r *UploadRepository) GetByUserID(userID string, limit, offset int) ([]*models.Upload, int, error) {
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM uploads WHERE user_id = $1", userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	query := `
+		SELECT id, user_id, filename, content_type, size, private, created_at
+		FROM uploads WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	uploads := []*models.Upload{}
+	for rows.Next() {
+		upload := &models.Upload{}
+		err := rows.Scan(
+			&upload.ID, &upload.UserID, &upload.Filename, &upload.ContentType, &upload.Size, &upload.Private, &upload.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, total, rows.Err()
+}
+// GetErasableByUserID returns all of the user's uploads except those the
+// catalog still serves as a product image or digital file.
+func (r *UploadRepository) GetErasableByUserID(userID string) ([]*models.Upload, error) {
+	query := `
+		SELECT u.id, u.user_id, u.filename, u.content_type, u.size, u.private, u.created_at
+		FROM uploads u
+		WHERE u.user_id = $1
+		  AND NOT EXISTS (SELECT 1 FROM products p WHERE p.digital_file = u.filename)
+		  AND NOT EXISTS (SELECT 1 FROM product_images pi WHERE pi.url LIKE '%/' || u.filename)
+	`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var uploads []*models.Upload
+	for rows.Next() {
+		upload := &models.Upload{}
+		err := rows.Scan(
+			&upload.ID, &upload.UserID, &upload.Filename, &upload.ContentType, &upload.Size, &upload.Private, &upload.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, rows.Err()
+}
+func (r *UploadRepository) GetTotalSizeByUser(userID string) (int64, error) {
+	query := "SELECT COALESCE(SUM(size), 0) FROM uploads WHERE user_id = $1"
+	var total int64
+	err := r.db.QueryRow(query, userID).Scan(&total)
+	return total, err
+}
+func (r *UploadRepository) Delete(id string) error {
+	query := "DELETE FROM uploads WHERE id = $1"
+	_, err := r.db.Exec(query, id)
+	return err
+}
@@ -3,7 +3,10 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 	"ecommerce-backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 type UserRepository struct {
 	db *sql.DB
@@ -17,30 +20,70 @@ func (r *UserRepository) Create(user *models.User) error {
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 	_, err := r.db.Exec(query, user.ID, user.Email, user.Name, user.Password, user.Role, user.Image, user.CreatedAt, user.UpdatedAt)
+	return userUniqueError(err)
+}
+// userUniqueError maps violations of the email unique constraints, which
+// back up the service's pre-check against concurrent sign-ups, and of the
+// store foreign key.
+func userUniqueError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		switch pqErr.Constraint {
+		case "users_email_key", "idx_users_email_lower":
+			return fmt.Errorf("email already registered")
+		}
+	}
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" && pqErr.Constraint == "users_store_id_fkey" {
+		return fmt.Errorf("store not found")
+	}
 	return err
 }
+// CreateBatch inserts users batchSize rows at a time. Users whose email is
+// already registered are skipped; the number actually inserted is returned.
+// Empty IDs are generated.
+func (r *UserRepository) CreateBatch(users []*models.User, batchSize int) (int, error) {
+	rows := make([][]interface{}, len(users))
+	for i, user := range users {
+		if user.ID == "" {
+			user.ID = uuid.New().String()
+		}
+		rows[i] = []interface{}{user.ID, user.Email, user.Name, user.Password, user.Role, user.Image, user.CreatedAt, user.UpdatedAt}
+	}
+	return insertBatches(r.db, `
+		INSERT INTO users (id, email, name, password, role, image, created_at, updated_at)
+		VALUES `, "ON CONFLICT (email) DO NOTHING", rows, batchSize)
+}
 func (r *UserRepository) GetByID(id string) (*models.User, error) {
 	query := `
-		SELECT id, email, name, password, role, image, created_at, updated_at
+		SELECT id, email, name, password, role, image, store_id, created_at, updated_at
 		FROM users WHERE id = $1
 	`
 	user := &models.User{}
 	err := r.db.QueryRow(query, id).Scan(
-		&user.ID, &user.Email, &user.Name, &user.Password, &user.Role, &user.Image, &user.CreatedAt, &user.UpdatedAt,
+		&user.ID, &user.Email, &user.Name, &user.Password, &user.Role, &user.Image, &user.StoreID, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("user not found")
 	}
 	return user, err
 }
+// HasRole reports whether any user has role.
+func (r *UserRepository) HasRole(role string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE role = $1)", role).Scan(&exists)
+	return exists, err
+}
+// GetByEmail matches email, which callers normalize, in any letter case.
+// Should legacy accounts still differ only by case, the exact match wins.
 func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	query := `
-		SELECT id, email, name, password, role, image, created_at, updated_at
-		FROM users WHERE email = $1
+		SELECT id, email, name, password, role, image, store_id, created_at, updated_at
+		FROM users WHERE LOWER(email) = $1
+		ORDER BY email = $1 DESC, created_at
+		LIMIT 1
 	`
 	user := &models.User{}
 	err := r.db.QueryRow(query, email).Scan(
-		&user.ID, &user.Email, &user.Name, &user.Password, &user.Role, &user.Image, &user.CreatedAt, &user.UpdatedAt,
+		&user.ID, &user.Email, &user.Name, &user.Password, &user.Role, &user.Image, &user.StoreID, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("user not found")
@@ -62,7 +105,45 @@ func (r *UserRepository) Update(id string, updates map[string]interface{}) error
 	query := fmt.Sprintf("UPDATE users SET %s WHERE id = $%d", strings.Join(setParts, ", "), argIndex)
 	args = append(args, id)
 	_, err := r.db.Exec(query, args...)
-	return err
+	return userUniqueError(err)
+}
+func (r *UserRepository) Anonymize(id string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	statements := []string{
+		"DELETE FROM cart_items WHERE user_id = $1",
+		"DELETE FROM wishlist_items WHERE user_id = $1",
+		"DELETE FROM data_exports WHERE user_id = $1",
+		// Order reads scan the addresses into strings, so they are
+		// replaced with a marker rather than set to NULL.
+		`UPDATE orders SET shipping_address = '{"redacted": true}', billing_address = '{"redacted": true}',
+		     customer_note = NULL, gift_message = NULL
+		 WHERE user_id = $1`,
+		"UPDATE returns SET reason = '[redacted]' WHERE user_id = $1",
+		`UPDATE users
+		 SET email = 'deleted-' || id || '@deleted.invalid', name = NULL, image = NULL,
+		     password = '!', tokens_revoked_at = NOW(), updated_at = NOW()
+		 WHERE id = $1`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+// GetTokensRevokedAt returns when the user's tokens were last revoked, or nil
+// if they never were.
+func (r *UserRepository) GetTokensRevokedAt(id string) (*time.Time, error) {
+	var revokedAt *time.Time
+	err := r.db.QueryRow("SELECT tokens_revoked_at FROM users WHERE id = $1", id).Scan(&revokedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	}
+	return revokedAt, err
 }
 func (r *UserRepository) Delete(id string) error {
 	query := "DELETE FROM users WHERE id = $1"
@@ -71,7 +152,7 @@ func (r *UserRepository) Delete(id string) error {
 }
 func (r *UserRepository) List(limit, offset int) ([]*models.User, error) {
 	query := `
-		SELECT id, email, name, password, role, image, created_at, updated_at
+		SELECT id, email, name, password, role, image, store_id, created_at, updated_at
 		FROM users ORDER BY created_at DESC LIMIT $1 OFFSET $2
 	`
 	rows, err := r.db.Query(query, limit, offset)
@@ -83,7 +164,7 @@ func (r *UserRepository) List(limit, offset int) ([]*models.User, error) {
 	for rows.Next() {
 		user := &models.User{}
 		err := rows.Scan(
-			&user.ID, &user.Email, &user.Name, &user.Password, &user.Role, &user.Image, &user.CreatedAt, &user.UpdatedAt,
+			&user.ID, &user.Email, &user.Name, &user.Password, &user.Role, &user.Image, &user.StoreID, &user.CreatedAt, &user.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
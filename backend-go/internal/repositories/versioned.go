@@ -0,0 +1,48 @@
+﻿package repositories
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+// updateVersioned sets the given columns on one row of table and bumps its
+// version column. With expected set, the row is only written while its
+// version still matches, so of two editors who loaded the same version only
+// the first to save succeeds. A row of a store other than storeID is not
+// found; an empty storeID matches every store. It returns the new version,
+// or "not found" / "version conflict". arg, if set, converts values such as
+// slices before they are bound.
+func updateVersioned(db *sql.DB, table, storeID, id string, expected *int64, updates map[string]interface{}, arg func(column string, value interface{}) interface{}) (int64, error) {
+	setParts := make([]string, 0, len(updates)+1)
+	args := make([]interface{}, 0, len(updates)+2)
+	for column, value := range updates {
+		if arg != nil {
+			value = arg(column, value)
+		}
+		args = append(args, value)
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	setParts = append(setParts, "version = version + 1")
+	args = append(args, id, storeParam(storeID))
+	where := fmt.Sprintf("id = $%d AND ($%d::uuid IS NULL OR store_id = $%d)", len(args)-1, len(args), len(args))
+	if expected != nil {
+		args = append(args, *expected)
+		where += fmt.Sprintf(" AND version = $%d", len(args))
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s RETURNING version", table, strings.Join(setParts, ", "), where)
+	var version int64
+	err := db.QueryRow(query, args...).Scan(&version)
+	if err != sql.ErrNoRows {
+		return version, err
+	}
+	if expected == nil {
+		return 0, fmt.Errorf("not found")
+	}
+	var exists bool
+	if err := db.QueryRow(fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1 AND ($2::uuid IS NULL OR store_id = $2))", table), id, storeParam(storeID)).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, fmt.Errorf("not found")
+	}
+	return 0, fmt.Errorf("version conflict")
+}
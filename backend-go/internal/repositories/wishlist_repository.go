@@ -1,9 +1,11 @@
 ﻿package repositories
 import (
 	"database/sql"
+	"fmt"
 	"time"
 	"ecommerce-backend/internal/models"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 type WishlistRepository struct {
 	db *sql.DB
@@ -11,17 +13,33 @@ type WishlistRepository struct {
 func NewWishlistRepository(db *sql.DB) *WishlistRepository {
 	return &WishlistRepository{db: db}
 }
-func (r *WishlistRepository) AddToWishlist(userID, productID string) error {
+// AddToWishlist inserts the item unless it is already present and reports
+// whether a row was added.
+func (r *WishlistRepository) AddToWishlist(userID, productID string) (bool, error) {
 	query := `
 		INSERT INTO wishlist_items (id, user_id, product_id, created_at)
-		VALUES ($1, $2, $3, $4)`
-	_, err := r.db.Exec(query, uuid.New().String(), userID, productID, time.Now())
-	return err
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, product_id) DO NOTHING`
+	result, err := r.db.Exec(query, uuid.New().String(), userID, productID, time.Now())
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+		return false, fmt.Errorf("product not found")
+	}
+	if err != nil {
+		return false, err
+	}
+	added, err := result.RowsAffected()
+	return added > 0, err
 }
-func (r *WishlistRepository) RemoveFromWishlist(userID, productID string) error {
+// RemoveFromWishlist reports whether a row was deleted; removing an item
+// that is not wishlisted is not an error.
+func (r *WishlistRepository) RemoveFromWishlist(userID, productID string) (bool, error) {
 	query := `DELETE FROM wishlist_items WHERE user_id = $1 AND product_id = $2`
-	_, err := r.db.Exec(query, userID, productID)
-	return err
+	result, err := r.db.Exec(query, userID, productID)
+	if err != nil {
+		return false, err
+	}
+	removed, err := result.RowsAffected()
+	return removed > 0, err
 }
 func (r *WishlistRepository) IsInWishlist(userID, productID string) (bool, error) {
 	query := `SELECT COUNT(*) FROM wishlist_items WHERE user_id = $1 AND product_id = $2`
@@ -33,7 +51,7 @@ func (r *WishlistRepository) GetUserWishlistItems(userID string, limit, offset i
 	query := `
 		SELECT wi.id, wi.user_id, wi.product_id, wi.created_at,
 		       p.id, p.name, p.description, p.price, p.images, p.category_id,
-		       p.stock, p.featured, p.created_at, p.updated_at
+		       p.stock, p.featured, p.product_type, p.created_at, p.updated_at
 		FROM wishlist_items wi
 		JOIN products p ON wi.product_id = p.id
 		WHERE wi.user_id = $1
@@ -52,7 +70,7 @@ func (r *WishlistRepository) GetUserWishlistItems(userID string, limit, offset i
 			&item.ID, &item.UserID, &item.ProductID, &item.CreatedAt,
 			&product.ID, &product.Name, &product.Description, &product.Price,
 			&product.Images, &product.CategoryID, &product.Stock,
-			&product.Featured, &product.CreatedAt, &product.UpdatedAt)
+			&product.Featured, &product.ProductType, &product.CreatedAt, &product.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -40,7 +40,7 @@ func (s *CategorySeeder) Seed(db *sql.DB) error {
 		_, err := db.Exec(`
 			INSERT INTO categories (name, slug, description, image, created_at, updated_at)
 			VALUES ($1, $2, $3, $4, NOW(), NOW())
-			ON CONFLICT (slug) DO NOTHING
+			ON CONFLICT (store_id, slug) DO NOTHING
 		`, cat.name, slug, cat.description, cat.image)
 
 		if err != nil {
@@ -0,0 +1,84 @@
+package seeds
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// faker produces plausible catalog, customer and address data. All values
+// come from rng so a run with the same seed generates the same rows.
+type faker struct {
+	rng *rand.Rand
+}
+
+func newFaker(rng *rand.Rand) *faker {
+	return &faker{rng: rng}
+}
+
+var (
+	fakeFirstNames = []string{"Alex", "Jordan", "Taylor", "Casey", "Morgan", "Riley", "Avery", "Quinn", "Sage", "River", "Emma", "Liam", "Olivia", "Noah", "Ava", "Lucas", "Mia", "Ethan", "Sofia", "Mason", "Isabella", "Logan", "Amelia", "Elijah", "Harper", "James", "Evelyn", "Benjamin", "Abigail", "Henry"}
+	fakeLastNames  = []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez", "Hernandez", "Lopez", "Gonzalez", "Wilson", "Anderson", "Thomas", "Taylor", "Moore", "Jackson", "Martin", "Lee", "Perez", "Thompson", "White", "Harris", "Sanchez", "Clark", "Ramirez", "Lewis", "Robinson"}
+
+	fakeAdjectives = []string{"Classic", "Compact", "Deluxe", "Ergonomic", "Essential", "Lightweight", "Modern", "Portable", "Premium", "Professional", "Rustic", "Sleek", "Smart", "Sturdy", "Ultra", "Vintage", "Wireless", "Eco"}
+	fakeMaterials  = []string{"Bamboo", "Carbon", "Ceramic", "Copper", "Cotton", "Glass", "Granite", "Leather", "Linen", "Oak", "Rubber", "Silicone", "Steel", "Titanium", "Walnut", "Wool"}
+	fakeNouns      = []string{"Backpack", "Blender", "Bottle", "Camera", "Chair", "Clock", "Desk Lamp", "Headphones", "Jacket", "Kettle", "Keyboard", "Knife Set", "Mug", "Notebook", "Pan", "Speaker", "Sneakers", "Sunglasses", "Tent", "Watch", "Wallet", "Yoga Mat"}
+	fakeBenefits   = []string{"built to last", "designed for everyday use", "easy to clean", "great for travel", "made from sustainable materials", "loved by professionals", "perfect as a gift", "with a two-year warranty"}
+
+	fakeStreets = []string{"Main St", "Oak Ave", "Pine Rd", "Elm St", "Maple Dr", "Cedar Ln", "Birch Way", "Spruce St", "Willow Ave", "Poplar Rd", "Lakeview Blvd", "Hillcrest Ct"}
+	fakeCities  = []struct{ city, state, zip string }{
+		{"New York", "NY", "10001"}, {"Los Angeles", "CA", "90012"}, {"Chicago", "IL", "60601"},
+		{"Houston", "TX", "77001"}, {"Phoenix", "AZ", "85001"}, {"Philadelphia", "PA", "19101"},
+		{"San Antonio", "TX", "78201"}, {"San Diego", "CA", "92101"}, {"Dallas", "TX", "75201"},
+		{"Seattle", "WA", "98101"}, {"Denver", "CO", "80202"}, {"Boston", "MA", "02108"},
+	}
+)
+
+func (f *faker) pick(values []string) string {
+	return values[f.rng.Intn(len(values))]
+}
+
+func (f *faker) firstName() string {
+	return f.pick(fakeFirstNames)
+}
+
+func (f *faker) lastName() string {
+	return f.pick(fakeLastNames)
+}
+
+func (f *faker) productName() string {
+	return fmt.Sprintf("%s %s %s", f.pick(fakeAdjectives), f.pick(fakeMaterials), f.pick(fakeNouns))
+}
+
+func (f *faker) productDescription(name string) string {
+	return fmt.Sprintf("%s, %s and %s.", name, f.pick(fakeBenefits), f.pick(fakeBenefits))
+}
+
+// price returns a price between min and max that ends in .99 or .49, like
+// most shop prices do.
+func (f *faker) price(min, max float64) float64 {
+	whole := min + f.rng.Float64()*(max-min)
+	if f.rng.Intn(4) == 0 {
+		return float64(int(whole)) + 0.49
+	}
+	return float64(int(whole)) + 0.99
+}
+
+func (f *faker) address() string {
+	location := fakeCities[f.rng.Intn(len(fakeCities))]
+	return fmt.Sprintf(`{"street": "%d %s", "city": "%s", "state": "%s", "zip": "%s", "country": "USA"}`,
+		1+f.rng.Intn(9999), f.pick(fakeStreets), location.city, location.state, location.zip)
+}
+
+// pastTime returns a moment within the last days days, relative to now.
+func (f *faker) pastTime(now time.Time, days int) time.Time {
+	return now.Add(-time.Duration(f.rng.Int63n(int64(days) * int64(24*time.Hour))))
+}
+
+func slugify(name string) string {
+	slug := strings.ToLower(name)
+	slug = strings.ReplaceAll(slug, " ", "-")
+	slug = strings.ReplaceAll(slug, "&", "and")
+	return slug
+}
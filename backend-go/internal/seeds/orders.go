@@ -5,6 +5,11 @@ import (
 	"fmt"
 	"math/rand"
 	"time"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+
+	"github.com/google/uuid"
 )
 
 type OrderSeeder struct{}
@@ -43,7 +48,7 @@ func (s *OrderSeeder) Seed(db *sql.DB) error {
 		numItems := 1 + rand.Intn(5)
 
 		var orderID string
-		total := 0.0
+		var total models.Money
 
 		status := orderStatuses[rand.Intn(len(orderStatuses))]
 
@@ -53,7 +58,7 @@ func (s *OrderSeeder) Seed(db *sql.DB) error {
 			INSERT INTO orders (user_id, total, subtotal, status, shipping_address, billing_address, created_at, updated_at)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 			RETURNING id
-		`, user.ID, 0.0, 0.0, status, s.generateAddress(), s.generateAddress(), createdAt, createdAt).Scan(&orderID)
+		`, user.ID, 0, 0, status, s.generateAddress(), s.generateAddress(), createdAt, createdAt).Scan(&orderID)
 
 		if err != nil {
 			return fmt.Errorf("failed to create order: %w", err)
@@ -63,7 +68,7 @@ func (s *OrderSeeder) Seed(db *sql.DB) error {
 		for j := 0; j < numItems; j++ {
 			var product struct {
 				ID    string
-				Price float64
+				Price models.Money
 			}
 
 			attempts := 0
@@ -78,12 +83,12 @@ func (s *OrderSeeder) Seed(db *sql.DB) error {
 			usedProducts[product.ID] = true
 
 			quantity := 1 + rand.Intn(3)
-			itemTotal := product.Price * float64(quantity)
+			itemTotal := product.Price.Mul(quantity)
 			total += itemTotal
 
 			_, err = db.Exec(`
-				INSERT INTO order_items (order_id, product_id, quantity, price, created_at)
-				VALUES ($1, $2, $3, $4, $5)
+				INSERT INTO order_items (order_id, product_id, product_name, product_image, quantity, price, created_at)
+				SELECT $1, id, name, images[1], $3, $4, $5 FROM products WHERE id = $2
 			`, orderID, product.ID, quantity, product.Price, createdAt)
 
 			if err != nil {
@@ -106,7 +111,7 @@ func (s *OrderSeeder) Seed(db *sql.DB) error {
 func (s *OrderSeeder) getUsers(db *sql.DB) ([]struct {
 	ID string
 }, error) {
-	rows, err := db.Query("SELECT id FROM users WHERE role = 'user'")
+	rows, err := db.Query("SELECT id FROM users WHERE role = 'user' ORDER BY id")
 	if err != nil {
 		return nil, err
 	}
@@ -131,7 +136,7 @@ func (s *OrderSeeder) getUsers(db *sql.DB) ([]struct {
 
 func (s *OrderSeeder) getProducts(db *sql.DB) ([]struct {
 	ID    string
-	Price float64
+	Price models.Money
 }, error) {
 	rows, err := db.Query("SELECT id, price FROM products WHERE in_stock = true")
 	if err != nil {
@@ -141,13 +146,13 @@ func (s *OrderSeeder) getProducts(db *sql.DB) ([]struct {
 
 	var products []struct {
 		ID    string
-		Price float64
+		Price models.Money
 	}
 
 	for rows.Next() {
 		var product struct {
 			ID    string
-			Price float64
+			Price models.Money
 		}
 		if err := rows.Scan(&product.ID, &product.Price); err != nil {
 			return nil, err
@@ -174,3 +179,103 @@ func (s *OrderSeeder) generateAddress() string {
 
 	return addresses[rand.Intn(len(addresses))]
 }
+
+type seedProduct struct {
+	ID    string
+	Name  string
+	Image sql.NullString
+	Price models.Money
+}
+
+// SeedCount generates orders of one to five items for the existing customers
+// and in-stock products, with item snapshots and totals filled in. Orders are
+// generated and written in chunks so memory stays flat for large counts.
+func (s *OrderSeeder) SeedCount(db *sql.DB, generation Generation) error {
+	users, err := s.getUsers(db)
+	if err != nil {
+		return fmt.Errorf("failed to get users: %w", err)
+	}
+	products, err := s.getSeedProducts(db)
+	if err != nil {
+		return fmt.Errorf("failed to get products: %w", err)
+	}
+	if len(users) == 0 || len(products) == 0 {
+		return fmt.Errorf("no users or products found for seeding orders")
+	}
+
+	// Weighted towards completed orders, like a shop that has been running
+	// for a while.
+	statuses := []models.OrderStatus{"delivered", "delivered", "delivered", "shipped", "processing", "pending", "cancelled"}
+	rng := generation.Rand
+	fake := newFaker(rng)
+	now := time.Now()
+	repo := repositories.NewOrderRepository(db)
+
+	const chunk = 5000
+	for start := 0; start < generation.Count; start += chunk {
+		size := min(chunk, generation.Count-start)
+		orders := make([]*models.OrderWithItems, 0, size)
+		for i := 0; i < size; i++ {
+			orderID, err := uuid.NewRandomFromReader(rng)
+			if err != nil {
+				return err
+			}
+			createdAt := fake.pastTime(now, 365)
+			address := fake.address()
+			order := &models.OrderWithItems{Order: models.Order{
+				ID:              orderID.String(),
+				UserID:          users[rng.Intn(len(users))].ID,
+				Status:          statuses[rng.Intn(len(statuses))],
+				ShippingAddress: address,
+				BillingAddress:  address,
+				CreatedAt:       createdAt,
+				UpdatedAt:       createdAt,
+			}}
+			picked := make(map[int]bool, 5)
+			for n := 1 + rng.Intn(min(5, len(products))); len(picked) < n; {
+				index := rng.Intn(len(products))
+				if picked[index] {
+					continue
+				}
+				picked[index] = true
+				product := products[index]
+				item := models.OrderItemWithProduct{OrderItem: models.OrderItem{
+					ProductID:   product.ID,
+					ProductName: product.Name,
+					Quantity:    models.QuantityFromInt(1 + rng.Intn(3)),
+					Price:       product.Price,
+				}}
+				if product.Image.Valid {
+					item.ProductImage = &product.Image.String
+				}
+				order.Subtotal += product.Price.MulQuantity(item.Quantity)
+				order.OrderItems = append(order.OrderItems, item)
+			}
+			order.Total = order.Subtotal
+			orders = append(orders, order)
+		}
+
+		if _, err := repo.CreateBatch(orders, generation.BatchSize); err != nil {
+			return fmt.Errorf("failed to insert orders: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *OrderSeeder) getSeedProducts(db *sql.DB) ([]seedProduct, error) {
+	rows, err := db.Query("SELECT id, name, images[1], price FROM products WHERE in_stock = true ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []seedProduct
+	for rows.Next() {
+		var product seedProduct
+		if err := rows.Scan(&product.ID, &product.Name, &product.Image, &product.Price); err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	return products, rows.Err()
+}
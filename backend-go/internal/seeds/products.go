@@ -7,6 +7,9 @@ import (
 	"strings"
 	"time"
 
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+
 	"github.com/lib/pq"
 )
 
@@ -102,15 +105,15 @@ func (s *ProductSeeder) Seed(db *sql.DB) error {
 		_, err := db.Exec(`
 			INSERT INTO products (name, slug, description, price, category_id, images, stock, featured, in_stock, created_at, updated_at)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
-			ON CONFLICT (slug) DO NOTHING
-		`, product.name, slug, product.description, product.price, categoryID, pq.Array(product.images), product.stock, product.featured, product.stock > 0)
+			ON CONFLICT (store_id, slug) DO NOTHING
+		`, product.name, slug, product.description, models.MoneyFromFloat(product.price), categoryID, pq.Array(product.images), product.stock, product.featured, product.stock > 0)
 
 		if err != nil {
 			return fmt.Errorf("failed to insert product %s: %w", product.name, err)
 		}
 	}
 
-	return nil
+	return recordOpeningStock(db)
 }
 
 func (s *ProductSeeder) getCategoryMap(db *sql.DB) (map[string]string, error) {
@@ -187,3 +190,73 @@ func (s *ProductSeeder) generateRandomProduct(categoryMap map[string]string) str
 		featured:     featured,
 	}
 }
+
+// SeedCount generates products spread over the existing categories. Slugs
+// end in the product's index, so rerunning with the same seed and a larger
+// count only adds the missing products.
+func (s *ProductSeeder) SeedCount(db *sql.DB, generation Generation) error {
+	categoryIDs, err := s.getCategoryIDs(db)
+	if err != nil {
+		return fmt.Errorf("failed to get categories: %w", err)
+	}
+	if len(categoryIDs) == 0 {
+		return fmt.Errorf("no categories found, seed categories first")
+	}
+
+	rng := generation.Rand
+	fake := newFaker(rng)
+	now := time.Now()
+	products := make([]*models.Product, 0, generation.Count)
+	for i := 1; i <= generation.Count; i++ {
+		name := fake.productName()
+		description := fake.productDescription(name)
+		stock := rng.Intn(201)
+		createdAt := fake.pastTime(now, 365)
+		products = append(products, &models.Product{
+			Name:        name,
+			Slug:        fmt.Sprintf("%s-%d", slugify(name), i),
+			Description: &description,
+			Price:       models.MoneyFromFloat(fake.price(5, 1500)),
+			Images:      []string{"default_product.jpg"},
+			InStock:     stock > 0,
+			Stock:       stock,
+			Featured:    rng.Float64() < 0.05,
+			ProductType: models.ProductTypePhysical,
+			CategoryID:  categoryIDs[rng.Intn(len(categoryIDs))],
+			CreatedAt:   createdAt,
+			UpdatedAt:   createdAt,
+		})
+	}
+
+	if _, err := repositories.NewProductRepository(db).CreateBatch(products, generation.BatchSize); err != nil {
+		return fmt.Errorf("failed to insert products: %w", err)
+	}
+	return recordOpeningStock(db)
+}
+
+// recordOpeningStock opens the inventory ledger for seeded products, which
+// are inserted with their stock directly.
+func recordOpeningStock(db *sql.DB) error {
+	if _, err := repositories.NewInventoryRepository(db).RecordOpeningBalances("seed"); err != nil {
+		return fmt.Errorf("failed to record opening stock: %w", err)
+	}
+	return nil
+}
+
+func (s *ProductSeeder) getCategoryIDs(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT id FROM categories ORDER BY slug, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
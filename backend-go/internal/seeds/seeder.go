@@ -3,6 +3,7 @@ package seeds
 import (
 	"database/sql"
 	"fmt"
+	"math/rand"
 	"os"
 	"time"
 
@@ -17,11 +18,37 @@ type Seeder interface {
 	Priority() int
 }
 
+// ScalableSeeder is implemented by seeders that can generate an arbitrary
+// number of records, for load testing with a large data set.
+type ScalableSeeder interface {
+	Seeder
+	SeedCount(db *sql.DB, generation Generation) error
+}
+
+// Generation tells a ScalableSeeder how much to generate and how.
+type Generation struct {
+	Count     int
+	BatchSize int
+	Rand      *rand.Rand
+}
+
+// Options size a seeding run. A positive count for a seeder name replaces
+// its sample data with that many generated records. RandSeed makes the
+// generated data reproducible; zero picks a seed from the clock. BatchSize is
+// the number of rows per insert, defaulting to repositories.DefaultBatchSize.
+type Options struct {
+	Counts    map[string]int
+	RandSeed  int64
+	BatchSize int
+}
+
 type SeedManager struct {
 	db      *sql.DB
 	seeders []Seeder
 	logger  *utils.Logger
 	config  *config.AppConfig
+	ownsDB  bool
+	options Options
 }
 
 func NewSeedManager() (*SeedManager, error) {
@@ -45,6 +72,7 @@ func NewSeedManager() (*SeedManager, error) {
 		logger:  logger,
 		config:  cfg,
 		seeders: make([]Seeder, 0),
+		ownsDB:  true,
 	}
 
 	sm.registerSeeders()
@@ -52,6 +80,18 @@ func NewSeedManager() (*SeedManager, error) {
 	return sm, nil
 }
 
+// NewSeedManagerWithDB seeds through an existing connection, for callers such
+// as the API server that already own the database. Close leaves db open.
+func NewSeedManagerWithDB(db *sql.DB) *SeedManager {
+	sm := &SeedManager{
+		db:      db,
+		logger:  utils.NewLogger(utils.INFO, os.Stdout),
+		seeders: make([]Seeder, 0),
+	}
+	sm.registerSeeders()
+	return sm
+}
+
 func (sm *SeedManager) registerSeeders() {
 	sm.seeders = append(sm.seeders, &CategorySeeder{})
 	sm.seeders = append(sm.seeders, &ProductSeeder{})
@@ -59,11 +99,24 @@ func (sm *SeedManager) registerSeeders() {
 	sm.seeders = append(sm.seeders, &OrderSeeder{})
 	sm.seeders = append(sm.seeders, &ReviewSeeder{})
 }
+func (sm *SeedManager) SetOptions(options Options) {
+	sm.options = options
+}
+
 func (sm *SeedManager) Run() error {
 	sm.logger.Info("Starting database seeding...")
 
 	sm.sortSeedersByPriority()
 
+	randSeed := sm.options.RandSeed
+	if randSeed == 0 {
+		randSeed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(randSeed))
+	if len(sm.options.Counts) > 0 {
+		sm.logger.Info("Generating data", "counts", sm.options.Counts, "seed", randSeed)
+	}
+
 	startTime := time.Now()
 	successCount := 0
 	errorCount := 0
@@ -72,7 +125,13 @@ func (sm *SeedManager) Run() error {
 		sm.logger.Info("Seeding", "type", seeder.Name())
 
 		seederStart := time.Now()
-		if err := seeder.Seed(sm.db); err != nil {
+		var err error
+		if scalable, ok := seeder.(ScalableSeeder); ok && sm.options.Counts[seeder.Name()] > 0 {
+			err = scalable.SeedCount(sm.db, Generation{Count: sm.options.Counts[seeder.Name()], BatchSize: sm.options.BatchSize, Rand: rng})
+		} else {
+			err = seeder.Seed(sm.db)
+		}
+		if err != nil {
 			sm.logger.Error("Failed to seed", "type", seeder.Name(), "error", err)
 			errorCount++
 			continue
@@ -140,5 +199,8 @@ func (sm *SeedManager) sortSeedersByPriority() {
 }
 
 func (sm *SeedManager) Close() error {
+	if !sm.ownsDB {
+		return nil
+	}
 	return database.CloseDatabase()
 }
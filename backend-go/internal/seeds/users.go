@@ -4,8 +4,11 @@ import (
 	"database/sql"
 	"fmt"
 	"math/rand"
+	"strings"
 	"time"
 
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
 	"ecommerce-backend/internal/utils"
 )
 
@@ -115,3 +118,35 @@ func (s *UserSeeder) generateRandomUser() struct {
 		image:    fmt.Sprintf("%s_%s.jpg", firstName, lastName),
 	}
 }
+
+// SeedCount generates customers with unique emails. They all share one
+// password hash, because hashing is deliberately slow and would dominate a
+// large run; the password is "password123".
+func (s *UserSeeder) SeedCount(db *sql.DB, generation Generation) error {
+	hashedPassword, err := utils.HashPassword("password123")
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	fake := newFaker(generation.Rand)
+	now := time.Now()
+	users := make([]*models.User, 0, generation.Count)
+	for i := 1; i <= generation.Count; i++ {
+		firstName, lastName := fake.firstName(), fake.lastName()
+		name := firstName + " " + lastName
+		createdAt := fake.pastTime(now, 730)
+		users = append(users, &models.User{
+			Email:     strings.ToLower(fmt.Sprintf("%s.%s.%d@loadtest.example.com", firstName, lastName, i)),
+			Name:      &name,
+			Password:  hashedPassword,
+			Role:      "user",
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		})
+	}
+
+	if _, err := repositories.NewUserRepository(db).CreateBatch(users, generation.BatchSize); err != nil {
+		return fmt.Errorf("failed to insert users: %w", err)
+	}
+	return nil
+}
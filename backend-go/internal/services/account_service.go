@@ -0,0 +1,268 @@
+﻿package services
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
+)
+var (
+	accountErasedData = []string{
+		"email address, name and profile image",
+		"password credentials",
+		"cart and wishlist contents",
+		"shipping and billing addresses and notes on orders",
+		"free-text reasons on return requests",
+		"uploaded files and review photos",
+	}
+	accountRetainedData = []string{
+		"order records (items, amounts, tax, dates) for accounting and tax obligations",
+		"payment and refund records required for financial reconciliation",
+		"reviews and their ratings, no longer attributable to you",
+		"product questions and answers, no longer attributable to you",
+	}
+)
+const (
+	dataExportInterval = 24 * time.Hour
+	dataExportTTL      = 7 * 24 * time.Hour
+	dataExportMaxRows  = 10000
+	// tokenRevocationTTL bounds how long another instance without a shared
+	// cache backend can go on accepting a revoked token.
+	tokenRevocationTTL = time.Minute
+)
+// DataExportSweepInterval is how often SweepExpiredExports should run.
+const DataExportSweepInterval = time.Hour
+const dataExportSweepBatch = 500
+type AccountService struct {
+	userRepo      *repositories.UserRepository
+	orderRepo     *repositories.OrderRepository
+	paymentRepo   *repositories.PaymentRepository
+	reviewRepo    *repositories.ReviewRepository
+	wishlistRepo  *repositories.WishlistRepository
+	exportRepo    *repositories.DataExportRepository
+	uploadService *UploadService
+	hasher        *utils.PasswordHasher
+	jobs          *JobQueue
+	emailService  *EmailService
+	exportPath    string
+	publicURL     string
+}
+func NewAccountService(userRepo *repositories.UserRepository, orderRepo *repositories.OrderRepository, paymentRepo *repositories.PaymentRepository, reviewRepo *repositories.ReviewRepository, wishlistRepo *repositories.WishlistRepository, exportRepo *repositories.DataExportRepository, uploadService *UploadService, hasher *utils.PasswordHasher, jobs *JobQueue, emailService *EmailService, exportPath, publicURL string) *AccountService {
+	return &AccountService{
+		userRepo:      userRepo,
+		orderRepo:     orderRepo,
+		paymentRepo:   paymentRepo,
+		reviewRepo:    reviewRepo,
+		wishlistRepo:  wishlistRepo,
+		exportRepo:    exportRepo,
+		uploadService: uploadService,
+		hasher:        hasher,
+		jobs:          jobs,
+		emailService:  emailService,
+		exportPath:    exportPath,
+		publicURL:     strings.TrimRight(publicURL, "/"),
+	}
+}
+func (s *AccountService) RequestErasure(userID, password, requestID string) (*models.AccountDeleteResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err := s.hasher.Verify(user.Password, password); err != nil {
+		return nil, fmt.Errorf("invalid credentials")
+	}
+	email := user.Email
+	s.jobs.EnqueueFor(requestID, "account_erase:"+userID, func() error {
+		// The export files go first: Anonymize drops the rows that point
+		// to them.
+		exports, err := s.exportRepo.ListByUser(userID)
+		if err != nil {
+			return fmt.Errorf("failed to list data exports: %w", err)
+		}
+		for _, export := range exports {
+			if err := removeExportFile(export); err != nil {
+				return fmt.Errorf("failed to delete data export: %w", err)
+			}
+		}
+		if err := s.userRepo.Anonymize(userID); err != nil {
+			return fmt.Errorf("failed to anonymize user: %w", err)
+		}
+		utils.CacheInvalidate("accounts", "tokens_revoked:"+userID)
+		if err := s.uploadService.DeleteUserUploads(userID); err != nil {
+			return fmt.Errorf("failed to delete uploads: %w", err)
+		}
+		body := "Your account and personal data have been erased.\n\n" +
+			"Erased:\n- " + strings.Join(accountErasedData, "\n- ") + "\n\n" +
+			"Retained for legal and financial reasons:\n- " + strings.Join(accountRetainedData, "\n- ") + "\n"
+		if err := s.emailService.Send(email, "Your account has been deleted", body); err != nil {
+			utils.Warn("failed to send account deletion confirmation", "user_id", userID, "error", err.Error())
+		}
+		return nil
+	})
+	return &models.AccountDeleteResponse{
+		Message:  "Account deletion scheduled; a confirmation will be sent by email",
+		Erased:   accountErasedData,
+		Retained: accountRetainedData,
+	}, nil
+}
+// TokenRevoked reports whether a token issued to userID at issuedAt was
+// revoked by a later account erasure. Tokens of unknown users are revoked.
+func (s *AccountService) TokenRevoked(userID string, issuedAt time.Time) (bool, error) {
+	result, err := utils.CacheGetOrSet("accounts", "tokens_revoked:"+userID, tokenRevocationTTL, func() (interface{}, error) {
+		return s.userRepo.GetTokensRevokedAt(userID)
+	})
+	if err != nil {
+		if err.Error() == "user not found" {
+			return true, nil
+		}
+		return false, err
+	}
+	revokedAt := result.(*time.Time)
+	return revokedAt != nil && !issuedAt.After(*revokedAt), nil
+}
+func (s *AccountService) RequestExport(userID, requestID string) (*models.DataExport, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	export := &models.DataExport{
+		ID:        generateID(),
+		UserID:    userID,
+		Token:     utils.GenerateRandomHex(64),
+		Status:    models.DataExportStatusPending,
+		CreatedAt: time.Now(),
+	}
+	created, err := s.exportRepo.CreateUnlessRecent(export, export.CreatedAt.Add(-dataExportInterval))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export: %w", err)
+	}
+	if !created {
+		return nil, fmt.Errorf("export already requested in the last 24 hours")
+	}
+	email := user.Email
+	s.jobs.EnqueueFor(requestID, "account_export:"+userID, func() error {
+		if err := s.buildExport(export); err != nil {
+			export.Status = models.DataExportStatusFailed
+			if updateErr := s.exportRepo.Update(export); updateErr != nil {
+				utils.Error("failed to mark data export failed", "export_id", export.ID, "error", updateErr.Error())
+			}
+			return err
+		}
+		link := fmt.Sprintf("%s/api/auth/account/export/%s", s.publicURL, export.Token)
+		body := "Your personal data export is ready. Download it within 7 days:\n\n" + link + "\n"
+		if err := s.emailService.Send(email, "Your data export is ready", body); err != nil {
+			utils.Warn("failed to send data export link", "user_id", userID, "error", err.Error())
+		}
+		return nil
+	})
+	return export, nil
+}
+// SweepExpiredExports is the background job that deletes the files of
+// exports whose download link has expired. The rows are kept, marked
+// expired, so late downloads are told the link expired.
+func (s *AccountService) SweepExpiredExports() error {
+	exports, err := s.exportRepo.ListExpired(time.Now(), dataExportSweepBatch)
+	if err != nil {
+		return fmt.Errorf("failed to list expired data exports: %w", err)
+	}
+	for _, export := range exports {
+		if err := removeExportFile(export); err != nil {
+			utils.Warn("failed to delete expired data export", "export_id", export.ID, "error", err.Error())
+			continue
+		}
+		export.Status = models.DataExportStatusExpired
+		export.FilePath = nil
+		if err := s.exportRepo.Update(export); err != nil {
+			return fmt.Errorf("failed to mark data export expired: %w", err)
+		}
+	}
+	if len(exports) > 0 {
+		utils.Info("deleted expired data exports", "count", len(exports))
+	}
+	return nil
+}
+// removeExportFile deletes the file of export, if it has one left.
+func removeExportFile(export *models.DataExport) error {
+	if export.FilePath == nil {
+		return nil
+	}
+	if err := os.Remove(*export.FilePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+func (s *AccountService) GetExportFile(token string) (string, error) {
+	export, err := s.exportRepo.GetByToken(token)
+	if err == nil && export.Status == models.DataExportStatusExpired {
+		return "", fmt.Errorf("export expired")
+	}
+	if err != nil || export.Status != models.DataExportStatusReady || export.FilePath == nil {
+		return "", fmt.Errorf("export not found")
+	}
+	if export.ExpiresAt != nil && time.Now().After(*export.ExpiresAt) {
+		return "", fmt.Errorf("export expired")
+	}
+	return *export.FilePath, nil
+}
+func (s *AccountService) buildExport(export *models.DataExport) error {
+	user, err := s.userRepo.GetByID(export.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+	orders, err := s.orderRepo.GetUserOrders(export.UserID, "", dataExportMaxRows, 0)
+	if err != nil {
+		return fmt.Errorf("failed to load orders: %w", err)
+	}
+	payments, err := s.paymentRepo.GetUserPayments(export.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load payments: %w", err)
+	}
+	reviews, _, err := s.reviewRepo.GetByUserID(export.UserID, models.ReviewQuery{}, dataExportMaxRows, 0)
+	if err != nil {
+		return fmt.Errorf("failed to load reviews: %w", err)
+	}
+	wishlist, err := s.wishlistRepo.GetUserWishlistItems(export.UserID, dataExportMaxRows, 0)
+	if err != nil {
+		return fmt.Errorf("failed to load wishlist: %w", err)
+	}
+	seen := make(map[string]bool)
+	addresses := []string{}
+	for _, order := range orders {
+		for _, address := range []string{order.ShippingAddress, order.BillingAddress} {
+			if address != "" && !seen[address] {
+				seen[address] = true
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	data, err := json.MarshalIndent(models.AccountExport{
+		GeneratedAt: time.Now(),
+		Profile:     user.ToResponse(),
+		Addresses:   addresses,
+		Orders:      orders,
+		Payments:    payments,
+		Reviews:     reviews,
+		Wishlist:    wishlist,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode export: %w", err)
+	}
+	if err := os.MkdirAll(s.exportPath, 0700); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+	path := filepath.Join(s.exportPath, export.Token+".json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	now := time.Now()
+	expiresAt := now.Add(dataExportTTL)
+	export.Status = models.DataExportStatusReady
+	export.FilePath = &path
+	export.CompletedAt = &now
+	export.ExpiresAt = &expiresAt
+	return s.exportRepo.Update(export)
+}
@@ -0,0 +1,37 @@
+﻿package services
+import (
+	"encoding/json"
+	"time"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"github.com/google/uuid"
+)
+type AuditService struct {
+	auditRepo *repositories.AuditRepository
+	jobs      *JobQueue
+}
+func NewAuditService(auditRepo *repositories.AuditRepository, jobs *JobQueue) *AuditService {
+	return &AuditService{auditRepo: auditRepo, jobs: jobs}
+}
+// Record writes the entry on the job queue so auditing never delays the
+// action being audited. before and after may be nil.
+func (s *AuditService) Record(entry models.AuditEntry, before, after interface{}) {
+	if s == nil {
+		return
+	}
+	entry.ID = uuid.New().String()
+	entry.CreatedAt = time.Now()
+	if before != nil {
+		entry.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		entry.After, _ = json.Marshal(after)
+	}
+	s.jobs.Enqueue("audit:"+entry.Action, func() error {
+		return s.auditRepo.Create(&entry)
+	})
+}
+func (s *AuditService) List(query models.AuditQuery) ([]models.AuditEntry, int, error) {
+	offset := (query.Page - 1) * query.Limit
+	return s.auditRepo.List(query, query.Limit, offset)
+}
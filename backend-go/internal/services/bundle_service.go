@@ -0,0 +1,84 @@
+﻿package services
+import (
+	"fmt"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
+)
+// BundleService manages products sold as a bundle of other products. The
+// bundle keeps its own price; its stock is the number of whole bundles its
+// components' stock can make up and moves with them.
+type BundleService struct {
+	bundleRepo  *repositories.BundleRepository
+	productRepo *repositories.ProductRepository
+}
+func NewBundleService(bundleRepo *repositories.BundleRepository, productRepo *repositories.ProductRepository) *BundleService {
+	return &BundleService{bundleRepo: bundleRepo, productRepo: productRepo}
+}
+// GetBundle returns the components of a bundle in storeID, or of any store
+// when storeID is empty. It fails with "bundle not found" for plain products.
+func (s *BundleService) GetBundle(productID, storeID string) (*models.ProductBundle, error) {
+	if _, err := s.bundleProduct(productID, storeID); err != nil {
+		return nil, err
+	}
+	components, err := s.bundleRepo.GetComponents(productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle: %w", err)
+	}
+	if len(components) == 0 {
+		return nil, fmt.Errorf("bundle not found")
+	}
+	return &models.ProductBundle{BundleID: productID, Components: components, Available: models.BundleAvailability(components)}, nil
+}
+// SetBundle makes a product a bundle of the requested components, replacing
+// any it had. Bundles are sold in whole units.
+func (s *BundleService) SetBundle(productID, storeID string, req models.BundleSetRequest) (*models.ProductBundle, error) {
+	product, err := s.bundleProduct(productID, storeID)
+	if err != nil {
+		return nil, err
+	}
+	if models.IsMeasuredUnit(product.Unit) {
+		return nil, fmt.Errorf("a bundle must be sold per item")
+	}
+	seen := make(map[string]bool, len(req.Items))
+	for _, item := range req.Items {
+		if item.ProductID == productID {
+			return nil, fmt.Errorf("a bundle cannot contain itself")
+		}
+		if seen[item.ProductID] {
+			return nil, fmt.Errorf("bundle component listed more than once")
+		}
+		seen[item.ProductID] = true
+	}
+	if err := s.bundleRepo.Set(productID, req.Items); err != nil {
+		switch err.Error() {
+		case "product not found", "bundle component not found", "bundles cannot contain bundles":
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to save bundle: %w", err)
+	}
+	utils.CacheInvalidatePrefix("products:")
+	return s.GetBundle(productID, storeID)
+}
+// RemoveBundle turns a bundle back into a plain product that keeps its
+// current stock.
+func (s *BundleService) RemoveBundle(productID, storeID string) error {
+	if _, err := s.bundleProduct(productID, storeID); err != nil {
+		return err
+	}
+	if err := s.bundleRepo.Delete(productID); err != nil {
+		if err.Error() == "bundle not found" {
+			return err
+		}
+		return fmt.Errorf("failed to remove bundle: %w", err)
+	}
+	utils.CacheInvalidatePrefix("products:")
+	return nil
+}
+func (s *BundleService) bundleProduct(productID, storeID string) (*models.Product, error) {
+	product, err := s.productRepo.GetByID(storeID, productID)
+	if err != nil {
+		return nil, fmt.Errorf("product not found")
+	}
+	return product, nil
+}
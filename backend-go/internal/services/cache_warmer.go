@@ -0,0 +1,79 @@
+﻿package services
+import (
+	"context"
+	"ecommerce-backend/internal/models"
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+// CacheWarmer fills the hot read caches before a fresh instance takes
+// traffic, so the first requests after a deploy don't all reach the
+// database. The keys match the ones the storefront's default requests use.
+type CacheWarmer struct {
+	products   *ProductService
+	categories *CategoryService
+	stores     *StoreService
+	locale     string
+	done       atomic.Bool
+}
+func NewCacheWarmer(products *ProductService, categories *CategoryService, stores *StoreService, locale string) *CacheWarmer {
+	return &CacheWarmer{
+		products:   products,
+		categories: categories,
+		stores:     stores,
+		locale:     locale,
+	}
+}
+// Done reports whether Warm has finished, successfully or not.
+func (w *CacheWarmer) Done() bool {
+	return w.done.Load()
+}
+// Warm loads the featured products, first category page and first product
+// listing page of every active store. It gives up when ctx ends; either way
+// Done reports true afterwards.
+func (w *CacheWarmer) Warm(ctx context.Context) error {
+	defer w.done.Store(true)
+	result := make(chan error, 1)
+	go func() {
+		result <- w.warmStores(ctx)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+func (w *CacheWarmer) warmStores(ctx context.Context) error {
+	stores, err := w.stores.List()
+	if err != nil {
+		return fmt.Errorf("failed to list stores: %w", err)
+	}
+	warmed := 0
+	for _, store := range stores {
+		if !store.Active {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := w.warmStore(store.ID); err != nil {
+			return fmt.Errorf("store %s: %w", store.Slug, err)
+		}
+		warmed++
+	}
+	log.Printf("Cache warmup finished for %d stores", warmed)
+	return nil
+}
+func (w *CacheWarmer) warmStore(storeID string) error {
+	if _, err := w.products.GetFeaturedProducts(storeID, 10, w.locale); err != nil {
+		return err
+	}
+	if _, err := w.products.GetProducts(models.ProductQuery{StoreID: storeID, Locale: w.locale}); err != nil {
+		return err
+	}
+	if _, _, err := w.categories.GetCategories(storeID, 1, 20, false, w.locale); err != nil {
+		return err
+	}
+	return nil
+}
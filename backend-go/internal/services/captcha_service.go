@@ -0,0 +1,59 @@
+﻿package services
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+var captchaVerifyURLs = map[string]string{
+	"hcaptcha":  "https://api.hcaptcha.com/siteverify",
+	"turnstile": "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+}
+type CaptchaService struct {
+	enabled   bool
+	secret    string
+	verifyURL string
+	client    *http.Client
+}
+func NewCaptchaService(enabled bool, provider, secret string) *CaptchaService {
+	return &CaptchaService{
+		enabled:   enabled,
+		secret:    secret,
+		verifyURL: captchaVerifyURLs[provider],
+		client:    &http.Client{Timeout: 5 * time.Second},
+	}
+}
+func (s *CaptchaService) Enabled() bool {
+	return s.enabled
+}
+func (s *CaptchaService) Verify(token, remoteIP string) error {
+	if !s.enabled {
+		return nil
+	}
+	if token == "" {
+		return fmt.Errorf("captcha token is required")
+	}
+	if s.verifyURL == "" {
+		return fmt.Errorf("captcha provider is not configured")
+	}
+	resp, err := s.client.PostForm(s.verifyURL, url.Values{
+		"secret":   {s.secret},
+		"response": {token},
+		"remoteip": {remoteIP},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to verify captcha: %w", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to verify captcha: %w", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("captcha token is invalid")
+	}
+	return nil
+}
@@ -4,29 +4,43 @@ import (
 	"time"
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
 )
 type CartService struct {
 	cartRepo    *repositories.CartRepository
 	productRepo *repositories.ProductRepository
+	pricing     *OrderPricing
+	shipping    *ShippingEstimator
+	itemMaxAge  time.Duration
 }
-func NewCartService(cartRepo *repositories.CartRepository, productRepo *repositories.ProductRepository) *CartService {
+// NewCartService expires cart lines untouched for itemMaxAge; zero keeps
+// them until their product is discontinued.
+func NewCartService(cartRepo *repositories.CartRepository, productRepo *repositories.ProductRepository, pricing *OrderPricing, shipping *ShippingEstimator, itemMaxAge time.Duration) *CartService {
 	return &CartService{
 		cartRepo:    cartRepo,
 		productRepo: productRepo,
+		pricing:     pricing,
+		shipping:    shipping,
+		itemMaxAge:  itemMaxAge,
 	}
 }
-func (s *CartService) AddToCart(userID, productID string, quantity int) (*models.CartItem, error) {
-	product, err := s.productRepo.GetByID(productID)
+// AddToCart accepts fractional quantities only for products sold by a
+// measured unit such as kg; count products take whole numbers.
+func (s *CartService) AddToCart(userID, productID string, quantity models.Quantity) (*models.CartItem, error) {
+	product, err := s.productRepo.GetByID("", productID)
 	if err != nil {
 		return nil, fmt.Errorf("product not found: %w", err)
 	}
-	if !product.InStock || product.Stock < quantity {
+	if err := models.CheckQuantity(product.Unit, quantity); err != nil {
+		return nil, err
+	}
+	if !product.InStock || models.QuantityFromInt(product.Stock) < quantity {
 		return nil, fmt.Errorf("insufficient stock")
 	}
 	existingItem, err := s.cartRepo.GetByUserAndProduct(userID, productID)
 	if err == nil {
 		newQuantity := existingItem.Quantity + quantity
-		if newQuantity > product.Stock {
+		if newQuantity > models.QuantityFromInt(product.Stock) {
 			return nil, fmt.Errorf("insufficient stock")
 		}
 		updates := map[string]interface{}{
@@ -40,12 +54,13 @@ func (s *CartService) AddToCart(userID, productID string, quantity int) (*models
 		return updatedItem, nil
 	}
 	cartItem := &models.CartItem{
-		ID:        generateID(),
-		UserID:    userID,
-		ProductID: productID,
-		Quantity:  quantity,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:         generateID(),
+		UserID:     userID,
+		ProductID:  productID,
+		Quantity:   quantity,
+		AddedPrice: &product.Price,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
 	}
 	if err := s.cartRepo.Create(cartItem); err != nil {
 		return nil, fmt.Errorf("failed to add to cart: %w", err)
@@ -55,7 +70,77 @@ func (s *CartService) AddToCart(userID, productID string, quantity int) (*models
 func (s *CartService) GetCartItems(userID string) ([]models.CartItemWithProduct, error) {
 	return s.cartRepo.GetByUserID(userID)
 }
-func (s *CartService) UpdateCartItem(userID, itemID string, quantity int) (*models.CartItem, error) {
+// GetCart sweeps the user's stale lines first so removals show up as notices
+// immediately rather than on the next background sweep.
+func (s *CartService) GetCart(userID string) (*models.CartResponse, error) {
+	if _, err := s.cartRepo.RemoveStaleItems(userID, s.expiryCutoff()); err != nil {
+		return nil, fmt.Errorf("failed to remove stale cart items: %w", err)
+	}
+	notices, err := s.cartRepo.GetNotices(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart notices: %w", err)
+	}
+	version, updatedAt, err := s.cartRepo.GetVersion(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart version: %w", err)
+	}
+	items, err := s.cartRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart items: %w", err)
+	}
+	var total models.Money
+	for i := range items {
+		item := &items[i]
+		total += item.Product.Price.MulQuantity(item.Quantity)
+		if item.AddedPrice != nil && *item.AddedPrice != item.Product.Price {
+			item.PriceChanged = true
+			newPrice := item.Product.Price
+			notices = append(notices, models.CartNotice{
+				Type:        models.CartNoticePriceChanged,
+				ProductID:   item.ProductID,
+				ProductName: item.Product.Name,
+				OldPrice:    item.AddedPrice,
+				NewPrice:    &newPrice,
+				CreatedAt:   item.Product.UpdatedAt,
+			})
+		}
+	}
+	return &models.CartResponse{
+		Items:     items,
+		Total:     total,
+		Totals:    s.pricing.CartTotals(total),
+		Version:   version,
+		UpdatedAt: updatedAt,
+		Notices:   notices,
+	}, nil
+}
+// AcknowledgeNotices dismisses removal notices and accepts current prices.
+func (s *CartService) AcknowledgeNotices(userID string) error {
+	return s.cartRepo.AcknowledgeNotices(userID)
+}
+// SweepStaleItems is the background job that removes discontinued and
+// expired lines from every cart.
+func (s *CartService) SweepStaleItems() error {
+	removed, err := s.cartRepo.RemoveStaleItems("", s.expiryCutoff())
+	if err != nil {
+		return fmt.Errorf("failed to sweep stale cart items: %w", err)
+	}
+	if removed > 0 {
+		utils.Info("removed stale cart items", "count", removed)
+	}
+	return nil
+}
+func (s *CartService) expiryCutoff() *time.Time {
+	if s.itemMaxAge <= 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-s.itemMaxAge)
+	return &cutoff
+}
+// UpdateCartItem applies the change only if the cart is still at
+// expectedVersion, when one is given, so a stale device cannot overwrite a
+// newer cart. Conflicts return "cart version conflict".
+func (s *CartService) UpdateCartItem(userID, itemID string, quantity models.Quantity, expectedVersion *int64) (*models.CartItem, error) {
 	item, err := s.cartRepo.GetByID(itemID)
 	if err != nil {
 		return nil, fmt.Errorf("cart item not found: %w", err)
@@ -63,15 +148,32 @@ func (s *CartService) UpdateCartItem(userID, itemID string, quantity int) (*mode
 	if item.UserID != userID {
 		return nil, fmt.Errorf("unauthorized")
 	}
-	if quantity <= 0 {
-		return s.RemoveFromCart(userID, itemID)
+	if quantity > 0 {
+		product, err := s.productRepo.GetByID("", item.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("product not found: %w", err)
+		}
+		if err := models.CheckQuantity(product.Unit, quantity); err != nil {
+			return nil, err
+		}
+		if quantity > models.QuantityFromInt(product.Stock) {
+			return nil, fmt.Errorf("insufficient stock")
+		}
 	}
-	product, err := s.productRepo.GetByID(item.ProductID)
-	if err != nil {
-		return nil, fmt.Errorf("product not found: %w", err)
+	if expectedVersion != nil {
+		if _, err := s.cartRepo.UpdateQuantityVersioned(userID, itemID, quantity, *expectedVersion); err != nil {
+			if err.Error() == "cart version conflict" || err.Error() == "cart item not found" {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to update cart item: %w", err)
+		}
+		if quantity <= 0 {
+			return item, nil
+		}
+		return s.cartRepo.GetByID(itemID)
 	}
-	if quantity > product.Stock {
-		return nil, fmt.Errorf("insufficient stock")
+	if quantity <= 0 {
+		return s.RemoveFromCart(userID, itemID)
 	}
 	updates := map[string]interface{}{
 		"quantity":   quantity,
@@ -99,14 +201,24 @@ func (s *CartService) RemoveFromCart(userID, itemID string) (*models.CartItem, e
 func (s *CartService) ClearCart(userID string) error {
 	return s.cartRepo.DeleteByUserID(userID)
 }
-func (s *CartService) GetCartTotal(userID string) (float64, error) {
+func (s *CartService) EstimateShipping(userID string, req models.ShippingEstimateRequest) (*models.ShippingEstimate, error) {
+	subtotal, err := s.GetCartTotal(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart total: %w", err)
+	}
+	if subtotal == 0 {
+		return nil, fmt.Errorf("cart is empty")
+	}
+	return s.shipping.Estimate(req.Country, req.PostalCode, subtotal)
+}
+func (s *CartService) GetCartTotal(userID string) (models.Money, error) {
 	items, err := s.cartRepo.GetByUserID(userID)
 	if err != nil {
 		return 0, err
 	}
-	var total float64
+	var total models.Money
 	for _, item := range items {
-		total += item.Product.Price * float64(item.Quantity)
+		total += item.Product.Price.MulQuantity(item.Quantity)
 	}
 	return total, nil
 }
\ No newline at end of file
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
 	"fmt"
 	"strings"
 	"time"
@@ -14,21 +15,49 @@ import (
 type CategoryService struct {
 	categoryRepo *repositories.CategoryRepository
 	productRepo  *repositories.ProductRepository
+	translations *TranslationService
 }
 
-func NewCategoryService(categoryRepo *repositories.CategoryRepository, productRepo *repositories.ProductRepository) *CategoryService {
+func NewCategoryService(categoryRepo *repositories.CategoryRepository, productRepo *repositories.ProductRepository, translations *TranslationService) *CategoryService {
 	return &CategoryService{
 		categoryRepo: categoryRepo,
 		productRepo:  productRepo,
+		translations: translations,
 	}
 }
-func (s *CategoryService) GetCategories(page, limit int, includeProducts bool) ([]models.CategoryWithProducts, int, error) {
+func (s *CategoryService) ResolveLocale(requested, acceptLanguage string) string {
+	return s.translations.ResolveLocale(requested, acceptLanguage)
+}
+// categoryPage is a page of GetCategories as kept in the products cache.
+type categoryPage struct {
+	categories []models.CategoryWithProducts
+	total      int
+}
+// GetCategories pages through the categories of a store. Pages live in the
+// products cache, which category, product and translation changes clear.
+func (s *CategoryService) GetCategories(storeID string, page, limit int, includeProducts bool, locale string) ([]models.CategoryWithProducts, int, error) {
+	cacheKey := fmt.Sprintf("categories:%s:%d:%d:%t:%s", storeID, page, limit, includeProducts, locale)
+	result, err := utils.CacheGetOrSet("products", cacheKey, 0, func() (interface{}, error) {
+		categories, total, err := s.loadCategories(storeID, page, limit, includeProducts, locale)
+		if err != nil {
+			return nil, err
+		}
+		return &categoryPage{categories: categories, total: total}, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	cached := result.(*categoryPage)
+	return cached.categories, cached.total, nil
+}
+func (s *CategoryService) loadCategories(storeID string, page, limit int, includeProducts bool, locale string) ([]models.CategoryWithProducts, int, error) {
 	offset := (page - 1) * limit
-	categories, err := s.categoryRepo.GetCategories(limit, offset)
+	categories, err := s.categoryRepo.GetCategories(storeID, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
-	total, err := s.categoryRepo.CountCategories()
+	s.translations.LocalizeCategories(categories, locale)
+	total, err := s.categoryRepo.CountCategories(storeID)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -46,6 +75,7 @@ func (s *CategoryService) GetCategories(page, limit int, includeProducts bool) (
 						Product: *product,
 					}
 				}
+				localizeProductsWithRating(s.translations, productsWithRating, locale)
 				categoryWithProducts.Products = productsWithRating
 				categoryWithProducts.Count = len(products)
 			}
@@ -54,11 +84,26 @@ func (s *CategoryService) GetCategories(page, limit int, includeProducts bool) (
 	}
 	return categoriesWithProducts, total, nil
 }
-func (s *CategoryService) GetCategoryBySlug(slug string, includeProducts bool) (*models.CategoryWithProducts, error) {
-	category, err := s.categoryRepo.GetCategoryBySlug(slug)
+// SearchCategories pages through the categories whose name or slug
+// contains text. Count holds each match's product count.
+func (s *CategoryService) SearchCategories(storeID, text string, page, limit int, locale string) ([]models.CategoryWithProducts, int, error) {
+	categories, total, err := s.categoryRepo.SearchByText(storeID, text, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	localized := make([]*models.Category, len(categories))
+	for i := range categories {
+		localized[i] = &categories[i].Category
+	}
+	s.translations.LocalizeCategories(localized, locale)
+	return categories, total, nil
+}
+func (s *CategoryService) GetCategoryBySlug(storeID, slug string, includeProducts bool, locale string) (*models.CategoryWithProducts, error) {
+	category, err := s.categoryRepo.GetCategoryBySlug(storeID, slug)
 	if err != nil {
 		return nil, err
 	}
+	s.translations.LocalizeCategories([]*models.Category{category}, locale)
 	categoryWithProducts := &models.CategoryWithProducts{
 		Category: *category,
 	}
@@ -71,15 +116,27 @@ func (s *CategoryService) GetCategoryBySlug(slug string, includeProducts bool) (
 					Product: *product,
 				}
 			}
+			localizeProductsWithRating(s.translations, productsWithRating, locale)
 			categoryWithProducts.Products = productsWithRating
 			categoryWithProducts.Count = len(products)
 		}
 	}
 	return categoryWithProducts, nil
 }
-func (s *CategoryService) CreateCategory(req models.CategoryCreateRequest) (*models.Category, error) {
+// GetCategoryStats returns the summary figures of a category. They live in
+// the products cache, so product and category changes refresh them.
+func (s *CategoryService) GetCategoryStats(storeID, slug string) (*models.CategoryStats, error) {
+	result, err := utils.CacheGetOrSet("products", "category-stats:"+storeID+":"+slug, 0, func() (interface{}, error) {
+		return s.categoryRepo.GetStats(storeID, slug)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*models.CategoryStats), nil
+}
+func (s *CategoryService) CreateCategory(storeID string, req models.CategoryCreateRequest) (*models.Category, error) {
 	slug := s.generateSlug(req.Name)
-	existing, err := s.categoryRepo.GetCategoryBySlug(slug)
+	existing, err := s.categoryRepo.GetCategoryBySlug(storeID, slug)
 	if err != sql.ErrNoRows && err != nil {
 		return nil, err
 	}
@@ -92,6 +149,7 @@ func (s *CategoryService) CreateCategory(req models.CategoryCreateRequest) (*mod
 		Slug:        slug,
 		Description: &req.Description,
 		Image:       req.Image,
+		StoreID:     storeID,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -99,10 +157,17 @@ func (s *CategoryService) CreateCategory(req models.CategoryCreateRequest) (*mod
 	if err != nil {
 		return nil, err
 	}
+	utils.CacheInvalidatePrefix("products:")
 	return category, nil
 }
-func (s *CategoryService) UpdateCategory(slug string, req models.CategoryUpdateRequest) (*models.Category, error) {
-	category, err := s.categoryRepo.GetCategoryBySlug(slug)
+// UpdateCategory saves the changes only if the category is still at
+// req.Version. On "category version conflict" the current category is
+// returned alongside the error.
+func (s *CategoryService) UpdateCategory(storeID, slug string, req models.CategoryUpdateRequest) (*models.Category, error) {
+	if req.Version == nil {
+		return nil, fmt.Errorf("version is required")
+	}
+	category, err := s.categoryRepo.GetCategoryBySlug(storeID, slug)
 	if err != nil {
 		return nil, err
 	}
@@ -117,7 +182,7 @@ func (s *CategoryService) UpdateCategory(slug string, req models.CategoryUpdateR
 		category.Image = req.Image
 	}
 	category.UpdatedAt = time.Now()
-	err = s.categoryRepo.UpdateCategory(category.ID, map[string]interface{}{
+	version, err := s.categoryRepo.UpdateVersioned(category.ID, *req.Version, map[string]interface{}{
 		"name":        category.Name,
 		"slug":        category.Slug,
 		"description": category.Description,
@@ -125,12 +190,21 @@ func (s *CategoryService) UpdateCategory(slug string, req models.CategoryUpdateR
 		"updated_at":  category.UpdatedAt,
 	})
 	if err != nil {
+		if err.Error() == "category version conflict" {
+			latest, getErr := s.categoryRepo.GetByID(category.ID)
+			if getErr != nil {
+				return nil, getErr
+			}
+			return latest, err
+		}
 		return nil, err
 	}
+	category.Version = version
+	utils.CacheInvalidatePrefix("products:")
 	return category, nil
 }
-func (s *CategoryService) DeleteCategory(slug string) error {
-	category, err := s.categoryRepo.GetCategoryBySlug(slug)
+func (s *CategoryService) DeleteCategory(storeID, slug string) error {
+	category, err := s.categoryRepo.GetCategoryBySlug(storeID, slug)
 	if err != nil {
 		return err
 	}
@@ -141,7 +215,11 @@ func (s *CategoryService) DeleteCategory(slug string) error {
 	if len(products) > 0 {
 		return fmt.Errorf("cannot delete category with products")
 	}
-	return s.categoryRepo.DeleteCategory(category.ID)
+	if err := s.categoryRepo.DeleteCategory(category.ID); err != nil {
+		return err
+	}
+	utils.CacheInvalidatePrefix("products:")
+	return nil
 }
 func (s *CategoryService) generateSlug(name string) string {
 	slug := strings.ToLower(name)
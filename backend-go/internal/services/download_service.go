@@ -0,0 +1,70 @@
+﻿package services
+import (
+	"fmt"
+	"time"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
+)
+type DownloadService struct {
+	downloadRepo *repositories.DownloadRepository
+	orderRepo    *repositories.OrderRepository
+	maxDownloads int
+	linkTTL      time.Duration
+}
+func NewDownloadService(downloadRepo *repositories.DownloadRepository, orderRepo *repositories.OrderRepository, maxDownloads int, linkTTL time.Duration) *DownloadService {
+	return &DownloadService{downloadRepo: downloadRepo, orderRepo: orderRepo, maxDownloads: maxDownloads, linkTTL: linkTTL}
+}
+// IssueForOrder creates a download link for every digital product in a paid
+// order and reports whether the order also has items to ship. Links that
+// already exist are left untouched.
+func (s *DownloadService) IssueForOrder(order *models.Order) (bool, error) {
+	productIDs, hasPhysical, err := s.downloadRepo.GetOrderProductTypes(order.ID)
+	if err != nil {
+		return true, fmt.Errorf("failed to get order items: %w", err)
+	}
+	now := time.Now()
+	for _, productID := range productIDs {
+		download := &models.OrderDownload{
+			OrderID:      order.ID,
+			ProductID:    productID,
+			UserID:       order.UserID,
+			Token:        utils.GenerateRandomHex(48),
+			MaxDownloads: s.maxDownloads,
+			ExpiresAt:    now.Add(s.linkTTL),
+			CreatedAt:    now,
+		}
+		if _, err := s.downloadRepo.Create(download); err != nil {
+			return hasPhysical, fmt.Errorf("failed to create download link: %w", err)
+		}
+	}
+	return hasPhysical, nil
+}
+func (s *DownloadService) GetOrderDownloads(orderID, userID, userRole string) ([]models.OrderDownload, error) {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil || (userRole != "admin" && order.UserID != userID) {
+		return nil, fmt.Errorf("order not found")
+	}
+	if order.Status == models.OrderStatusPending || order.Status == models.OrderStatusCancelled {
+		return nil, fmt.Errorf("order is not paid")
+	}
+	downloads, err := s.downloadRepo.GetByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range downloads {
+		downloads[i].URL = "/api/downloads/" + downloads[i].Token
+	}
+	return downloads, nil
+}
+func (s *DownloadService) Consume(token string) (*models.OrderDownload, error) {
+	download, err := s.downloadRepo.Consume(token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if download.File == "" {
+		utils.Warn("digital product has no file", "product_id", download.ProductID)
+		return nil, fmt.Errorf("download file missing")
+	}
+	return download, nil
+}
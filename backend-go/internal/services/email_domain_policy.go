@@ -0,0 +1,135 @@
+﻿package services
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"ecommerce-backend/internal/config"
+	"ecommerce-backend/internal/utils"
+)
+// disposableEmailDomains are well-known throwaway inbox providers, blocked
+// when REGISTER_BLOCK_DISPOSABLE_EMAILS is on.
+var disposableEmailDomains = []string{
+	"10minutemail.com", "dispostable.com", "emailondeck.com", "fakeinbox.com", "getnada.com",
+	"guerrillamail.com", "guerrillamail.net", "mailinator.com", "maildrop.cc", "mailnesia.com",
+	"mintemail.com", "moakt.com", "sharklasers.com", "spamgourmet.com", "temp-mail.org",
+	"tempmail.com", "throwawaymail.com", "trashmail.com", "yopmail.com",
+}
+// EmailDomainPolicy decides which email domains may register. Domains come
+// from config and an optional file, which Reload re-reads when it changes
+// so the denylist can be updated without a restart. A blocked domain also
+// blocks its subdomains.
+type EmailDomainPolicy struct {
+	enabled    bool
+	static     []string
+	disposable map[string]bool
+	path       string
+	mu         sync.RWMutex
+	blocked    map[string]bool
+	modTime    time.Time
+}
+func NewEmailDomainPolicy(cfg config.RegisterConfig) *EmailDomainPolicy {
+	p := &EmailDomainPolicy{
+		enabled:    !cfg.EmailDomainCheckDisabled,
+		static:     cfg.BlockedEmailDomains,
+		disposable: make(map[string]bool),
+		path:       cfg.BlockedEmailDomainsFile,
+	}
+	if cfg.BlockDisposableEmails {
+		for _, domain := range disposableEmailDomains {
+			p.disposable[domain] = true
+		}
+	}
+	p.blocked = domainSet(p.static, nil)
+	if err := p.Reload(); err != nil {
+		utils.Warn("failed to load blocked email domains", "path", p.path, "error", err.Error())
+	}
+	return p
+}
+// Reload re-reads the domains file if it changed since the last load. On
+// error the previous list stays in effect.
+func (p *EmailDomainPolicy) Reload() error {
+	if p.path == "" {
+		return nil
+	}
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+	p.mu.RLock()
+	unchanged := info.ModTime().Equal(p.modTime)
+	p.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+	domains, err := readDomainFile(p.path)
+	if err != nil {
+		return err
+	}
+	blocked := domainSet(p.static, domains)
+	p.mu.Lock()
+	p.blocked = blocked
+	p.modTime = info.ModTime()
+	p.mu.Unlock()
+	utils.Info("blocked email domains loaded", "path", p.path, "domains", len(blocked))
+	return nil
+}
+// Check returns an error when email's domain may not register.
+func (p *EmailDomainPolicy) Check(email string) error {
+	if !p.enabled {
+		return nil
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return nil
+	}
+	domain := strings.TrimSuffix(utils.NormalizeEmail(email[at+1:]), ".")
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for candidate := domain; candidate != ""; {
+		if p.blocked[candidate] {
+			return fmt.Errorf("email domain is not allowed")
+		}
+		if p.disposable[candidate] {
+			return fmt.Errorf("disposable email addresses are not allowed")
+		}
+		dot := strings.Index(candidate, ".")
+		if dot < 0 {
+			break
+		}
+		candidate = candidate[dot+1:]
+	}
+	return nil
+}
+func domainSet(lists ...[]string) map[string]bool {
+	set := make(map[string]bool)
+	for _, list := range lists {
+		for _, domain := range list {
+			if domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), "@."); domain != "" {
+				set[domain] = true
+			}
+		}
+	}
+	return set
+}
+// readDomainFile reads one domain per line; blank lines and # comments are
+// ignored.
+func readDomainFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var domains []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		domains = append(domains, line)
+	}
+	return domains, scanner.Err()
+}
@@ -0,0 +1,42 @@
+﻿package services
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+	"ecommerce-backend/internal/config"
+	"ecommerce-backend/internal/utils"
+)
+type EmailService struct {
+	cfg config.EmailConfig
+}
+func NewEmailService(cfg config.EmailConfig) *EmailService {
+	return &EmailService{cfg: cfg}
+}
+// Send delivers transactional mail (account and order messages), which
+// notification preferences never suppress. No promotion, price or stock
+// mail is sent, so those preferences only affect WebSocket notifications.
+func (s *EmailService) Send(to, subject, body string) error {
+	if s.cfg.SMTPHost == "" {
+		// The body is left out: it can carry links and tokens.
+		utils.Info("email delivery disabled, message not sent", "to", to, "subject", subject)
+		return nil
+	}
+	msg := strings.Join([]string{
+		"From: " + s.cfg.From,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.SMTPHost)
+	}
+	addr := fmt.Sprintf("%s:%d", s.cfg.SMTPHost, s.cfg.SMTPPort)
+	if err := smtp.SendMail(addr, auth, s.cfg.From, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+﻿package services
+import (
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
+	"fmt"
+	"strings"
+	"time"
+)
+const (
+	sitemapMaxURLs    = 50000
+	sitemapXmlns      = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	feedBatchSize     = 500
+	feedGoogleXmlns   = "http://base.google.com/ns/1.0"
+	sitemapDateLayout = "2006-01-02"
+)
+type FeedService struct {
+	productRepo  *repositories.ProductRepository
+	categoryRepo *repositories.CategoryRepository
+	siteURL      string
+	publicURL    string
+	currency     string
+	storeName    string
+}
+func NewFeedService(productRepo *repositories.ProductRepository, categoryRepo *repositories.CategoryRepository, siteURL, publicURL, currency, storeName string) *FeedService {
+	return &FeedService{
+		productRepo:  productRepo,
+		categoryRepo: categoryRepo,
+		siteURL:      strings.TrimRight(siteURL, "/"),
+		publicURL:    strings.TrimRight(publicURL, "/"),
+		currency:     currency,
+		storeName:    storeName,
+	}
+}
+func (s *FeedService) Sitemap(storeID string) (interface{}, error) {
+	return utils.CacheGetOrSet("products", "sitemap:"+storeID+":root", 0, func() (interface{}, error) {
+		categories, products, err := s.countSitemapEntries(storeID)
+		if err != nil {
+			return nil, err
+		}
+		total := s.staticURLCount() + categories + products
+		if total <= sitemapMaxURLs {
+			return s.loadSitemapPage(storeID, 1, categories)
+		}
+		pages := (total + sitemapMaxURLs - 1) / sitemapMaxURLs
+		index := &models.SitemapIndex{Xmlns: sitemapXmlns}
+		lastMod := time.Now().UTC().Format(sitemapDateLayout)
+		for page := 1; page <= pages; page++ {
+			index.Sitemaps = append(index.Sitemaps, models.SitemapRef{
+				Loc:     fmt.Sprintf("%s/sitemaps/%d.xml", s.publicURL, page),
+				LastMod: lastMod,
+			})
+		}
+		return index, nil
+	})
+}
+func (s *FeedService) SitemapPage(storeID string, page int) (*models.SitemapURLSet, error) {
+	result, err := utils.CacheGetOrSet("products", fmt.Sprintf("sitemap:%s:%d", storeID, page), 0, func() (interface{}, error) {
+		categories, products, err := s.countSitemapEntries(storeID)
+		if err != nil {
+			return nil, err
+		}
+		total := s.staticURLCount() + categories + products
+		if page < 1 || (page-1)*sitemapMaxURLs >= total {
+			return nil, fmt.Errorf("sitemap page not found")
+		}
+		return s.loadSitemapPage(storeID, page, categories)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*models.SitemapURLSet), nil
+}
+func (s *FeedService) ProductFeed(storeID string) (*models.ProductFeed, error) {
+	result, err := utils.CacheGetOrSet("products", "feed:"+storeID, 0, func() (interface{}, error) {
+		return s.loadProductFeed(storeID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*models.ProductFeed), nil
+}
+func (s *FeedService) staticURLs() []models.SitemapURL {
+	return []models.SitemapURL{
+		{Loc: s.siteURL + "/"},
+		{Loc: s.siteURL + "/products"},
+		{Loc: s.siteURL + "/categories"},
+	}
+}
+func (s *FeedService) staticURLCount() int {
+	return len(s.staticURLs())
+}
+func (s *FeedService) countSitemapEntries(storeID string) (int, int, error) {
+	categories, err := s.categoryRepo.CountCategories(storeID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count categories: %w", err)
+	}
+	products, err := s.productRepo.CountProducts(storeID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count products: %w", err)
+	}
+	return categories, products, nil
+}
+func (s *FeedService) loadSitemapPage(storeID string, page, categoryCount int) (*models.SitemapURLSet, error) {
+	start := (page - 1) * sitemapMaxURLs
+	end := start + sitemapMaxURLs
+	urlset := &models.SitemapURLSet{Xmlns: sitemapXmlns}
+	position := 0
+	for _, u := range s.staticURLs() {
+		if position >= start && position < end {
+			urlset.URLs = append(urlset.URLs, u)
+		}
+		position++
+	}
+	if position+categoryCount > start && position < end {
+		categories, err := s.categoryRepo.List(storeID, categoryCount, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get categories: %w", err)
+		}
+		for _, category := range categories {
+			if position >= start && position < end {
+				urlset.URLs = append(urlset.URLs, models.SitemapURL{
+					Loc:     fmt.Sprintf("%s/products?category=%s", s.siteURL, category.ID),
+					LastMod: category.UpdatedAt.UTC().Format(sitemapDateLayout),
+				})
+			}
+			position++
+		}
+	} else {
+		position += categoryCount
+	}
+	offset := start - position
+	if offset < 0 {
+		offset = 0
+	}
+	remaining := end - position - offset
+	for remaining > 0 {
+		batch := feedBatchSize
+		if remaining < batch {
+			batch = remaining
+		}
+		products, err := s.productRepo.ListForFeed(storeID, batch, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get products: %w", err)
+		}
+		for _, product := range products {
+			urlset.URLs = append(urlset.URLs, models.SitemapURL{
+				Loc:     fmt.Sprintf("%s/products/%s", s.siteURL, product.ID),
+				LastMod: product.UpdatedAt.UTC().Format(sitemapDateLayout),
+			})
+		}
+		if len(products) < batch {
+			break
+		}
+		offset += batch
+		remaining -= batch
+	}
+	return urlset, nil
+}
+func (s *FeedService) loadProductFeed(storeID string) (*models.ProductFeed, error) {
+	feed := &models.ProductFeed{
+		Version: "2.0",
+		XmlnsG:  feedGoogleXmlns,
+		Channel: models.ProductFeedChannel{
+			Title:       s.storeName,
+			Link:        s.siteURL,
+			Description: s.storeName + " product feed",
+		},
+	}
+	for offset := 0; ; offset += feedBatchSize {
+		products, err := s.productRepo.ListForFeed(storeID, feedBatchSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get products: %w", err)
+		}
+		for _, product := range products {
+			feed.Channel.Items = append(feed.Channel.Items, s.feedItem(product))
+		}
+		if len(products) < feedBatchSize {
+			break
+		}
+	}
+	return feed, nil
+}
+func (s *FeedService) feedItem(product models.ProductWithCategory) models.ProductFeedItem {
+	item := models.ProductFeedItem{
+		ID:           product.ID,
+		Title:        product.Name,
+		Link:         fmt.Sprintf("%s/products/%s", s.siteURL, product.ID),
+		Availability: "out_of_stock",
+		Price:        s.formatPrice(product.Price),
+		Condition:    "new",
+	}
+	// Merchant feeds key on the SKU when one is set so item ids stay stable
+	// across re-imports.
+	if product.SKU != nil {
+		item.ID = *product.SKU
+	}
+	if product.Barcode != nil {
+		item.GTIN = *product.Barcode
+	}
+	if product.Description != nil {
+		item.Description = *product.Description
+	}
+	if item.Description == "" {
+		item.Description = product.Name
+	}
+	if product.InStock && product.Stock > 0 {
+		item.Availability = "in_stock"
+	}
+	if product.ComparePrice != nil && *product.ComparePrice > product.Price {
+		item.Price = s.formatPrice(*product.ComparePrice)
+		item.SalePrice = s.formatPrice(product.Price)
+	}
+	if product.Category != nil {
+		item.ProductType = product.Category.Name
+	}
+	for i, image := range product.Images {
+		link := s.absoluteURL(image)
+		if i == 0 {
+			item.ImageLink = link
+			continue
+		}
+		item.AdditionalImageLinks = append(item.AdditionalImageLinks, link)
+	}
+	return item
+}
+func (s *FeedService) formatPrice(price models.Money) string {
+	return fmt.Sprintf("%s %s", price, s.currency)
+}
+func (s *FeedService) absoluteURL(path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return s.publicURL + "/" + strings.TrimLeft(path, "/")
+}
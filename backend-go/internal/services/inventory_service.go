@@ -0,0 +1,34 @@
+﻿package services
+import (
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+)
+type InventoryService struct {
+	inventoryRepo *repositories.InventoryRepository
+	productRepo   *repositories.ProductRepository
+}
+func NewInventoryService(inventoryRepo *repositories.InventoryRepository, productRepo *repositories.ProductRepository) *InventoryService {
+	return &InventoryService{inventoryRepo: inventoryRepo, productRepo: productRepo}
+}
+func (s *InventoryService) GetHistory(storeID, productID string, query models.InventoryHistoryQuery) ([]models.InventoryMovement, int, error) {
+	if _, err := s.productRepo.GetByID(storeID, productID); err != nil {
+		return nil, 0, err
+	}
+	offset := (query.Page - 1) * query.Limit
+	return s.inventoryRepo.ListByProduct(productID, query.Reason, query.Limit, offset)
+}
+// Reconcile checks current stock against the ledger for every product of a
+// store, or only the products that disagree when mismatchedOnly is set.
+func (s *InventoryService) Reconcile(storeID string, mismatchedOnly bool) (*models.InventoryReport, error) {
+	products, err := s.inventoryRepo.Reconcile(storeID, mismatchedOnly)
+	if err != nil {
+		return nil, err
+	}
+	report := &models.InventoryReport{Products: products, Checked: len(products)}
+	for _, product := range products {
+		if product.Difference != 0 {
+			report.Mismatched++
+		}
+	}
+	return report, nil
+}
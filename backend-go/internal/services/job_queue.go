@@ -0,0 +1,137 @@
+﻿package services
+import (
+	"sync"
+	"time"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/utils"
+	"github.com/google/uuid"
+)
+// jobRetention is how long finished tracked jobs stay queryable.
+const jobRetention = 24 * time.Hour
+type JobQueue struct {
+	pool        *utils.WorkerPool
+	maxAttempts int
+	mu          sync.RWMutex
+	tracked     map[string]*models.Job
+}
+func NewJobQueue(workers, maxAttempts int) *JobQueue {
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	return &JobQueue{pool: utils.NewWorkerPool(workers), maxAttempts: maxAttempts, tracked: make(map[string]*models.Job)}
+}
+// Schedule enqueues job immediately and then every interval.
+func (q *JobQueue) Schedule(name string, interval time.Duration, job func() error) {
+	q.Enqueue(name, job)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			q.Enqueue(name, job)
+		}
+	}()
+}
+func (q *JobQueue) Enqueue(name string, job func() error) {
+	q.EnqueueFor("", name, job)
+}
+// EnqueueFor is Enqueue for work started by a request; the request ID is
+// logged with every attempt so the job can be traced back to it.
+func (q *JobQueue) EnqueueFor(requestID, name string, job func() error) {
+	q.pool.Submit(func() {
+		q.run(name, requestID, job, nil)
+	})
+}
+// Submit enqueues job like Enqueue but records its progress so callers can
+// poll it with Get. onDone, if set, runs once with the final error after the
+// last attempt. The returned copy is in the queued state.
+func (q *JobQueue) Submit(name string, job func() error, onDone func(error)) models.Job {
+	return q.SubmitFor("", name, job, onDone)
+}
+// SubmitFor is Submit for work started by a request; the job carries and
+// logs the request ID.
+func (q *JobQueue) SubmitFor(requestID, name string, job func() error, onDone func(error)) models.Job {
+	tracked := &models.Job{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Status:    models.JobStatusQueued,
+		RequestID: requestID,
+		CreatedAt: time.Now(),
+	}
+	q.mu.Lock()
+	q.pruneLocked()
+	q.tracked[tracked.ID] = tracked
+	snapshot := *tracked
+	q.mu.Unlock()
+	q.pool.Submit(func() {
+		err := q.run(name, requestID, job, tracked)
+		if onDone != nil {
+			onDone(err)
+		}
+	})
+	return snapshot
+}
+// Get returns a snapshot of a job started with Submit.
+func (q *JobQueue) Get(id string) (models.Job, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	job, ok := q.tracked[id]
+	if !ok {
+		return models.Job{}, false
+	}
+	return *job, true
+}
+func (q *JobQueue) run(name, requestID string, job func() error, tracked *models.Job) error {
+	q.update(tracked, func(j *models.Job) {
+		now := time.Now()
+		j.Status = models.JobStatusRunning
+		j.StartedAt = &now
+	})
+	for attempt := 1; ; attempt++ {
+		q.update(tracked, func(j *models.Job) { j.Attempts = attempt })
+		err := job()
+		if err == nil {
+			utils.Info("job completed", jobLogFields(name, requestID, "attempt", attempt)...)
+			q.finish(tracked, models.JobStatusCompleted, "")
+			return nil
+		}
+		if attempt >= q.maxAttempts {
+			utils.Error("job failed", jobLogFields(name, requestID, "attempts", attempt, "error", err.Error())...)
+			q.finish(tracked, models.JobStatusFailed, err.Error())
+			return err
+		}
+		utils.Warn("job attempt failed, retrying", jobLogFields(name, requestID, "attempt", attempt, "error", err.Error())...)
+		q.update(tracked, func(j *models.Job) { j.Error = err.Error() })
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+}
+func jobLogFields(name, requestID string, fields ...any) []any {
+	fields = append([]any{"job", name}, fields...)
+	if requestID != "" {
+		fields = append(fields, "request_id", requestID)
+	}
+	return fields
+}
+func (q *JobQueue) finish(tracked *models.Job, status, errMsg string) {
+	q.update(tracked, func(j *models.Job) {
+		now := time.Now()
+		j.Status = status
+		j.Error = errMsg
+		j.FinishedAt = &now
+	})
+}
+func (q *JobQueue) update(tracked *models.Job, fn func(*models.Job)) {
+	if tracked == nil {
+		return
+	}
+	q.mu.Lock()
+	fn(tracked)
+	q.mu.Unlock()
+}
+func (q *JobQueue) pruneLocked() {
+	cutoff := time.Now().Add(-jobRetention)
+	for id, job := range q.tracked {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(q.tracked, id)
+		}
+	}
+}
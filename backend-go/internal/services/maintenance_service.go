@@ -0,0 +1,168 @@
+﻿package services
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
+	"ecommerce-backend/internal/websocket"
+)
+// MaintenanceSwitch turns maintenance mode on and off; it is satisfied by
+// *middleware.MaintenanceMode.
+type MaintenanceSwitch interface {
+	Set(enabled bool, message string)
+}
+type MaintenanceService struct {
+	repo      *repositories.MaintenanceRepository
+	mode      MaintenanceSwitch
+	hub       *websocket.Hub
+	leadTimes []time.Duration
+}
+// NewMaintenanceService broadcasts a countdown alert as each of leadTimes
+// before a scheduled window's start is reached.
+func NewMaintenanceService(repo *repositories.MaintenanceRepository, mode MaintenanceSwitch, hub *websocket.Hub, leadTimes []time.Duration) *MaintenanceService {
+	leads := append([]time.Duration(nil), leadTimes...)
+	sort.Slice(leads, func(i, j int) bool { return leads[i] < leads[j] })
+	return &MaintenanceService{
+		repo:      repo,
+		mode:      mode,
+		hub:       hub,
+		leadTimes: leads,
+	}
+}
+func (s *MaintenanceService) List(includeClosed bool) ([]models.MaintenanceWindow, error) {
+	return s.repo.List(includeClosed)
+}
+func (s *MaintenanceService) Schedule(userID string, req models.MaintenanceWindowRequest) (*models.MaintenanceWindow, error) {
+	message := strings.TrimSpace(req.Message)
+	if message == "" {
+		return nil, fmt.Errorf("message is required")
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		return nil, fmt.Errorf("maintenance window must end after it starts")
+	}
+	if !req.EndsAt.After(time.Now()) {
+		return nil, fmt.Errorf("maintenance window is already over")
+	}
+	overlaps, err := s.repo.Overlaps(req.StartsAt, req.EndsAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check maintenance windows: %w", err)
+	}
+	if overlaps {
+		return nil, fmt.Errorf("maintenance window overlaps another window")
+	}
+	now := time.Now()
+	window := &models.MaintenanceWindow{
+		StartsAt:  req.StartsAt.UTC(),
+		EndsAt:    req.EndsAt.UTC(),
+		Message:   message,
+		Status:    models.MaintenanceWindowScheduled,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if userID != "" {
+		window.CreatedBy = &userID
+	}
+	if err := s.repo.Create(window); err != nil {
+		return nil, fmt.Errorf("failed to schedule maintenance window: %w", err)
+	}
+	// Announce right away rather than waiting for the next check.
+	if err := s.Tick(); err != nil {
+		utils.Warn("failed to check maintenance windows", "error", err.Error())
+	}
+	return s.repo.GetByID(window.ID)
+}
+// Cancel withdraws a scheduled window, or ends an active one early.
+func (s *MaintenanceService) Cancel(id string) (*models.MaintenanceWindow, error) {
+	window, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	switch window.Status {
+	case models.MaintenanceWindowScheduled, models.MaintenanceWindowActive:
+	default:
+		return nil, fmt.Errorf("maintenance window is already %s", window.Status)
+	}
+	ok, err := s.repo.Transition(id, window.Status, models.MaintenanceWindowCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel maintenance window: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("maintenance window changed, try again")
+	}
+	if window.Status == models.MaintenanceWindowActive {
+		s.mode.Set(false, "")
+	} else if window.LastAlertLead != nil && s.hub != nil {
+		s.hub.SendMaintenanceAlert("The scheduled maintenance has been cancelled", window.StartsAt)
+	}
+	return s.repo.GetByID(id)
+}
+// Tick is the background job that moves windows through their lifecycle:
+// countdown alerts before the start, maintenance mode on for the duration
+// and off at the end. Maintenance mode is re-asserted for active windows so
+// it survives a restart.
+func (s *MaintenanceService) Tick() error {
+	windows, err := s.repo.List(false)
+	if err != nil {
+		return fmt.Errorf("failed to list maintenance windows: %w", err)
+	}
+	now := time.Now()
+	for _, window := range windows {
+		if err := s.advance(window, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (s *MaintenanceService) advance(window models.MaintenanceWindow, now time.Time) error {
+	switch {
+	case !now.Before(window.EndsAt):
+		// Also closes windows missed entirely while the server was down.
+		ok, err := s.repo.Transition(window.ID, window.Status, models.MaintenanceWindowCompleted)
+		if err != nil {
+			return fmt.Errorf("failed to complete maintenance window: %w", err)
+		}
+		if ok && window.Status == models.MaintenanceWindowActive {
+			s.mode.Set(false, "")
+		}
+	case window.Status == models.MaintenanceWindowActive:
+		s.mode.Set(true, window.Message)
+	case !now.Before(window.StartsAt):
+		ok, err := s.repo.Transition(window.ID, models.MaintenanceWindowScheduled, models.MaintenanceWindowActive)
+		if err != nil {
+			return fmt.Errorf("failed to start maintenance window: %w", err)
+		}
+		if ok {
+			s.mode.Set(true, window.Message)
+		}
+	default:
+		return s.sendCountdown(window, window.StartsAt.Sub(now))
+	}
+	return nil
+}
+// sendCountdown broadcasts an alert for the shortest lead time already
+// reached, unless that alert (or a later one) has gone out.
+func (s *MaintenanceService) sendCountdown(window models.MaintenanceWindow, remaining time.Duration) error {
+	lead := time.Duration(0)
+	for _, candidate := range s.leadTimes {
+		if remaining <= candidate {
+			lead = candidate
+			break
+		}
+	}
+	if lead == 0 {
+		return nil
+	}
+	if window.LastAlertLead != nil && time.Duration(*window.LastAlertLead)*time.Second <= lead {
+		return nil
+	}
+	if s.hub != nil {
+		s.hub.SendMaintenanceAlert(window.Message, window.StartsAt)
+	}
+	if err := s.repo.SetLastAlertLead(window.ID, lead); err != nil {
+		return fmt.Errorf("failed to record maintenance alert: %w", err)
+	}
+	return nil
+}
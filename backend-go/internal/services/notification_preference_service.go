@@ -0,0 +1,76 @@
+﻿package services
+import (
+	"fmt"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
+)
+type NotificationPreferenceService struct {
+	prefRepo *repositories.NotificationPreferenceRepository
+}
+func NewNotificationPreferenceService(prefRepo *repositories.NotificationPreferenceRepository) *NotificationPreferenceService {
+	return &NotificationPreferenceService{prefRepo: prefRepo}
+}
+// Get returns the user's full preference matrix with defaults filled in.
+func (s *NotificationPreferenceService) Get(userID string) (models.NotificationPreferences, error) {
+	stored, err := s.prefRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	prefs := models.DefaultNotificationPreferences()
+	for channel, events := range stored {
+		for event, enabled := range events {
+			if prefs[channel] != nil {
+				prefs[channel][event] = enabled
+			}
+		}
+	}
+	return prefs, nil
+}
+// Update stores the given settings, which may cover only some channels and
+// events, and returns the resulting preferences.
+func (s *NotificationPreferenceService) Update(userID string, req models.NotificationPreferences) (models.NotificationPreferences, error) {
+	for channel, events := range req {
+		if !models.IsNotificationChannel(channel) {
+			return nil, fmt.Errorf("unknown notification channel")
+		}
+		for event, enabled := range events {
+			if !models.IsNotificationEvent(event) {
+				return nil, fmt.Errorf("unknown notification event")
+			}
+			if event == models.NotificationEventOrder && !enabled {
+				return nil, fmt.Errorf("order notifications cannot be disabled")
+			}
+		}
+	}
+	if err := s.prefRepo.Upsert(userID, req); err != nil {
+		return nil, fmt.Errorf("failed to update notification preferences: %w", err)
+	}
+	return s.Get(userID)
+}
+// WebSocketOptOuts returns the users among userIDs that do not want event
+// pushed over the websocket. It matches websocket.OptOutFilter.
+func (s *NotificationPreferenceService) WebSocketOptOuts(event string, userIDs []string) map[string]bool {
+	channel := models.NotificationChannelWebSocket
+	notificationEvent := models.NotificationEvent(event)
+	optedOut := make(map[string]bool)
+	if notificationEvent == models.NotificationEventOrder || len(userIDs) == 0 {
+		return optedOut
+	}
+	settings, err := s.prefRepo.GetSettings(channel, notificationEvent, userIDs)
+	if err != nil {
+		utils.Warn("failed to load notification preferences", "event", event, "error", err)
+		settings = map[string]bool{}
+	}
+	allowedByDefault := models.DefaultNotificationPreferences().Allows(channel, notificationEvent)
+	for _, userID := range userIDs {
+		enabled, ok := settings[userID]
+		if !ok {
+			enabled = allowedByDefault
+		}
+		if !enabled {
+			optedOut[userID] = true
+		}
+	}
+	return optedOut
+}
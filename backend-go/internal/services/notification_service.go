@@ -0,0 +1,65 @@
+﻿package services
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
+	"github.com/google/uuid"
+)
+type NotificationService struct {
+	notificationRepo *repositories.NotificationRepository
+}
+func NewNotificationService(notificationRepo *repositories.NotificationRepository) *NotificationService {
+	return &NotificationService{notificationRepo: notificationRepo}
+}
+// Record stores a notification in the user's inbox. It matches
+// websocket.NotificationStore; failures are logged because the real-time
+// delivery has already happened.
+func (s *NotificationService) Record(userID, messageType, message string, data interface{}) {
+	if userID == "" {
+		return
+	}
+	notification := &models.Notification{
+		UserID:    userID,
+		Type:      messageType,
+		Message:   message,
+		CreatedAt: time.Now(),
+	}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			utils.Warn("failed to encode notification data", "user_id", userID, "type", messageType, "error", err)
+		} else {
+			notification.Data = encoded
+		}
+	}
+	if err := s.notificationRepo.Create(notification); err != nil {
+		utils.Warn("failed to store notification", "user_id", userID, "type", messageType, "error", err)
+	}
+}
+func (s *NotificationService) GetUserNotifications(userID string, unreadOnly bool, page, limit int) ([]models.Notification, int, int, error) {
+	offset := (page - 1) * limit
+	notifications, err := s.notificationRepo.GetByUserID(userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	total, err := s.notificationRepo.CountByUserID(userID, unreadOnly)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	unread := total
+	if !unreadOnly {
+		if unread, err = s.notificationRepo.CountByUserID(userID, true); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+	return notifications, total, unread, nil
+}
+func (s *NotificationService) MarkRead(id, userID string) (*models.Notification, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("notification not found")
+	}
+	return s.notificationRepo.MarkRead(id, userID)
+}
@@ -0,0 +1,90 @@
+﻿package services
+import (
+	"fmt"
+	"ecommerce-backend/internal/config"
+	"ecommerce-backend/internal/models"
+)
+type OrderPricing struct {
+	minOrderAmount models.Money
+	giftWrapFee    models.Money
+	shipping       *ShippingEstimator
+	taxRate        models.TaxRate
+	taxIncluded    bool
+	taxRounding    string
+}
+func NewOrderPricing(orders config.OrdersConfig, shipping *ShippingEstimator) *OrderPricing {
+	return &OrderPricing{
+		minOrderAmount: models.MoneyFromFloat(orders.MinOrderAmount),
+		giftWrapFee:    models.MoneyFromFloat(orders.GiftWrapFee),
+		shipping:       shipping,
+		taxRate:        models.TaxRateFromFloat(orders.TaxRate),
+		taxIncluded:    orders.PricesIncludeTax,
+		taxRounding:    orders.TaxRounding,
+	}
+}
+func (p *OrderPricing) MinOrderAmount() models.Money {
+	return p.minOrderAmount
+}
+func (p *OrderPricing) GiftWrapFee() models.Money {
+	return p.giftWrapFee
+}
+// TaxIncluded reports whether product prices already contain tax.
+func (p *OrderPricing) TaxIncluded() bool {
+	return p.taxIncluded
+}
+// Tax returns the tax on subtotal: the part contained in it when prices
+// include tax, or the amount to add on top otherwise.
+func (p *OrderPricing) Tax(subtotal models.Money) models.Money {
+	if p.taxIncluded {
+		return subtotal.TaxIn(p.taxRate, p.taxRounding)
+	}
+	return subtotal.TaxOn(p.taxRate, p.taxRounding)
+}
+// Total adds tax and the given charges, such as shipping, to subtotal. Tax
+// is only added when prices exclude it.
+func (p *OrderPricing) Total(subtotal, tax models.Money, charges ...models.Money) models.Money {
+	total := subtotal
+	if !p.taxIncluded {
+		total += tax
+	}
+	for _, charge := range charges {
+		total += charge
+	}
+	return total
+}
+func (p *OrderPricing) EstimateShipping(subtotal models.Money) models.Money {
+	return p.shipping.DefaultCost(subtotal)
+}
+// ShippingCost is the shipping charged at checkout, priced by the same rules
+// as the shipping estimate.
+func (p *OrderPricing) ShippingCost(country, postalCode, method string, subtotal models.Money) (models.Money, error) {
+	return p.shipping.Cost(country, postalCode, method, subtotal)
+}
+func (p *OrderPricing) CheckMinimum(subtotal models.Money) error {
+	if p.minOrderAmount > 0 && subtotal < p.minOrderAmount {
+		return fmt.Errorf("order below minimum amount")
+	}
+	return nil
+}
+func (p *OrderPricing) CartTotals(subtotal models.Money) models.CartTotals {
+	threshold := p.shipping.FreeShippingThreshold()
+	totals := models.CartTotals{
+		Subtotal:                 subtotal,
+		EstimatedShipping:        p.EstimateShipping(subtotal),
+		MinOrderAmount:           p.minOrderAmount,
+		MeetsMinimum:             p.CheckMinimum(subtotal) == nil,
+		FreeShippingThreshold:    threshold,
+		QualifiesForFreeShipping: p.shipping.QualifiesForFreeShipping(subtotal),
+		Tax:                      p.Tax(subtotal),
+		TaxRate:                  p.taxRate.Float64(),
+		TaxIncluded:              p.taxIncluded,
+	}
+	totals.EstimatedTotal = p.Total(subtotal, totals.Tax, totals.EstimatedShipping)
+	if !totals.MeetsMinimum {
+		totals.AmountToMinimum = p.minOrderAmount - subtotal
+	}
+	if threshold > 0 && !totals.QualifiesForFreeShipping {
+		totals.AmountToFreeShipping = threshold - subtotal
+	}
+	return totals
+}
@@ -3,56 +3,142 @@
 import (
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
+	"ecommerce-backend/internal/websocket"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type OrderService struct {
-	orderRepo   *repositories.OrderRepository
-	cartRepo    *repositories.CartRepository
-	productRepo *repositories.ProductRepository
+	orderRepo    *repositories.OrderRepository
+	cartRepo     *repositories.CartRepository
+	productRepo  *repositories.ProductRepository
+	userRepo     *repositories.UserRepository
+	shipmentRepo  *repositories.ShipmentRepository
+	inventoryRepo *repositories.InventoryRepository
+	bundleRepo    *repositories.BundleRepository
+	auditService  *AuditService
+	pricing       *OrderPricing
+	emailService  *EmailService
+	jobs          *JobQueue
+	hub           *websocket.Hub
+	// At most resendLimit confirmation emails per order within resendWindow.
+	resendLimit  int
+	resendWindow time.Duration
 }
 
-func NewOrderService(orderRepo *repositories.OrderRepository, cartRepo *repositories.CartRepository, productRepo *repositories.ProductRepository) *OrderService {
+func NewOrderService(orderRepo *repositories.OrderRepository, cartRepo *repositories.CartRepository, productRepo *repositories.ProductRepository, userRepo *repositories.UserRepository, shipmentRepo *repositories.ShipmentRepository, inventoryRepo *repositories.InventoryRepository, bundleRepo *repositories.BundleRepository, auditService *AuditService, pricing *OrderPricing, emailService *EmailService, jobs *JobQueue, hub *websocket.Hub, resendLimit int, resendWindow time.Duration) *OrderService {
 	return &OrderService{
-		orderRepo:   orderRepo,
-		cartRepo:    cartRepo,
-		productRepo: productRepo,
+		orderRepo:     orderRepo,
+		cartRepo:      cartRepo,
+		productRepo:   productRepo,
+		userRepo:      userRepo,
+		shipmentRepo:  shipmentRepo,
+		inventoryRepo: inventoryRepo,
+		bundleRepo:    bundleRepo,
+		auditService:  auditService,
+		pricing:       pricing,
+		emailService:  emailService,
+		jobs:          jobs,
+		hub:           hub,
+		resendLimit:   resendLimit,
+		resendWindow:  resendWindow,
 	}
 }
-func (s *OrderService) GetUserOrders(userID string, page, limit int) ([]models.OrderWithItems, int, error) {
+func (s *OrderService) MinOrderAmount() models.Money {
+	return s.pricing.MinOrderAmount()
+}
+func (s *OrderService) GetUserOrders(userID, storeID string, page, limit int) ([]models.OrderWithItems, int, error) {
 	offset := (page - 1) * limit
-	orders, err := s.orderRepo.GetUserOrders(userID, limit, offset)
+	orders, err := s.orderRepo.GetUserOrders(userID, storeID, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
-	total, err := s.orderRepo.CountUserOrders(userID)
+	total, err := s.orderRepo.CountUserOrders(userID, storeID)
 	if err != nil {
 		return nil, 0, err
 	}
 	return orders, total, nil
 }
-func (s *OrderService) GetOrderByID(orderID, userID string) (*models.OrderWithItems, error) {
+func (s *OrderService) GetOrderByID(orderID, userID, storeID string) (*models.OrderWithItems, error) {
 	order, err := s.orderRepo.GetOrderByID(orderID)
 	if err != nil {
 		return nil, err
 	}
-	if order.UserID != userID {
+	if order.UserID != userID || storeID != "" && order.StoreID != storeID {
 		return nil, fmt.Errorf("order not found")
 	}
 	orderItems, err := s.orderRepo.GetOrderItems(orderID)
 	if err != nil {
 		return nil, err
 	}
+	shipments, err := s.shipmentRepo.GetByOrderID(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipments: %w", err)
+	}
 	orderWithItems := &models.OrderWithItems{
 		Order:      *order,
 		OrderItems: orderItems,
+		Shipments:  shipments,
 	}
 	return orderWithItems, nil
 }
-func (s *OrderService) CreateOrder(userID string, req models.OrderCreateRequest) (*models.OrderWithItems, error) {
+func (s *OrderService) GetOrderForAdmin(orderID, storeID string) (*models.OrderWithItems, error) {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil || storeID != "" && order.StoreID != storeID {
+		return nil, fmt.Errorf("order not found")
+	}
+	orderItems, err := s.orderRepo.GetOrderItems(orderID)
+	if err != nil {
+		return nil, err
+	}
+	shipments, err := s.shipmentRepo.GetByOrderID(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipments: %w", err)
+	}
+	notes, err := s.orderRepo.GetInternalNotes(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get internal notes: %w", err)
+	}
+	return &models.OrderWithItems{
+		Order:         *order,
+		OrderItems:    orderItems,
+		Shipments:     shipments,
+		InternalNotes: notes,
+	}, nil
+}
+func (s *OrderService) GetInternalNotes(orderID string) ([]models.OrderInternalNote, error) {
+	if _, err := s.orderRepo.GetOrderByID(orderID); err != nil {
+		return nil, fmt.Errorf("order not found")
+	}
+	return s.orderRepo.GetInternalNotes(orderID)
+}
+func (s *OrderService) AddInternalNote(orderID, authorID string, req models.OrderNoteRequest) (*models.OrderInternalNote, error) {
+	if _, err := s.orderRepo.GetOrderByID(orderID); err != nil {
+		return nil, fmt.Errorf("order not found")
+	}
+	note := &models.OrderInternalNote{
+		ID:        uuid.New().String(),
+		OrderID:   orderID,
+		AuthorID:  authorID,
+		Note:      req.Note,
+		CreatedAt: time.Now(),
+	}
+	if err := s.orderRepo.AddInternalNote(note); err != nil {
+		return nil, fmt.Errorf("failed to add internal note: %w", err)
+	}
+	return note, nil
+}
+func (s *OrderService) CreateOrder(userID, storeID string, req models.OrderCreateRequest) (*models.OrderWithItems, error) {
+	// The order is priced from this version of the cart; it is only placed
+	// if the cart is unchanged when the order is written.
+	cartVersion, _, err := s.cartRepo.GetVersion(userID)
+	if err != nil {
+		return nil, err
+	}
 	cartItems, err := s.cartRepo.GetUserCartItems(userID)
 	if err != nil {
 		return nil, err
@@ -60,53 +146,117 @@ func (s *OrderService) CreateOrder(userID string, req models.OrderCreateRequest)
 	if len(cartItems) == 0 {
 		return nil, fmt.Errorf("cart is empty")
 	}
-	var subtotal float64
+	var subtotal models.Money
+	hasPhysical := false
 	var orderItems []models.OrderItem
 	for _, item := range cartItems {
-		product, err := s.productRepo.GetProductByID(item.ProductID)
+		// Looked up in every store so a line from another store gets its
+		// own error below.
+		product, err := s.productRepo.GetProductByID("", item.ProductID)
 		if err != nil {
 			return nil, err
 		}
-		itemTotal := product.Price * float64(item.Quantity)
+		if storeID != "" && product.StoreID != storeID {
+			return nil, fmt.Errorf("cart contains products from another store")
+		}
+		// The product may have switched from a measured unit to a count
+		// since the item was added.
+		if err := models.CheckQuantity(product.Unit, item.Quantity); err != nil {
+			return nil, err
+		}
+		itemTotal := product.Price.MulQuantity(item.Quantity)
 		subtotal += itemTotal
-		orderItems = append(orderItems, models.OrderItem{
-			ID:        uuid.New().String(),
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     product.Price,
-		})
-	}
-	tax := subtotal * 0.1 // 10% tax
-	shipping := 10.0      // Fixed shipping cost
-	total := subtotal + tax + shipping
+		var image *string
+		if len(product.Images) > 0 {
+			image = &product.Images[0]
+		}
+		line := models.OrderItem{
+			ID:           uuid.New().String(),
+			ProductID:    item.ProductID,
+			ProductName:  product.Name,
+			ProductImage: image,
+			Quantity:     item.Quantity,
+			Unit:         product.Unit,
+			Price:        product.Price,
+		}
+		components, err := s.bundleRepo.GetComponents(item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		// A bundle is charged at its own price on its line and fulfilled
+		// from zero-priced component lines.
+		for _, component := range components {
+			line.Components = append(line.Components, models.OrderItem{
+				ID:           uuid.New().String(),
+				ProductID:    component.ProductID,
+				ProductName:  component.Name,
+				ProductImage: component.Image,
+				Quantity:     item.Quantity * models.Quantity(component.Quantity),
+				Unit:         component.Unit,
+			})
+			if component.ProductType != models.ProductTypeDigital {
+				hasPhysical = true
+			}
+		}
+		if len(components) == 0 && product.ProductType != models.ProductTypeDigital {
+			hasPhysical = true
+		}
+		orderItems = append(orderItems, line)
+	}
+	if err := s.pricing.CheckMinimum(subtotal); err != nil {
+		return nil, err
+	}
+	if hasPhysical && strings.TrimSpace(req.ShippingAddress) == "" {
+		return nil, fmt.Errorf("shipping address is required")
+	}
+	tax := s.pricing.Tax(subtotal)
+	var shipping models.Money
+	var shippingMethod *string
+	if hasPhysical {
+		method := req.ShippingMethod
+		if method == "" {
+			method = models.ShippingMethodStandard
+		}
+		cost, err := s.pricing.ShippingCost(req.ShippingCountry, req.ShippingPostalCode, method, subtotal)
+		if err != nil {
+			return nil, err
+		}
+		shipping = cost
+		shippingMethod = &method
+	}
+	// Digital-only orders have nothing to wrap.
+	req.GiftWrap = req.GiftWrap && hasPhysical
+	var giftWrapFee models.Money
+	if req.GiftWrap {
+		giftWrapFee = s.pricing.GiftWrapFee()
+	}
+	total := s.pricing.Total(subtotal, tax, shipping, giftWrapFee)
 	order := &models.Order{
 		ID:              uuid.New().String(),
 		UserID:          userID,
+		StoreID:         storeID,
 		Status:          models.OrderStatusPending,
 		Total:           total,
 		Subtotal:        subtotal,
 		Tax:             tax,
+		TaxIncluded:     s.pricing.TaxIncluded(),
 		Shipping:        shipping,
+		ShippingMethod:  shippingMethod,
 		ShippingAddress: req.ShippingAddress,
 		BillingAddress:  req.BillingAddress,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 	}
-	err = s.orderRepo.CreateOrder(order)
-	if err != nil {
-		return nil, err
+	if note := strings.TrimSpace(req.CustomerNote); note != "" {
+		order.CustomerNote = &note
 	}
-	for i := range orderItems {
-		orderItems[i].OrderID = order.ID
-		err = s.orderRepo.CreateOrderItem(&orderItems[i])
-		if err != nil {
-			return nil, err
-		}
-	}
-	err = s.cartRepo.ClearUserCart(userID)
-	if err != nil {
+	applyGiftOptions(order, req, giftWrapFee)
+	// The stock is taken in the same transaction, so a checkout racing
+	// another for the last units fails here rather than overselling.
+	if err := s.orderRepo.CreateFromCart(order, orderItems, cartVersion); err != nil {
 		return nil, err
 	}
+	utils.CacheInvalidatePrefix("products:")
 	orderItemsWithProduct := make([]models.OrderItemWithProduct, len(orderItems))
 	for i, item := range orderItems {
 		orderItemsWithProduct[i] = models.OrderItemWithProduct{
@@ -117,20 +267,179 @@ func (s *OrderService) CreateOrder(userID string, req models.OrderCreateRequest)
 		Order:      *order,
 		OrderItems: orderItemsWithProduct,
 	}
+	s.publishOrderFeed(orderWithItems)
+	s.jobs.EnqueueFor(req.RequestID, "order_confirmation:"+order.ID, func() error {
+		return s.sendConfirmation(orderWithItems)
+	})
 	return orderWithItems, nil
 }
-func (s *OrderService) UpdateOrderStatus(orderID string, status models.OrderStatus) (*models.Order, error) {
+// ResendConfirmation sends the confirmation email of an order to its
+// customer again. Only the customer or an admin may ask for it. When the
+// per-order limit is reached it fails with "confirmation resend limit
+// reached" and returns the time the limit resets.
+func (s *OrderService) ResendConfirmation(orderID, userID, storeID string, admin bool) (time.Time, error) {
+	var order *models.OrderWithItems
+	var err error
+	if admin {
+		order, err = s.GetOrderForAdmin(orderID, storeID)
+	} else {
+		order, err = s.GetOrderByID(orderID, userID, storeID)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("order not found")
+	}
+	if order.Status == models.OrderStatusCancelled {
+		return time.Time{}, fmt.Errorf("order has no confirmation to send")
+	}
+	if s.resendLimit > 0 {
+		count, resetAt := utils.GetCache("order_confirmation").Increment(orderID, s.resendWindow)
+		if count > s.resendLimit {
+			return resetAt, fmt.Errorf("confirmation resend limit reached")
+		}
+	}
+	return time.Time{}, s.sendConfirmation(order)
+}
+func (s *OrderService) sendConfirmation(order *models.OrderWithItems) error {
+	user, err := s.userRepo.GetByID(order.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load customer: %w", err)
+	}
+	subject, body := orderConfirmationEmail(order)
+	if err := s.emailService.Send(user.Email, subject, body); err != nil {
+		return fmt.Errorf("failed to send order confirmation: %w", err)
+	}
+	return nil
+}
+func orderConfirmationEmail(order *models.OrderWithItems) (string, string) {
+	var body strings.Builder
+	body.WriteString("Thank you for your order.\n\n")
+	fmt.Fprintf(&body, "Order: %s\nPlaced: %s\n\n", order.ID, order.CreatedAt.Format("2006-01-02 15:04 MST"))
+	for _, item := range order.OrderItems {
+		quantity := item.Quantity.String()
+		if item.Unit != "" && item.Unit != models.UnitEach {
+			quantity += " " + item.Unit
+		}
+		fmt.Fprintf(&body, "%s x %s  %s\n", quantity, item.ProductName, item.Price.MulQuantity(item.Quantity))
+		for _, component := range item.Components {
+			fmt.Fprintf(&body, "    includes %s x %s\n", component.Quantity, component.ProductName)
+		}
+	}
+	fmt.Fprintf(&body, "\nSubtotal: %s\n", order.Subtotal)
+	if order.TaxIncluded {
+		fmt.Fprintf(&body, "Includes tax: %s\n", order.Tax)
+	} else {
+		fmt.Fprintf(&body, "Tax: %s\n", order.Tax)
+	}
+	fmt.Fprintf(&body, "Shipping: %s\n", order.Shipping)
+	if order.GiftWrap {
+		fmt.Fprintf(&body, "Gift wrap: %s\n", order.GiftWrapFee)
+	}
+	fmt.Fprintf(&body, "Total: %s\n", order.Total)
+	if order.ShippingAddress != "" {
+		fmt.Fprintf(&body, "\nShipping to:\n%s\n", order.ShippingAddress)
+	}
+	return "Order confirmation " + order.ID, body.String()
+}
+// releaseStock puts back the stock taken by a cancelled order. Orders placed
+// before sales were recorded have nothing to release.
+func (s *OrderService) releaseStock(orderID string) {
+	if _, err := s.inventoryRepo.Revert(orderID, models.InventoryReasonSale, models.InventoryReasonCancellation); err != nil {
+		utils.Warn("failed to release stock of cancelled order", "order_id", orderID, "error", err.Error())
+	}
+	utils.CacheInvalidatePrefix("products:")
+}
+// publishOrderFeed sends a summary of a new order to admins subscribed to the
+// live order feed. Only the customer's display name is included.
+func (s *OrderService) publishOrderFeed(order *models.OrderWithItems) {
+	if s.hub == nil {
+		return
+	}
+	itemCount := 0
+	for _, item := range order.OrderItems {
+		// A measured line such as 1.5 kg counts as a single item.
+		if models.IsMeasuredUnit(item.Unit) {
+			itemCount++
+		} else {
+			itemCount += item.Quantity.Int()
+		}
+	}
+	customer := "Customer"
+	if user, err := s.userRepo.GetByID(order.UserID); err == nil && user.Name != nil && strings.TrimSpace(*user.Name) != "" {
+		customer = strings.TrimSpace(*user.Name)
+	}
+	s.hub.SendOrderFeed(websocket.OrderFeedData{
+		OrderID:   order.ID,
+		StoreID:   order.StoreID,
+		Total:     order.Total.Float64(),
+		ItemCount: itemCount,
+		Customer:  customer,
+		CreatedAt: order.CreatedAt,
+	})
+}
+// UpdateOrderStatus applies one status change under the same transition
+// rules and row lock as BulkUpdateStatus.
+func (s *OrderService) UpdateOrderStatus(orderID string, status models.OrderStatus) (*models.Order, models.OrderStatus, error) {
+	if !status.Valid() {
+		return nil, "", fmt.Errorf("invalid order status")
+	}
+	results, err := s.orderRepo.BulkUpdateStatus([]string{orderID}, status, true, transitionCheck(status))
+	if err != nil {
+		return nil, "", err
+	}
+	if !results[0].Applied {
+		return nil, "", fmt.Errorf("%s", results[0].Error)
+	}
+	if status == models.OrderStatusCancelled {
+		s.releaseStock(orderID)
+	}
 	order, err := s.orderRepo.GetOrderByID(orderID)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	order.Status = status
-	order.UpdatedAt = time.Now()
-	err = s.orderRepo.UpdateOrder(order)
+	return order, results[0].PreviousStatus, nil
+}
+func transitionCheck(next models.OrderStatus) func(current models.OrderStatus) error {
+	return func(current models.OrderStatus) error {
+		if !current.CanTransitionTo(next) {
+			return fmt.Errorf("cannot change status from %s to %s", current, next)
+		}
+		return nil
+	}
+}
+// BulkUpdateStatus applies one status to many orders, validating each against
+// the order status transitions. Duplicate ids are processed once. Customers
+// of applied orders are notified over WebSocket.
+func (s *OrderService) BulkUpdateStatus(req models.BulkOrderStatusRequest) ([]models.BulkOrderStatusResult, error) {
+	if !req.Status.Valid() {
+		return nil, fmt.Errorf("invalid order status")
+	}
+	seen := make(map[string]bool, len(req.OrderIDs))
+	orderIDs := make([]string, 0, len(req.OrderIDs))
+	for _, id := range req.OrderIDs {
+		if !seen[id] {
+			seen[id] = true
+			orderIDs = append(orderIDs, id)
+		}
+	}
+	results, err := s.orderRepo.BulkUpdateStatus(orderIDs, req.Status, req.Atomic, transitionCheck(req.Status))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to update order statuses: %w", err)
+	}
+	if req.Status == models.OrderStatusCancelled {
+		for _, result := range results {
+			if result.Applied {
+				s.releaseStock(result.OrderID)
+			}
+		}
 	}
-	return order, nil
+	if s.hub != nil {
+		for _, result := range results {
+			if result.Applied {
+				s.hub.SendOrderUpdate(result.OrderID, string(result.Status), "Your order is now "+strings.ReplaceAll(string(result.Status), "_", " "), result.UserID)
+			}
+		}
+	}
+	return results, nil
 }
 func (s *OrderService) CancelOrder(orderID, userID string) error {
 	order, err := s.orderRepo.GetOrderByID(orderID)
@@ -145,5 +454,48 @@ func (s *OrderService) CancelOrder(orderID, userID string) error {
 	}
 	order.Status = models.OrderStatusCancelled
 	order.UpdatedAt = time.Now()
-	return s.orderRepo.UpdateOrder(order)
+	if err := s.orderRepo.UpdateOrder(order); err != nil {
+		return err
+	}
+	s.releaseStock(orderID)
+	return nil
+}
+// unpaidCancelBatch caps the orders one CancelUnpaidOrders run cancels.
+const unpaidCancelBatch = 100
+// CancelUnpaidOrders is the background job that cancels orders left pending
+// for longer than timeout without a payment, puts their stock back and tells
+// the customer. Orders with a payment under way are left alone.
+func (s *OrderService) CancelUnpaidOrders(timeout time.Duration) error {
+	if timeout <= 0 {
+		return nil
+	}
+	orders, err := s.orderRepo.CancelUnpaid(time.Now().Add(-timeout), unpaidCancelBatch)
+	if err != nil {
+		return fmt.Errorf("failed to cancel unpaid orders: %w", err)
+	}
+	for _, order := range orders {
+		s.releaseStock(order.ID)
+		s.auditService.Record(models.AuditEntry{Action: models.AuditActionOrderAutoCancel, TargetType: "order", TargetID: order.ID},
+			map[string]models.OrderStatus{"status": models.OrderStatusPending},
+			map[string]interface{}{"status": order.Status, "reason": "unpaid", "timeout": timeout.String()})
+		if s.hub != nil {
+			s.hub.SendOrderUpdate(order.ID, string(order.Status), "Your order was cancelled because it was not paid in time", order.UserID)
+		}
+		utils.Info("cancelled unpaid order", "order_id", order.ID, "created_at", order.CreatedAt)
+	}
+	return nil
+}
+// applyGiftOptions treats a gift message or wrapping as a gift order even if
+// the client did not set is_gift explicitly.
+func applyGiftOptions(order *models.Order, req models.OrderCreateRequest, giftWrapFee models.Money) {
+	message := strings.TrimSpace(req.GiftMessage)
+	order.IsGift = req.IsGift || req.GiftWrap || message != ""
+	if !order.IsGift {
+		return
+	}
+	if message != "" {
+		order.GiftMessage = &message
+	}
+	order.GiftWrap = req.GiftWrap
+	order.GiftWrapFee = giftWrapFee
 }
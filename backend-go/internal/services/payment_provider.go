@@ -0,0 +1,342 @@
+﻿package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"ecommerce-backend/internal/models"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/stripe/stripe-go/v78"
+	"github.com/stripe/stripe-go/v78/paymentintent"
+	"github.com/stripe/stripe-go/v78/refund"
+	"github.com/stripe/stripe-go/v78/webhook"
+)
+
+// PaymentProvider is a payment gateway. Amounts are in minor units.
+type PaymentProvider interface {
+	Name() string
+	CreateIntent(req models.ProviderIntentRequest) (*models.ProviderIntent, error)
+	// Confirm returns the current status of an intent.
+	Confirm(intentID string) (models.PaymentStatus, error)
+	// Status returns the status of an intent without acting on it. An intent
+	// that can still be paid is pending.
+	Status(intentID string) (models.PaymentStatus, error)
+	// Cancel voids an intent that has not been paid.
+	Cancel(intentID string) error
+	// Refund refunds amount of a captured intent and returns the refund id.
+	// A repeated non-empty idempotency key returns the first refund instead
+	// of refunding again.
+	Refund(intentID string, amount models.Money, idempotencyKey string, metadata map[string]string) (string, error)
+	// VerifyWebhook checks the signature of a webhook delivery and decodes it.
+	VerifyWebhook(payload []byte, header http.Header) (*models.PaymentEvent, error)
+}
+
+// PaymentProviders holds the enabled providers by name.
+type PaymentProviders struct {
+	defaultName string
+	providers   map[string]PaymentProvider
+}
+
+func NewPaymentProviders(defaultName string, providers ...PaymentProvider) *PaymentProviders {
+	registry := &PaymentProviders{defaultName: defaultName, providers: make(map[string]PaymentProvider)}
+	for _, provider := range providers {
+		registry.providers[provider.Name()] = provider
+	}
+	return registry
+}
+
+// Get returns the named provider; an empty name selects the default.
+func (p *PaymentProviders) Get(name string) (PaymentProvider, error) {
+	if name == "" {
+		name = p.defaultName
+	}
+	provider, ok := p.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown payment provider")
+	}
+	return provider, nil
+}
+
+type StripeProvider struct {
+	intents       paymentintent.Client
+	refunds       refund.Client
+	webhookSecret string
+}
+
+func NewStripeProvider(secretKey, webhookSecret string) *StripeProvider {
+	backend := stripe.GetBackend(stripe.APIBackend)
+	return &StripeProvider{
+		intents:       paymentintent.Client{B: backend, Key: secretKey},
+		refunds:       refund.Client{B: backend, Key: secretKey},
+		webhookSecret: webhookSecret,
+	}
+}
+func (p *StripeProvider) Name() string {
+	return models.PaymentProviderStripe
+}
+func (p *StripeProvider) CreateIntent(req models.ProviderIntentRequest) (*models.ProviderIntent, error) {
+	params := &stripe.PaymentIntentParams{
+		Amount:   stripe.Int64(int64(req.Amount)),
+		Currency: stripe.String(req.Currency),
+		Metadata: req.Metadata,
+	}
+	if req.IdempotencyKey != "" {
+		params.SetIdempotencyKey(req.IdempotencyKey)
+	}
+	pi, err := p.intents.New(params)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ProviderIntent{
+		ID:             pi.ID,
+		ClientSecret:   pi.ClientSecret,
+		Status:         stripeIntentStatus(pi.Status),
+		ProviderStatus: string(pi.Status),
+	}, nil
+}
+func (p *StripeProvider) Confirm(intentID string) (models.PaymentStatus, error) {
+	pi, err := p.intents.Get(intentID, nil)
+	if err != nil {
+		return "", err
+	}
+	return stripeIntentStatus(pi.Status), nil
+}
+func (p *StripeProvider) Status(intentID string) (models.PaymentStatus, error) {
+	pi, err := p.intents.Get(intentID, nil)
+	if err != nil {
+		return "", err
+	}
+	// A declined intent goes back to requires_payment_method and can be
+	// paid again, so it is still open.
+	if pi.Status == stripe.PaymentIntentStatusRequiresPaymentMethod {
+		return models.PaymentStatusPending, nil
+	}
+	return stripeIntentStatus(pi.Status), nil
+}
+func (p *StripeProvider) Cancel(intentID string) error {
+	_, err := p.intents.Cancel(intentID, nil)
+	return err
+}
+func (p *StripeProvider) Refund(intentID string, amount models.Money, idempotencyKey string, metadata map[string]string) (string, error) {
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(intentID),
+		Amount:        stripe.Int64(int64(amount)),
+		Metadata:      metadata,
+	}
+	if idempotencyKey != "" {
+		params.SetIdempotencyKey(idempotencyKey)
+	}
+	r, err := p.refunds.New(params)
+	if err != nil {
+		return "", err
+	}
+	return r.ID, nil
+}
+
+// VerifyWebhook checks the Stripe-Signature header against the webhook
+// secret. Without a secret the payload is accepted unverified, which is only
+// suitable for local development.
+func (p *StripeProvider) VerifyWebhook(payload []byte, header http.Header) (*models.PaymentEvent, error) {
+	var body models.StripeWebhookPayload
+	if p.webhookSecret != "" {
+		event, err := webhook.ConstructEventWithOptions(payload, header.Get("Stripe-Signature"), p.webhookSecret,
+			webhook.ConstructEventOptions{IgnoreAPIVersionMismatch: true})
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook signature")
+		}
+		body.Type = string(event.Type)
+		if event.Data != nil {
+			body.Data = map[string]interface{}{"object": event.Data.Object}
+		}
+	} else if err := json.Unmarshal(payload, &body); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload")
+	}
+	event := &models.PaymentEvent{}
+	switch body.Type {
+	case "payment_intent.succeeded":
+		event.Type = models.PaymentEventSucceeded
+	case "payment_intent.payment_failed":
+		event.Type = models.PaymentEventFailed
+	default:
+		return event, nil
+	}
+	object, _ := body.Data["object"].(map[string]interface{})
+	intentID, _ := object["id"].(string)
+	if intentID == "" {
+		return nil, fmt.Errorf("invalid webhook payload")
+	}
+	event.IntentID = intentID
+	return event, nil
+}
+func stripeIntentStatus(status stripe.PaymentIntentStatus) models.PaymentStatus {
+	switch status {
+	case stripe.PaymentIntentStatusSucceeded:
+		return models.PaymentStatusSucceeded
+	case stripe.PaymentIntentStatusCanceled:
+		return models.PaymentStatusCancelled
+	case stripe.PaymentIntentStatusRequiresPaymentMethod:
+		return models.PaymentStatusFailed
+	default:
+		return models.PaymentStatusPending
+	}
+}
+
+// MockPaymentProvider is an in-memory gateway for development and tests.
+// Intents succeed on confirmation unless SetOutcome says otherwise. Like
+// Stripe, a repeated idempotency key returns the intent or refund first
+// created with it.
+type MockPaymentProvider struct {
+	mu            sync.Mutex
+	intents       map[string]*mockIntent
+	byKey         map[string]string
+	refundsByKey  map[string]string
+	webhookSecret string
+}
+type mockIntent struct {
+	amount   models.Money
+	refunded models.Money
+	status   models.PaymentStatus
+	outcome  models.PaymentStatus
+}
+
+func NewMockPaymentProvider(webhookSecret string) *MockPaymentProvider {
+	return &MockPaymentProvider{intents: make(map[string]*mockIntent), byKey: make(map[string]string), refundsByKey: make(map[string]string), webhookSecret: webhookSecret}
+}
+func (p *MockPaymentProvider) Name() string {
+	return models.PaymentProviderMock
+}
+func (p *MockPaymentProvider) CreateIntent(req models.ProviderIntentRequest) (*models.ProviderIntent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id, ok := p.byKey[req.IdempotencyKey]
+	if !ok {
+		id = "mock_pi_" + uuid.New().String()
+		p.intents[id] = &mockIntent{amount: req.Amount, status: models.PaymentStatusPending, outcome: models.PaymentStatusSucceeded}
+		if req.IdempotencyKey != "" {
+			p.byKey[req.IdempotencyKey] = id
+		}
+	}
+	status := p.intents[id].status
+	return &models.ProviderIntent{
+		ID:             id,
+		ClientSecret:   id + "_secret",
+		Status:         status,
+		ProviderStatus: string(status),
+	}, nil
+}
+
+// SetOutcome sets the status the intent reaches when it is confirmed.
+func (p *MockPaymentProvider) SetOutcome(intentID string, status models.PaymentStatus) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return fmt.Errorf("payment intent not found")
+	}
+	intent.outcome = status
+	return nil
+}
+func (p *MockPaymentProvider) Confirm(intentID string) (models.PaymentStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return "", fmt.Errorf("payment intent not found")
+	}
+	if intent.status == models.PaymentStatusPending {
+		intent.status = intent.outcome
+	}
+	return intent.status, nil
+}
+func (p *MockPaymentProvider) Status(intentID string) (models.PaymentStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return "", fmt.Errorf("payment intent not found")
+	}
+	return intent.status, nil
+}
+func (p *MockPaymentProvider) Cancel(intentID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return fmt.Errorf("payment intent not found")
+	}
+	if intent.status == models.PaymentStatusSucceeded {
+		return fmt.Errorf("payment has already succeeded")
+	}
+	intent.status = models.PaymentStatusCancelled
+	return nil
+}
+func (p *MockPaymentProvider) Refund(intentID string, amount models.Money, idempotencyKey string, metadata map[string]string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if id, ok := p.refundsByKey[idempotencyKey]; ok {
+		return id, nil
+	}
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return "", fmt.Errorf("payment intent not found")
+	}
+	if intent.status != models.PaymentStatusSucceeded {
+		return "", fmt.Errorf("payment has not succeeded")
+	}
+	if amount <= 0 || intent.refunded+amount > intent.amount {
+		return "", fmt.Errorf("refund exceeds captured amount")
+	}
+	intent.refunded += amount
+	id := "mock_re_" + uuid.New().String()
+	if idempotencyKey != "" {
+		p.refundsByKey[idempotencyKey] = id
+	}
+	return id, nil
+}
+
+// MockWebhookBody is the payload the mock provider accepts on its webhook.
+type MockWebhookBody struct {
+	Type     models.PaymentEventType `json:"type"`
+	IntentID string                  `json:"intent_id"`
+}
+
+// SignMockWebhook returns the X-Mock-Signature value for payload.
+func SignMockWebhook(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks the hex HMAC-SHA256 of the body in X-Mock-Signature
+// when a webhook secret is configured.
+func (p *MockPaymentProvider) VerifyWebhook(payload []byte, header http.Header) (*models.PaymentEvent, error) {
+	if p.webhookSecret != "" {
+		expected := SignMockWebhook(payload, p.webhookSecret)
+		if !hmac.Equal([]byte(expected), []byte(header.Get("X-Mock-Signature"))) {
+			return nil, fmt.Errorf("invalid webhook signature")
+		}
+	}
+	var body MockWebhookBody
+	if err := json.Unmarshal(payload, &body); err != nil || body.IntentID == "" {
+		return nil, fmt.Errorf("invalid webhook payload")
+	}
+	switch body.Type {
+	case models.PaymentEventSucceeded, models.PaymentEventFailed:
+	default:
+		return &models.PaymentEvent{}, nil
+	}
+	p.mu.Lock()
+	if intent, ok := p.intents[body.IntentID]; ok && intent.status == models.PaymentStatusPending {
+		intent.status = models.PaymentStatusSucceeded
+		if body.Type == models.PaymentEventFailed {
+			intent.status = models.PaymentStatusFailed
+		}
+	}
+	p.mu.Unlock()
+	return &models.PaymentEvent{Type: body.Type, IntentID: body.IntentID}, nil
+}
@@ -3,41 +3,97 @@
 import (
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
+	"ecommerce-backend/internal/websocket"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/stripe/stripe-go/v78"
-	"github.com/stripe/stripe-go/v78/paymentintent"
 )
 
 type PaymentService struct {
 	paymentRepo *repositories.PaymentRepository
 	orderRepo   *repositories.OrderRepository
+	providers   *PaymentProviders
+	downloads   *DownloadService
+	hub         *websocket.Hub
+	// In test mode every new payment goes through the sandbox, which
+	// settles it by delivering a signed webhook to itself.
+	testMode      bool
+	sandbox       *MockPaymentProvider
+	sandboxSecret string
 }
 
-func NewPaymentService(paymentRepo *repositories.PaymentRepository, orderRepo *repositories.OrderRepository) *PaymentService {
+func NewPaymentService(paymentRepo *repositories.PaymentRepository, orderRepo *repositories.OrderRepository, providers *PaymentProviders, downloads *DownloadService, hub *websocket.Hub, testMode bool) *PaymentService {
+	sandboxSecret := uuid.New().String()
 	return &PaymentService{
-		paymentRepo: paymentRepo,
-		orderRepo:   orderRepo,
+		paymentRepo:   paymentRepo,
+		orderRepo:     orderRepo,
+		providers:     providers,
+		downloads:     downloads,
+		hub:           hub,
+		testMode:      testMode,
+		sandbox:       NewMockPaymentProvider(sandboxSecret),
+		sandboxSecret: sandboxSecret,
 	}
 }
+// CreatePaymentIntent opens a payment intent. For an order it is
+// idempotent: the order's open intent is returned while it still matches the
+// request, and cancelled before a new one is opened when it does not.
 func (s *PaymentService) CreatePaymentIntent(userID string, req models.PaymentIntentRequest) (*models.PaymentIntentResponse, error) {
-	amountInCents := int64(req.Amount * 100)
-	params := &stripe.PaymentIntentParams{
-		Amount:   stripe.Int64(amountInCents),
-		Currency: stripe.String(req.Currency),
-		Metadata: map[string]string{
-			"user_id": userID,
-		},
+	var provider PaymentProvider = s.sandbox
+	if !s.testMode {
+		var err error
+		if provider, err = s.providers.Get(req.Provider); err != nil {
+			return nil, err
+		}
+	}
+	metadata := map[string]string{
+		"user_id": userID,
+	}
+	// The request ID shows up with the intent in the provider's dashboard.
+	if req.RequestID != "" {
+		metadata["request_id"] = req.RequestID
+	}
+	var order *models.Order
+	if req.OrderID != nil {
+		var err error
+		if order, err = s.payableOrder(userID, *req.OrderID); err != nil {
+			return nil, err
+		}
+		// An order is charged its own total; the client's amount is ignored.
+		req.Amount = order.Total
+	}
+	intentReq := models.ProviderIntentRequest{
+		Amount:   req.Amount,
+		Currency: req.Currency,
+		Metadata: metadata,
 	}
 	if req.OrderID != nil {
-		params.Metadata["order_id"] = *req.OrderID
+		metadata["order_id"] = *req.OrderID
+		open, err := s.reuseOrderIntent(order, req, provider)
+		if err != nil || open != nil {
+			return open, err
+		}
+		// Retries that race past the lookup share one provider intent.
+		attempt, err := s.paymentRepo.CountOrderPayments(*req.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		intentReq.IdempotencyKey = fmt.Sprintf("order-%s-%d-%d-%s", *req.OrderID, attempt, req.Amount, strings.ToLower(req.Currency))
 	}
-	pi, err := paymentintent.New(params)
+	intent, err := provider.CreateIntent(intentReq)
 	if err != nil {
 		return nil, err
 	}
+	if s.testMode {
+		if err := s.sandbox.SetOutcome(intent.ID, sandboxOutcome(req)); err != nil {
+			return nil, err
+		}
+	}
 	payment := &models.Payment{
 		ID:              uuid.New().String(),
 		UserID:          userID,
@@ -45,28 +101,129 @@ func (s *PaymentService) CreatePaymentIntent(userID string, req models.PaymentIn
 		Amount:          req.Amount,
 		Currency:        req.Currency,
 		Status:          models.PaymentStatusPending,
-		PaymentIntentID: pi.ID,
-		ClientSecret:    pi.ClientSecret,
+		Provider:        provider.Name(),
+		TestMode:        s.testMode,
+		PaymentIntentID: intent.ID,
+		ClientSecret:    intent.ClientSecret,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 	}
 	err = s.paymentRepo.CreatePayment(payment)
+	if err != nil && err.Error() == "order has an open payment" {
+		// A concurrent request opened the order's payment first.
+		open, openErr := s.paymentRepo.GetOpenOrderPayment(*req.OrderID)
+		if openErr != nil || open == nil {
+			return nil, err
+		}
+		if open.PaymentIntentID != intent.ID {
+			if err := provider.Cancel(intent.ID); err != nil {
+				utils.Error("failed to cancel duplicate payment intent", "intent_id", intent.ID, "error", err.Error())
+			}
+		}
+		return openIntentResponse(open), nil
+	}
+	if err != nil && err.Error() == "order is not awaiting payment" {
+		// The order was cancelled while the intent was being created.
+		if err := provider.Cancel(intent.ID); err != nil {
+			utils.Error("failed to cancel payment intent of cancelled order", "intent_id", intent.ID, "error", err.Error())
+		}
+		return nil, err
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &models.PaymentIntentResponse{
-		ID:           pi.ID,
-		ClientSecret: pi.ClientSecret,
-		Amount:       amountInCents,
+		ID:           intent.ID,
+		ClientSecret: intent.ClientSecret,
+		Amount:       int64(req.Amount),
 		Currency:     req.Currency,
-		Status:       string(pi.Status),
+		Status:       intent.ProviderStatus,
+		Provider:     provider.Name(),
+		TestMode:     s.testMode,
 	}, nil
 }
-func (s *PaymentService) ConfirmPayment(userID string, req models.PaymentConfirmRequest) (*models.Payment, error) {
-	pi, err := paymentintent.Get(req.PaymentIntentID, nil)
+// payableOrder returns the user's order if it is awaiting payment.
+func (s *PaymentService) payableOrder(userID, orderID string) (*models.Order, error) {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil || order.UserID != userID {
+		return nil, fmt.Errorf("order not found")
+	}
+	if order.Status != models.OrderStatusPending {
+		return nil, fmt.Errorf("order is not awaiting payment")
+	}
+	return order, nil
+}
+// reuseOrderIntent returns the order's open intent if it is still for the
+// order's total in req's currency and provider. An open intent that no longer
+// matches is cancelled; nil means a new intent is needed.
+func (s *PaymentService) reuseOrderIntent(order *models.Order, req models.PaymentIntentRequest, provider PaymentProvider) (*models.PaymentIntentResponse, error) {
+	open, err := s.paymentRepo.GetOpenOrderPayment(order.ID)
+	if err != nil || open == nil {
+		return nil, err
+	}
+	openProvider, err := s.paymentProvider(open)
 	if err != nil {
 		return nil, err
 	}
+	status, err := openProvider.Status(open.PaymentIntentID)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case models.PaymentStatusPending:
+		if open.Amount == order.Total && strings.EqualFold(open.Currency, req.Currency) && openProvider.Name() == provider.Name() {
+			return openIntentResponse(open), nil
+		}
+		if err := openProvider.Cancel(open.PaymentIntentID); err != nil {
+			return nil, fmt.Errorf("failed to cancel stale payment intent: %w", err)
+		}
+		status = models.PaymentStatusCancelled
+	case models.PaymentStatusSucceeded:
+		// The success webhook has not arrived yet.
+		if err := s.handlePaymentSucceeded(open.PaymentIntentID); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("order is not awaiting payment")
+	}
+	open.Status = status
+	open.UpdatedAt = time.Now()
+	if err := s.paymentRepo.UpdatePayment(open); err != nil {
+		return nil, err
+	}
+	s.notifyPaymentUpdate(open)
+	return nil, nil
+}
+func openIntentResponse(payment *models.Payment) *models.PaymentIntentResponse {
+	return &models.PaymentIntentResponse{
+		ID:           payment.PaymentIntentID,
+		ClientSecret: payment.ClientSecret,
+		Amount:       int64(payment.Amount),
+		Currency:     payment.Currency,
+		Status:       string(payment.Status),
+		Provider:     payment.Provider,
+		TestMode:     payment.TestMode,
+		Reused:       true,
+	}
+}
+// sandboxOutcome is the result a test-mode payment settles with: the
+// requested test_outcome, else a decline for amounts ending in .02.
+func sandboxOutcome(req models.PaymentIntentRequest) models.PaymentStatus {
+	if req.TestOutcome != "" {
+		return models.PaymentStatus(req.TestOutcome)
+	}
+	if req.Amount%100 == models.TestDeclineCents {
+		return models.PaymentStatusFailed
+	}
+	return models.PaymentStatusSucceeded
+}
+// paymentProvider returns the provider that holds payment's intent.
+func (s *PaymentService) paymentProvider(payment *models.Payment) (PaymentProvider, error) {
+	if payment.TestMode {
+		return s.sandbox, nil
+	}
+	return s.providers.Get(payment.Provider)
+}
+func (s *PaymentService) ConfirmPayment(userID string, req models.PaymentConfirmRequest) (*models.Payment, error) {
 	payment, err := s.paymentRepo.GetPaymentByIntentID(req.PaymentIntentID)
 	if err != nil {
 		return nil, err
@@ -74,76 +231,234 @@ func (s *PaymentService) ConfirmPayment(userID string, req models.PaymentConfirm
 	if payment.UserID != userID {
 		return nil, fmt.Errorf("payment not found")
 	}
-	switch pi.Status {
-	case stripe.PaymentIntentStatusSucceeded:
-		payment.Status = models.PaymentStatusSucceeded
-	case stripe.PaymentIntentStatusCanceled:
-		payment.Status = models.PaymentStatusCancelled
-	case stripe.PaymentIntentStatusRequiresPaymentMethod:
-		payment.Status = models.PaymentStatusFailed
-	default:
-		payment.Status = models.PaymentStatusPending
+	provider, err := s.paymentProvider(payment)
+	if err != nil {
+		return nil, err
+	}
+	status, err := provider.Confirm(req.PaymentIntentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.TestMode && payment.Status == models.PaymentStatusPending {
+		if err := s.settleSandboxPayment(req.PaymentIntentID, status); err != nil {
+			return nil, err
+		}
+		return s.paymentRepo.GetPaymentByIntentID(req.PaymentIntentID)
 	}
+	payment.Status = status
 	payment.UpdatedAt = time.Now()
 	err = s.paymentRepo.UpdatePayment(payment)
 	if err != nil {
 		return nil, err
 	}
+	var orderErr error
 	if payment.Status == models.PaymentStatusSucceeded && payment.OrderID != nil {
-		order, err := s.orderRepo.GetOrderByID(*payment.OrderID)
-		if err == nil {
-			order.Status = models.OrderStatusProcessing
-			order.PaymentIntent = &payment.PaymentIntentID
-			order.UpdatedAt = time.Now()
-			s.orderRepo.UpdateOrder(order)
-		}
+		orderErr = s.markOrderPaid(*payment.OrderID, payment.PaymentIntentID)
+	}
+	s.notifyPaymentUpdate(payment)
+	if orderErr != nil {
+		return nil, orderErr
 	}
 	return payment, nil
 }
-func (s *PaymentService) GetUserPayments(userID string) ([]models.Payment, error) {
-	return s.paymentRepo.GetUserPayments(userID)
+// markOrderPaid moves a pending order to processing once its payment
+// succeeds and issues download links for its digital products. An order with
+// nothing to ship is delivered once its links exist; when issuing them fails
+// it stays in processing and the error is returned, so a repeated payment
+// event can deliver it. A payment that succeeds on a cancelled order is
+// refunded instead.
+func (s *PaymentService) markOrderPaid(orderID, paymentIntentID string) error {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return err
+	}
+	if order.Status == models.OrderStatusCancelled {
+		return s.refundCancelledOrder(order, paymentIntentID)
+	}
+	hasPhysical := true
+	var issueErr error
+	if s.downloads != nil {
+		if hasPhysical, issueErr = s.downloads.IssueForOrder(order); issueErr != nil {
+			utils.Error("failed to issue download links", "order_id", orderID, "error", issueErr.Error())
+		}
+	}
+	previousStatus := order.Status
+	order.PaymentIntent = &paymentIntentID
+	order.UpdatedAt = time.Now()
+	if order.Status == models.OrderStatusPending {
+		order.Status = models.OrderStatusProcessing
+	}
+	if order.Status == models.OrderStatusProcessing && !hasPhysical && issueErr == nil {
+		order.Status = models.OrderStatusDelivered
+	}
+	if err := s.orderRepo.UpdateOrder(order); err != nil {
+		utils.Error("failed to update paid order", "order_id", orderID, "error", err.Error())
+		return err
+	}
+	if issueErr != nil {
+		return issueErr
+	}
+	if order.Status == models.OrderStatusDelivered && previousStatus != models.OrderStatusDelivered && s.hub != nil {
+		s.hub.SendOrderUpdate(order.ID, string(order.Status), "Your downloads are ready", order.UserID)
+	}
+	return nil
+}
+// refundCancelledOrder refunds a payment that succeeded after its order
+// was cancelled, e.g. by the unpaid-order sweep, since the order will not
+// be fulfilled.
+func (s *PaymentService) refundCancelledOrder(order *models.Order, paymentIntentID string) error {
+	payment, err := s.paymentRepo.GetPaymentByIntentID(paymentIntentID)
+	if err != nil {
+		return err
+	}
+	provider, err := s.paymentProvider(payment)
+	if err != nil {
+		return err
+	}
+	refundID, err := provider.Refund(paymentIntentID, payment.Amount, "cancelled-order-"+paymentIntentID, map[string]string{
+		"order_id": order.ID,
+		"reason":   "order_cancelled",
+	})
+	if err != nil {
+		utils.Error("failed to refund payment of cancelled order", "order_id", order.ID, "intent_id", paymentIntentID, "error", err.Error())
+		return fmt.Errorf("failed to refund payment: %w", err)
+	}
+	utils.Warn("refunded payment of cancelled order", "order_id", order.ID, "intent_id", paymentIntentID, "refund_id", refundID)
+	if s.hub != nil {
+		s.hub.SendOrderUpdate(order.ID, string(order.Status), "Your payment was refunded because the order had been cancelled", order.UserID)
+	}
+	return nil
+}
+// RefundOrder refunds amount of the order's captured payment. Callers pass
+// an idempotency key that identifies the refund so a retry cannot refund
+// twice.
+func (s *PaymentService) RefundOrder(orderID string, amount models.Money, idempotencyKey string) (string, error) {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return "", fmt.Errorf("order not found")
+	}
+	if order.PaymentIntent == nil || *order.PaymentIntent == "" {
+		return "", fmt.Errorf("order has no captured payment")
+	}
+	var provider PaymentProvider
+	if payment, err := s.paymentRepo.GetPaymentByIntentID(*order.PaymentIntent); err == nil {
+		provider, err = s.paymentProvider(payment)
+		if err != nil {
+			return "", err
+		}
+	} else if provider, err = s.providers.Get(""); err != nil {
+		return "", err
+	}
+	refundID, err := provider.Refund(*order.PaymentIntent, amount, idempotencyKey, map[string]string{
+		"order_id": orderID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to refund payment: %w", err)
+	}
+	return refundID, nil
 }
-func (s *PaymentService) HandleWebhook(payload models.StripeWebhookPayload) error {
-	switch payload.Type {
-	case "payment_intent.succeeded":
-		return s.handlePaymentIntentSucceeded(payload)
-	case "payment_intent.payment_failed":
-		return s.handlePaymentIntentFailed(payload)
+func (s *PaymentService) GetPaymentHistory(userID string, query models.PaymentHistoryQuery) ([]models.PaymentHistoryEntry, int, error) {
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return nil, 0, fmt.Errorf("from must be before to")
+	}
+	offset := (query.Page - 1) * query.Limit
+	return s.paymentRepo.ListUserPayments(userID, query, query.Limit, offset)
+}
+// HandleWebhook verifies a webhook delivery with the named provider and
+// applies the payment event it carries.
+func (s *PaymentService) HandleWebhook(providerName string, payload []byte, header http.Header) error {
+	provider, err := s.providers.Get(providerName)
+	if err != nil || providerName == "" {
+		return fmt.Errorf("unknown payment provider")
+	}
+	event, err := provider.VerifyWebhook(payload, header)
+	if err != nil {
+		return err
+	}
+	return s.applyPaymentEvent(event)
+}
+// settleSandboxPayment delivers a signed sandbox webhook for a confirmed
+// test payment so it takes the same path as a real gateway notification.
+func (s *PaymentService) settleSandboxPayment(intentID string, status models.PaymentStatus) error {
+	eventType := models.PaymentEventSucceeded
+	switch status {
+	case models.PaymentStatusSucceeded:
+	case models.PaymentStatusFailed:
+		eventType = models.PaymentEventFailed
 	default:
 		return nil
 	}
-}
-func (s *PaymentService) handlePaymentIntentSucceeded(payload models.StripeWebhookPayload) error {
-	paymentIntentData, ok := payload.Data["object"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid payment intent data")
+	payload, err := json.Marshal(MockWebhookBody{Type: eventType, IntentID: intentID})
+	if err != nil {
+		return err
 	}
-	paymentIntentID, ok := paymentIntentData["id"].(string)
-	if !ok {
-		return fmt.Errorf("invalid payment intent ID")
+	header := http.Header{}
+	header.Set("X-Mock-Signature", SignMockWebhook(payload, s.sandboxSecret))
+	event, err := s.sandbox.VerifyWebhook(payload, header)
+	if err != nil {
+		return err
 	}
+	return s.applyPaymentEvent(event)
+}
+func (s *PaymentService) applyPaymentEvent(event *models.PaymentEvent) error {
+	switch event.Type {
+	case models.PaymentEventSucceeded:
+		return s.handlePaymentSucceeded(event.IntentID)
+	case models.PaymentEventFailed:
+		return s.handlePaymentFailed(event.IntentID)
+	default:
+		return nil
+	}
+}
+func (s *PaymentService) handlePaymentSucceeded(paymentIntentID string) error {
 	payment, err := s.paymentRepo.GetPaymentByIntentID(paymentIntentID)
 	if err != nil {
 		return err
 	}
 	payment.Status = models.PaymentStatusSucceeded
 	payment.UpdatedAt = time.Now()
-	return s.paymentRepo.UpdatePayment(payment)
-}
-func (s *PaymentService) handlePaymentIntentFailed(payload models.StripeWebhookPayload) error {
-	paymentIntentData, ok := payload.Data["object"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid payment intent data")
+	if err := s.paymentRepo.UpdatePayment(payment); err != nil {
+		return err
 	}
-	paymentIntentID, ok := paymentIntentData["id"].(string)
-	if !ok {
-		return fmt.Errorf("invalid payment intent ID")
+	var orderErr error
+	if payment.OrderID != nil {
+		orderErr = s.markOrderPaid(*payment.OrderID, payment.PaymentIntentID)
 	}
+	s.notifyPaymentUpdate(payment)
+	return orderErr
+}
+func (s *PaymentService) handlePaymentFailed(paymentIntentID string) error {
 	payment, err := s.paymentRepo.GetPaymentByIntentID(paymentIntentID)
 	if err != nil {
 		return err
 	}
 	payment.Status = models.PaymentStatusFailed
 	payment.UpdatedAt = time.Now()
-	return s.paymentRepo.UpdatePayment(payment)
+	if err := s.paymentRepo.UpdatePayment(payment); err != nil {
+		return err
+	}
+	s.notifyPaymentUpdate(payment)
+	return nil
+}
+
+func (s *PaymentService) notifyPaymentUpdate(payment *models.Payment) {
+	if s.hub == nil {
+		return
+	}
+	var message string
+	switch payment.Status {
+	case models.PaymentStatusSucceeded:
+		message = "Your payment was successful"
+	case models.PaymentStatusFailed:
+		message = "Your payment failed. Please try another payment method"
+	case models.PaymentStatusCancelled:
+		message = "Your payment was cancelled"
+	default:
+		return
+	}
+	orderID := ""
+	if payment.OrderID != nil {
+		orderID = *payment.OrderID
+	}
+	s.hub.SendPaymentUpdate(payment.ID, orderID, string(payment.Status), message, payment.UserID)
 }
@@ -2,22 +2,49 @@
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strings"
+	"time"
+	"ecommerce-backend/internal/config"
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
 )
 type ProductService struct {
 	productRepo *repositories.ProductRepository
 	categoryRepo *repositories.CategoryRepository
 	reviewRepo   *repositories.ReviewRepository
+	priceHistoryRepo *repositories.PriceHistoryRepository
+	imageRepo        *repositories.ProductImageRepository
+	inventoryRepo    *repositories.InventoryRepository
+	uploadRepo       *repositories.UploadRepository
+	auditService     *AuditService
+	translations     *TranslationService
+	catalog          config.CatalogConfig
 }
-func NewProductService(productRepo *repositories.ProductRepository, categoryRepo *repositories.CategoryRepository, reviewRepo *repositories.ReviewRepository) *ProductService {
+func NewProductService(productRepo *repositories.ProductRepository, categoryRepo *repositories.CategoryRepository, reviewRepo *repositories.ReviewRepository, priceHistoryRepo *repositories.PriceHistoryRepository, imageRepo *repositories.ProductImageRepository, inventoryRepo *repositories.InventoryRepository, uploadRepo *repositories.UploadRepository, auditService *AuditService, translations *TranslationService, catalog config.CatalogConfig) *ProductService {
 	return &ProductService{
-		productRepo:  productRepo,
-		categoryRepo: categoryRepo,
-		reviewRepo:   reviewRepo,
+		productRepo:      productRepo,
+		categoryRepo:     categoryRepo,
+		reviewRepo:       reviewRepo,
+		priceHistoryRepo: priceHistoryRepo,
+		imageRepo:        imageRepo,
+		inventoryRepo:    inventoryRepo,
+		uploadRepo:       uploadRepo,
+		auditService:     auditService,
+		translations:     translations,
+		catalog:          catalog,
 	}
 }
+// checkDigitalFile requires a product's file to be a private upload, which
+// is served only through order download links and never publicly.
+func (s *ProductService) checkDigitalFile(file string) error {
+	upload, err := s.uploadRepo.GetByFilename(file)
+	if err != nil || !upload.Private {
+		return fmt.Errorf("digital file must be a private upload")
+	}
+	return nil
+}
 func (s *ProductService) CreateProduct(req models.ProductCreateRequest) (*models.ProductWithCategory, error) {
 	product := &models.Product{
 		ID:          generateID(),
@@ -30,18 +57,167 @@ func (s *ProductService) CreateProduct(req models.ProductCreateRequest) (*models
 		InStock:     req.Stock > 0,
 		Stock:       req.Stock,
 		Featured:    req.Featured,
+		ProductType: models.ProductTypePhysical,
+		Unit:        models.UnitEach,
 		CategoryID:  req.CategoryID,
 	}
+	if req.ProductType != "" {
+		product.ProductType = models.ProductType(req.ProductType)
+	}
+	if req.Unit != "" {
+		product.Unit = req.Unit
+	}
+	if file := strings.TrimSpace(req.DigitalFile); file != "" {
+		if err := s.checkDigitalFile(file); err != nil {
+			return nil, err
+		}
+		product.DigitalFile = &file
+	}
+	product.SKU = optionalIdentifier(req.SKU)
+	product.Barcode = optionalIdentifier(req.Barcode)
 	if err := s.productRepo.Create(product); err != nil {
+		if isProductIdentifierConflict(err) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to create product: %w", err)
 	}
-	return s.GetProductWithCategory(product.ID)
+	if err := s.priceHistoryRepo.Record(product.ID, product.Price); err != nil {
+		utils.Warn("failed to record price history", "product_id", product.ID, "error", err.Error())
+	}
+	if product.Stock != 0 {
+		movement := &models.InventoryMovement{ProductID: product.ID, Delta: product.Stock, StockAfter: product.Stock, Reason: models.InventoryReasonInitial}
+		if err := s.inventoryRepo.Record(movement); err != nil {
+			utils.Warn("failed to record opening stock", "product_id", product.ID, "error", err.Error())
+		}
+	}
+	if len(req.Images) > 0 {
+		if err := s.imageRepo.Replace(product.ID, productImagesFromURLs(product.ID, req.Images)); err != nil {
+			return nil, fmt.Errorf("failed to save product images: %w", err)
+		}
+	}
+	utils.CacheInvalidatePrefix("products:")
+	return s.GetProductWithCategory(product.StoreID, product.ID)
+}
+// optionalIdentifier trims a SKU or barcode; blank values are stored as NULL
+// so they do not collide in the unique indexes.
+func optionalIdentifier(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+func isProductIdentifierConflict(err error) bool {
+	return err.Error() == "sku already exists" || err.Error() == "barcode already exists"
+}
+func (s *ProductService) ResolveLocale(requested, acceptLanguage string) string {
+	return s.translations.ResolveLocale(requested, acceptLanguage)
+}
+func (s *ProductService) GetProduct(storeID, id, locale string) (*models.ProductWithCategory, error) {
+	product, err := s.GetProductWithCategory(storeID, id)
+	if err != nil {
+		return nil, err
+	}
+	s.translations.LocalizeProducts([]*models.Product{&product.Product}, locale)
+	s.translations.LocalizeCategories([]*models.Category{product.Category}, locale)
+	return product, nil
+}
+// GetProductsByIDs hydrates a cart or order view in one query. Products come
+// back in the order of ids, with duplicates collapsed, and ids with no
+// product in the store are listed as missing.
+func (s *ProductService) GetProductsByIDs(storeID string, ids []string, locale string) (*models.ProductBatchResponse, error) {
+	unique := uniqueProductIDs(ids)
+	if len(unique) > models.MaxProductBatchSize {
+		return nil, fmt.Errorf("too many product ids")
+	}
+	found, err := s.productRepo.GetByIDs(storeID, unique)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+	byID := make(map[string]*models.ProductWithCategory, len(found))
+	for i := range found {
+		byID[found[i].ID] = &found[i]
+	}
+	response := &models.ProductBatchResponse{
+		Products: make([]models.ProductWithCategory, 0, len(found)),
+		Missing:  []string{},
+	}
+	for _, id := range unique {
+		if product, ok := byID[id]; ok {
+			response.Products = append(response.Products, *product)
+		} else {
+			response.Missing = append(response.Missing, id)
+		}
+	}
+	products := make([]*models.Product, len(response.Products))
+	categories := make([]*models.Category, 0, len(response.Products))
+	for i := range response.Products {
+		products[i] = &response.Products[i].Product
+		if response.Products[i].Category != nil {
+			categories = append(categories, response.Products[i].Category)
+		}
+	}
+	s.translations.LocalizeProducts(products, locale)
+	s.translations.LocalizeCategories(categories, locale)
+	return response, nil
+}
+// CheckStock reports the current availability of each requested product in
+// request order. It reads stock directly, bypassing the product cache.
+// Stock is taken when an order is placed, so it already excludes the
+// quantities held by unpaid orders.
+func (s *ProductService) CheckStock(storeID string, ids []string) ([]models.StockCheckItem, error) {
+	unique := uniqueProductIDs(ids)
+	if len(unique) > models.MaxProductBatchSize {
+		return nil, fmt.Errorf("too many product ids")
+	}
+	available, err := s.productRepo.GetAvailableStock(storeID, unique)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check stock: %w", err)
+	}
+	items := make([]models.StockCheckItem, len(unique))
+	for i, id := range unique {
+		items[i] = models.StockCheckItem{ProductID: id, Availability: models.StockUnavailable}
+		if stock, ok := available[id]; ok {
+			items[i].Available = stock
+			items[i].Availability = models.StockOutOfStock
+			if stock > 0 {
+				items[i].Availability = models.StockInStock
+			}
+		}
+	}
+	return items, nil
+}
+// uniqueProductIDs trims ids and drops blanks and repeats, keeping order.
+func uniqueProductIDs(ids []string) []string {
+	unique := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
+// GetProductBySKU and GetProductByBarcode serve warehouse and POS lookups.
+func (s *ProductService) GetProductBySKU(storeID, sku, locale string) (*models.ProductWithCategory, error) {
+	product, err := s.productRepo.GetBySKU(storeID, sku)
+	if err != nil {
+		return nil, fmt.Errorf("product not found: %w", err)
+	}
+	return s.GetProduct(storeID, product.ID, locale)
 }
-func (s *ProductService) GetProduct(id string) (*models.ProductWithCategory, error) {
-	return s.GetProductWithCategory(id)
+func (s *ProductService) GetProductByBarcode(storeID, barcode, locale string) (*models.ProductWithCategory, error) {
+	product, err := s.productRepo.GetByBarcode(storeID, barcode)
+	if err != nil {
+		return nil, fmt.Errorf("product not found: %w", err)
+	}
+	return s.GetProduct(storeID, product.ID, locale)
 }
-func (s *ProductService) GetProductWithCategory(id string) (*models.ProductWithCategory, error) {
-	product, err := s.productRepo.GetByID(id)
+func (s *ProductService) GetProductWithCategory(storeID, id string) (*models.ProductWithCategory, error) {
+	product, err := s.productRepo.GetByID(storeID, id)
 	if err != nil {
 		return nil, fmt.Errorf("product not found: %w", err)
 	}
@@ -49,21 +225,135 @@ func (s *ProductService) GetProductWithCategory(id string) (*models.ProductWithC
 	if product.CategoryID != "" {
 		category, _ = s.categoryRepo.GetByID(product.CategoryID)
 	}
+	gallery, err := s.imageRepo.GetByProductID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product images: %w", err)
+	}
 	return &models.ProductWithCategory{
 		Product:  *product,
 		Category: category,
+		Gallery:  gallery,
 	}, nil
 }
-func (s *ProductService) GetProducts(query models.ProductQuery) (*models.PaginatedProducts, error) {
+func (s *ProductService) ApplyQueryDefaults(query *models.ProductQuery) bool {
 	if query.Page <= 0 {
 		query.Page = 1
 	}
 	if query.Limit <= 0 {
-		query.Limit = 20
+		query.Limit = s.catalog.DefaultPageSize
+	}
+	clamped := false
+	if query.Limit > s.catalog.MaxPageSize {
+		query.Limit = s.catalog.MaxPageSize
+		clamped = true
+	}
+	if query.SortBy == "" {
+		query.SortBy = s.catalog.DefaultSort
+		if query.SortOrder == "" {
+			query.SortOrder = s.catalog.DefaultSortOrder
+		}
+	}
+	return clamped
+}
+// GetProducts pages through the catalog. When query.Facets names any
+// facets, their counts for the same filters are attached; they are cached
+// apart from the page so paging does not recount them.
+func (s *ProductService) GetProducts(query models.ProductQuery) (*models.PaginatedProducts, error) {
+	facets, err := parseProductFacets(query.Facets)
+	if err != nil {
+		return nil, err
+	}
+	s.ApplyQueryDefaults(&query)
+	cacheKey := fmt.Sprintf("list:%s:%d:%d:%s:%s:%t:%s:%s:%s", query.StoreID, query.Page, query.Limit, query.Category, query.Search, query.Featured, query.SortBy, query.SortOrder, query.Locale)
+	result, err := utils.CacheGetOrSet("products", cacheKey, 0, func() (interface{}, error) {
+		return s.loadProducts(query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	products := result.(*models.PaginatedProducts)
+	if len(facets) == 0 {
+		return products, nil
 	}
-	if query.Limit > 100 {
-		query.Limit = 100
+	facetKey := fmt.Sprintf("facets:%s:%s:%s:%t:%s:%s", query.StoreID, query.Category, query.Search, query.Featured, strings.Join(facets, ","), query.Locale)
+	counts, err := utils.CacheGetOrSet("products", facetKey, 0, func() (interface{}, error) {
+		return s.loadProductFacets(query, facets)
+	})
+	if err != nil {
+		return nil, err
+	}
+	withFacets := *products
+	withFacets.Facets = counts.(*models.ProductFacets)
+	return &withFacets, nil
+}
+func parseProductFacets(raw string) ([]string, error) {
+	var facets []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !utils.Contains(models.ProductFacetNames, name) {
+			return nil, fmt.Errorf("unknown facet %q, expected one of %s", name, strings.Join(models.ProductFacetNames, ", "))
+		}
+		if !utils.Contains(facets, name) {
+			facets = append(facets, name)
+		}
 	}
+	sort.Strings(facets)
+	return facets, nil
+}
+func (s *ProductService) loadProductFacets(query models.ProductQuery, facets []string) (*models.ProductFacets, error) {
+	result := &models.ProductFacets{}
+	for _, facet := range facets {
+		switch facet {
+		case models.FacetCategory:
+			counts, err := s.productRepo.CountByCategory(query)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count categories: %w", err)
+			}
+			categories := make([]*models.Category, len(counts))
+			for i := range counts {
+				categories[i] = &models.Category{ID: counts[i].Value, Name: counts[i].Label}
+			}
+			s.translations.LocalizeCategories(categories, query.Locale)
+			result.Category = make([]models.FacetCount, len(counts))
+			for i := range counts {
+				result.Category[i] = models.FacetCount{Value: counts[i].Value, Label: categories[i].Name, Count: counts[i].Count}
+			}
+		case models.FacetPrice:
+			bounds := make([]models.Money, len(s.catalog.PriceFacetBounds))
+			for i, bound := range s.catalog.PriceFacetBounds {
+				bounds[i] = models.MoneyFromFloat(bound)
+			}
+			counts, err := s.productRepo.CountByPrice(query, bounds)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count prices: %w", err)
+			}
+			result.Price = make([]models.PriceFacetBucket, len(counts))
+			for i, count := range counts {
+				result.Price[i].Count = count
+				if i > 0 {
+					result.Price[i].Min = &bounds[i-1]
+				}
+				if i < len(bounds) {
+					result.Price[i].Max = &bounds[i]
+				}
+			}
+		case models.FacetAvailability:
+			inStock, outOfStock, err := s.productRepo.CountByAvailability(query)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count availability: %w", err)
+			}
+			result.Availability = []models.FacetCount{
+				{Value: string(models.StockInStock), Count: inStock},
+				{Value: string(models.StockOutOfStock), Count: outOfStock},
+			}
+		}
+	}
+	return result, nil
+}
+func (s *ProductService) loadProducts(query models.ProductQuery) (*models.PaginatedProducts, error) {
 	offset := (query.Page - 1) * query.Limit
 	products, total, err := s.productRepo.ListWithFilters(query, offset)
 	if err != nil {
@@ -79,9 +369,11 @@ func (s *ProductService) GetProducts(query models.ProductQuery) (*models.Paginat
 			ReviewCount:   reviewCount,
 		}
 	}
+	localizeProductsWithRating(s.translations, productsWithRating, query.Locale)
 	pages := int(math.Ceil(float64(total) / float64(query.Limit)))
 	return &models.PaginatedProducts{
-		Data: productsWithRating,
+		Locale: query.Locale,
+		Data:   productsWithRating,
 		Pagination: models.Pagination{
 			Page:  query.Page,
 			Limit: query.Limit,
@@ -90,11 +382,76 @@ func (s *ProductService) GetProducts(query models.ProductQuery) (*models.Paginat
 		},
 	}, nil
 }
-func (s *ProductService) GetFeaturedProducts(limit int) ([]models.ProductWithRating, error) {
+// GetNewArrivals pages through products added within the last query.Days
+// days. Results are cached until the next product change.
+func (s *ProductService) GetNewArrivals(query models.NewArrivalsQuery) (*models.PaginatedProducts, error) {
+	if query.Days == 0 {
+		query.Days = s.catalog.NewArrivalsDays
+	}
+	if query.Days < 0 || query.Days > models.MaxNewArrivalsDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", models.MaxNewArrivalsDays)
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 {
+		query.Limit = s.catalog.DefaultPageSize
+	}
+	if query.Limit > s.catalog.MaxPageSize {
+		query.Limit = s.catalog.MaxPageSize
+	}
+	cacheKey := fmt.Sprintf("new:%s:%d:%d:%d:%s", query.StoreID, query.Days, query.Page, query.Limit, query.Locale)
+	result, err := utils.CacheGetOrSet("products", cacheKey, 0, func() (interface{}, error) {
+		return s.loadNewArrivals(query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*models.PaginatedProducts), nil
+}
+func (s *ProductService) loadNewArrivals(query models.NewArrivalsQuery) (*models.PaginatedProducts, error) {
+	since := time.Now().AddDate(0, 0, -query.Days)
+	offset := (query.Page - 1) * query.Limit
+	products, total, err := s.productRepo.ListNewArrivals(query.StoreID, since, query.Limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get new arrivals: %w", err)
+	}
+	productsWithRating := make([]models.ProductWithRating, len(products))
+	for i, product := range products {
+		rating, reviewCount := s.getProductRating(product.ID)
+		productsWithRating[i] = models.ProductWithRating{
+			Product:       product.Product,
+			Category:      product.Category,
+			AverageRating: rating,
+			ReviewCount:   reviewCount,
+		}
+	}
+	localizeProductsWithRating(s.translations, productsWithRating, query.Locale)
+	return &models.PaginatedProducts{
+		Locale: query.Locale,
+		Data:   productsWithRating,
+		Pagination: models.Pagination{
+			Page:  query.Page,
+			Limit: query.Limit,
+			Total: total,
+			Pages: int(math.Ceil(float64(total) / float64(query.Limit))),
+		},
+	}, nil
+}
+func (s *ProductService) GetFeaturedProducts(storeID string, limit int, locale string) ([]models.ProductWithRating, error) {
 	if limit <= 0 {
 		limit = 10
 	}
-	products, err := s.productRepo.GetFeatured(limit)
+	result, err := utils.CacheGetOrSet("products", fmt.Sprintf("featured:%s:%d:%s", storeID, limit, locale), 0, func() (interface{}, error) {
+		return s.loadFeaturedProducts(storeID, limit, locale)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]models.ProductWithRating), nil
+}
+func (s *ProductService) loadFeaturedProducts(storeID string, limit int, locale string) ([]models.ProductWithRating, error) {
+	products, err := s.productRepo.GetFeatured(storeID, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get featured products: %w", err)
 	}
@@ -108,9 +465,17 @@ func (s *ProductService) GetFeaturedProducts(limit int) ([]models.ProductWithRat
 			ReviewCount:   reviewCount,
 		}
 	}
+	localizeProductsWithRating(s.translations, productsWithRating, locale)
 	return productsWithRating, nil
 }
-func (s *ProductService) UpdateProduct(id string, req models.ProductUpdateRequest) (*models.ProductWithCategory, error) {
+// UpdateProduct saves the changes only if the product is still at
+// req.Version. On "product version conflict" the current product is returned
+// alongside the error so the editor can merge. A price change is audited
+// with audit's actor. A product of a store other than storeID is not found.
+func (s *ProductService) UpdateProduct(storeID, id string, req models.ProductUpdateRequest, audit models.AuditEntry) (*models.ProductWithCategory, error) {
+	if req.Version == nil {
+		return nil, fmt.Errorf("version is required")
+	}
 	updates := make(map[string]interface{})
 	if req.Name != nil {
 		updates["name"] = *req.Name
@@ -119,40 +484,196 @@ func (s *ProductService) UpdateProduct(id string, req models.ProductUpdateReques
 	if req.Description != nil {
 		updates["description"] = *req.Description
 	}
+	var previousPrice *models.Money
 	if req.Price != nil {
 		updates["price"] = *req.Price
+		current, err := s.productRepo.GetByID(storeID, id)
+		if err != nil {
+			return nil, fmt.Errorf("product not found: %w", err)
+		}
+		previousPrice = &current.Price
 	}
 	if req.ComparePrice != nil {
 		updates["compare_price"] = *req.ComparePrice
 	}
-	if req.Images != nil {
-		updates["images"] = req.Images
-	}
-	if req.Stock != nil {
-		updates["stock"] = *req.Stock
-		updates["in_stock"] = *req.Stock > 0
-	}
 	if req.Featured != nil {
 		updates["featured"] = *req.Featured
 	}
+	if req.ProductType != nil {
+		updates["product_type"] = *req.ProductType
+	}
+	if req.DigitalFile != nil {
+		if file := strings.TrimSpace(*req.DigitalFile); file != "" {
+			if err := s.checkDigitalFile(file); err != nil {
+				return nil, err
+			}
+			updates["digital_file"] = file
+		} else {
+			updates["digital_file"] = nil
+		}
+	}
 	if req.CategoryID != nil {
 		updates["category_id"] = *req.CategoryID
 	}
-	if len(updates) > 0 {
-		if err := s.productRepo.Update(id, updates); err != nil {
-			return nil, fmt.Errorf("failed to update product: %w", err)
+	if req.Unit != nil {
+		updates["unit"] = *req.Unit
+	}
+	if req.SKU != nil {
+		updates["sku"] = optionalIdentifier(*req.SKU)
+	}
+	if req.Barcode != nil {
+		updates["barcode"] = optionalIdentifier(*req.Barcode)
+	}
+	// The version is bumped even when only the images change, so every save
+	// goes through the check.
+	updates["updated_at"] = time.Now()
+	if _, err := s.productRepo.UpdateVersioned(storeID, id, *req.Version, updates); err != nil {
+		switch {
+		case err.Error() == "product version conflict":
+			current, getErr := s.GetProductWithCategory(storeID, id)
+			if getErr != nil {
+				return nil, getErr
+			}
+			return current, err
+		case err.Error() == "product not found", isProductIdentifierConflict(err):
+			return nil, err
 		}
+		return nil, fmt.Errorf("failed to update product: %w", err)
 	}
-	return s.GetProductWithCategory(id)
+	if req.Images != nil {
+		if err := s.imageRepo.Replace(id, productImagesFromURLs(id, req.Images)); err != nil {
+			return nil, fmt.Errorf("failed to save product images: %w", err)
+		}
+	}
+	// Stock goes through the ledger rather than the update so the movement
+	// records exactly the change that was applied.
+	if req.Stock != nil {
+		reason := models.InventoryReasonAdjustment
+		if req.StockReason != "" {
+			reason = models.InventoryReason(req.StockReason)
+		}
+		if _, err := s.inventoryRepo.SetStock(id, *req.Stock, reason, req.StockReference); err != nil {
+			if err.Error() == "bundle stock is derived from its components" {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to update stock: %w", err)
+		}
+	}
+	if previousPrice != nil && *previousPrice != *req.Price {
+		if err := s.priceHistoryRepo.Record(id, *req.Price); err != nil {
+			utils.Warn("failed to record price history", "product_id", id, "error", err.Error())
+		}
+		s.auditService.Record(audit,
+			map[string]models.Money{"price": *previousPrice}, map[string]models.Money{"price": *req.Price})
+	}
+	utils.CacheInvalidatePrefix("products:")
+	return s.GetProductWithCategory(storeID, id)
 }
-func (s *ProductService) DeleteProduct(id string) error {
-	return s.productRepo.Delete(id)
+func (s *ProductService) AddProductImage(storeID, productID string, req models.ProductImageCreateRequest) (*models.ProductWithCategory, error) {
+	if _, err := s.productRepo.GetByID(storeID, productID); err != nil {
+		return nil, fmt.Errorf("product not found")
+	}
+	image := &models.ProductImage{
+		ID:        generateID(),
+		ProductID: productID,
+		URL:       req.URL,
+		IsPrimary: req.IsPrimary,
+		CreatedAt: time.Now(),
+	}
+	if err := s.imageRepo.Create(image); err != nil {
+		return nil, fmt.Errorf("failed to add product image: %w", err)
+	}
+	utils.CacheInvalidatePrefix("products:")
+	return s.GetProductWithCategory(storeID, productID)
 }
-func (s *ProductService) SearchProducts(query string, limit int) ([]models.ProductWithRating, error) {
+// MergeProducts folds duplicate products of storeID into req.PrimaryID and
+// records the outcome under the given audit entry.
+func (s *ProductService) MergeProducts(storeID string, req models.ProductMergeRequest, audit models.AuditEntry) (*models.ProductMergeResult, error) {
+	duplicates := uniqueProductIDs(req.DuplicateIDs)
+	if utils.Contains(duplicates, req.PrimaryID) {
+		return nil, fmt.Errorf("cannot merge a product into itself")
+	}
+	result, err := s.productRepo.Merge(storeID, req.PrimaryID, duplicates)
+	if err != nil {
+		switch err.Error() {
+		case "product not found", "duplicate product not found", "products belong to different stores":
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to merge products: %w", err)
+	}
+	invalidateRatingSummary(req.PrimaryID)
+	for _, id := range duplicates {
+		invalidateRatingSummary(id)
+	}
+	utils.CacheInvalidatePrefix("products:")
+	s.auditService.Record(audit, map[string][]string{"duplicate_ids": duplicates}, result)
+	return result, nil
+}
+func (s *ProductService) ReorderProductImages(storeID, productID string, imageIDs []string) (*models.ProductWithCategory, error) {
+	if _, err := s.productRepo.GetByID(storeID, productID); err != nil {
+		return nil, fmt.Errorf("product not found")
+	}
+	if err := s.imageRepo.Reorder(productID, imageIDs); err != nil {
+		if err.Error() == "image list does not match product images" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to reorder product images: %w", err)
+	}
+	utils.CacheInvalidatePrefix("products:")
+	return s.GetProductWithCategory(storeID, productID)
+}
+func (s *ProductService) SetPrimaryProductImage(storeID, productID, imageID string) (*models.ProductWithCategory, error) {
+	if _, err := s.productRepo.GetByID(storeID, productID); err != nil {
+		return nil, fmt.Errorf("product not found")
+	}
+	if err := s.imageRepo.SetPrimary(productID, imageID); err != nil {
+		if err.Error() == "image not found" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to set primary image: %w", err)
+	}
+	utils.CacheInvalidatePrefix("products:")
+	return s.GetProductWithCategory(storeID, productID)
+}
+func (s *ProductService) DeleteProductImage(storeID, productID, imageID string) (*models.ProductWithCategory, error) {
+	if _, err := s.productRepo.GetByID(storeID, productID); err != nil {
+		return nil, fmt.Errorf("product not found")
+	}
+	if err := s.imageRepo.Delete(productID, imageID); err != nil {
+		if err.Error() == "image not found" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to delete product image: %w", err)
+	}
+	utils.CacheInvalidatePrefix("products:")
+	return s.GetProductWithCategory(storeID, productID)
+}
+func productImagesFromURLs(productID string, urls []string) []models.ProductImage {
+	images := make([]models.ProductImage, len(urls))
+	for i, url := range urls {
+		images[i] = models.ProductImage{
+			ID:        generateID(),
+			ProductID: productID,
+			URL:       url,
+			Position:  i,
+			IsPrimary: i == 0,
+			CreatedAt: time.Now(),
+		}
+	}
+	return images
+}
+func (s *ProductService) DeleteProduct(storeID, id string) error {
+	if err := s.productRepo.Delete(storeID, id); err != nil {
+		return err
+	}
+	utils.CacheInvalidatePrefix("products:")
+	return nil
+}
+func (s *ProductService) SearchProducts(storeID, query string, limit int) ([]models.ProductWithRating, error) {
 	if limit <= 0 {
 		limit = 20
 	}
-	products, err := s.productRepo.Search(query, limit)
+	products, err := s.productRepo.Search(storeID, query, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search products: %w", err)
 	}
@@ -168,6 +689,16 @@ func (s *ProductService) SearchProducts(query string, limit int) ([]models.Produ
 	}
 	return productsWithRating, nil
 }
+func localizeProductsWithRating(translations *TranslationService, products []models.ProductWithRating, locale string) {
+	items := make([]*models.Product, len(products))
+	categories := make([]*models.Category, len(products))
+	for i := range products {
+		items[i] = &products[i].Product
+		categories[i] = products[i].Category
+	}
+	translations.LocalizeProducts(items, locale)
+	translations.LocalizeCategories(categories, locale)
+}
 func (s *ProductService) getProductRating(productID string) (float64, int) {
 	rating, count, err := s.reviewRepo.GetProductRating(productID)
 	if err != nil {
@@ -0,0 +1,174 @@
+﻿package services
+import (
+	"fmt"
+	"strings"
+	"time"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/websocket"
+)
+type QuestionService struct {
+	questionRepo *repositories.QuestionRepository
+	productRepo  *repositories.ProductRepository
+	auditService *AuditService
+	hub          *websocket.Hub
+}
+func NewQuestionService(questionRepo *repositories.QuestionRepository, productRepo *repositories.ProductRepository, auditService *AuditService, hub *websocket.Hub) *QuestionService {
+	return &QuestionService{questionRepo: questionRepo, productRepo: productRepo, auditService: auditService, hub: hub}
+}
+// isStaff reports whether a role may post official answers and moderate Q&A
+// from other users, matching who may reply to reviews.
+func isStaff(role string) bool {
+	return role == "admin" || role == "seller"
+}
+// initialQAStatus publishes staff posts at once and holds the rest for
+// moderation.
+func initialQAStatus(role string) string {
+	if isStaff(role) {
+		return models.QAStatusApproved
+	}
+	return models.QAStatusPending
+}
+// CreateQuestion accepts questions on live products only; GetByID does not
+// return soft-deleted ones.
+func (s *QuestionService) CreateQuestion(userID, userRole string, req models.QuestionCreateRequest) (*models.ProductQuestion, error) {
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		return nil, fmt.Errorf("question body is required")
+	}
+	if _, err := s.productRepo.GetByID("", req.ProductID); err != nil {
+		return nil, fmt.Errorf("product not found")
+	}
+	question := &models.ProductQuestion{
+		ID:        generateID(),
+		ProductID: req.ProductID,
+		UserID:    &userID,
+		Body:      body,
+		Status:    initialQAStatus(userRole),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Answers:   []models.ProductAnswer{},
+	}
+	if err := s.questionRepo.CreateQuestion(question); err != nil {
+		return nil, fmt.Errorf("failed to create question: %w", err)
+	}
+	if s.hub != nil {
+		s.hub.SendProductQuestion(question.ID, question.ProductID, question.Body)
+	}
+	return question, nil
+}
+func (s *QuestionService) GetProductQuestions(productID string, query *models.QuestionQuery) ([]*models.ProductQuestion, int, error) {
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 {
+		query.Limit = 10
+	}
+	if query.Limit > 50 {
+		query.Limit = 50
+	}
+	offset := (query.Page - 1) * query.Limit
+	questions, total, err := s.questionRepo.GetByProductID(productID, query.Sort, query.Limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get questions: %w", err)
+	}
+	questionIDs := make([]string, len(questions))
+	for i, question := range questions {
+		questionIDs[i] = question.ID
+	}
+	answers, err := s.questionRepo.GetAnswersByQuestionIDs(questionIDs)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get answers: %w", err)
+	}
+	for _, question := range questions {
+		question.Answers = answers[question.ID]
+		if question.Answers == nil {
+			question.Answers = []models.ProductAnswer{}
+		}
+	}
+	return questions, total, nil
+}
+func (s *QuestionService) DeleteQuestion(userID, userRole, questionID string) error {
+	question, err := s.questionRepo.GetQuestionByID(questionID)
+	if err != nil {
+		return err
+	}
+	if !ownedBy(question.UserID, userID) && userRole != "admin" {
+		return fmt.Errorf("unauthorized")
+	}
+	return s.questionRepo.DeleteQuestion(questionID)
+}
+func (s *QuestionService) CreateAnswer(userID, userRole, questionID string, req models.AnswerCreateRequest) (*models.ProductAnswer, error) {
+	if req.Official && !isStaff(userRole) {
+		return nil, fmt.Errorf("only staff can post official answers")
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		return nil, fmt.Errorf("answer body is required")
+	}
+	question, err := s.questionRepo.GetQuestionByID(questionID)
+	if err != nil {
+		return nil, err
+	}
+	if question.Status != models.QAStatusApproved {
+		return nil, fmt.Errorf("question not found")
+	}
+	answer := &models.ProductAnswer{
+		ID:         generateID(),
+		QuestionID: questionID,
+		UserID:     &userID,
+		Body:       body,
+		IsOfficial: req.Official,
+		Status:     initialQAStatus(userRole),
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	if err := s.questionRepo.CreateAnswer(answer); err != nil {
+		return nil, fmt.Errorf("failed to create answer: %w", err)
+	}
+	return answer, nil
+}
+func (s *QuestionService) DeleteAnswer(userID, userRole, answerID string) error {
+	answer, err := s.questionRepo.GetAnswerByID(answerID)
+	if err != nil {
+		return err
+	}
+	if !ownedBy(answer.UserID, userID) && userRole != "admin" {
+		return fmt.Errorf("unauthorized")
+	}
+	return s.questionRepo.DeleteAnswer(answerID)
+}
+// Vote adds (up) or withdraws a user's upvote on a question or answer and
+// returns the new count.
+func (s *QuestionService) Vote(userID, targetType, targetID string, up bool) (int, error) {
+	return s.questionRepo.SetVote(userID, targetType, targetID, up)
+}
+// Report flags an approved question or answer for moderation and returns its
+// resulting status.
+func (s *QuestionService) Report(userID, targetType, targetID string, req models.QAReportRequest) (string, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return "", fmt.Errorf("report reason is required")
+	}
+	return s.questionRepo.Report(userID, targetType, targetID, reason, models.QAReportThreshold)
+}
+// QuestionQueue and AnswerQueue list the content in one moderation status.
+func (s *QuestionService) QuestionQueue(query models.QAModerationQuery) ([]*models.ProductQuestion, int, error) {
+	return s.questionRepo.GetByStatus(query.Status, query.Limit, (query.Page-1)*query.Limit)
+}
+func (s *QuestionService) AnswerQueue(query models.QAModerationQuery) ([]models.ProductAnswer, int, error) {
+	return s.questionRepo.GetAnswersByStatus(query.Status, query.Limit, (query.Page-1)*query.Limit)
+}
+// Moderate sets the status of a question or answer and records the change
+// in the audit log.
+func (s *QuestionService) Moderate(targetType, targetID string, req models.QAStatusRequest, audit models.AuditEntry) error {
+	previous, err := s.questionRepo.SetStatus(targetType, targetID, req.Status)
+	if err != nil {
+		return err
+	}
+	s.auditService.Record(audit, map[string]string{"status": previous}, map[string]string{"status": req.Status})
+	return nil
+}
+func ownedBy(ownerID *string, userID string) bool {
+	return ownerID != nil && *ownerID == userID
+}
@@ -0,0 +1,76 @@
+﻿package services
+import (
+	"fmt"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
+)
+const (
+	maxRecommendations           = 20
+	associationMinSupport        = 2
+	recommendationSourceOrders   = "orders"
+	recommendationSourceCategory = "category"
+	recommendationSourceMixed    = "mixed"
+)
+type RecommendationService struct {
+	recommendationRepo *repositories.RecommendationRepository
+	productRepo        *repositories.ProductRepository
+}
+func NewRecommendationService(recommendationRepo *repositories.RecommendationRepository, productRepo *repositories.ProductRepository) *RecommendationService {
+	return &RecommendationService{recommendationRepo: recommendationRepo, productRepo: productRepo}
+}
+func (s *RecommendationService) RefreshAssociations() error {
+	count, err := s.recommendationRepo.RebuildAssociations(associationMinSupport)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild product associations: %w", err)
+	}
+	utils.Info("product associations rebuilt", "pairs", count)
+	return nil
+}
+func (s *RecommendationService) FrequentlyBoughtTogether(productID string, limit int) ([]*models.Product, string, error) {
+	if limit <= 0 || limit > maxRecommendations {
+		limit = maxRecommendations
+	}
+	product, err := s.productRepo.GetByID("", productID)
+	if err != nil {
+		return nil, "", fmt.Errorf("product not found")
+	}
+	ids, err := s.recommendationRepo.GetAssociatedProductIDs(productID, limit)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get associations: %w", err)
+	}
+	seen := map[string]bool{productID: true}
+	products := []*models.Product{}
+	for _, id := range ids {
+		associated, err := s.productRepo.GetByID("", id)
+		if err != nil {
+			continue
+		}
+		seen[id] = true
+		products = append(products, associated)
+	}
+	fromOrders := len(products)
+	if len(products) < limit && product.CategoryID != "" {
+		related, err := s.productRepo.GetProductsByCategory(product.CategoryID, limit+len(seen), 0)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get related products: %w", err)
+		}
+		for _, candidate := range related {
+			if len(products) >= limit {
+				break
+			}
+			if seen[candidate.ID] || !candidate.InStock {
+				continue
+			}
+			seen[candidate.ID] = true
+			products = append(products, candidate)
+		}
+	}
+	source := recommendationSourceOrders
+	if fromOrders == 0 {
+		source = recommendationSourceCategory
+	} else if fromOrders < len(products) {
+		source = recommendationSourceMixed
+	}
+	return products, source, nil
+}
@@ -0,0 +1,193 @@
+﻿package services
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+)
+// OrderReportSyncLimit is the largest report streamed in the request; bigger
+// ones are generated on the job queue.
+const OrderReportSyncLimit = 5000
+var orderReportHeader = []string{
+	"record_type", "order_id", "created_at", "status", "store_id", "user_id",
+	"subtotal", "tax", "shipping", "gift_wrap_fee", "total", "paid", "refunded", "net",
+	"item_id", "product_id", "product_name", "quantity", "unit", "unit_price", "line_total",
+}
+type ReportService struct {
+	reportRepo *repositories.ReportRepository
+	jobs       *JobQueue
+	path       string
+	mu         sync.Mutex
+	files      map[string]string
+}
+func NewReportService(reportRepo *repositories.ReportRepository, jobs *JobQueue, path string) *ReportService {
+	return &ReportService{reportRepo: reportRepo, jobs: jobs, path: path, files: make(map[string]string)}
+}
+// ValidateOrderReport checks the query and format before anything is written.
+func (s *ReportService) ValidateOrderReport(q models.OrderReportQuery, format string) error {
+	if format != models.ReportFormatCSV && format != models.ReportFormatJSON {
+		return fmt.Errorf("invalid report format")
+	}
+	if q.Status != "" && !q.Status.Valid() {
+		return fmt.Errorf("invalid order status")
+	}
+	if !q.From.Before(q.To) {
+		return fmt.Errorf("invalid date range")
+	}
+	return nil
+}
+func (s *ReportService) CountOrders(q models.OrderReportQuery) (int, error) {
+	return s.reportRepo.CountOrders(q)
+}
+// WriteOrderReport streams the report to w, ending with a totals row.
+func (s *ReportService) WriteOrderReport(w io.Writer, q models.OrderReportQuery, format string) error {
+	if format == models.ReportFormatJSON {
+		return s.writeOrderReportJSON(w, q)
+	}
+	return s.writeOrderReportCSV(w, q)
+}
+func (s *ReportService) writeOrderReportCSV(w io.Writer, q models.OrderReportQuery) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(orderReportHeader); err != nil {
+		return err
+	}
+	var totals models.OrderReportTotals
+	err := s.reportRepo.StreamOrders(q, func(row *models.OrderReportRow) error {
+		totals.Add(row)
+		record := []string{
+			"order", row.OrderID, row.CreatedAt.UTC().Format(time.RFC3339), string(row.Status), row.StoreID, row.UserID,
+			row.Subtotal.String(), row.Tax.String(), row.Shipping.String(), row.GiftWrapFee.String(),
+			row.Total.String(), row.Paid.String(), row.Refunded.String(), row.Net.String(),
+			"", "", "", "", "", "", "",
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+		for _, item := range row.Items {
+			record := make([]string, len(orderReportHeader))
+			record[0], record[1] = "item", row.OrderID
+			copy(record[14:], []string{
+				item.ID, item.ProductID, item.ProductName, item.Quantity.String(), item.Unit,
+				item.Price.String(), item.Price.MulQuantity(item.Quantity).String(),
+			})
+			if err := out.Write(record); err != nil {
+				return err
+			}
+		}
+		return out.Error()
+	})
+	if err != nil {
+		return err
+	}
+	record := make([]string, len(orderReportHeader))
+	record[0], record[1] = "total", strconv.Itoa(totals.Orders)
+	copy(record[6:], []string{
+		totals.Subtotal.String(), totals.Tax.String(), totals.Shipping.String(), "",
+		totals.Total.String(), totals.Paid.String(), totals.Refunded.String(), totals.Net.String(),
+	})
+	out.Write(record)
+	out.Flush()
+	return out.Error()
+}
+// writeOrderReportJSON writes {"from","to","orders":[...],"totals":{...}} one
+// order at a time.
+func (s *ReportService) writeOrderReportJSON(w io.Writer, q models.OrderReportQuery) error {
+	head, err := json.Marshal(map[string]time.Time{"from": q.From, "to": q.To})
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%s,\"orders\":[", head[:len(head)-1]); err != nil {
+		return err
+	}
+	var totals models.OrderReportTotals
+	enc := json.NewEncoder(w)
+	err = s.reportRepo.StreamOrders(q, func(row *models.OrderReportRow) error {
+		if totals.Orders > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		totals.Add(row)
+		return enc.Encode(row)
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "],\"totals\":"); err != nil {
+		return err
+	}
+	if err := enc.Encode(totals); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "}")
+	return err
+}
+// StartOrderReport generates the report on the job queue. The file can be
+// downloaded with OrderReportFile once the job has completed.
+func (s *ReportService) StartOrderReport(q models.OrderReportQuery, format, requestID string) models.Job {
+	s.pruneFiles()
+	path := filepath.Join(s.path, generateID()+"."+format)
+	job := s.jobs.SubmitFor(requestID, "order_report", func() error {
+		return s.writeOrderReportFile(path, q, format)
+	}, nil)
+	s.mu.Lock()
+	s.files[job.ID] = path
+	s.mu.Unlock()
+	return job
+}
+func (s *ReportService) writeOrderReportFile(path string, q models.OrderReportQuery, format string) error {
+	if err := os.MkdirAll(s.path, 0700); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	if err := s.WriteOrderReport(file, q, format); err != nil {
+		file.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return file.Close()
+}
+func (s *ReportService) GetJob(id string) (models.Job, bool) {
+	s.mu.Lock()
+	_, ok := s.files[id]
+	s.mu.Unlock()
+	if !ok {
+		return models.Job{}, false
+	}
+	return s.jobs.Get(id)
+}
+// OrderReportFile returns the path of a finished report job.
+func (s *ReportService) OrderReportFile(jobID string) (string, error) {
+	job, ok := s.GetJob(jobID)
+	if !ok {
+		return "", fmt.Errorf("report not found")
+	}
+	if job.Status != models.JobStatusCompleted {
+		return "", fmt.Errorf("report not ready")
+	}
+	s.mu.Lock()
+	path := s.files[jobID]
+	s.mu.Unlock()
+	return path, nil
+}
+// pruneFiles deletes reports whose jobs the queue no longer tracks.
+func (s *ReportService) pruneFiles() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, path := range s.files {
+		if _, ok := s.jobs.Get(id); !ok {
+			os.Remove(path)
+			delete(s.files, id)
+		}
+	}
+}
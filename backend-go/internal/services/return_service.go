@@ -0,0 +1,245 @@
+﻿package services
+import (
+	"fmt"
+	"time"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
+	"ecommerce-backend/internal/websocket"
+	"github.com/google/uuid"
+)
+type ReturnService struct {
+	returnRepo     *repositories.ReturnRepository
+	orderRepo      *repositories.OrderRepository
+	shipmentRepo   *repositories.ShipmentRepository
+	inventoryRepo  *repositories.InventoryRepository
+	paymentService *PaymentService
+	hub            *websocket.Hub
+	returnWindow   time.Duration
+}
+func NewReturnService(returnRepo *repositories.ReturnRepository, orderRepo *repositories.OrderRepository, shipmentRepo *repositories.ShipmentRepository, inventoryRepo *repositories.InventoryRepository, paymentService *PaymentService, hub *websocket.Hub, returnWindowDays int) *ReturnService {
+	return &ReturnService{
+		returnRepo:     returnRepo,
+		orderRepo:      orderRepo,
+		shipmentRepo:   shipmentRepo,
+		inventoryRepo:  inventoryRepo,
+		paymentService: paymentService,
+		hub:            hub,
+		returnWindow:   time.Duration(returnWindowDays) * 24 * time.Hour,
+	}
+}
+func (s *ReturnService) GetReturns(orderID, userID, userRole string) ([]models.OrderReturn, error) {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil || (userRole != "admin" && order.UserID != userID) {
+		return nil, fmt.Errorf("order not found")
+	}
+	return s.returnRepo.GetByOrderID(orderID)
+}
+func (s *ReturnService) RequestReturn(userID, orderID string, req models.ReturnCreateRequest) (*models.OrderReturn, error) {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil || order.UserID != userID {
+		return nil, fmt.Errorf("order not found")
+	}
+	if order.Status != models.OrderStatusDelivered {
+		return nil, fmt.Errorf("order is not eligible for return")
+	}
+	deliveredAt, err := s.deliveredAt(order)
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(deliveredAt) > s.returnWindow {
+		return nil, fmt.Errorf("return window has expired")
+	}
+	orderItems, err := s.orderRepo.GetOrderItems(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+	returned, err := s.returnRepo.GetReturnedQuantities(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get returned quantities: %w", err)
+	}
+	itemsByID := make(map[string]models.OrderItem, len(orderItems))
+	for _, item := range orderItems {
+		itemsByID[item.ID] = item.OrderItem
+	}
+	var refundAmount models.Money
+	seen := make(map[string]bool)
+	for _, item := range req.Items {
+		orderItem, ok := itemsByID[item.OrderItemID]
+		if !ok {
+			return nil, fmt.Errorf("order item %s does not belong to this order", item.OrderItemID)
+		}
+		if seen[item.OrderItemID] {
+			return nil, fmt.Errorf("order item %s listed more than once", item.OrderItemID)
+		}
+		seen[item.OrderItemID] = true
+		if err := models.CheckQuantity(orderItem.Unit, item.Quantity); err != nil {
+			return nil, fmt.Errorf("invalid quantity for order item %s: %w", item.OrderItemID, err)
+		}
+		left := orderItem.Quantity - returned[item.OrderItemID]
+		if item.Quantity > left {
+			return nil, fmt.Errorf("invalid quantity for order item %s: %s returnable", item.OrderItemID, left)
+		}
+		refundAmount += orderItem.Price.MulQuantity(item.Quantity)
+	}
+	now := time.Now()
+	ret := &models.OrderReturn{
+		ID:           uuid.New().String(),
+		OrderID:      orderID,
+		UserID:       userID,
+		Status:       models.ReturnStatusRequested,
+		Reason:       req.Reason,
+		RefundAmount: refundAmount,
+		Items:        req.Items,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.returnRepo.Create(ret); err != nil {
+		return nil, fmt.Errorf("failed to create return: %w", err)
+	}
+	s.notify(order, ret, "Your return request has been received")
+	return ret, nil
+}
+func (s *ReturnService) ApproveReturn(orderID, returnID string, req models.ReturnDecisionRequest) (*models.OrderReturn, error) {
+	return s.transition(orderID, returnID, models.ReturnStatusRequested, models.ReturnStatusApproved, req.Note, "Your return has been approved")
+}
+func (s *ReturnService) RejectReturn(orderID, returnID string, req models.ReturnDecisionRequest) (*models.OrderReturn, error) {
+	return s.transition(orderID, returnID, models.ReturnStatusRequested, models.ReturnStatusRejected, req.Note, "Your return has been rejected")
+}
+func (s *ReturnService) ReceiveReturn(orderID, returnID string, req models.ReturnDecisionRequest) (*models.OrderReturn, error) {
+	order, ret, err := s.load(orderID, returnID)
+	if err != nil {
+		return nil, err
+	}
+	if ret.Status != models.ReturnStatusApproved {
+		return nil, fmt.Errorf("return cannot be received from status %s", ret.Status)
+	}
+	// Claim the return before refunding, so a retried or concurrent receive
+	// neither refunds nor restocks a second time.
+	now := time.Now()
+	ret.Status = models.ReturnStatusReceived
+	ret.ReceivedAt = &now
+	ret.UpdatedAt = now
+	if req.Note != nil {
+		ret.AdminNote = req.Note
+	}
+	claimed, err := s.returnRepo.UpdateFrom(ret, models.ReturnStatusApproved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update return: %w", err)
+	}
+	if !claimed {
+		return nil, fmt.Errorf("return cannot be received: it was changed by another request")
+	}
+	if ret.RefundAmount > 0 {
+		refundID, err := s.paymentService.RefundOrder(orderID, ret.RefundAmount, "return-"+ret.ID)
+		if err != nil {
+			ret.Status = models.ReturnStatusApproved
+			ret.ReceivedAt = nil
+			ret.UpdatedAt = time.Now()
+			if _, revertErr := s.returnRepo.UpdateFrom(ret, models.ReturnStatusReceived); revertErr != nil {
+				utils.Error("failed to reopen return after refund failure", "return_id", ret.ID, "error", revertErr.Error())
+			}
+			return nil, err
+		}
+		ret.RefundID = &refundID
+		if err := s.returnRepo.Update(ret); err != nil {
+			utils.Error("failed to record refund of return", "return_id", ret.ID, "refund_id", refundID, "error", err.Error())
+		}
+	}
+	s.restock(orderID, ret.Items)
+	s.notify(order, ret, fmt.Sprintf("Your return has been received and %s has been refunded", ret.RefundAmount))
+	return ret, nil
+}
+func (s *ReturnService) transition(orderID, returnID string, from, to models.ReturnStatus, note *string, message string) (*models.OrderReturn, error) {
+	order, ret, err := s.load(orderID, returnID)
+	if err != nil {
+		return nil, err
+	}
+	if ret.Status != from {
+		return nil, fmt.Errorf("return cannot be %s from status %s", to, ret.Status)
+	}
+	ret.Status = to
+	ret.UpdatedAt = time.Now()
+	if note != nil {
+		ret.AdminNote = note
+	}
+	updated, err := s.returnRepo.UpdateFrom(ret, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update return: %w", err)
+	}
+	if !updated {
+		return nil, fmt.Errorf("return cannot be %s: it was changed by another request", to)
+	}
+	s.notify(order, ret, message)
+	return ret, nil
+}
+func (s *ReturnService) load(orderID, returnID string) (*models.Order, *models.OrderReturn, error) {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("order not found")
+	}
+	ret, err := s.returnRepo.GetByID(returnID)
+	if err != nil || ret.OrderID != orderID {
+		return nil, nil, fmt.Errorf("return not found")
+	}
+	return order, ret, nil
+}
+func (s *ReturnService) deliveredAt(order *models.Order) (time.Time, error) {
+	shipments, err := s.shipmentRepo.GetByOrderID(order.ID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get shipments: %w", err)
+	}
+	var latest time.Time
+	for _, shipment := range shipments {
+		if shipment.DeliveredAt != nil && shipment.DeliveredAt.After(latest) {
+			latest = *shipment.DeliveredAt
+		}
+	}
+	if latest.IsZero() {
+		latest = order.UpdatedAt
+	}
+	return latest, nil
+}
+func (s *ReturnService) restock(orderID string, items []models.ReturnItem) {
+	orderItems, err := s.orderRepo.GetOrderItems(orderID)
+	if err != nil {
+		utils.Warn("failed to load order items for restock", "order_id", orderID, "error", err.Error())
+		return
+	}
+	itemsByID := make(map[string]models.OrderItem, len(orderItems))
+	for _, item := range orderItems {
+		itemsByID[item.ID] = item.OrderItem
+	}
+	for _, item := range items {
+		orderItem := itemsByID[item.OrderItemID]
+		// Stock is kept in whole units, so only the whole part of a
+		// measured return goes back on the shelf.
+		if item.Quantity.Int() == 0 {
+			continue
+		}
+		// A returned bundle puts its components back; the bundle's own
+		// stock follows.
+		restocked := []models.OrderItem{orderItem}
+		if len(orderItem.Components) > 0 {
+			restocked = orderItem.Components
+		}
+		for _, line := range restocked {
+			if line.ProductID == "" {
+				continue
+			}
+			units := item.Quantity.Int()
+			if line.ID != orderItem.ID {
+				units *= int(line.Quantity / orderItem.Quantity)
+			}
+			if _, err := s.inventoryRepo.Apply(line.ProductID, units, models.InventoryReasonReturn, &orderID); err != nil {
+				utils.Warn("failed to restock returned item", "order_item_id", item.OrderItemID, "error", err.Error())
+			}
+		}
+	}
+	utils.CacheInvalidatePrefix("products:")
+}
+func (s *ReturnService) notify(order *models.Order, ret *models.OrderReturn, message string) {
+	if s.hub != nil {
+		s.hub.SendOrderUpdate(order.ID, "return_"+string(ret.Status), message, order.UserID)
+	}
+}
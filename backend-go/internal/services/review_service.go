@@ -4,22 +4,53 @@ import (
 	"time"
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
+	"ecommerce-backend/internal/websocket"
+)
+const (
+	maxRatingSummaryBatch    = 100
+	maxReviewImages          = 5
+	maxReviewImagesTotalSize = 20 * 1024 * 1024
 )
 type ReviewService struct {
-	reviewRepo *repositories.ReviewRepository
+	reviewRepo    *repositories.ReviewRepository
+	uploadService *UploadService
+	hub           *websocket.Hub
+	limits        models.ReviewLimits
+	upsert        bool
 }
-func NewReviewService(reviewRepo *repositories.ReviewRepository) *ReviewService {
-	return &ReviewService{reviewRepo: reviewRepo}
+func NewReviewService(reviewRepo *repositories.ReviewRepository, uploadService *UploadService, hub *websocket.Hub, limits models.ReviewLimits, upsert bool) *ReviewService {
+	return &ReviewService{reviewRepo: reviewRepo, uploadService: uploadService, hub: hub, limits: limits, upsert: upsert}
 }
-func (s *ReviewService) CreateReview(userID string, req models.ReviewCreateRequest) (*models.Review, error) {
+// CreateReview returns utils.ValidationErrors when the rating or comment is
+// out of bounds. A user has one review per product: with upsert enabled a
+// repeat review updates the existing one and created is false, otherwise it
+// fails with "review already exists for this product".
+func (s *ReviewService) CreateReview(userID string, req models.ReviewCreateRequest) (review *models.Review, created bool, err error) {
+	if errs := s.limits.Check(&req.Rating, &req.Comment); errs.HasErrors() {
+		return nil, false, errs
+	}
 	existingReview, err := s.reviewRepo.GetUserReviewForProduct(userID, req.ProductID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check existing review: %w", err)
+		return nil, false, fmt.Errorf("failed to check existing review: %w", err)
 	}
 	if existingReview != nil {
-		return nil, fmt.Errorf("review already exists for this product")
+		if !s.upsert {
+			return nil, false, fmt.Errorf("review already exists for this product")
+		}
+		review, err := s.UpdateReview(userID, existingReview.ID, models.ReviewUpdateRequest{
+			Rating:  &req.Rating,
+			Comment: &req.Comment,
+			Helpful: req.Helpful,
+			Images:  req.Images,
+		})
+		return review, false, err
 	}
-	review := &models.Review{
+	uploads, err := s.validateImages(userID, req.Images)
+	if err != nil {
+		return nil, false, err
+	}
+	review = &models.Review{
 		ID:        generateID(),
 		UserID:    userID,
 		ProductID: req.ProductID,
@@ -30,9 +61,20 @@ func (s *ReviewService) CreateReview(userID string, req models.ReviewCreateReque
 		UpdatedAt: time.Now(),
 	}
 	if err := s.reviewRepo.Create(review); err != nil {
-		return nil, fmt.Errorf("failed to create review: %w", err)
+		// A concurrent request may have created the review since the check.
+		if err.Error() == "review already exists for this product" {
+			return nil, false, err
+		}
+		return nil, false, fmt.Errorf("failed to create review: %w", err)
+	}
+	if len(uploads) > 0 {
+		if err := s.reviewRepo.ReplaceImages(review.ID, uploads); err != nil {
+			return nil, false, fmt.Errorf("failed to attach review images: %w", err)
+		}
+		review.Images = reviewImagesFromUploads(review.ID, uploads)
 	}
-	return review, nil
+	invalidateRatingSummary(review.ProductID)
+	return review, true, nil
 }
 func (s *ReviewService) GetProductReviews(productID string, page, limit int) ([]models.ReviewWithUser, error) {
 	if page <= 0 {
@@ -45,29 +87,133 @@ func (s *ReviewService) GetProductReviews(productID string, page, limit int) ([]
 		limit = 50
 	}
 	offset := (page - 1) * limit
-	return s.reviewRepo.GetByProductID(productID, limit, offset)
+	reviews, err := s.reviewRepo.GetByProductID(productID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	reviewIDs := make([]string, len(reviews))
+	for i := range reviews {
+		reviewIDs[i] = reviews[i].ID
+	}
+	images, err := s.reviewRepo.GetImagesByReviewIDs(reviewIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review images: %w", err)
+	}
+	for i := range reviews {
+		reviews[i].Images = images[reviews[i].ID]
+	}
+	return reviews, nil
 }
-func (s *ReviewService) GetUserReviews(userID string, page, limit int) ([]*models.Review, error) {
-	if page <= 0 {
-		page = 1
+func (s *ReviewService) GetProductReviewSummary(productID string) (*models.ReviewSummary, error) {
+	summary, err := s.reviewRepo.GetProductSummary(productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review summary: %w", err)
 	}
-	if limit <= 0 {
-		limit = 10
+	return summary, nil
+}
+func (s *ReviewService) GetRatingSummaries(productIDs []string) ([]*models.RatingSummary, error) {
+	if len(productIDs) > maxRatingSummaryBatch {
+		return nil, fmt.Errorf("too many product ids")
 	}
-	if limit > 50 {
-		limit = 50
+	cache := utils.GetCache("reviews")
+	var ids, missing []string
+	found := make(map[string]*models.RatingSummary, len(productIDs))
+	for _, id := range productIDs {
+		if _, seen := found[id]; seen {
+			continue
+		}
+		ids = append(ids, id)
+		if cached, ok := cache.Get(ratingSummaryKey(id)); ok {
+			found[id] = cached.(*models.RatingSummary)
+			continue
+		}
+		found[id] = nil
+		missing = append(missing, id)
 	}
-	offset := (page - 1) * limit
-	return s.reviewRepo.GetByUserID(userID, limit, offset)
+	if len(missing) > 0 {
+		fetched, err := s.reviewRepo.GetRatingSummaries(missing)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rating summaries: %w", err)
+		}
+		for _, id := range missing {
+			summary, ok := fetched[id]
+			if !ok {
+				summary = &models.RatingSummary{ProductID: id}
+			}
+			cache.Set(ratingSummaryKey(id), summary, cache.TTL())
+			found[id] = summary
+		}
+	}
+	summaries := make([]*models.RatingSummary, len(ids))
+	for i, id := range ids {
+		summaries[i] = found[id]
+	}
+	return summaries, nil
 }
-func (s *ReviewService) UpdateReview(userID, reviewID string, req models.ReviewUpdateRequest) (*models.Review, error) {
+func ratingSummaryKey(productID string) string {
+	return "rating:" + productID
+}
+// invalidateRatingSummary drops the cached rating summary of productID and
+// the cached product listings, which embed ratings.
+func invalidateRatingSummary(productID string) {
+	utils.CacheInvalidate("reviews", ratingSummaryKey(productID))
+	utils.CacheInvalidatePrefix("products:")
+}
+func (s *ReviewService) GetUserReviews(userID string, query *models.ReviewQuery) ([]*models.Review, int, error) {
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 {
+		query.Limit = 10
+	}
+	if query.Limit > 50 {
+		query.Limit = 50
+	}
+	offset := (query.Page - 1) * query.Limit
+	reviews, total, err := s.reviewRepo.GetByUserID(userID, *query, query.Limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get user reviews: %w", err)
+	}
+	reviewIDs := make([]string, len(reviews))
+	for i, review := range reviews {
+		reviewIDs[i] = review.ID
+	}
+	images, err := s.reviewRepo.GetImagesByReviewIDs(reviewIDs)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get review images: %w", err)
+	}
+	for _, review := range reviews {
+		review.Images = images[review.ID]
+	}
+	return reviews, total, nil
+}
+// getReview loads a review. Only a missing review yields "review not found";
+// any other lookup failure is wrapped.
+func (s *ReviewService) getReview(reviewID string) (*models.Review, error) {
 	review, err := s.reviewRepo.GetByID(reviewID)
+	if err != nil && err.Error() != "review not found" {
+		return nil, fmt.Errorf("failed to get review: %w", err)
+	}
+	return review, err
+}
+func (s *ReviewService) UpdateReview(userID, reviewID string, req models.ReviewUpdateRequest) (*models.Review, error) {
+	review, err := s.getReview(reviewID)
 	if err != nil {
-		return nil, fmt.Errorf("review not found: %w", err)
+		return nil, err
 	}
 	if review.UserID != userID {
 		return nil, fmt.Errorf("unauthorized")
 	}
+	if errs := s.limits.Check(req.Rating, req.Comment); errs.HasErrors() {
+		return nil, errs
+	}
+	var uploads []*models.Upload
+	if req.Images != nil {
+		uploads, err = s.validateImages(userID, req.Images)
+		if err != nil {
+			return nil, err
+		}
+	}
 	updates := make(map[string]interface{})
 	if req.Rating != nil {
 		updates["rating"] = *req.Rating
@@ -78,28 +224,181 @@ func (s *ReviewService) UpdateReview(userID, reviewID string, req models.ReviewU
 	if req.Helpful != nil {
 		updates["helpful"] = *req.Helpful
 	}
+	if req.Images != nil {
+		if err := s.replaceImages(reviewID, uploads); err != nil {
+			return nil, err
+		}
+		updates["updated_at"] = time.Now()
+	}
 	if len(updates) > 0 {
 		updates["updated_at"] = time.Now()
 		if err := s.reviewRepo.Update(reviewID, updates); err != nil {
 			return nil, fmt.Errorf("failed to update review: %w", err)
 		}
+		invalidateRatingSummary(review.ProductID)
 	}
 	updatedReview, err := s.reviewRepo.GetByID(reviewID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get updated review: %w", err)
 	}
+	images, err := s.reviewRepo.GetImagesByReviewIDs([]string{reviewID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review images: %w", err)
+	}
+	updatedReview.Images = images[reviewID]
 	return updatedReview, nil
 }
 func (s *ReviewService) DeleteReview(userID, reviewID string) error {
-	review, err := s.reviewRepo.GetByID(reviewID)
+	review, err := s.getReview(reviewID)
 	if err != nil {
-		return fmt.Errorf("review not found: %w", err)
+		return err
 	}
 	if review.UserID != userID {
 		return fmt.Errorf("unauthorized")
 	}
-	return s.reviewRepo.Delete(reviewID)
+	images, err := s.reviewRepo.GetImagesByReviewIDs([]string{reviewID})
+	if err != nil {
+		return fmt.Errorf("failed to get review images: %w", err)
+	}
+	if err := s.reviewRepo.Delete(reviewID); err != nil {
+		return err
+	}
+	invalidateRatingSummary(review.ProductID)
+	s.deleteUnusedImages(images[reviewID])
+	return nil
 }
 func (s *ReviewService) GetUserReviewForProduct(userID, productID string) (*models.Review, error) {
 	return s.reviewRepo.GetUserReviewForProduct(userID, productID)
+}
+func (s *ReviewService) CreateReply(userID, reviewID string, req models.ReviewReplyRequest) (*models.ReviewReply, error) {
+	review, err := s.getReview(reviewID)
+	if err != nil {
+		return nil, err
+	}
+	existingReply, err := s.reviewRepo.GetReplyByReviewID(reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing reply: %w", err)
+	}
+	if existingReply != nil {
+		return nil, fmt.Errorf("reply already exists for this review")
+	}
+	reply := &models.ReviewReply{
+		ID:        generateID(),
+		ReviewID:  reviewID,
+		UserID:    userID,
+		Body:      req.Body,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := s.reviewRepo.CreateReply(reply); err != nil {
+		return nil, fmt.Errorf("failed to create reply: %w", err)
+	}
+	if s.hub != nil {
+		s.hub.SendReviewReply(review.UserID, review.ID, review.ProductID, reply.Body)
+	}
+	return reply, nil
+}
+func (s *ReviewService) UpdateReply(userID, userRole, reviewID string, req models.ReviewReplyRequest) (*models.ReviewReply, error) {
+	reply, err := s.reviewRepo.GetReplyByReviewID(reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reply: %w", err)
+	}
+	if reply == nil {
+		return nil, fmt.Errorf("reply not found")
+	}
+	if reply.UserID != userID && userRole != "admin" {
+		return nil, fmt.Errorf("unauthorized")
+	}
+	if err := s.reviewRepo.UpdateReply(reply.ID, req.Body); err != nil {
+		return nil, fmt.Errorf("failed to update reply: %w", err)
+	}
+	reply.Body = req.Body
+	reply.UpdatedAt = time.Now()
+	return reply, nil
+}
+func (s *ReviewService) DeleteReply(userID, userRole, reviewID string) error {
+	reply, err := s.reviewRepo.GetReplyByReviewID(reviewID)
+	if err != nil {
+		return fmt.Errorf("failed to get reply: %w", err)
+	}
+	if reply == nil {
+		return fmt.Errorf("reply not found")
+	}
+	if reply.UserID != userID && userRole != "admin" {
+		return fmt.Errorf("unauthorized")
+	}
+	return s.reviewRepo.DeleteReply(reply.ID)
+}
+func (s *ReviewService) validateImages(userID string, filenames []string) ([]*models.Upload, error) {
+	if len(filenames) == 0 {
+		return nil, nil
+	}
+	if len(filenames) > maxReviewImages {
+		return nil, fmt.Errorf("too many images: maximum %d allowed", maxReviewImages)
+	}
+	uploads, err := s.uploadService.GetUserImages(userID, filenames)
+	if err != nil {
+		return nil, err
+	}
+	var totalSize int64
+	for _, upload := range uploads {
+		totalSize += upload.Size
+	}
+	if totalSize > maxReviewImagesTotalSize {
+		return nil, fmt.Errorf("images too large: maximum total size is %d bytes", maxReviewImagesTotalSize)
+	}
+	return uploads, nil
+}
+func (s *ReviewService) replaceImages(reviewID string, uploads []*models.Upload) error {
+	current, err := s.reviewRepo.GetImagesByReviewIDs([]string{reviewID})
+	if err != nil {
+		return fmt.Errorf("failed to get review images: %w", err)
+	}
+	if err := s.reviewRepo.ReplaceImages(reviewID, uploads); err != nil {
+		return fmt.Errorf("failed to update review images: %w", err)
+	}
+	kept := make(map[string]bool, len(uploads))
+	for _, upload := range uploads {
+		kept[upload.ID] = true
+	}
+	var dropped []models.ReviewImage
+	for _, image := range current[reviewID] {
+		if !kept[image.UploadID] {
+			dropped = append(dropped, image)
+		}
+	}
+	s.deleteUnusedImages(dropped)
+	return nil
+}
+// deleteUnusedImages deletes the uploads of images removed from a review
+// unless another review still shows them. The review change is already
+// saved, so a failed delete is logged rather than returned.
+func (s *ReviewService) deleteUnusedImages(images []models.ReviewImage) {
+	for _, image := range images {
+		referenced, err := s.reviewRepo.IsUploadReferenced(image.UploadID)
+		if err != nil {
+			utils.Warn("failed to check review image references", "upload_id", image.UploadID, "error", err.Error())
+			continue
+		}
+		if referenced {
+			continue
+		}
+		if err := s.uploadService.DeleteUpload(&models.Upload{ID: image.UploadID, Filename: image.Filename}); err != nil {
+			utils.Warn("failed to delete review image", "upload_id", image.UploadID, "error", err.Error())
+		}
+	}
+}
+func reviewImagesFromUploads(reviewID string, uploads []*models.Upload) []models.ReviewImage {
+	images := make([]models.ReviewImage, len(uploads))
+	for i, upload := range uploads {
+		images[i] = models.ReviewImage{
+			ReviewID: reviewID,
+			UploadID: upload.ID,
+			Filename: upload.Filename,
+			URL:      upload.URL,
+			Size:     upload.Size,
+			Position: i,
+		}
+	}
+	return images
 }
\ No newline at end of file
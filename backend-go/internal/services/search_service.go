@@ -0,0 +1,80 @@
+﻿package services
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
+)
+const maxSuggestions = 10
+type SearchService struct {
+	productService *ProductService
+	productRepo    *repositories.ProductRepository
+	categoryRepo   *repositories.CategoryRepository
+}
+func NewSearchService(productService *ProductService, productRepo *repositories.ProductRepository, categoryRepo *repositories.CategoryRepository) *SearchService {
+	return &SearchService{productService: productService, productRepo: productRepo, categoryRepo: categoryRepo}
+}
+func (s *SearchService) Search(storeID, query string, types []string, limit int) (*models.SearchResults, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+	if limit > 20 {
+		limit = 20
+	}
+	if len(types) == 0 {
+		types = []string{models.SearchTypeProducts, models.SearchTypeCategories}
+	}
+	results := &models.SearchResults{Query: query}
+	for _, searchType := range types {
+		switch searchType {
+		case models.SearchTypeProducts:
+			products, err := s.productService.SearchProducts(storeID, query, limit)
+			if err != nil {
+				return nil, err
+			}
+			results.Products = products
+		case models.SearchTypeCategories:
+			categories, err := s.categoryRepo.Search(storeID, query, limit)
+			if err != nil {
+				return nil, fmt.Errorf("failed to search categories: %w", err)
+			}
+			results.Categories = categories
+		default:
+			return nil, fmt.Errorf("unsupported search type: %s", searchType)
+		}
+	}
+	return results, nil
+}
+func (s *SearchService) Suggest(storeID, prefix string) ([]models.SearchSuggestion, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return []models.SearchSuggestion{}, nil
+	}
+	result, err := utils.CacheGetOrSet("search", "suggest:"+storeID+":"+prefix, 0, func() (interface{}, error) {
+		products, err := s.productRepo.SuggestByPrefix(storeID, prefix, maxSuggestions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to suggest products: %w", err)
+		}
+		categories, err := s.categoryRepo.SuggestByPrefix(storeID, prefix, maxSuggestions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to suggest categories: %w", err)
+		}
+		suggestions := append(products, categories...)
+		sort.SliceStable(suggestions, func(i, j int) bool {
+			return suggestions[i].Score > suggestions[j].Score
+		})
+		if len(suggestions) > maxSuggestions {
+			suggestions = suggestions[:maxSuggestions]
+		}
+		if suggestions == nil {
+			suggestions = []models.SearchSuggestion{}
+		}
+		return suggestions, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]models.SearchSuggestion), nil
+}
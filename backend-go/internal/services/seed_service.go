@@ -0,0 +1,62 @@
+﻿package services
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/seeds"
+)
+// SeedAll runs every registered seeder.
+const SeedAll = "all"
+// SeedService runs seeders on the job queue. Only one run per seed type may
+// be in flight, and an "all" run excludes every other type.
+type SeedService struct {
+	db      *sql.DB
+	jobs    *JobQueue
+	types   map[string]bool
+	mu      sync.Mutex
+	running map[string]bool
+}
+func NewSeedService(db *sql.DB, jobs *JobQueue) *SeedService {
+	types := map[string]bool{SeedAll: true}
+	for _, name := range seeds.NewSeedManagerWithDB(db).ListAvailableSeeders() {
+		types[name] = true
+	}
+	return &SeedService{db: db, jobs: jobs, types: types, running: make(map[string]bool)}
+}
+// Start queues a seed run and returns its job for polling. requestID ties
+// the job to the request that started it.
+func (s *SeedService) Start(seedType, requestID string) (models.Job, error) {
+	if !s.types[seedType] {
+		return models.Job{}, fmt.Errorf("unknown seed type")
+	}
+	if !s.acquire(seedType) {
+		return models.Job{}, fmt.Errorf("seed already running")
+	}
+	return s.jobs.SubmitFor(requestID, "seed:"+seedType, func() error {
+		manager := seeds.NewSeedManagerWithDB(s.db)
+		if seedType == SeedAll {
+			return manager.Run()
+		}
+		return manager.RunSpecific([]string{seedType})
+	}, func(error) {
+		s.release(seedType)
+	}), nil
+}
+func (s *SeedService) GetJob(id string) (models.Job, bool) {
+	return s.jobs.Get(id)
+}
+func (s *SeedService) acquire(seedType string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[seedType] || s.running[SeedAll] || (seedType == SeedAll && len(s.running) > 0) {
+		return false
+	}
+	s.running[seedType] = true
+	return true
+}
+func (s *SeedService) release(seedType string) {
+	s.mu.Lock()
+	delete(s.running, seedType)
+	s.mu.Unlock()
+}
@@ -0,0 +1,205 @@
+﻿package services
+import (
+	"fmt"
+	"strings"
+	"time"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/websocket"
+	"github.com/google/uuid"
+)
+type ShipmentService struct {
+	shipmentRepo *repositories.ShipmentRepository
+	orderRepo    *repositories.OrderRepository
+	hub          *websocket.Hub
+}
+func NewShipmentService(shipmentRepo *repositories.ShipmentRepository, orderRepo *repositories.OrderRepository, hub *websocket.Hub) *ShipmentService {
+	return &ShipmentService{shipmentRepo: shipmentRepo, orderRepo: orderRepo, hub: hub}
+}
+func (s *ShipmentService) GetShipments(orderID string) ([]models.Shipment, error) {
+	if _, err := s.orderRepo.GetOrderByID(orderID); err != nil {
+		return nil, fmt.Errorf("order not found")
+	}
+	return s.shipmentRepo.GetByOrderID(orderID)
+}
+func (s *ShipmentService) CreateShipment(orderID string, req models.ShipmentCreateRequest) (*models.Shipment, error) {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("order not found")
+	}
+	if !order.Status.Shippable() {
+		return nil, fmt.Errorf("order cannot be shipped")
+	}
+	ordered, err := s.orderedQuantities(orderID)
+	if err != nil {
+		return nil, err
+	}
+	remaining, err := s.remainingQuantities(orderID)
+	if err != nil {
+		return nil, err
+	}
+	items := req.Items
+	if len(items) == 0 {
+		for orderItemID, quantity := range remaining {
+			if quantity > 0 {
+				items = append(items, models.ShipmentItem{OrderItemID: orderItemID, Quantity: quantity})
+			}
+		}
+	} else {
+		orderItems, err := s.orderRepo.GetOrderItems(orderID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get order items: %w", err)
+		}
+		units := make(map[string]string, len(orderItems))
+		for _, item := range models.FulfillmentItems(orderItems) {
+			units[item.ID] = item.Unit
+		}
+		seen := make(map[string]bool)
+		for _, item := range items {
+			left, ok := remaining[item.OrderItemID]
+			if !ok {
+				return nil, fmt.Errorf("order item %s does not belong to this order", item.OrderItemID)
+			}
+			if seen[item.OrderItemID] {
+				return nil, fmt.Errorf("order item %s listed more than once", item.OrderItemID)
+			}
+			seen[item.OrderItemID] = true
+			if item.Quantity <= 0 || item.Quantity > left {
+				return nil, fmt.Errorf("invalid quantity for order item %s: %s remaining", item.OrderItemID, left)
+			}
+			if err := models.CheckQuantity(units[item.OrderItemID], item.Quantity); err != nil {
+				return nil, fmt.Errorf("invalid quantity for order item %s: %w", item.OrderItemID, err)
+			}
+		}
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("order has no unfulfilled items")
+	}
+	now := time.Now()
+	shipment := &models.Shipment{
+		ID:             uuid.New().String(),
+		OrderID:        orderID,
+		Carrier:        req.Carrier,
+		TrackingNumber: req.TrackingNumber,
+		Status:         models.ShipmentStatusShipped,
+		Items:          items,
+		ShippedAt:      now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	// The repository re-checks status and quantities under a lock on the
+	// order, in case another shipment was created since they were read.
+	if err := s.shipmentRepo.Create(shipment, ordered); err != nil {
+		if err.Error() == "order cannot be shipped" || strings.HasPrefix(err.Error(), "invalid quantity") {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create shipment: %w", err)
+	}
+	if err := s.syncOrderStatus(order, fmt.Sprintf("Shipment %s has been dispatched", shipment.ID)); err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+func (s *ShipmentService) UpdateShipment(orderID, shipmentID string, req models.ShipmentUpdateRequest) (*models.Shipment, error) {
+	order, err := s.orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("order not found")
+	}
+	shipment, err := s.shipmentRepo.GetByID(shipmentID)
+	if err != nil || shipment.OrderID != orderID {
+		return nil, fmt.Errorf("shipment not found")
+	}
+	if req.Carrier != nil {
+		shipment.Carrier = req.Carrier
+	}
+	if req.TrackingNumber != nil {
+		shipment.TrackingNumber = req.TrackingNumber
+	}
+	if req.Status != nil {
+		shipment.Status = *req.Status
+		if shipment.Status == models.ShipmentStatusDelivered {
+			now := time.Now()
+			shipment.DeliveredAt = &now
+		} else {
+			shipment.DeliveredAt = nil
+		}
+	}
+	shipment.UpdatedAt = time.Now()
+	if err := s.shipmentRepo.Update(shipment); err != nil {
+		return nil, fmt.Errorf("failed to update shipment: %w", err)
+	}
+	if err := s.syncOrderStatus(order, fmt.Sprintf("Shipment %s is now %s", shipment.ID, shipment.Status)); err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+// remainingQuantities is keyed by the order item ids shipments refer to,
+// which for a bundle are its component lines.
+func (s *ShipmentService) remainingQuantities(orderID string) (map[string]models.Quantity, error) {
+	ordered, err := s.orderedQuantities(orderID)
+	if err != nil {
+		return nil, err
+	}
+	shipped, err := s.shipmentRepo.GetShippedQuantities(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipped quantities: %w", err)
+	}
+	remaining := make(map[string]models.Quantity, len(ordered))
+	for id, quantity := range ordered {
+		remaining[id] = quantity - shipped[id]
+	}
+	return remaining, nil
+}
+// orderedQuantities is keyed like remainingQuantities.
+func (s *ShipmentService) orderedQuantities(orderID string) (map[string]models.Quantity, error) {
+	orderItems, err := s.orderRepo.GetOrderItems(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+	ordered := make(map[string]models.Quantity, len(orderItems))
+	for _, item := range models.FulfillmentItems(orderItems) {
+		ordered[item.ID] = item.Quantity
+	}
+	return ordered, nil
+}
+func (s *ShipmentService) syncOrderStatus(order *models.Order, message string) error {
+	remaining, err := s.remainingQuantities(order.ID)
+	if err != nil {
+		return err
+	}
+	shipments, err := s.shipmentRepo.GetByOrderID(order.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get shipments: %w", err)
+	}
+	fullyShipped := true
+	for _, quantity := range remaining {
+		if quantity > 0 {
+			fullyShipped = false
+			break
+		}
+	}
+	allDelivered := len(shipments) > 0
+	for _, shipment := range shipments {
+		if shipment.Status != models.ShipmentStatusDelivered {
+			allDelivered = false
+			break
+		}
+	}
+	status := models.OrderStatusPartiallyShipped
+	if fullyShipped && allDelivered {
+		status = models.OrderStatusDelivered
+	} else if fullyShipped {
+		status = models.OrderStatusShipped
+	}
+	if order.Status != status {
+		order.Status = status
+		order.UpdatedAt = time.Now()
+		if err := s.orderRepo.UpdateOrder(order); err != nil {
+			return fmt.Errorf("failed to update order status: %w", err)
+		}
+	}
+	if s.hub != nil {
+		s.hub.SendOrderUpdate(order.ID, string(order.Status), message, order.UserID)
+	}
+	return nil
+}
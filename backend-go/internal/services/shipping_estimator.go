@@ -0,0 +1,144 @@
+﻿package services
+import (
+	"fmt"
+	"strings"
+	"time"
+	"ecommerce-backend/internal/config"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/utils"
+)
+type ShippingEstimator struct {
+	originCountry         string
+	flatRate              models.Money
+	freeShippingThreshold models.Money
+	expressSurcharge      models.Money
+	internationalRate     models.Money
+	remoteAreaSurcharge   models.Money
+}
+// US postal code prefixes outside the contiguous states.
+var remotePostalPrefixes = map[string][]string{
+	"US": {"006", "007", "008", "009", "967", "968", "995", "996", "997", "998", "999"},
+}
+func NewShippingEstimator(orders config.OrdersConfig) *ShippingEstimator {
+	return &ShippingEstimator{
+		originCountry:         orders.OriginCountry,
+		flatRate:              models.MoneyFromFloat(orders.ShippingFlatRate),
+		freeShippingThreshold: models.MoneyFromFloat(orders.FreeShippingThreshold),
+		expressSurcharge:      models.MoneyFromFloat(orders.ExpressSurcharge),
+		internationalRate:     models.MoneyFromFloat(orders.InternationalRate),
+		remoteAreaSurcharge:   models.MoneyFromFloat(orders.RemoteAreaSurcharge),
+	}
+}
+func (e *ShippingEstimator) FreeShippingThreshold() models.Money {
+	return e.freeShippingThreshold
+}
+func (e *ShippingEstimator) QualifiesForFreeShipping(subtotal models.Money) bool {
+	return e.freeShippingThreshold > 0 && subtotal >= e.freeShippingThreshold
+}
+// DefaultCost is the standard domestic rate, shown in cart totals before a
+// destination is known.
+func (e *ShippingEstimator) DefaultCost(subtotal models.Money) models.Money {
+	cost, _ := e.Cost("", "", "", subtotal)
+	return cost
+}
+// Cost prices one shipping method to a destination. Checkout charges it and
+// Estimate quotes it, so the two always agree. An empty country means the
+// origin country and an empty method means standard.
+func (e *ShippingEstimator) Cost(country, postalCode, method string, subtotal models.Money) (models.Money, error) {
+	country, postalCode = normalizeDestination(country, postalCode)
+	if country == "" {
+		country = e.originCountry
+	}
+	if method == "" {
+		method = models.ShippingMethodStandard
+	}
+	rates, err := e.cachedRates(country, postalCode)
+	if err != nil {
+		return 0, err
+	}
+	for _, rate := range rates {
+		if rate.Code == method {
+			return e.charge(rate, country, subtotal), nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported shipping method")
+}
+func (e *ShippingEstimator) Estimate(country, postalCode string, subtotal models.Money) (*models.ShippingEstimate, error) {
+	country, postalCode = normalizeDestination(country, postalCode)
+	rates, err := e.cachedRates(country, postalCode)
+	if err != nil {
+		return nil, err
+	}
+	methods := make([]models.ShippingMethod, len(rates))
+	now := time.Now()
+	for i, method := range rates {
+		method.Cost = e.charge(method, country, subtotal)
+		method.Free = method.Cost == 0
+		earliest := addBusinessDays(now, method.MinDays)
+		latest := addBusinessDays(now, method.MaxDays)
+		method.EarliestDate = &earliest
+		method.LatestDate = &latest
+		methods[i] = method
+	}
+	return &models.ShippingEstimate{
+		Country:    country,
+		PostalCode: postalCode,
+		Subtotal:   subtotal,
+		Methods:    methods,
+	}, nil
+}
+func normalizeDestination(country, postalCode string) (string, string) {
+	return strings.ToUpper(strings.TrimSpace(country)), strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(postalCode), " ", ""))
+}
+func (e *ShippingEstimator) cachedRates(country, postalCode string) ([]models.ShippingMethod, error) {
+	result, err := utils.CacheGetOrSet("shipping", fmt.Sprintf("rates:%s:%s", country, postalCode), 0, func() (interface{}, error) {
+		return e.rates(country, postalCode), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]models.ShippingMethod), nil
+}
+// charge applies free shipping, which only covers standard domestic
+// delivery, to a method's rate.
+func (e *ShippingEstimator) charge(method models.ShippingMethod, country string, subtotal models.Money) models.Money {
+	if method.Code == models.ShippingMethodStandard && country == e.originCountry && e.QualifiesForFreeShipping(subtotal) {
+		return 0
+	}
+	return method.Cost
+}
+func (e *ShippingEstimator) rates(country, postalCode string) []models.ShippingMethod {
+	if country != e.originCountry {
+		return []models.ShippingMethod{
+			{Code: models.ShippingMethodStandard, Name: "International Standard", Cost: e.internationalRate, MinDays: 7, MaxDays: 14},
+			{Code: models.ShippingMethodExpress, Name: "International Express", Cost: e.internationalRate + e.expressSurcharge, MinDays: 3, MaxDays: 5},
+		}
+	}
+	standard := models.ShippingMethod{Code: models.ShippingMethodStandard, Name: "Standard", Cost: e.flatRate, MinDays: 3, MaxDays: 5}
+	express := models.ShippingMethod{Code: models.ShippingMethodExpress, Name: "Express", Cost: e.flatRate + e.expressSurcharge, MinDays: 1, MaxDays: 2}
+	if isRemotePostalCode(country, postalCode) {
+		standard.Cost += e.remoteAreaSurcharge
+		standard.MinDays, standard.MaxDays = 5, 8
+		express.Cost += e.remoteAreaSurcharge
+		express.MinDays, express.MaxDays = 2, 4
+	}
+	return []models.ShippingMethod{standard, express}
+}
+func isRemotePostalCode(country, postalCode string) bool {
+	for _, prefix := range remotePostalPrefixes[country] {
+		if strings.HasPrefix(postalCode, prefix) {
+			return true
+		}
+	}
+	return false
+}
+func addBusinessDays(from time.Time, days int) time.Time {
+	date := from
+	for days > 0 {
+		date = date.AddDate(0, 0, 1)
+		if date.Weekday() != time.Saturday && date.Weekday() != time.Sunday {
+			days--
+		}
+	}
+	return date
+}
@@ -0,0 +1,150 @@
+﻿package services
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
+	"github.com/google/uuid"
+)
+var storeSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+type StoreService struct {
+	storeRepo  *repositories.StoreRepository
+	baseDomain string
+}
+// NewStoreService creates the store service. baseDomain enables subdomain
+// resolution: with "shop.example.com", "acme.shop.example.com" resolves to
+// the store with slug "acme".
+func NewStoreService(storeRepo *repositories.StoreRepository, baseDomain string) *StoreService {
+	return &StoreService{storeRepo: storeRepo, baseDomain: strings.ToLower(strings.TrimPrefix(baseDomain, "."))}
+}
+// Resolve returns the active store identified by an ID or a slug.
+func (s *StoreService) Resolve(identifier string) (*models.Store, error) {
+	identifier = strings.TrimSpace(identifier)
+	result, err := utils.CacheGetOrSet("stores", "id:"+identifier, 0, func() (interface{}, error) {
+		if _, err := uuid.Parse(identifier); err == nil {
+			return s.storeRepo.GetByID(identifier)
+		}
+		return s.storeRepo.GetBySlug(strings.ToLower(identifier))
+	})
+	if err != nil {
+		return nil, err
+	}
+	store := result.(*models.Store)
+	if !store.Active {
+		return nil, fmt.Errorf("store not found")
+	}
+	return store, nil
+}
+// ResolveHost returns the active store serving host, matched first against
+// custom store domains and then as a subdomain of the base domain.
+func (s *StoreService) ResolveHost(host string) (*models.Store, error) {
+	host = strings.ToLower(host)
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	if host == "" {
+		return nil, fmt.Errorf("store not found")
+	}
+	// Misses are cached too, since every request without a store header or
+	// claim ends up here.
+	result, err := utils.CacheGetOrSet("stores", "host:"+host, 0, func() (interface{}, error) {
+		store, err := s.storeRepo.GetByDomain(host)
+		if err != nil && err.Error() == "store not found" {
+			return (*models.Store)(nil), nil
+		}
+		return store, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if store := result.(*models.Store); store != nil {
+		if !store.Active {
+			return nil, fmt.Errorf("store not found")
+		}
+		return store, nil
+	}
+	if s.baseDomain == "" || !strings.HasSuffix(host, "."+s.baseDomain) {
+		return nil, fmt.Errorf("store not found")
+	}
+	subdomain := strings.TrimSuffix(host, "."+s.baseDomain)
+	if !storeSlugPattern.MatchString(subdomain) {
+		return nil, fmt.Errorf("store not found")
+	}
+	return s.Resolve(subdomain)
+}
+func (s *StoreService) List() ([]models.Store, error) {
+	return s.storeRepo.List()
+}
+func (s *StoreService) Get(id string) (*models.Store, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("store not found")
+	}
+	return s.storeRepo.GetByID(id)
+}
+func (s *StoreService) Create(req models.StoreCreateRequest) (*models.Store, error) {
+	slug := strings.ToLower(strings.TrimSpace(req.Slug))
+	if !storeSlugPattern.MatchString(slug) {
+		return nil, fmt.Errorf("invalid store slug")
+	}
+	if _, err := s.storeRepo.GetBySlug(slug); err == nil {
+		return nil, fmt.Errorf("store already exists")
+	}
+	store := &models.Store{
+		Slug:      slug,
+		Name:      req.Name,
+		Domain:    normalizeStoreDomain(req.Domain),
+		Active:    true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if store.Domain != nil {
+		if _, err := s.storeRepo.GetByDomain(*store.Domain); err == nil {
+			return nil, fmt.Errorf("store domain already in use")
+		}
+	}
+	if err := s.storeRepo.Create(store); err != nil {
+		return nil, err
+	}
+	utils.CacheInvalidatePrefix("stores:")
+	return store, nil
+}
+func (s *StoreService) Update(id string, req models.StoreUpdateRequest) (*models.Store, error) {
+	store, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		store.Name = *req.Name
+	}
+	if req.Domain != nil {
+		store.Domain = normalizeStoreDomain(*req.Domain)
+		if store.Domain != nil {
+			if existing, err := s.storeRepo.GetByDomain(*store.Domain); err == nil && existing.ID != store.ID {
+				return nil, fmt.Errorf("store domain already in use")
+			}
+		}
+	}
+	if req.Active != nil {
+		if !*req.Active && store.ID == models.DefaultStoreID {
+			return nil, fmt.Errorf("default store cannot be deactivated")
+		}
+		store.Active = *req.Active
+	}
+	store.UpdatedAt = time.Now()
+	if err := s.storeRepo.Update(store); err != nil {
+		return nil, err
+	}
+	utils.CacheInvalidatePrefix("stores:")
+	return store, nil
+}
+func normalizeStoreDomain(domain string) *string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if domain == "" {
+		return nil
+	}
+	return &domain
+}
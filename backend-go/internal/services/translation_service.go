@@ -0,0 +1,160 @@
+﻿package services
+import (
+	"fmt"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
+)
+type TranslationService struct {
+	translationRepo *repositories.TranslationRepository
+	productRepo     *repositories.ProductRepository
+	categoryRepo    *repositories.CategoryRepository
+	defaultLocale   string
+	locales         []string
+}
+func NewTranslationService(translationRepo *repositories.TranslationRepository, productRepo *repositories.ProductRepository, categoryRepo *repositories.CategoryRepository, defaultLocale string, locales []string) *TranslationService {
+	return &TranslationService{
+		translationRepo: translationRepo,
+		productRepo:     productRepo,
+		categoryRepo:    categoryRepo,
+		defaultLocale:   defaultLocale,
+		locales:         locales,
+	}
+}
+func (s *TranslationService) ResolveLocale(requested, acceptLanguage string) string {
+	return utils.NegotiateLocale(requested, acceptLanguage, s.locales, s.defaultLocale)
+}
+// LocalizeProducts overwrites names and descriptions in place. Products
+// without a translation keep their default-locale content.
+func (s *TranslationService) LocalizeProducts(products []*models.Product, locale string) {
+	if locale == "" || locale == s.defaultLocale || len(products) == 0 {
+		return
+	}
+	ids := make([]string, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+	translations, err := s.translationRepo.GetProductTranslations(ids, locale)
+	if err != nil {
+		utils.Warn("failed to load product translations", "locale", locale, "error", err.Error())
+		return
+	}
+	for _, product := range products {
+		if t, ok := translations[product.ID]; ok {
+			product.Name = t.Name
+			if t.Description != nil {
+				product.Description = t.Description
+			}
+		}
+	}
+}
+func (s *TranslationService) LocalizeCategories(categories []*models.Category, locale string) {
+	if locale == "" || locale == s.defaultLocale || len(categories) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(categories))
+	for _, category := range categories {
+		if category != nil {
+			ids = append(ids, category.ID)
+		}
+	}
+	translations, err := s.translationRepo.GetCategoryTranslations(ids, locale)
+	if err != nil {
+		utils.Warn("failed to load category translations", "locale", locale, "error", err.Error())
+		return
+	}
+	for _, category := range categories {
+		if category == nil {
+			continue
+		}
+		if t, ok := translations[category.ID]; ok {
+			category.Name = t.Name
+			if t.Description != nil {
+				category.Description = t.Description
+			}
+		}
+	}
+}
+func (s *TranslationService) ListProductTranslations(storeID, productID string) ([]models.Translation, error) {
+	if _, err := s.productRepo.GetByID(storeID, productID); err != nil {
+		return nil, fmt.Errorf("product not found")
+	}
+	translations, err := s.translationRepo.ListProductTranslations(productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product translations: %w", err)
+	}
+	return translations, nil
+}
+func (s *TranslationService) SetProductTranslation(storeID, productID, locale string, req models.TranslationRequest) (*models.Translation, error) {
+	locale, err := s.validateLocale(locale)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.productRepo.GetByID(storeID, productID); err != nil {
+		return nil, fmt.Errorf("product not found")
+	}
+	translation := &models.Translation{EntityID: productID, Locale: locale, Name: req.Name, Description: req.Description}
+	if err := s.translationRepo.UpsertProductTranslation(translation); err != nil {
+		return nil, fmt.Errorf("failed to save product translation: %w", err)
+	}
+	utils.CacheInvalidatePrefix("products:")
+	return translation, nil
+}
+func (s *TranslationService) DeleteProductTranslation(storeID, productID, locale string) error {
+	if _, err := s.productRepo.GetByID(storeID, productID); err != nil {
+		return fmt.Errorf("product not found")
+	}
+	if err := s.translationRepo.DeleteProductTranslation(productID, utils.NormalizeLocale(locale)); err != nil {
+		return err
+	}
+	utils.CacheInvalidatePrefix("products:")
+	return nil
+}
+func (s *TranslationService) ListCategoryTranslations(storeID, slug string) ([]models.Translation, error) {
+	category, err := s.categoryRepo.GetCategoryBySlug(storeID, slug)
+	if err != nil {
+		return nil, fmt.Errorf("category not found")
+	}
+	translations, err := s.translationRepo.ListCategoryTranslations(category.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category translations: %w", err)
+	}
+	return translations, nil
+}
+func (s *TranslationService) SetCategoryTranslation(storeID, slug, locale string, req models.TranslationRequest) (*models.Translation, error) {
+	locale, err := s.validateLocale(locale)
+	if err != nil {
+		return nil, err
+	}
+	category, err := s.categoryRepo.GetCategoryBySlug(storeID, slug)
+	if err != nil {
+		return nil, fmt.Errorf("category not found")
+	}
+	translation := &models.Translation{EntityID: category.ID, Locale: locale, Name: req.Name, Description: req.Description}
+	if err := s.translationRepo.UpsertCategoryTranslation(translation); err != nil {
+		return nil, fmt.Errorf("failed to save category translation: %w", err)
+	}
+	utils.CacheInvalidatePrefix("products:")
+	return translation, nil
+}
+func (s *TranslationService) DeleteCategoryTranslation(storeID, slug, locale string) error {
+	category, err := s.categoryRepo.GetCategoryBySlug(storeID, slug)
+	if err != nil {
+		return fmt.Errorf("category not found")
+	}
+	if err := s.translationRepo.DeleteCategoryTranslation(category.ID, utils.NormalizeLocale(locale)); err != nil {
+		return err
+	}
+	utils.CacheInvalidatePrefix("products:")
+	return nil
+}
+func (s *TranslationService) validateLocale(locale string) (string, error) {
+	locale = utils.NormalizeLocale(locale)
+	if locale == s.defaultLocale {
+		return "", fmt.Errorf("default locale is edited on the entity itself")
+	}
+	if !utils.Contains(s.locales, locale) {
+		return "", fmt.Errorf("unsupported locale")
+	}
+	return locale, nil
+}
@@ -0,0 +1,223 @@
+﻿package services
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/utils"
+	_ "golang.org/x/image/webp"
+)
+type UploadService struct {
+	uploadRepo      *repositories.UploadRepository
+	uploadPath      string
+	userQuotaBytes  int64
+	adminQuotaBytes int64
+	signingSecret   string
+	signedURLTTL    time.Duration
+	productImages   models.ImageConstraints
+}
+func NewUploadService(uploadRepo *repositories.UploadRepository, uploadPath string, userQuotaBytes, adminQuotaBytes int64, signingSecret string, signedURLTTL time.Duration, productImages models.ImageConstraints) *UploadService {
+	return &UploadService{uploadRepo: uploadRepo, uploadPath: uploadPath, userQuotaBytes: userQuotaBytes, adminQuotaBytes: adminQuotaBytes, signingSecret: signingSecret, signedURLTTL: signedURLTTL, productImages: productImages}
+}
+func (s *UploadService) ProductImageConstraints() models.ImageConstraints {
+	return s.productImages
+}
+// CheckProductImage reads the image header from file, rewinding it
+// afterwards, and checks the dimensions against the product image
+// constraints. It returns the dimensions so they can be reported back.
+func (s *UploadService) CheckProductImage(file io.ReadSeeker) (int, int, error) {
+	config, _, err := image.DecodeConfig(file)
+	if _, seekErr := file.Seek(0, io.SeekStart); seekErr != nil {
+		return 0, 0, fmt.Errorf("failed to rewind image: %w", seekErr)
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid image")
+	}
+	return config.Width, config.Height, s.productImages.Check(config.Width, config.Height)
+}
+// setURL fills in upload.URL. Public uploads get a plain path; private ones
+// get a path signed with an expiry signedURLTTL from now.
+func (s *UploadService) setURL(upload *models.Upload) {
+	path := fmt.Sprintf("/uploads/%s", upload.Filename)
+	if !upload.Private {
+		upload.URL = path
+		upload.URLExpires = nil
+		return
+	}
+	expires := time.Now().Add(s.signedURLTTL).Truncate(time.Second)
+	upload.URL = fmt.Sprintf("%s?expires=%d&signature=%s", path, expires.Unix(), utils.SignPath(s.signingSecret, path, expires))
+	upload.URLExpires = &expires
+}
+// AuthorizeServe decides whether filename may be served for the given
+// expires and signature query values. Files without an upload record and
+// public uploads need no signature.
+func (s *UploadService) AuthorizeServe(filename, expires, signature string) (*models.Upload, error) {
+	upload, err := s.uploadRepo.GetByFilename(filename)
+	if err != nil {
+		if err.Error() == "upload not found" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !upload.Private {
+		return upload, nil
+	}
+	if err := utils.VerifyPathSignature(s.signingSecret, fmt.Sprintf("/uploads/%s", filename), expires, signature, time.Now()); err != nil {
+		return upload, err
+	}
+	return upload, nil
+}
+// SignedURL issues a fresh URL for an upload owned by userID; admins may
+// sign any upload.
+func (s *UploadService) SignedURL(userID, role, filename string) (*models.Upload, error) {
+	upload, err := s.uploadRepo.GetByFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+	if upload.UserID != userID && role != "admin" {
+		return nil, fmt.Errorf("unauthorized")
+	}
+	s.setURL(upload)
+	return upload, nil
+}
+func (s *UploadService) GetUsage(userID, role string) (*models.UploadUsage, error) {
+	used, err := s.uploadRepo.GetTotalSizeByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload usage: %w", err)
+	}
+	quota := s.quota(role)
+	usage := &models.UploadUsage{UsedBytes: used, QuotaBytes: quota, Unlimited: quota <= 0}
+	if !usage.Unlimited && quota > used {
+		usage.RemainingBytes = quota - used
+	}
+	return usage, nil
+}
+func (s *UploadService) quota(role string) int64 {
+	if role == "admin" {
+		return s.adminQuotaBytes
+	}
+	return s.userQuotaBytes
+}
+// CheckQuota is a quick check before the file is written; RecordUpload
+// enforces the quota again atomically.
+func (s *UploadService) CheckQuota(userID, role string, size int64) (*models.UploadUsage, error) {
+	usage, err := s.GetUsage(userID, role)
+	if err != nil {
+		return nil, err
+	}
+	if !usage.Unlimited && size > usage.RemainingBytes {
+		return usage, fmt.Errorf("upload quota exceeded")
+	}
+	return usage, nil
+}
+func (s *UploadService) RecordUpload(userID, role, filename, contentType string, size int64, private bool) (*models.Upload, error) {
+	upload := &models.Upload{
+		ID:          generateID(),
+		UserID:      userID,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+		Private:     private,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.uploadRepo.CreateWithinQuota(upload, s.quota(role)); err != nil {
+		if err.Error() == "upload quota exceeded" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to record upload: %w", err)
+	}
+	s.setURL(upload)
+	return upload, nil
+}
+func (s *UploadService) ListUserUploads(userID string, page, limit int) ([]*models.Upload, int, error) {
+	uploads, total, err := s.uploadRepo.GetByUserID(userID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list uploads: %w", err)
+	}
+	for _, upload := range uploads {
+		s.setURL(upload)
+	}
+	return uploads, total, nil
+}
+func (s *UploadService) GetUserImages(userID string, filenames []string) ([]*models.Upload, error) {
+	uploads, err := s.uploadRepo.GetByFilenames(filenames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get uploads: %w", err)
+	}
+	byName := make(map[string]*models.Upload, len(uploads))
+	for _, upload := range uploads {
+		byName[upload.Filename] = upload
+	}
+	result := make([]*models.Upload, 0, len(filenames))
+	for _, filename := range filenames {
+		upload, ok := byName[filename]
+		if !ok {
+			return nil, fmt.Errorf("upload %s not found", filename)
+		}
+		if upload.UserID != userID {
+			return nil, fmt.Errorf("upload %s does not belong to user", filename)
+		}
+		if !strings.HasPrefix(upload.ContentType, "image/") {
+			return nil, fmt.Errorf("upload %s is not an image", filename)
+		}
+		if upload.Private {
+			return nil, fmt.Errorf("upload %s is private", filename)
+		}
+		s.setURL(upload)
+		result = append(result, upload)
+	}
+	return result, nil
+}
+func (s *UploadService) DeleteUpload(upload *models.Upload) error {
+	if err := s.uploadRepo.Delete(upload.ID); err != nil {
+		return fmt.Errorf("failed to delete upload: %w", err)
+	}
+	if err := os.Remove(filepath.Join(s.uploadPath, upload.Filename)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+// DeleteUserUploads deletes the files userID uploaded, keeping any the
+// catalog still serves.
+func (s *UploadService) DeleteUserUploads(userID string) error {
+	uploads, err := s.uploadRepo.GetErasableByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to list uploads: %w", err)
+	}
+	for _, upload := range uploads {
+		if err := s.DeleteUpload(upload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+// DeleteByFilename deletes an upload owned by userID; admins may delete any
+// upload. Legacy files written before uploads were recorded have no owner
+// row and are removed from disk directly.
+func (s *UploadService) DeleteByFilename(userID, role, filename string) error {
+	upload, err := s.uploadRepo.GetByFilename(filename)
+	if err != nil {
+		if err.Error() != "upload not found" {
+			return err
+		}
+		if err := os.Remove(filepath.Join(s.uploadPath, filepath.Base(filename))); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("upload not found")
+			}
+			return fmt.Errorf("failed to delete file: %w", err)
+		}
+		return nil
+	}
+	if upload.UserID != userID && role != "admin" {
+		return fmt.Errorf("unauthorized")
+	}
+	return s.DeleteUpload(upload)
+}
@@ -6,16 +6,23 @@ import (
 	"time"
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/repositories"
-	"golang.org/x/crypto/bcrypt"
+	"ecommerce-backend/internal/utils"
 )
 type UserService struct {
 	userRepo *repositories.UserRepository
+	hasher   *utils.PasswordHasher
 }
-func NewUserService(userRepo *repositories.UserRepository) *UserService {
-	return &UserService{userRepo: userRepo}
+func NewUserService(userRepo *repositories.UserRepository, hasher *utils.PasswordHasher) *UserService {
+	return &UserService{userRepo: userRepo, hasher: hasher}
 }
+// CreateUser registers an account. A taken email, in any letter case,
+// fails with "email already registered".
 func (s *UserService) CreateUser(req models.UserCreateRequest) (*models.UserResponse, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	req.Email = utils.NormalizeEmail(req.Email)
+	if _, err := s.userRepo.GetByEmail(req.Email); err == nil {
+		return nil, fmt.Errorf("email already registered")
+	}
+	hashedPassword, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -23,19 +30,67 @@ func (s *UserService) CreateUser(req models.UserCreateRequest) (*models.UserResp
 		ID:        generateID(),
 		Email:     req.Email,
 		Name:      &req.Name,
-		Password:  string(hashedPassword),
+		Password:  hashedPassword,
 		Role:      "user",
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 	if err := s.userRepo.Create(user); err != nil {
+		if err.Error() == "email already registered" {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 	response := user.ToResponse()
 	return &response, nil
 }
+// CreateFirstAdmin creates an admin account for bootstrapping a new
+// install. When any admin already exists nothing is changed and created is
+// false, so it is safe to run repeatedly.
+func (s *UserService) CreateFirstAdmin(email, password, name string) (*models.UserResponse, bool, error) {
+	exists, err := s.userRepo.HasRole("admin")
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to check for admins: %w", err)
+	}
+	if exists {
+		return nil, false, nil
+	}
+	email = utils.NormalizeEmail(email)
+	if email == "" || password == "" {
+		return nil, false, fmt.Errorf("email and password are required")
+	}
+	if err := utils.ValidateEmail(email); err != nil {
+		return nil, false, err
+	}
+	if err := utils.ValidatePassword(password); err != nil {
+		return nil, false, err
+	}
+	if _, err := s.userRepo.GetByEmail(email); err == nil {
+		return nil, false, fmt.Errorf("a user with email %s already exists", email)
+	}
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to hash password: %w", err)
+	}
+	admin := &models.User{
+		ID:        generateID(),
+		Email:     email,
+		Password:  hashedPassword,
+		Role:      "admin",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if name != "" {
+		admin.Name = &name
+	}
+	if err := s.userRepo.Create(admin); err != nil {
+		return nil, false, fmt.Errorf("failed to create admin: %w", err)
+	}
+	response := admin.ToResponse()
+	return &response, true, nil
+}
 func (s *UserService) GetUserByEmail(email string) (*models.User, error) {
-	return s.userRepo.GetByEmail(email)
+	return s.userRepo.GetByEmail(utils.NormalizeEmail(email))
 }
 func (s *UserService) GetUserByID(id string) (*models.User, error) {
 	return s.userRepo.GetByID(id)
@@ -45,15 +100,28 @@ func (s *UserService) UpdateUser(id string, updates map[string]interface{}) (*mo
 	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
+	if email, ok := updates["email"].(string); ok {
+		email = utils.NormalizeEmail(email)
+		if err := utils.ValidateEmail(email); err != nil {
+			return nil, err
+		}
+		if existing, err := s.userRepo.GetByEmail(email); err == nil && existing.ID != id {
+			return nil, fmt.Errorf("email already registered")
+		}
+		updates["email"] = email
+	}
 	if password, ok := updates["password"].(string); ok {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		hashedPassword, err := s.hasher.Hash(password)
 		if err != nil {
 			return nil, fmt.Errorf("failed to hash password: %w", err)
 		}
-		updates["password"] = string(hashedPassword)
+		updates["password"] = hashedPassword
 	}
 	updates["updated_at"] = time.Now()
 	if err := s.userRepo.Update(id, updates); err != nil {
+		if err.Error() == "email already registered" {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 	user, err = s.userRepo.GetByID(id)
@@ -63,11 +131,68 @@ func (s *UserService) UpdateUser(id string, updates map[string]interface{}) (*mo
 	response := user.ToResponse()
 	return &response, nil
 }
+func (s *UserService) UpdateRole(id, role string) (*models.UserResponse, string, error) {
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil, "", err
+		}
+		return nil, "", fmt.Errorf("failed to get user: %w", err)
+	}
+	previousRole := user.Role
+	if err := s.userRepo.Update(id, map[string]interface{}{"role": role, "updated_at": time.Now()}); err != nil {
+		return nil, "", fmt.Errorf("failed to update role: %w", err)
+	}
+	user.Role = role
+	response := user.ToResponse()
+	return &response, previousRole, nil
+}
+// UpdateStore pins the user to storeID, or unpins them when it is nil, and
+// revokes their tokens so none outlive the change. The previous store is
+// returned for the audit log.
+func (s *UserService) UpdateStore(id string, storeID *string) (*models.UserResponse, *string, error) {
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	previousStore := user.StoreID
+	now := time.Now()
+	if err := s.userRepo.Update(id, map[string]interface{}{"store_id": storeID, "tokens_revoked_at": now, "updated_at": now}); err != nil {
+		if err.Error() == "store not found" {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to update store: %w", err)
+	}
+	user.StoreID = storeID
+	response := user.ToResponse()
+	return &response, previousStore, nil
+}
 func (s *UserService) DeleteUser(id string) error {
 	return s.userRepo.Delete(id)
 }
 func (s *UserService) VerifyPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	return s.hasher.Verify(hashedPassword, password)
+}
+func (s *UserService) Authenticate(email, password string) (*models.User, error) {
+	user, err := s.userRepo.GetByEmail(utils.NormalizeEmail(email))
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials")
+	}
+	rehashed, err := s.hasher.VerifyAndRehash(user.Password, password)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials")
+	}
+	if rehashed != "" {
+		if err := s.userRepo.Update(user.ID, map[string]interface{}{"password": rehashed}); err != nil {
+			utils.Warn("failed to upgrade password hash", "user_id", user.ID, "error", err.Error())
+		} else {
+			user.Password = rehashed
+		}
+	}
+	return user, nil
 }
 func generateID() string {
 	bytes := make([]byte, 16)
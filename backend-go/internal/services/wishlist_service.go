@@ -1,14 +1,20 @@
 ﻿package services
 import (
+	"fmt"
+	"time"
 	"ecommerce-backend/internal/models"
 	"ecommerce-backend/internal/repositories"
 )
 type WishlistService struct {
-	wishlistRepo *repositories.WishlistRepository
+	wishlistRepo     *repositories.WishlistRepository
+	productRepo      *repositories.ProductRepository
+	priceHistoryRepo *repositories.PriceHistoryRepository
 }
-func NewWishlistService(wishlistRepo *repositories.WishlistRepository) *WishlistService {
+func NewWishlistService(wishlistRepo *repositories.WishlistRepository, productRepo *repositories.ProductRepository, priceHistoryRepo *repositories.PriceHistoryRepository) *WishlistService {
 	return &WishlistService{
-		wishlistRepo: wishlistRepo,
+		wishlistRepo:     wishlistRepo,
+		productRepo:      productRepo,
+		priceHistoryRepo: priceHistoryRepo,
 	}
 }
 func (s *WishlistService) GetUserWishlist(userID string, page, limit int) ([]models.WishlistItemWithProduct, int, error) {
@@ -23,17 +29,12 @@ func (s *WishlistService) GetUserWishlist(userID string, page, limit int) ([]mod
 	}
 	return items, total, nil
 }
-func (s *WishlistService) AddToWishlist(userID, productID string) error {
-	exists, err := s.wishlistRepo.IsInWishlist(userID, productID)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return nil // Already in wishlist, no error
-	}
+// AddToWishlist is idempotent: it reports whether the product was newly
+// added rather than failing when it is already wishlisted.
+func (s *WishlistService) AddToWishlist(userID, productID string) (bool, error) {
 	return s.wishlistRepo.AddToWishlist(userID, productID)
 }
-func (s *WishlistService) RemoveFromWishlist(userID, productID string) error {
+func (s *WishlistService) RemoveFromWishlist(userID, productID string) (bool, error) {
 	return s.wishlistRepo.RemoveFromWishlist(userID, productID)
 }
 func (s *WishlistService) IsInWishlist(userID, productID string) (bool, error) {
@@ -42,3 +43,36 @@ func (s *WishlistService) IsInWishlist(userID, productID string) (bool, error) {
 func (s *WishlistService) ClearWishlist(userID string) error {
 	return s.wishlistRepo.ClearUserWishlist(userID)
 }
+func (s *WishlistService) GetPriceHistory(userID, productID string) (*models.PriceHistory, error) {
+	exists, err := s.wishlistRepo.IsInWishlist(userID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("product not in wishlist")
+	}
+	product, err := s.productRepo.GetByID("", productID)
+	if err != nil {
+		return nil, err
+	}
+	points, err := s.priceHistoryRepo.GetByProductID(productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price history: %w", err)
+	}
+	history := &models.PriceHistory{
+		ProductID:    productID,
+		CurrentPrice: product.Price,
+		Points:       points,
+	}
+	since := time.Now().AddDate(0, 0, -30)
+	for _, point := range points {
+		if point.RecordedAt.Before(since) {
+			continue
+		}
+		if history.Lowest30Days == nil || point.Price < *history.Lowest30Days {
+			price := point.Price
+			history.Lowest30Days = &price
+		}
+	}
+	return history, nil
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, injected at link time:
+//
+//	go build -ldflags "-X ecommerce-backend/internal/utils.Version=2.1.0 \
+//	  -X ecommerce-backend/internal/utils.GitCommit=$(git rev-parse HEAD) \
+//	  -X ecommerce-backend/internal/utils.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// When they are not set, the commit and time recorded by the Go toolchain for
+// builds inside a git checkout are used instead.
+var (
+	Version   = "dev"
+	GitCommit = ""
+	BuildTime = ""
+)
+
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+func GetBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.GitCommit == "" {
+					info.GitCommit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	if info.GitCommit == "" {
+		info.GitCommit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
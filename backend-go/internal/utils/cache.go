@@ -1,24 +1,41 @@
 package utils
 
 import (
+	"container/list"
+	"strings"
 	"sync"
 	"time"
+
+	"ecommerce-backend/internal/database"
 )
 
+const defaultCacheTTL = 5 * time.Minute
+
 type CacheItem struct {
 	Value     interface{}
 	ExpiresAt time.Time
 	CreatedAt time.Time
+	key       string
+	element   *list.Element
 }
 
 type Cache struct {
-	items map[string]*CacheItem
-	mutex sync.RWMutex
+	items     map[string]*CacheItem
+	lru       *list.List
+	maxSize   int
+	evictions int64
+	mutex     sync.RWMutex
 }
 
 func NewCache() *Cache {
+	return NewCacheWithLimit(0)
+}
+
+func NewCacheWithLimit(maxSize int) *Cache {
 	cache := &Cache{
-		items: make(map[string]*CacheItem),
+		items:   make(map[string]*CacheItem),
+		lru:     list.New(),
+		maxSize: maxSize,
 	}
 
 	go cache.cleanup()
@@ -29,16 +46,56 @@ func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	c.items[key] = &CacheItem{
+	c.set(key, value, ttl)
+}
+
+// Increment atomically bumps an integer counter stored under key and returns
+// the new count along with the time the counter's window expires. A missing
+// or expired counter starts a fresh window of length ttl.
+func (c *Cache) Increment(key string, ttl time.Duration) (int, time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if item, exists := c.items[key]; exists && time.Now().Before(item.ExpiresAt) {
+		if count, ok := item.Value.(int); ok {
+			item.Value = count + 1
+			c.lru.MoveToFront(item.element)
+			return count + 1, item.ExpiresAt
+		}
+	}
+
+	item := c.set(key, 1, ttl)
+	return 1, item.ExpiresAt
+}
+
+func (c *Cache) set(key string, value interface{}, ttl time.Duration) *CacheItem {
+	if existing, exists := c.items[key]; exists {
+		c.removeItem(existing)
+	}
+
+	item := &CacheItem{
 		Value:     value,
 		ExpiresAt: time.Now().Add(ttl),
 		CreatedAt: time.Now(),
+		key:       key,
+	}
+	item.element = c.lru.PushFront(item)
+	c.items[key] = item
+
+	for c.maxSize > 0 && len(c.items) > c.maxSize {
+		oldest := c.lru.Back()
+		if oldest == nil {
+			break
+		}
+		c.removeItem(oldest.Value.(*CacheItem))
+		c.evictions++
 	}
+	return item
 }
 
 func (c *Cache) Get(key string) (interface{}, bool) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
 	item, exists := c.items[key]
 	if !exists {
@@ -46,10 +103,11 @@ func (c *Cache) Get(key string) (interface{}, bool) {
 	}
 
 	if time.Now().After(item.ExpiresAt) {
-		delete(c.items, key)
+		c.removeItem(item)
 		return nil, false
 	}
 
+	c.lru.MoveToFront(item.element)
 	return item.Value, true
 }
 
@@ -57,7 +115,9 @@ func (c *Cache) Delete(key string) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	delete(c.items, key)
+	if item, exists := c.items[key]; exists {
+		c.removeItem(item)
+	}
 }
 
 func (c *Cache) Clear() {
@@ -65,6 +125,53 @@ func (c *Cache) Clear() {
 	defer c.mutex.Unlock()
 
 	c.items = make(map[string]*CacheItem)
+	c.lru.Init()
+}
+
+func (c *Cache) DeletePrefix(prefix string) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	removed := 0
+	for key, item := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.removeItem(item)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (c *Cache) SetMaxSize(maxSize int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.maxSize = maxSize
+	for c.maxSize > 0 && len(c.items) > c.maxSize {
+		c.removeItem(c.lru.Back().Value.(*CacheItem))
+		c.evictions++
+	}
+}
+
+func (c *Cache) MaxSize() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.maxSize
+}
+
+func (c *Cache) Evictions() int64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.evictions
+}
+
+func (c *Cache) resetEvictions() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.evictions = 0
 }
 
 func (c *Cache) Keys() []string {
@@ -85,6 +192,11 @@ func (c *Cache) Size() int {
 	return len(c.items)
 }
 
+func (c *Cache) removeItem(item *CacheItem) {
+	delete(c.items, item.key)
+	c.lru.Remove(item.element)
+}
+
 func (c *Cache) cleanup() {
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
@@ -92,9 +204,9 @@ func (c *Cache) cleanup() {
 	for range ticker.C {
 		c.mutex.Lock()
 		now := time.Now()
-		for key, item := range c.items {
+		for _, item := range c.items {
 			if now.After(item.ExpiresAt) {
-				delete(c.items, key)
+				c.removeItem(item)
 			}
 		}
 		c.mutex.Unlock()
@@ -102,28 +214,95 @@ func (c *Cache) cleanup() {
 }
 
 type CacheStats struct {
-	Size        int
-	HitRate     float64
-	MissRate    float64
-	TotalHits   int64
-	TotalMisses int64
-	Hits        int64
-	Misses      int64
+	Size        int           `json:"size"`
+	MaxSize     int           `json:"max_size"`
+	TTL         time.Duration `json:"ttl"`
+	HitRate     float64       `json:"hit_rate"`
+	MissRate    float64       `json:"miss_rate"`
+	TotalHits   int64         `json:"total_hits"`
+	TotalMisses int64         `json:"total_misses"`
+	Hits        int64         `json:"hits"`
+	Misses      int64         `json:"misses"`
+	Evictions   int64         `json:"evictions"`
 }
 
 type StatsCache struct {
 	*Cache
-	hits   int64
-	misses int64
-	mutex  sync.RWMutex
+	hits    int64
+	misses  int64
+	ttl     time.Duration
+	mutex   sync.RWMutex
+	loaders loaderGroup
+}
+
+type loaderCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+type loaderGroup struct {
+	mutex sync.Mutex
+	calls map[string]*loaderCall
+}
+
+func (g *loaderGroup) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*loaderCall)
+	}
+	if call, exists := g.calls[key]; exists {
+		g.mutex.Unlock()
+		<-call.done
+		return call.value, call.err
+	}
+
+	call := &loaderCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mutex.Unlock()
+
+	defer func() {
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+		close(call.done)
+	}()
+
+	call.value, call.err = fn()
+	return call.value, call.err
 }
 
 func NewStatsCache() *StatsCache {
+	return NewStatsCacheWithOptions(defaultCacheTTL, 0)
+}
+
+func NewStatsCacheWithOptions(ttl time.Duration, maxSize int) *StatsCache {
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
 	return &StatsCache{
-		Cache: NewCache(),
+		Cache: NewCacheWithLimit(maxSize),
+		ttl:   ttl,
 	}
 }
 
+func (sc *StatsCache) TTL() time.Duration {
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+
+	return sc.ttl
+}
+
+func (sc *StatsCache) Configure(ttl time.Duration, maxSize int) {
+	sc.mutex.Lock()
+	if ttl > 0 {
+		sc.ttl = ttl
+	}
+	sc.mutex.Unlock()
+
+	sc.SetMaxSize(maxSize)
+}
+
 func (sc *StatsCache) Get(key string) (interface{}, bool) {
 	value, exists := sc.Cache.Get(key)
 
@@ -152,12 +331,15 @@ func (sc *StatsCache) GetStats() CacheStats {
 
 	return CacheStats{
 		Size:        sc.Size(),
+		MaxSize:     sc.MaxSize(),
+		TTL:         sc.ttl,
 		HitRate:     hitRate,
 		MissRate:    missRate,
 		TotalHits:   sc.hits,
 		TotalMisses: sc.misses,
 		Hits:        sc.hits,
 		Misses:      sc.misses,
+		Evictions:   sc.Evictions(),
 	}
 }
 
@@ -167,11 +349,14 @@ func (sc *StatsCache) ResetStats() {
 
 	sc.hits = 0
 	sc.misses = 0
+	sc.Cache.resetEvictions()
 }
 
 type CacheManager struct {
-	caches map[string]*StatsCache
-	mutex  sync.RWMutex
+	caches  map[string]*StatsCache
+	backend CacheBackend
+	breaker *database.CircuitBreaker
+	mutex   sync.RWMutex
 }
 
 func NewCacheManager() *CacheManager {
@@ -187,14 +372,41 @@ func (cm *CacheManager) GetCache(name string) *StatsCache {
 
 	if !exists {
 		cm.mutex.Lock()
-		cache = NewStatsCache()
-		cm.caches[name] = cache
+		if cache, exists = cm.caches[name]; !exists {
+			cache = NewStatsCache()
+			cm.caches[name] = cache
+		}
 		cm.mutex.Unlock()
 	}
 
 	return cache
 }
 
+func (cm *CacheManager) Configure(name string, ttl time.Duration, maxSize int) *StatsCache {
+	cache := cm.GetCache(name)
+	cache.Configure(ttl, maxSize)
+	return cache
+}
+
+func (cm *CacheManager) ResetStats(name string) bool {
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
+
+	if name != "" {
+		cache, exists := cm.caches[name]
+		if !exists {
+			return false
+		}
+		cache.ResetStats()
+		return true
+	}
+
+	for _, cache := range cm.caches {
+		cache.ResetStats()
+	}
+	return true
+}
+
 func (cm *CacheManager) DeleteCache(name string) {
 	cm.mutex.Lock()
 	defer cm.mutex.Unlock()
@@ -225,12 +437,72 @@ func (cm *CacheManager) GetStats() map[string]CacheStats {
 }
 
 func (cm *CacheManager) ClearAll() {
-	cm.mutex.Lock()
-	defer cm.mutex.Unlock()
-
-	for _, cache := range cm.caches {
+	cm.mutex.RLock()
+	names := make([]string, 0, len(cm.caches))
+	for name, cache := range cm.caches {
 		cache.Clear()
+		names = append(names, name+":")
+	}
+	cm.mutex.RUnlock()
+
+	cm.backendDeletePrefix(names...)
+}
+
+// GetOrSet returns the value cached under key in the named cache, looking in
+// the shared backend before calling fn to load it.
+func (cm *CacheManager) GetOrSet(cacheName, key string, ttl time.Duration, fn func() (interface{}, error)) (interface{}, error) {
+	cache := cm.GetCache(cacheName)
+	if ttl <= 0 {
+		ttl = cache.TTL()
+	}
+	shared := cacheName + ":" + key
+	decorator := NewCacheDecorator(cache, ttl)
+	return decorator.GetOrSet(key, func() (interface{}, error) {
+		if value, found := cm.backendGet(shared); found {
+			return value, nil
+		}
+		value, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		cm.backendSet(shared, value, ttl)
+		return value, nil
+	})
+}
+
+func (cm *CacheManager) Invalidate(cacheName, key string) {
+	cm.GetCache(cacheName).Delete(key)
+	cm.backendDelete(cacheName + ":" + key)
+}
+
+func (cm *CacheManager) InvalidatePrefix(prefix string) int {
+	prefix = strings.TrimSuffix(prefix, "*")
+	if prefix == "" {
+		return 0
 	}
+
+	cm.mutex.RLock()
+	removed := 0
+	// The backend is shared with other instances, which may hold caches
+	// this one has never used, so the prefix goes to it as given too.
+	shared := []string{prefix}
+	for name, cache := range cm.caches {
+		qualified := name + ":"
+		switch {
+		case strings.HasPrefix(qualified, prefix):
+			removed += cache.Size()
+			cache.Clear()
+		case strings.HasPrefix(prefix, qualified):
+			removed += cache.DeletePrefix(strings.TrimPrefix(prefix, qualified))
+		default:
+			removed += cache.DeletePrefix(prefix)
+			shared = append(shared, qualified+prefix)
+		}
+	}
+	cm.mutex.RUnlock()
+
+	cm.backendDeletePrefix(shared...)
+	return removed
 }
 
 type CacheDecorator struct {
@@ -250,13 +522,19 @@ func (cd *CacheDecorator) GetOrSet(key string, fn func() (interface{}, error)) (
 		return value, nil
 	}
 
-	value, err := fn()
-	if err != nil {
-		return nil, err
-	}
+	return cd.cache.loaders.Do(key, func() (interface{}, error) {
+		if value, exists := cd.cache.Cache.Get(key); exists {
+			return value, nil
+		}
+
+		value, err := fn()
+		if err != nil {
+			return nil, err
+		}
 
-	cd.cache.Set(key, value, cd.ttl)
-	return value, nil
+		cd.cache.Set(key, value, cd.ttl)
+		return value, nil
+	})
 }
 
 func (cd *CacheDecorator) Invalidate(key string) {
@@ -299,19 +577,20 @@ func matchPattern(str, pattern string) bool {
 
 var globalCacheManager = NewCacheManager()
 
+func GetCacheManager() *CacheManager {
+	return globalCacheManager
+}
+
 func GetCache(name string) *StatsCache {
 	return globalCacheManager.GetCache(name)
 }
 
 func CacheGetOrSet(cacheName, key string, ttl time.Duration, fn func() (interface{}, error)) (interface{}, error) {
-	cache := GetCache(cacheName)
-	decorator := NewCacheDecorator(cache, ttl)
-	return decorator.GetOrSet(key, fn)
+	return globalCacheManager.GetOrSet(cacheName, key, ttl, fn)
 }
 
 func CacheInvalidate(cacheName, key string) {
-	cache := GetCache(cacheName)
-	cache.Delete(key)
+	globalCacheManager.Invalidate(cacheName, key)
 }
 
 func CacheInvalidatePattern(cacheName, pattern string) {
@@ -320,6 +599,22 @@ func CacheInvalidatePattern(cacheName, pattern string) {
 	decorator.InvalidatePattern(pattern)
 }
 
+func CacheInvalidatePrefix(prefix string) int {
+	return globalCacheManager.InvalidatePrefix(prefix)
+}
+
+func ConfigureCache(name string, ttl time.Duration, maxSize int) {
+	globalCacheManager.Configure(name, ttl, maxSize)
+}
+
+func ResetCacheStats(name string) bool {
+	return globalCacheManager.ResetStats(name)
+}
+
+func ClearAllCaches() {
+	globalCacheManager.ClearAll()
+}
+
 func GetCacheStats() map[string]CacheStats {
 	return globalCacheManager.GetStats()
 }
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"time"
+
+	"ecommerce-backend/internal/database"
+)
+
+// CacheBackend is a shared cache tier, such as Redis, consulted behind the
+// in-process caches. Keys are qualified with the cache name ("products:...")
+// and Get must return values of the type they were stored with.
+type CacheBackend interface {
+	Get(key string) (interface{}, bool, error)
+	Set(key string, value interface{}, ttl time.Duration) error
+	Delete(key string) error
+	DeletePrefix(prefix string) error
+}
+
+// Cache backend states reported by CacheBackendStatus.
+const (
+	CacheBackendNone     = "none"
+	CacheBackendHealthy  = "healthy"
+	CacheBackendDegraded = "degraded"
+)
+
+// SetBackend puts backend behind the in-process caches. After
+// failureThreshold consecutive errors its circuit opens: reads go straight
+// to their loaders, usually the database, and the backend is tried again
+// once retryInterval has passed. A nil backend removes it.
+func (cm *CacheManager) SetBackend(backend CacheBackend, failureThreshold int, retryInterval time.Duration) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.backend = backend
+	cm.breaker = database.NewCircuitBreaker(failureThreshold, retryInterval)
+}
+
+// BackendStatus returns CacheBackendNone, CacheBackendHealthy or
+// CacheBackendDegraded, with the state of the backend's circuit.
+func (cm *CacheManager) BackendStatus() (string, database.BreakerState) {
+	cm.mutex.RLock()
+	backend, breaker := cm.backend, cm.breaker
+	cm.mutex.RUnlock()
+
+	if backend == nil {
+		return CacheBackendNone, database.BreakerClosed
+	}
+	state := breaker.State()
+	if state == database.BreakerClosed {
+		return CacheBackendHealthy, state
+	}
+	return CacheBackendDegraded, state
+}
+
+// useBackend runs fn against the backend unless there is none or its circuit
+// is open. A failure is logged and counted, and the caller carries on as if
+// the backend had missed, so an outage only costs cache hits.
+func (cm *CacheManager) useBackend(op string, fn func(CacheBackend) error) bool {
+	cm.mutex.RLock()
+	backend, breaker := cm.backend, cm.breaker
+	cm.mutex.RUnlock()
+
+	if backend == nil || breaker.Allow() != nil {
+		return false
+	}
+	if err := fn(backend); err != nil {
+		breaker.Failure()
+		Warn("cache backend unavailable, falling back to direct reads", "op", op, "error", err.Error(), "circuit", string(breaker.State()))
+		return false
+	}
+	breaker.Success()
+	return true
+}
+
+func (cm *CacheManager) backendGet(key string) (interface{}, bool) {
+	var value interface{}
+	var found bool
+	ok := cm.useBackend("get", func(backend CacheBackend) error {
+		var err error
+		value, found, err = backend.Get(key)
+		return err
+	})
+	return value, ok && found
+}
+
+func (cm *CacheManager) backendSet(key string, value interface{}, ttl time.Duration) {
+	cm.useBackend("set", func(backend CacheBackend) error {
+		return backend.Set(key, value, ttl)
+	})
+}
+
+func (cm *CacheManager) backendDelete(key string) {
+	cm.useBackend("delete", func(backend CacheBackend) error {
+		return backend.Delete(key)
+	})
+}
+
+func (cm *CacheManager) backendDeletePrefix(prefixes ...string) {
+	for _, prefix := range prefixes {
+		cm.useBackend("delete_prefix", func(backend CacheBackend) error {
+			return backend.DeletePrefix(prefix)
+		})
+	}
+}
+
+func SetCacheBackend(backend CacheBackend, failureThreshold int, retryInterval time.Duration) {
+	globalCacheManager.SetBackend(backend, failureThreshold, retryInterval)
+}
+
+func CacheBackendStatus() (string, database.BreakerState) {
+	return globalCacheManager.BackendStatus()
+}
@@ -10,8 +10,6 @@ import (
 	"strconv"
 	"strings"
 	"time"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 func GenerateUUID() string {
@@ -257,16 +255,11 @@ func IsValidPassword(password string) bool {
 }
 
 func HashPassword(password string) (string, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hashed), nil
+	return defaultPasswordHasher.Hash(password)
 }
 
 func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	return defaultPasswordHasher.Verify(hash, password) == nil
 }
 
 func GenerateOTP(length int) string {
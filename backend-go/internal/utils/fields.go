@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ParseFieldSelection splits a comma-separated fields= value and checks every
+// name against allowed. An empty value returns nil, meaning "all fields".
+func ParseFieldSelection(raw string, allowed []string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !Contains(allowed, name) {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		fields = append(fields, name)
+	}
+	return RemoveDuplicates(fields), nil
+}
+
+// ProjectFields returns the JSON object for v reduced to the given keys. A
+// nil fields slice returns v unchanged.
+func ProjectFields(v interface{}, fields []string) (interface{}, error) {
+	if fields == nil {
+		return v, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var full map[string]json.RawMessage
+	if err := json.Unmarshal(data, &full); err != nil {
+		return nil, err
+	}
+
+	projected := make(map[string]json.RawMessage, len(fields))
+	for _, name := range fields {
+		if value, ok := full[name]; ok {
+			projected[name] = value
+		}
+	}
+	return projected, nil
+}
@@ -12,11 +12,12 @@ import (
 )
 
 type APIResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
-	Message string      `json:"message,omitempty"`
-	Meta    *Meta       `json:"meta,omitempty"`
+	Success   bool        `json:"success"`
+	Data      interface{} `json:"data,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Message   string      `json:"message,omitempty"`
+	Meta      *Meta       `json:"meta,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
 }
 
 type Meta struct {
@@ -35,16 +36,18 @@ func SuccessResponse(c *gin.Context, data interface{}) {
 
 func ErrorResponse(c *gin.Context, statusCode int, message string) {
 	c.JSON(statusCode, APIResponse{
-		Success: false,
-		Error:   message,
+		Success:   false,
+		Error:     message,
+		RequestID: GetRequestID(c),
 	})
 }
 
 func ValidationErrorResponse(c *gin.Context, errors ValidationErrors) {
 	c.JSON(http.StatusBadRequest, APIResponse{
-		Success: false,
-		Error:   "Validation failed",
-		Data:    errors,
+		Success:   false,
+		Error:     "Validation failed",
+		Data:      errors,
+		RequestID: GetRequestID(c),
 	})
 }
 
@@ -316,20 +319,11 @@ func SendJSONFile(c *gin.Context, data interface{}, filename string) {
 	c.Data(http.StatusOK, "application/json", jsonData)
 }
 
+// GetClientIP returns c.ClientIP(). Forwarded headers are not read here
+// directly: they are only honoured from trusted proxies, see
+// middleware.ConfigureClientIP.
 func GetClientIP(c *gin.Context) string {
-	ip := c.GetHeader("X-Forwarded-For")
-	if ip == "" {
-		ip = c.GetHeader("X-Real-IP")
-	}
-	if ip == "" {
-		ip = c.ClientIP()
-	}
-
-	if strings.Contains(ip, ",") {
-		ip = strings.Split(ip, ",")[0]
-	}
-
-	return strings.TrimSpace(ip)
+	return c.ClientIP()
 }
 
 func GetUserAgent(c *gin.Context) string {
@@ -11,9 +11,21 @@ type JWTClaims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
 	Role   string `json:"role"`
+	// ImpersonatorID is the admin acting as UserID; it is only set on
+	// impersonation tokens.
+	ImpersonatorID string `json:"impersonator_id,omitempty"`
+	// StoreID pins the token to a store; it is empty for users who are not
+	// tied to one.
+	StoreID string `json:"store_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// IsImpersonation reports whether the token was issued to an admin acting as
+// another user.
+func (c *JWTClaims) IsImpersonation() bool {
+	return c.ImpersonatorID != ""
+}
+
 type JWTConfig struct {
 	Secret    string
 	ExpiresIn time.Duration
@@ -24,6 +36,15 @@ type JWTConfig struct {
 
 var jwtConfig *JWTConfig
 
+// revocationCheck reports whether a token issued to userID at issuedAt has
+// been revoked since. Tokens are not checked until it is set.
+var revocationCheck func(userID string, issuedAt time.Time) (bool, error)
+
+// SetRevocationCheck installs the check ValidateJWT runs on every token.
+func SetRevocationCheck(check func(userID string, issuedAt time.Time) (bool, error)) {
+	revocationCheck = check
+}
+
 func InitJWT(secret string, expiresIn, refreshIn time.Duration, issuer, audience string) {
 	jwtConfig = &JWTConfig{
 		Secret:    secret,
@@ -34,16 +55,17 @@ func InitJWT(secret string, expiresIn, refreshIn time.Duration, issuer, audience
 	}
 }
 
-func GenerateJWT(userID, email, role string) (string, error) {
+func GenerateJWT(userID, email, role, storeID string) (string, error) {
 	if jwtConfig == nil {
 		return "", errors.New("JWT not initialized")
 	}
 
 	now := time.Now()
 	claims := JWTClaims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:  userID,
+		Email:   email,
+		Role:    role,
+		StoreID: storeID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    jwtConfig.Issuer,
 			Audience:  []string{jwtConfig.Audience},
@@ -58,6 +80,37 @@ func GenerateJWT(userID, email, role string) (string, error) {
 	return token.SignedString([]byte(jwtConfig.Secret))
 }
 
+// GenerateImpersonationJWT issues a token for userID on behalf of adminID,
+// pinned to storeID. It lives for ttl rather than the normal expiry and
+// cannot be refreshed.
+func GenerateImpersonationJWT(userID, email, role, adminID, storeID string, ttl time.Duration) (string, time.Time, error) {
+	if jwtConfig == nil {
+		return "", time.Time{}, errors.New("JWT not initialized")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := JWTClaims{
+		UserID:         userID,
+		Email:          email,
+		Role:           role,
+		ImpersonatorID: adminID,
+		StoreID:        storeID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    jwtConfig.Issuer,
+			Audience:  []string{jwtConfig.Audience},
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(jwtConfig.Secret))
+	return signed, expiresAt, err
+}
+
 func GenerateRefreshToken(userID string) (string, error) {
 	if jwtConfig == nil {
 		return "", errors.New("JWT not initialized")
@@ -96,11 +149,47 @@ func ValidateJWT(tokenString string) (*JWTClaims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if revocationCheck != nil && claims.IssuedAt != nil {
+		revoked, err := revocationCheck(claims.UserID, claims.IssuedAt.Time)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, errors.New("token revoked")
+		}
+	}
+
+	return claims, nil
+}
+
+// StringClaim validates tokenString like ValidateJWT and returns the value of
+// an arbitrary string claim, or "" when the token does not carry it. It lets
+// deployments name custom claims, such as the store a token is issued for.
+func StringClaim(tokenString, claim string) (string, error) {
+	if jwtConfig == nil {
+		return "", errors.New("JWT not initialized")
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(jwtConfig.Secret), nil
+	})
+	if err != nil {
+		return "", err
 	}
 
-	return nil, errors.New("invalid token")
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return "", errors.New("invalid token")
+	}
+	value, _ := claims[claim].(string)
+	return value, nil
 }
 
 func RefreshJWT(refreshToken string) (string, error) {
@@ -108,8 +197,11 @@ func RefreshJWT(refreshToken string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if claims.IsImpersonation() {
+		return "", errors.New("impersonation tokens cannot be refreshed")
+	}
 
-	return GenerateJWT(claims.UserID, claims.Email, claims.Role)
+	return GenerateJWT(claims.UserID, claims.Email, claims.Role, claims.StoreID)
 }
 
 func ExtractUserID(tokenString string) (string, error) {
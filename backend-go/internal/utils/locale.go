@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NormalizeLocale lowercases a locale tag and uses "-" as the separator,
+// so "pt_BR" and "pt-br" compare equal.
+func NormalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// ParseAcceptLanguage returns the locales in an Accept-Language header
+// ordered by descending quality. Wildcards and q=0 entries are skipped.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale  string
+		quality float64
+	}
+
+	var entries []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		locale := NormalizeLocale(fields[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		entries = append(entries, weighted{locale: locale, quality: quality})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].quality > entries[j].quality
+	})
+
+	locales := make([]string, len(entries))
+	for i, entry := range entries {
+		locales[i] = entry.locale
+	}
+	return locales
+}
+
+// NegotiateLocale picks the supported locale that best matches an explicit
+// request (e.g. ?lang=) or, failing that, the Accept-Language header. A
+// regional tag falls back to its base language ("de-AT" matches "de").
+// Returns fallback when nothing matches.
+func NegotiateLocale(requested, acceptLanguage string, supported []string, fallback string) string {
+	candidates := ParseAcceptLanguage(acceptLanguage)
+	if requested = NormalizeLocale(requested); requested != "" {
+		candidates = append([]string{requested}, candidates...)
+	}
+
+	for _, candidate := range candidates {
+		base := strings.SplitN(candidate, "-", 2)[0]
+		for _, locale := range supported {
+			if NormalizeLocale(locale) == candidate {
+				return locale
+			}
+		}
+		for _, locale := range supported {
+			if NormalizeLocale(locale) == base {
+				return locale
+			}
+		}
+	}
+	return fallback
+}
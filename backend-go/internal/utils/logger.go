@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 )
 
@@ -130,6 +131,21 @@ func (l *Logger) Clear() {
 	l.Info("Logs cleared")
 }
 
+func ParseLogLevel(level string) LogLevel {
+	switch strings.ToLower(level) {
+	case "debug":
+		return DEBUG
+	case "warn", "warning":
+		return WARN
+	case "error":
+		return ERROR
+	case "fatal":
+		return FATAL
+	default:
+		return INFO
+	}
+}
+
 var defaultLogger = NewLogger(INFO, os.Stdout)
 
 func SetDefaultLogger(logger *Logger) {
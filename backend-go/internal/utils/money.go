@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// currencySymbols lists the currencies prices can be kept in. All of them
+// have two minor-unit digits, matching how amounts are stored.
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"CAD": "CA$",
+	"AUD": "A$",
+	"CHF": "CHF",
+	"SEK": "kr",
+	"NOK": "kr",
+	"DKK": "kr.",
+	"PLN": "zł",
+	"BRL": "R$",
+}
+
+type numberFormat struct {
+	group        string
+	decimal      string
+	symbolBefore bool
+	space        bool
+}
+
+var localeNumberFormats = map[string]numberFormat{
+	"en":    {group: ",", decimal: ".", symbolBefore: true},
+	"de":    {group: ".", decimal: ",", space: true},
+	"fr":    {group: "\u202f", decimal: ",", space: true},
+	"es":    {group: ".", decimal: ",", space: true},
+	"it":    {group: ".", decimal: ",", space: true},
+	"nl":    {group: ".", decimal: ",", symbolBefore: true, space: true},
+	"pt":    {group: "\u00a0", decimal: ",", space: true},
+	"pt-br": {group: ".", decimal: ",", symbolBefore: true, space: true},
+	"sv":    {group: "\u00a0", decimal: ",", space: true},
+	"pl":    {group: "\u00a0", decimal: ",", space: true},
+}
+
+// IsSupportedCurrency reports whether code is a currency FormatMoney knows.
+func IsSupportedCurrency(code string) bool {
+	_, ok := currencySymbols[strings.ToUpper(code)]
+	return ok
+}
+
+// FormatMoney renders an amount in minor units using the separators and
+// symbol placement of locale, falling back to its base language and then to
+// English. Unknown currencies are shown by their code.
+func FormatMoney(minor int64, currency, locale string) string {
+	locale = NormalizeLocale(locale)
+	format, ok := localeNumberFormats[locale]
+	if !ok {
+		format, ok = localeNumberFormats[strings.SplitN(locale, "-", 2)[0]]
+	}
+	if !ok {
+		format = localeNumberFormats["en"]
+	}
+
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	digits := strconv.FormatInt(minor/100, 10)
+	var grouped strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteString(format.group)
+		}
+		grouped.WriteRune(digit)
+	}
+	cents := strconv.FormatInt(minor%100, 10)
+	if len(cents) == 1 {
+		cents = "0" + cents
+	}
+	number := grouped.String() + format.decimal + cents
+
+	currency = strings.ToUpper(currency)
+	symbol, ok := currencySymbols[currency]
+	if !ok {
+		symbol = currency
+	}
+	// A no-break space keeps the symbol on the same line as the number.
+	separator := ""
+	if format.space || len(symbol) > 1 && !strings.ContainsAny(symbol, "$€£") {
+		separator = "\u00a0"
+	}
+	if format.symbolBefore {
+		return sign + symbol + separator + number
+	}
+	return sign + number + separator + symbol
+}
@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	PasswordAlgorithmBcrypt   = "bcrypt"
+	PasswordAlgorithmArgon2id = "argon2id"
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var ErrPasswordMismatch = errors.New("password does not match")
+
+type PasswordHashOptions struct {
+	Algorithm     string
+	BcryptCost    int
+	Argon2Time    uint32
+	Argon2Memory  uint32
+	Argon2Threads uint8
+}
+
+// PasswordHasher hashes passwords with the configured algorithm and can
+// verify hashes produced by any supported algorithm. bcrypt hashes carry
+// their own "$2a$" prefix; argon2id hashes use the PHC string format
+// "$argon2id$v=19$m=...,t=...,p=...$salt$key" so the two can coexist.
+type PasswordHasher struct {
+	opts PasswordHashOptions
+}
+
+func NewPasswordHasher(opts PasswordHashOptions) *PasswordHasher {
+	if opts.Algorithm == "" {
+		opts.Algorithm = PasswordAlgorithmBcrypt
+	}
+	if opts.BcryptCost == 0 {
+		opts.BcryptCost = bcrypt.DefaultCost
+	}
+	if opts.Argon2Time == 0 {
+		opts.Argon2Time = 1
+	}
+	if opts.Argon2Memory == 0 {
+		opts.Argon2Memory = 64 * 1024
+	}
+	if opts.Argon2Threads == 0 {
+		opts.Argon2Threads = 4
+	}
+	return &PasswordHasher{opts: opts}
+}
+
+func (h *PasswordHasher) Algorithm() string {
+	return h.opts.Algorithm
+}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	switch h.opts.Algorithm {
+	case PasswordAlgorithmArgon2id:
+		salt := make([]byte, argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, h.opts.Argon2Time, h.opts.Argon2Memory, h.opts.Argon2Threads, argon2KeyLength)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, h.opts.Argon2Memory, h.opts.Argon2Time, h.opts.Argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	case PasswordAlgorithmBcrypt:
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.opts.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashed), nil
+	default:
+		return "", fmt.Errorf("unsupported password algorithm: %s", h.opts.Algorithm)
+	}
+}
+
+func (h *PasswordHasher) Verify(hash, password string) error {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := parseArgon2Hash(hash)
+		if err != nil {
+			return err
+		}
+		candidate := argon2.IDKey([]byte(password), salt, params.Argon2Time, params.Argon2Memory, params.Argon2Threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(candidate, key) != 1 {
+			return ErrPasswordMismatch
+		}
+		return nil
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrPasswordMismatch
+		}
+		return err
+	}
+	return nil
+}
+
+// NeedsRehash reports whether hash was produced with a different algorithm
+// or weaker parameters than the hasher is currently configured with.
+func (h *PasswordHasher) NeedsRehash(hash string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		if h.opts.Algorithm != PasswordAlgorithmArgon2id {
+			return true
+		}
+		params, _, _, err := parseArgon2Hash(hash)
+		if err != nil {
+			return true
+		}
+		return params.Argon2Time != h.opts.Argon2Time ||
+			params.Argon2Memory != h.opts.Argon2Memory ||
+			params.Argon2Threads != h.opts.Argon2Threads
+	}
+	if h.opts.Algorithm != PasswordAlgorithmBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+	return cost != h.opts.BcryptCost
+}
+
+// VerifyAndRehash checks password against hash and, when the hash is
+// outdated, returns a replacement hash the caller should persist. The
+// returned string is empty when no rehash is needed.
+func (h *PasswordHasher) VerifyAndRehash(hash, password string) (string, error) {
+	if err := h.Verify(hash, password); err != nil {
+		return "", err
+	}
+	if !h.NeedsRehash(hash) {
+		return "", nil
+	}
+	return h.Hash(password)
+}
+
+func parseArgon2Hash(hash string) (PasswordHashOptions, []byte, []byte, error) {
+	var params PasswordHashOptions
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, errors.New("invalid argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errors.New("unsupported argon2id version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Argon2Memory, &params.Argon2Time, &params.Argon2Threads); err != nil {
+		return params, nil, nil, errors.New("invalid argon2id parameters")
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errors.New("invalid argon2id salt")
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, errors.New("invalid argon2id key")
+	}
+	params.Algorithm = PasswordAlgorithmArgon2id
+	return params, salt, key, nil
+}
+
+var defaultPasswordHasher = NewPasswordHasher(PasswordHashOptions{})
+
+func SetPasswordHasher(hasher *PasswordHasher) {
+	defaultPasswordHasher = hasher
+}
+
+func GetPasswordHasher() *PasswordHasher {
+	return defaultPasswordHasher
+}
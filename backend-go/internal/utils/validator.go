@@ -9,8 +9,8 @@ import (
 )
 
 type ValidationError struct {
-	Field   string
-	Message string
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 func (ve ValidationError) Error() string {
@@ -268,6 +268,12 @@ func ValidateStruct(data interface{}) error {
 	return validator.Validate()
 }
 
+// NormalizeEmail trims and lowercases an email address so that addresses
+// differing only by case are treated as the same account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 func ValidateEmail(email string) error {
 	validator := NewValidator()
 	validator.Email("email", email)
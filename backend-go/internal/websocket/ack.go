@@ -0,0 +1,111 @@
+package websocket
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	maxUnackedPerUser = 100
+	unackedRetention  = 24 * time.Hour
+)
+
+type unackedMessage struct {
+	seq    uint64
+	data   []byte
+	sentAt time.Time
+}
+
+// ackTracker keeps critical messages per user until the user's client
+// acknowledges them, so they can be redelivered after a reconnect.
+type ackTracker struct {
+	seq     uint64
+	mutex   sync.Mutex
+	pending map[string][]unackedMessage
+}
+
+func newAckTracker() *ackTracker {
+	return &ackTracker{pending: make(map[string][]unackedMessage)}
+}
+
+func (t *ackTracker) track(userID string, message *Message) ([]byte, error) {
+	tracked := *message
+	tracked.Seq = atomic.AddUint64(&t.seq, 1)
+	data, err := tracked.ToJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	queue := append(t.pending[userID], unackedMessage{seq: tracked.Seq, data: data, sentAt: time.Now()})
+	if len(queue) > maxUnackedPerUser {
+		log.Printf("Unacked queue full for user %s, discarding oldest critical message", userID)
+		queue = queue[len(queue)-maxUnackedPerUser:]
+	}
+	t.pending[userID] = queue
+	return data, nil
+}
+
+// ack acknowledges every pending message for the user up to and including seq.
+func (t *ackTracker) ack(userID string, seq uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	queue := t.pending[userID]
+	i := 0
+	for i < len(queue) && queue[i].seq <= seq {
+		i++
+	}
+	if i == len(queue) {
+		delete(t.pending, userID)
+		return
+	}
+	t.pending[userID] = queue[i:]
+}
+
+func (t *ackTracker) unacked(userID string) [][]byte {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	queue := t.pending[userID]
+	messages := make([][]byte, len(queue))
+	for i, message := range queue {
+		messages[i] = message.data
+	}
+	return messages
+}
+
+func (t *ackTracker) expire(maxAge time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	cutoff := time.Now().Add(-maxAge)
+	for userID, queue := range t.pending {
+		i := 0
+		for i < len(queue) && queue[i].sentAt.Before(cutoff) {
+			i++
+		}
+		if i == len(queue) {
+			delete(t.pending, userID)
+		} else if i > 0 {
+			t.pending[userID] = queue[i:]
+		}
+	}
+}
+
+func (t *ackTracker) counts() (int, map[string]int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	total := 0
+	perUser := make(map[string]int, len(t.pending))
+	for userID, queue := range t.pending {
+		total += len(queue)
+		perUser[userID] = len(queue)
+	}
+	return total, perUser
+}
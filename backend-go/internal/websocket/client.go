@@ -9,12 +9,7 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-const (
-	writeWait      = 10 * time.Second
-	pongWait       = 60 * time.Second
-	pingPeriod     = (pongWait * 9) / 10
-	maxMessageSize = 512
-)
+const maxMessageSize = 512
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
@@ -26,11 +21,12 @@ var upgrader = websocket.Upgrader{
 
 func (c *Client) readPump() {
 	defer func() {
-		c.Hub.unregister <- c
+		c.Hub.Unregister(c)
 		c.Conn.Close()
 	}()
 
 	c.Conn.SetReadLimit(maxMessageSize)
+	pongWait := c.Hub.keepalive.PongWait
 	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.Conn.SetPongHandler(func(string) error {
 		c.Conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -45,6 +41,7 @@ func (c *Client) readPump() {
 			}
 			break
 		}
+		c.Hub.messagesReceived.Add(1)
 
 		var message Message
 		if err := json.Unmarshal(messageBytes, &message); err != nil {
@@ -57,7 +54,8 @@ func (c *Client) readPump() {
 }
 
 func (c *Client) writePump() {
-	ticker := time.NewTicker(pingPeriod)
+	writeWait := c.Hub.keepalive.WriteWait
+	ticker := time.NewTicker(c.Hub.keepalive.PingInterval)
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
@@ -101,6 +99,10 @@ func (c *Client) handleMessage(message *Message) {
 	switch message.Type {
 	case MessageTypePing:
 		c.handlePing()
+	case MessageTypeAck:
+		c.handleAck(message)
+	case MessageTypeSubscribe, MessageTypeUnsubscribe:
+		c.handleSubscription(message)
 	case MessageTypeNotification:
 		c.handleChatMessage(message)
 	case MessageTypeUserActivity:
@@ -115,12 +117,44 @@ func (c *Client) handlePing() {
 		"timestamp": time.Now().Unix(),
 	}, c.UserID)
 
-	data, _ := pongMsg.ToJSON()
-	select {
-	case c.Send <- data:
-	default:
-		close(c.Send)
+	c.Hub.deliver(c, pongMsg)
+}
+
+func (c *Client) handleAck(message *Message) {
+	ackData, ok := message.Data.(map[string]interface{})
+	if !ok {
+		return
 	}
+
+	seq, ok := ackData["seq"].(float64)
+	if !ok || seq < 1 {
+		return
+	}
+
+	c.Hub.Ack(c.UserID, uint64(seq))
+}
+
+func (c *Client) handleSubscription(message *Message) {
+	subData, ok := message.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	topic, _ := subData["topic"].(string)
+	if topic == "" {
+		return
+	}
+
+	reply := SubscriptionData{Topic: topic}
+	if message.Type == MessageTypeUnsubscribe {
+		c.Hub.Unsubscribe(c, topic)
+	} else if err := c.Hub.Subscribe(c, topic); err != nil {
+		reply.Error = err.Error()
+	} else {
+		reply.Subscribed = true
+	}
+
+	c.Hub.deliver(c, CreateMessage(message.Type, reply, c.UserID))
 }
 
 func (c *Client) handleChatMessage(message *Message) {
@@ -47,7 +47,12 @@ func (h *Handler) HandleWebSocket(c *gin.Context) {
 		JoinedAt: time.Now(),
 	}
 
-	client.Hub.register <- client
+	if err := client.Hub.Register(client); err != nil {
+		closeMsg := gorilla.FormatCloseMessage(gorilla.CloseTryAgainLater, err.Error())
+		conn.WriteControl(gorilla.CloseMessage, closeMsg, time.Now().Add(h.hub.keepalive.WriteWait))
+		conn.Close()
+		return
+	}
 
 	go client.WritePump()
 	go client.ReadPump()
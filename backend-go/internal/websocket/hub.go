@@ -1,91 +1,304 @@
 ﻿package websocket
 
 import (
+	"errors"
 	"log"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	defaultBroadcastBuffer   = 256
+	defaultPingInterval      = 30 * time.Second
+	defaultPongWait          = 60 * time.Second
+	defaultWriteWait         = 10 * time.Second
+	criticalBroadcastTimeout = 2 * time.Second
+	healthCheckTimeout       = time.Second
+)
+
+// broadcastShard owns a fixed subset of clients. Each shard is served by a
+// single worker, so messages reach a given client in the order they were
+// broadcast while different shards deliver in parallel.
+type broadcastShard struct {
+	clients map[*Client]bool
+	jobs    chan []byte
+}
+
+// KeepaliveConfig controls how often clients are pinged, how long a client
+// may stay silent before it is dropped and how long a single write may take.
+type KeepaliveConfig struct {
+	PingInterval time.Duration `json:"ping_interval"`
+	PongWait     time.Duration `json:"pong_wait"`
+	WriteWait    time.Duration `json:"write_wait"`
+}
+
+// ConnectionLimits cap the clients the hub accepts. Zero means no limit.
+// The per-user limit applies to signed-in clients only.
+type ConnectionLimits struct {
+	MaxConnections        int `json:"max_connections"`
+	MaxConnectionsPerUser int `json:"max_connections_per_user"`
+}
+
+var (
+	ErrTooManyConnections     = errors.New("too many connections")
+	ErrTooManyUserConnections = errors.New("too many connections for this user")
+)
+
+// registration asks the Run loop to admit a client and carries back the
+// reason it was refused, if any.
+type registration struct {
+	client *Client
+	result chan error
+}
+
+// OptOutFilter returns the users among userIDs who have turned off the given
+// notification event. Only non-critical messages are filtered.
+type OptOutFilter func(event string, userIDs []string) map[string]bool
+
+// NotificationStore persists a message sent to a single user so it can be
+// read later from their inbox.
+type NotificationStore func(userID, messageType, message string, data interface{})
+
 type Hub struct {
-	clients          map[*Client]bool
-	broadcast        chan []byte
-	register         chan *Client
-	unregister       chan *Client
-	mutex            sync.RWMutex
-	startTime        time.Time
-	messagesSent     int64
-	messagesReceived int64
-	lastActivity     time.Time
-}
-
-func NewHub() *Hub {
-	return &Hub{
+	clients           map[*Client]bool
+	shards            []*broadcastShard
+	nextShard         int
+	broadcast         chan []byte
+	register          chan registration
+	unregister        chan *Client
+	healthProbe       chan chan struct{}
+	mutex             sync.RWMutex
+	startTime         time.Time
+	messagesSent      atomic.Int64
+	messagesReceived  atomic.Int64
+	broadcastsQueued  atomic.Int64
+	broadcastsDropped atomic.Int64
+	connsRejected     atomic.Int64
+	userConns         map[string]int
+	limits            ConnectionLimits
+	lastActivity      time.Time
+	acks              *ackTracker
+	keepalive         KeepaliveConfig
+	optOuts           OptOutFilter
+	store             NotificationStore
+}
+
+func NewHub(broadcastWorkers, broadcastBuffer int) *Hub {
+	if broadcastWorkers <= 0 {
+		broadcastWorkers = runtime.NumCPU()
+	}
+	if broadcastBuffer <= 0 {
+		broadcastBuffer = defaultBroadcastBuffer
+	}
+
+	h := &Hub{
 		clients:      make(map[*Client]bool),
-		broadcast:    make(chan []byte),
-		register:     make(chan *Client),
+		userConns:    make(map[string]int),
+		shards:       make([]*broadcastShard, broadcastWorkers),
+		broadcast:    make(chan []byte, broadcastBuffer),
+		register:     make(chan registration),
 		unregister:   make(chan *Client),
+		healthProbe:  make(chan chan struct{}),
 		startTime:    time.Now(),
 		lastActivity: time.Now(),
+		acks:         newAckTracker(),
+		keepalive: KeepaliveConfig{
+			PingInterval: defaultPingInterval,
+			PongWait:     defaultPongWait,
+			WriteWait:    defaultWriteWait,
+		},
 	}
+	for i := range h.shards {
+		h.shards[i] = &broadcastShard{
+			clients: make(map[*Client]bool),
+			jobs:    make(chan []byte, 64),
+		}
+	}
+	return h
 }
 
 func (h *Hub) Run() {
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(h.keepalive.PingInterval)
 	defer ticker.Stop()
 
+	for _, shard := range h.shards {
+		go h.runShard(shard)
+	}
+
 	for {
 		select {
-		case client := <-h.register:
+		case reg := <-h.register:
+			client := reg.client
 			h.mutex.Lock()
+			if err := h.admit(client); err != nil {
+				h.mutex.Unlock()
+				h.connsRejected.Add(1)
+				log.Printf("Client rejected (user %q): %v", client.UserID, err)
+				reg.result <- err
+				continue
+			}
 			h.clients[client] = true
+			if client.UserID != "" {
+				h.userConns[client.UserID]++
+			}
+			client.shard = h.nextShard
+			h.shards[client.shard].clients[client] = true
+			h.nextShard = (h.nextShard + 1) % len(h.shards)
+			h.lastActivity = time.Now()
+			total := len(h.clients)
 			h.mutex.Unlock()
 
-			log.Printf("Client connected. Total clients: %d", len(h.clients))
-			h.lastActivity = time.Now()
+			log.Printf("Client connected. Total clients: %d", total)
+			reg.result <- nil
 
 			welcomeMsg := CreateMessage(MessageTypeNotification, NotificationData{
 				Title:   "Welcome",
 				Message: "Connected to Eshop WebSocket",
 				Icon:    "success",
 			}, client.UserID)
-			h.sendToClient(client, welcomeMsg)
+			h.deliver(client, welcomeMsg)
+			if client.UserID != "" {
+				h.mutex.RLock()
+				if h.clients[client] {
+					for _, data := range h.acks.unacked(client.UserID) {
+						h.sendRaw(client, data)
+					}
+				}
+				h.mutex.RUnlock()
+			}
 
 		case client := <-h.unregister:
 			h.mutex.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.Send)
-			}
+			h.removeClient(client)
+			h.lastActivity = time.Now()
+			total := len(h.clients)
 			h.mutex.Unlock()
 
-			log.Printf("Client disconnected. Total clients: %d", len(h.clients))
-			h.lastActivity = time.Now()
+			log.Printf("Client disconnected. Total clients: %d", total)
 
 		case message := <-h.broadcast:
-			h.mutex.RLock()
-			for client := range h.clients {
-				select {
-				case client.Send <- message:
-					h.messagesSent++
-				default:
-					close(client.Send)
-					delete(h.clients, client)
-				}
+			for _, shard := range h.shards {
+				shard.jobs <- message
 			}
-			h.mutex.RUnlock()
+			h.mutex.Lock()
 			h.lastActivity = time.Now()
+			h.mutex.Unlock()
+
+		case reply := <-h.healthProbe:
+			close(reply)
 
 		case <-ticker.C:
+			var slow []*Client
 			h.mutex.RLock()
 			for client := range h.clients {
 				pingMsg := CreateMessage(MessageTypePing, map[string]interface{}{
 					"timestamp": time.Now().Unix(),
 				}, client.UserID)
-				h.sendToClient(client, pingMsg)
+				if !h.sendToClient(client, pingMsg) {
+					slow = append(slow, client)
+				}
 			}
 			h.mutex.RUnlock()
+			h.dropClients(slow)
+			h.acks.expire(unackedRetention)
+		}
+	}
+}
+
+func (h *Hub) runShard(shard *broadcastShard) {
+	for message := range shard.jobs {
+		var slow []*Client
+
+		h.mutex.RLock()
+		for client := range shard.clients {
+			select {
+			case client.Send <- message:
+				h.messagesSent.Add(1)
+			default:
+				slow = append(slow, client)
+			}
+		}
+		h.mutex.RUnlock()
+
+		h.dropClients(slow)
+	}
+}
+
+// Sends to client.Send happen under the read lock and only while the client
+// is registered; the channel is closed only by removeClient under the write
+// lock, so a send can never hit a closed channel.
+func (h *Hub) dropClients(clients []*Client) {
+	if len(clients) == 0 {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, client := range clients {
+		h.removeClient(client)
+	}
+}
+
+// admit must be called with the write lock held.
+func (h *Hub) admit(client *Client) error {
+	if h.limits.MaxConnections > 0 && len(h.clients) >= h.limits.MaxConnections {
+		return ErrTooManyConnections
+	}
+	if client.UserID != "" && h.limits.MaxConnectionsPerUser > 0 && h.userConns[client.UserID] >= h.limits.MaxConnectionsPerUser {
+		return ErrTooManyUserConnections
+	}
+	return nil
+}
+
+// removeClient must be called with the write lock held.
+func (h *Hub) removeClient(client *Client) {
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+	delete(h.clients, client)
+	if client.UserID != "" {
+		if h.userConns[client.UserID]--; h.userConns[client.UserID] <= 0 {
+			delete(h.userConns, client.UserID)
 		}
 	}
+	delete(h.shards[client.shard].clients, client)
+	close(client.Send)
+}
+
+// Healthy reports whether the Run loop is alive by round-tripping a probe
+// through it.
+func (h *Hub) Healthy() bool {
+	timer := time.NewTimer(healthCheckTimeout)
+	defer timer.Stop()
+
+	reply := make(chan struct{})
+	select {
+	case h.healthProbe <- reply:
+	case <-timer.C:
+		return false
+	}
+
+	select {
+	case <-reply:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// Register adds a client to the hub, or returns ErrTooManyConnections or
+// ErrTooManyUserConnections when a connection limit is reached.
+func (h *Hub) Register(client *Client) error {
+	result := make(chan error, 1)
+	h.register <- registration{client: client, result: result}
+	return <-result
+}
+
+func (h *Hub) Unregister(client *Client) {
+	h.unregister <- client
 }
 
 func (h *Hub) Broadcast(message *Message) {
@@ -95,49 +308,179 @@ func (h *Hub) Broadcast(message *Message) {
 		return
 	}
 
+	if IsCriticalMessage(message.Type) {
+		timer := time.NewTimer(criticalBroadcastTimeout)
+		defer timer.Stop()
+
+		select {
+		case h.broadcast <- data:
+			h.broadcastsQueued.Add(1)
+		case <-timer.C:
+			h.broadcastsDropped.Add(1)
+			log.Printf("Broadcast channel stayed full for %s, dropping critical %s message", criticalBroadcastTimeout, message.Type)
+		}
+		return
+	}
+
 	select {
 	case h.broadcast <- data:
+		h.broadcastsQueued.Add(1)
 	default:
+		h.broadcastsDropped.Add(1)
 		log.Println("Broadcast channel is full, dropping message")
 	}
 }
 
 func (h *Hub) BroadcastToUser(userID string, message *Message) {
-	h.mutex.RLock()
-	defer h.mutex.RUnlock()
+	h.sendWhere(message, func(client *Client) bool {
+		return client.UserID == userID
+	})
+}
+
+func (h *Hub) BroadcastToRole(role string, message *Message) {
+	h.sendWhere(message, func(client *Client) bool {
+		return client.UserRole == role
+	})
+}
+
+// SetKeepalive must be called before Run; zero fields keep their defaults.
+func (h *Hub) SetKeepalive(cfg KeepaliveConfig) {
+	if cfg.PingInterval > 0 {
+		h.keepalive.PingInterval = cfg.PingInterval
+	}
+	if cfg.PongWait > 0 {
+		h.keepalive.PongWait = cfg.PongWait
+	}
+	if cfg.WriteWait > 0 {
+		h.keepalive.WriteWait = cfg.WriteWait
+	}
+}
+
+// SetConnectionLimits must be called before Run.
+func (h *Hub) SetConnectionLimits(limits ConnectionLimits) {
+	h.limits = limits
+}
+
+// SetOptOutFilter must be called before any messages are sent; the filter
+// is read without locking.
+func (h *Hub) SetOptOutFilter(filter OptOutFilter) {
+	h.optOuts = filter
+}
+
+// SetNotificationStore has the same restriction as SetOptOutFilter.
+func (h *Hub) SetNotificationStore(store NotificationStore) {
+	h.store = store
+}
+
+func (h *Hub) record(userID string, messageType MessageType, text string, data interface{}) {
+	if h.store != nil && userID != "" {
+		h.store(userID, string(messageType), text, data)
+	}
+}
 
+// sendOptional delivers a non-critical message to the matching clients,
+// skipping users who opted out of event. Anonymous clients always receive it.
+func (h *Hub) sendOptional(event string, message *Message, match func(*Client) bool) {
+	if h.optOuts == nil {
+		h.sendWhere(message, match)
+		return
+	}
+
+	var userIDs []string
+	seen := make(map[string]bool)
+	h.mutex.RLock()
 	for client := range h.clients {
-		if client.UserID == userID {
-			h.sendToClient(client, message)
+		if client.UserID != "" && !seen[client.UserID] && match(client) {
+			seen[client.UserID] = true
+			userIDs = append(userIDs, client.UserID)
 		}
 	}
+	h.mutex.RUnlock()
+
+	optedOut := h.optOuts(event, userIDs)
+	h.sendWhere(message, func(client *Client) bool {
+		return match(client) && !optedOut[client.UserID]
+	})
 }
 
-func (h *Hub) BroadcastToRole(role string, message *Message) {
-	h.mutex.RLock()
-	defer h.mutex.RUnlock()
+func (h *Hub) sendWhere(message *Message, match func(*Client) bool) {
+	var slow []*Client
 
+	h.mutex.RLock()
 	for client := range h.clients {
-		if client.UserRole == role {
-			h.sendToClient(client, message)
+		if match(client) && !h.sendToClient(client, message) {
+			slow = append(slow, client)
 		}
 	}
+	h.mutex.RUnlock()
+
+	h.dropClients(slow)
+}
+
+func everyClient(*Client) bool { return true }
+
+// deliver sends a message to a single client if it is still registered.
+func (h *Hub) deliver(client *Client, message *Message) {
+	h.mutex.RLock()
+	ok := !h.clients[client] || h.sendToClient(client, message)
+	h.mutex.RUnlock()
+
+	if !ok {
+		h.dropClients([]*Client{client})
+	}
 }
 
-func (h *Hub) sendToClient(client *Client, message *Message) {
+// sendToClient must be called with the read lock held. It reports false when
+// the client's buffer is full; the caller is responsible for dropping it.
+func (h *Hub) sendToClient(client *Client, message *Message) bool {
 	data, err := message.ToJSON()
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return true
+	}
+
+	select {
+	case client.Send <- data:
+		h.messagesSent.Add(1)
+		return true
+	default:
+		return false
+	}
+}
+
+// sendCritical delivers a message that must be acknowledged by the user's
+// client; it stays queued and is redelivered on reconnect until acked.
+func (h *Hub) sendCritical(userID string, message *Message) {
+	data, err := h.acks.track(userID, message)
 	if err != nil {
 		log.Printf("Error marshaling message: %v", err)
 		return
 	}
 
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for client := range h.clients {
+		if client.UserID == userID {
+			h.sendRaw(client, data)
+		}
+	}
+}
+
+func (h *Hub) sendRaw(client *Client, data []byte) {
 	select {
 	case client.Send <- data:
-		h.messagesSent++
+		h.messagesSent.Add(1)
 	default:
-		close(client.Send)
-		delete(h.clients, client)
+		log.Printf("Send buffer full for user %s, critical message left pending", client.UserID)
+	}
+}
+
+func (h *Hub) Ack(userID string, seq uint64) {
+	if userID == "" {
+		return
 	}
+	h.acks.ack(userID, seq)
 }
 
 func (h *Hub) GetClientCount() int {
@@ -185,16 +528,27 @@ func (h *Hub) GetStats() HubStats {
 		}
 	}
 
+	unacked, unackedByUser := h.acks.counts()
+
 	return HubStats{
 		TotalClients:     len(h.clients),
 		ConnectedUsers:   connectedUsers,
-		MessagesSent:     h.messagesSent,
-		MessagesReceived: h.messagesReceived,
+		MessagesSent:     h.messagesSent.Load(),
+		MessagesReceived: h.messagesReceived.Load(),
 		Uptime:           time.Since(h.startTime),
 		LastActivity:     h.lastActivity,
+		UnackedMessages:  unacked,
+		Keepalive:        h.keepalive,
+		Limits:           h.limits,
+		Rejected:         h.connsRejected.Load(),
 		Metrics: map[string]interface{}{
-			"active_connections": len(h.clients),
-			"unique_users":       len(connectedUsers),
+			"active_connections":   len(h.clients),
+			"unique_users":         len(connectedUsers),
+			"unacked_by_user":      unackedByUser,
+			"broadcasts_queued":    h.broadcastsQueued.Load(),
+			"broadcasts_dropped":   h.broadcastsDropped.Load(),
+			"connections_rejected": h.connsRejected.Load(),
+			"broadcast_backlog":    len(h.broadcast),
 		},
 	}
 }
@@ -207,10 +561,18 @@ func (h *Hub) SendNotification(title, message, icon, priority, category string)
 func (h *Hub) SendOrderUpdate(orderID, status, message, userID string) {
 	orderUpdate := CreateOrderUpdateMessage(orderID, status, message, userID)
 
-	h.BroadcastToUser(userID, orderUpdate)
+	h.record(userID, orderUpdate.Type, message, orderUpdate.Data)
+	h.sendCritical(userID, orderUpdate)
 	h.BroadcastToRole("admin", orderUpdate)
 }
 
+func (h *Hub) SendPaymentUpdate(paymentID, orderID, status, message, userID string) {
+	paymentUpdate := CreatePaymentUpdateMessage(paymentID, orderID, status, message, userID)
+
+	h.record(userID, paymentUpdate.Type, message, paymentUpdate.Data)
+	h.sendCritical(userID, paymentUpdate)
+}
+
 func (h *Hub) SendProductUpdate(productID, action string, data interface{}) {
 	productUpdate := CreateProductUpdateMessage(productID, action, data)
 	h.Broadcast(productUpdate)
@@ -218,22 +580,24 @@ func (h *Hub) SendProductUpdate(productID, action string, data interface{}) {
 
 func (h *Hub) SendStockAlert(productID, productName string, currentStock int) {
 	alert := CreateStockAlertMessage(productID, productName, currentStock)
-	h.BroadcastToRole("admin", alert)
+	h.sendOptional("stock", alert, func(client *Client) bool {
+		return client.UserRole == "admin"
+	})
 }
 
 func (h *Hub) SendPriceAlert(productID, productName string, oldPrice, newPrice float64) {
 	alert := CreatePriceAlertMessage(productID, productName, oldPrice, newPrice)
-	h.Broadcast(alert)
+	h.sendOptional("price", alert, everyClient)
 }
 
 func (h *Hub) SendNewProductAlert(productID, productName string) {
 	alert := CreateNewProductAlertMessage(productID, productName)
-	h.Broadcast(alert)
+	h.sendOptional("promotion", alert, everyClient)
 }
 
 func (h *Hub) SendPromotionAlert(title, message, actionURL string) {
 	alert := CreatePromotionAlertMessage(title, message, actionURL)
-	h.Broadcast(alert)
+	h.sendOptional("promotion", alert, everyClient)
 }
 
 func (h *Hub) SendMaintenanceAlert(message string, scheduledTime time.Time) {
@@ -241,6 +605,17 @@ func (h *Hub) SendMaintenanceAlert(message string, scheduledTime time.Time) {
 	h.Broadcast(alert)
 }
 
+func (h *Hub) SendReviewReply(userID, reviewID, productID, reply string) {
+	replyMsg := CreateReviewReplyMessage(reviewID, productID, reply, userID)
+	h.record(userID, replyMsg.Type, "Your review received a reply", replyMsg.Data)
+	h.BroadcastToUser(userID, replyMsg)
+}
+
+func (h *Hub) SendProductQuestion(questionID, productID, question string) {
+	questionMsg := CreateProductQuestionMessage(questionID, productID, question)
+	h.BroadcastToRole("admin", questionMsg)
+}
+
 func (h *Hub) SendUserActivity(userID, activity, details string) {
 	activityMsg := CreateUserActivityMessage(userID, activity, details)
 	h.BroadcastToRole("admin", activityMsg)
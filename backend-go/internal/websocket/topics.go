@@ -0,0 +1,53 @@
+package websocket
+
+import "fmt"
+
+// TopicAdminOrders carries a summary of every newly placed order.
+const TopicAdminOrders = "admin:orders"
+
+// topicRoles lists the topics clients may subscribe to and the role each one
+// requires; an empty role means any connected client may subscribe.
+var topicRoles = map[string]string{
+	TopicAdminOrders: "admin",
+}
+
+// Subscribe adds client to topic if the topic exists and the client's role
+// is allowed to receive it.
+func (h *Hub) Subscribe(client *Client, topic string) error {
+	role, ok := topicRoles[topic]
+	if !ok {
+		return fmt.Errorf("unknown topic")
+	}
+	if role != "" && client.UserRole != role {
+		return fmt.Errorf("not allowed to subscribe to this topic")
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if client.topics == nil {
+		client.topics = make(map[string]bool)
+	}
+	client.topics[topic] = true
+	return nil
+}
+
+func (h *Hub) Unsubscribe(client *Client, topic string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	delete(client.topics, topic)
+}
+
+// publish sends message to the clients subscribed to topic. The role check
+// is repeated so a topic restricted to a role never leaks to anyone else.
+func (h *Hub) publish(topic string, message *Message) {
+	role := topicRoles[topic]
+	h.sendWhere(message, func(client *Client) bool {
+		return client.topics[topic] && (role == "" || client.UserRole == role)
+	})
+}
+
+func (h *Hub) SendOrderFeed(data OrderFeedData) {
+	h.publish(TopicAdminOrders, CreateOrderFeedMessage(data))
+}
@@ -13,6 +13,8 @@ type Client struct {
 	UserID   string
 	UserRole string
 	JoinedAt time.Time
+	shard    int
+	topics   map[string]bool
 }
 
 type MessageType string
@@ -20,6 +22,7 @@ type MessageType string
 const (
 	MessageTypeNotification     MessageType = "notification"
 	MessageTypeOrderUpdate      MessageType = "order_update"
+	MessageTypePaymentUpdate    MessageType = "payment_update"
 	MessageTypeProductUpdate    MessageType = "product_update"
 	MessageTypeStockAlert       MessageType = "stock_alert"
 	MessageTypePriceAlert       MessageType = "price_alert"
@@ -29,8 +32,14 @@ const (
 	MessageTypeUserActivity     MessageType = "user_activity"
 	MessageTypeAnalyticsUpdate  MessageType = "analytics_update"
 	MessageTypeRealTimeStats    MessageType = "real_time_stats"
+	MessageTypeReviewReply      MessageType = "review_reply"
+	MessageTypeProductQuestion  MessageType = "product_question"
 	MessageTypePing             MessageType = "ping"
 	MessageTypePong             MessageType = "pong"
+	MessageTypeAck              MessageType = "ack"
+	MessageTypeSubscribe        MessageType = "subscribe"
+	MessageTypeUnsubscribe      MessageType = "unsubscribe"
+	MessageTypeOrderFeed        MessageType = "order_feed"
 )
 
 type Message struct {
@@ -38,6 +47,7 @@ type Message struct {
 	Data      interface{} `json:"data"`
 	Timestamp time.Time   `json:"timestamp"`
 	ID        string      `json:"id,omitempty"`
+	Seq       uint64      `json:"seq,omitempty"`
 	UserID    string      `json:"user_id,omitempty"`
 	Priority  string      `json:"priority,omitempty"`
 	Category  string      `json:"category,omitempty"`
@@ -56,6 +66,13 @@ type OrderUpdateData struct {
 	UserID  string `json:"user_id"`
 }
 
+type PaymentUpdateData struct {
+	PaymentID string `json:"payment_id"`
+	OrderID   string `json:"order_id,omitempty"`
+	Status    string `json:"status"`
+	Message   string `json:"message"`
+}
+
 type ProductUpdateData struct {
 	ProductID string      `json:"product_id"`
 	Action    string      `json:"action"`
@@ -105,6 +122,33 @@ type RealTimeStatsData struct {
 	Stats map[string]interface{} `json:"stats"`
 }
 
+type ReviewReplyData struct {
+	ReviewID  string `json:"review_id"`
+	ProductID string `json:"product_id"`
+	Reply     string `json:"reply"`
+}
+
+type ProductQuestionData struct {
+	QuestionID string `json:"question_id"`
+	ProductID  string `json:"product_id"`
+	Question   string `json:"question"`
+}
+
+type SubscriptionData struct {
+	Topic      string `json:"topic"`
+	Subscribed bool   `json:"subscribed"`
+	Error      string `json:"error,omitempty"`
+}
+
+type OrderFeedData struct {
+	OrderID   string    `json:"order_id"`
+	StoreID   string    `json:"store_id,omitempty"`
+	Total     float64   `json:"total"`
+	ItemCount int       `json:"item_count"`
+	Customer  string    `json:"customer"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type ClientInfo struct {
 	UserID   string    `json:"user_id"`
 	UserRole string    `json:"user_role"`
@@ -118,5 +162,9 @@ type HubStats struct {
 	MessagesReceived int64                  `json:"messages_received"`
 	Uptime           time.Duration          `json:"uptime"`
 	LastActivity     time.Time              `json:"last_activity"`
+	UnackedMessages  int                    `json:"unacked_messages"`
+	Keepalive        KeepaliveConfig        `json:"keepalive"`
+	Limits           ConnectionLimits       `json:"limits"`
+	Rejected         int64                  `json:"rejected_connections"`
 	Metrics          map[string]interface{} `json:"metrics"`
 }
@@ -35,6 +35,15 @@ func CreateOrderUpdateMessage(orderID, status, message, userID string) *Message
 	}, userID)
 }
 
+func CreatePaymentUpdateMessage(paymentID, orderID, status, message, userID string) *Message {
+	return CreateMessage(MessageTypePaymentUpdate, PaymentUpdateData{
+		PaymentID: paymentID,
+		OrderID:   orderID,
+		Status:    status,
+		Message:   message,
+	}, userID)
+}
+
 func CreateProductUpdateMessage(productID, action string, data interface{}) *Message {
 	return CreateMessage(MessageTypeProductUpdate, ProductUpdateData{
 		ProductID: productID,
@@ -102,6 +111,26 @@ func CreateRealTimeStatsMessage(stats map[string]interface{}) *Message {
 	}, "")
 }
 
+func CreateReviewReplyMessage(reviewID, productID, reply, userID string) *Message {
+	return CreateMessage(MessageTypeReviewReply, ReviewReplyData{
+		ReviewID:  reviewID,
+		ProductID: productID,
+		Reply:     reply,
+	}, userID)
+}
+
+func CreateProductQuestionMessage(questionID, productID, question string) *Message {
+	return CreateMessage(MessageTypeProductQuestion, ProductQuestionData{
+		QuestionID: questionID,
+		ProductID:  productID,
+		Question:   question,
+	}, "")
+}
+
+func CreateOrderFeedMessage(data OrderFeedData) *Message {
+	return CreateMessage(MessageTypeOrderFeed, data, "")
+}
+
 func (m *Message) ToJSON() ([]byte, error) {
 	return json.Marshal(m)
 }
@@ -131,6 +160,7 @@ func ValidateMessageType(msgType MessageType) bool {
 	validTypes := []MessageType{
 		MessageTypeNotification,
 		MessageTypeOrderUpdate,
+		MessageTypePaymentUpdate,
 		MessageTypeProductUpdate,
 		MessageTypeStockAlert,
 		MessageTypePriceAlert,
@@ -140,8 +170,11 @@ func ValidateMessageType(msgType MessageType) bool {
 		MessageTypeUserActivity,
 		MessageTypeAnalyticsUpdate,
 		MessageTypeRealTimeStats,
+		MessageTypeReviewReply,
+		MessageTypeProductQuestion,
 		MessageTypePing,
 		MessageTypePong,
+		MessageTypeAck,
 	}
 	
 	for _, validType := range validTypes {
@@ -157,7 +190,11 @@ func GetMessageTypeFromString(typeStr string) MessageType {
 }
 
 func IsSystemMessage(msgType MessageType) bool {
-	return msgType == MessageTypePing || msgType == MessageTypePong
+	return msgType == MessageTypePing || msgType == MessageTypePong || msgType == MessageTypeAck
+}
+
+func IsCriticalMessage(msgType MessageType) bool {
+	return msgType == MessageTypeOrderUpdate || msgType == MessageTypePaymentUpdate
 }
 
 func IsUserMessage(msgType MessageType) bool {
@@ -168,6 +205,8 @@ func GetMessagePriority(msgType MessageType) string {
 	switch msgType {
 	case MessageTypeMaintenanceAlert, MessageTypeStockAlert:
 		return "high"
+	case MessageTypePaymentUpdate:
+		return "high"
 	case MessageTypeOrderUpdate, MessageTypePriceAlert:
 		return "medium"
 	default:
@@ -179,13 +218,15 @@ func GetMessageCategory(msgType MessageType) string {
 	switch msgType {
 	case MessageTypeOrderUpdate:
 		return "orders"
-	case MessageTypeProductUpdate, MessageTypeStockAlert, MessageTypePriceAlert, MessageTypeNewProductAlert:
+	case MessageTypePaymentUpdate:
+		return "payments"
+	case MessageTypeProductUpdate, MessageTypeStockAlert, MessageTypePriceAlert, MessageTypeNewProductAlert, MessageTypeProductQuestion:
 		return "products"
 	case MessageTypePromotionAlert:
 		return "promotions"
 	case MessageTypeMaintenanceAlert:
 		return "system"
-	case MessageTypeUserActivity:
+	case MessageTypeUserActivity, MessageTypeReviewReply:
 		return "user"
 	case MessageTypeAnalyticsUpdate, MessageTypeRealTimeStats:
 		return "analytics"
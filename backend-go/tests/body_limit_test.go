@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ecommerce-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newBodyLimitRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.MaxBodySize(16, map[string]int64{"/upload": 64}))
+
+	read := func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.String(http.StatusOK, "%d", len(body))
+	}
+	r.POST("/json", read)
+	r.POST("/upload", read)
+	return r
+}
+
+func TestMaxBodySize(t *testing.T) {
+	r := newBodyLimitRouter()
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"within global limit", "/json", strings.Repeat("a", 16), http.StatusOK},
+		{"over global limit", "/json", strings.Repeat("a", 17), http.StatusRequestEntityTooLarge},
+		{"route limit raises global limit", "/upload", strings.Repeat("a", 64), http.StatusOK},
+		{"over route limit", "/upload", strings.Repeat("a", 65), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			r.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestMaxBodySizeWithoutContentLength(t *testing.T) {
+	r := newBodyLimitRouter()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/json", io.NopCloser(strings.NewReader(strings.Repeat("a", 32))))
+	req.ContentLength = -1
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
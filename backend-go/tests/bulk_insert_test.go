@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// Run with TEST_DATABASE_URL set:
+//
+//	go test ./tests -run '^$' -bench ProductInsert -benchtime 3x
+//
+// Each iteration inserts productInsertCount products, so the one-by-one and
+// batched results compare directly.
+const productInsertCount = 2000
+
+func benchmarkProducts(prefix string) []*models.Product {
+	now := time.Now()
+	products := make([]*models.Product, productInsertCount)
+	for i := range products {
+		products[i] = &models.Product{
+			ID:          uuid.New().String(),
+			Name:        fmt.Sprintf("Bench product %d", i),
+			Slug:        fmt.Sprintf("%s-%d", prefix, i),
+			Price:       1999,
+			Images:      []string{},
+			InStock:     true,
+			Stock:       10,
+			ProductType: models.ProductTypePhysical,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+	}
+	return products
+}
+
+func BenchmarkProductInsertOneByOne(b *testing.B) {
+	db := openTestDatabase(b)
+	repo := repositories.NewProductRepository(db)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		products := benchmarkProducts("bench-single-" + uuid.New().String())
+		b.StartTimer()
+		for _, product := range products {
+			if err := repo.Create(product); err != nil {
+				b.Fatalf("Create returned error: %v", err)
+			}
+		}
+	}
+}
+
+func BenchmarkProductInsertBatch(b *testing.B) {
+	db := openTestDatabase(b)
+	repo := repositories.NewProductRepository(db)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		products := benchmarkProducts("bench-batch-" + uuid.New().String())
+		b.StartTimer()
+		if _, err := repo.CreateBatch(products, repositories.DefaultBatchSize); err != nil {
+			b.Fatalf("CreateBatch returned error: %v", err)
+		}
+	}
+}
+
+func TestProductCreateBatchSkipsExistingSlugs(t *testing.T) {
+	db := openTestDatabase(t)
+	repo := repositories.NewProductRepository(db)
+	products := benchmarkProducts("batch-" + uuid.New().String())[:5]
+
+	inserted, err := repo.CreateBatch(products[:3], 2)
+	if err != nil || inserted != 3 {
+		t.Fatalf("Expected 3 inserted products, got %d (%v)", inserted, err)
+	}
+	for _, product := range products {
+		product.ID = ""
+	}
+	inserted, err = repo.CreateBatch(products, 2)
+	if err != nil || inserted != 2 {
+		t.Errorf("Expected only the 2 new slugs to be inserted, got %d (%v)", inserted, err)
+	}
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"testing"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestBundleAvailability(t *testing.T) {
+	components := []models.BundleComponent{
+		{ProductID: "a", Quantity: 2, Stock: 9, InStock: true},
+		{ProductID: "b", Quantity: 1, Stock: 7, InStock: true},
+	}
+	if got := models.BundleAvailability(components); got != 4 {
+		t.Errorf("Expected 4 bundles, got %d", got)
+	}
+	components[1].InStock = false
+	if got := models.BundleAvailability(components); got != 0 {
+		t.Errorf("Expected an unavailable component to make the bundle unavailable, got %d", got)
+	}
+	if got := models.BundleAvailability(nil); got != 0 {
+		t.Errorf("Expected no components to make no bundles, got %d", got)
+	}
+}
+
+func TestProductBundleStock(t *testing.T) {
+	db := openTestDatabase(t)
+	productRepo := repositories.NewProductRepository(db)
+	inventoryRepo := repositories.NewInventoryRepository(db)
+	service := services.NewBundleService(repositories.NewBundleRepository(db), productRepo)
+
+	var ids []string
+	for _, stock := range []int{10, 3, 0} {
+		id := uuid.New().String()
+		if _, err := db.Exec("INSERT INTO products (id, name, slug, price, stock) VALUES ($1, 'Kit part', $2, 10, $3)", id, "bundle-"+id, stock); err != nil {
+			t.Fatalf("Failed to create product: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM product_bundle_items WHERE bundle_id = ANY($1)", pq.Array(ids))
+		db.Exec("DELETE FROM products WHERE id = ANY($1)", pq.Array(ids))
+	})
+	partA, partB, bundleID := ids[0], ids[1], ids[2]
+
+	bundle, err := service.SetBundle(bundleID, "", models.BundleSetRequest{Items: []models.BundleItemRequest{
+		{ProductID: partA, Quantity: 2},
+		{ProductID: partB, Quantity: 1},
+	}})
+	if err != nil {
+		t.Fatalf("SetBundle returned error: %v", err)
+	}
+	if bundle.Available != 3 || len(bundle.Components) != 2 {
+		t.Fatalf("Expected 3 bundles from 2 components, got %d from %d", bundle.Available, len(bundle.Components))
+	}
+	stockOf := func(id string) int {
+		product, err := productRepo.GetByID("", id)
+		if err != nil {
+			t.Fatalf("GetByID returned error: %v", err)
+		}
+		return product.Stock
+	}
+	if stock := stockOf(bundleID); stock != 3 {
+		t.Errorf("Expected the bundle stock to follow its components, got %d", stock)
+	}
+
+	// Selling a component lowers the bundle's stock with it.
+	if _, err := inventoryRepo.Apply(partB, -2, models.InventoryReasonSale, nil); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	if stock := stockOf(bundleID); stock != 1 {
+		t.Errorf("Expected 1 bundle left, got %d", stock)
+	}
+	if _, err := inventoryRepo.SetStock(bundleID, 50, models.InventoryReasonAdjustment, nil); err == nil {
+		t.Error("Expected setting a bundle's stock directly to fail")
+	}
+	if _, err := service.SetBundle(partA, "", models.BundleSetRequest{Items: []models.BundleItemRequest{{ProductID: bundleID, Quantity: 1}}}); err == nil || err.Error() != "bundles cannot contain bundles" {
+		t.Errorf("Expected nested bundles to be rejected, got %v", err)
+	}
+
+	if err := service.RemoveBundle(bundleID, ""); err != nil {
+		t.Fatalf("RemoveBundle returned error: %v", err)
+	}
+	if _, err := service.GetBundle(bundleID, ""); err == nil || err.Error() != "bundle not found" {
+		t.Errorf("Expected the product to no longer be a bundle, got %v", err)
+	}
+	if stock := stockOf(bundleID); stock != 1 {
+		t.Errorf("Expected the former bundle to keep its stock, got %d", stock)
+	}
+}
+
+func TestOrderBundleLines(t *testing.T) {
+	db := openTestDatabase(t)
+	orders := repositories.NewOrderRepository(db)
+	userID, item := createCartFixture(t, db)
+	version, _, err := repositories.NewCartRepository(db).GetVersion(userID)
+	if err != nil {
+		t.Fatalf("GetVersion returned error: %v", err)
+	}
+
+	order := checkoutOrder(userID)
+	line := models.OrderItem{
+		ID: uuid.New().String(), ProductID: item.ProductID, ProductName: "Kit", Quantity: models.QuantityFromInt(2), Price: models.MoneyFromFloat(5),
+		Components: []models.OrderItem{
+			{ID: uuid.New().String(), ProductID: item.ProductID, ProductName: "Kit part", Quantity: models.QuantityFromInt(4)},
+		},
+	}
+	if err := orders.CreateFromCart(order, []models.OrderItem{line}, version); err != nil {
+		t.Fatalf("CreateFromCart returned error: %v", err)
+	}
+	items, err := orders.GetOrderItems(order.ID)
+	if err != nil {
+		t.Fatalf("GetOrderItems returned error: %v", err)
+	}
+	if len(items) != 1 || len(items[0].Components) != 1 {
+		t.Fatalf("Expected one bundle line with one component, got %+v", items)
+	}
+	fulfillment := models.FulfillmentItems(items)
+	if len(fulfillment) != 1 || fulfillment[0].ID != line.Components[0].ID || fulfillment[0].Quantity != models.QuantityFromInt(4) {
+		t.Errorf("Expected the component line to be fulfilled, got %+v", fulfillment)
+	}
+}
@@ -2,9 +2,13 @@ package tests
 
 import (
 	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"ecommerce-backend/internal/database"
 	"ecommerce-backend/internal/utils"
 )
 
@@ -225,3 +229,203 @@ func TestCacheInvalidatePattern(t *testing.T) {
 		t.Error("Product key should not be invalidated")
 	}
 }
+
+func TestCacheManagerInvalidatePrefix(t *testing.T) {
+	manager := utils.NewCacheManager()
+
+	products := manager.GetCache("products")
+	products.Set("list:1", "page1", time.Minute)
+	products.Set("featured", "featured", time.Minute)
+	search := manager.GetCache("search")
+	search.Set("products:laptop", "laptops", time.Minute)
+	search.Set("suggest:lap", "suggestions", time.Minute)
+
+	removed := manager.InvalidatePrefix("products:*")
+	if removed != 3 {
+		t.Errorf("Expected 3 removed entries, got %d", removed)
+	}
+
+	if products.Size() != 0 {
+		t.Error("Products cache should be empty after prefix invalidation")
+	}
+
+	if _, exists := search.Get("suggest:lap"); !exists {
+		t.Error("Non-matching key should not be invalidated")
+	}
+}
+
+func TestCacheDecoratorDeduplicatesConcurrentMisses(t *testing.T) {
+	cache := utils.NewStatsCache()
+	decorator := utils.NewCacheDecorator(cache, time.Minute)
+
+	var calls int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	const goroutines = 50
+
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			value, err := decorator.GetOrSet("hot-key", func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(50 * time.Millisecond)
+				return "value", nil
+			})
+			if err != nil || value != "value" {
+				t.Errorf("Unexpected result: %v, %v", value, err)
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("Expected loader to run once, ran %d times", calls)
+	}
+}
+
+func TestCacheLRUEviction(t *testing.T) {
+	cache := utils.NewStatsCacheWithOptions(time.Minute, 2)
+
+	cache.Set("key1", "value1", time.Minute)
+	cache.Set("key2", "value2", time.Minute)
+	cache.Get("key1")
+	cache.Set("key3", "value3", time.Minute)
+
+	if _, exists := cache.Get("key2"); exists {
+		t.Error("Least recently used key should be evicted")
+	}
+	if _, exists := cache.Get("key1"); !exists {
+		t.Error("Recently used key should be kept")
+	}
+
+	stats := cache.GetStats()
+	if stats.Evictions != 1 {
+		t.Errorf("Expected 1 eviction, got %d", stats.Evictions)
+	}
+
+	cache.ResetStats()
+	stats = cache.GetStats()
+	if stats.Hits != 0 || stats.Misses != 0 || stats.Evictions != 0 {
+		t.Error("Stats should be zero after reset")
+	}
+}
+
+func TestCacheIncrement(t *testing.T) {
+	cache := utils.NewCache()
+
+	count, resetAt := cache.Increment("ip:1", 50*time.Millisecond)
+	if count != 1 {
+		t.Errorf("Expected first increment to be 1, got %d", count)
+	}
+	if again, againReset := cache.Increment("ip:1", 50*time.Millisecond); again != 2 || !againReset.Equal(resetAt) {
+		t.Errorf("Expected second increment to be 2 within the same window, got %d", again)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if count, _ := cache.Increment("ip:1", 50*time.Millisecond); count != 1 {
+		t.Errorf("Expected counter to restart after window expiry, got %d", count)
+	}
+}
+
+// flakyCacheBackend is an in-memory CacheBackend that can be switched off to
+// simulate an outage.
+type flakyCacheBackend struct {
+	mutex sync.Mutex
+	items map[string]interface{}
+	down  bool
+	calls int
+}
+
+func (b *flakyCacheBackend) do(fn func()) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.calls++
+	if b.down {
+		return errors.New("connection refused")
+	}
+	fn()
+	return nil
+}
+
+func (b *flakyCacheBackend) Get(key string) (value interface{}, found bool, err error) {
+	err = b.do(func() { value, found = b.items[key] })
+	return value, found, err
+}
+
+func (b *flakyCacheBackend) Set(key string, value interface{}, ttl time.Duration) error {
+	return b.do(func() { b.items[key] = value })
+}
+
+func (b *flakyCacheBackend) Delete(key string) error {
+	return b.do(func() { delete(b.items, key) })
+}
+
+func (b *flakyCacheBackend) DeletePrefix(prefix string) error {
+	return b.do(func() {
+		for key := range b.items {
+			if strings.HasPrefix(key, prefix) {
+				delete(b.items, key)
+			}
+		}
+	})
+}
+
+func (b *flakyCacheBackend) setDown(down bool) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.down = down
+	return b.calls
+}
+
+func TestCacheManagerSurvivesBackendOutage(t *testing.T) {
+	backend := &flakyCacheBackend{items: map[string]interface{}{"products:shared": "from backend"}}
+	manager := utils.NewCacheManager()
+	manager.SetBackend(backend, 2, 50*time.Millisecond)
+
+	value, err := manager.GetOrSet("products", "shared", time.Minute, func() (interface{}, error) {
+		return "from database", nil
+	})
+	if err != nil || value != "from backend" {
+		t.Fatalf("Expected the backend value, got %v (%v)", value, err)
+	}
+	if status, _ := manager.BackendStatus(); status != utils.CacheBackendHealthy {
+		t.Errorf("Expected a healthy backend, got %s", status)
+	}
+
+	backend.setDown(true)
+	loads := 0
+	for i := 0; i < 5; i++ {
+		manager.Invalidate("products", "list")
+		value, err := manager.GetOrSet("products", "list", time.Minute, func() (interface{}, error) {
+			loads++
+			return "from database", nil
+		})
+		if err != nil || value != "from database" {
+			t.Fatalf("Expected a direct read during the outage, got %v (%v)", value, err)
+		}
+	}
+	if loads != 5 {
+		t.Errorf("Expected 5 direct reads, got %d", loads)
+	}
+	status, circuit := manager.BackendStatus()
+	if status != utils.CacheBackendDegraded || circuit != database.BreakerOpen {
+		t.Errorf("Expected a degraded backend with an open circuit, got %s (%s)", status, circuit)
+	}
+	// Two failures open the circuit; after that the backend is left alone.
+	if calls := backend.setDown(true); calls > 1+2 {
+		t.Errorf("Expected the open circuit to stop backend calls, got %d calls", calls)
+	}
+
+	backend.setDown(false)
+	time.Sleep(60 * time.Millisecond)
+	manager.GetOrSet("products", "fresh", time.Minute, func() (interface{}, error) {
+		return "from database", nil
+	})
+	if status, _ := manager.BackendStatus(); status != utils.CacheBackendHealthy {
+		t.Errorf("Expected the backend to recover after the retry interval, got %s", status)
+	}
+}
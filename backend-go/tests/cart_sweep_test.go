@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"testing"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+)
+
+func TestCartSweepRemovesDiscontinuedItems(t *testing.T) {
+	db := openTestDatabase(t)
+	repo := repositories.NewCartRepository(db)
+	userID, item := createCartFixture(t, db)
+
+	before, _, err := repo.GetVersion(userID)
+	if err != nil {
+		t.Fatalf("GetVersion returned error: %v", err)
+	}
+	if _, err := db.Exec("UPDATE products SET deleted_at = NOW() WHERE id = $1", item.ProductID); err != nil {
+		t.Fatalf("Failed to discontinue product: %v", err)
+	}
+
+	removed, err := repo.RemoveStaleItems(userID, nil)
+	if err != nil {
+		t.Fatalf("RemoveStaleItems returned error: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 removed item, got %d", removed)
+	}
+
+	notices, err := repo.GetNotices(userID)
+	if err != nil {
+		t.Fatalf("GetNotices returned error: %v", err)
+	}
+	if len(notices) != 1 || notices[0].Type != models.CartNoticeDiscontinued || notices[0].ProductName != "Widget" {
+		t.Errorf("Expected one discontinued notice for Widget, got %+v", notices)
+	}
+
+	after, _, err := repo.GetVersion(userID)
+	if err != nil {
+		t.Fatalf("GetVersion returned error: %v", err)
+	}
+	if after != before+1 {
+		t.Errorf("Expected version %d, got %d", before+1, after)
+	}
+
+	if err := repo.AcknowledgeNotices(userID); err != nil {
+		t.Fatalf("AcknowledgeNotices returned error: %v", err)
+	}
+	if notices, _ := repo.GetNotices(userID); len(notices) != 0 {
+		t.Errorf("Expected notices to be cleared, got %d", len(notices))
+	}
+}
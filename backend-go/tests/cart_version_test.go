@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/database"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// openTestDatabase connects to TEST_DATABASE_URL and applies migrations.
+// Tests that need PostgreSQL are skipped when it is not set.
+func openTestDatabase(t testing.TB) *sql.DB {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.RunMigrations(db); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	return db
+}
+
+func createCartFixture(t *testing.T, db *sql.DB) (string, *models.CartItem) {
+	userID := uuid.New().String()
+	productID := uuid.New().String()
+
+	if _, err := db.Exec("INSERT INTO users (id, email, password) VALUES ($1, $2, 'x')", userID, userID+"@example.com"); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO products (id, name, slug, price, stock) VALUES ($1, 'Widget', $2, 10, 100)", productID, "widget-"+productID); err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM users WHERE id = $1", userID)
+		db.Exec("DELETE FROM products WHERE id = $1", productID)
+	})
+
+	item := &models.CartItem{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		ProductID: productID,
+		Quantity:  models.QuantityFromInt(1),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := repositories.NewCartRepository(db).Create(item); err != nil {
+		t.Fatalf("Failed to create cart item: %v", err)
+	}
+	return userID, item
+}
+
+func TestCartConcurrentVersionedUpdates(t *testing.T) {
+	db := openTestDatabase(t)
+	repo := repositories.NewCartRepository(db)
+	userID, item := createCartFixture(t, db)
+
+	version, _, err := repo.GetVersion(userID)
+	if err != nil {
+		t.Fatalf("GetVersion returned error: %v", err)
+	}
+
+	const writers = 8
+	var wg sync.WaitGroup
+	results := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(quantity models.Quantity) {
+			defer wg.Done()
+			_, err := repo.UpdateQuantityVersioned(userID, item.ID, quantity, version)
+			results <- err
+		}(models.QuantityFromInt(i + 2))
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded, conflicts := 0, 0
+	for err := range results {
+		switch {
+		case err == nil:
+			succeeded++
+		case err.Error() == "cart version conflict":
+			conflicts++
+		default:
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if succeeded != 1 || conflicts != writers-1 {
+		t.Errorf("Expected 1 success and %d conflicts, got %d and %d", writers-1, succeeded, conflicts)
+	}
+
+	current, _, err := repo.GetVersion(userID)
+	if err != nil {
+		t.Fatalf("GetVersion returned error: %v", err)
+	}
+	if current != version+1 {
+		t.Errorf("Expected version %d, got %d", version+1, current)
+	}
+	if _, err := repo.UpdateQuantityVersioned(userID, item.ID, models.QuantityFromInt(1), version); err == nil || err.Error() != "cart version conflict" {
+		t.Errorf("Stale write should conflict, got %v", err)
+	}
+}
+
+func TestCartUnversionedWritesBumpVersion(t *testing.T) {
+	db := openTestDatabase(t)
+	repo := repositories.NewCartRepository(db)
+	userID, item := createCartFixture(t, db)
+
+	before, _, err := repo.GetVersion(userID)
+	if err != nil {
+		t.Fatalf("GetVersion returned error: %v", err)
+	}
+	if err := repo.Update(item.ID, map[string]interface{}{"quantity": 3}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if err := repo.DeleteByUserID(userID); err != nil {
+		t.Fatalf("DeleteByUserID returned error: %v", err)
+	}
+
+	after, _, err := repo.GetVersion(userID)
+	if err != nil {
+		t.Fatalf("GetVersion returned error: %v", err)
+	}
+	if after != before+2 {
+		t.Errorf("Expected version %d, got %d", before+2, after)
+	}
+}
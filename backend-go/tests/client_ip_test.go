@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func clientIPRouter(t *testing.T, cfg middleware.ClientIPConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := middleware.ConfigureClientIP(r, cfg); err != nil {
+		t.Fatalf("ConfigureClientIP returned error: %v", err)
+	}
+	r.GET("/ip", func(c *gin.Context) {
+		c.String(http.StatusOK, c.ClientIP())
+	})
+	return r
+}
+
+func clientIPFrom(r *gin.Engine, remoteAddr string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+	req.RemoteAddr = remoteAddr
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestClientIPTrustedProxies(t *testing.T) {
+	r := clientIPRouter(t, middleware.ClientIPConfig{TrustedProxies: []string{"10.0.0.0/8"}})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{"no proxy", "203.0.113.7:5123", nil, "203.0.113.7"},
+		{"forged header from an untrusted peer", "203.0.113.7:5123", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.7"},
+		{"forged real ip from an untrusted peer", "203.0.113.7:5123", map[string]string{"X-Real-IP": "198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:443", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+		// The client's own X-Forwarded-For is kept by the proxy, which appends
+		// the address it saw; only that last untrusted hop counts.
+		{"forged entry through a trusted proxy", "10.1.2.3:443", map[string]string{"X-Forwarded-For": "192.0.2.99, 198.51.100.1"}, "198.51.100.1"},
+		{"chained trusted proxies", "10.1.2.3:443", map[string]string{"X-Forwarded-For": "198.51.100.1, 10.9.9.9"}, "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clientIPFrom(r, tt.remoteAddr, tt.headers).Body.String(); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestClientIPTrustsNoProxyByDefault(t *testing.T) {
+	r := clientIPRouter(t, middleware.ClientIPConfig{})
+	if got := clientIPFrom(r, "10.1.2.3:443", map[string]string{"X-Forwarded-For": "198.51.100.1"}).Body.String(); got != "10.1.2.3" {
+		t.Errorf("Expected the peer address with no trusted proxies, got %s", got)
+	}
+}
+
+func TestClientIPTrustedPlatform(t *testing.T) {
+	r := clientIPRouter(t, middleware.ClientIPConfig{TrustedPlatform: "cloudflare"})
+	if got := clientIPFrom(r, "203.0.113.7:5123", map[string]string{"CF-Connecting-IP": "198.51.100.1"}).Body.String(); got != "198.51.100.1" {
+		t.Errorf("Expected the Cloudflare client address, got %s", got)
+	}
+}
+
+func TestClientIPRejectsInvalidProxy(t *testing.T) {
+	if err := middleware.ConfigureClientIP(gin.New(), middleware.ClientIPConfig{TrustedProxies: []string{"not-a-network"}}); err == nil {
+		t.Error("Expected an error for an invalid trusted proxy")
+	}
+}
+
+func TestRateLimitIgnoresForgedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := middleware.ConfigureClientIP(r, middleware.ClientIPConfig{TrustedProxies: []string{"10.0.0.0/8"}}); err != nil {
+		t.Fatalf("ConfigureClientIP returned error: %v", err)
+	}
+	r.Use(middleware.RateLimitMiddleware(1, time.Minute))
+	r.GET("/ip", func(c *gin.Context) {
+		c.String(http.StatusOK, c.ClientIP())
+	})
+
+	if w := clientIPFrom(r, "203.0.113.7:5123", map[string]string{"X-Forwarded-For": "198.51.100.1"}); w.Code != http.StatusOK {
+		t.Fatalf("Expected the first request to pass, got %d", w.Code)
+	}
+	// A new forged address does not buy a fresh allowance.
+	if w := clientIPFrom(r, "203.0.113.7:5123", map[string]string{"X-Forwarded-For": "198.51.100.2"}); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 for the same peer with a different forged header, got %d", w.Code)
+	}
+	// Clients behind the trusted proxy are limited separately.
+	if w := clientIPFrom(r, "10.1.2.3:443", map[string]string{"X-Forwarded-For": "198.51.100.3"}); w.Code != http.StatusOK {
+		t.Errorf("Expected a different client behind the proxy to pass, got %d", w.Code)
+	}
+}
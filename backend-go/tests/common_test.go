@@ -1,6 +1,8 @@
 package tests
 
 import (
+	"encoding/json"
+	"strconv"
 	"testing"
 	"time"
 
@@ -175,3 +177,82 @@ func TestFormatDuration(t *testing.T) {
 		t.Errorf("FormatDuration returned %s, expected 2h30m45s", result)
 	}
 }
+
+func TestNegotiateLocale(t *testing.T) {
+	supported := []string{"en", "de", "pt-br"}
+
+	tests := []struct {
+		name           string
+		requested      string
+		acceptLanguage string
+		expected       string
+	}{
+		{"explicit lang wins", "de", "pt-BR", "de"},
+		{"highest quality first", "", "en;q=0.5, pt_BR;q=0.9", "pt-br"},
+		{"regional falls back to base", "", "de-AT", "de"},
+		{"unsupported lang uses header", "fr", "de", "de"},
+		{"no match uses default", "fr", "it, *;q=0.1", "en"},
+		{"empty uses default", "", "", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := utils.NegotiateLocale(tt.requested, tt.acceptLanguage, supported, "en")
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFieldSelection(t *testing.T) {
+	allowed := []string{"id", "name", "price"}
+
+	fields, err := utils.ParseFieldSelection(" id,price,id ", allowed)
+	if err != nil {
+		t.Fatalf("ParseFieldSelection returned error: %v", err)
+	}
+	if len(fields) != 2 || fields[0] != "id" || fields[1] != "price" {
+		t.Errorf("Expected [id price], got %v", fields)
+	}
+	if _, err := utils.ParseFieldSelection("id,secret", allowed); err == nil {
+		t.Error("Expected unknown field to be rejected")
+	}
+	if fields, _ := utils.ParseFieldSelection("", allowed); fields != nil {
+		t.Errorf("Expected nil for empty selection, got %v", fields)
+	}
+
+	product := struct {
+		ID    string  `json:"id"`
+		Name  string  `json:"name"`
+		Price float64 `json:"price"`
+	}{"p1", "Widget", 9.5}
+	projected, err := utils.ProjectFields(product, []string{"id", "price"})
+	if err != nil {
+		t.Fatalf("ProjectFields returned error: %v", err)
+	}
+	data, _ := json.Marshal(projected)
+	if string(data) != `{"id":"p1","price":9.5}` {
+		t.Errorf("Unexpected projection: %s", data)
+	}
+}
+
+func TestPathSignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	expires := now.Add(time.Hour)
+	signature := utils.SignPath("secret", "/uploads/a.png", expires)
+	unix := strconv.FormatInt(expires.Unix(), 10)
+
+	if err := utils.VerifyPathSignature("secret", "/uploads/a.png", unix, signature, now); err != nil {
+		t.Errorf("Expected valid signature, got %v", err)
+	}
+	if err := utils.VerifyPathSignature("secret", "/uploads/b.png", unix, signature, now); err != utils.ErrSignatureInvalid {
+		t.Errorf("Expected other path to be rejected, got %v", err)
+	}
+	if err := utils.VerifyPathSignature("secret", "/uploads/a.png", strconv.FormatInt(expires.Unix()+3600, 10), signature, now); err != utils.ErrSignatureInvalid {
+		t.Errorf("Expected extended expiry to be rejected, got %v", err)
+	}
+	if err := utils.VerifyPathSignature("secret", "/uploads/a.png", unix, signature, expires.Add(time.Second)); err != utils.ErrSignatureExpired {
+		t.Errorf("Expected expired signature, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/config"
+)
+
+func writeConfigFile(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigServerTimeouts(t *testing.T) {
+	path := writeConfigFile(t, `{"server": {"read_timeout": 15, "write_timeout": "45s", "idle_timeout": "2m"}}`)
+
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+
+	if cfg.Server.ReadTimeout != 15*time.Second {
+		t.Errorf("Expected read timeout 15s, got %s", cfg.Server.ReadTimeout)
+	}
+	if cfg.Server.WriteTimeout != 45*time.Second {
+		t.Errorf("Expected write timeout 45s, got %s", cfg.Server.WriteTimeout)
+	}
+	if cfg.Server.IdleTimeout != 2*time.Minute {
+		t.Errorf("Expected idle timeout 2m, got %s", cfg.Server.IdleTimeout)
+	}
+	if cfg.Server.ReadHeaderTimeout <= 0 {
+		t.Error("Read header timeout should default to a positive value")
+	}
+	if cfg.Server.MaxHeaderBytes <= 0 {
+		t.Error("Max header bytes should default to a positive value")
+	}
+}
+
+func TestLoadConfigRejectsNegativeTimeout(t *testing.T) {
+	path := writeConfigFile(t, `{"server": {"read_timeout": "-5s"}}`)
+
+	if _, err := config.LoadConfig(path); err == nil {
+		t.Error("LoadConfig should reject a negative read timeout")
+	}
+}
+
+func TestLoadConfigOrderThresholds(t *testing.T) {
+	path := writeConfigFile(t, `{"orders": {"min_order_amount": 20, "free_shipping_threshold": 50}}`)
+	if _, err := config.LoadConfig(path); err != nil {
+		t.Errorf("LoadConfig returned error: %v", err)
+	}
+
+	path = writeConfigFile(t, `{"orders": {"min_order_amount": 80, "free_shipping_threshold": 50}}`)
+	if _, err := config.LoadConfig(path); err == nil {
+		t.Error("LoadConfig should reject a minimum order amount above the free-shipping threshold")
+	}
+}
+
+func TestLoadConfigWebSocketKeepalive(t *testing.T) {
+	t.Setenv("WS_PING_INTERVAL", "20s")
+	t.Setenv("WS_PONG_WAIT", "45s")
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.WebSocket.PingInterval != 20*time.Second || cfg.WebSocket.PongWait != 45*time.Second {
+		t.Errorf("Expected 20s/45s keepalive, got %s/%s", cfg.WebSocket.PingInterval, cfg.WebSocket.PongWait)
+	}
+	if cfg.WebSocket.WriteWait <= 0 {
+		t.Error("Write wait should default to a positive value")
+	}
+
+	t.Setenv("WS_PING_INTERVAL", "90s")
+	if _, err := config.LoadConfig(""); err == nil {
+		t.Error("LoadConfig should reject a pong wait that is not greater than the ping interval")
+	}
+}
@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/services"
+	"ecommerce-backend/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+func TestSweepExpiredExportsDeletesFiles(t *testing.T) {
+	db := openTestDatabase(t)
+	exportRepo := repositories.NewDataExportRepository(db)
+	service := services.NewAccountService(repositories.NewUserRepository(db), nil, nil, nil, nil, exportRepo, nil, nil, nil, nil, t.TempDir(), "")
+
+	userID := uuid.New().String()
+	if _, err := db.Exec("INSERT INTO users (id, email, password) VALUES ($1, $2, 'x')", userID, userID+"@example.com"); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	t.Cleanup(func() { db.Exec("DELETE FROM users WHERE id = $1", userID) })
+
+	dir := t.TempDir()
+	create := func(expiresAt time.Time) (string, string) {
+		token := utils.GenerateRandomHex(64)
+		path := filepath.Join(dir, token+".json")
+		if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+			t.Fatalf("Failed to write export: %v", err)
+		}
+		_, err := db.Exec(`INSERT INTO data_exports (user_id, token, status, file_path, completed_at, expires_at)
+			VALUES ($1, $2, $3, $4, NOW(), $5)`, userID, token, models.DataExportStatusReady, path, expiresAt)
+		if err != nil {
+			t.Fatalf("Failed to create export: %v", err)
+		}
+		return token, path
+	}
+	expiredToken, expiredPath := create(time.Now().Add(-time.Hour))
+	liveToken, livePath := create(time.Now().Add(time.Hour))
+
+	if err := service.SweepExpiredExports(); err != nil {
+		t.Fatalf("SweepExpiredExports returned error: %v", err)
+	}
+	if _, err := os.Stat(expiredPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the expired export file to be deleted, got %v", err)
+	}
+	if _, err := service.GetExportFile(expiredToken); err == nil || err.Error() != "export expired" {
+		t.Errorf("Expected export expired, got %v", err)
+	}
+	if _, err := os.Stat(livePath); err != nil {
+		t.Errorf("Expected the live export file to be kept, got %v", err)
+	}
+	if path, err := service.GetExportFile(liveToken); err != nil || path != livePath {
+		t.Errorf("Expected the live export to download, got %q (%v)", path, err)
+	}
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/database"
+	"ecommerce-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadPolicyRetriesTransientErrors(t *testing.T) {
+	policy := &database.ReadPolicy{Retries: 2, Backoff: time.Millisecond, Breaker: database.NewCircuitBreaker(5, time.Minute)}
+
+	calls := 0
+	err := policy.Read(func() error {
+		calls++
+		if calls < 3 {
+			return driver.ErrBadConn
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Expected success after 3 calls, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	err = policy.Read(func() error {
+		calls++
+		return sql.ErrNoRows
+	})
+	if err != sql.ErrNoRows || calls != 1 {
+		t.Errorf("Non-transient errors should not be retried, got %v after %d calls", err, calls)
+	}
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	breaker := database.NewCircuitBreaker(2, 50*time.Millisecond)
+	policy := &database.ReadPolicy{Retries: 0, Backoff: time.Millisecond, Breaker: breaker}
+	failing := func() error { return driver.ErrBadConn }
+
+	policy.Read(failing)
+	policy.Read(failing)
+	if breaker.State() != database.BreakerOpen {
+		t.Fatalf("Expected open breaker, got %s", breaker.State())
+	}
+
+	called := false
+	if err := policy.Read(func() error { called = true; return nil }); err != database.ErrUnavailable || called {
+		t.Errorf("Open breaker should fail fast, got %v (called: %v)", err, called)
+	}
+	if breaker.RetryAfter() < time.Second {
+		t.Errorf("Retry-After should round up to at least a second, got %s", breaker.RetryAfter())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if breaker.State() != database.BreakerHalfOpen {
+		t.Fatalf("Expected half-open breaker after cooldown, got %s", breaker.State())
+	}
+	if err := policy.Read(func() error { return nil }); err != nil {
+		t.Errorf("Half-open breaker should let a read through, got %v", err)
+	}
+	if breaker.State() != database.BreakerClosed {
+		t.Errorf("Expected closed breaker after a successful read, got %s", breaker.State())
+	}
+}
+
+func TestDatabaseCircuitBreakerMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	breaker := database.NewCircuitBreaker(1, time.Minute)
+	breaker.Failure()
+
+	r := gin.New()
+	r.Use(middleware.DatabaseCircuitBreaker(breaker, []string{"/api/health"}))
+	r.GET("/api/products", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/api/health/ready", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/products", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 503 with Retry-After, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/health/ready", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Health checks should bypass the breaker, got %d", w.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ecommerce-backend/internal/middleware"
+	"ecommerce-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDebugBodyLoggingRedactsSensitiveFields(t *testing.T) {
+	var logs bytes.Buffer
+	previous := utils.GetLogger()
+	utils.SetDefaultLogger(utils.NewLogger(utils.DEBUG, &logs))
+	defer utils.SetDefaultLogger(previous)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.DebugBodyLogging([]string{"/api/payments"}, 1024))
+	r.POST("/api/payments/intent", func(c *gin.Context) {
+		var body map[string]interface{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"amount": body["amount"], "client_secret": "pi_secret_value"})
+	})
+	r.POST("/api/auth/login", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"token": "jwt_value"})
+	})
+
+	body := `{"amount": 42, "password": "hunter2", "card": {"number": "4242424242424242"}, "items": [{"token": "tok_visa"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/payments/intent", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Handler should still receive the request body, got status %d", w.Code)
+	}
+	output := logs.String()
+	for _, secret := range []string{"hunter2", "4242424242424242", "tok_visa", "pi_secret_value"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("Debug log should not contain %q: %s", secret, output)
+		}
+	}
+	if !strings.Contains(output, "[REDACTED]") || !strings.Contains(output, `"amount":42`) {
+		t.Errorf("Debug log should contain redacted body, got: %s", output)
+	}
+
+	logs.Reset()
+	req = httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"password": "hunter2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if logs.Len() != 0 {
+		t.Errorf("Routes outside the allowlist should not be logged, got: %s", logs.String())
+	}
+}
@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/middleware"
+	"ecommerce-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestImpersonationTokenBlocksOwnerOnlyActions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	utils.InitJWT("impersonation-test-secret", time.Hour, time.Hour, "eshop", "eshop")
+
+	r := gin.New()
+	r.DELETE("/account", middleware.AuthMiddleware(), middleware.NoImpersonationMiddleware(), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("user_id"))
+	})
+	call := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/account", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	own, err := utils.GenerateJWT("user-1", "user@example.com", "user", "")
+	if err != nil {
+		t.Fatalf("GenerateJWT returned error: %v", err)
+	}
+	if w := call(own); w.Code != http.StatusOK {
+		t.Fatalf("Expected owner to be allowed, got %d", w.Code)
+	}
+
+	impersonated, expiresAt, err := utils.GenerateImpersonationJWT("user-1", "user@example.com", "user", "admin-1", "", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateImpersonationJWT returned error: %v", err)
+	}
+	if until := time.Until(expiresAt); until > 15*time.Minute || until < 14*time.Minute {
+		t.Errorf("Expected a 15 minute token, expires in %s", until)
+	}
+	w := call(impersonated)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected impersonation to be refused, got %d", w.Code)
+	}
+	if got := w.Header().Get("X-Impersonated-By"); got != "admin-1" {
+		t.Errorf("Expected X-Impersonated-By admin-1, got %q", got)
+	}
+
+	claims, err := utils.ValidateJWT(impersonated)
+	if err != nil {
+		t.Fatalf("ValidateJWT returned error: %v", err)
+	}
+	if !claims.IsImpersonation() || claims.ImpersonatorID != "admin-1" || claims.UserID != "user-1" {
+		t.Errorf("Unexpected impersonation claims: %+v", claims)
+	}
+	if _, err := utils.RefreshJWT(impersonated); err == nil {
+		t.Error("Expected impersonation tokens to be non-refreshable")
+	}
+}
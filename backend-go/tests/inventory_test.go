@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"testing"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+func TestQuantityCeil(t *testing.T) {
+	tests := []struct {
+		quantity models.Quantity
+		expected int
+	}{
+		{models.QuantityFromInt(2), 2},
+		{1500, 2},
+		{1, 1},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.quantity.Ceil(); got != tt.expected {
+			t.Errorf("Ceil(%s) = %d, expected %d", tt.quantity, got, tt.expected)
+		}
+	}
+}
+
+func TestInventoryLedger(t *testing.T) {
+	db := openTestDatabase(t)
+	repo := repositories.NewInventoryRepository(db)
+
+	productID := uuid.New().String()
+	if _, err := db.Exec("INSERT INTO products (id, name, slug, price, stock) VALUES ($1, 'Ledger widget', $2, 10, 0)", productID, "ledger-"+productID); err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+	t.Cleanup(func() { db.Exec("DELETE FROM products WHERE id = $1", productID) })
+
+	if _, err := repo.SetStock(productID, 20, models.InventoryReasonRestock, nil); err != nil {
+		t.Fatalf("SetStock returned error: %v", err)
+	}
+	if movement, err := repo.SetStock(productID, 20, models.InventoryReasonAdjustment, nil); err != nil || movement != nil {
+		t.Errorf("Expected no movement when stock is unchanged, got %+v, %v", movement, err)
+	}
+	orderID := uuid.New().String()
+	sale, err := repo.Apply(productID, -3, models.InventoryReasonSale, &orderID)
+	if err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	if sale.StockAfter != 17 {
+		t.Errorf("Expected 17 left after the sale, got %d", sale.StockAfter)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := repo.Revert(orderID, models.InventoryReasonSale, models.InventoryReasonCancellation); err != nil {
+			t.Fatalf("Revert returned error: %v", err)
+		}
+	}
+	movements, total, err := repo.ListByProduct(productID, "", 10, 0)
+	if err != nil {
+		t.Fatalf("ListByProduct returned error: %v", err)
+	}
+	if total != 3 || movements[0].Reason != models.InventoryReasonCancellation || movements[0].StockAfter != 20 {
+		t.Errorf("Expected restock, sale and one cancellation back to 20, got %d movements: %+v", total, movements)
+	}
+
+	// A write that bypasses the ledger shows up as a mismatch.
+	if _, err := db.Exec("UPDATE products SET stock = stock - 5 WHERE id = $1", productID); err != nil {
+		t.Fatalf("Failed to update stock: %v", err)
+	}
+	report, err := repo.Reconcile("", true)
+	if err != nil {
+		t.Fatalf("Reconcile returned error: %v", err)
+	}
+	found := false
+	for _, product := range report {
+		if product.ProductID == productID {
+			found = true
+			if product.Stock != 15 || product.LedgerStock != 20 || product.Difference != -5 {
+				t.Errorf("Expected stock 15 against a ledger of 20, got %+v", product)
+			}
+		}
+	}
+	if !found {
+		t.Error("Expected the product in the mismatch report")
+	}
+}
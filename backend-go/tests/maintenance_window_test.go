@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/services"
+)
+
+type recordingSwitch struct {
+	enabled bool
+	message string
+}
+
+func (s *recordingSwitch) Set(enabled bool, message string) {
+	s.enabled = enabled
+	if message != "" {
+		s.message = message
+	}
+}
+
+func TestMaintenanceWindowLifecycle(t *testing.T) {
+	db := openTestDatabase(t)
+	repo := repositories.NewMaintenanceRepository(db)
+	mode := &recordingSwitch{}
+	service := services.NewMaintenanceService(repo, mode, nil, []time.Duration{time.Hour, 15 * time.Minute, time.Minute})
+
+	// Far enough out not to collide with windows left by other runs.
+	base := time.Now().Add(24 * 365 * time.Hour).Truncate(time.Second)
+	upcoming, err := service.Schedule("", models.MaintenanceWindowRequest{
+		StartsAt: base.Add(10 * time.Minute),
+		EndsAt:   base.Add(time.Hour),
+		Message:  "Database upgrade",
+	})
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	t.Cleanup(func() { db.Exec("DELETE FROM maintenance_windows WHERE id = $1", upcoming.ID) })
+	if upcoming.Status != models.MaintenanceWindowScheduled || mode.enabled {
+		t.Fatalf("Expected a scheduled window with maintenance off, got %s and %v", upcoming.Status, mode.enabled)
+	}
+
+	if _, err := service.Schedule("", models.MaintenanceWindowRequest{
+		StartsAt: base.Add(30 * time.Minute),
+		EndsAt:   base.Add(2 * time.Hour),
+		Message:  "Overlapping",
+	}); err == nil || err.Error() != "maintenance window overlaps another window" {
+		t.Errorf("Expected an overlap error, got %v", err)
+	}
+	if _, err := service.Schedule("", models.MaintenanceWindowRequest{
+		StartsAt: base,
+		EndsAt:   base.Add(-time.Minute),
+		Message:  "Backwards",
+	}); err == nil || err.Error() != "maintenance window must end after it starts" {
+		t.Errorf("Expected an ordering error, got %v", err)
+	}
+
+	cancelled, err := service.Cancel(upcoming.ID)
+	if err != nil {
+		t.Fatalf("Cancel returned error: %v", err)
+	}
+	if cancelled.Status != models.MaintenanceWindowCancelled {
+		t.Errorf("Expected cancelled, got %s", cancelled.Status)
+	}
+	if _, err := service.Cancel(upcoming.ID); err == nil || err.Error() != "maintenance window is already cancelled" {
+		t.Errorf("Expected a second cancel to fail, got %v", err)
+	}
+
+	current, err := service.Schedule("", models.MaintenanceWindowRequest{
+		StartsAt: time.Now().Add(-time.Minute),
+		EndsAt:   time.Now().Add(time.Hour),
+		Message:  "Emergency fix",
+	})
+	if err != nil {
+		t.Fatalf("Schedule returned error: %v", err)
+	}
+	t.Cleanup(func() { db.Exec("DELETE FROM maintenance_windows WHERE id = $1", current.ID) })
+	if current.Status != models.MaintenanceWindowActive || !mode.enabled || mode.message != "Emergency fix" {
+		t.Fatalf("Expected the window to start immediately, got %s, enabled=%v, message=%q", current.Status, mode.enabled, mode.message)
+	}
+
+	if _, err := service.Cancel(current.ID); err != nil {
+		t.Fatalf("Cancel returned error: %v", err)
+	}
+	if mode.enabled {
+		t.Error("Expected cancelling an active window to switch maintenance mode off")
+	}
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"ecommerce-backend/internal/models"
+)
+
+func TestMoneyJSONRoundTrip(t *testing.T) {
+	var product struct {
+		Price models.Money `json:"price"`
+	}
+	if err := json.Unmarshal([]byte(`{"price": 0.29}`), &product); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+	if product.Price != 29 {
+		t.Errorf("Expected 29 cents, got %d", product.Price)
+	}
+
+	data, _ := json.Marshal(product)
+	if string(data) != `{"price":0.29}` {
+		t.Errorf("Unexpected JSON: %s", data)
+	}
+	if got := models.Money(-1005).String(); got != "-10.05" {
+		t.Errorf("Expected -10.05, got %s", got)
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	// 0.1 + 0.2 style drift must not leak into totals.
+	total := models.MoneyFromFloat(0.1) + models.MoneyFromFloat(0.2)
+	if total != models.MoneyFromFloat(0.3) {
+		t.Errorf("Expected 30 cents, got %d", total)
+	}
+	if tax := models.Money(1999).Mul(3).MulRate(0.1); tax != 600 {
+		t.Errorf("Expected tax of 600 cents, got %d", tax)
+	}
+}
+
+func TestMoneyFormat(t *testing.T) {
+	tests := []struct {
+		amount   models.Money
+		currency string
+		locale   string
+		expected string
+	}{
+		{123450, "USD", "en", "$1,234.50"},
+		{123450, "EUR", "de-AT", "1.234,50\u00a0€"},
+		{-99, "GBP", "en-GB", "-£0.99"},
+		{100000000, "BRL", "pt-BR", "R$\u00a01.000.000,00"},
+		{500, "CHF", "en", "CHF\u00a05.00"},
+		{500, "XYZ", "zz", "XYZ\u00a05.00"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.amount.Format(tt.currency, tt.locale); got != tt.expected {
+			t.Errorf("Format(%d, %s, %s) = %q, expected %q", tt.amount, tt.currency, tt.locale, got, tt.expected)
+		}
+	}
+}
+
+func TestMoneyTaxRounding(t *testing.T) {
+	rate := models.TaxRateFromFloat(0.05)
+	tests := []struct {
+		amount   models.Money
+		rounding string
+		expected models.Money
+	}{
+		{1010, models.RoundHalfUp, 51},
+		{1010, models.RoundHalfEven, 50},
+		{1030, models.RoundHalfEven, 52},
+		{-1010, models.RoundHalfUp, -51},
+		{-1010, models.RoundHalfEven, -50},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.TaxOn(rate, tt.rounding); got != tt.expected {
+			t.Errorf("TaxOn(%d, %s) = %d, expected %d", tt.amount, tt.rounding, got, tt.expected)
+		}
+	}
+}
+
+// Tax extracted from a gross price must leave a net price that, taxed
+// again, comes back to the gross price within a cent.
+func TestMoneyTaxInReconciles(t *testing.T) {
+	for _, rateValue := range []float64{0.07, 0.0825, 0.19, 0.2} {
+		rate := models.TaxRateFromFloat(rateValue)
+		for gross := models.Money(0); gross <= 20000; gross++ {
+			tax := gross.TaxIn(rate, models.RoundHalfUp)
+			net := gross - tax
+			if diff := net + net.TaxOn(rate, models.RoundHalfUp) - gross; diff < -1 || diff > 1 {
+				t.Fatalf("rate %v: gross %d splits into %d + %d, which re-taxes %d off", rateValue, gross, net, tax, diff)
+			}
+		}
+	}
+	if tax := models.Money(1190).TaxIn(models.TaxRateFromFloat(0.19), models.RoundHalfUp); tax != 190 {
+		t.Errorf("Expected 190 cents of tax in 11.90 at 19%%, got %d", tax)
+	}
+}
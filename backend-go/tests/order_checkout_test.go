@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/services"
+
+	"github.com/google/uuid"
+)
+
+func checkoutOrder(userID string) *models.Order {
+	return &models.Order{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Status:    models.OrderStatusPending,
+		Total:     models.MoneyFromFloat(10),
+		Subtotal:  models.MoneyFromFloat(10),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func TestCreateFromCartKeepsCartWhenOrderFails(t *testing.T) {
+	db := openTestDatabase(t)
+	orders := repositories.NewOrderRepository(db)
+	carts := repositories.NewCartRepository(db)
+	userID, item := createCartFixture(t, db)
+	version, _, err := carts.GetVersion(userID)
+	if err != nil {
+		t.Fatalf("GetVersion returned error: %v", err)
+	}
+
+	// The second item references a product that does not exist, so the
+	// insert fails after the order row has been written.
+	order := checkoutOrder(userID)
+	items := []models.OrderItem{
+		{ID: uuid.New().String(), ProductID: item.ProductID, ProductName: "Widget", Quantity: item.Quantity, Price: models.MoneyFromFloat(10)},
+		{ID: uuid.New().String(), ProductID: uuid.New().String(), ProductName: "Missing", Quantity: item.Quantity, Price: models.MoneyFromFloat(10)},
+	}
+	if err := orders.CreateFromCart(order, items, version); err == nil {
+		t.Fatal("Expected CreateFromCart to fail")
+	}
+	if _, err := orders.GetOrderByID(order.ID); err == nil {
+		t.Error("Expected no order to be stored")
+	}
+	if cartItems, err := carts.GetUserCartItems(userID); err != nil || len(cartItems) != 1 {
+		t.Errorf("Expected the cart to keep its item, got %d (%v)", len(cartItems), err)
+	}
+
+	order = checkoutOrder(userID)
+	if err := orders.CreateFromCart(order, items[:1], version); err != nil {
+		t.Fatalf("CreateFromCart returned error: %v", err)
+	}
+	if cartItems, err := carts.GetUserCartItems(userID); err != nil || len(cartItems) != 0 {
+		t.Errorf("Expected the cart to be cleared, got %d (%v)", len(cartItems), err)
+	}
+}
+
+func TestCreateFromCartRejectsChangedCart(t *testing.T) {
+	db := openTestDatabase(t)
+	orders := repositories.NewOrderRepository(db)
+	carts := repositories.NewCartRepository(db)
+	userID, item := createCartFixture(t, db)
+	version, _, err := carts.GetVersion(userID)
+	if err != nil {
+		t.Fatalf("GetVersion returned error: %v", err)
+	}
+	if _, err := carts.UpdateQuantityVersioned(userID, item.ID, models.QuantityFromInt(2), version); err != nil {
+		t.Fatalf("UpdateQuantityVersioned returned error: %v", err)
+	}
+
+	order := checkoutOrder(userID)
+	items := []models.OrderItem{{ID: uuid.New().String(), ProductID: item.ProductID, ProductName: "Widget", Quantity: item.Quantity, Price: models.MoneyFromFloat(10)}}
+	if err := orders.CreateFromCart(order, items, version); err == nil || err.Error() != "cart changed during checkout" {
+		t.Fatalf("Expected cart changed during checkout, got %v", err)
+	}
+	if _, err := orders.GetOrderByID(order.ID); err == nil {
+		t.Error("Expected no order to be stored")
+	}
+}
+
+func TestCreateFromCartTakesStock(t *testing.T) {
+	db := openTestDatabase(t)
+	orders := repositories.NewOrderRepository(db)
+	carts := repositories.NewCartRepository(db)
+	productRepo := repositories.NewProductRepository(db)
+	userID, item := createCartFixture(t, db)
+	version, _, err := carts.GetVersion(userID)
+	if err != nil {
+		t.Fatalf("GetVersion returned error: %v", err)
+	}
+	items := []models.OrderItem{{ID: uuid.New().String(), ProductID: item.ProductID, ProductName: "Widget", Quantity: models.QuantityFromInt(2), Price: models.MoneyFromFloat(10)}}
+
+	if _, err := db.Exec("UPDATE products SET stock = 1 WHERE id = $1", item.ProductID); err != nil {
+		t.Fatalf("Failed to set stock: %v", err)
+	}
+	order := checkoutOrder(userID)
+	if err := orders.CreateFromCart(order, items, version); err == nil || err.Error() != "insufficient stock" {
+		t.Fatalf("Expected insufficient stock, got %v", err)
+	}
+	if _, err := orders.GetOrderByID(order.ID); err == nil {
+		t.Error("Expected no order to be stored")
+	}
+
+	if _, err := db.Exec("UPDATE products SET stock = 2 WHERE id = $1", item.ProductID); err != nil {
+		t.Fatalf("Failed to set stock: %v", err)
+	}
+	order = checkoutOrder(userID)
+	if err := orders.CreateFromCart(order, items, version); err != nil {
+		t.Fatalf("CreateFromCart returned error: %v", err)
+	}
+	product, err := productRepo.GetByID("", item.ProductID)
+	if err != nil {
+		t.Fatalf("GetByID returned error: %v", err)
+	}
+	if product.Stock != 0 || product.InStock {
+		t.Errorf("Expected the order to take the last 2 units, stock is %d (in stock %v)", product.Stock, product.InStock)
+	}
+	var delta int
+	if err := db.QueryRow("SELECT COALESCE(SUM(delta), 0) FROM inventory_movements WHERE reference = $1 AND reason = $2", order.ID, models.InventoryReasonSale).Scan(&delta); err != nil {
+		t.Fatalf("Failed to read movements: %v", err)
+	}
+	if delta != -2 {
+		t.Errorf("Expected a sale of 2 in the ledger, got %d", delta)
+	}
+}
+
+func TestCancelUnpaidOrders(t *testing.T) {
+	db := openTestDatabase(t)
+	orders := repositories.NewOrderRepository(db)
+	productRepo := repositories.NewProductRepository(db)
+	inventoryRepo := repositories.NewInventoryRepository(db)
+	service := services.NewOrderService(orders, repositories.NewCartRepository(db), productRepo, repositories.NewUserRepository(db),
+		repositories.NewShipmentRepository(db), inventoryRepo, repositories.NewBundleRepository(db), nil, nil, nil, nil, nil, 0, 0)
+	userID, item := createCartFixture(t, db)
+
+	placed := func(age time.Duration) *models.Order {
+		order := checkoutOrder(userID)
+		order.CreatedAt = time.Now().Add(-age)
+		if err := orders.CreateOrder(order); err != nil {
+			t.Fatalf("CreateOrder returned error: %v", err)
+		}
+		return order
+	}
+	unpaid, paying, recent := placed(2*time.Hour), placed(2*time.Hour), placed(time.Minute)
+	if _, err := inventoryRepo.Apply(item.ProductID, -3, models.InventoryReasonSale, &unpaid.ID); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	err := repositories.NewPaymentRepository(db).CreatePayment(&models.Payment{
+		ID: uuid.New().String(), UserID: userID, OrderID: &paying.ID, Amount: paying.Total, Currency: "usd",
+		Status: models.PaymentStatusPending, Provider: models.PaymentProviderMock, PaymentIntentID: "pi_" + paying.ID,
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("CreatePayment returned error: %v", err)
+	}
+
+	if err := service.CancelUnpaidOrders(time.Hour); err != nil {
+		t.Fatalf("CancelUnpaidOrders returned error: %v", err)
+	}
+	for order, expected := range map[*models.Order]models.OrderStatus{
+		unpaid: models.OrderStatusCancelled,
+		paying: models.OrderStatusPending,
+		recent: models.OrderStatusPending,
+	} {
+		stored, err := orders.GetOrderByID(order.ID)
+		if err != nil {
+			t.Fatalf("GetOrderByID returned error: %v", err)
+		}
+		if stored.Status != expected {
+			t.Errorf("Order placed %s ago: expected %s, got %s", time.Since(order.CreatedAt).Round(time.Minute), expected, stored.Status)
+		}
+	}
+	product, err := productRepo.GetByID("", item.ProductID)
+	if err != nil {
+		t.Fatalf("GetByID returned error: %v", err)
+	}
+	if product.Stock != 100 {
+		t.Errorf("Expected the cancelled order's stock to be released, got %d", product.Stock)
+	}
+}
@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"testing"
+
+	"ecommerce-backend/internal/config"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/services"
+)
+
+func newTestOrderPricing(rate float64, inclusive bool) *services.OrderPricing {
+	orders := config.OrdersConfig{
+		OriginCountry:    "US",
+		ShippingFlatRate: 10,
+		TaxRate:          rate,
+		PricesIncludeTax: inclusive,
+		TaxRounding:      models.RoundHalfUp,
+	}
+	return services.NewOrderPricing(orders, services.NewShippingEstimator(orders))
+}
+
+func TestOrderPricingTaxModes(t *testing.T) {
+	tests := []struct {
+		name      string
+		rate      float64
+		inclusive bool
+		subtotal  models.Money
+		tax       models.Money
+		total     models.Money
+	}{
+		{"exclusive 20%", 0.2, false, 1999, 400, 3399},
+		{"inclusive 20%", 0.2, true, 1999, 333, 2999},
+		{"exclusive 8.25%", 0.0825, false, 4599, 379, 5978},
+		{"inclusive 19%", 0.19, true, 11900, 1900, 12900},
+		{"inclusive 7%", 0.07, true, 1, 0, 1001},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pricing := newTestOrderPricing(tt.rate, tt.inclusive)
+			tax := pricing.Tax(tt.subtotal)
+			if tax != tt.tax {
+				t.Errorf("Expected tax %s, got %s", tt.tax, tax)
+			}
+			if total := pricing.Total(tt.subtotal, tax, 1000); total != tt.total {
+				t.Errorf("Expected total %s, got %s", tt.total, total)
+			}
+
+			totals := pricing.CartTotals(tt.subtotal)
+			if totals.Tax != tt.tax || totals.TaxIncluded != tt.inclusive {
+				t.Errorf("Unexpected cart totals %+v", totals)
+			}
+			if totals.EstimatedTotal != pricing.Total(tt.subtotal, tax, totals.EstimatedShipping) {
+				t.Errorf("Estimated total %s does not reconcile", totals.EstimatedTotal)
+			}
+		})
+	}
+}
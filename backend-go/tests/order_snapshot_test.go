@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+func TestOrderItemsKeepProductSnapshot(t *testing.T) {
+	db := openTestDatabase(t)
+	repo := repositories.NewOrderRepository(db)
+	userID, cartItem := createCartFixture(t, db)
+
+	order := &models.Order{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Status:    models.OrderStatusPending,
+		Total:     1000,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := repo.CreateOrder(order); err != nil {
+		t.Fatalf("CreateOrder returned error: %v", err)
+	}
+	item := &models.OrderItem{
+		ID:          uuid.New().String(),
+		OrderID:     order.ID,
+		ProductID:   cartItem.ProductID,
+		ProductName: "Widget",
+		Quantity:    models.QuantityFromInt(1),
+		Price:       1000,
+	}
+	if err := repo.CreateOrderItem(item); err != nil {
+		t.Fatalf("CreateOrderItem returned error: %v", err)
+	}
+
+	if _, err := db.Exec("UPDATE products SET name = 'Widget v2', price = 2500 WHERE id = $1", cartItem.ProductID); err != nil {
+		t.Fatalf("Failed to update product: %v", err)
+	}
+	if _, err := db.Exec("DELETE FROM products WHERE id = $1", cartItem.ProductID); err != nil {
+		t.Fatalf("Failed to delete product: %v", err)
+	}
+
+	items, err := repo.GetOrderItems(order.ID)
+	if err != nil {
+		t.Fatalf("GetOrderItems returned error: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("Expected the item to survive product deletion, got %d items", len(items))
+	}
+	if items[0].ProductName != "Widget" || items[0].Price != 1000 || items[0].ProductID != "" {
+		t.Errorf("Expected the order-time snapshot, got %+v", items[0].OrderItem)
+	}
+}
+
+func TestAnonymizedUserOrdersStayReadable(t *testing.T) {
+	db := openTestDatabase(t)
+	repo := repositories.NewOrderRepository(db)
+	userID, _ := createCartFixture(t, db)
+
+	order := &models.Order{
+		ID:              uuid.New().String(),
+		UserID:          userID,
+		Status:          models.OrderStatusDelivered,
+		Total:           1000,
+		ShippingAddress: `{"line1": "1 Main St"}`,
+		BillingAddress:  `{"line1": "1 Main St"}`,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+	if err := repo.CreateOrder(order); err != nil {
+		t.Fatalf("CreateOrder returned error: %v", err)
+	}
+	if err := repositories.NewUserRepository(db).Anonymize(userID); err != nil {
+		t.Fatalf("Anonymize returned error: %v", err)
+	}
+
+	stored, err := repo.GetOrderByID(order.ID)
+	if err != nil {
+		t.Fatalf("GetOrderByID returned error after erasure: %v", err)
+	}
+	redacted := `{"redacted": true}`
+	if stored.ShippingAddress != redacted || stored.BillingAddress != redacted || stored.Total != 1000 {
+		t.Errorf("Expected the order kept with redacted addresses, got %+v", stored)
+	}
+	orders, err := repo.GetUserOrders(userID, "", 10, 0)
+	if err != nil || len(orders) != 1 {
+		t.Errorf("Expected the erased user's order to be listed, got %d (%v)", len(orders), err)
+	}
+}
@@ -0,0 +1,37 @@
+package tests
+
+import (
+	"testing"
+
+	"ecommerce-backend/internal/models"
+)
+
+func TestOrderStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from     models.OrderStatus
+		to       models.OrderStatus
+		expected bool
+	}{
+		{models.OrderStatusPending, models.OrderStatusProcessing, true},
+		{models.OrderStatusPending, models.OrderStatusCancelled, true},
+		{models.OrderStatusPending, models.OrderStatusShipped, false},
+		{models.OrderStatusProcessing, models.OrderStatusShipped, true},
+		{models.OrderStatusPartiallyShipped, models.OrderStatusDelivered, true},
+		{models.OrderStatusShipped, models.OrderStatusCancelled, false},
+		{models.OrderStatusDelivered, models.OrderStatusPending, false},
+		{models.OrderStatusCancelled, models.OrderStatusProcessing, false},
+		{models.OrderStatusProcessing, models.OrderStatusProcessing, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			if got := tt.from.CanTransitionTo(tt.to); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if models.OrderStatus("refunded").Valid() {
+		t.Error("Unknown status should not be valid")
+	}
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"ecommerce-backend/internal/utils"
+)
+
+func TestPasswordRehashOnLoginWhenBcryptCostChanges(t *testing.T) {
+	oldHasher := utils.NewPasswordHasher(utils.PasswordHashOptions{Algorithm: utils.PasswordAlgorithmBcrypt, BcryptCost: 4})
+	hash, err := oldHasher.Hash("s3cret!")
+	if err != nil {
+		t.Fatalf("Hash failed: %v", err)
+	}
+
+	hasher := utils.NewPasswordHasher(utils.PasswordHashOptions{Algorithm: utils.PasswordAlgorithmBcrypt, BcryptCost: 5})
+	if !hasher.NeedsRehash(hash) {
+		t.Fatal("hash with outdated cost should need rehash")
+	}
+
+	rehashed, err := hasher.VerifyAndRehash(hash, "s3cret!")
+	if err != nil {
+		t.Fatalf("VerifyAndRehash failed: %v", err)
+	}
+	if rehashed == "" || rehashed == hash {
+		t.Fatal("expected a new hash for outdated cost")
+	}
+	if hasher.NeedsRehash(rehashed) {
+		t.Error("rehashed password should use the current cost")
+	}
+
+	again, err := hasher.VerifyAndRehash(rehashed, "s3cret!")
+	if err != nil {
+		t.Fatalf("VerifyAndRehash failed: %v", err)
+	}
+	if again != "" {
+		t.Error("up-to-date hash should not be rehashed")
+	}
+}
+
+func TestPasswordRehashOnLoginMigratesToArgon2id(t *testing.T) {
+	bcryptHasher := utils.NewPasswordHasher(utils.PasswordHashOptions{Algorithm: utils.PasswordAlgorithmBcrypt, BcryptCost: 4})
+	hash, _ := bcryptHasher.Hash("s3cret!")
+
+	hasher := utils.NewPasswordHasher(utils.PasswordHashOptions{
+		Algorithm:     utils.PasswordAlgorithmArgon2id,
+		Argon2Time:    1,
+		Argon2Memory:  8 * 1024,
+		Argon2Threads: 1,
+	})
+
+	if _, err := hasher.VerifyAndRehash(hash, "wrong"); err != utils.ErrPasswordMismatch {
+		t.Fatalf("expected ErrPasswordMismatch for wrong password, got %v", err)
+	}
+
+	rehashed, err := hasher.VerifyAndRehash(hash, "s3cret!")
+	if err != nil {
+		t.Fatalf("VerifyAndRehash failed: %v", err)
+	}
+	if !strings.HasPrefix(rehashed, "$argon2id$v=19$m=8192,t=1,p=1$") {
+		t.Fatalf("expected argon2id hash, got %q", rehashed)
+	}
+	if err := hasher.Verify(rehashed, "s3cret!"); err != nil {
+		t.Errorf("argon2id hash should verify: %v", err)
+	}
+	if err := bcryptHasher.Verify(rehashed, "s3cret!"); err != nil {
+		t.Errorf("bcrypt-configured hasher should still verify argon2id hashes: %v", err)
+	}
+	if !bcryptHasher.NeedsRehash(rehashed) {
+		t.Error("argon2id hash should need rehash when bcrypt is configured")
+	}
+}
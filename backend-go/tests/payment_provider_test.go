@@ -0,0 +1,308 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/config"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/stripe/stripe-go/v78/webhook"
+)
+
+func TestMockPaymentProviderLifecycle(t *testing.T) {
+	provider := services.NewMockPaymentProvider("")
+	intent, err := provider.CreateIntent(models.ProviderIntentRequest{Amount: 2500, Currency: "usd"})
+	if err != nil {
+		t.Fatalf("CreateIntent returned error: %v", err)
+	}
+	if intent.Status != models.PaymentStatusPending || intent.ClientSecret == "" {
+		t.Fatalf("Expected a pending intent with a client secret, got %+v", intent)
+	}
+	if _, err := provider.Refund(intent.ID, 100, "", nil); err == nil {
+		t.Error("Refund should fail before the payment succeeds")
+	}
+	status, err := provider.Confirm(intent.ID)
+	if err != nil || status != models.PaymentStatusSucceeded {
+		t.Fatalf("Expected succeeded, got %s (%v)", status, err)
+	}
+	refundID, err := provider.Refund(intent.ID, 2000, "refund-1", nil)
+	if err != nil {
+		t.Errorf("Refund returned error: %v", err)
+	}
+	if again, err := provider.Refund(intent.ID, 2000, "refund-1", nil); err != nil || again != refundID {
+		t.Errorf("Expected a repeated key to return refund %s, got %s (%v)", refundID, again, err)
+	}
+	if _, err := provider.Refund(intent.ID, 501, "", nil); err == nil {
+		t.Error("Refund should not exceed the captured amount")
+	}
+
+	declined, _ := provider.CreateIntent(models.ProviderIntentRequest{Amount: 100, Currency: "usd"})
+	if err := provider.SetOutcome(declined.ID, models.PaymentStatusFailed); err != nil {
+		t.Fatalf("SetOutcome returned error: %v", err)
+	}
+	if status, _ := provider.Confirm(declined.ID); status != models.PaymentStatusFailed {
+		t.Errorf("Expected failed, got %s", status)
+	}
+}
+
+func TestMockPaymentProviderWebhookSignature(t *testing.T) {
+	provider := services.NewMockPaymentProvider("mock-secret")
+	payload, _ := json.Marshal(services.MockWebhookBody{Type: models.PaymentEventSucceeded, IntentID: "mock_pi_1"})
+
+	header := http.Header{}
+	header.Set("X-Mock-Signature", services.SignMockWebhook(payload, "mock-secret"))
+	event, err := provider.VerifyWebhook(payload, header)
+	if err != nil {
+		t.Fatalf("VerifyWebhook returned error: %v", err)
+	}
+	if event.Type != models.PaymentEventSucceeded || event.IntentID != "mock_pi_1" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	header.Set("X-Mock-Signature", services.SignMockWebhook(payload, "other-secret"))
+	if _, err := provider.VerifyWebhook(payload, header); err == nil || err.Error() != "invalid webhook signature" {
+		t.Errorf("Expected invalid webhook signature, got %v", err)
+	}
+}
+
+func TestStripeProviderVerifiesWebhookSignature(t *testing.T) {
+	provider := services.NewStripeProvider("sk_test_unused", "whsec_test")
+	payload := []byte(`{"id":"evt_1","object":"event","type":"payment_intent.payment_failed","data":{"object":{"id":"pi_123","object":"payment_intent"}}}`)
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload:   payload,
+		Secret:    "whsec_test",
+		Timestamp: time.Now(),
+	})
+
+	header := http.Header{}
+	header.Set("Stripe-Signature", signed.Header)
+	event, err := provider.VerifyWebhook(payload, header)
+	if err != nil {
+		t.Fatalf("VerifyWebhook returned error: %v", err)
+	}
+	if event.Type != models.PaymentEventFailed || event.IntentID != "pi_123" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	header.Set("Stripe-Signature", "t=1,v1=deadbeef")
+	if _, err := provider.VerifyWebhook(payload, header); err == nil {
+		t.Error("VerifyWebhook should reject a bad signature")
+	}
+}
+
+func TestPaymentProvidersSelection(t *testing.T) {
+	providers := services.NewPaymentProviders("mock", services.NewMockPaymentProvider(""), services.NewStripeProvider("", ""))
+	if provider, err := providers.Get(""); err != nil || provider.Name() != "mock" {
+		t.Errorf("Expected the default mock provider, got %v (%v)", provider, err)
+	}
+	if provider, err := providers.Get("stripe"); err != nil || provider.Name() != "stripe" {
+		t.Errorf("Expected the stripe provider, got %v (%v)", provider, err)
+	}
+	if _, err := providers.Get("paypal"); err == nil || err.Error() != "unknown payment provider" {
+		t.Errorf("Expected unknown payment provider, got %v", err)
+	}
+}
+
+func TestLoadConfigPaymentProviders(t *testing.T) {
+	t.Setenv("PAYMENT_PROVIDER", "mock")
+	t.Setenv("PAYMENT_PROVIDERS", "stripe,mock")
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.Payments.Provider != "mock" || len(cfg.Payments.Providers) != 2 {
+		t.Errorf("Unexpected payments config %+v", cfg.Payments)
+	}
+
+	t.Setenv("PAYMENT_PROVIDERS", "stripe")
+	if _, err := config.LoadConfig(""); err == nil {
+		t.Error("LoadConfig should reject a default provider that is not enabled")
+	}
+
+	t.Setenv("PAYMENT_PROVIDERS", "mock")
+	t.Setenv("ENVIRONMENT", "production")
+	if _, err := config.LoadConfig(""); err == nil {
+		t.Error("LoadConfig should refuse the mock provider in production")
+	}
+}
+
+func TestPaymentTestModeSettlesThroughWebhook(t *testing.T) {
+	db := openTestDatabase(t)
+	paymentRepo := repositories.NewPaymentRepository(db)
+	orderRepo := repositories.NewOrderRepository(db)
+	service := services.NewPaymentService(paymentRepo, orderRepo, services.NewPaymentProviders("stripe"), nil, nil, true)
+
+	userID := uuid.New().String()
+	if _, err := db.Exec("INSERT INTO users (id, email, password) VALUES ($1, $2, 'x')", userID, userID+"@example.com"); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	t.Cleanup(func() { db.Exec("DELETE FROM users WHERE id = $1", userID) })
+
+	tests := []struct {
+		name        string
+		amount      models.Money
+		outcome     string
+		payment     models.PaymentStatus
+		orderStatus models.OrderStatus
+	}{
+		{"succeeds by default", 2500, "", models.PaymentStatusSucceeded, models.OrderStatusProcessing},
+		{"declined amount", 1002, "", models.PaymentStatusFailed, models.OrderStatusPending},
+		{"requested outcome wins", 1002, "succeeded", models.PaymentStatusSucceeded, models.OrderStatusProcessing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderID := uuid.New().String()
+			if _, err := db.Exec("INSERT INTO orders (id, user_id, status, total) VALUES ($1, $2, 'pending', $3)", orderID, userID, tt.amount); err != nil {
+				t.Fatalf("Failed to create order: %v", err)
+			}
+			intent, err := service.CreatePaymentIntent(userID, models.PaymentIntentRequest{
+				Amount: tt.amount, Currency: "usd", OrderID: &orderID, Provider: "stripe", TestOutcome: tt.outcome,
+			})
+			if err != nil {
+				t.Fatalf("CreatePaymentIntent returned error: %v", err)
+			}
+			if !intent.TestMode || intent.Provider != "mock" {
+				t.Errorf("Expected a sandbox intent, got %+v", intent)
+			}
+			payment, err := service.ConfirmPayment(userID, models.PaymentConfirmRequest{PaymentIntentID: intent.ID})
+			if err != nil {
+				t.Fatalf("ConfirmPayment returned error: %v", err)
+			}
+			if payment.Status != tt.payment || !payment.TestMode {
+				t.Errorf("Expected %s test payment, got %s (test_mode %v)", tt.payment, payment.Status, payment.TestMode)
+			}
+			order, err := orderRepo.GetOrderByID(orderID)
+			if err != nil {
+				t.Fatalf("GetOrderByID returned error: %v", err)
+			}
+			if order.Status != tt.orderStatus {
+				t.Errorf("Expected order %s, got %s", tt.orderStatus, order.Status)
+			}
+		})
+	}
+}
+
+func TestMockPaymentProviderIdempotencyAndCancel(t *testing.T) {
+	provider := services.NewMockPaymentProvider("")
+	req := models.ProviderIntentRequest{Amount: 2500, Currency: "usd", IdempotencyKey: "order-1-0-2500-usd"}
+	first, _ := provider.CreateIntent(req)
+	second, _ := provider.CreateIntent(req)
+	if first.ID != second.ID {
+		t.Errorf("Expected the same intent for a repeated key, got %s and %s", first.ID, second.ID)
+	}
+
+	if err := provider.Cancel(first.ID); err != nil {
+		t.Fatalf("Cancel returned error: %v", err)
+	}
+	if status, _ := provider.Status(first.ID); status != models.PaymentStatusCancelled {
+		t.Errorf("Expected cancelled, got %s", status)
+	}
+
+	paid, _ := provider.CreateIntent(models.ProviderIntentRequest{Amount: 100, Currency: "usd"})
+	if status, _ := provider.Status(paid.ID); status != models.PaymentStatusPending {
+		t.Errorf("Status should not settle the intent, got %s", status)
+	}
+	provider.Confirm(paid.ID)
+	if err := provider.Cancel(paid.ID); err == nil {
+		t.Error("Cancel should fail once the payment succeeded")
+	}
+}
+
+func TestCreatePaymentIntentIsIdempotentPerOrder(t *testing.T) {
+	db := openTestDatabase(t)
+	paymentRepo := repositories.NewPaymentRepository(db)
+	orderRepo := repositories.NewOrderRepository(db)
+	service := services.NewPaymentService(paymentRepo, orderRepo, services.NewPaymentProviders("mock", services.NewMockPaymentProvider("")), nil, nil, false)
+
+	userID, orderID := uuid.New().String(), uuid.New().String()
+	if _, err := db.Exec("INSERT INTO users (id, email, password) VALUES ($1, $2, 'x')", userID, userID+"@example.com"); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	t.Cleanup(func() { db.Exec("DELETE FROM users WHERE id = $1", userID) })
+	if _, err := db.Exec("INSERT INTO orders (id, user_id, status, total) VALUES ($1, $2, 'pending', 2500)", orderID, userID); err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+
+	req := models.PaymentIntentRequest{Amount: 2500, Currency: "usd", OrderID: &orderID}
+	first, err := service.CreatePaymentIntent(userID, req)
+	if err != nil {
+		t.Fatalf("CreatePaymentIntent returned error: %v", err)
+	}
+	// The order is charged its total whatever amount the client sends.
+	req.Amount = 1
+	retry, err := service.CreatePaymentIntent(userID, req)
+	if err != nil {
+		t.Fatalf("CreatePaymentIntent returned error: %v", err)
+	}
+	if retry.ID != first.ID || !retry.Reused || retry.Amount != 2500 {
+		t.Errorf("Expected the open intent %s for 2500 to be reused, got %+v", first.ID, retry)
+	}
+
+	if _, err := db.Exec("UPDATE orders SET total = 3000 WHERE id = $1", orderID); err != nil {
+		t.Fatalf("Failed to update order: %v", err)
+	}
+	changed, err := service.CreatePaymentIntent(userID, req)
+	if err != nil {
+		t.Fatalf("CreatePaymentIntent returned error: %v", err)
+	}
+	if changed.ID == first.ID || changed.Reused || changed.Amount != 3000 {
+		t.Errorf("Expected a new intent for 3000 after the total changed, got %+v", changed)
+	}
+	stale, err := paymentRepo.GetPaymentByIntentID(first.ID)
+	if err != nil {
+		t.Fatalf("GetPaymentByIntentID returned error: %v", err)
+	}
+	if stale.Status != models.PaymentStatusCancelled {
+		t.Errorf("Expected the stale intent to be cancelled, got %s", stale.Status)
+	}
+
+	if _, err := service.CreatePaymentIntent(uuid.New().String(), req); err == nil || err.Error() != "order not found" {
+		t.Errorf("Expected order not found for another user, got %v", err)
+	}
+}
+
+func TestPaymentOnCancelledOrderIsRefunded(t *testing.T) {
+	db := openTestDatabase(t)
+	paymentRepo := repositories.NewPaymentRepository(db)
+	orderRepo := repositories.NewOrderRepository(db)
+	provider := services.NewMockPaymentProvider("")
+	service := services.NewPaymentService(paymentRepo, orderRepo, services.NewPaymentProviders("mock", provider), nil, nil, false)
+
+	userID, orderID := uuid.New().String(), uuid.New().String()
+	if _, err := db.Exec("INSERT INTO users (id, email, password) VALUES ($1, $2, 'x')", userID, userID+"@example.com"); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	t.Cleanup(func() { db.Exec("DELETE FROM users WHERE id = $1", userID) })
+	if _, err := db.Exec("INSERT INTO orders (id, user_id, status, total) VALUES ($1, $2, 'pending', 2500)", orderID, userID); err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+
+	intent, err := service.CreatePaymentIntent(userID, models.PaymentIntentRequest{Amount: 2500, Currency: "usd", OrderID: &orderID})
+	if err != nil {
+		t.Fatalf("CreatePaymentIntent returned error: %v", err)
+	}
+	// The unpaid-order sweep cancels the order before the customer pays.
+	if _, err := db.Exec("UPDATE orders SET status = 'cancelled' WHERE id = $1", orderID); err != nil {
+		t.Fatalf("Failed to cancel order: %v", err)
+	}
+	if _, err := service.ConfirmPayment(userID, models.PaymentConfirmRequest{PaymentIntentID: intent.ID}); err != nil {
+		t.Fatalf("ConfirmPayment returned error: %v", err)
+	}
+
+	order, err := orderRepo.GetOrderByID(orderID)
+	if err != nil {
+		t.Fatalf("GetOrderByID returned error: %v", err)
+	}
+	if order.Status != models.OrderStatusCancelled {
+		t.Errorf("Expected the order to stay cancelled, got %s", order.Status)
+	}
+	if _, err := provider.Refund(intent.ID, 1, "", nil); err == nil || err.Error() != "refund exceeds captured amount" {
+		t.Errorf("Expected the payment to be fully refunded, got %v", err)
+	}
+}
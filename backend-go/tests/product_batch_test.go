@@ -0,0 +1,226 @@
+package tests
+
+import (
+	"testing"
+
+	"ecommerce-backend/internal/config"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestGetProductsByIDs(t *testing.T) {
+	db := openTestDatabase(t)
+	productRepo := repositories.NewProductRepository(db)
+	categoryRepo := repositories.NewCategoryRepository(db)
+	translations := services.NewTranslationService(repositories.NewTranslationRepository(db), productRepo, categoryRepo, "en", []string{"en"})
+	service := services.NewProductService(productRepo, categoryRepo, repositories.NewReviewRepository(db), repositories.NewPriceHistoryRepository(db),
+		repositories.NewProductImageRepository(db), repositories.NewInventoryRepository(db), repositories.NewUploadRepository(db), nil, translations, config.CatalogConfig{})
+
+	var ids []string
+	for _, name := range []string{"Alpha", "Beta", "Gamma"} {
+		id := uuid.New().String()
+		if _, err := db.Exec("INSERT INTO products (id, name, slug, price, stock) VALUES ($1, $2, $3, 10, 1)", id, name, "batch-"+id); err != nil {
+			t.Fatalf("Failed to create product: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	t.Cleanup(func() {
+		for _, id := range ids {
+			db.Exec("DELETE FROM products WHERE id = $1", id)
+		}
+	})
+
+	unknown := uuid.New().String()
+	batch, err := service.GetProductsByIDs("", []string{ids[2], unknown, ids[0], "not-a-uuid", ids[2], ids[1]}, "en")
+	if err != nil {
+		t.Fatalf("GetProductsByIDs returned error: %v", err)
+	}
+	expected := []string{ids[2], ids[0], ids[1]}
+	if len(batch.Products) != len(expected) {
+		t.Fatalf("Expected %d products, got %d", len(expected), len(batch.Products))
+	}
+	for i, id := range expected {
+		if batch.Products[i].ID != id {
+			t.Errorf("Product %d: expected %s, got %s", i, id, batch.Products[i].ID)
+		}
+	}
+	if len(batch.Missing) != 2 || batch.Missing[0] != unknown || batch.Missing[1] != "not-a-uuid" {
+		t.Errorf("Expected the unknown ids to be missing, got %v", batch.Missing)
+	}
+}
+
+func TestCheckStock(t *testing.T) {
+	db := openTestDatabase(t)
+	productRepo := repositories.NewProductRepository(db)
+	service := services.NewProductService(productRepo, repositories.NewCategoryRepository(db), repositories.NewReviewRepository(db), repositories.NewPriceHistoryRepository(db),
+		repositories.NewProductImageRepository(db), repositories.NewInventoryRepository(db), repositories.NewUploadRepository(db), nil, nil, config.CatalogConfig{})
+
+	inStock, soldOut, hidden := uuid.New().String(), uuid.New().String(), uuid.New().String()
+	for _, p := range []struct {
+		id      string
+		stock   int
+		inStock bool
+	}{{inStock, 7, true}, {soldOut, 0, true}, {hidden, 5, false}} {
+		if _, err := db.Exec("INSERT INTO products (id, name, slug, price, stock, in_stock) VALUES ($1, 'Widget', $2, 10, $3, $4)", p.id, "stock-"+p.id, p.stock, p.inStock); err != nil {
+			t.Fatalf("Failed to create product: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM products WHERE id = ANY($1)", pq.Array([]string{inStock, soldOut, hidden}))
+	})
+
+	unknown := uuid.New().String()
+	items, err := service.CheckStock("", []string{soldOut, inStock, unknown, hidden, inStock})
+	if err != nil {
+		t.Fatalf("CheckStock returned error: %v", err)
+	}
+	expected := []models.StockCheckItem{
+		{ProductID: soldOut, Available: 0, Availability: models.StockOutOfStock},
+		{ProductID: inStock, Available: 7, Availability: models.StockInStock},
+		{ProductID: unknown, Available: 0, Availability: models.StockUnavailable},
+		{ProductID: hidden, Available: 0, Availability: models.StockOutOfStock},
+	}
+	if len(items) != len(expected) {
+		t.Fatalf("Expected %d items, got %d", len(expected), len(items))
+	}
+	for i := range expected {
+		if items[i] != expected[i] {
+			t.Errorf("Item %d: expected %+v, got %+v", i, expected[i], items[i])
+		}
+	}
+}
+
+func TestProductFacets(t *testing.T) {
+	db := openTestDatabase(t)
+	productRepo := repositories.NewProductRepository(db)
+	categoryRepo := repositories.NewCategoryRepository(db)
+	translations := services.NewTranslationService(repositories.NewTranslationRepository(db), productRepo, categoryRepo, "en", []string{"en"})
+	service := services.NewProductService(productRepo, categoryRepo, repositories.NewReviewRepository(db), repositories.NewPriceHistoryRepository(db),
+		repositories.NewProductImageRepository(db), repositories.NewInventoryRepository(db), repositories.NewUploadRepository(db), nil, translations,
+		config.CatalogConfig{DefaultPageSize: 1, MaxPageSize: 10, PriceFacetBounds: []float64{50, 500}})
+
+	if _, err := service.GetProducts(models.ProductQuery{Facets: "price,colour"}); err == nil {
+		t.Fatal("Expected an unknown facet to be rejected")
+	}
+
+	marker := "facet-" + uuid.New().String()
+	categoryID := uuid.New().String()
+	if _, err := db.Exec("INSERT INTO categories (id, name, slug) VALUES ($1, 'Facets', $2)", categoryID, marker); err != nil {
+		t.Fatalf("Failed to create category: %v", err)
+	}
+	var ids []string
+	// Prices are in cents: $10, $60 and $600 against bounds of $50 and $500.
+	for _, p := range []struct {
+		price    models.Money
+		inStock  bool
+		category interface{}
+	}{{1000, true, categoryID}, {6000, true, categoryID}, {60000, false, nil}} {
+		id := uuid.New().String()
+		if _, err := db.Exec("INSERT INTO products (id, name, slug, price, stock, in_stock, category_id) VALUES ($1, $2, $3, $4, 1, $5, $6)",
+			id, marker, "facet-"+id, p.price, p.inStock, p.category); err != nil {
+			t.Fatalf("Failed to create product: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM products WHERE id = ANY($1)", pq.Array(ids))
+		db.Exec("DELETE FROM categories WHERE id = $1", categoryID)
+	})
+
+	result, err := service.GetProducts(models.ProductQuery{Search: marker, Category: categoryID, Facets: "category, price,availability,price"})
+	if err != nil {
+		t.Fatalf("GetProducts returned error: %v", err)
+	}
+	if result.Pagination.Total != 2 || len(result.Data) != 1 {
+		t.Fatalf("Expected a one-item page of 2 products, got %d of %d", len(result.Data), result.Pagination.Total)
+	}
+	facets := result.Facets
+	if facets == nil {
+		t.Fatal("Expected facets in the response")
+	}
+	if len(facets.Category) != 1 || facets.Category[0].Value != categoryID || facets.Category[0].Count != 2 {
+		t.Errorf("Unexpected category facet: %+v", facets.Category)
+	}
+	if len(facets.Price) != 3 || facets.Price[0].Count != 1 || facets.Price[1].Count != 1 || facets.Price[2].Count != 0 {
+		t.Errorf("Unexpected price facet: %+v", facets.Price)
+	}
+	if facets.Price[0].Min != nil || facets.Price[2].Max != nil {
+		t.Errorf("Expected open-ended first and last price buckets, got %+v", facets.Price)
+	}
+	if len(facets.Availability) != 2 || facets.Availability[0].Count != 2 || facets.Availability[1].Count != 0 {
+		t.Errorf("Unexpected availability facet: %+v", facets.Availability)
+	}
+}
+
+func TestMergeProducts(t *testing.T) {
+	db := openTestDatabase(t)
+	productRepo := repositories.NewProductRepository(db)
+	imageRepo := repositories.NewProductImageRepository(db)
+	audit := services.NewAuditService(repositories.NewAuditRepository(db), services.NewJobQueue(1, 1))
+	service := services.NewProductService(productRepo, repositories.NewCategoryRepository(db), repositories.NewReviewRepository(db), repositories.NewPriceHistoryRepository(db),
+		imageRepo, repositories.NewInventoryRepository(db), repositories.NewUploadRepository(db), audit, nil, config.CatalogConfig{})
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id := uuid.New().String()
+		if _, err := db.Exec("INSERT INTO products (id, name, slug, price, stock) VALUES ($1, 'Widget', $2, 10, 1)", id, "merge-"+id); err != nil {
+			t.Fatalf("Failed to create product: %v", err)
+		}
+		if _, err := db.Exec("INSERT INTO product_images (product_id, url, position, is_primary) VALUES ($1, $2, 0, true)", id, "/img/"+id); err != nil {
+			t.Fatalf("Failed to create image: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM products WHERE id = ANY($1)", pq.Array(ids))
+	})
+	primary, duplicates := ids[0], ids[1:]
+
+	otherStore := uuid.New().String()
+	if _, err := productRepo.GetByID(otherStore, primary); err == nil || err.Error() != "product not found" {
+		t.Errorf("Expected a product of another store to be not found, got %v", err)
+	}
+	if _, err := service.MergeProducts(otherStore, models.ProductMergeRequest{PrimaryID: primary, DuplicateIDs: duplicates}, models.AuditEntry{}); err == nil || err.Error() != "product not found" {
+		t.Errorf("Expected merging products of another store to fail, got %v", err)
+	}
+	if _, err := service.MergeProducts("", models.ProductMergeRequest{PrimaryID: primary, DuplicateIDs: []string{primary}}, models.AuditEntry{}); err == nil {
+		t.Error("Expected merging a product into itself to fail")
+	}
+	result, err := service.MergeProducts("", models.ProductMergeRequest{PrimaryID: primary, DuplicateIDs: duplicates}, models.AuditEntry{Action: models.AuditActionProductMerge})
+	if err != nil {
+		t.Fatalf("MergeProducts returned error: %v", err)
+	}
+	if result.ImagesReassigned != 2 {
+		t.Errorf("Expected 2 images reassigned, got %d", result.ImagesReassigned)
+	}
+	images, err := imageRepo.GetByProductID(primary)
+	if err != nil {
+		t.Fatalf("GetByProductID returned error: %v", err)
+	}
+	primaries := 0
+	for _, image := range images {
+		if image.IsPrimary {
+			primaries++
+		}
+	}
+	if len(images) != 3 || primaries != 1 {
+		t.Errorf("Expected 3 images with one primary, got %d with %d", len(images), primaries)
+	}
+	var deleted int
+	if err := db.QueryRow("SELECT COUNT(*) FROM products WHERE id = ANY($1) AND deleted_at IS NOT NULL", pq.Array(duplicates)).Scan(&deleted); err != nil {
+		t.Fatalf("Failed to count deleted products: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected both duplicates soft-deleted, got %d", deleted)
+	}
+	if _, err := productRepo.GetByID("", duplicates[0]); err == nil || err.Error() != "product not found" {
+		t.Errorf("Expected a merged duplicate to be not found, got %v", err)
+	}
+	if _, err := service.MergeProducts("", models.ProductMergeRequest{PrimaryID: primary, DuplicateIDs: duplicates}, models.AuditEntry{}); err == nil || err.Error() != "duplicate product not found" {
+		t.Errorf("Expected merged duplicates to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/services"
+)
+
+func encodePNG(t *testing.T, width, height int) *bytes.Reader {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("Failed to encode image: %v", err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestCheckProductImage(t *testing.T) {
+	service := services.NewUploadService(nil, t.TempDir(), 0, 0, "secret", 0, models.ImageConstraints{
+		MinWidth:        400,
+		MinHeight:       300,
+		AspectRatio:     4.0 / 3.0,
+		AspectTolerance: 0.02,
+	})
+
+	tests := []struct {
+		name          string
+		width, height int
+		expected      string
+	}{
+		{"exact minimum", 400, 300, ""},
+		{"larger", 1600, 1200, ""},
+		{"within tolerance", 1610, 1200, ""},
+		{"too narrow", 399, 300, "image is too small"},
+		{"too short", 800, 200, "image is too small"},
+		{"square", 800, 800, "image aspect ratio is not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := encodePNG(t, tt.width, tt.height)
+			width, height, err := service.CheckProductImage(file)
+			if width != tt.width || height != tt.height {
+				t.Errorf("Expected %dx%d, got %dx%d", tt.width, tt.height, width, height)
+			}
+			if tt.expected == "" && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if tt.expected != "" && (err == nil || err.Error() != tt.expected) {
+				t.Errorf("Expected %q, got %v", tt.expected, err)
+			}
+			if offset, _ := file.Seek(0, 1); offset != 0 {
+				t.Errorf("Expected the file to be rewound, offset is %d", offset)
+			}
+		})
+	}
+
+	if _, _, err := service.CheckProductImage(bytes.NewReader([]byte("not an image"))); err == nil || err.Error() != "invalid image" {
+		t.Errorf("Expected invalid image, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+
+	"github.com/google/uuid"
+)
+
+// countVersionedWrites runs writers concurrently against the same expected
+// version and counts successes and conflicts.
+func countVersionedWrites(t *testing.T, writers int, conflict string, write func(i int) error) (int, int) {
+	var wg sync.WaitGroup
+	results := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results <- write(i)
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded, conflicts := 0, 0
+	for err := range results {
+		switch {
+		case err == nil:
+			succeeded++
+		case err.Error() == conflict:
+			conflicts++
+		default:
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	return succeeded, conflicts
+}
+
+func TestProductConcurrentVersionedUpdates(t *testing.T) {
+	db := openTestDatabase(t)
+	repo := repositories.NewProductRepository(db)
+
+	productID := uuid.New().String()
+	if _, err := db.Exec("INSERT INTO products (id, name, slug, price, stock) VALUES ($1, 'Widget', $2, 10, 100)", productID, "widget-"+productID); err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+	t.Cleanup(func() { db.Exec("DELETE FROM products WHERE id = $1", productID) })
+
+	product, err := repo.GetByID("", productID)
+	if err != nil {
+		t.Fatalf("GetByID returned error: %v", err)
+	}
+
+	const writers = 8
+	succeeded, conflicts := countVersionedWrites(t, writers, "product version conflict", func(i int) error {
+		_, err := repo.UpdateVersioned("", productID, product.Version, map[string]interface{}{"stock": i, "updated_at": time.Now()})
+		return err
+	})
+	if succeeded != 1 || conflicts != writers-1 {
+		t.Errorf("Expected 1 success and %d conflicts, got %d and %d", writers-1, succeeded, conflicts)
+	}
+
+	if _, err := repositories.NewInventoryRepository(db).Apply(productID, -1, models.InventoryReasonAdjustment, nil); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	current, err := repo.GetByID("", productID)
+	if err != nil {
+		t.Fatalf("GetByID returned error: %v", err)
+	}
+	if current.Version != product.Version+2 {
+		t.Errorf("Expected version %d after a save and a stock adjustment, got %d", product.Version+2, current.Version)
+	}
+	if _, err := repo.UpdateVersioned("", productID, product.Version+1, map[string]interface{}{"stock": 1}); err == nil || err.Error() != "product version conflict" {
+		t.Errorf("Write based on a version older than the stock adjustment should conflict, got %v", err)
+	}
+	if _, err := repo.UpdateVersioned("", uuid.New().String(), 1, map[string]interface{}{"stock": 1}); err == nil || err.Error() != "product not found" {
+		t.Errorf("Expected product not found, got %v", err)
+	}
+	if _, err := repo.UpdateVersioned(uuid.New().String(), productID, current.Version, map[string]interface{}{"stock": 1}); err == nil || err.Error() != "product not found" {
+		t.Errorf("Expected a product of another store to be not found, got %v", err)
+	}
+}
+
+func TestCategoryConcurrentVersionedUpdates(t *testing.T) {
+	db := openTestDatabase(t)
+	repo := repositories.NewCategoryRepository(db)
+
+	category := &models.Category{
+		ID:        uuid.New().String(),
+		Name:      "Tools",
+		Slug:      "tools-" + uuid.New().String(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := repo.Create(category); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	t.Cleanup(func() { repo.Delete(category.ID) })
+
+	loaded, err := repo.GetByID(category.ID)
+	if err != nil {
+		t.Fatalf("GetByID returned error: %v", err)
+	}
+
+	const writers = 8
+	succeeded, conflicts := countVersionedWrites(t, writers, "category version conflict", func(i int) error {
+		_, err := repo.UpdateVersioned(category.ID, loaded.Version, map[string]interface{}{"name": "Tools", "updated_at": time.Now()})
+		return err
+	})
+	if succeeded != 1 || conflicts != writers-1 {
+		t.Errorf("Expected 1 success and %d conflicts, got %d and %d", writers-1, succeeded, conflicts)
+	}
+}
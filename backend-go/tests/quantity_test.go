@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/services"
+
+	"github.com/google/uuid"
+)
+
+func TestQuantityJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected models.Quantity
+		output   string
+	}{
+		{`1.5`, 1500, `1.5`},
+		{`3`, 3000, `3`},
+		{`"0.125"`, 125, `0.125`},
+		{`2.250`, 2250, `2.25`},
+	}
+	for _, tt := range tests {
+		var quantity models.Quantity
+		if err := json.Unmarshal([]byte(tt.input), &quantity); err != nil {
+			t.Fatalf("Unmarshal(%s) returned error: %v", tt.input, err)
+		}
+		if quantity != tt.expected {
+			t.Errorf("Unmarshal(%s) = %d, expected %d", tt.input, quantity, tt.expected)
+		}
+		data, _ := json.Marshal(quantity)
+		if string(data) != tt.output {
+			t.Errorf("Marshal(%d) = %s, expected %s", quantity, data, tt.output)
+		}
+	}
+
+	var quantity models.Quantity
+	if err := json.Unmarshal([]byte(`1.0005`), &quantity); err == nil {
+		t.Error("Expected an error for more than three decimal places")
+	}
+}
+
+func TestCheckQuantity(t *testing.T) {
+	tests := []struct {
+		unit     string
+		quantity models.Quantity
+		valid    bool
+	}{
+		{models.UnitEach, models.QuantityFromInt(2), true},
+		{models.UnitEach, 1500, false},
+		{"", 1500, false},
+		{models.UnitEach, 0, false},
+		{models.UnitKilogram, 1500, true},
+		{models.UnitMeter, 1, true},
+		{models.UnitKilogram, -500, false},
+	}
+	for _, tt := range tests {
+		err := models.CheckQuantity(tt.unit, tt.quantity)
+		if (err == nil) != tt.valid {
+			t.Errorf("CheckQuantity(%q, %s) returned %v, expected valid=%v", tt.unit, tt.quantity, err, tt.valid)
+		}
+	}
+}
+
+func TestMoneyMulQuantity(t *testing.T) {
+	tests := []struct {
+		price    models.Money
+		quantity models.Quantity
+		expected models.Money
+	}{
+		{1999, models.QuantityFromInt(3), 5997},
+		{450, 1500, 675},
+		// 3.33 per kg x 0.25 kg = 0.8325, rounded half away from zero.
+		{333, 250, 83},
+		{333, 1350, 450},
+		{-333, 1350, -450},
+	}
+	for _, tt := range tests {
+		if got := tt.price.MulQuantity(tt.quantity); got != tt.expected {
+			t.Errorf("%s x %s = %s, expected %s", tt.price, tt.quantity, got, tt.expected)
+		}
+	}
+}
+
+func TestCartFractionalQuantities(t *testing.T) {
+	db := openTestDatabase(t)
+	userID, _ := createCartFixture(t, db)
+	productRepo := repositories.NewProductRepository(db)
+	cartService := services.NewCartService(repositories.NewCartRepository(db), productRepo, nil, nil, 0)
+
+	looseID := uuid.New().String()
+	if _, err := db.Exec("INSERT INTO products (id, name, slug, price, stock, unit) VALUES ($1, 'Coffee beans', $2, 2400, 50, 'kg')", looseID, "coffee-"+looseID); err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+	countID := uuid.New().String()
+	if _, err := db.Exec("INSERT INTO products (id, name, slug, price, stock) VALUES ($1, 'Mug', $2, 1200, 50)", countID, "mug-"+countID); err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM cart_items WHERE user_id = $1", userID)
+		db.Exec("DELETE FROM products WHERE id IN ($1, $2)", looseID, countID)
+	})
+
+	if _, err := cartService.AddToCart(userID, looseID, 1500); err != nil {
+		t.Fatalf("Adding 1.5 kg returned error: %v", err)
+	}
+	if _, err := cartService.AddToCart(userID, looseID, 250); err != nil {
+		t.Fatalf("Adding 0.25 kg returned error: %v", err)
+	}
+	if _, err := cartService.AddToCart(userID, countID, 1500); err == nil || err.Error() != "quantity must be a whole number" {
+		t.Errorf("Expected a fractional mug to be rejected, got %v", err)
+	}
+	if _, err := cartService.AddToCart(userID, countID, models.QuantityFromInt(2)); err != nil {
+		t.Fatalf("Adding 2 mugs returned error: %v", err)
+	}
+
+	items, err := cartService.GetCartItems(userID)
+	if err != nil {
+		t.Fatalf("GetCartItems returned error: %v", err)
+	}
+	for _, item := range items {
+		switch item.ProductID {
+		case looseID:
+			if item.Quantity != 1750 || item.Product.Unit != models.UnitKilogram {
+				t.Errorf("Expected 1.75 kg of coffee, got %s %s", item.Quantity, item.Product.Unit)
+			}
+		case countID:
+			if item.Quantity != models.QuantityFromInt(2) {
+				t.Errorf("Expected 2 mugs, got %s", item.Quantity)
+			}
+		}
+	}
+	total, err := cartService.GetCartTotal(userID)
+	if err != nil {
+		t.Fatalf("GetCartTotal returned error: %v", err)
+	}
+	// The fixture's widget (0.10) + 1.75 kg at 24.00 + 2 x 12.00
+	if total != 10+4200+2400 {
+		t.Errorf("Expected total of 66.10, got %s", total)
+	}
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitBypassPaths(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RateLimitMiddleware(1, time.Minute, "/api/health", "/api/metrics"))
+	for _, path := range []string{"/api/health", "/api/health/ready", "/api/metrics", "/api/healthz", "/api/products"} {
+		r.GET(path, func(c *gin.Context) { c.Status(http.StatusOK) })
+	}
+
+	request := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "203.0.113.7:5123"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request("/api/products"); code != http.StatusOK {
+		t.Fatalf("Expected the first request to pass, got %d", code)
+	}
+	if code := request("/api/products"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 once the limit is used, got %d", code)
+	}
+	for _, path := range []string{"/api/health", "/api/health/ready", "/api/metrics"} {
+		for i := 0; i < 3; i++ {
+			if code := request(path); code != http.StatusOK {
+				t.Errorf("Expected %s to bypass the rate limit, got %d", path, code)
+			}
+		}
+	}
+	if code := request("/api/healthz"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected /api/healthz to be limited, got %d", code)
+	}
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ecommerce-backend/internal/middleware"
+	"ecommerce-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestIDPropagation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RequestIDMiddlewareWithHeader("X-Trace-ID"))
+	r.GET("/fail", func(c *gin.Context) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "bad input")
+	})
+
+	call := func(incoming string) (string, utils.APIResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+		if incoming != "" {
+			req.Header.Set("X-Trace-ID", incoming)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var body utils.APIResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return w.Header().Get("X-Trace-ID"), body
+	}
+
+	header, body := call("trace-1234.abc:9")
+	if header != "trace-1234.abc:9" {
+		t.Errorf("Expected the incoming ID to be echoed, got %q", header)
+	}
+	if body.RequestID != header {
+		t.Errorf("Expected the error body to carry %q, got %q", header, body.RequestID)
+	}
+
+	for _, incoming := range []string{"", "id with spaces", "<script>", strings.Repeat("a", 129)} {
+		header, body := call(incoming)
+		if header == "" || header == incoming || !middleware.ValidRequestID(header) {
+			t.Errorf("Expected a generated ID for %q, got %q", incoming, header)
+		}
+		if body.RequestID != header {
+			t.Errorf("Expected the error body to carry %q, got %q", header, body.RequestID)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/services"
+
+	"github.com/google/uuid"
+)
+
+func TestDeleteReviewKeepsSharedImages(t *testing.T) {
+	db := openTestDatabase(t)
+	dir := t.TempDir()
+	uploadService := services.NewUploadService(repositories.NewUploadRepository(db), dir, 0, 0, "secret", 0, models.ImageConstraints{})
+	service := services.NewReviewService(repositories.NewReviewRepository(db), uploadService, nil, models.ReviewLimits{}, false)
+
+	userID, uploadID := uuid.New().String(), uuid.New().String()
+	if _, err := db.Exec("INSERT INTO users (id, email, password) VALUES ($1, $2, 'x')", userID, userID+"@example.com"); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	t.Cleanup(func() { db.Exec("DELETE FROM users WHERE id = $1", userID) })
+	filename := uploadID + ".png"
+	path := filepath.Join(dir, filename)
+	if err := os.WriteFile(path, []byte("png"), 0600); err != nil {
+		t.Fatalf("Failed to write upload: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO uploads (id, user_id, filename, content_type, size) VALUES ($1, $2, $3, 'image/png', 3)", uploadID, userID, filename); err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+
+	// Two reviews of different products show the same upload.
+	var reviewIDs []string
+	for i := 0; i < 2; i++ {
+		productID, reviewID := uuid.New().String(), uuid.New().String()
+		if _, err := db.Exec("INSERT INTO products (id, name, slug, price, stock) VALUES ($1, 'Widget', $2, 10, 100)", productID, "widget-"+productID); err != nil {
+			t.Fatalf("Failed to create product: %v", err)
+		}
+		t.Cleanup(func() { db.Exec("DELETE FROM products WHERE id = $1", productID) })
+		if _, err := db.Exec("INSERT INTO reviews (id, user_id, product_id, rating, comment) VALUES ($1, $2, $3, 5, 'Great')", reviewID, userID, productID); err != nil {
+			t.Fatalf("Failed to create review: %v", err)
+		}
+		if _, err := db.Exec("INSERT INTO review_images (review_id, upload_id) VALUES ($1, $2)", reviewID, uploadID); err != nil {
+			t.Fatalf("Failed to attach image: %v", err)
+		}
+		reviewIDs = append(reviewIDs, reviewID)
+	}
+
+	if err := service.DeleteReview(userID, reviewIDs[0]); err != nil {
+		t.Fatalf("DeleteReview returned error: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the image still shown by another review to be kept, got %v", err)
+	}
+	if err := service.DeleteReview(userID, reviewIDs[1]); err != nil {
+		t.Fatalf("DeleteReview returned error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the image to be deleted with its last review, got %v", err)
+	}
+}
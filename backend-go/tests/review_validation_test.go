@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"ecommerce-backend/internal/models"
+)
+
+func TestReviewLimitsCheck(t *testing.T) {
+	limits := models.ReviewLimits{CommentMinLength: 3, CommentMaxLength: 10}
+	intPtr := func(v int) *int { return &v }
+
+	tests := []struct {
+		name    string
+		rating  *int
+		comment string
+		field   string
+	}{
+		{"zero stars", intPtr(0), "Great", "rating"},
+		{"six stars", intPtr(6), "Great", "rating"},
+		{"one star", intPtr(1), "Great", ""},
+		{"five stars", intPtr(5), "Great", ""},
+		{"empty comment", intPtr(4), "", "comment"},
+		{"whitespace comment", intPtr(4), "   \n ", "comment"},
+		{"below minimum", intPtr(4), "ok", "comment"},
+		{"at minimum", intPtr(4), "yes", ""},
+		{"at maximum", intPtr(4), strings.Repeat("é", 10), ""},
+		{"oversized comment", intPtr(4), strings.Repeat("a", 11), "comment"},
+		{"trimmed to fit", intPtr(4), "  " + strings.Repeat("a", 10) + "  ", ""},
+		{"control character", intPtr(4), "bad\x00text", "comment"},
+		{"newlines allowed", intPtr(4), "line\nline", ""},
+		{"rating unchanged", nil, "Great", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comment := tt.comment
+			errs := limits.Check(tt.rating, &comment)
+			if tt.field == "" {
+				if errs.HasErrors() {
+					t.Fatalf("Expected no errors, got %v", errs)
+				}
+				if comment != strings.TrimSpace(tt.comment) {
+					t.Errorf("Expected comment to be trimmed, got %q", comment)
+				}
+				return
+			}
+			if len(errs) == 0 || errs[0].Field != tt.field {
+				t.Fatalf("Expected an error on %s, got %v", tt.field, errs)
+			}
+		})
+	}
+}
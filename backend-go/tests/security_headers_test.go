@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func securityHeadersFor(t *testing.T, cfg middleware.SecurityHeadersConfig) http.Header {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.SecurityHeadersWithConfig(cfg))
+	r.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	return w.Header()
+}
+
+func TestStrictSecurityHeaders(t *testing.T) {
+	cfg, err := middleware.SecurityHeadersPreset(middleware.SecurityPresetStrict)
+	if err != nil {
+		t.Fatalf("SecurityHeadersPreset returned error: %v", err)
+	}
+	headers := securityHeadersFor(t, cfg)
+
+	expected := map[string]string{
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           "no-referrer",
+		"Strict-Transport-Security": "max-age=63072000; includeSubDomains; preload",
+		"Content-Security-Policy":   "default-src 'none'; frame-ancestors 'none'; base-uri 'none'; form-action 'none'",
+	}
+	for name, value := range expected {
+		if got := headers.Get(name); got != value {
+			t.Errorf("Expected %s %q, got %q", name, value, got)
+		}
+	}
+	if headers.Get("Permissions-Policy") == "" {
+		t.Error("Permissions-Policy should be set")
+	}
+}
+
+func TestStrictSecurityHeadersRelaxPagePolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := middleware.StrictSecurityHeaders()
+	r := gin.New()
+	r.Use(middleware.SecurityHeadersWithConfig(cfg))
+	r.GET("/admin", middleware.PageSecurityHeaders(cfg), func(c *gin.Context) {
+		c.String(http.StatusOK, "<html></html>")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	if got := w.Header().Get("Content-Security-Policy"); got != cfg.PageContentSecurityPolicy {
+		t.Errorf("Expected the page policy on /admin, got %q", got)
+	}
+	if !strings.Contains(cfg.PageContentSecurityPolicy, "'unsafe-inline'") {
+		t.Error("The page policy should allow the inline scripts and styles of /admin and /docs")
+	}
+}
+
+func TestSecurityHeadersWithoutHSTS(t *testing.T) {
+	cfg := middleware.DefaultSecurityHeaders()
+	cfg.HSTSMaxAge = 0
+	headers := securityHeadersFor(t, cfg)
+
+	if got := headers.Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("Expected no HSTS header, got %q", got)
+	}
+	if headers.Get("Content-Security-Policy") == "" || headers.Get("X-Frame-Options") == "" {
+		t.Error("Disabling HSTS should keep the other security headers")
+	}
+}
+
+func TestSecurityHeadersPresetRejectsUnknownName(t *testing.T) {
+	if _, err := middleware.SecurityHeadersPreset("lax"); err == nil {
+		t.Error("Expected an error for an unknown preset")
+	}
+}
+
+func TestCORSPreflightMaxAge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("FRONTEND_URL", "http://localhost:3000")
+	r := gin.New()
+	r.Use(middleware.CORSMiddlewareWithMaxAge(5 * time.Minute))
+	r.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/ping", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "300" {
+		t.Errorf("Expected Access-Control-Max-Age 300, got %q", got)
+	}
+}
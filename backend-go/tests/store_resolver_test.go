@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/middleware"
+	"ecommerce-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestStoreResolver(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	utils.InitJWT("store-test-secret", time.Hour, time.Hour, "eshop", "eshop")
+
+	stores := map[string]string{"default": "store-default", "acme": "store-acme", "globex": "store-globex"}
+	lookup := func(value string) (string, error) {
+		if id, ok := stores[value]; ok {
+			return id, nil
+		}
+		return "", fmt.Errorf("store not found")
+	}
+	r := gin.New()
+	r.Use(middleware.StoreResolver(middleware.StoreResolverConfig{
+		Header:         "X-Store",
+		JWTClaim:       "store_id",
+		DefaultStoreID: "store-default",
+		ByIdentifier:   lookup,
+		ByHost: func(host string) (string, error) {
+			return lookup(host[:len(host)-len(".shop.test")])
+		},
+	}))
+	r.GET("/api/products", func(c *gin.Context) { c.String(http.StatusOK, c.GetString("store_id")) })
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "u1", "store_id": "acme"})
+	signed, err := token.SignedString([]byte("store-test-secret"))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	issued, err := utils.GenerateJWT("u2", "staff@example.com", "admin", "globex")
+	if err != nil {
+		t.Fatalf("GenerateJWT returned error: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		host   string
+		header string
+		token  string
+		code   int
+		store  string
+	}{
+		{name: "default", host: "api.shop.test", code: http.StatusOK, store: "store-default"},
+		{name: "subdomain", host: "globex.shop.test", code: http.StatusOK, store: "store-globex"},
+		{name: "header beats host", host: "globex.shop.test", header: "acme", code: http.StatusOK, store: "store-acme"},
+		{name: "unknown header", host: "api.shop.test", header: "initech", code: http.StatusNotFound},
+		{name: "claim", host: "api.shop.test", token: signed, code: http.StatusOK, store: "store-acme"},
+		{name: "claim matches header", host: "api.shop.test", header: "acme", token: signed, code: http.StatusOK, store: "store-acme"},
+		{name: "claim conflicts with header", host: "api.shop.test", header: "globex", token: signed, code: http.StatusForbidden},
+		{name: "claim conflicts with host", host: "globex.shop.test", token: signed, code: http.StatusForbidden},
+		{name: "issued claim", host: "api.shop.test", token: issued, code: http.StatusOK, store: "store-globex"},
+		{name: "issued claim conflicts with header", host: "api.shop.test", header: "acme", token: issued, code: http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+		req.Host = tc.host
+		if tc.header != "" {
+			req.Header.Set("X-Store", tc.header)
+		}
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.code, w.Code)
+			continue
+		}
+		if tc.code == http.StatusOK && w.Body.String() != tc.store {
+			t.Errorf("%s: expected store %q, got %q", tc.name, tc.store, w.Body.String())
+		}
+	}
+}
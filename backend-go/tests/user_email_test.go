@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/config"
+	"ecommerce-backend/internal/models"
+	"ecommerce-backend/internal/repositories"
+	"ecommerce-backend/internal/services"
+	"ecommerce-backend/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	cases := map[string]string{
+		"a@x.com":            "a@x.com",
+		"  A@X.Com ":         "a@x.com",
+		"\tJane.Doe@Mail.io": "jane.doe@mail.io",
+	}
+	for input, expected := range cases {
+		if got := utils.NormalizeEmail(input); got != expected {
+			t.Errorf("NormalizeEmail(%q): expected %q, got %q", input, expected, got)
+		}
+	}
+}
+
+func TestRegisterDuplicateEmailConflict(t *testing.T) {
+	db := openTestDatabase(t)
+	userRepo := repositories.NewUserRepository(db)
+	hasher := utils.NewPasswordHasher(utils.PasswordHashOptions{Algorithm: utils.PasswordAlgorithmBcrypt, BcryptCost: 4})
+	service := services.NewUserService(userRepo, hasher)
+
+	local := "dup-" + uuid.New().String()[:8]
+	user, err := service.CreateUser(models.UserCreateRequest{Email: " " + local + "@Example.com", Name: "First", Password: "Password123!"})
+	if err != nil {
+		t.Fatalf("CreateUser returned error: %v", err)
+	}
+	t.Cleanup(func() { db.Exec("DELETE FROM users WHERE id = $1", user.ID) })
+	if user.Email != local+"@example.com" {
+		t.Errorf("Expected the stored email to be normalized, got %q", user.Email)
+	}
+
+	_, err = service.CreateUser(models.UserCreateRequest{Email: local + "@EXAMPLE.COM", Name: "Second", Password: "Password123!"})
+	if err == nil || err.Error() != "email already registered" {
+		t.Fatalf("Expected email already registered, got %v", err)
+	}
+
+	// A sign-up that races past the pre-check hits the unique constraint.
+	err = userRepo.Create(&models.User{ID: uuid.New().String(), Email: local + "@example.com", Password: "x", Role: "user", CreatedAt: time.Now(), UpdatedAt: time.Now()})
+	if err == nil || err.Error() != "email already registered" {
+		t.Fatalf("Expected the constraint violation to map to email already registered, got %v", err)
+	}
+
+	if _, err := service.Authenticate(local+"@EXAMPLE.com", "Password123!"); err != nil {
+		t.Errorf("Expected login to ignore email case, got %v", err)
+	}
+}
+
+func TestEmailDomainPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocked.txt")
+	if err := os.WriteFile(path, []byte("# internal list\nspam.example\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write domains file: %v", err)
+	}
+	cfg := config.RegisterConfig{BlockedEmailDomains: []string{"Blocked.test"}, BlockedEmailDomainsFile: path, BlockDisposableEmails: true}
+	policy := services.NewEmailDomainPolicy(cfg)
+
+	for _, email := range []string{"a@blocked.test", "a@mail.BLOCKED.test", "a@spam.example", "a@mailinator.com"} {
+		if err := policy.Check(email); err == nil {
+			t.Errorf("Expected %s to be blocked", email)
+		}
+	}
+	for _, email := range []string{"a@example.com", "a@notblocked.test"} {
+		if err := policy.Check(email); err != nil {
+			t.Errorf("Expected %s to be allowed, got %v", email, err)
+		}
+	}
+
+	// Edits to the file apply on the next reload without a restart.
+	if err := os.WriteFile(path, []byte("other.example\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write domains file: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if err := policy.Reload(); err != nil {
+		t.Fatalf("Reload returned error: %v", err)
+	}
+	if policy.Check("a@spam.example") != nil || policy.Check("a@other.example") == nil || policy.Check("a@blocked.test") == nil {
+		t.Error("Expected the reloaded file to replace the previous file entries")
+	}
+
+	cfg.EmailDomainCheckDisabled = true
+	if err := services.NewEmailDomainPolicy(cfg).Check("a@mailinator.com"); err != nil {
+		t.Errorf("Expected the disabled policy to allow every domain, got %v", err)
+	}
+}
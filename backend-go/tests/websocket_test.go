@@ -0,0 +1,310 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ecommerce-backend/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	gorilla "github.com/gorilla/websocket"
+)
+
+type hubClients struct {
+	clients  []*websocket.Client
+	received int64
+	wg       sync.WaitGroup
+}
+
+func connectHubClients(hub *websocket.Hub, n int) *hubClients {
+	hc := &hubClients{}
+	for i := 0; i < n; i++ {
+		client := &websocket.Client{
+			Hub:      hub,
+			Send:     make(chan []byte, 256),
+			UserID:   fmt.Sprintf("user-%d", i),
+			JoinedAt: time.Now(),
+		}
+		hub.Register(client)
+		<-client.Send // welcome message
+
+		go func() {
+			for range client.Send {
+				atomic.AddInt64(&hc.received, 1)
+				hc.wg.Done()
+			}
+		}()
+		hc.clients = append(hc.clients, client)
+	}
+	return hc
+}
+
+// broadcastAndWait broadcasts a message and blocks until every client has
+// received it, re-sending only if the hub dropped the message entirely.
+func (hc *hubClients) broadcastAndWait(hub *websocket.Hub, message *websocket.Message) {
+	hc.wg.Add(len(hc.clients))
+	done := make(chan struct{})
+	go func() {
+		hc.wg.Wait()
+		close(done)
+	}()
+
+	before := atomic.LoadInt64(&hc.received)
+	hub.Broadcast(message)
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(20 * time.Millisecond):
+			if atomic.LoadInt64(&hc.received) == before {
+				hub.Broadcast(message)
+			}
+		}
+	}
+}
+
+func TestHubBroadcastReachesAllClients(t *testing.T) {
+	hub := websocket.NewHub(4, 0)
+	go hub.Run()
+
+	hc := connectHubClients(hub, 50)
+	message := websocket.CreateNotificationMessage("Sale", "Everything is 10% off", "info", "low", "promotions")
+	for i := 0; i < 5; i++ {
+		hc.broadcastAndWait(hub, message)
+	}
+
+	if got := atomic.LoadInt64(&hc.received); got != 250 {
+		t.Errorf("Expected 250 deliveries, got %d", got)
+	}
+}
+
+func TestHubRejectsConnectionsOverLimits(t *testing.T) {
+	hub := websocket.NewHub(1, 0)
+	hub.SetConnectionLimits(websocket.ConnectionLimits{MaxConnections: 3, MaxConnectionsPerUser: 2})
+	go hub.Run()
+
+	register := func(userID string) error {
+		client := &websocket.Client{Hub: hub, Send: make(chan []byte, 256), UserID: userID, JoinedAt: time.Now()}
+		return hub.Register(client)
+	}
+	for i := 0; i < 2; i++ {
+		if err := register("user-a"); err != nil {
+			t.Fatalf("Register returned error: %v", err)
+		}
+	}
+	if err := register("user-a"); err != websocket.ErrTooManyUserConnections {
+		t.Errorf("Expected ErrTooManyUserConnections, got %v", err)
+	}
+	if err := register("user-b"); err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+	if err := register(""); err != websocket.ErrTooManyConnections {
+		t.Errorf("Expected ErrTooManyConnections, got %v", err)
+	}
+
+	stats := hub.GetStats()
+	if stats.TotalClients != 3 || stats.Rejected != 2 {
+		t.Errorf("Expected 3 clients and 2 rejections, got %d and %d", stats.TotalClients, stats.Rejected)
+	}
+}
+
+func TestHubHealthy(t *testing.T) {
+	hub := websocket.NewHub(1, 0)
+	if hub.Healthy() {
+		t.Error("Hub should not be healthy before Run is started")
+	}
+
+	go hub.Run()
+	if !hub.Healthy() {
+		t.Error("Hub should be healthy while Run is processing")
+	}
+}
+
+func TestHubBroadcastCountsDroppedMessages(t *testing.T) {
+	hub := websocket.NewHub(1, 2)
+
+	message := websocket.CreateNotificationMessage("Sale", "Everything is 10% off", "info", "low", "promotions")
+	for i := 0; i < 5; i++ {
+		hub.Broadcast(message)
+	}
+
+	metrics := hub.GetStats().Metrics
+	if metrics["broadcasts_queued"] != int64(2) {
+		t.Errorf("Expected 2 queued broadcasts, got %v", metrics["broadcasts_queued"])
+	}
+	if metrics["broadcasts_dropped"] != int64(3) {
+		t.Errorf("Expected 3 dropped broadcasts, got %v", metrics["broadcasts_dropped"])
+	}
+}
+
+// Run with -race: broadcasts, targeted sends and stats reads race against
+// clients connecting, disconnecting and being dropped for full buffers.
+func TestHubBroadcastDuringClientChurn(t *testing.T) {
+	hub := websocket.NewHub(4, 0)
+	go hub.Run()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				client := &websocket.Client{
+					Hub:      hub,
+					Send:     make(chan []byte, 1+i%4),
+					UserID:   fmt.Sprintf("user-%d", i%10),
+					UserRole: "admin",
+					JoinedAt: time.Now(),
+				}
+				hub.Register(client)
+				if i%3 == 0 {
+					// Never drained: the hub must drop it once its buffer fills.
+					continue
+				}
+				for j := 0; j < 2; j++ {
+					select {
+					case <-client.Send:
+					default:
+					}
+				}
+				hub.Unregister(client)
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		message := websocket.CreateNotificationMessage("Sale", "Everything is 10% off", "info", "low", "promotions")
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			hub.Broadcast(message)
+			hub.BroadcastToUser("user-1", message)
+			hub.SendOrderUpdate("order-1", "shipped", "Your order has shipped", "user-2")
+			hub.SendStockAlert("product-1", "Widget", 3)
+			hub.GetStats()
+		}
+	}()
+
+	time.Sleep(300 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	client := &websocket.Client{Hub: hub, Send: make(chan []byte, 1), JoinedAt: time.Now()}
+	hub.Register(client)
+	select {
+	case <-client.Send:
+	case <-time.After(time.Second):
+		t.Error("Hub should still deliver to new clients after churn")
+	}
+}
+
+// Run with -race: inbound messages are counted from each client's read loop
+// while stats are read concurrently.
+func TestHubCountsReceivedMessages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hub := websocket.NewHub(2, 0)
+	go hub.Run()
+
+	r := gin.New()
+	r.GET("/ws", websocket.NewHandler(hub).HandleWebSocket)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	const clients, perClient = 3, 5
+	for i := 0; i < clients; i++ {
+		conn, _, err := gorilla.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", http.Header{})
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer conn.Close()
+		go func() {
+			for j := 0; j < perClient; j++ {
+				conn.WriteJSON(websocket.Message{Type: websocket.MessageTypePing, Timestamp: time.Now()})
+			}
+		}()
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.GetStats().MessagesReceived < clients*perClient {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d received messages, got %d", clients*perClient, hub.GetStats().MessagesReceived)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHubOrderFeedReachesSubscribedAdminsOnly(t *testing.T) {
+	hub := websocket.NewHub(2, 0)
+	go hub.Run()
+
+	newClient := func(userID, role string) *websocket.Client {
+		client := &websocket.Client{Hub: hub, Send: make(chan []byte, 8), UserID: userID, UserRole: role, JoinedAt: time.Now()}
+		hub.Register(client)
+		<-client.Send // welcome message
+		return client
+	}
+	subscribed := newClient("admin-1", "admin")
+	unsubscribed := newClient("admin-2", "admin")
+	customer := newClient("user-1", "user")
+
+	if err := hub.Subscribe(subscribed, websocket.TopicAdminOrders); err != nil {
+		t.Fatalf("Admin should be able to subscribe: %v", err)
+	}
+	if err := hub.Subscribe(customer, websocket.TopicAdminOrders); err == nil {
+		t.Error("Non-admin should not be able to subscribe to the order feed")
+	}
+	if err := hub.Subscribe(subscribed, "unknown"); err == nil {
+		t.Error("Subscribing to an unknown topic should fail")
+	}
+
+	hub.SendOrderFeed(websocket.OrderFeedData{OrderID: "order-1", Total: 42.5, ItemCount: 3, Customer: "Ada"})
+
+	select {
+	case data := <-subscribed.Send:
+		if !strings.Contains(string(data), `"order_feed"`) || !strings.Contains(string(data), `"item_count":3`) {
+			t.Errorf("Unexpected order feed message: %s", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Subscribed admin should receive the order feed")
+	}
+	if len(unsubscribed.Send) != 0 || len(customer.Send) != 0 {
+		t.Error("Only subscribed admins should receive the order feed")
+	}
+}
+
+func BenchmarkHubBroadcast(b *testing.B) {
+	const clients = 5000
+
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			hub := websocket.NewHub(workers, 0)
+			go hub.Run()
+
+			hc := connectHubClients(hub, clients)
+			message := websocket.CreateNotificationMessage("Sale", "Everything is 10% off", "info", "low", "promotions")
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				hc.broadcastAndWait(hub, message)
+			}
+		})
+	}
+}